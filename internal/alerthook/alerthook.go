@@ -0,0 +1,341 @@
+// Package alerthook receives Alertmanager webhook notifications and turns
+// the alerts in them into actions on the supervisor: restarting the
+// trainer when rewards flatline, pausing it while an alert fires, or
+// passing the alert on to the node's chats. External monitoring can then
+// act on a node instead of only paging its operator.
+package alerthook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Path is where the status API receives Alertmanager's webhooks
+const Path = "/api/v1/alertmanager"
+
+// Actions an alert can trigger
+const (
+	ActionRestart = "restart"
+	ActionPause   = "pause"
+	ActionNotify  = "notify"
+)
+
+// DefaultCooldown is the least time between two restarts caused by
+// alerts, so an alert that keeps firing after a restart can't loop
+const DefaultCooldown = 30 * time.Minute
+
+// maxPayloadSize bounds a webhook body; Alertmanager sends a few KiB
+const maxPayloadSize = 1 << 20
+
+// Rule maps alerts by name to an action; the name "*" matches any alert
+type Rule struct {
+	Alert  string
+	Action string
+	// For is how long a pause lasts; 0 pauses until the alert resolves
+	For time.Duration
+}
+
+func (r Rule) String() string {
+	if r.Action == ActionPause && r.For > 0 {
+		return fmt.Sprintf("%s=%s:%s", r.Alert, r.Action, r.For)
+	}
+	return r.Alert + "=" + r.Action
+}
+
+// ParseRules reads rules such as "GswarmNoRewardGrowth=restart",
+// "GswarmGPUHot=pause", "GswarmGPUHot=pause:1h" or "*=notify"
+func ParseRules(specs []string) ([]Rule, error) {
+	var rules []Rule
+	for _, spec := range specs {
+		name, action, ok := strings.Cut(strings.TrimSpace(spec), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid alert action %q: want <alertname>=<action>", spec)
+		}
+		r := Rule{Alert: name, Action: action}
+		if a, d, ok := strings.Cut(action, ":"); ok && a == ActionPause {
+			dur, err := time.ParseDuration(d)
+			if err != nil || dur <= 0 {
+				return nil, fmt.Errorf("invalid alert action %q: %q isn't a pause duration", spec, d)
+			}
+			r.Action, r.For = ActionPause, dur
+		}
+		switch r.Action {
+		case ActionRestart, ActionPause, ActionNotify:
+		default:
+			return nil, fmt.Errorf("invalid alert action %q: the action must be %s, %s or %s", spec, ActionRestart, ActionPause, ActionNotify)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Payload is the body of an Alertmanager webhook (version 4)
+type Payload struct {
+	Version     string  `json:"version"`
+	Status      string  `json:"status"`
+	Receiver    string  `json:"receiver"`
+	ExternalURL string  `json:"externalURL"`
+	Alerts      []Alert `json:"alerts"`
+}
+
+// Alert is one alert in a payload
+type Alert struct {
+	// Status is "firing" or "resolved"
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// Name is the alert's alertname label
+func (a Alert) Name() string {
+	return a.Labels["alertname"]
+}
+
+// Firing reports whether the alert is firing rather than resolved
+func (a Alert) Firing() bool {
+	return a.Status != "resolved"
+}
+
+// Summary is the alert's summary or description annotation, or its name
+func (a Alert) Summary() string {
+	for _, key := range []string{"summary", "description"} {
+		if s := a.Annotations[key]; s != "" {
+			return s
+		}
+	}
+	return a.Name()
+}
+
+// key identifies the alert across notifications
+func (a Alert) key() string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	return a.Name()
+}
+
+// Command is an action the supervisor is asked to carry out
+type Command struct {
+	Action string
+	Alert  Alert
+	// Until is when a pause ends; zero when it lasts until the alert
+	// resolves
+	Until time.Time
+}
+
+// Result is what became of one alert, as reported to Alertmanager and
+// the supervisor log
+type Result struct {
+	Alert  string `json:"alert"`
+	Status string `json:"status"`
+	Action string `json:"action,omitempty"`
+	// Outcome is "done", or why nothing was done
+	Outcome string `json:"outcome"`
+}
+
+// hold is a pause an alert keeps in place
+type hold struct {
+	alert string
+	until time.Time
+}
+
+// Controller applies the rules to incoming alerts. Pauses are held until
+// their alert resolves or their time is up; restarts are limited to one
+// per Cooldown.
+type Controller struct {
+	Rules    []Rule
+	Token    string
+	Cooldown time.Duration
+
+	mu          sync.Mutex
+	lastRestart time.Time
+	holds       map[string]hold
+	// changed is closed and replaced whenever a hold is added or removed
+	changed chan struct{}
+}
+
+// New returns a controller for rules; cooldown 0 is DefaultCooldown
+func New(rules []Rule, token string, cooldown time.Duration) *Controller {
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	return &Controller{Rules: rules, Token: token, Cooldown: cooldown, holds: map[string]hold{}, changed: make(chan struct{})}
+}
+
+// rule returns the first rule matching name
+func (c *Controller) rule(name string) (Rule, bool) {
+	for _, r := range c.Rules {
+		if r.Alert == name || r.Alert == "*" {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Handle applies the rules to the alerts in p at now, returning the
+// commands to carry out and what became of each alert
+func (c *Controller) Handle(p Payload, now time.Time) ([]Command, []Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var commands []Command
+	var results []Result
+	for _, a := range p.Alerts {
+		res := Result{Alert: a.Name(), Status: a.Status}
+		r, ok := c.rule(a.Name())
+		if !ok {
+			res.Outcome = "no action configured"
+			results = append(results, res)
+			continue
+		}
+		res.Action, res.Outcome = r.Action, "done"
+		switch {
+		case r.Action == ActionNotify:
+			commands = append(commands, Command{Action: ActionNotify, Alert: a})
+		case r.Action == ActionPause && a.Firing():
+			if _, held := c.holds[a.key()]; held {
+				res.Outcome = "already paused"
+				break
+			}
+			h := hold{alert: a.Name()}
+			if r.For > 0 {
+				h.until = now.Add(r.For)
+			}
+			c.holds[a.key()] = h
+			c.notifyChanged()
+			commands = append(commands, Command{Action: ActionPause, Alert: a, Until: h.until})
+		case r.Action == ActionPause:
+			if _, held := c.holds[a.key()]; !held {
+				res.Outcome = "not paused"
+				break
+			}
+			delete(c.holds, a.key())
+			c.notifyChanged()
+		case r.Action == ActionRestart && a.Firing():
+			if !c.lastRestart.IsZero() && now.Sub(c.lastRestart) < c.Cooldown {
+				res.Outcome = fmt.Sprintf("skipped, restarted %s ago", now.Sub(c.lastRestart).Round(time.Second))
+				break
+			}
+			c.lastRestart = now
+			commands = append(commands, Command{Action: ActionRestart, Alert: a})
+		default:
+			res.Outcome = "resolved, nothing to do"
+		}
+		results = append(results, res)
+	}
+	return commands, results
+}
+
+// notifyChanged wakes Wait. Callers hold c.mu.
+func (c *Controller) notifyChanged() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Paused returns the alert holding the trainer paused at now, and when
+// the pause ends; the time is zero for a pause lasting until the alert
+// resolves
+func (c *Controller) Paused(now time.Time) (string, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused(now)
+}
+
+// paused drops expired holds and returns the one that lasts longest.
+// Callers hold c.mu.
+func (c *Controller) paused(now time.Time) (string, time.Time, bool) {
+	var longest hold
+	found := false
+	for key, h := range c.holds {
+		if !h.until.IsZero() && !now.Before(h.until) {
+			delete(c.holds, key)
+			continue
+		}
+		if !found || h.until.IsZero() || (!longest.until.IsZero() && h.until.After(longest.until)) {
+			longest, found = h, true
+		}
+	}
+	return longest.alert, longest.until, found
+}
+
+// Wait blocks while an alert holds the trainer paused. It returns false
+// when ctx is done first.
+func (c *Controller) Wait(ctx context.Context) bool {
+	for {
+		c.mu.Lock()
+		_, until, held := c.paused(time.Now())
+		changed := c.changed
+		c.mu.Unlock()
+		if !held {
+			return true
+		}
+		var expire <-chan time.Time
+		var timer *time.Timer
+		if !until.IsZero() {
+			timer = time.NewTimer(time.Until(until))
+			expire = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-expire:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
+
+// Handler receives Alertmanager's webhooks and passes each command to run.
+// Without a token, webhooks are refused unless the listener is loopback,
+// and must be sent as JSON, which a web page can't do without the
+// browser asking the API first.
+func (c *Controller) Handler(loopback bool, run func(Command)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if c.Token == "" && !loopback {
+			http.Error(w, "alerts are refused on a listener beyond loopback without --alert-token", http.StatusForbidden)
+			return
+		}
+		if c.Token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(c.Token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); c.Token == "" && mediaType != "application/json" {
+			http.Error(w, "alerts must be sent as application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		var p Payload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxPayloadSize)).Decode(&p); err != nil {
+			http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		commands, results := c.Handle(p, time.Now())
+		for _, cmd := range commands {
+			run(cmd)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]Result{"results": results})
+	})
+}
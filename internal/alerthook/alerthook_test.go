@@ -0,0 +1,148 @@
+package alerthook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]string{"GswarmNoRewardGrowth=restart", "GswarmGPUHot=pause:1h", "*=notify"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 || rules[1].Action != ActionPause || rules[1].For != time.Hour || rules[1].String() != "GswarmGPUHot=pause:1h0m0s" {
+		t.Errorf("ParseRules() = %+v", rules)
+	}
+	for _, bad := range []string{"restart", "=restart", "X=reboot", "X=pause:soon", "X=restart:1h"} {
+		if _, err := ParseRules([]string{bad}); err == nil {
+			t.Errorf("ParseRules(%q) accepted it", bad)
+		}
+	}
+}
+
+func alert(name, status string) Alert {
+	return Alert{Status: status, Labels: map[string]string{"alertname": name}, Fingerprint: "fp-" + name}
+}
+
+func TestHandle(t *testing.T) {
+	rules, _ := ParseRules([]string{"Flatline=restart", "Hot=pause", "Busy=pause:1h", "*=notify"})
+	c := New(rules, "", 30*time.Minute)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cmds, _ := c.Handle(Payload{Alerts: []Alert{alert("Flatline", "firing"), alert("Other", "firing")}}, now)
+	if len(cmds) != 2 || cmds[0].Action != ActionRestart || cmds[1].Action != ActionNotify {
+		t.Fatalf("Handle(firing) = %+v", cmds)
+	}
+	// Alertmanager repeats firing alerts; the restart isn't repeated
+	cmds, results := c.Handle(Payload{Alerts: []Alert{alert("Flatline", "firing")}}, now.Add(10*time.Minute))
+	if len(cmds) != 0 || !strings.HasPrefix(results[0].Outcome, "skipped") {
+		t.Errorf("Handle(repeat within cooldown) = %+v, %+v", cmds, results)
+	}
+	if cmds, _ := c.Handle(Payload{Alerts: []Alert{alert("Flatline", "firing")}}, now.Add(time.Hour)); len(cmds) != 1 {
+		t.Errorf("Handle(after cooldown) = %+v", cmds)
+	}
+
+	cmds, _ = c.Handle(Payload{Alerts: []Alert{alert("Hot", "firing")}}, now)
+	if len(cmds) != 1 || cmds[0].Action != ActionPause || !cmds[0].Until.IsZero() {
+		t.Fatalf("Handle(pause) = %+v", cmds)
+	}
+	if name, until, ok := c.Paused(now); !ok || name != "Hot" || !until.IsZero() {
+		t.Errorf("Paused() = %q, %v, %v", name, until, ok)
+	}
+	c.Handle(Payload{Alerts: []Alert{alert("Hot", "resolved")}}, now)
+	if _, _, ok := c.Paused(now); ok {
+		t.Error("still paused after the alert resolved")
+	}
+
+	c.Handle(Payload{Alerts: []Alert{alert("Busy", "firing")}}, now)
+	if _, until, ok := c.Paused(now.Add(30 * time.Minute)); !ok || !until.Equal(now.Add(time.Hour)) {
+		t.Errorf("Paused() during a timed pause = %v, %v", until, ok)
+	}
+	if _, _, ok := c.Paused(now.Add(2 * time.Hour)); ok {
+		t.Error("timed pause didn't end")
+	}
+}
+
+func TestWait(t *testing.T) {
+	rules, _ := ParseRules([]string{"Hot=pause"})
+	c := New(rules, "", 0)
+	c.Handle(Payload{Alerts: []Alert{alert("Hot", "firing")}}, time.Now())
+
+	done := make(chan bool)
+	go func() { done <- c.Wait(context.Background()) }()
+	select {
+	case <-done:
+		t.Fatal("Wait returned while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.Handle(Payload{Alerts: []Alert{alert("Hot", "resolved")}}, time.Now())
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("Wait() = false")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait didn't return after the alert resolved")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rules, _ := ParseRules([]string{"Flatline=restart"})
+	c := New(rules, "s3cret", 0)
+	var got []Command
+	h := c.Handler(false, func(cmd Command) { got = append(got, cmd) })
+
+	body := `{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"alertname":"Flatline","peer_id":"QmA"},"annotations":{"summary":"no rewards in 6h"}}]}`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized || len(got) != 0 {
+		t.Fatalf("request without token: %d, %+v", rec.Code, got)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || len(got) != 1 || got[0].Alert.Summary() != "no rewards in 6h" {
+		t.Errorf("webhook: %d %s, %+v", rec.Code, rec.Body, got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d", rec.Code)
+	}
+}
+
+func TestHandler_NoToken(t *testing.T) {
+	rules, _ := ParseRules([]string{"Flatline=restart"})
+	c := New(rules, "", 0)
+	var got []Command
+	run := func(cmd Command) { got = append(got, cmd) }
+	body := `{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"alertname":"Flatline"}}]}`
+	post := func(h http.Handler, contentType string) int {
+		req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post(c.Handler(false, run), "application/json"); code != http.StatusForbidden {
+		t.Errorf("webhook beyond loopback = %d, want %d", code, http.StatusForbidden)
+	}
+	// A form posted by a web page isn't JSON
+	if code := post(c.Handler(true, run), "text/plain"); code != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain webhook = %d, want %d", code, http.StatusUnsupportedMediaType)
+	}
+	if len(got) != 0 {
+		t.Fatalf("refused webhooks ran %+v", got)
+	}
+	if code := post(c.Handler(true, run), "application/json; charset=utf-8"); code != http.StatusOK || len(got) != 1 {
+		t.Errorf("JSON webhook on loopback = %d, %+v", code, got)
+	}
+}
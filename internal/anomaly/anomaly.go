@@ -0,0 +1,208 @@
+// Package anomaly watches per-peer reward totals for the patterns that the
+// combined totals hide: one peer's rewards flatlining while its siblings
+// keep earning, which usually means a stuck node, and rewards going down.
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+	"github.com/Deep-Commit/gswarm/internal/int256"
+)
+
+// DefaultFlatline is how long a peer may go without new rewards while a
+// sibling earns before it is reported
+const DefaultFlatline = 2 * time.Hour
+
+// StateFile is where the detector persists per-peer state, relative to the
+// state directory
+const StateFile = "peer_rewards.json"
+
+// Kind is the type of anomaly
+type Kind string
+
+const (
+	// Flatline means a peer stopped earning while a sibling kept earning
+	Flatline Kind = "flatline"
+	// Decrease means a peer's reward total went down
+	Decrease Kind = "decrease"
+	// Recovered means a flatlined peer is earning again
+	Recovered Kind = "recovered"
+)
+
+// Alert is a detected anomaly for one peer
+type Alert struct {
+	Peer     string
+	Kind     Kind
+	Previous *big.Int
+	Current  *big.Int
+	// Since is when the peer last earned, for flatlines and recoveries
+	Since time.Time
+}
+
+// Title summarises the alert for a notification
+func (a Alert) Title() string {
+	switch a.Kind {
+	case Flatline:
+		return "Peer Stopped Earning"
+	case Decrease:
+		return "Peer Rewards Decreased"
+	default:
+		return "Peer Earning Again"
+	}
+}
+
+// Text describes the alert, formatting amounts with f
+func (a Alert) Text(f humanize.Format, now time.Time) string {
+	switch a.Kind {
+	case Flatline:
+		return fmt.Sprintf("Peer %s has earned nothing for %s while other peers on this EOA kept earning; it may be stuck. Rewards: %s",
+			a.Peer, now.Sub(a.Since).Round(time.Minute), f.Int(a.Current))
+	case Decrease:
+		return fmt.Sprintf("Peer %s rewards went from %s to %s (%s).",
+			a.Peer, f.Int(a.Previous), f.Int(a.Current), f.Delta(a.Previous, a.Current))
+	default:
+		return fmt.Sprintf("Peer %s is earning again after %s. Rewards: %s (%s)",
+			a.Peer, now.Sub(a.Since).Round(time.Minute), f.Int(a.Current), f.Delta(a.Previous, a.Current))
+	}
+}
+
+// peerState is what the detector remembers about a peer
+type peerState struct {
+	Rewards *big.Int `json:"rewards"`
+	// Changed is when Rewards last changed
+	Changed time.Time `json:"changed"`
+	// PerHour is the reward velocity over the last change
+	PerHour *big.Int `json:"perHour,omitempty"`
+	Flat    bool     `json:"flat,omitempty"`
+}
+
+// Detector tracks per-peer reward velocity across checks
+type Detector struct {
+	// FlatAfter is how long a peer may go without rewards while a sibling
+	// earns; 0 disables flatline alerts. It comes from the flag on every
+	// start, never from the state file.
+	FlatAfter time.Duration         `json:"-"`
+	Peers     map[string]*peerState `json:"peers"`
+}
+
+// New creates a detector with no history
+func New(flatAfter time.Duration) *Detector {
+	return &Detector{FlatAfter: flatAfter, Peers: map[string]*peerState{}}
+}
+
+// Load reads a detector saved with Save, starting fresh if path doesn't exist
+func Load(path string, flatAfter time.Duration) (*Detector, error) {
+	d := New(flatAfter)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(data, d); err != nil {
+		return New(flatAfter), fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if d.Peers == nil {
+		d.Peers = map[string]*peerState{}
+	}
+	for _, p := range d.Peers {
+		int256.Repair(p.Rewards)
+	}
+	return d, nil
+}
+
+// Save writes the per-peer state to path
+func (d *Detector) Save(path string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Velocity returns the peer's rewards per hour over its last change, or
+// nil if unknown
+func (d *Detector) Velocity(peer string) *big.Int {
+	if p, ok := d.Peers[peer]; ok {
+		return p.PerHour
+	}
+	return nil
+}
+
+// Observe records the current reward totals and returns the anomalies
+// they reveal. Peers missing from rewards, e.g. because their query
+// failed, keep their state.
+func (d *Detector) Observe(now time.Time, rewards map[string]*big.Int) []Alert {
+	peers := make([]string, 0, len(rewards))
+	for peer := range rewards {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+
+	var alerts []Alert
+	for _, peer := range peers {
+		cur := rewards[peer]
+		p, ok := d.Peers[peer]
+		if !ok {
+			d.Peers[peer] = &peerState{Rewards: new(big.Int).Set(cur), Changed: now}
+			continue
+		}
+		switch cur.Cmp(p.Rewards) {
+		case -1:
+			alerts = append(alerts, Alert{Peer: peer, Kind: Decrease, Previous: p.Rewards, Current: new(big.Int).Set(cur)})
+			p.PerHour = nil
+		case 1:
+			if p.Flat {
+				alerts = append(alerts, Alert{Peer: peer, Kind: Recovered, Previous: p.Rewards, Current: new(big.Int).Set(cur), Since: p.Changed})
+			}
+			p.PerHour = perHour(new(big.Int).Sub(cur, p.Rewards), now.Sub(p.Changed))
+		default:
+			continue
+		}
+		p.Rewards = new(big.Int).Set(cur)
+		p.Changed = now
+		p.Flat = false
+	}
+
+	if d.FlatAfter <= 0 {
+		return alerts
+	}
+	for _, peer := range peers {
+		p := d.Peers[peer]
+		if p.Flat || now.Sub(p.Changed) < d.FlatAfter || !d.siblingEarning(peer, now) {
+			continue
+		}
+		p.Flat = true
+		alerts = append(alerts, Alert{Peer: peer, Kind: Flatline, Current: p.Rewards, Since: p.Changed})
+	}
+	return alerts
+}
+
+// siblingEarning reports whether another peer has earned within FlatAfter
+// and after peer last earned. If every peer is quiet, the swarm as a whole
+// is, and that isn't one node's fault.
+func (d *Detector) siblingEarning(peer string, now time.Time) bool {
+	since := d.Peers[peer].Changed
+	for name, p := range d.Peers {
+		if name != peer && p.Changed.After(since) && now.Sub(p.Changed) < d.FlatAfter {
+			return true
+		}
+	}
+	return false
+}
+
+// perHour scales delta over elapsed to a per-hour rate
+func perHour(delta *big.Int, elapsed time.Duration) *big.Int {
+	if elapsed <= 0 {
+		return nil
+	}
+	rate := new(big.Int).Mul(delta, big.NewInt(int64(time.Hour)))
+	return rate.Quo(rate, big.NewInt(int64(elapsed)))
+}
@@ -0,0 +1,108 @@
+package anomaly
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func rewards(kv ...interface{}) map[string]*big.Int {
+	m := map[string]*big.Int{}
+	for i := 0; i < len(kv); i += 2 {
+		m[kv[i].(string)] = big.NewInt(int64(kv[i+1].(int)))
+	}
+	return m
+}
+
+func TestDetector_Flatline(t *testing.T) {
+	d := New(2 * time.Hour)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		after   time.Duration
+		rewards map[string]*big.Int
+		want    []Kind
+	}{
+		{0, rewards("a", 10, "b", 10), nil},
+		{time.Hour, rewards("a", 20, "b", 20), nil},
+		// b stops earning
+		{2 * time.Hour, rewards("a", 30, "b", 20), nil},
+		{3*time.Hour + time.Minute, rewards("a", 40, "b", 20), []Kind{Flatline}},
+		// reported once per episode
+		{4 * time.Hour, rewards("a", 50, "b", 20), nil},
+		{5 * time.Hour, rewards("a", 60, "b", 25), []Kind{Recovered}},
+	}
+	for i, s := range steps {
+		alerts := d.Observe(start.Add(s.after), s.rewards)
+		if len(alerts) != len(s.want) {
+			t.Fatalf("step %d: alerts = %+v, want %v", i, alerts, s.want)
+		}
+		for j, a := range alerts {
+			if a.Kind != s.want[j] || a.Peer != "b" {
+				t.Errorf("step %d: alert = %s for %s, want %s for b", i, a.Kind, a.Peer, s.want[j])
+			}
+		}
+	}
+	if got := d.Velocity("a"); got == nil || got.Int64() != 10 {
+		t.Errorf("Velocity(a) = %v, want 10 per hour", got)
+	}
+}
+
+func TestDetector_WholeSwarmQuiet(t *testing.T) {
+	d := New(time.Hour)
+	start := time.Now()
+	d.Observe(start, rewards("a", 10, "b", 10))
+	if alerts := d.Observe(start.Add(3*time.Hour), rewards("a", 10, "b", 10)); len(alerts) != 0 {
+		t.Errorf("alerts = %+v, want none when no peer is earning", alerts)
+	}
+}
+
+func TestDetector_Decrease(t *testing.T) {
+	d := New(0)
+	start := time.Now()
+	d.Observe(start, rewards("a", 10))
+	alerts := d.Observe(start.Add(time.Minute), rewards("a", 7))
+	if len(alerts) != 1 || alerts[0].Kind != Decrease || alerts[0].Previous.Int64() != 10 || alerts[0].Current.Int64() != 7 {
+		t.Fatalf("alerts = %+v, want a decrease from 10 to 7", alerts)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := New(time.Hour)
+	d.Observe(start, rewards("a", 10, "b", 10))
+	d.Observe(start.Add(30*time.Minute), rewards("a", 15, "b", 10))
+	if err := d.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	alerts := loaded.Observe(start.Add(90*time.Minute), rewards("a", 20, "b", 10))
+	if len(alerts) != 1 || alerts[0].Kind != Flatline || alerts[0].Peer != "b" {
+		t.Errorf("alerts after reload = %+v, want b flatlined", alerts)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json"), time.Hour); err != nil {
+		t.Errorf("Load(missing) error = %v", err)
+	}
+}
+
+func TestLoad_FlagWins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	if err := New(2 * time.Hour).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, flat := range []time.Duration{0, 30 * time.Minute} {
+		d, err := Load(path, flat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.FlatAfter != flat {
+			t.Errorf("Load(%s).FlatAfter = %s, want the flag's value", flat, d.FlatAfter)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package approval asks for approval in Telegram before the supervisor
+// does something hard to undo, such as switching swarms, reinstalling the
+// trainer's requirements or replacing the node's identity. The request has
+// Approve and Deny buttons; only the configured users can press them, and
+// an unanswered request falls back to a default after a timeout.
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/notify"
+)
+
+// DefaultTimeout is how long a request waits for an answer
+const DefaultTimeout = 10 * time.Minute
+
+// pollWait is how long each getUpdates call waits
+const pollWait = 20 * time.Second
+
+// Bot is the part of the Telegram client approvals use
+type Bot interface {
+	SendButtons(text string, buttons []notify.Button) (int, error)
+	EditHTML(messageID int, text string) error
+	AnswerCallback(id, text string) error
+	GetUpdates(ctx context.Context, offset int, wait time.Duration) ([]notify.Update, error)
+}
+
+// Decision is the outcome of a request
+type Decision struct {
+	Approved bool
+	// By is who answered, empty when the request timed out
+	By       string
+	TimedOut bool
+}
+
+// Approver sends requests to a Telegram chat. Requests may wait at the
+// same time; one getUpdates poller serves them all, since concurrent polls
+// of a bot conflict and each would confirm away the others' updates.
+type Approver struct {
+	Bot    Bot
+	ChatID string
+	// Users may answer: numeric Telegram user IDs or @usernames
+	Users []string
+	// Timeout is how long to wait; Default is the outcome after it
+	Timeout time.Duration
+	Default bool
+	// Node names the node in requests
+	Node string
+
+	mu sync.Mutex
+	// waiting holds the presses for each waiting request by its nonce
+	waiting map[string]chan *notify.CallbackQuery
+	polling bool
+	// stopPoll ends the current getUpdates once nobody waits
+	stopPoll context.CancelFunc
+	offset   int
+}
+
+// ParseUsers splits a comma-separated list of user IDs and @usernames
+func ParseUsers(specs []string) ([]string, error) {
+	var users []string
+	for _, spec := range specs {
+		for _, u := range strings.Split(spec, ",") {
+			u = strings.TrimSpace(u)
+			if u == "" {
+				continue
+			}
+			if _, err := strconv.ParseInt(u, 10, 64); err != nil && (!strings.HasPrefix(u, "@") || len(u) < 2) {
+				return nil, fmt.Errorf("invalid approver %q: use a numeric Telegram user ID or an @username", u)
+			}
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// authorized reports whether u may answer
+func (a *Approver) authorized(u notify.User) bool {
+	for _, allowed := range a.Users {
+		if allowed == strconv.FormatInt(u.ID, 10) ||
+			(u.Username != "" && strings.EqualFold(strings.TrimPrefix(allowed, "@"), u.Username) && strings.HasPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+func name(u notify.User) string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	return strconv.FormatInt(u.ID, 10)
+}
+
+// Ask sends the request and waits for an authorized user to answer, the
+// timeout, or ctx. text is HTML. When ctx ends first, ctx's error is
+// returned and the request is marked as cancelled.
+func (a *Approver) Ask(parent context.Context, title, text string) (Decision, error) {
+	nonce := make([]byte, 6)
+	rand.Read(nonce)
+	id := hex.EncodeToString(nonce)
+	approve, deny := "approve:"+id, "deny:"+id
+
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	fallback := "denied"
+	if a.Default {
+		fallback = "approved"
+	}
+	header := "<b>" + html.EscapeString(title) + "</b>"
+	if a.Node != "" {
+		header += " on " + html.EscapeString(a.Node)
+	}
+	body := fmt.Sprintf("%s\n\n%s\n\nWithout an answer within %s this is %s.", header, text, timeout, fallback)
+	msgID, err := a.Bot.SendButtons(body, []notify.Button{{Text: "✅ Approve", Data: approve}, {Text: "❌ Deny", Data: deny}})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	presses := a.wait(id)
+	defer a.done(id)
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	for {
+		var q *notify.CallbackQuery
+		select {
+		case <-ctx.Done():
+		case q = <-presses:
+		}
+		if q == nil {
+			break
+		}
+		if q.Message != nil && strconv.FormatInt(q.Message.Chat.ID, 10) != a.ChatID {
+			continue
+		}
+		if !a.authorized(q.From) {
+			a.Bot.AnswerCallback(q.ID, "You are not allowed to approve this")
+			continue
+		}
+		d := Decision{Approved: q.Data == approve, By: name(q.From)}
+		verdict := "Denied"
+		if d.Approved {
+			verdict = "Approved"
+		}
+		a.Bot.AnswerCallback(q.ID, verdict)
+		a.Bot.EditHTML(msgID, fmt.Sprintf("%s\n\n%s\n\n<b>%s</b> by %s", header, text, verdict, html.EscapeString(d.By)))
+		return d, nil
+	}
+
+	if err := parent.Err(); err != nil {
+		a.Bot.EditHTML(msgID, fmt.Sprintf("%s\n\n%s\n\n<b>Cancelled</b>", header, text))
+		return Decision{}, err
+	}
+	a.Bot.EditHTML(msgID, fmt.Sprintf("%s\n\n%s\n\n<b>No answer; %s</b>", header, text, fallback))
+	return Decision{Approved: a.Default, TimedOut: true}, nil
+}
+
+// wait registers a request and starts the poller if it isn't running
+func (a *Approver) wait(id string) <-chan *notify.CallbackQuery {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.waiting == nil {
+		a.waiting = map[string]chan *notify.CallbackQuery{}
+	}
+	ch := make(chan *notify.CallbackQuery, 8)
+	a.waiting[id] = ch
+	if !a.polling {
+		a.polling = true
+		go a.poll()
+	}
+	return ch
+}
+
+// done unregisters a request, stopping the poll when it was the last
+func (a *Approver) done(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.waiting, id)
+	if len(a.waiting) == 0 && a.stopPoll != nil {
+		a.stopPoll()
+	}
+}
+
+// poll reads updates while requests wait and hands each press to the
+// request whose nonce it carries
+func (a *Approver) poll() {
+	for {
+		a.mu.Lock()
+		if len(a.waiting) == 0 {
+			a.polling = false
+			a.stopPoll = nil
+			a.mu.Unlock()
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		a.stopPoll = cancel
+		offset := a.offset
+		a.mu.Unlock()
+
+		updates, err := a.Bot.GetUpdates(ctx, offset, pollWait)
+		if err != nil && ctx.Err() == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+		cancel()
+
+		a.mu.Lock()
+		for _, u := range updates {
+			a.offset = u.ID + 1
+			q := u.CallbackQuery
+			if q == nil {
+				continue
+			}
+			_, id, _ := strings.Cut(q.Data, ":")
+			if ch, ok := a.waiting[id]; ok && (q.Data == "approve:"+id || q.Data == "deny:"+id) {
+				select {
+				case ch <- q:
+				default:
+				}
+			}
+		}
+		a.mu.Unlock()
+	}
+}
@@ -0,0 +1,204 @@
+package approval
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/notify"
+)
+
+// fakeBot answers the first getUpdates with presses of the sent buttons
+type fakeBot struct {
+	presses  func(approve, deny string) []notify.Update
+	buttons  []notify.Button
+	edited   string
+	answered []string
+	polled   int
+}
+
+func (b *fakeBot) SendButtons(text string, buttons []notify.Button) (int, error) {
+	b.buttons = buttons
+	return 5, nil
+}
+
+func (b *fakeBot) EditHTML(id int, text string) error {
+	b.edited = text
+	return nil
+}
+
+func (b *fakeBot) AnswerCallback(id, text string) error {
+	b.answered = append(b.answered, text)
+	return nil
+}
+
+func (b *fakeBot) GetUpdates(ctx context.Context, offset int, wait time.Duration) ([]notify.Update, error) {
+	b.polled++
+	if b.polled == 1 && b.presses != nil {
+		return b.presses(b.buttons[0].Data, b.buttons[1].Data), nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func press(updateID int, chat int64, from notify.User, data string) notify.Update {
+	msg := &notify.Message{ID: 5}
+	msg.Chat.ID = chat
+	return notify.Update{ID: updateID, CallbackQuery: &notify.CallbackQuery{ID: "q", From: from, Message: msg, Data: data}}
+}
+
+func TestAskApproved(t *testing.T) {
+	bot := &fakeBot{presses: func(approve, deny string) []notify.Update {
+		return []notify.Update{
+			press(1, 42, notify.User{ID: 7, Username: "mallory"}, approve),
+			press(2, 99, notify.User{ID: 1}, deny),
+			press(3, 42, notify.User{ID: 1, Username: "Alice"}, "approve:stale"),
+			press(4, 42, notify.User{ID: 1, Username: "Alice"}, approve),
+		}
+	}}
+	a := &Approver{Bot: bot, ChatID: "42", Users: []string{"@alice", "12345"}, Timeout: time.Minute, Node: "gpu-a"}
+	d, err := a.Ask(context.Background(), "Switch swarms?", "From the small swarm to the big swarm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Approved || d.By != "@Alice" || d.TimedOut {
+		t.Errorf("Decision = %+v", d)
+	}
+	if len(bot.answered) != 2 || !strings.Contains(bot.answered[0], "not allowed") {
+		t.Errorf("answered = %v", bot.answered)
+	}
+	if !strings.Contains(bot.edited, "<b>Approved</b> by @Alice") || !strings.Contains(bot.edited, "on gpu-a") {
+		t.Errorf("edited = %q", bot.edited)
+	}
+}
+
+func TestAskDenied(t *testing.T) {
+	bot := &fakeBot{presses: func(approve, deny string) []notify.Update {
+		return []notify.Update{press(1, 42, notify.User{ID: 12345}, deny)}
+	}}
+	a := &Approver{Bot: bot, ChatID: "42", Users: []string{"12345"}, Default: true}
+	d, err := a.Ask(context.Background(), "Reinstall?", "x")
+	if err != nil || d.Approved || d.By != "12345" {
+		t.Errorf("Ask() = %+v, %v", d, err)
+	}
+}
+
+func TestAskTimeout(t *testing.T) {
+	for _, def := range []bool{false, true} {
+		bot := &fakeBot{}
+		a := &Approver{Bot: bot, ChatID: "42", Users: []string{"1"}, Timeout: 10 * time.Millisecond, Default: def}
+		d, err := a.Ask(context.Background(), "Reinstall?", "x")
+		if err != nil || !d.TimedOut || d.Approved != def {
+			t.Errorf("Ask() with default %v = %+v, %v", def, d, err)
+		}
+		if !strings.Contains(bot.edited, "No answer") {
+			t.Errorf("edited = %q", bot.edited)
+		}
+	}
+}
+
+func TestAskCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bot := &fakeBot{}
+	a := &Approver{Bot: bot, ChatID: "42", Users: []string{"1"}, Default: true}
+	if d, err := a.Ask(ctx, "Reinstall?", "x"); err == nil || d.Approved {
+		t.Errorf("Ask() after cancel = %+v, %v", d, err)
+	}
+	if !strings.Contains(bot.edited, "Cancelled") {
+		t.Errorf("edited = %q", bot.edited)
+	}
+}
+
+// sharedBot answers presses for every request sent so far and records
+// whether getUpdates was ever called while another call was running
+type sharedBot struct {
+	mu         sync.Mutex
+	sent       [][]notify.Button
+	polling    bool
+	concurrent bool
+}
+
+func (b *sharedBot) SendButtons(text string, buttons []notify.Button) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = append(b.sent, buttons)
+	return len(b.sent), nil
+}
+
+func (b *sharedBot) EditHTML(id int, text string) error   { return nil }
+func (b *sharedBot) AnswerCallback(id, text string) error { return nil }
+
+func (b *sharedBot) GetUpdates(ctx context.Context, offset int, wait time.Duration) ([]notify.Update, error) {
+	b.mu.Lock()
+	if b.polling {
+		b.concurrent = true
+	}
+	b.polling = true
+	sent := len(b.sent)
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.polling = false
+		b.mu.Unlock()
+	}()
+
+	if sent < 2 || offset > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+			return nil, nil
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// The second request is denied, then the first approved
+	return []notify.Update{
+		press(1, 42, notify.User{ID: 1}, b.sent[1][1].Data),
+		press(2, 42, notify.User{ID: 1}, b.sent[0][0].Data),
+	}, nil
+}
+
+func TestAskConcurrent(t *testing.T) {
+	bot := &sharedBot{}
+	a := &Approver{Bot: bot, ChatID: "42", Users: []string{"1"}, Timeout: time.Minute}
+	first := make(chan Decision)
+	go func() {
+		d, _ := a.Ask(context.Background(), "Switch swarms?", "x")
+		first <- d
+	}()
+	for {
+		bot.mu.Lock()
+		n := len(bot.sent)
+		bot.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	second, err := a.Ask(context.Background(), "Reinstall?", "y")
+	if err != nil || second.Approved {
+		t.Errorf("second Ask() = %+v, %v, want denied", second, err)
+	}
+	if d := <-first; !d.Approved {
+		t.Errorf("first Ask() = %+v, want approved", d)
+	}
+	if bot.concurrent {
+		t.Error("getUpdates was called concurrently")
+	}
+}
+
+func TestParseUsers(t *testing.T) {
+	users, err := ParseUsers([]string{"@alice, 12345", "@bob"})
+	if err != nil || strings.Join(users, " ") != "@alice 12345 @bob" {
+		t.Errorf("ParseUsers() = %v, %v", users, err)
+	}
+	for _, bad := range []string{"alice", "@"} {
+		if _, err := ParseUsers([]string{bad}); err == nil {
+			t.Errorf("ParseUsers(%q) succeeded", bad)
+		}
+	}
+}
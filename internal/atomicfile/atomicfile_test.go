@@ -0,0 +1,32 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "pin.json")
+	if err := WriteJSON(path, map[string]int{"index": 1}, 0o666); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "{\n  \"index\": 1\n}\n" {
+		t.Errorf("file = %q, %v", data, err)
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0o666 {
+		t.Errorf("mode = %v, want 0666 despite the umask", info.Mode().Perm())
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind: %v", err)
+	}
+
+	if err := WriteJSON(path, func() {}, 0o644); err == nil {
+		t.Error("WriteJSON() of a func succeeded")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{\n  \"index\": 1\n}\n" {
+		t.Errorf("failed write changed the file: %q", data)
+	}
+}
@@ -0,0 +1,159 @@
+// Package bench runs a short, standardized generation and training
+// benchmark of the swarm model and keeps the results in the state
+// directory, so hosts can be compared before joining the big swarm.
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Script is the Python benchmark run inside the trainer's virtual environment
+//
+//go:embed bench.py
+var Script []byte
+
+// FileName is the benchmark history inside the state directory
+const FileName = "bench.jsonl"
+
+// resultPrefix marks the script's result line
+const resultPrefix = "GSWARM_BENCH "
+
+// Defaults for the benchmark run
+const (
+	DefaultTokens = 128
+	DefaultSteps  = 5
+)
+
+// Result is one benchmark run
+type Result struct {
+	Time         time.Time `json:"time"`
+	ParamB       string    `json:"param_b,omitempty"`
+	Model        string    `json:"model"`
+	Device       string    `json:"device"`
+	GPU          string    `json:"gpu,omitempty"`
+	Tokens       int       `json:"tokens"`
+	TokensPerSec float64   `json:"tokens_per_sec"`
+	Steps        int       `json:"steps,omitempty"`
+	StepSeconds  float64   `json:"step_seconds,omitempty"`
+	StepError    string    `json:"step_error,omitempty"`
+	PeakVRAMGB   float64   `json:"peak_vram_gb,omitempty"`
+}
+
+// Model returns the model_name_or_path of a trainer YAML config
+func Model(config []byte) (string, error) {
+	var doc struct {
+		Model string `yaml:"model_name_or_path"`
+	}
+	if err := yaml.Unmarshal(config, &doc); err != nil {
+		return "", err
+	}
+	model := doc.Model
+	if model == "" {
+		return "", errors.New("trainer config has no model_name_or_path")
+	}
+	return model, nil
+}
+
+// Parse extracts the result from the benchmark script's output
+func Parse(output []byte) (Result, error) {
+	var r Result
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, resultPrefix) {
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, resultPrefix)), &r); err != nil {
+				return Result{}, fmt.Errorf("invalid benchmark result: %w", err)
+			}
+			return r, nil
+		}
+	}
+	return Result{}, errors.New("the benchmark printed no result")
+}
+
+// Save appends r to the history in stateDir
+func Save(stateDir string, r Result) error {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(stateDir, FileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// History returns the saved results, oldest first
+func History(stateDir string) ([]Result, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var r Result
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// Text renders the result as a human-readable summary
+func (r Result) Text() string {
+	var b strings.Builder
+	device := r.Device
+	if r.GPU != "" {
+		device = fmt.Sprintf("%s (%s)", r.Device, r.GPU)
+	}
+	fmt.Fprintf(&b, "Model: %s\n", r.Model)
+	fmt.Fprintf(&b, "Device: %s\n", device)
+	fmt.Fprintf(&b, "Generation: %.1f tokens/sec (%d tokens)\n", r.TokensPerSec, r.Tokens)
+	switch {
+	case r.StepError != "":
+		fmt.Fprintf(&b, "Training step: not measured (%s)\n", r.StepError)
+	case r.Steps > 0:
+		fmt.Fprintf(&b, "Training step: %.2fs (median of %d)\n", r.StepSeconds, r.Steps)
+	}
+	if r.PeakVRAMGB > 0 {
+		fmt.Fprintf(&b, "Peak VRAM: %.1f GB\n", r.PeakVRAMGB)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Compare describes how r compares to the previous result for the same
+// model, or "" if there is none
+func Compare(r Result, history []Result) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		prev := history[i]
+		if prev.Model != r.Model || prev.TokensPerSec <= 0 {
+			continue
+		}
+		change := (r.TokensPerSec/prev.TokensPerSec - 1) * 100
+		return fmt.Sprintf("%+.0f%% tokens/sec vs. %s on %s", change, prev.Time.Format("2006-01-02"), prev.Device)
+	}
+	return ""
+}
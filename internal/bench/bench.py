@@ -0,0 +1,84 @@
+"""Standardized gswarm benchmark: greedy generation and a few training steps
+of the swarm model on this machine. Prints one GSWARM_BENCH JSON line."""
+
+import argparse
+import json
+import time
+
+import torch
+from transformers import AutoModelForCausalLM, AutoTokenizer
+
+PROMPT = (
+    "Natalia sold clips to 48 of her friends in April, and then she sold half "
+    "as many clips in May. How many clips did Natalia sell altogether in April "
+    "and May? Think step by step."
+)
+
+
+def main():
+    parser = argparse.ArgumentParser()
+    parser.add_argument("--model", required=True)
+    parser.add_argument("--tokens", type=int, default=128)
+    parser.add_argument("--steps", type=int, default=5)
+    args = parser.parse_args()
+
+    if torch.cuda.is_available():
+        device, dtype = "cuda", torch.bfloat16
+    elif getattr(torch.backends, "mps", None) and torch.backends.mps.is_available():
+        device, dtype = "mps", torch.float32
+    else:
+        device, dtype = "cpu", torch.float32
+
+    def sync():
+        if device == "cuda":
+            torch.cuda.synchronize()
+
+    tokenizer = AutoTokenizer.from_pretrained(args.model)
+    model = AutoModelForCausalLM.from_pretrained(args.model, torch_dtype=dtype).to(device)
+    inputs = tokenizer(PROMPT, return_tensors="pt").to(device)
+
+    # Warm up kernels and caches before timing
+    model.generate(**inputs, max_new_tokens=8, do_sample=False)
+    sync()
+    start = time.perf_counter()
+    output = model.generate(
+        **inputs, max_new_tokens=args.tokens, min_new_tokens=args.tokens, do_sample=False
+    )
+    sync()
+    gen_seconds = time.perf_counter() - start
+    generated = output.shape[1] - inputs["input_ids"].shape[1]
+
+    result = {
+        "model": args.model,
+        "device": device,
+        "gpu": torch.cuda.get_device_name(0) if device == "cuda" else "",
+        "tokens": int(generated),
+        "tokens_per_sec": generated / gen_seconds,
+    }
+
+    # Quantized checkpoints can't be fine-tuned directly; report generation only
+    try:
+        model.train()
+        optimizer = torch.optim.AdamW(model.parameters(), lr=1e-6)
+        times = []
+        for _ in range(args.steps):
+            sync()
+            start = time.perf_counter()
+            loss = model(input_ids=output, labels=output).loss
+            loss.backward()
+            optimizer.step()
+            optimizer.zero_grad()
+            sync()
+            times.append(time.perf_counter() - start)
+        result["steps"] = len(times)
+        result["step_seconds"] = sorted(times)[len(times) // 2]
+    except Exception as e:  # noqa: BLE001
+        result["step_error"] = str(e).splitlines()[0][:200]
+
+    if device == "cuda":
+        result["peak_vram_gb"] = torch.cuda.max_memory_allocated() / 2**30
+    print("GSWARM_BENCH " + json.dumps(result), flush=True)
+
+
+if __name__ == "__main__":
+    main()
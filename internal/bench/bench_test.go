@@ -0,0 +1,62 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModel(t *testing.T) {
+	config := `# Model arguments
+model_revision: main
+model_name_or_path: Gensyn/Qwen2.5-0.5B-Instruct
+torch_dtype: float32
+`
+	got, err := Model([]byte(config))
+	if err != nil || got != "Gensyn/Qwen2.5-0.5B-Instruct" {
+		t.Errorf("Model() = %q, %v", got, err)
+	}
+	if _, err := Model([]byte("max_steps: 20\n")); err == nil {
+		t.Error("Model() without model_name_or_path error = nil, want an error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	output := `Loading checkpoint shards: 100%
+GSWARM_BENCH {"model": "m", "device": "cuda", "gpu": "NVIDIA RTX 4090", "tokens": 128, "tokens_per_sec": 42.5, "steps": 5, "step_seconds": 0.8, "peak_vram_gb": 3.2}
+`
+	r, err := Parse([]byte(output))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if r.TokensPerSec != 42.5 || r.Steps != 5 || r.GPU != "NVIDIA RTX 4090" {
+		t.Errorf("Parse() = %+v", r)
+	}
+	for _, want := range []string{"42.5 tokens/sec", "0.80s (median of 5)", "3.2 GB", "cuda (NVIDIA RTX 4090)"} {
+		if !strings.Contains(r.Text(), want) {
+			t.Errorf("Text() = %q, want it to contain %q", r.Text(), want)
+		}
+	}
+
+	if _, err := Parse([]byte("Traceback (most recent call last):\n")); err == nil {
+		t.Error("Parse() without a result error = nil, want an error")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	first := Result{Time: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Model: "m", Device: "cuda", TokensPerSec: 40}
+	if err := Save(dir, first); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	history, err := History(dir)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("History() = %v, %v", history, err)
+	}
+	if got := Compare(Result{Model: "m", TokensPerSec: 50}, history); got != "+25% tokens/sec vs. 2025-07-01 on cuda" {
+		t.Errorf("Compare() = %q", got)
+	}
+	if got := Compare(Result{Model: "other", TokensPerSec: 50}, history); got != "" {
+		t.Errorf("Compare() for another model = %q, want empty", got)
+	}
+}
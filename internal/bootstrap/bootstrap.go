@@ -10,6 +10,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+
+	"github.com/Deep-Commit/gswarm/internal/console"
 )
 
 // CommandRunner is a package-level variable that can be replaced in tests
@@ -27,7 +29,7 @@ const (
 // EnsureRepo ensures we're in the correct repository
 func EnsureRepo() error {
 	if _, err := os.Stat("go.mod"); os.IsNotExist(err) {
-		fmt.Println("Not in RL-Swarm repository. Cloning...")
+		console.Infof("Not in RL-Swarm repository. Cloning...")
 
 		// Check if git is available
 		if err := checkGit(); err != nil {
@@ -37,7 +39,7 @@ func EnsureRepo() error {
 		}
 
 		cmd := CommandRunner("git", "clone", "https://github.com/gensyn-ai/rl-swarm.git")
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = console.Out()
 		cmd.Stderr = os.Stderr
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("failed to clone rl-swarm: %w", err)
@@ -51,7 +53,7 @@ func EnsureRepo() error {
 		if err := os.Chdir("rl-swarm"); err != nil {
 			return fmt.Errorf("failed to change to rl-swarm directory: %w", err)
 		}
-		fmt.Println("Successfully cloned and entered RL-Swarm repository")
+		console.Successf("Successfully cloned and entered RL-Swarm repository")
 	}
 	return nil
 }
@@ -62,24 +64,28 @@ func checkGit() error {
 }
 
 func installGit() error {
-	fmt.Println("Git not found. Installing...")
+	console.Infof("Git not found. Installing...")
 
 	switch runtime.GOOS {
 	case OSDarwin:
 		cmd := CommandRunner("brew", "install", "git")
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = console.Out()
 		cmd.Stderr = os.Stderr
 		return cmd.Run()
 	case OSLinux:
-		cmd := CommandRunner("sudo", "apt-get", "update")
-		cmd.Stdout = os.Stdout
+		priv := DetectPrivilege()
+		if priv == Rootless {
+			return ErrGitRootless
+		}
+		cmd := priv.Command("apt-get", "update")
+		cmd.Stdout = console.Out()
 		cmd.Stderr = os.Stderr
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("failed to update package list: %w", err)
 		}
 
-		cmd = CommandRunner("sudo", "apt-get", "install", "-y", "git")
-		cmd.Stdout = os.Stdout
+		cmd = priv.Command("apt-get", "install", "-y", "git")
+		cmd.Stdout = console.Out()
 		cmd.Stderr = os.Stderr
 		return cmd.Run()
 	default:
@@ -93,10 +99,10 @@ func EnsureVenv() (string, error) {
 
 	// Check if venv already exists
 	if _, err := os.Stat(venvPath); os.IsNotExist(err) {
-		fmt.Printf("Creating virtual environment: %s\n", venvPath)
+		console.Infof("Creating virtual environment: %s", venvPath)
 
 		cmd := CommandRunner("python3", "-m", "venv", venvPath)
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = console.Out()
 		cmd.Stderr = os.Stderr
 		if err := cmd.Run(); err != nil {
 			return "", fmt.Errorf("failed to create virtual environment: %w", err)
@@ -121,9 +127,9 @@ func EnsureVenv() (string, error) {
 	}
 
 	// Upgrade pip in the virtual environment
-	fmt.Println("Upgrading pip in virtual environment...")
+	console.Infof("Upgrading pip in virtual environment...")
 	cmd := CommandRunner(venvPython, "-m", "pip", "install", "--upgrade", "pip")
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = console.Out()
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
 		return "", fmt.Errorf("failed to upgrade pip: %w", err)
@@ -175,13 +181,13 @@ func EnsureNodeAndNpm() error {
 	npmErr := checkNpm()
 
 	if nodeErr != nil || npmErr != nil {
-		fmt.Println("Node.js or npm not found. Installing via NVM...")
+		console.Infof("Node.js or npm not found. Installing via NVM...")
 
 		// Install NVM if not present
 		nvmDir := filepath.Join(os.Getenv("HOME"), ".nvm")
 		if _, err := os.Stat(nvmDir); os.IsNotExist(err) {
 			cmd := CommandRunner("bash", "-c", "curl -o- https://raw.githubusercontent.com/nvm-sh/nvm/v0.39.7/install.sh | bash")
-			cmd.Stdout = os.Stdout
+			cmd.Stdout = console.Out()
 			cmd.Stderr = os.Stderr
 			if err := cmd.Run(); err != nil {
 				return fmt.Errorf("failed to install NVM: %w", err)
@@ -195,11 +201,14 @@ func EnsureNodeAndNpm() error {
 			nvm use --lts
 		`
 		cmd := CommandRunner("bash", "-lc", install)
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = console.Out()
 		cmd.Stderr = os.Stderr
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("failed to install node.js via NVM: %w", err)
 		}
+		if err := UseNVMNode("--lts"); err != nil {
+			return err
+		}
 
 		// Verify installation
 		if err := checkNodeJS(); err != nil {
@@ -230,28 +239,39 @@ func CheckYarn() error {
 }
 
 func InstallYarn() error {
-	fmt.Println("Yarn not found. Installing Yarn...")
+	console.Infof("Yarn not found. Installing Yarn...")
+
+	priv := DetectPrivilege()
+	if priv == Rootless {
+		if err := InstallYarnRootless(); err != nil {
+			return err
+		}
+		if err := CheckYarn(); err != nil {
+			return fmt.Errorf("yarn installation verification failed: %w", err)
+		}
+		return nil
+	}
 
 	// Try npm install first (with proper NVM sourcing and timeout)
 	cmd := CommandRunner("bash", "-lc", "source ~/.nvm/nvm.sh && npm install -g yarn --silent")
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = console.Out()
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
-		fmt.Printf("npm install failed: %v, trying system package managers...\n", err)
+		console.Infof("npm install failed: %v, trying system package managers...", err)
 
 		// Fallback to system package manager based on OS
 		switch runtime.GOOS {
 		case "darwin":
 			// macOS - use Homebrew
-			fmt.Println("Trying Homebrew installation...")
+			console.Infof("Trying Homebrew installation...")
 			cmd = CommandRunner("bash", "-lc", "brew install yarn --quiet")
-			cmd.Stdout = os.Stdout
+			cmd.Stdout = console.Out()
 			cmd.Stderr = os.Stderr
 			if err := cmd.Run(); err != nil {
 				// Try corepack as last resort (available in Node.js 16.10+)
-				fmt.Println("Homebrew failed, trying corepack...")
+				console.Infof("Homebrew failed, trying corepack...")
 				cmd = CommandRunner("bash", "-lc", "source ~/.nvm/nvm.sh && corepack enable")
-				cmd.Stdout = os.Stdout
+				cmd.Stdout = console.Out()
 				cmd.Stderr = os.Stderr
 				if err := cmd.Run(); err != nil {
 					return fmt.Errorf("failed to install yarn via Homebrew or corepack: %w", err)
@@ -259,20 +279,20 @@ func InstallYarn() error {
 			}
 		case "linux":
 			// Linux - use modern apt approach
-			fmt.Println("Trying apt installation...")
-			installScript := `
+			console.Infof("Trying apt installation...")
+			installScript := strings.ReplaceAll(`
 				set -e
 				echo "Adding Yarn repository..."
-				curl -fsSL https://dl.yarnpkg.com/debian/pubkey.gpg | sudo gpg --dearmor -o /usr/share/keyrings/yarnkey.gpg
+				curl -fsSL https://dl.yarnpkg.com/debian/pubkey.gpg | SUDO gpg --dearmor -o /usr/share/keyrings/yarnkey.gpg
 				echo "deb [signed-by=/usr/share/keyrings/yarnkey.gpg] https://dl.yarnpkg.com/debian/ stable main" | \
-					sudo tee /etc/apt/sources.list.d/yarn.list
+					SUDO tee /etc/apt/sources.list.d/yarn.list
 				echo "Updating package list..."
-				sudo apt update -qq
+				SUDO apt update -qq
 				echo "Installing Yarn..."
-				sudo apt install -y yarn -qq
-			`
+				SUDO apt install -y yarn -qq
+			`, "SUDO ", priv.Prefix())
 			cmd = CommandRunner("bash", "-c", installScript)
-			cmd.Stdout = os.Stdout
+			cmd.Stdout = console.Out()
 			cmd.Stderr = os.Stderr
 			if err := cmd.Run(); err != nil {
 				return fmt.Errorf("failed to install yarn via apt: %w", err)
@@ -298,27 +318,27 @@ func Env() (string, error) {
 	}
 
 	// Check Python version
-	fmt.Println("Checking Python version...")
+	console.Infof("Checking Python version...")
 	if err := CheckPythonVersion(); err != nil {
 		return "", fmt.Errorf("python version check failed: %w", err)
 	}
-	fmt.Println("Python version OK")
+	console.Successf("Python version OK")
 
 	// Ensure Node.js and npm are available
-	fmt.Println("Checking Node.js and npm...")
+	console.Infof("Checking Node.js and npm...")
 	if err := EnsureNodeAndNpm(); err != nil {
 		return "", fmt.Errorf("node.js/npm setup failed: %w", err)
 	}
-	fmt.Println("Node.js and npm OK")
+	console.Successf("Node.js and npm OK")
 
 	// Check for Yarn and install if missing
-	fmt.Println("Checking for Yarn...")
+	console.Infof("Checking for Yarn...")
 	if err := CheckYarn(); err != nil {
 		if err := InstallYarn(); err != nil {
 			return "", fmt.Errorf("yarn installation failed: %w", err)
 		}
 	}
-	fmt.Println("Yarn is available.")
+	console.Successf("Yarn is available.")
 
 	// Ensure virtual environment
 	venvPath, err := EnsureVenv()
@@ -0,0 +1,106 @@
+package bootstrap
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultEntrypoint is the trainer module of the rl-swarm releases gswarm
+// was written against
+const DefaultEntrypoint = "hivemind_exp.gsm8k.train_single_gpu"
+
+// knownEntrypoints are trainer modules used by rl-swarm releases, newest
+// layout last
+var knownEntrypoints = []string{
+	DefaultEntrypoint,
+	"hivemind_exp.train_single_gpu",
+	"hivemind_exp.runner.train_single_gpu",
+}
+
+// entrypointFile is the trainer script name looked for when none of the
+// known modules exist
+const entrypointFile = "train_single_gpu.py"
+
+// HasModule reports whether a Python module exists in the checkout, as a
+// .py file or a package with __main__.py
+func HasModule(dir, module string) bool {
+	path := filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(module, ".", "/")))
+	for _, p := range []string{path + ".py", filepath.Join(path, "__main__.py")} {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectEntrypoint finds the trainer module in an rl-swarm checkout: the
+// first known module that exists, otherwise the shallowest
+// train_single_gpu.py anywhere in the checkout
+func DetectEntrypoint(dir string) (string, error) {
+	for _, module := range knownEntrypoints {
+		if HasModule(dir, module) {
+			return module, nil
+		}
+	}
+
+	var found []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules" || d.Name() == "__pycache__") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == entrypointFile {
+			rel, err := filepath.Rel(dir, path)
+			if err == nil {
+				found = append(found, strings.ReplaceAll(strings.TrimSuffix(filepath.ToSlash(rel), ".py"), "/", "."))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "", errors.New("no trainer entrypoint found in the rl-swarm checkout; set --train-entrypoint")
+	}
+	sort.Slice(found, func(i, j int) bool {
+		di, dj := strings.Count(found[i], "."), strings.Count(found[j], ".")
+		if di != dj {
+			return di < dj
+		}
+		return found[i] < found[j]
+	})
+	return found[0], nil
+}
+
+// HasTrainerOption reports whether the Python package holding entrypoint
+// mentions option, such as a dataclass field or argparse flag the trainer
+// accepts. Upstream adds options between releases without announcing them,
+// so this is a best guess rather than a guarantee.
+func HasTrainerOption(dir, entrypoint, option string) bool {
+	pkg, _, _ := strings.Cut(entrypoint, ".")
+	pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(option) + `\b`)
+	found := false
+	filepath.WalkDir(filepath.Join(dir, pkg), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || found {
+			return nil
+		}
+		if d.IsDir() && (strings.HasPrefix(d.Name(), ".") || d.Name() == "__pycache__") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".py") {
+			if data, err := os.ReadFile(path); err == nil && pattern.Match(data) {
+				found = true
+			}
+		}
+		return nil
+	})
+	return found
+}
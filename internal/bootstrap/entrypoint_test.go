@@ -0,0 +1,60 @@
+package bootstrap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectEntrypoint(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   []string
+		want    string
+		wantErr bool
+	}{
+		{"current layout", []string{"hivemind_exp/gsm8k/train_single_gpu.py", "hivemind_exp/train_single_gpu.py"}, DefaultEntrypoint, false},
+		{"known rename", []string{"hivemind_exp/train_single_gpu.py"}, "hivemind_exp.train_single_gpu", false},
+		{"package main", []string{"hivemind_exp/runner/train_single_gpu/__main__.py"}, "hivemind_exp.runner.train_single_gpu", false},
+		{"unknown layout", []string{"swarm/games/math/train_single_gpu.py", "swarm/trainers/train_single_gpu.py", ".venv/lib/train_single_gpu.py"}, "swarm.trainers.train_single_gpu", false},
+		{"missing", []string{"hivemind_exp/configs/x.yaml"}, "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range c.files {
+				path := filepath.Join(dir, f)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := DetectEntrypoint(dir)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("DetectEntrypoint() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("DetectEntrypoint() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestHasTrainerOption(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hivemind_exp", "runner", "grpo_runner.py")
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	os.WriteFile(path, []byte("@dataclass\nclass GRPOArguments:\n    start_round: int = 0\n"), 0o644)
+
+	if !HasTrainerOption(dir, DefaultEntrypoint, "start_round") {
+		t.Error("HasTrainerOption(start_round) = false, want true")
+	}
+	if HasTrainerOption(dir, DefaultEntrypoint, "start_stage") {
+		t.Error("HasTrainerOption(start_stage) = true, want false")
+	}
+	if HasTrainerOption(dir, "other.train", "start_round") {
+		t.Error("HasTrainerOption() found an option outside the entrypoint's package")
+	}
+}
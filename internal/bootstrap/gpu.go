@@ -0,0 +1,188 @@
+package bootstrap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GPUInfo describes the NVIDIA GPU and driver detected on the host
+type GPUInfo struct {
+	Name          string
+	DriverVersion string
+	// CUDAVersion is the highest CUDA runtime the driver supports
+	CUDAVersion string
+}
+
+// minDriverForCUDA maps a CUDA runtime version to the minimum Linux driver
+// release that ships with it
+var minDriverForCUDA = map[string]int{
+	"11.7": 515,
+	"11.8": 520,
+	"12.1": 530,
+	"12.4": 550,
+	"12.6": 560,
+	"12.8": 570,
+}
+
+// defaultCUDAForTorch maps a torch minor release to the CUDA runtime of its
+// default PyPI wheel, used when the requirements file doesn't pin one
+var defaultCUDAForTorch = map[string]string{
+	"2.0": "11.7",
+	"2.1": "12.1",
+	"2.2": "12.1",
+	"2.3": "12.1",
+	"2.4": "12.1",
+	"2.5": "12.4",
+	"2.6": "12.4",
+	"2.7": "12.6",
+	"2.8": "12.8",
+}
+
+var (
+	cudaVersionRe = regexp.MustCompile(`CUDA Version:\s*([0-9]+\.[0-9]+)`)
+	torchPinRe    = regexp.MustCompile(`^torch\s*(?:==|~=|>=)\s*([0-9]+\.[0-9]+)(?:\.[0-9]+)?(?:\+cu([0-9]+))?`)
+	cudaIndexRe   = regexp.MustCompile(`/cu([0-9]{3})\b`)
+)
+
+// DetectGPU queries nvidia-smi for the GPU name, driver version and the
+// CUDA runtime version supported by the driver
+func DetectGPU() (*GPUInfo, error) {
+	output, err := CommandRunner("nvidia-smi", "--query-gpu=name,driver_version", "--format=csv,noheader").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi not available: %w", err)
+	}
+
+	line := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+	parts := strings.SplitN(line, ",", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("unable to parse nvidia-smi output: %q", line)
+	}
+	info := &GPUInfo{
+		Name:          strings.TrimSpace(parts[0]),
+		DriverVersion: strings.TrimSpace(parts[1]),
+	}
+
+	// The plain nvidia-smi banner reports the maximum supported CUDA runtime
+	if banner, err := CommandRunner("nvidia-smi").Output(); err == nil {
+		if m := cudaVersionRe.FindStringSubmatch(string(banner)); m != nil {
+			info.CUDAVersion = m[1]
+		}
+	}
+
+	return info, nil
+}
+
+// RequiredCUDA inspects a requirements file and returns the CUDA runtime
+// version the pinned torch build needs, or "" when it can't be determined
+func RequiredCUDA(requirementsFile string) (string, error) {
+	f, err := os.Open(requirementsFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to open requirements file: %w", err)
+	}
+	defer f.Close()
+
+	var indexCUDA, torchMinor, torchCUDA string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := cudaIndexRe.FindStringSubmatch(line); m != nil && strings.HasPrefix(line, "-") {
+			indexCUDA = cuTagToVersion(m[1])
+			continue
+		}
+		if m := torchPinRe.FindStringSubmatch(line); m != nil {
+			torchMinor = m[1]
+			if m[2] != "" {
+				torchCUDA = cuTagToVersion(m[2])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read requirements file: %w", err)
+	}
+
+	switch {
+	case torchCUDA != "":
+		return torchCUDA, nil
+	case indexCUDA != "":
+		return indexCUDA, nil
+	case torchMinor != "":
+		return defaultCUDAForTorch[torchMinor], nil
+	}
+	return "", nil
+}
+
+// CheckGPUDriver validates that the installed NVIDIA driver can run the
+// torch build selected by the requirements file
+func CheckGPUDriver(requirementsFile string) error {
+	required, err := RequiredCUDA(requirementsFile)
+	if err != nil {
+		return err
+	}
+	if required == "" {
+		// Nothing pinned; let pip pick a compatible build
+		return nil
+	}
+
+	gpu, err := DetectGPU()
+	if err != nil {
+		return fmt.Errorf("%s requires an NVIDIA GPU with CUDA %s support: %w (use --cpu-only to train on CPU)", requirementsFile, required, err)
+	}
+
+	if gpu.CUDAVersion != "" && compareVersions(gpu.CUDAVersion, required) >= 0 {
+		return nil
+	}
+
+	minDriver, known := minDriverForCUDA[required]
+	driverMajor, _ := strconv.Atoi(strings.SplitN(gpu.DriverVersion, ".", 2)[0])
+	if known && driverMajor >= minDriver && gpu.CUDAVersion == "" {
+		return nil
+	}
+
+	if known {
+		return fmt.Errorf("NVIDIA driver %d+ required for CUDA %s (from %s); found driver %s on %s supporting CUDA %s. Upgrade the driver or use --cpu-only",
+			minDriver, required, requirementsFile, gpu.DriverVersion, gpu.Name, orUnknown(gpu.CUDAVersion))
+	}
+	return fmt.Errorf("CUDA %s required (from %s) but driver %s on %s supports CUDA %s. Upgrade the driver or use --cpu-only",
+		required, requirementsFile, gpu.DriverVersion, gpu.Name, orUnknown(gpu.CUDAVersion))
+}
+
+// cuTagToVersion converts a wheel tag like "121" to "12.1"
+func cuTagToVersion(tag string) string {
+	if len(tag) < 2 {
+		return tag
+	}
+	return tag[:len(tag)-1] + "." + tag[len(tag)-1:]
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package bootstrap
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequiredCUDA(t *testing.T) {
+	cases := []struct {
+		name         string
+		requirements string
+		want         string
+	}{
+		{"local version tag", "torch==2.5.1+cu121\nnumpy", "12.1"},
+		{"extra index url", "--extra-index-url https://download.pytorch.org/whl/cu118\ntorch==2.4.0", "11.8"},
+		{"default wheel", "torch==2.5.1\ntransformers", "12.4"},
+		{"no torch pin", "numpy\ntransformers", ""},
+		{"unknown torch release", "torch==1.13.1", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "requirements-gpu.txt")
+			if err := os.WriteFile(path, []byte(c.requirements), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			got, err := RequiredCUDA(path)
+			if err != nil {
+				t.Fatalf("RequiredCUDA() error = %v", err)
+			}
+			if got != c.want {
+				t.Errorf("RequiredCUDA() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckGPUDriver(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		banner    string
+		noGPU     bool
+		wantErr   bool
+		errSubstr string
+	}{
+		{"compatible driver", "NVIDIA A10G, 550.54.15", "| NVIDIA-SMI 550.54.15  Driver Version: 550.54.15  CUDA Version: 12.4 |", false, false, ""},
+		{"newer driver", "NVIDIA H100, 570.86.10", "CUDA Version: 12.8", false, false, ""},
+		{"old driver", "Tesla T4, 535.104.05", "CUDA Version: 12.2", false, true, "driver 550+ required"},
+		{"no gpu", "", "", true, true, "--cpu-only"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "requirements-gpu.txt")
+			if err := os.WriteFile(path, []byte("torch==2.5.1\n"), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			origCommandRunner := CommandRunner
+			defer func() { CommandRunner = origCommandRunner }()
+			CommandRunner = func(name string, args ...string) *exec.Cmd {
+				if name != "nvidia-smi" || c.noGPU {
+					return exec.Command("false")
+				}
+				if len(args) > 0 {
+					return exec.Command("echo", c.query)
+				}
+				return exec.Command("echo", c.banner)
+			}
+
+			err := CheckGPUDriver(path)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("CheckGPUDriver() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if c.wantErr && !strings.Contains(err.Error(), c.errSubstr) {
+				t.Errorf("CheckGPUDriver() error = %q, want it to contain %q", err, c.errSubstr)
+			}
+		})
+	}
+}
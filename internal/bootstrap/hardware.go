@@ -0,0 +1,187 @@
+package bootstrap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Hardware describes the resources available for training
+type Hardware struct {
+	GPUName string
+	// VRAMMiB is the memory of the largest NVIDIA GPU, 0 when there is none
+	VRAMMiB int
+	CPUs    int
+	RAMMiB  int
+}
+
+// String summarises the hardware for logs and prompts
+func (h Hardware) String() string {
+	gpu := "no NVIDIA GPU"
+	if h.VRAMMiB > 0 {
+		gpu = fmt.Sprintf("%s (%d GiB VRAM)", h.GPUName, (h.VRAMMiB+512)/1024)
+	}
+	return fmt.Sprintf("%s, %d CPU cores, %d GiB RAM", gpu, h.CPUs, (h.RAMMiB+512)/1024)
+}
+
+// modelRequirements lists each model size, largest first, with the GPU
+// memory and system RAM needed to train it. 32B and 72B use the 4-bit configs.
+var modelRequirements = []struct {
+	ParamB     string
+	MinVRAMGiB int
+	MinRAMGiB  int
+}{
+	{"72", 80, 128},
+	{"32", 48, 64},
+	{"7", 24, 32},
+	{"1.5", 16, 16},
+	{"0.5", 8, 8},
+}
+
+// CPU training only has a 0.5B config and needs this much to keep up
+const (
+	minCPUCores  = 4
+	minCPURAMGiB = 8
+)
+
+// Recommendation is the largest model size the hardware can train
+type Recommendation struct {
+	ParamB       string
+	CPUOnly      bool
+	Requirements string
+	// Reason explains the choice, or why the hardware falls short
+	Reason string
+}
+
+// Fits reports whether paramB is no larger than the recommended size
+func (r Recommendation) Fits(paramB string) bool {
+	want, err := strconv.ParseFloat(paramB, 64)
+	if err != nil {
+		return false
+	}
+	max, _ := strconv.ParseFloat(r.ParamB, 64)
+	return want <= max
+}
+
+// DetectHardware inspects the GPU, CPU and RAM. Anything that can't be
+// detected is left at zero.
+func DetectHardware() Hardware {
+	hw := Hardware{CPUs: runtime.NumCPU()}
+	if name, vram, err := detectVRAM(); err == nil {
+		hw.GPUName, hw.VRAMMiB = name, vram
+	}
+	hw.RAMMiB = detectRAM()
+	return hw
+}
+
+// RecommendModelSize picks the largest model size and the matching
+// requirements file for hw. GPUs too small for 0.5B fall back to CPU.
+func RecommendModelSize(hw Hardware, cpuOnly bool) Recommendation {
+	if !cpuOnly && hw.VRAMMiB > 0 {
+		for _, m := range modelRequirements {
+			if atLeast(hw.VRAMMiB, m.MinVRAMGiB) && (hw.RAMMiB == 0 || atLeast(hw.RAMMiB, m.MinRAMGiB)) {
+				return Recommendation{
+					ParamB:       m.ParamB,
+					Requirements: "requirements-gpu.txt",
+					Reason:       fmt.Sprintf("%sB needs %d GiB VRAM and %d GiB RAM", m.ParamB, m.MinVRAMGiB, m.MinRAMGiB),
+				}
+			}
+		}
+	}
+
+	rec := Recommendation{ParamB: "0.5", CPUOnly: true, Requirements: "requirements-cpu.txt", Reason: "CPU training only supports 0.5B"}
+	switch {
+	case !cpuOnly && hw.VRAMMiB > 0:
+		rec.Reason = fmt.Sprintf("%s has less than the %d GiB VRAM needed for 0.5B; training on CPU", hw.GPUName, modelRequirements[len(modelRequirements)-1].MinVRAMGiB)
+	case hw.CPUs > 0 && hw.CPUs < minCPUCores:
+		rec.Reason = fmt.Sprintf("only %d CPU cores; %d or more are recommended for CPU training", hw.CPUs, minCPUCores)
+	case hw.RAMMiB > 0 && !atLeast(hw.RAMMiB, minCPURAMGiB):
+		rec.Reason = fmt.Sprintf("only %d GiB RAM; %d GiB or more is recommended for CPU training", hw.RAMMiB/1024, minCPURAMGiB)
+	}
+	return rec
+}
+
+// atLeast reports whether mib covers gib, allowing 10% for the memory that
+// drivers and the kernel reserve, so a 24 GB card counts as 24 GiB
+func atLeast(mib, gib int) bool {
+	return mib >= gib*1024*9/10
+}
+
+// detectVRAM returns the name and memory of the NVIDIA GPU with the most VRAM
+// CountGPUs returns the number of NVIDIA GPUs nvidia-smi lists
+func CountGPUs() (int, error) {
+	output, err := CommandRunner("nvidia-smi", "--query-gpu=index", "--format=csv,noheader").Output()
+	if err != nil {
+		return 0, fmt.Errorf("nvidia-smi not available: %w", err)
+	}
+	var n int
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) != "" {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func detectVRAM() (string, int, error) {
+	output, err := CommandRunner("nvidia-smi", "--query-gpu=name,memory.total", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("nvidia-smi not available: %w", err)
+	}
+
+	var name string
+	var best int
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.Split(line, ",")
+		if len(parts) != 2 {
+			continue
+		}
+		mib, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			continue
+		}
+		if mib > best {
+			name, best = strings.TrimSpace(parts[0]), mib
+		}
+	}
+	if best == 0 {
+		return "", 0, fmt.Errorf("unable to parse nvidia-smi output: %q", output)
+	}
+	return name, best, nil
+}
+
+// detectRAM returns the total system memory in MiB, or 0 if unknown
+func detectRAM() int {
+	if runtime.GOOS == OSDarwin {
+		output, err := CommandRunner("sysctl", "-n", "hw.memsize").Output()
+		if err != nil {
+			return 0
+		}
+		bytes, _ := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+		return int(bytes >> 20)
+	}
+
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	return parseMemInfo(f)
+}
+
+// parseMemInfo reads MemTotal from /proc/meminfo, in MiB
+func parseMemInfo(r io.Reader) int {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.Atoi(fields[1])
+			return kb / 1024
+		}
+	}
+	return 0
+}
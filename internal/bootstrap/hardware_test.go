@@ -0,0 +1,96 @@
+package bootstrap
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRecommendModelSize(t *testing.T) {
+	cases := []struct {
+		name     string
+		hw       Hardware
+		cpuOnly  bool
+		want     string
+		wantCPU  bool
+		wantNote string
+	}{
+		{"8 GiB card", Hardware{GPUName: "RTX 3070", VRAMMiB: 8192, CPUs: 8, RAMMiB: 32768}, false, "0.5", false, ""},
+		{"24 GiB card", Hardware{GPUName: "RTX 4090", VRAMMiB: 24564, CPUs: 16, RAMMiB: 65536}, false, "7", false, ""},
+		{"24 GiB card, little RAM", Hardware{GPUName: "RTX 4090", VRAMMiB: 24564, CPUs: 16, RAMMiB: 16384}, false, "1.5", false, ""},
+		{"80 GiB card", Hardware{GPUName: "H100", VRAMMiB: 81559, CPUs: 32, RAMMiB: 262144}, false, "72", false, ""},
+		{"RAM unknown", Hardware{GPUName: "A6000", VRAMMiB: 49140}, false, "32", false, ""},
+		{"4 GiB card", Hardware{GPUName: "GTX 1650", VRAMMiB: 4096, CPUs: 8, RAMMiB: 16384}, false, "0.5", true, "GTX 1650"},
+		{"forced CPU", Hardware{GPUName: "H100", VRAMMiB: 81559, CPUs: 32, RAMMiB: 262144}, true, "0.5", true, ""},
+		{"two cores", Hardware{CPUs: 2, RAMMiB: 16384}, false, "0.5", true, "2 CPU cores"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := RecommendModelSize(c.hw, c.cpuOnly)
+			if got.ParamB != c.want || got.CPUOnly != c.wantCPU {
+				t.Errorf("RecommendModelSize() = %sB cpu=%v, want %sB cpu=%v", got.ParamB, got.CPUOnly, c.want, c.wantCPU)
+			}
+			wantReqs := "requirements-gpu.txt"
+			if c.wantCPU {
+				wantReqs = "requirements-cpu.txt"
+			}
+			if got.Requirements != wantReqs {
+				t.Errorf("Requirements = %s, want %s", got.Requirements, wantReqs)
+			}
+			if !strings.Contains(got.Reason, c.wantNote) {
+				t.Errorf("Reason = %q, want it to mention %q", got.Reason, c.wantNote)
+			}
+		})
+	}
+}
+
+func TestRecommendation_Fits(t *testing.T) {
+	r := Recommendation{ParamB: "7"}
+	for paramB, want := range map[string]bool{"0.5": true, "7": true, "32": false, "72": false, "bogus": false} {
+		if got := r.Fits(paramB); got != want {
+			t.Errorf("Fits(%s) = %v, want %v", paramB, got, want)
+		}
+	}
+}
+
+func TestDetectVRAM(t *testing.T) {
+	origCommandRunner := CommandRunner
+	defer func() { CommandRunner = origCommandRunner }()
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("printf", "NVIDIA A10G, 23028\nNVIDIA A100-SXM4-80GB, 81920\n")
+	}
+
+	name, vram, err := detectVRAM()
+	if err != nil {
+		t.Fatalf("detectVRAM() error = %v", err)
+	}
+	if name != "NVIDIA A100-SXM4-80GB" || vram != 81920 {
+		t.Errorf("detectVRAM() = %s, %d, want the A100 with 81920 MiB", name, vram)
+	}
+}
+
+func TestParseMemInfo(t *testing.T) {
+	meminfo := "MemTotal:       32779264 kB\nMemFree:         1024000 kB\n"
+	if got := parseMemInfo(strings.NewReader(meminfo)); got != 32011 {
+		t.Errorf("parseMemInfo() = %d, want 32011", got)
+	}
+}
+
+func TestCountGPUs(t *testing.T) {
+	origCommandRunner := CommandRunner
+	defer func() { CommandRunner = origCommandRunner }()
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("printf", "0\n1\n")
+	}
+	if n, err := CountGPUs(); err != nil || n != 2 {
+		t.Errorf("CountGPUs() = %d, %v, want 2", n, err)
+	}
+
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("false")
+	}
+	if _, err := CountGPUs(); err == nil {
+		t.Error("CountGPUs() without nvidia-smi error = nil")
+	}
+}
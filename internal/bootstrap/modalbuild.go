@@ -0,0 +1,128 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Building modal-login with Next.js needs about this much memory; below it
+// the build is given a heap limit so it fails with a clear error instead of
+// being killed by the kernel or swapping for hours
+const modalBuildMinRAMMiB = 8 * 1024
+
+// ErrModalBuildOOM means the modal-login build ran out of memory
+var ErrModalBuildOOM = errors.New("modal-login build ran out of memory")
+
+// ErrModalBuildStuck means the modal-login build didn't finish in time
+var ErrModalBuildStuck = errors.New("modal-login build timed out")
+
+// ModalBuildHeapMiB is the Node.js heap limit for building modal-login on a
+// machine with ramMiB of memory, or 0 to keep Node.js's default
+func ModalBuildHeapMiB(ramMiB int) int {
+	if ramMiB <= 0 || ramMiB >= modalBuildMinRAMMiB {
+		return 0
+	}
+	// Leave a quarter for the OS and the build's worker processes
+	heap := ramMiB * 3 / 4
+	if heap < 512 {
+		heap = 512
+	}
+	return heap
+}
+
+// ModalBuildEnv adds a heap limit to NODE_OPTIONS in env on machines with
+// little memory, unless one is already set, and returns the limit it used
+func ModalBuildEnv(env []string) ([]string, int) {
+	heap := ModalBuildHeapMiB(detectRAM())
+	if heap == 0 {
+		return env, 0
+	}
+	opts := ""
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "NODE_OPTIONS="); ok {
+			opts = v
+		}
+	}
+	if strings.Contains(opts, "--max-old-space-size") {
+		return env, 0
+	}
+	opts = strings.TrimSpace(fmt.Sprintf("%s --max-old-space-size=%d", opts, heap))
+	return append(env, "NODE_OPTIONS="+opts), heap
+}
+
+// ModalBuilt reports whether dir holds a production build of modal-login
+// that "yarn start" can serve, with its dependencies installed
+func ModalBuilt(dir string) bool {
+	for _, p := range []string{filepath.Join(".next", "BUILD_ID"), "node_modules"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// oomMarkers appear in the output of a Node.js build that ran out of memory
+var oomMarkers = []string{
+	"JavaScript heap out of memory",
+	"Reached heap limit",
+	"Allocation failed",
+	"ENOMEM",
+	"Cannot allocate memory",
+}
+
+// ModalBuildError explains why step ("install" or "build") failed, using
+// the end of its output. A process killed by the kernel's OOM killer exits
+// with SIGKILL, often without printing anything.
+func ModalBuildError(step string, err error, output string, timedOut bool) error {
+	if timedOut {
+		return fmt.Errorf("%w: yarn %s didn't finish within --modal-build-timeout, which usually means the machine is swapping; "+
+			"add memory or build modal-login elsewhere and use --modal-skip-build", ErrModalBuildStuck, step)
+	}
+	oom := strings.Contains(err.Error(), "signal: killed")
+	if exitErr, ok := err.(interface{ ExitCode() int }); ok && exitErr.ExitCode() == 137 {
+		oom = true
+	}
+	for _, m := range oomMarkers {
+		if strings.Contains(output, m) {
+			oom = true
+		}
+	}
+	if oom {
+		return fmt.Errorf("%w during yarn %s (%v): add swap or memory, set NODE_OPTIONS=--max-old-space-size=<MiB>, "+
+			"or build modal-login elsewhere and use --modal-skip-build", ErrModalBuildOOM, step, err)
+	}
+	return fmt.Errorf("yarn %s failed: %w", step, err)
+}
+
+// OutputTail keeps the last bytes written to it, to inspect a command's
+// output after it fails
+type OutputTail struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+// NewOutputTail keeps up to max bytes
+func NewOutputTail(max int) *OutputTail {
+	return &OutputTail{max: max}
+}
+
+func (t *OutputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (t *OutputTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,66 @@
+package bootstrap
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModalBuildHeapMiB(t *testing.T) {
+	cases := []struct {
+		ramMiB int
+		want   int
+	}{
+		{0, 0},
+		{16384, 0},
+		{8192, 0},
+		{4096, 3072},
+		{2048, 1536},
+		{512, 512},
+	}
+	for _, c := range cases {
+		if got := ModalBuildHeapMiB(c.ramMiB); got != c.want {
+			t.Errorf("ModalBuildHeapMiB(%d) = %d, want %d", c.ramMiB, got, c.want)
+		}
+	}
+}
+
+func TestModalBuildError(t *testing.T) {
+	failed := errors.New("exit status 1")
+	if err := ModalBuildError("build", failed, "FATAL ERROR: Reached heap limit Allocation failed - JavaScript heap out of memory", false); !errors.Is(err, ErrModalBuildOOM) {
+		t.Errorf("heap exhaustion: got %v, want ErrModalBuildOOM", err)
+	}
+	if err := ModalBuildError("build", errors.New("signal: killed"), "", false); !errors.Is(err, ErrModalBuildOOM) {
+		t.Errorf("SIGKILL: got %v, want ErrModalBuildOOM", err)
+	}
+	if err := ModalBuildError("build", errors.New("signal: killed"), "", true); !errors.Is(err, ErrModalBuildStuck) {
+		t.Errorf("timeout: got %v, want ErrModalBuildStuck", err)
+	}
+	err := ModalBuildError("install", failed, "error Couldn't find package", false)
+	if errors.Is(err, ErrModalBuildOOM) || !errors.Is(err, failed) {
+		t.Errorf("ordinary failure: got %v", err)
+	}
+}
+
+func TestModalBuilt(t *testing.T) {
+	dir := t.TempDir()
+	if ModalBuilt(dir) {
+		t.Fatal("ModalBuilt() = true for an empty directory")
+	}
+	os.MkdirAll(filepath.Join(dir, ".next"), 0o755)
+	os.MkdirAll(filepath.Join(dir, "node_modules"), 0o755)
+	os.WriteFile(filepath.Join(dir, ".next", "BUILD_ID"), []byte("abc"), 0o644)
+	if !ModalBuilt(dir) {
+		t.Error("ModalBuilt() = false for a built directory")
+	}
+}
+
+func TestOutputTail(t *testing.T) {
+	tail := NewOutputTail(8)
+	tail.Write([]byte("hello "))
+	tail.Write([]byte("world"))
+	if got := tail.String(); got != "lo world" {
+		t.Errorf("String() = %q, want %q", got, "lo world")
+	}
+}
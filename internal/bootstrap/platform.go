@@ -0,0 +1,114 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// ErrJetsonTorch means the requirements install PyTorch's CUDA wheels on a
+// Jetson, whose integrated GPU they don't support
+var ErrJetsonTorch = errors.New("PyTorch's CUDA wheels don't run on Jetson")
+
+// Files that identify a Jetson board; variables so tests can replace them
+var (
+	tegraReleasePath = "/etc/nv_tegra_release"
+	deviceModelPath  = "/proc/device-tree/model"
+)
+
+var (
+	// tegraReleaseRe matches "# R36 (release), REVISION: 3.0, ..."
+	tegraReleaseRe = regexp.MustCompile(`R(\d+) \(release\), REVISION: ([\d.]+)`)
+	// missingWheelRe matches pip's report of a package without a build for
+	// this machine
+	missingWheelRe = regexp.MustCompile(`(?:No matching distribution found for|Could not find a version that satisfies the requirement) (\S+)`)
+)
+
+// Platform is the machine the trainer is installed for
+type Platform struct {
+	// Arch is Go's name for the architecture, e.g. amd64 or arm64
+	Arch string
+	// Jetson is set on NVIDIA Jetson boards, and L4T is their Jetson
+	// Linux release, e.g. "R36.3.0", when known
+	Jetson bool
+	L4T    string
+}
+
+// DetectPlatform reports the architecture and whether this is a Jetson
+func DetectPlatform() Platform {
+	p := Platform{Arch: runtime.GOARCH}
+	if data, err := os.ReadFile(tegraReleasePath); err == nil {
+		p.Jetson = true
+		if m := tegraReleaseRe.FindStringSubmatch(string(data)); m != nil {
+			p.L4T = "R" + m[1] + "." + m[2]
+		}
+	} else if model, err := os.ReadFile(deviceModelPath); err == nil && strings.Contains(string(model), "Jetson") {
+		p.Jetson = true
+	}
+	return p
+}
+
+// Machine is the architecture as pip and uname name it, e.g. aarch64
+func (p Platform) Machine() string {
+	switch p.Arch {
+	case "amd64":
+		return "x86_64"
+	case "arm64":
+		return "aarch64"
+	}
+	return p.Arch
+}
+
+// String describes the platform, e.g. "aarch64 (Jetson, L4T R36.3.0)"
+func (p Platform) String() string {
+	if !p.Jetson {
+		return p.Machine()
+	}
+	if p.L4T == "" {
+		return p.Machine() + " (Jetson)"
+	}
+	return p.Machine() + " (Jetson, L4T " + p.L4T + ")"
+}
+
+// FlashAttn reports whether flash-attn can be installed. It only publishes
+// x86_64 wheels; elsewhere pip compiles it for an hour or more and the
+// build usually fails.
+func (p Platform) FlashAttn() bool {
+	return p.Arch == "amd64"
+}
+
+// CheckPlatform reports requirements that can't work on p before pip
+// spends a long time failing on them. rl-swarm's requirements-gpu.txt
+// installs PyTorch's CUDA wheels, which are built for discrete GPUs; a
+// Jetson needs NVIDIA's JetPack build of torch instead.
+func CheckPlatform(p Platform, requirementsFile string) error {
+	if p.Jetson && filepath.Base(requirementsFile) == "requirements-gpu.txt" {
+		release := "your JetPack release"
+		if p.L4T != "" {
+			release = "L4T " + p.L4T
+		}
+		return fmt.Errorf("%w: %s can't use the Jetson's GPU. Put NVIDIA's torch wheel for %s "+
+			"(https://docs.nvidia.com/deeplearning/frameworks/install-pytorch-jetson-platform/) in a requirements file "+
+			"with the rest of %s and pass it with --requirements, or use --cpu-only", ErrJetsonTorch, requirementsFile, release, requirementsFile)
+	}
+	return nil
+}
+
+// PipError explains a failed pip install of requirementsFile from the end
+// of its output. Off x86_64, a package pip can't find usually has no wheel
+// for the architecture at the pinned version.
+func PipError(err error, output, requirementsFile string, p Platform) error {
+	if m := missingWheelRe.FindStringSubmatch(output); m != nil && p.Arch != "amd64" {
+		return fmt.Errorf("failed to install requirements: %s has no %s build of %s (%w). "+
+			"Pin a version that publishes %s wheels in a copy of the file and pass it with --requirements, or use --cpu-only",
+			requirementsFile, p.Machine(), m[1], err, p.Machine())
+	}
+	if strings.Contains(output, "is not a supported wheel on this platform") {
+		return fmt.Errorf("failed to install requirements: %s names a wheel built for another platform than %s (%w)", requirementsFile, p, err)
+	}
+	return fmt.Errorf("failed to install requirements: %w", err)
+}
@@ -0,0 +1,79 @@
+package bootstrap
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	dir := t.TempDir()
+	oldRelease, oldModel := tegraReleasePath, deviceModelPath
+	defer func() { tegraReleasePath, deviceModelPath = oldRelease, oldModel }()
+	tegraReleasePath = filepath.Join(dir, "nv_tegra_release")
+	deviceModelPath = filepath.Join(dir, "model")
+
+	if p := DetectPlatform(); p.Jetson {
+		t.Errorf("DetectPlatform() without Jetson files = %+v", p)
+	}
+
+	os.WriteFile(deviceModelPath, []byte("NVIDIA Jetson AGX Orin Developer Kit\x00"), 0o644)
+	if p := DetectPlatform(); !p.Jetson || p.L4T != "" {
+		t.Errorf("DetectPlatform() from the device tree = %+v", p)
+	}
+
+	os.WriteFile(tegraReleasePath, []byte("# R36 (release), REVISION: 3.0, GCID: 36923193, BOARD: generic, EABI: aarch64\n"), 0o644)
+	if p := DetectPlatform(); !p.Jetson || p.L4T != "R36.3.0" {
+		t.Errorf("DetectPlatform() from nv_tegra_release = %+v", p)
+	}
+
+	p := Platform{Arch: "arm64", Jetson: true, L4T: "R36.3.0"}
+	if got := p.String(); got != "aarch64 (Jetson, L4T R36.3.0)" {
+		t.Errorf("String() = %q", got)
+	}
+	if p.FlashAttn() || !(Platform{Arch: "amd64"}).FlashAttn() {
+		t.Error("flash-attn should only be installed on amd64")
+	}
+}
+
+func TestCheckPlatform(t *testing.T) {
+	jetson := Platform{Arch: "arm64", Jetson: true, L4T: "R35.4.1"}
+	err := CheckPlatform(jetson, "rl-swarm/requirements-gpu.txt")
+	if !errors.Is(err, ErrJetsonTorch) || !strings.Contains(err.Error(), "L4T R35.4.1") {
+		t.Errorf("CheckPlatform(Jetson, GPU requirements) = %v", err)
+	}
+	for _, c := range []struct {
+		p    Platform
+		file string
+	}{
+		{jetson, "requirements-cpu.txt"},
+		{jetson, "requirements-jetson.txt"},
+		{Platform{Arch: "arm64"}, "requirements-gpu.txt"},
+	} {
+		if err := CheckPlatform(c.p, c.file); err != nil {
+			t.Errorf("CheckPlatform(%s, %s) = %v", c.p, c.file, err)
+		}
+	}
+}
+
+func TestPipError(t *testing.T) {
+	exit := errors.New("exit status 1")
+	output := "ERROR: Could not find a version that satisfies the requirement bitsandbytes==0.41.0 (from versions: none)\n" +
+		"ERROR: No matching distribution found for bitsandbytes==0.41.0\n"
+
+	err := PipError(exit, output, "requirements-gpu.txt", Platform{Arch: "arm64"})
+	if !errors.Is(err, exit) || !strings.Contains(err.Error(), "no aarch64 build of bitsandbytes==0.41.0") {
+		t.Errorf("PipError(arm64) = %v", err)
+	}
+	// On x86_64 a missing package is a typo or a network problem, not the
+	// architecture
+	if err := PipError(exit, output, "requirements-gpu.txt", Platform{Arch: "amd64"}); strings.Contains(err.Error(), "build of") {
+		t.Errorf("PipError(amd64) = %v", err)
+	}
+	err = PipError(exit, "ERROR: torch-2.5.1-cp311-cp311-linux_x86_64.whl is not a supported wheel on this platform.", "reqs.txt", Platform{Arch: "arm64"})
+	if !strings.Contains(err.Error(), "another platform than aarch64") {
+		t.Errorf("PipError(wrong wheel) = %v", err)
+	}
+}
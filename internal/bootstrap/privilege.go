@@ -0,0 +1,178 @@
+package bootstrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Deep-Commit/gswarm/internal/console"
+)
+
+// Privilege is how system packages can be installed
+type Privilege int
+
+const (
+	// Rootless installs only into the user's home directory
+	Rootless Privilege = iota
+	// Root runs the package manager directly
+	Root
+	// Sudo runs the package manager through sudo
+	Sudo
+)
+
+func (p Privilege) String() string {
+	switch p {
+	case Root:
+		return "root"
+	case Sudo:
+		return "sudo"
+	default:
+		return "rootless"
+	}
+}
+
+// Prefix is what goes in front of a privileged command in a shell script
+func (p Privilege) Prefix() string {
+	if p == Sudo {
+		return "sudo "
+	}
+	return ""
+}
+
+// Command builds a privileged command, through sudo when needed
+func (p Privilege) Command(name string, args ...string) *exec.Cmd {
+	if p == Sudo {
+		return CommandRunner("sudo", append([]string{name}, args...)...)
+	}
+	return CommandRunner(name, args...)
+}
+
+// Replaced in tests
+var (
+	geteuid         = os.Geteuid
+	lookPath        = exec.LookPath
+	stdinIsTerminal = func() bool {
+		fi, err := os.Stdin.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0
+	}
+)
+
+var (
+	privilegeMu sync.Mutex
+	noSudo      bool
+	detected    *Privilege
+)
+
+// SetNoSudo forces the rootless strategy even when sudo is available
+func SetNoSudo(v bool) {
+	privilegeMu.Lock()
+	defer privilegeMu.Unlock()
+	noSudo = v
+	detected = nil
+}
+
+// DetectPrivilege works out, once, how system packages can be installed:
+// directly as root, through sudo when it works without a password or can
+// prompt for one, and otherwise rootless
+func DetectPrivilege() Privilege {
+	privilegeMu.Lock()
+	defer privilegeMu.Unlock()
+	if detected != nil {
+		return *detected
+	}
+	p := detectPrivilege()
+	detected = &p
+	if p == Rootless {
+		if noSudo {
+			console.Infof("Using rootless installs (--no-sudo)")
+		} else {
+			console.Infof("sudo isn't available; using rootless installs")
+		}
+	}
+	return p
+}
+
+func detectPrivilege() Privilege {
+	if noSudo {
+		return Rootless
+	}
+	if geteuid() == 0 {
+		return Root
+	}
+	if _, err := lookPath("sudo"); err != nil {
+		return Rootless
+	}
+	// sudo -v fails without a cached or passwordless login, but only
+	// says a password is required to users who may use sudo at all
+	probe := CommandRunner("sudo", "-n", "-v")
+	probe.Env = append(os.Environ(), "LC_ALL=C")
+	var stderr bytes.Buffer
+	probe.Stderr = &stderr
+	if err := probe.Run(); err == nil {
+		return Sudo
+	}
+	if stdinIsTerminal() && strings.Contains(stderr.String(), "password is required") {
+		return Sudo
+	}
+	return Rootless
+}
+
+// LocalBinDir is where rootless installs put executables
+func LocalBinDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".local", "bin")
+}
+
+// PrependPath puts dir first on this process's PATH, so tools installed
+// into the home directory are found by the commands gswarm starts
+func PrependPath(dir string) {
+	path := os.Getenv("PATH")
+	for _, d := range filepath.SplitList(path) {
+		if d == dir {
+			return
+		}
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+}
+
+// UseNVMNode puts the nvm-installed Node.js on PATH, since nvm only
+// changes PATH inside the shell that sources it
+func UseNVMNode(version string) error {
+	cmd := CommandRunner("bash", "-c", fmt.Sprintf(`source ~/.nvm/nvm.sh >/dev/null && nvm use %s >/dev/null && dirname "$(command -v node)"`, version))
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to locate the nvm Node.js: %w", err)
+	}
+	if dir := strings.TrimSpace(string(out)); filepath.IsAbs(dir) {
+		PrependPath(dir)
+	}
+	return nil
+}
+
+// InstallYarnRootless enables Yarn through Node.js's corepack, linking it
+// into LocalBinDir so it works even when Node.js itself is system-owned
+func InstallYarnRootless() error {
+	dir := LocalBinDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	console.Infof("Enabling Yarn with corepack in %s...", dir)
+	cmd := CommandRunner("bash", "-c", fmt.Sprintf(`[ -s ~/.nvm/nvm.sh ] && source ~/.nvm/nvm.sh >/dev/null; corepack enable --install-directory %q`, dir))
+	cmd.Stdout = console.Out()
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to enable yarn with corepack (needs Node.js 16.10+): %w", err)
+	}
+	PrependPath(dir)
+	// Corepack otherwise asks before downloading Yarn on first use
+	os.Setenv("COREPACK_ENABLE_DOWNLOAD_PROMPT", "0")
+	return nil
+}
+
+// ErrGitRootless explains how to get git when it can't be installed
+var ErrGitRootless = errors.New("git is not installed and installing it needs root: ask an administrator to install it (e.g. \"apt-get install git\"), " +
+	"or install it into your home directory (e.g. \"conda install git\") and make sure it is on PATH")
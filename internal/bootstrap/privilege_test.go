@@ -0,0 +1,110 @@
+package bootstrap
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectPrivilege(t *testing.T) {
+	origEuid, origLookPath, origTerminal, origRunner := geteuid, lookPath, stdinIsTerminal, CommandRunner
+	defer func() {
+		geteuid, lookPath, stdinIsTerminal, CommandRunner = origEuid, origLookPath, origTerminal, origRunner
+		SetNoSudo(false)
+	}()
+
+	const (
+		passwordless = ""
+		password     = "sudo: a password is required"
+		notSudoer    = "Sorry, user bob may not run sudo on host."
+	)
+	cases := []struct {
+		name     string
+		noSudo   bool
+		euid     int
+		hasSudo  bool
+		sudo     string
+		terminal bool
+		want     Privilege
+	}{
+		{"root", false, 0, false, passwordless, false, Root},
+		{"passwordless sudo", false, 1000, true, passwordless, false, Sudo},
+		{"sudo can prompt", false, 1000, true, password, true, Sudo},
+		{"sudo needs a password, no terminal", false, 1000, true, password, false, Rootless},
+		{"not a sudoer", false, 1000, true, notSudoer, true, Rootless},
+		{"no sudo binary", false, 1000, false, passwordless, true, Rootless},
+		{"--no-sudo", true, 1000, true, passwordless, true, Rootless},
+		{"--no-sudo as root", true, 0, true, passwordless, true, Rootless},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			geteuid = func() int { return c.euid }
+			lookPath = func(string) (string, error) {
+				if c.hasSudo {
+					return "/usr/bin/sudo", nil
+				}
+				return "", errors.New("not found")
+			}
+			stdinIsTerminal = func() bool { return c.terminal }
+			CommandRunner = func(name string, args ...string) *exec.Cmd {
+				if name == "sudo" && c.sudo == passwordless {
+					return exec.Command("true")
+				}
+				return exec.Command("sh", "-c", "echo \"$0\" >&2; exit 1", c.sudo)
+			}
+			SetNoSudo(c.noSudo)
+			if got := DetectPrivilege(); got != c.want {
+				t.Errorf("DetectPrivilege() = %s, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPrivilege_Command(t *testing.T) {
+	origRunner := CommandRunner
+	defer func() { CommandRunner = origRunner }()
+	var got []string
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		got = append(got, strings.Join(append([]string{name}, args...), " "))
+		return exec.Command("true")
+	}
+
+	Sudo.Command("apt-get", "install", "-y", "git")
+	Root.Command("apt-get", "install", "-y", "git")
+	want := []string{"sudo apt-get install -y git", "apt-get install -y git"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestInstallYarnRootless(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("COREPACK_ENABLE_DOWNLOAD_PROMPT", "")
+
+	origRunner := CommandRunner
+	defer func() { CommandRunner = origRunner }()
+	var script string
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		script = args[len(args)-1]
+		return exec.Command("true")
+	}
+
+	if err := InstallYarnRootless(); err != nil {
+		t.Fatalf("InstallYarnRootless() error = %v", err)
+	}
+	bin := filepath.Join(home, ".local", "bin")
+	if !strings.Contains(script, "corepack enable --install-directory") || !strings.Contains(script, bin) {
+		t.Errorf("script = %q, want corepack enable into %s", script, bin)
+	}
+	if path := os.Getenv("PATH"); path != bin+string(os.PathListSeparator)+"/usr/bin" {
+		t.Errorf("PATH = %q, want %s first", path, bin)
+	}
+	if os.Getenv("COREPACK_ENABLE_DOWNLOAD_PROMPT") != "0" {
+		t.Error("COREPACK_ENABLE_DOWNLOAD_PROMPT not set")
+	}
+}
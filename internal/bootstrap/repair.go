@@ -0,0 +1,137 @@
+package bootstrap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/console"
+)
+
+// RLSwarmRepoURL is the upstream rl-swarm repository
+const RLSwarmRepoURL = "https://github.com/gensyn-ai/rl-swarm.git"
+
+// requiredPaths must exist in a healthy rl-swarm checkout whatever its
+// layout. The trainer itself is found with DetectEntrypoint, since it has
+// moved between releases.
+var requiredPaths = []string{
+	".git",
+	"modal-login",
+	"requirements-cpu.txt",
+	"requirements-gpu.txt",
+}
+
+// UserFiles are node-specific files that survive a repair, relative to the
+// checkout. Losing swarm.pem would change the peer identity.
+var UserFiles = []string{
+	"swarm.pem",
+	"modal-login/temp-data/userData.json",
+	"modal-login/temp-data/userApiKey.json",
+}
+
+// CheckCheckout returns the problems found in an rl-swarm checkout, or nil
+// when it looks healthy
+func CheckCheckout(dir string) []string {
+	var problems []string
+	for _, p := range requiredPaths {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			problems = append(problems, fmt.Sprintf("missing %s", p))
+		}
+	}
+	if _, err := DetectEntrypoint(dir); err != nil {
+		problems = append(problems, "missing the trainer entrypoint")
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	// git status fails on a broken index, HEAD or object store
+	cmd := CommandRunner("git", "-C", dir, "status", "--porcelain")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		problems = append(problems, fmt.Sprintf("git status failed: %v: %s", err, firstLine(string(out))))
+	}
+	return problems
+}
+
+// RepairCheckout re-clones the rl-swarm checkout at dir, carrying over the
+// user files. The old checkout is moved aside first and only removed once
+// the new one is in place, so a failed clone leaves it untouched.
+func RepairCheckout(dir string) error {
+	backup := fmt.Sprintf("%s.broken-%s", dir, time.Now().Format("20060102-150405"))
+	if _, err := os.Stat(dir); err == nil {
+		if err := os.Rename(dir, backup); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", dir, err)
+		}
+		console.Infof("Moved existing checkout to %s", backup)
+	} else {
+		backup = ""
+	}
+
+	cmd := CommandRunner("git", "clone", RLSwarmRepoURL, dir)
+	cmd.Stdout = console.Out()
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if backup != "" {
+			os.RemoveAll(dir)
+			if rerr := os.Rename(backup, dir); rerr != nil {
+				return fmt.Errorf("failed to clone rl-swarm: %w (old checkout left at %s)", err, backup)
+			}
+		}
+		return fmt.Errorf("failed to clone rl-swarm: %w", err)
+	}
+
+	if backup == "" {
+		return nil
+	}
+	for _, f := range UserFiles {
+		src := filepath.Join(backup, f)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := copyFile(src, filepath.Join(dir, f)); err != nil {
+			return fmt.Errorf("failed to restore %s (old checkout left at %s): %w", f, backup, err)
+		}
+		console.Infof("Restored %s", f)
+	}
+	if err := os.RemoveAll(backup); err != nil {
+		console.Warnf("could not remove old checkout %s: %v", backup, err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst, creating parent directories and keeping the
+// file mode
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func firstLine(s string) string {
+	for i, r := range s {
+		if r == '\n' {
+			return s[:i]
+		}
+	}
+	return s
+}
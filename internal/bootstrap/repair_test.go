@@ -0,0 +1,123 @@
+package bootstrap
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// makeCheckout creates a minimal healthy-looking rl-swarm checkout
+func makeCheckout(t *testing.T, dir string) {
+	t.Helper()
+	for _, p := range []string{".git", "hivemind_exp/gsm8k", "modal-login/temp-data"} {
+		if err := os.MkdirAll(filepath.Join(dir, p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"requirements-cpu.txt", "requirements-gpu.txt", "hivemind_exp/gsm8k/train_single_gpu.py"} {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckCheckout(t *testing.T) {
+	cases := []struct {
+		name      string
+		remove    string
+		add       string
+		gitOK     bool
+		wantCount int
+	}{
+		{"healthy", "", "", true, 0},
+		{"missing trainer", "hivemind_exp", "", true, 1},
+		{"missing requirements", "requirements-gpu.txt", "", true, 1},
+		{"git corrupted", "", "", false, 1},
+		{"newer layout", "hivemind_exp", "rgym_exp/runner/train_single_gpu.py", true, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			makeCheckout(t, dir)
+			if c.remove != "" {
+				os.RemoveAll(filepath.Join(dir, c.remove))
+			}
+			if c.add != "" {
+				os.MkdirAll(filepath.Dir(filepath.Join(dir, c.add)), 0o755)
+				os.WriteFile(filepath.Join(dir, c.add), nil, 0o644)
+			}
+
+			origCommandRunner := CommandRunner
+			defer func() { CommandRunner = origCommandRunner }()
+			CommandRunner = func(name string, args ...string) *exec.Cmd {
+				if c.gitOK {
+					return exec.Command("true")
+				}
+				return exec.Command("sh", "-c", "echo 'fatal: bad object HEAD'; exit 128")
+			}
+
+			if got := CheckCheckout(dir); len(got) != c.wantCount {
+				t.Errorf("CheckCheckout() = %v, want %d problems", got, c.wantCount)
+			}
+		})
+	}
+}
+
+func TestRepairCheckout(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rl-swarm")
+	makeCheckout(t, dir)
+	os.RemoveAll(filepath.Join(dir, "hivemind_exp"))
+	os.WriteFile(filepath.Join(dir, "swarm.pem"), []byte("key"), 0o600)
+	os.WriteFile(filepath.Join(dir, "modal-login/temp-data/userData.json"), []byte("{}"), 0o644)
+
+	origCommandRunner := CommandRunner
+	defer func() { CommandRunner = origCommandRunner }()
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		if name == "git" && len(args) > 0 && args[0] == "clone" {
+			makeCheckout(t, args[len(args)-1])
+		}
+		return exec.Command("true")
+	}
+
+	if err := RepairCheckout(dir); err != nil {
+		t.Fatalf("RepairCheckout() error = %v", err)
+	}
+
+	if problems := CheckCheckout(dir); len(problems) != 0 {
+		t.Errorf("CheckCheckout() after repair = %v", problems)
+	}
+	key, err := os.ReadFile(filepath.Join(dir, "swarm.pem"))
+	if err != nil || string(key) != "key" {
+		t.Errorf("swarm.pem not restored: %q, %v", key, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "swarm.pem")); err == nil && info.Mode().Perm() != 0o600 {
+		t.Errorf("swarm.pem mode = %v, want 0600", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "modal-login/temp-data/userData.json")); err != nil {
+		t.Errorf("userData.json not restored: %v", err)
+	}
+	if matches, _ := filepath.Glob(dir + ".broken-*"); len(matches) != 0 {
+		t.Errorf("old checkout not removed: %v", matches)
+	}
+}
+
+func TestRepairCheckout_CloneFails(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rl-swarm")
+	makeCheckout(t, dir)
+	os.WriteFile(filepath.Join(dir, "swarm.pem"), []byte("key"), 0o600)
+
+	origCommandRunner := CommandRunner
+	defer func() { CommandRunner = origCommandRunner }()
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("false")
+	}
+
+	if err := RepairCheckout(dir); err == nil {
+		t.Fatal("RepairCheckout() expected error when clone fails")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "swarm.pem")); err != nil {
+		t.Errorf("old checkout not restored after failed clone: %v", err)
+	}
+}
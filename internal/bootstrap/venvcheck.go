@@ -0,0 +1,196 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RequiredModules are imported by every rl-swarm trainer release
+var RequiredModules = []string{"torch", "transformers", "hivemind"}
+
+// probeScript imports each module named in its arguments and prints, as
+// the last line of its output, the interpreter's prefix and where each
+// module came from. A namespace package has no file, only its path.
+const probeScript = `
+import importlib, json, os, sys
+out = {"executable": sys.executable, "prefix": sys.prefix, "pythonpath": os.environ.get("PYTHONPATH", ""), "modules": {}}
+for name in sys.argv[1:]:
+    try:
+        m = importlib.import_module(name)
+        out["modules"][name] = {"file": getattr(m, "__file__", None) or "", "path": [str(p) for p in getattr(m, "__path__", [])], "version": str(getattr(m, "__version__", ""))}
+    except BaseException as e:
+        out["modules"][name] = {"error": "%s: %s" % (type(e).__name__, e)}
+print()
+print(json.dumps(out))
+`
+
+// versionRe matches a package's __version__ = "..." assignment
+var versionRe = regexp.MustCompile(`(?m)^__version__\s*=\s*["']([^"']+)["']`)
+
+// EnvProbe is what the venv's Python reports about itself
+type EnvProbe struct {
+	Executable string                 `json:"executable"`
+	Prefix     string                 `json:"prefix"`
+	PythonPath string                 `json:"pythonpath"`
+	Modules    map[string]ModuleProbe `json:"modules"`
+}
+
+// ModuleProbe is where a module was imported from, or why it wasn't
+type ModuleProbe struct {
+	File string `json:"file,omitempty"`
+	// Path is a package's directories; a namespace package has no File
+	Path    []string `json:"path,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// location is where the module was imported from
+func (m ModuleProbe) location() string {
+	if m.File == "" {
+		return strings.Join(m.Path, ", ")
+	}
+	return m.File
+}
+
+// within reports whether the module was imported from dir. A namespace
+// package is, as long as one of its directories is.
+func (m ModuleProbe) within(dir string) bool {
+	if m.File != "" {
+		return within(m.File, dir)
+	}
+	for _, p := range m.Path {
+		if within(p, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvCheck describes the environment a run is about to use
+type EnvCheck struct {
+	// Python is the venv's interpreter and Venv the venv's directory
+	Python string
+	Venv   string
+	// Checkout is the rl-swarm checkout and Package the trainer's
+	// top-level package in it, e.g. hivemind_exp
+	Checkout string
+	Package  string
+	// Requirements is the file the venv was installed from
+	Requirements string
+}
+
+// ProbeCommand returns the command that probes the venv's Python. The
+// caller runs it in the checkout with the trainer's environment, so the
+// modules resolve the way the trainer's will.
+func (e EnvCheck) ProbeCommand(ctx context.Context) *exec.Cmd {
+	args := append([]string{"-c", probeScript}, RequiredModules...)
+	args = append(args, e.Package)
+	return exec.CommandContext(ctx, e.Python, args...)
+}
+
+// ParseProbe reads the probe's output, skipping anything the imports
+// printed before it
+func ParseProbe(output []byte) (EnvProbe, error) {
+	var probe EnvProbe
+	lines := bytes.Split(bytes.TrimSpace(output), []byte("\n"))
+	last := lines[len(lines)-1]
+	if err := json.Unmarshal(last, &probe); err != nil {
+		return probe, fmt.Errorf("unexpected output from the venv's Python: %q", last)
+	}
+	return probe, nil
+}
+
+// Check compares the probe with the environment the run expects and
+// returns every problem with the steps that fix it, or nil
+func (e EnvCheck) Check(probe EnvProbe) error {
+	var problems []string
+	if !within(probe.Prefix, e.Venv) {
+		problems = append(problems, fmt.Sprintf("%s runs the Python installed at %s, not the venv at %s; the venv's pyvenv.cfg is missing or broken. "+
+			"Delete %s and start gswarm again to recreate it.", e.Python, probe.Prefix, e.Venv, e.Venv))
+	}
+
+	leak := "Something outside the venv is on the module path"
+	if probe.PythonPath != "" {
+		leak = fmt.Sprintf("PYTHONPATH=%s puts it on the module path", probe.PythonPath)
+	}
+	for _, name := range RequiredModules {
+		m := probe.Modules[name]
+		switch {
+		case m.Error != "":
+			problems = append(problems, fmt.Sprintf("%s doesn't import: %s. Reinstall the requirements with %s -m pip install -r %s, "+
+				"or delete %s and start gswarm again.", name, m.Error, e.Python, e.Requirements, e.Venv))
+		case !m.within(probe.Prefix):
+			problems = append(problems, fmt.Sprintf("%s is imported from %s, outside the venv. %s; unset it in the environment or the config file's env.",
+				name, m.location(), leak))
+		}
+	}
+
+	pkg := probe.Modules[e.Package]
+	own := filepath.Join(e.Checkout, e.Package)
+	switch {
+	case pkg.Error != "":
+		problems = append(problems, fmt.Sprintf("the trainer package %s doesn't import: %s. Run gswarm repair to restore the checkout at %s.",
+			e.Package, pkg.Error, e.Checkout))
+	case !pkg.within(own):
+		found := pkg.location()
+		if pkg.Version != "" {
+			found = fmt.Sprintf("%s (version %s)", found, pkg.Version)
+		}
+		msg := fmt.Sprintf("%s is imported from %s, not the checkout at %s", e.Package, found, own)
+		if v := checkoutVersion(own); v != "" && v != pkg.Version {
+			msg += fmt.Sprintf(", which has version %s", v)
+		}
+		if pkg.within(probe.Prefix) {
+			msg += fmt.Sprintf(". A copy installed in the venv shadows it; remove it with %s -m pip uninstall -y %s.", e.Python, e.Package)
+		} else {
+			msg += ". " + leak + "; unset it in the environment or the config file's env."
+		}
+		problems = append(problems, msg)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("- " + strings.Join(problems, "\n- "))
+}
+
+// checkoutVersion is the __version__ in the package at dir, if it sets one
+func checkoutVersion(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "__init__.py"))
+	if err != nil {
+		return ""
+	}
+	if m := versionRe.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// within reports whether path is dir or inside it, following symlinks so a
+// venv reached through a link still matches
+func within(path, dir string) bool {
+	if path == "" || dir == "" {
+		return false
+	}
+	path, dir = resolve(path), resolve(dir)
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func resolve(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvCheck(t *testing.T) {
+	dir := t.TempDir()
+	venv := filepath.Join(dir, "gswarm-venv")
+	site := filepath.Join(venv, "lib", "python3.11", "site-packages")
+	checkout := filepath.Join(dir, "rl-swarm")
+	os.MkdirAll(filepath.Join(checkout, "hivemind_exp"), 0o755)
+	os.WriteFile(filepath.Join(checkout, "hivemind_exp", "__init__.py"), []byte("__version__ = \"0.2.0\"\n"), 0o644)
+
+	e := EnvCheck{Python: filepath.Join(venv, "bin", "python"), Venv: venv, Checkout: checkout, Package: "hivemind_exp", Requirements: "requirements-gpu.txt"}
+	healthy := func() EnvProbe {
+		return EnvProbe{Prefix: venv, Modules: map[string]ModuleProbe{
+			"torch":        {File: filepath.Join(site, "torch", "__init__.py")},
+			"transformers": {File: filepath.Join(site, "transformers", "__init__.py")},
+			"hivemind":     {File: filepath.Join(site, "hivemind", "__init__.py")},
+			"hivemind_exp": {File: filepath.Join(checkout, "hivemind_exp", "__init__.py"), Version: "0.2.0"},
+		}}
+	}
+	if err := e.Check(healthy()); err != nil {
+		t.Errorf("Check(healthy) = %v", err)
+	}
+	// A checkout without __init__.py is a namespace package
+	namespace := healthy()
+	namespace.Modules["hivemind_exp"] = ModuleProbe{Path: []string{filepath.Join(checkout, "hivemind_exp")}}
+	if err := e.Check(namespace); err != nil {
+		t.Errorf("Check(namespace package) = %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		breakIt func(*EnvProbe)
+		want    string
+	}{
+		{"system python", func(p *EnvProbe) { p.Prefix = "/usr" }, "runs the Python installed at /usr, not the venv"},
+		{"missing module", func(p *EnvProbe) {
+			p.Modules["hivemind"] = ModuleProbe{Error: "ModuleNotFoundError: No module named 'hivemind'"}
+		}, "hivemind doesn't import: ModuleNotFoundError"},
+		{"PYTHONPATH leak", func(p *EnvProbe) {
+			p.PythonPath = "/usr/lib/python3/dist-packages"
+			p.Modules["torch"] = ModuleProbe{File: "/usr/lib/python3/dist-packages/torch/__init__.py"}
+		}, "PYTHONPATH=/usr/lib/python3/dist-packages puts it on the module path"},
+		{"installed copy", func(p *EnvProbe) {
+			p.Modules["hivemind_exp"] = ModuleProbe{File: filepath.Join(site, "hivemind_exp", "__init__.py"), Version: "0.1.0"}
+		}, "which has version 0.2.0. A copy installed in the venv shadows it; remove it with"},
+		{"namespace package elsewhere", func(p *EnvProbe) {
+			p.Modules["hivemind_exp"] = ModuleProbe{Path: []string{"/opt/old/hivemind_exp"}}
+		}, "hivemind_exp is imported from /opt/old/hivemind_exp, not the checkout"},
+		{"broken checkout", func(p *EnvProbe) {
+			p.Modules["hivemind_exp"] = ModuleProbe{Error: "SyntaxError: invalid syntax"}
+		}, "Run gswarm repair"},
+	}
+	for _, c := range cases {
+		probe := healthy()
+		c.breakIt(&probe)
+		if err := e.Check(probe); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: Check() = %v, want %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	e := EnvCheck{Python: python, Package: "json"}
+	out, err := e.ProbeCommand(context.Background()).Output()
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	probe, err := ParseProbe(append([]byte("a warning printed on import\n"), out...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probe.Prefix == "" || probe.Modules["json"].File == "" {
+		t.Errorf("ParseProbe() = %+v", probe)
+	}
+	if _, ok := probe.Modules["torch"]; !ok {
+		t.Errorf("probe didn't report torch: %+v", probe.Modules)
+	}
+}
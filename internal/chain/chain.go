@@ -0,0 +1,355 @@
+// Package chain reads swarm state from the coordinator contract over JSON-RPC.
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/int256"
+	"github.com/Deep-Commit/gswarm/internal/rpc"
+)
+
+// Function selectors of the coordinator
+const (
+	// getVoterVoteCount(string)
+	selectorVoterVoteCount = "dfb3c7df"
+	// getEoa(string[])
+	selectorEOA = "96bac35a"
+	// getPeerId(address[])
+	selectorPeerIDs = "b894a469"
+	// uniqueVoters()
+	selectorUniqueVoters = "b0c77404"
+	// voterLeaderboard(uint256,uint256)
+	selectorVoterLeaderboard = "18a6fd88"
+	// getTotalRewards(string[])
+	selectorTotalRewards = "80c3d97f"
+)
+
+// chainIDCacheTTL is how long a cached chain ID is trusted
+const chainIDCacheTTL = 24 * time.Hour
+
+// zeroAddress is returned by getEoa for peers that aren't registered
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// ErrEmptyResult is an eth_call that returned no data, which no coordinator
+// function does: there is no contract at the address, or the call reverted.
+// It must not be read as zero votes or rewards.
+var ErrEmptyResult = errors.New("empty eth_call result; no contract at the address, or the call reverted")
+
+// Reader queries a coordinator contract
+type Reader struct {
+	Client   *rpc.Client
+	Endpoint string
+	Contract string
+}
+
+// NewReader creates a Reader for contract on the Gensyn testnet
+func NewReader(contract string) *Reader {
+	return &Reader{Client: rpc.NewClient(), Endpoint: rpc.GensynTestnetURL, Contract: contract}
+}
+
+// VoterVoteCount returns the number of votes submitted by peerID. It only
+// increases while a node with that identity is participating in rounds.
+func (r *Reader) VoterVoteCount(ctx context.Context, peerID string) (*big.Int, error) {
+	result, err := r.call(ctx, selectorVoterVoteCount+encodeString(peerID))
+	if err != nil {
+		return nil, err
+	}
+	return decodeUint(result)
+}
+
+// EOA returns the address that registered peerID, or "" if no one has
+func (r *Reader) EOA(ctx context.Context, peerID string) (string, error) {
+	// A one-element string[]: offset, length, then the element's offset
+	// relative to the array's contents, which encodeString starts with
+	result, err := r.call(ctx, selectorEOA+fmt.Sprintf("%064x%064x", 32, 1)+encodeString(peerID))
+	if err != nil {
+		return "", err
+	}
+	data, err := hex.DecodeString(result)
+	if err != nil {
+		return "", fmt.Errorf("invalid eth_call result: %w", err)
+	}
+	array, err := wordAt(data, 0)
+	if err != nil {
+		return "", err
+	}
+	n, err := wordAt(data, array)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", nil
+	}
+	if array+64 > len(data) {
+		return "", fmt.Errorf("short eth_call result")
+	}
+	addr := "0x" + hex.EncodeToString(data[array+32+12:array+64])
+	if addr == zeroAddress {
+		return "", nil
+	}
+	return addr, nil
+}
+
+// PeerIDs returns the peers registered by eoa
+func (r *Reader) PeerIDs(ctx context.Context, eoa string) ([]string, error) {
+	address := strings.ToLower(strings.TrimPrefix(eoa, "0x"))
+	result, err := r.call(ctx, selectorPeerIDs+fmt.Sprintf("%064x%064x%064s", 32, 1, address))
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(result)
+	if err != nil {
+		return nil, fmt.Errorf("invalid eth_call result: %w", err)
+	}
+	// string[][]: the outer array holds one string[] per address queried
+	outer, err := wordAt(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := wordAt(data, outer); err != nil || n == 0 {
+		return nil, err
+	}
+	first, err := wordAt(data, outer+32)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStrings(data, outer+32+first)
+}
+
+// UniqueVoters returns the number of peers that have voted, which is the
+// length of the voter leaderboard
+func (r *Reader) UniqueVoters(ctx context.Context) (int, error) {
+	result, err := r.call(ctx, selectorUniqueVoters)
+	if err != nil {
+		return 0, err
+	}
+	n, err := decodeUint(result)
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("invalid voter count %s", n)
+	}
+	return int(n.Int64()), nil
+}
+
+// VoterLeaderboard returns the peers ranked start to end-1 by votes, most
+// votes first, with their vote counts
+func (r *Reader) VoterLeaderboard(ctx context.Context, start, end int) ([]string, []*big.Int, error) {
+	result, err := r.call(ctx, selectorVoterLeaderboard+fmt.Sprintf("%064x%064x", start, end))
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := hex.DecodeString(result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid eth_call result: %w", err)
+	}
+	// (string[], uint256[]): the head holds both arrays' offsets
+	peersAt, err := wordAt(data, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	votesAt, err := wordAt(data, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	peers, err := decodeStrings(data, peersAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	votes, err := decodeWords(data, votesAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(votes) != len(peers) {
+		return nil, nil, fmt.Errorf("leaderboard has %d peers but %d vote counts", len(peers), len(votes))
+	}
+	return peers, votes, nil
+}
+
+// TotalRewards returns the rewards of each of peerIDs, in order. Rewards
+// are signed, as penalties can take a peer below zero.
+func (r *Reader) TotalRewards(ctx context.Context, peerIDs []string) ([]*big.Int, error) {
+	result, err := r.call(ctx, selectorTotalRewards+encodeStrings(peerIDs))
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(result)
+	if err != nil {
+		return nil, fmt.Errorf("invalid eth_call result: %w", err)
+	}
+	array, err := wordAt(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	rewards, err := decodeWords(data, array)
+	if err != nil {
+		return nil, err
+	}
+	if len(rewards) != len(peerIDs) {
+		return nil, fmt.Errorf("got rewards for %d peers, asked for %d", len(rewards), len(peerIDs))
+	}
+	for _, v := range rewards {
+		int256.FromUint256(v)
+	}
+	return rewards, nil
+}
+
+// ChainID returns the chain ID served by the endpoint
+func (r *Reader) ChainID(ctx context.Context) (uint64, error) {
+	result, err := r.Client.CallCached(ctx, r.Endpoint, "eth_chainId", []interface{}{}, chainIDCacheTTL)
+	if err != nil {
+		return 0, fmt.Errorf("eth_chainId failed: %w", err)
+	}
+	s, ok := result.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected eth_chainId result %v", result)
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid chain ID %q", s)
+	}
+	return id, nil
+}
+
+func (r *Reader) call(ctx context.Context, data string) (string, error) {
+	params := []interface{}{
+		map[string]interface{}{"to": r.Contract, "data": "0x" + data},
+		"latest",
+	}
+	result, err := r.Client.Call(ctx, r.Endpoint, "eth_call", params)
+	if err != nil {
+		return "", fmt.Errorf("eth_call failed: %w", err)
+	}
+	s, ok := result.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected eth_call result %v", result)
+	}
+	return checkResult(s)
+}
+
+// checkResult strips the 0x from an eth_call result and checks it is
+// made of whole ABI words, as every coordinator function returns
+func checkResult(s string) (string, error) {
+	data, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		return "", fmt.Errorf("malformed eth_call result %q: missing 0x", abbreviate(s))
+	}
+	if data == "" {
+		return "", ErrEmptyResult
+	}
+	if len(data)%64 != 0 {
+		return "", fmt.Errorf("malformed eth_call result: %d bytes is not a whole number of ABI words", len(data)/2)
+	}
+	if _, err := hex.DecodeString(data); err != nil {
+		return "", fmt.Errorf("malformed eth_call result %q: not hex", abbreviate(s))
+	}
+	return data, nil
+}
+
+// abbreviate shortens s for error messages
+func abbreviate(s string) string {
+	if len(s) > 40 {
+		return s[:40] + "..."
+	}
+	return s
+}
+
+// encodeString ABI-encodes a single dynamic string argument
+func encodeString(s string) string {
+	padded := make([]byte, (len(s)+31)/32*32)
+	copy(padded, s)
+	return fmt.Sprintf("%064x%064x%s", 32, len(s), hex.EncodeToString(padded))
+}
+
+// encodeStrings ABI-encodes a single string[] argument
+func encodeStrings(list []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%064x%064x", 32, len(list))
+	// Each element's offset is relative to the start of the offsets
+	offset := len(list) * 32
+	for _, s := range list {
+		fmt.Fprintf(&b, "%064x", offset)
+		offset += 32 + (len(s)+31)/32*32
+	}
+	for _, s := range list {
+		padded := make([]byte, (len(s)+31)/32*32)
+		copy(padded, s)
+		fmt.Fprintf(&b, "%064x%s", len(s), hex.EncodeToString(padded))
+	}
+	return b.String()
+}
+
+// decodeStrings decodes the string[] whose length word is at offset
+func decodeStrings(data []byte, offset int) ([]string, error) {
+	count, err := wordAt(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		off, err := wordAt(data, offset+32+i*32)
+		if err != nil {
+			return nil, err
+		}
+		str := offset + 32 + off
+		length, err := wordAt(data, str)
+		if err != nil {
+			return nil, err
+		}
+		if str+32+length > len(data) {
+			return nil, fmt.Errorf("short eth_call result")
+		}
+		list = append(list, string(data[str+32:str+32+length]))
+	}
+	return list, nil
+}
+
+// decodeWords decodes the uint256[] or int256[] whose length word is at
+// offset, as unsigned values
+func decodeWords(data []byte, offset int) ([]*big.Int, error) {
+	count, err := wordAt(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	if offset+32+count*32 > len(data) {
+		return nil, fmt.Errorf("short eth_call result")
+	}
+	list := make([]*big.Int, count)
+	for i := range list {
+		at := offset + 32 + i*32
+		list[i] = new(big.Int).SetBytes(data[at : at+32])
+	}
+	return list, nil
+}
+
+// wordAt reads the ABI word at offset as an offset or length
+func wordAt(data []byte, offset int) (int, error) {
+	if offset < 0 || offset+32 > len(data) {
+		return 0, fmt.Errorf("short eth_call result")
+	}
+	n := new(big.Int).SetBytes(data[offset : offset+32])
+	if !n.IsInt64() || n.Int64() > int64(len(data)) {
+		return 0, fmt.Errorf("invalid offset in eth_call result")
+	}
+	return int(n.Int64()), nil
+}
+
+// decodeUint decodes a single uint256 return value
+func decodeUint(result string) (*big.Int, error) {
+	if len(result) < 64 {
+		return nil, fmt.Errorf("short eth_call result %q", result)
+	}
+	n, ok := new(big.Int).SetString(result[:64], 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid eth_call result %q", result)
+	}
+	return n, nil
+}
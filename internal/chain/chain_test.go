@@ -0,0 +1,220 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Deep-Commit/gswarm/internal/rpc"
+)
+
+func TestEncodeString(t *testing.T) {
+	got := encodeString("Qm")
+	want := fmt.Sprintf("%064x%064x%s", 32, 2, "516d"+strings.Repeat("0", 60))
+	if got != want {
+		t.Errorf("encodeString() = %s, want %s", got, want)
+	}
+	if len(encodeString("")) != 128 {
+		t.Errorf("encodeString(\"\") should encode offset and zero length only")
+	}
+}
+
+func TestReader_VoterVoteCount(t *testing.T) {
+	var gotData string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpc.Request
+		json.NewDecoder(r.Body).Decode(&req)
+		gotData = req.Params[0].(map[string]interface{})["data"].(string)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x%064x"}`, req.ID, 42)
+	}))
+	defer srv.Close()
+
+	r := &Reader{Client: rpc.NewClient(), Endpoint: srv.URL, Contract: "0xabc"}
+	got, err := r.VoterVoteCount(context.Background(), "QmPeer")
+	if err != nil {
+		t.Fatalf("VoterVoteCount() error = %v", err)
+	}
+	if got.Int64() != 42 {
+		t.Errorf("VoterVoteCount() = %s, want 42", got)
+	}
+	if !strings.HasPrefix(gotData, "0x"+selectorVoterVoteCount) {
+		t.Errorf("call data = %s, want getVoterVoteCount selector", gotData)
+	}
+}
+
+func TestReader_BadResults(t *testing.T) {
+	for _, c := range []struct {
+		result string
+		want   string
+	}{
+		{`"0x"`, "empty eth_call result"},
+		{`null`, "unexpected eth_call result"},
+		{`"0x2a"`, "not a whole number of ABI words"},
+		{`"` + strings.Repeat("0", 64) + `"`, "missing 0x"},
+		{`"0x` + strings.Repeat("zz", 32) + `"`, "not hex"},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req rpc.Request
+			json.NewDecoder(r.Body).Decode(&req)
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, c.result)
+		}))
+		r := &Reader{Client: rpc.NewClient(), Endpoint: srv.URL, Contract: "0xabc"}
+		got, err := r.VoterVoteCount(context.Background(), "QmPeer")
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("result %s: VoterVoteCount() = %v, %v, want an error containing %q", c.result, got, err, c.want)
+		}
+		srv.Close()
+	}
+}
+
+func TestReader_ChainID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpc.Request
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method != "eth_chainId" {
+			t.Errorf("method = %s, want eth_chainId", req.Method)
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0xa7675"}`, req.ID)
+	}))
+	defer srv.Close()
+
+	r := &Reader{Client: rpc.NewClient(), Endpoint: srv.URL}
+	if got, err := r.ChainID(context.Background()); err != nil || got != 685685 {
+		t.Errorf("ChainID() = %d, %v; want 685685", got, err)
+	}
+}
+
+// ethCallServer answers every eth_call with result and records the call data
+func ethCallServer(t *testing.T, result string, gotData *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpc.Request
+		json.NewDecoder(r.Body).Decode(&req)
+		*gotData = req.Params[0].(map[string]interface{})["data"].(string)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x%s"}`, req.ID, result)
+	}))
+}
+
+func word(n int) string { return fmt.Sprintf("%064x", n) }
+
+func TestReader_EOA(t *testing.T) {
+	var gotData string
+	addr := "00000000000000000000000012345678901234567890abcdef12345678901234"
+	srv := ethCallServer(t, word(32)+word(1)+addr, &gotData)
+	defer srv.Close()
+
+	r := &Reader{Client: rpc.NewClient(), Endpoint: srv.URL, Contract: "0xabc"}
+	got, err := r.EOA(context.Background(), "Qm")
+	if err != nil || got != "0x12345678901234567890abcdef12345678901234" {
+		t.Errorf("EOA() = %q, %v", got, err)
+	}
+	if want := "0x" + selectorEOA + word(32) + word(1) + encodeString("Qm"); gotData != want {
+		t.Errorf("call data = %s, want %s", gotData, want)
+	}
+
+	unregistered := ethCallServer(t, word(32)+word(1)+word(0), &gotData)
+	defer unregistered.Close()
+	r.Endpoint = unregistered.URL
+	if got, err := r.EOA(context.Background(), "Qm"); err != nil || got != "" {
+		t.Errorf("EOA() of an unregistered peer = %q, %v; want none", got, err)
+	}
+}
+
+func TestReader_PeerIDs(t *testing.T) {
+	pad := func(s string) string {
+		return fmt.Sprintf("%x", s) + strings.Repeat("0", 64-2*len(s))
+	}
+	// [["QmA", "QmBB"]]
+	result := word(32) + word(1) + word(32) +
+		word(2) + word(64) + word(128) +
+		word(3) + pad("QmA") +
+		word(4) + pad("QmBB")
+	var gotData string
+	srv := ethCallServer(t, result, &gotData)
+	defer srv.Close()
+
+	r := &Reader{Client: rpc.NewClient(), Endpoint: srv.URL, Contract: "0xabc"}
+	got, err := r.PeerIDs(context.Background(), "0xABCDEF0123456789abcdef0123456789ABCDEF01")
+	if err != nil || len(got) != 2 || got[0] != "QmA" || got[1] != "QmBB" {
+		t.Errorf("PeerIDs() = %q, %v", got, err)
+	}
+	if !strings.HasSuffix(gotData, "000000000000000000000000abcdef0123456789abcdef0123456789abcdef01") {
+		t.Errorf("call data = %s, want the lower-cased address last", gotData)
+	}
+}
+
+func TestReader_UniqueVoters(t *testing.T) {
+	var gotData string
+	srv := ethCallServer(t, word(1234), &gotData)
+	defer srv.Close()
+
+	r := &Reader{Client: rpc.NewClient(), Endpoint: srv.URL, Contract: "0xabc"}
+	if got, err := r.UniqueVoters(context.Background()); err != nil || got != 1234 {
+		t.Errorf("UniqueVoters() = %d, %v; want 1234", got, err)
+	}
+	if gotData != "0x"+selectorUniqueVoters {
+		t.Errorf("call data = %s", gotData)
+	}
+}
+
+func TestReader_VoterLeaderboard(t *testing.T) {
+	pad := func(s string) string {
+		return fmt.Sprintf("%x", s) + strings.Repeat("0", 64-2*len(s))
+	}
+	// (["QmA", "QmBB"], [9, 7])
+	result := word(64) + word(288) +
+		word(2) + word(64) + word(128) +
+		word(3) + pad("QmA") +
+		word(4) + pad("QmBB") +
+		word(2) + word(9) + word(7)
+	var gotData string
+	srv := ethCallServer(t, result, &gotData)
+	defer srv.Close()
+
+	r := &Reader{Client: rpc.NewClient(), Endpoint: srv.URL, Contract: "0xabc"}
+	peers, votes, err := r.VoterLeaderboard(context.Background(), 10, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 || peers[0] != "QmA" || peers[1] != "QmBB" || votes[0].Int64() != 9 || votes[1].Int64() != 7 {
+		t.Errorf("VoterLeaderboard() = %q, %v", peers, votes)
+	}
+	if want := "0x" + selectorVoterLeaderboard + word(10) + word(12); gotData != want {
+		t.Errorf("call data = %s, want %s", gotData, want)
+	}
+}
+
+func TestReader_TotalRewards(t *testing.T) {
+	// [500, -3]
+	result := word(32) + word(2) + word(500) + strings.Repeat("f", 63) + "d"
+	var gotData string
+	srv := ethCallServer(t, result, &gotData)
+	defer srv.Close()
+
+	r := &Reader{Client: rpc.NewClient(), Endpoint: srv.URL, Contract: "0xabc"}
+	got, err := r.TotalRewards(context.Background(), []string{"QmA", "QmBB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Int64() != 500 || got[1].Int64() != -3 {
+		t.Errorf("TotalRewards() = %v, want [500 -3]", got)
+	}
+	wantArgs := encodeStrings([]string{"QmA", "QmBB"})
+	if gotData != "0x"+selectorTotalRewards+wantArgs {
+		t.Errorf("call data = %s", gotData)
+	}
+}
+
+func TestEncodeStrings(t *testing.T) {
+	pad := func(s string) string {
+		return fmt.Sprintf("%x", s) + strings.Repeat("0", 64-2*len(s))
+	}
+	got := encodeStrings([]string{"QmA", "QmBB"})
+	want := word(32) + word(2) + word(64) + word(128) + word(3) + pad("QmA") + word(4) + pad("QmBB")
+	if got != want {
+		t.Errorf("encodeStrings() =\n%s\nwant\n%s", got, want)
+	}
+}
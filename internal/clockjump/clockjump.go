@@ -0,0 +1,110 @@
+// Package clockjump notices jumps of the system clock. An NTP correction
+// moves the wall clock, and a VM pause or a suspend stops the monotonic
+// clock while the wall clock keeps going. Timers and tickers run on the
+// monotonic clock and are unaffected by the former, but times saved to
+// disk are wall clock times, so after a jump they can lie in the future.
+package clockjump
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Defaults for Watch
+const (
+	DefaultInterval  = time.Minute
+	DefaultThreshold = 2 * time.Minute
+)
+
+// Jump is a difference between the wall clock and the monotonic clock
+// that built up between two checks
+type Jump struct {
+	// Offset is how far the wall clock moved beyond the monotonic clock;
+	// negative when it went back
+	Offset time.Duration `json:"offset"`
+	At     time.Time     `json:"at"`
+}
+
+// String describes the jump, e.g. "forward by 3h0m0s"
+func (j Jump) String() string {
+	if j.Offset < 0 {
+		return fmt.Sprintf("back by %s", (-j.Offset).Round(time.Second))
+	}
+	return fmt.Sprintf("forward by %s", j.Offset.Round(time.Second))
+}
+
+// Detector compares the wall clock with the monotonic clock
+type Detector struct {
+	threshold time.Duration
+	last      time.Time
+}
+
+// NewDetector returns a detector reporting jumps larger than threshold
+func NewDetector(threshold time.Duration) *Detector {
+	return &Detector{threshold: threshold}
+}
+
+// Check reports the jump since the previous call, if it exceeds the
+// threshold. now must come from time.Now, which carries the monotonic
+// reading; the first call only takes a reading.
+func (d *Detector) Check(now time.Time) (Jump, bool) {
+	last := d.last
+	d.last = now
+	if last.IsZero() {
+		return Jump{}, false
+	}
+	return d.compare(now.Round(0).Sub(last.Round(0)), now.Sub(last), now)
+}
+
+// compare reports a jump when the wall clock and the monotonic clock
+// advanced by amounts further apart than the threshold
+func (d *Detector) compare(wall, monotonic time.Duration, now time.Time) (Jump, bool) {
+	offset := wall - monotonic
+	if offset < d.threshold && -offset < d.threshold {
+		return Jump{}, false
+	}
+	return Jump{Offset: offset, At: now}, true
+}
+
+// Watch checks the clock every interval until ctx is done, calling fn for
+// each jump larger than threshold
+func Watch(ctx context.Context, interval, threshold time.Duration, fn func(Jump)) {
+	d := NewDetector(threshold)
+	d.Check(time.Now())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if j, ok := d.Check(time.Now()); ok {
+				fn(j)
+			}
+		}
+	}
+}
+
+// Since is now - t, or 0 when t is in the future, which only a clock that
+// went back can cause for a time that has passed
+func Since(t, now time.Time) time.Duration {
+	if d := now.Sub(t); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Until is how long to wait for a saved deadline t, at most max: a
+// deadline set at most max ahead that is now further away was saved
+// before the clock went back
+func Until(t, now time.Time, max time.Duration) time.Duration {
+	d := t.Sub(now)
+	if d > max {
+		return max
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,72 @@
+package clockjump
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	d := NewDetector(time.Minute)
+	now := time.Now()
+	if _, ok := d.Check(now); ok {
+		t.Fatal("first Check reported a jump")
+	}
+	// times from time.Now carry the monotonic reading, which moves with
+	// the wall clock here
+	if j, ok := d.Check(now.Add(5 * time.Minute)); ok {
+		t.Errorf("steady clock reported %v", j)
+	}
+	// without monotonic readings there is nothing to compare against
+	if j, ok := d.Check(now.Add(3 * time.Hour).Round(0)); ok {
+		t.Errorf("wall clock only reported %v", j)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	d := NewDetector(time.Minute)
+	cases := []struct {
+		name            string
+		wall, monotonic time.Duration
+		want            string
+	}{
+		{"steady", time.Minute, time.Minute, ""},
+		// the wall clock went on for three hours the monotonic clock
+		// didn't see
+		{"VM resume", 3*time.Hour + time.Minute, time.Minute, "forward by 3h0m0s"},
+		{"NTP correction", -9 * time.Minute, time.Minute, "back by 10m0s"},
+		{"small correction", 90 * time.Second, time.Minute, ""},
+	}
+	for _, c := range cases {
+		j, ok := d.compare(c.wall, c.monotonic, time.Now())
+		got := ""
+		if ok {
+			got = j.String()
+		}
+		if got != c.want {
+			t.Errorf("%s: compare() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSinceUntil(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	if got := Since(now.Add(-time.Hour), now); got != time.Hour {
+		t.Errorf("Since(past) = %v", got)
+	}
+	if got := Since(now.Add(3*time.Hour), now); got != 0 {
+		t.Errorf("Since(future) = %v, want 0", got)
+	}
+	for _, c := range []struct {
+		deadline time.Time
+		want     time.Duration
+	}{
+		{now.Add(2 * time.Minute), 2 * time.Minute},
+		{now.Add(-time.Minute), 0},
+		// saved with the clock hours ahead
+		{now.Add(5 * time.Hour), 30 * time.Minute},
+	} {
+		if got := Until(c.deadline, now, 30*time.Minute); got != c.want {
+			t.Errorf("Until(%v) = %v, want %v", c.deadline, got, c.want)
+		}
+	}
+}
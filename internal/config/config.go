@@ -3,11 +3,11 @@
 package config
 
 import (
-	"flag"
 	"fmt"
 	"log"
 	"os"
 
+	"github.com/Deep-Commit/gswarm/internal/contracts"
 	"github.com/Deep-Commit/gswarm/internal/prompt"
 )
 
@@ -16,24 +16,8 @@ const (
 	DefaultPublicMaddr = "" // Empty by default, let Python pick OS address
 	DefaultPeerMaddr   = "/ip4/38.101.215.13/tcp/30002/p2p/QmQ2gEXoPJg6iMBSUFWGzAabS2VhnzuS782Y637hGjfsRJ"
 	DefaultHostMaddr   = "/ip4/0.0.0.0/tcp/38331"
-	SmallSwarmContract = "0x69C6e1D608ec64885E7b185d39b04B491a71768C"
-	BigSwarmContract   = "0x6947c6E196a48B77eFa9331EC1E3e45f3Ee5Fd58"
-)
-
-// Flag definitions - using consistent hyphen naming
-var (
-	TestnetFlag      = flag.Bool("testnet", false, "Connect to the Testnet?")
-	BigSwarmFlag     = flag.Bool("big-swarm", false, "Use big swarm (Math Hard)?")
-	ModelSizeFlag    = flag.String("model-size", "0.5", "Parameter count (0.5, 1.5, 7, 32, 72)")
-	HFTokenFlag      = flag.String("hf-token", "", "HuggingFace access token")
-	OrgIDFlag        = flag.String("org-id", "", "Modal ORG_ID (for testnet)")
-	IdentityPathFlag = flag.String("identity-path", "swarm.pem", "Path to identity PEM")
-	ContractAddrFlag = flag.String("contract-address", "", "Override smart‐contract address")
-	GameFlag         = flag.String("game", "", "Game type ('gsm8k' or 'dapo')")
-	ConfigPathFlag   = flag.String("config-path", "", "Path to YAML config file")
-	CPUOnlyFlag      = flag.Bool("cpu-only", false, "Force CPU-only mode")
-	RequirementsFlag = flag.String("requirements", "", "Requirements file (overrides default)")
-	ShowVersionFlag  = flag.Bool("version", false, "Show version information")
+	SmallSwarmContract = contracts.MathTestnet
+	BigSwarmContract   = contracts.MathHardTestnet
 )
 
 // Configuration holds all the settings for the RL Swarm
@@ -80,8 +64,9 @@ func GetConfigPath(paramB string, useBigSwarm bool) string {
 	}
 }
 
-// GetConfiguration builds a Configuration, prompting only for flags not in `visited`
-func GetConfiguration(visited map[string]bool) Configuration {
+// GetConfiguration builds a Configuration, taking the fields named in
+// `visited` from flags and prompting for the rest
+func GetConfiguration(flags Configuration, visited map[string]bool) Configuration {
 	cfg := Configuration{
 		PublicMaddr: DefaultPublicMaddr,
 		PeerMaddr:   DefaultPeerMaddr,
@@ -90,14 +75,14 @@ func GetConfiguration(visited map[string]bool) Configuration {
 
 	// -- Testnet?
 	if visited["testnet"] {
-		cfg.ConnectToTestnet = *TestnetFlag
+		cfg.ConnectToTestnet = flags.ConnectToTestnet
 	} else {
 		cfg.ConnectToTestnet = testPromptYesNo("Would you like to connect to the Testnet?", "Y")
 	}
 
 	// -- Big swarm?
 	if visited["big-swarm"] {
-		cfg.UseBigSwarm = *BigSwarmFlag
+		cfg.UseBigSwarm = flags.UseBigSwarm
 	} else {
 		choice := testPromptChoice(
 			"Which swarm would you like to join (Math (A) or Math Hard (B))?",
@@ -109,7 +94,7 @@ func GetConfiguration(visited map[string]bool) Configuration {
 
 	// -- Model size?
 	if visited["model-size"] {
-		cfg.ParamB = *ModelSizeFlag
+		cfg.ParamB = flags.ParamB
 	} else {
 		cfg.ParamB = testPromptUser(
 			"How many parameters (in billions)? [0.5,1.5,7,32,72]",
@@ -120,21 +105,21 @@ func GetConfiguration(visited map[string]bool) Configuration {
 
 	// -- Identity path
 	if visited["identity-path"] {
-		cfg.IdentityPath = *IdentityPathFlag
+		cfg.IdentityPath = flags.IdentityPath
 	} else {
 		cfg.IdentityPath = "swarm.pem"
 	}
 
 	// -- CPU-only?
 	if visited["cpu-only"] {
-		cfg.CPUOnly = *CPUOnlyFlag
+		cfg.CPUOnly = flags.CPUOnly
 	} else {
 		cfg.CPUOnly = false // This will be determined by isCPUOnly() in main
 	}
 
 	// -- Contract address
 	if visited["contract-address"] {
-		cfg.ContractAddress = *ContractAddrFlag
+		cfg.ContractAddress = flags.ContractAddress
 	} else {
 		if cfg.UseBigSwarm {
 			cfg.ContractAddress = BigSwarmContract
@@ -145,7 +130,7 @@ func GetConfiguration(visited map[string]bool) Configuration {
 
 	// -- Game type
 	if visited["game"] {
-		cfg.Game = *GameFlag
+		cfg.Game = flags.Game
 	} else {
 		if cfg.UseBigSwarm {
 			cfg.Game = "dapo"
@@ -156,21 +141,21 @@ func GetConfiguration(visited map[string]bool) Configuration {
 
 	// -- Config path
 	if visited["config-path"] {
-		cfg.ConfigPath = *ConfigPathFlag
+		cfg.ConfigPath = flags.ConfigPath
 	} else {
 		cfg.ConfigPath = GetConfigPath(cfg.ParamB, cfg.UseBigSwarm)
 	}
 
 	// -- HuggingFace token
 	if visited["hf-token"] {
-		cfg.HFToken = *HFTokenFlag
+		cfg.HFToken = flags.HFToken
 	} else {
 		cfg.HFToken = testPromptHFToken()
 	}
 
 	// -- Org ID
 	if visited["org-id"] {
-		cfg.OrgID = *OrgIDFlag
+		cfg.OrgID = flags.OrgID
 		if cfg.OrgID != "" {
 			cfg.ConnectToTestnet = true
 		}
@@ -178,7 +163,7 @@ func GetConfiguration(visited map[string]bool) Configuration {
 
 	// -- Requirements override
 	if visited["requirements"] {
-		cfg.RequirementsFile = *RequirementsFlag
+		cfg.RequirementsFile = flags.RequirementsFile
 	}
 
 	return cfg
@@ -1,7 +1,6 @@
 package config
 
 import (
-	"flag"
 	"testing"
 )
 
@@ -90,22 +89,6 @@ func TestGetConfiguration_FlagOverrides(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			// Reset flags for each test case
-			flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
-
-			// Re-declare package-level flags for this test case
-			TestnetFlag = flag.Bool("testnet", false, "Connect to the Testnet?")
-			BigSwarmFlag = flag.Bool("big-swarm", false, "Use big swarm (Math Hard)?")
-			ModelSizeFlag = flag.String("model-size", "0.5", "Parameter count (0.5, 1.5, 7, 32, 72)")
-			HFTokenFlag = flag.String("hf-token", "", "HuggingFace access token")
-			OrgIDFlag = flag.String("org-id", "", "Modal ORG_ID (for testnet)")
-			IdentityPathFlag = flag.String("identity-path", "swarm.pem", "Path to identity PEM")
-			ContractAddrFlag = flag.String("contract-address", "", "Override smart‐contract address")
-			GameFlag = flag.String("game", "", "Game type ('gsm8k' or 'dapo')")
-			ConfigPathFlag = flag.String("config-path", "", "Path to YAML config file")
-			CPUOnlyFlag = flag.Bool("cpu-only", false, "Force CPU-only mode")
-			RequirementsFlag = flag.String("requirements", "", "Requirements file (overrides default)")
-
 			// Override testable prompt functions to return predictable values
 			testPromptHFToken = func() string { return "None" }
 			testPromptYesNo = func(_ string, _ string) bool { return false }
@@ -114,7 +97,7 @@ func TestGetConfiguration_FlagOverrides(t *testing.T) {
 			}
 			testPromptUser = func(_ string, defaultValue string, _ []string) string { return defaultValue }
 
-			got := GetConfiguration(c.visited)
+			got := GetConfiguration(Configuration{}, c.visited)
 
 			// Compare key fields
 			if got.ConnectToTestnet != c.want.ConnectToTestnet {
@@ -140,20 +123,15 @@ func TestGetConfiguration_FlagOverrides(t *testing.T) {
 }
 
 func TestGetConfiguration_OrgIDOverridesTestnet(t *testing.T) {
-	// Reset flags
-	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
-	OrgIDFlag = flag.String("org-id", "", "Modal ORG_ID (for testnet)")
-
 	// Override testable prompt functions
 	origTestPromptHFToken := testPromptHFToken
 	defer func() { testPromptHFToken = origTestPromptHFToken }()
 	testPromptHFToken = func() string { return "None" }
 
 	// Test that setting org-id forces testnet to true
-	*OrgIDFlag = "test-org"
 	visited := map[string]bool{"org-id": true}
 
-	cfg := GetConfiguration(visited)
+	cfg := GetConfiguration(Configuration{OrgID: "test-org"}, visited)
 	if !cfg.ConnectToTestnet {
 		t.Error("Setting org-id should force ConnectToTestnet to true")
 	}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Deep-Commit/gswarm/internal/contracts"
+)
+
+// DefaultConfigFile is the gswarm config file loaded when present
+const DefaultConfigFile = "gswarm.json"
+
+// essentialEnv lists host variables that are always passed to the trainer,
+// even in allowlist mode, since Python and CUDA can't work without them.
+// They match case-insensitively, as Windows spells SYSTEMROOT SystemRoot.
+var essentialEnv = []string{
+	"PATH", "HOME", "USER", "LANG", "LC_ALL", "TERM", "TMPDIR", "SHELL", "VIRTUAL_ENV",
+	// CUDA libraries outside the default loader path, and the GPUs to use
+	"LD_LIBRARY_PATH", "CUDA_HOME", "CUDA_VISIBLE_DEVICES",
+	// Python on Windows can't load its DLLs without it
+	"SYSTEMROOT",
+}
+
+// File is the on-disk gswarm configuration
+type File struct {
+	// Env holds variables injected into the training process
+	Env map[string]string `json:"env,omitempty"`
+	// EnvAllowlist restricts which host variables reach the training
+	// process; entries ending in "*" match by prefix. Empty inherits all.
+	EnvAllowlist []string `json:"envAllowlist,omitempty"`
+	// PauseWindows are times training is paused, e.g. "08:00-18:00 weekdays"
+	PauseWindows []string `json:"pauseWindows,omitempty"`
+	// Profiles are named node variants selected with --profile
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+	// Contracts adds or replaces coordinator contracts in the built-in registry
+	Contracts []contracts.Contract `json:"contracts,omitempty"`
+	// Timezone is the IANA timezone for message, report and log times
+	Timezone string `json:"timezone,omitempty"`
+	// TimeFormat is default, rfc3339, us, eu or a Go time layout
+	TimeFormat string `json:"timeFormat,omitempty"`
+	// ExtraTrainArgs are appended verbatim to the trainer command line
+	ExtraTrainArgs []string `json:"extraTrainArgs,omitempty"`
+	// EOA is the wallet address the monitor watches when --eoa isn't given
+	EOA string `json:"eoa,omitempty"`
+	// LogRetention is the disk budget for logs and their archives, e.g. "2GB"
+	LogRetention string `json:"logRetention,omitempty"`
+}
+
+// LoadFile reads a gswarm config file
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// ChildEnv builds the training process environment from the host
+// environment, the supervisor-provided variables and the configured
+// injections, in increasing order of precedence
+func (f *File) ChildEnv(host []string, supervisor []string) []string {
+	var env []string
+	for _, kv := range host {
+		key, _, _ := strings.Cut(kv, "=")
+		if f.allowed(key) {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, supervisor...)
+
+	// Sort injected keys so the resulting environment is deterministic
+	keys := make([]string, 0, len(f.Env))
+	for k := range f.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+f.Env[k])
+	}
+	return env
+}
+
+// TrainArgs builds the extra trainer arguments: the config file's
+// extraTrainArgs, then each --train-arg value, so the flags win for
+// arguments given twice. A key=value flag becomes --key=value and a bare
+// key becomes --key.
+func (f *File) TrainArgs(flagArgs []string) ([]string, error) {
+	args := append([]string{}, f.ExtraTrainArgs...)
+	for _, arg := range flagArgs {
+		key, value, hasValue := strings.Cut(arg, "=")
+		key = strings.TrimLeft(key, "-")
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("invalid train argument %q: expected key=value", arg)
+		}
+		if hasValue {
+			args = append(args, "--"+key+"="+value)
+		} else {
+			args = append(args, "--"+key)
+		}
+	}
+	return args, nil
+}
+
+// allowed reports whether a host variable passes the allowlist
+func (f *File) allowed(key string) bool {
+	if len(f.EnvAllowlist) == 0 {
+		return true
+	}
+	for _, e := range essentialEnv {
+		if strings.EqualFold(key, e) {
+			return true
+		}
+	}
+	for _, pattern := range f.EnvAllowlist {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	content := `{"env": {"HF_HUB_OFFLINE": "1"}, "envAllowlist": ["TORCH_*"]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if f.Env["HF_HUB_OFFLINE"] != "1" {
+		t.Errorf("Env[HF_HUB_OFFLINE] = %q, want 1", f.Env["HF_HUB_OFFLINE"])
+	}
+	if !reflect.DeepEqual(f.EnvAllowlist, []string{"TORCH_*"}) {
+		t.Errorf("EnvAllowlist = %v, want [TORCH_*]", f.EnvAllowlist)
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() expected error for invalid JSON")
+	}
+}
+
+func TestFile_ChildEnv(t *testing.T) {
+	host := []string{"PATH=/usr/bin", "TORCH_HOME=/cache", "AWS_SECRET_ACCESS_KEY=x", "WANDB_API_KEY=old"}
+	supervisor := []string{"IDENTITY_PATH=swarm.pem"}
+
+	cases := []struct {
+		name string
+		file File
+		want []string
+	}{
+		{
+			name: "inherit everything",
+			file: File{},
+			want: []string{"PATH=/usr/bin", "TORCH_HOME=/cache", "AWS_SECRET_ACCESS_KEY=x", "WANDB_API_KEY=old", "IDENTITY_PATH=swarm.pem"},
+		},
+		{
+			name: "allowlist with prefix",
+			file: File{EnvAllowlist: []string{"TORCH_*"}},
+			want: []string{"PATH=/usr/bin", "TORCH_HOME=/cache", "IDENTITY_PATH=swarm.pem"},
+		},
+		{
+			name: "injection overrides host",
+			file: File{EnvAllowlist: []string{"WANDB_API_KEY"}, Env: map[string]string{"WANDB_API_KEY": "new", "HF_HUB_OFFLINE": "1"}},
+			want: []string{"PATH=/usr/bin", "WANDB_API_KEY=old", "IDENTITY_PATH=swarm.pem", "HF_HUB_OFFLINE=1", "WANDB_API_KEY=new"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.file.ChildEnv(host, supervisor)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("ChildEnv() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFile_ChildEnvEssentials(t *testing.T) {
+	host := []string{"LD_LIBRARY_PATH=/usr/local/cuda/lib64", "CUDA_HOME=/usr/local/cuda", "CUDA_VISIBLE_DEVICES=1", "SystemRoot=C:\\Windows", "AWS_SECRET_ACCESS_KEY=x"}
+	f := File{EnvAllowlist: []string{"TORCH_*"}}
+	want := []string{"LD_LIBRARY_PATH=/usr/local/cuda/lib64", "CUDA_HOME=/usr/local/cuda", "CUDA_VISIBLE_DEVICES=1", "SystemRoot=C:\\Windows"}
+	if got := f.ChildEnv(host, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChildEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestFile_TrainArgs(t *testing.T) {
+	f := File{ExtraTrainArgs: []string{"--num_train_samples", "4"}}
+	got, err := f.TrainArgs([]string{"max_rounds=100", "--dht_timeout=30", "use_vllm", "prompt=a=b"})
+	if err != nil {
+		t.Fatalf("TrainArgs() error = %v", err)
+	}
+	want := []string{"--num_train_samples", "4", "--max_rounds=100", "--dht_timeout=30", "--use_vllm", "--prompt=a=b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TrainArgs() = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"=1", "--", "bad key=1"} {
+		if _, err := f.TrainArgs([]string{bad}); err == nil {
+			t.Errorf("TrainArgs(%q) error = nil, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile is a named set of overrides for running one node variant
+type Profile struct {
+	IdentityPath       string `json:"identityPath,omitempty"`
+	ModelSize          string `json:"modelSize,omitempty"`
+	StateDir           string `json:"stateDir,omitempty"`
+	TelegramConfigPath string `json:"telegramConfigPath,omitempty"`
+	// TelegramChatID sends this profile's notifications to a different chat
+	// than the one in the Telegram config
+	TelegramChatID string `json:"telegramChatId,omitempty"`
+	// Env is merged over the file-level env
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// Flags returns the profile's settings keyed by CLI flag name, omitting
+// unset values
+func (p Profile) Flags() map[string]string {
+	flags := map[string]string{}
+	for name, v := range map[string]string{
+		"identity-path":        p.IdentityPath,
+		"model-size":           p.ModelSize,
+		"state-dir":            p.StateDir,
+		"telegram-config-path": p.TelegramConfigPath,
+	} {
+		if v != "" {
+			flags[name] = v
+		}
+	}
+	return flags
+}
+
+// ApplyProfile selects the named profile, merging its env into the file
+func (f *File) ApplyProfile(name string) (*Profile, error) {
+	p, ok := f.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(f.Profiles))
+		for n := range f.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("profile %q not found: the config file defines no profiles", name)
+		}
+		return nil, fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(names, ", "))
+	}
+
+	if len(p.Env) > 0 {
+		env := make(map[string]string, len(f.Env)+len(p.Env))
+		for k, v := range f.Env {
+			env[k] = v
+		}
+		for k, v := range p.Env {
+			env[k] = v
+		}
+		f.Env = env
+	}
+	return &p, nil
+}
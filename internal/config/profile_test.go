@@ -0,0 +1,35 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFile_ApplyProfile(t *testing.T) {
+	f := &File{
+		Env: map[string]string{"A": "1", "B": "2"},
+		Profiles: map[string]Profile{
+			"big-gpu-a": {IdentityPath: "a.pem", ModelSize: "72", Env: map[string]string{"B": "3"}},
+			"small-cpu": {ModelSize: "0.5"},
+		},
+	}
+
+	p, err := f.ApplyProfile("big-gpu-a")
+	if err != nil {
+		t.Fatalf("ApplyProfile() error = %v", err)
+	}
+	wantFlags := map[string]string{"identity-path": "a.pem", "model-size": "72"}
+	if got := p.Flags(); !reflect.DeepEqual(got, wantFlags) {
+		t.Errorf("Flags() = %v, want %v", got, wantFlags)
+	}
+	wantEnv := map[string]string{"A": "1", "B": "3"}
+	if !reflect.DeepEqual(f.Env, wantEnv) {
+		t.Errorf("Env after ApplyProfile() = %v, want %v", f.Env, wantEnv)
+	}
+
+	_, err = f.ApplyProfile("missing")
+	if err == nil || !strings.Contains(err.Error(), "big-gpu-a, small-cpu") {
+		t.Errorf("ApplyProfile(missing) error = %v, want it to list available profiles", err)
+	}
+}
@@ -0,0 +1,481 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/logarchive"
+	"github.com/Deep-Commit/gswarm/internal/schedule"
+	"github.com/Deep-Commit/gswarm/internal/timefmt"
+)
+
+// Schema is the JSON Schema for the config file, exported with
+// gswarm config schema
+//
+//go:embed schema.json
+var Schema []byte
+
+// ValidationError is a problem found in a config file, located by line and
+// column (both 1-based) and by the JSON path of the offending value
+type ValidationError struct {
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// ValidateFile checks the config file at path against Schema and the
+// checks gswarm applies when loading it. The error is for failing to read
+// the file or the schema, not for problems in the file.
+func ValidateFile(path string) ([]ValidationError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(data)
+}
+
+// Validate checks a config file's contents, returning the problems in the
+// order they appear. The error is only for an invalid embedded schema.
+func Validate(data []byte) ([]ValidationError, error) {
+	s, err := loadSchema()
+	if err != nil {
+		return nil, err
+	}
+	root, perr := parseNode(data)
+	if perr != nil {
+		return []ValidationError{*perr}, nil
+	}
+	v := &validator{data: data, root: s}
+	v.check(s, root, "")
+	v.checkValues(root)
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		if v.errs[i].Line != v.errs[j].Line {
+			return v.errs[i].Line < v.errs[j].Line
+		}
+		return v.errs[i].Column < v.errs[j].Column
+	})
+	return v.errs, nil
+}
+
+// schema is the subset of JSON Schema used by schema.json
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *additional        `json:"additionalProperties"`
+	PropertyNames        *schema            `json:"propertyNames"`
+	Items                *schema            `json:"items"`
+	Required             []string           `json:"required"`
+	Enum                 []string           `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            int                `json:"minLength"`
+	Minimum              *float64           `json:"minimum"`
+	Defs                 map[string]*schema `json:"$defs"`
+}
+
+// additional is additionalProperties: false, or a schema for the values
+type additional struct {
+	Allowed bool
+	Schema  *schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+func loadSchema() (*schema, error) {
+	var s schema
+	if err := json.Unmarshal(Schema, &s); err != nil {
+		return nil, fmt.Errorf("invalid embedded schema: %w", err)
+	}
+	if err := checkPatterns(&s); err != nil {
+		return nil, fmt.Errorf("invalid embedded schema: %w", err)
+	}
+	return &s, nil
+}
+
+// checkPatterns compiles every pattern in s, so validating a file can't
+// fail on one
+func checkPatterns(s *schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return err
+		}
+	}
+	subs := []*schema{s.PropertyNames, s.Items}
+	if s.AdditionalProperties != nil {
+		subs = append(subs, s.AdditionalProperties.Schema)
+	}
+	for _, m := range []map[string]*schema{s.Properties, s.Defs} {
+		for _, sub := range m {
+			subs = append(subs, sub)
+		}
+	}
+	for _, sub := range subs {
+		if err := checkPatterns(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// node is a parsed JSON value with its position in the file
+type node struct {
+	offset int
+	// value is a string, json.Number, bool or nil for scalars
+	value interface{}
+	kind  string
+	// keys holds object keys in file order
+	keys       []string
+	keyOffsets map[string]int
+	fields     map[string]*node
+	items      []*node
+}
+
+// parser builds nodes with json.Decoder, which reports where each token
+// ends; the start of the next value is found by skipping the separators
+type parser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+func parseNode(data []byte) (*node, *ValidationError) {
+	p := &parser{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+	n, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if n.kind != "object" {
+		e := p.errorAt(n.offset, "", "the config must be a JSON object")
+		return nil, &e
+	}
+	if off := p.skip(int(p.dec.InputOffset())); off < len(data) {
+		e := p.errorAt(off, "", "unexpected data after the config object")
+		return nil, &e
+	}
+	return n, nil
+}
+
+func (p *parser) value() (*node, *ValidationError) {
+	start := p.skip(int(p.dec.InputOffset()))
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, p.syntaxError(start, err)
+	}
+	n := &node{offset: start}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			n.kind = "object"
+			n.fields = map[string]*node{}
+			n.keyOffsets = map[string]int{}
+			for p.dec.More() {
+				keyStart := p.skip(int(p.dec.InputOffset()))
+				tok, err := p.dec.Token()
+				if err != nil {
+					return nil, p.syntaxError(keyStart, err)
+				}
+				key, _ := tok.(string)
+				child, verr := p.value()
+				if verr != nil {
+					return nil, verr
+				}
+				if _, dup := n.fields[key]; dup {
+					e := p.errorAt(keyStart, "", fmt.Sprintf("duplicate key %q", key))
+					return nil, &e
+				}
+				n.keys = append(n.keys, key)
+				n.keyOffsets[key] = keyStart
+				n.fields[key] = child
+			}
+		} else {
+			n.kind = "array"
+			for p.dec.More() {
+				child, verr := p.value()
+				if verr != nil {
+					return nil, verr
+				}
+				n.items = append(n.items, child)
+			}
+		}
+		end := p.skip(int(p.dec.InputOffset()))
+		if _, err := p.dec.Token(); err != nil {
+			return nil, p.syntaxError(end, err)
+		}
+	case string:
+		n.kind, n.value = "string", t
+	case json.Number:
+		n.kind, n.value = "number", t
+	case bool:
+		n.kind, n.value = "boolean", t
+	default:
+		n.kind = "null"
+	}
+	return n, nil
+}
+
+// skip returns the offset of the next token at or after off
+func (p *parser) skip(off int) int {
+	for off < len(p.data) && strings.IndexByte(" \t\r\n,:", p.data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+func (p *parser) syntaxError(off int, err error) *ValidationError {
+	var serr *json.SyntaxError
+	if errors.As(err, &serr) {
+		off = int(serr.Offset)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || off >= len(p.data) {
+		e := p.errorAt(len(p.data), "", "unexpected end of file")
+		return &e
+	}
+	if serr != nil && off > 0 {
+		// Offset is just past the offending byte
+		off--
+	}
+	e := p.errorAt(off, "", "invalid JSON: "+err.Error())
+	return &e
+}
+
+func (p *parser) errorAt(off int, path, msg string) ValidationError {
+	line, col := position(p.data, off)
+	return ValidationError{Line: line, Column: col, Path: path, Message: msg}
+}
+
+// position converts a byte offset into a 1-based line and column
+func position(data []byte, off int) (int, int) {
+	if off > len(data) {
+		off = len(data)
+	}
+	line, col := 1, 1
+	for _, b := range data[:off] {
+		if b == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+type validator struct {
+	data []byte
+	root *schema
+	errs []ValidationError
+}
+
+func (v *validator) errorf(off int, path, format string, args ...interface{}) {
+	line, col := position(v.data, off)
+	v.errs = append(v.errs, ValidationError{Line: line, Column: col, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) resolve(s *schema) *schema {
+	for s.Ref != "" {
+		s = v.root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+	}
+	return s
+}
+
+// check validates n against s, recording every problem found
+func (v *validator) check(s *schema, n *node, path string) {
+	s = v.resolve(s)
+	if s.Type != "" && !hasType(n, s.Type) {
+		v.errorf(n.offset, path, "expected %s, got %s", article(s.Type), article(n.kind))
+		return
+	}
+	if len(s.Enum) > 0 {
+		str, _ := n.value.(string)
+		if n.kind != "string" || !contains(s.Enum, str) {
+			v.errorf(n.offset, path, "must be one of %s", quoteList(s.Enum))
+			return
+		}
+	}
+
+	switch n.kind {
+	case "string":
+		str := n.value.(string)
+		if len([]rune(str)) < s.MinLength {
+			v.errorf(n.offset, path, "must not be empty")
+		} else if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			v.errorf(n.offset, path, "%q does not match %s", str, s.Pattern)
+		}
+	case "number":
+		if f, err := n.value.(json.Number).Float64(); err == nil && s.Minimum != nil && f < *s.Minimum {
+			v.errorf(n.offset, path, "must be at least %v", *s.Minimum)
+		}
+	case "array":
+		if s.Items != nil {
+			for i, item := range n.items {
+				v.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case "object":
+		v.checkObject(s, n, path)
+	}
+}
+
+func (v *validator) checkObject(s *schema, n *node, path string) {
+	for _, name := range s.Required {
+		if _, ok := n.fields[name]; !ok {
+			v.errorf(n.offset, path, "missing required property %q", name)
+		}
+	}
+	for _, key := range n.keys {
+		child := n.fields[key]
+		childPath := joinPath(path, key)
+		if s.PropertyNames != nil && s.PropertyNames.Pattern != "" && !regexp.MustCompile(s.PropertyNames.Pattern).MatchString(key) {
+			v.errorf(n.keyOffsets[key], childPath, "invalid key %q", key)
+		}
+		if prop, ok := s.Properties[key]; ok {
+			v.check(prop, child, childPath)
+			continue
+		}
+		switch {
+		case s.AdditionalProperties == nil:
+		case s.AdditionalProperties.Schema != nil:
+			v.check(s.AdditionalProperties.Schema, child, childPath)
+		case !s.AdditionalProperties.Allowed:
+			msg := fmt.Sprintf("unknown property %q", key)
+			if guess := closest(key, s.Properties); guess != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", guess)
+			}
+			v.errorf(n.keyOffsets[key], childPath, "%s", msg)
+		}
+	}
+}
+
+// checkValues applies the checks the schema can't express, using the same
+// parsers gswarm uses at startup
+func (v *validator) checkValues(root *node) {
+	if n := root.fields["timezone"]; n != nil && n.kind == "string" && n.value != "" {
+		if _, err := time.LoadLocation(n.value.(string)); err != nil {
+			v.errorf(n.offset, "timezone", "unknown timezone %q", n.value)
+		}
+	}
+	if n := root.fields["timeFormat"]; n != nil && n.kind == "string" && n.value != "" {
+		if err := timefmt.CheckFormat(n.value.(string)); err != nil {
+			v.errorf(n.offset, "timeFormat", "%v", err)
+		}
+	}
+	if n := root.fields["logRetention"]; n != nil && n.kind == "string" && n.value != "" {
+		if _, err := logarchive.ParseSize(n.value.(string)); err != nil {
+			v.errorf(n.offset, "logRetention", "%v", err)
+		}
+	}
+	if n := root.fields["pauseWindows"]; n != nil {
+		for i, item := range n.items {
+			if item.kind != "string" || item.value == "" {
+				continue
+			}
+			if _, err := schedule.ParseWindow(item.value.(string)); err != nil {
+				v.errorf(item.offset, fmt.Sprintf("pauseWindows[%d]", i), "%v", err)
+			}
+		}
+	}
+}
+
+func hasType(n *node, want string) bool {
+	switch want {
+	case "integer":
+		return n.kind == "number" && !strings.ContainsAny(string(n.value.(json.Number)), ".eE")
+	default:
+		return n.kind == want
+	}
+}
+
+func article(kind string) string {
+	switch kind {
+	case "object", "array", "integer":
+		return "an " + kind
+	case "null":
+		return kind
+	}
+	return "a " + kind
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteList(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// closest returns the known property nearest to key, for typos, or "" if
+// none is close
+func closest(key string, props map[string]*schema) string {
+	best, bestDist := "", 3
+	for name := range props {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+		if d := distance(strings.ToLower(key), strings.ToLower(name)); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Deep-Commit/gswarm/internal/contracts"
+)
+
+func TestValidate_Valid(t *testing.T) {
+	content := `{
+  "$schema": "./gswarm.schema.json",
+  "env": {"HF_HUB_OFFLINE": "1"},
+  "envAllowlist": ["TORCH_*", "WANDB_API_KEY"],
+  "pauseWindows": ["08:00-18:00 weekdays"],
+  "profiles": {"gpu1": {"modelSize": "7", "telegramChatId": "-100123"}},
+  "contracts": [{"swarm": "math", "chainId": 685685, "address": "0x69C6e1D608ec64885E7b185d39b04B491a71768C"}],
+  "timezone": "Europe/Berlin",
+  "timeFormat": "rfc3339",
+  "eoa": "0x6947c6E196a48B77eFa9331EC1E3e45f3Ee5Fd58",
+  "logRetention": "2GB"
+}`
+	if errs := validate(t, []byte(content)); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+}
+
+func TestValidate_LogRetention(t *testing.T) {
+	errs := validate(t, []byte(`{"logRetention": "lots"}`))
+	if len(errs) != 1 || errs[0].Path != "logRetention" {
+		t.Errorf("Validate() = %v, want an invalid logRetention", errs)
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	content := `{
+  "pauseWindow": ["08:00-18:00"],
+  "profiles": {
+    "gpu1": {"modelSize": 7}
+  },
+  "contracts": [
+    {"swarm": "math", "address": "0x123"}
+  ],
+  "timezone": "Mars/Olympus",
+  "pauseWindows": ["25:00-26:00"]
+}`
+	want := []string{
+		`2:3: pauseWindow: unknown property "pauseWindow" (did you mean "pauseWindows"?)`,
+		`4:27: profiles.gpu1.modelSize: must be one of "0.5", "1.5", "7", "32", "72"`,
+		`7:5: contracts[0]: missing required property "chainId"`,
+		`7:34: contracts[0].address: "0x123" does not match ^0x[0-9a-fA-F]{40}$`,
+		`9:15: timezone: unknown timezone "Mars/Olympus"`,
+	}
+	errs := validate(t, []byte(content))
+	if len(errs) != len(want)+1 {
+		t.Fatalf("Validate() = %v, want %d errors", errs, len(want)+1)
+	}
+	for i, w := range want {
+		if got := errs[i].Error(); got != w {
+			t.Errorf("error %d = %q, want %q", i, got, w)
+		}
+	}
+	if last := errs[len(want)]; last.Line != 10 || last.Path != "pauseWindows[0]" {
+		t.Errorf("last error = %v, want the pause window on line 10", last)
+	}
+}
+
+func TestValidate_Syntax(t *testing.T) {
+	cases := []struct {
+		content string
+		want    string
+	}{
+		{"{\n  \"env\": {\"A\": \"1\",}\n}", "2:19: invalid JSON"},
+		{`{"eoa": "0x1", "eoa": "0x2"}`, `1:16: duplicate key "eoa"`},
+		{`["not", "an", "object"]`, "1:1: the config must be a JSON object"},
+		{"{\"env\": {}", "1:11: unexpected end of file"},
+		{`{"timezone": 1.5}`, "1:14: timezone: expected a string, got a number"},
+	}
+	for _, c := range cases {
+		errs := validate(t, []byte(c.content))
+		if len(errs) == 0 || !strings.HasPrefix(errs[0].Error(), c.want) {
+			t.Errorf("Validate(%q) = %v, want an error starting %q", c.content, errs, c.want)
+		}
+	}
+}
+
+func TestValidate_BadSchema(t *testing.T) {
+	saved := Schema
+	defer func() { Schema = saved }()
+	for _, bad := range []string{`{"type": `, `{"properties": {"eoa": {"pattern": "["}}}`} {
+		Schema = []byte(bad)
+		if _, err := Validate([]byte(`{}`)); err == nil {
+			t.Errorf("Validate() with schema %s error = nil, want an error", bad)
+		}
+	}
+}
+
+// validate is Validate for a schema that must load
+func validate(t *testing.T, data []byte) []ValidationError {
+	t.Helper()
+	errs, err := Validate(data)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	return errs
+}
+
+// TestSchema_CoversFile keeps schema.json in step with File, Profile and
+// the contract registry
+func TestSchema_CoversFile(t *testing.T) {
+	s, err := loadSchema()
+	if err != nil {
+		t.Fatalf("loadSchema() error = %v", err)
+	}
+	for typ, props := range map[reflect.Type]map[string]*schema{
+		reflect.TypeOf(File{}):               s.Properties,
+		reflect.TypeOf(Profile{}):            s.Defs["profile"].Properties,
+		reflect.TypeOf(contracts.Contract{}): s.Defs["contract"].Properties,
+	} {
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if _, ok := props[name]; !ok {
+				t.Errorf("schema is missing %s.%s (%q)", typ.Name(), typ.Field(i).Name, name)
+			}
+		}
+	}
+	if swarms := s.Defs["contract"].Properties["swarm"].Enum; !reflect.DeepEqual(swarms, []string{contracts.SwarmMath, contracts.SwarmMathHard}) {
+		t.Errorf("swarm enum = %v", swarms)
+	}
+	if !json.Valid(Schema) {
+		t.Error("Schema is not valid JSON")
+	}
+}
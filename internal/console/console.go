@@ -0,0 +1,163 @@
+// Package console prints leveled, colored messages to the terminal.
+// --quiet shows only errors, the default adds key events, warnings and
+// successes, and --verbose adds debug detail such as raw API responses.
+// Secrets registered with the redact package are masked.
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Deep-Commit/gswarm/internal/redact"
+)
+
+// Level controls how much is printed
+type Level int
+
+const (
+	// Quiet prints errors only
+	Quiet Level = iota
+	// Normal prints key events, warnings and errors
+	Normal
+	// Verbose also prints debug detail
+	Verbose
+)
+
+// ANSI colors for each kind of message
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorGray   = "\033[90m"
+)
+
+var (
+	mu     sync.Mutex
+	level            = Normal
+	color            = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// levelNames are the names levels are given by, e.g. in the status API
+var levelNames = []string{Quiet: "quiet", Normal: "normal", Verbose: "verbose"}
+
+func (l Level) String() string {
+	if l >= 0 && int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return fmt.Sprintf("level %d", int(l))
+}
+
+// ParseLevel returns the level named quiet, normal or verbose
+func ParseLevel(name string) (Level, error) {
+	for l, n := range levelNames {
+		if n == name {
+			return Level(l), nil
+		}
+	}
+	return Normal, fmt.Errorf("unknown log level %q: want quiet, normal or verbose", name)
+}
+
+// SetLevel sets the minimum level that is printed
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+}
+
+// CurrentLevel returns the level set last
+func CurrentLevel() Level {
+	mu.Lock()
+	defer mu.Unlock()
+	return level
+}
+
+// SetColor turns ANSI colors on or off
+func SetColor(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	color = on
+}
+
+// SetOutput redirects normal output to out and errors to errOut
+func SetOutput(out, errOut io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	stdout, stderr = out, errOut
+}
+
+// Enabled reports whether messages at l are printed
+func Enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return level >= l
+}
+
+// Out returns the writer for pass-through output such as the trainer's,
+// which is discarded in quiet mode
+func Out() io.Writer {
+	if !Enabled(Normal) {
+		return io.Discard
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return stdout
+}
+
+// Debugf prints detail that is only useful when troubleshooting
+func Debugf(format string, args ...interface{}) {
+	emit(Verbose, false, colorGray, format, args...)
+}
+
+// Infof prints a key event
+func Infof(format string, args ...interface{}) {
+	emit(Normal, false, "", format, args...)
+}
+
+// Info prints a key event, formatting its operands like fmt.Println
+func Info(args ...interface{}) {
+	emit(Normal, false, "", "%s", strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+// Successf prints a completed step in green
+func Successf(format string, args ...interface{}) {
+	emit(Normal, false, colorGreen, format, args...)
+}
+
+// Warnf prints a warning in yellow, prefixed with "Warning: "
+func Warnf(format string, args ...interface{}) {
+	emit(Normal, false, colorYellow, "Warning: "+format, args...)
+}
+
+// Errorf prints an error in red to stderr; errors are shown at every level
+func Errorf(format string, args ...interface{}) {
+	emit(Quiet, true, colorRed, format, args...)
+}
+
+func emit(l Level, toStderr bool, code, format string, args ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	if level < l {
+		return
+	}
+	msg := redact.String(strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+	if color && code != "" {
+		msg = code + msg + colorReset
+	}
+	w := stdout
+	if toStderr {
+		w = stderr
+	}
+	fmt.Fprintln(w, msg)
+}
+
+// isTerminal reports whether f is a character device such as a TTY
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
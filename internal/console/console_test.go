@@ -0,0 +1,84 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	defer SetOutput(stdout, stderr)
+	defer SetLevel(level)
+	defer SetColor(color)
+	SetColor(false)
+
+	cases := []struct {
+		level   Level
+		wantOut string
+		wantErr string
+	}{
+		{Quiet, "", "boom\n"},
+		{Normal, "started 1\nWarning: slow\ndone\n", "boom\n"},
+		{Verbose, "response {}\nstarted 1\nWarning: slow\ndone\n", "boom\n"},
+	}
+	for _, c := range cases {
+		var out, errOut bytes.Buffer
+		SetOutput(&out, &errOut)
+		SetLevel(c.level)
+
+		Debugf("response %s", "{}")
+		Infof("started %d\n", 1)
+		Warnf("slow")
+		Successf("done")
+		Errorf("boom")
+
+		if out.String() != c.wantOut {
+			t.Errorf("level %d stdout = %q, want %q", c.level, out.String(), c.wantOut)
+		}
+		if errOut.String() != c.wantErr {
+			t.Errorf("level %d stderr = %q, want %q", c.level, errOut.String(), c.wantErr)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	defer SetOutput(stdout, stderr)
+	defer SetLevel(level)
+	defer SetColor(color)
+
+	var out bytes.Buffer
+	SetOutput(&out, io.Discard)
+	SetLevel(Normal)
+	SetColor(true)
+
+	Warnf("disk %d%% full", 95)
+	Info("plain", 1)
+	want := colorYellow + "Warning: disk 95% full" + colorReset + "\nplain 1\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestOut(t *testing.T) {
+	defer SetLevel(level)
+
+	SetLevel(Quiet)
+	if Out() != io.Discard {
+		t.Error("Out() should discard pass-through output in quiet mode")
+	}
+	SetLevel(Normal)
+	if Out() == io.Discard {
+		t.Error("Out() should not discard pass-through output by default")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{Quiet, Normal, Verbose} {
+		if got, err := ParseLevel(l.String()); err != nil || got != l {
+			t.Errorf("ParseLevel(%q) = %v, %v", l, got, err)
+		}
+	}
+	if _, err := ParseLevel("debug"); err == nil {
+		t.Error("ParseLevel accepted an unknown level")
+	}
+}
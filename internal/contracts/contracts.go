@@ -0,0 +1,114 @@
+// Package contracts is the registry of swarm coordinator contracts, keyed by
+// swarm and chain ID, so contract upgrades can be picked up from the config
+// file instead of requiring a new release.
+package contracts
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Swarm types
+const (
+	SwarmMath     = "math"      // small swarm (gsm8k)
+	SwarmMathHard = "math-hard" // big swarm (dapo)
+)
+
+// GensynTestnetChainID is the chain ID of the Gensyn testnet
+const GensynTestnetChainID uint64 = 685685
+
+// Coordinator addresses shipped with gswarm
+const (
+	MathTestnet     = "0x69C6e1D608ec64885E7b185d39b04B491a71768C"
+	MathHardTestnet = "0x6947c6E196a48B77eFa9331EC1E3e45f3Ee5Fd58"
+)
+
+var addressRe = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// IsAddress reports whether s is a 0x-prefixed 20-byte hex address
+func IsAddress(s string) bool {
+	return addressRe.MatchString(s)
+}
+
+// Contract is a coordinator deployment
+type Contract struct {
+	Swarm   string `json:"swarm"`
+	ChainID uint64 `json:"chainId"`
+	Address string `json:"address"`
+}
+
+// Validate checks the entry is complete and the address well-formed
+func (c Contract) Validate() error {
+	if c.Swarm != SwarmMath && c.Swarm != SwarmMathHard {
+		return fmt.Errorf("contract %s: swarm must be %q or %q", c.Address, SwarmMath, SwarmMathHard)
+	}
+	if c.ChainID == 0 {
+		return fmt.Errorf("contract %s: missing chainId", c.Address)
+	}
+	if !IsAddress(c.Address) {
+		return fmt.Errorf("contract for %s on chain %d: invalid address %q", c.Swarm, c.ChainID, c.Address)
+	}
+	return nil
+}
+
+// Registry lists known coordinator deployments
+type Registry []Contract
+
+// Default returns the built-in registry
+func Default() Registry {
+	return Registry{
+		{Swarm: SwarmMath, ChainID: GensynTestnetChainID, Address: MathTestnet},
+		{Swarm: SwarmMathHard, ChainID: GensynTestnetChainID, Address: MathHardTestnet},
+	}
+}
+
+// With returns the registry extended with extra; an entry for the same
+// swarm and chain replaces the existing one
+func (r Registry) With(extra []Contract) (Registry, error) {
+	merged := append(Registry{}, r...)
+	for _, c := range extra {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		replaced := false
+		for i := range merged {
+			if merged[i].Swarm == c.Swarm && merged[i].ChainID == c.ChainID {
+				merged[i] = c
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, c)
+		}
+	}
+	return merged, nil
+}
+
+// Address returns the coordinator for swarm on chainID, or "" if unknown
+func (r Registry) Address(swarm string, chainID uint64) string {
+	for _, c := range r {
+		if c.Swarm == swarm && c.ChainID == chainID {
+			return c.Address
+		}
+	}
+	return ""
+}
+
+// Addresses returns the coordinators of every swarm on chainID, small swarm first
+func (r Registry) Addresses(chainID uint64) []string {
+	var addrs []string
+	for _, swarm := range []string{SwarmMath, SwarmMathHard} {
+		if addr := r.Address(swarm, chainID); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// Swarm returns the swarm type for the big swarm setting
+func Swarm(bigSwarm bool) string {
+	if bigSwarm {
+		return SwarmMathHard
+	}
+	return SwarmMath
+}
@@ -0,0 +1,58 @@
+package contracts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistry_With(t *testing.T) {
+	upgraded := "0x1111111111111111111111111111111111111111"
+	devnet := Contract{Swarm: SwarmMath, ChainID: 1337, Address: "0x2222222222222222222222222222222222222222"}
+
+	r, err := Default().With([]Contract{
+		{Swarm: SwarmMathHard, ChainID: GensynTestnetChainID, Address: upgraded},
+		devnet,
+	})
+	if err != nil {
+		t.Fatalf("With() error = %v", err)
+	}
+
+	cases := []struct {
+		swarm   string
+		chainID uint64
+		want    string
+	}{
+		{SwarmMath, GensynTestnetChainID, MathTestnet},
+		{SwarmMathHard, GensynTestnetChainID, upgraded},
+		{SwarmMath, 1337, devnet.Address},
+		{SwarmMathHard, 1337, ""},
+	}
+	for _, c := range cases {
+		if got := r.Address(c.swarm, c.chainID); got != c.want {
+			t.Errorf("Address(%s, %d) = %q, want %q", c.swarm, c.chainID, got, c.want)
+		}
+	}
+	if got := r.Addresses(GensynTestnetChainID); !reflect.DeepEqual(got, []string{MathTestnet, upgraded}) {
+		t.Errorf("Addresses() = %v", got)
+	}
+}
+
+func TestContract_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		c       Contract
+		wantErr bool
+	}{
+		{"valid", Contract{Swarm: SwarmMath, ChainID: 1, Address: MathTestnet}, false},
+		{"unknown swarm", Contract{Swarm: "code", ChainID: 1, Address: MathTestnet}, true},
+		{"missing chain", Contract{Swarm: SwarmMath, Address: MathTestnet}, true},
+		{"short address", Contract{Swarm: SwarmMath, ChainID: 1, Address: "0x1234"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.c.Validate(); (err != nil) != c.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
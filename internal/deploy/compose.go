@@ -0,0 +1,71 @@
+package deploy
+
+import (
+	_ "embed"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// ComposeFile and EnvFileName are the files Compose writes
+const (
+	ComposeFile = "docker-compose.yml"
+	EnvFileName = ".env"
+)
+
+//go:embed compose.yml.tmpl
+var defaultComposeTemplate string
+
+// ComposeTemplate is the default docker-compose.yml template, a Go
+// text/template executed with ComposeData
+func ComposeTemplate() string {
+	return defaultComposeTemplate
+}
+
+// ComposeData is what the compose template is executed with
+type ComposeData struct {
+	Options
+	Instances     []Instance
+	ModalPortIn   int
+	DataDir       string
+	HFCacheDir    string
+	EnvFileName   string
+	MonitorVolume string
+}
+
+// Compose renders the docker-compose.yml for o from tmpl, or from the
+// default template when tmpl is empty
+func Compose(o Options, tmpl string) ([]byte, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if tmpl == "" {
+		tmpl = defaultComposeTemplate
+	}
+	data := ComposeData{
+		Options:       o,
+		Instances:     o.InstanceList(),
+		ModalPortIn:   ModalPort,
+		DataDir:       DataDir,
+		HFCacheDir:    HFCacheDir,
+		EnvFileName:   EnvFileName,
+		MonitorVolume: o.Name + "-monitor-data",
+	}
+	return render("compose", tmpl, composeFuncs, data)
+}
+
+var composeFuncs = template.FuncMap{
+	// quote makes a YAML double-quoted string; Compose interpolates
+	// $ even there, so it is escaped as $$
+	"quote": func(s string) string {
+		return strings.ReplaceAll(yamlQuote(s), "$", "$$")
+	},
+	// bind makes a relative path a bind mount rather than a volume name
+	"bind": func(path string) string {
+		if filepath.IsAbs(path) || strings.HasPrefix(path, ".") {
+			return path
+		}
+		return "./" + path
+	},
+	"quoteList": yamlQuoteList,
+}
@@ -0,0 +1,165 @@
+// Package deploy generates files for running gswarm-supervised nodes under
+// a container orchestrator, from the configuration a node runs with here.
+// Settings shared by every instance go into an env file; each instance gets
+// its own volume, node name, modal-login port and GPUs.
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultImage is the image run when none is given
+const DefaultImage = "gswarm:latest"
+
+// ModalPort is the port modal-login listens on inside a container
+const ModalPort = 3000
+
+// DataDir is the instance's working directory in the container. It holds
+// the rl-swarm checkout and venv, the identity, logs and state, so all of
+// them outlive the container.
+const DataDir = "/data"
+
+// HFCacheDir is the Hugging Face cache in the container, shared by the
+// instances so each model is downloaded once
+const HFCacheDir = "/root/.cache/huggingface"
+
+// EnvVar is a setting passed to every instance
+type EnvVar struct {
+	Name  string
+	Value string
+	// Secret marks tokens and credentials, which orchestrators that
+	// tell them apart keep out of plain configuration
+	Secret bool
+}
+
+// Options describes the deployment
+type Options struct {
+	// Name prefixes the services and the instances' node names
+	Name      string
+	Image     string
+	Instances int
+	// GPUsPerInstance is how many GPUs each instance reserves; 0 runs
+	// the instances on CPU
+	GPUsPerInstance int
+	// ModalPort is the host port of the first instance's modal-login;
+	// the others follow it
+	ModalPort int
+	// ConfigFile is a gswarm config file given to every instance, as
+	// a path relative to the generated files, or ""
+	ConfigFile string
+	// Monitor adds a service running gswarm monitor
+	Monitor bool
+	// TelegramConfig is the monitor's Telegram config, as a path relative
+	// to the generated files, or ""
+	TelegramConfig string
+	Env            []EnvVar
+	// Runtime is the engine the files are generated for; it decides how
+	// GPUs are passed
+	Runtime Runtime
+}
+
+// Instance is one supervised node
+type Instance struct {
+	Name      string
+	NodeName  string
+	Volume    string
+	ModalPort int
+	GPUs      []string
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Validate reports options the generated files couldn't work with
+func (o Options) Validate() error {
+	if !namePattern.MatchString(o.Name) {
+		return fmt.Errorf("invalid name %q: use lowercase letters, digits and dashes", o.Name)
+	}
+	if o.Image == "" {
+		return fmt.Errorf("no image given")
+	}
+	if o.Instances < 1 {
+		return fmt.Errorf("need at least one instance, got %d", o.Instances)
+	}
+	if o.GPUsPerInstance < 0 {
+		return fmt.Errorf("invalid GPU count %d", o.GPUsPerInstance)
+	}
+	if o.ModalPort < 1 || o.ModalPort+o.Instances-1 > 65535 {
+		return fmt.Errorf("modal-login ports %d-%d are out of range", o.ModalPort, o.ModalPort+o.Instances-1)
+	}
+	return nil
+}
+
+// InstanceList returns the instances, numbered from 1. Instance i gets
+// the next GPUsPerInstance GPUs after instance i-1's.
+func (o Options) InstanceList() []Instance {
+	list := make([]Instance, o.Instances)
+	for i := range list {
+		name := fmt.Sprintf("%s-%d", o.Name, i+1)
+		inst := Instance{Name: name, NodeName: name, Volume: name + "-data", ModalPort: o.ModalPort + i}
+		for g := 0; g < o.GPUsPerInstance; g++ {
+			inst.GPUs = append(inst.GPUs, strconv.Itoa(i*o.GPUsPerInstance+g))
+		}
+		list[i] = inst
+	}
+	return list
+}
+
+// EnvFile formats env as a .env file, sorted by name. Values that need
+// it are quoted so they are read back unchanged and not interpolated.
+func EnvFile(header string, env []EnvVar) []byte {
+	sorted := append([]EnvVar{}, env...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(header), "\n") {
+		if line != "" {
+			b.WriteString("# " + line + "\n")
+		}
+	}
+	for _, e := range sorted {
+		b.WriteString(e.Name + "=" + envQuote(e.Value) + "\n")
+	}
+	return []byte(b.String())
+}
+
+func envQuote(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\"'#$\\`\n") {
+		return v
+	}
+	if !strings.ContainsAny(v, "'\n") {
+		return "'" + v + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`)
+	return `"` + r.Replace(v) + `"`
+}
+
+// render executes a generator's template with data
+func render(kind, tmpl string, funcs template.FuncMap, data interface{}) ([]byte, error) {
+	t, err := template.New(kind).Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", kind, err)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s template: %w", kind, err)
+	}
+	return out.Bytes(), nil
+}
+
+// yamlQuote makes a YAML double-quoted string, which JSON strings are
+func yamlQuote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// yamlQuoteList makes a YAML flow sequence of double-quoted strings
+func yamlQuoteList(list []string) string {
+	b, _ := json.Marshal(list)
+	return string(b)
+}
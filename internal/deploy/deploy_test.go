@@ -0,0 +1,248 @@
+package deploy
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func testOptions() Options {
+	return Options{Name: "gswarm", Image: DefaultImage, Instances: 2, GPUsPerInstance: 2, ModalPort: 3000}
+}
+
+func TestInstanceList(t *testing.T) {
+	got := testOptions().InstanceList()
+	want := []Instance{
+		{Name: "gswarm-1", NodeName: "gswarm-1", Volume: "gswarm-1-data", ModalPort: 3000, GPUs: []string{"0", "1"}},
+		{Name: "gswarm-2", NodeName: "gswarm-2", Volume: "gswarm-2-data", ModalPort: 3001, GPUs: []string{"2", "3"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InstanceList() = %+v, want %+v", got, want)
+	}
+
+	o := testOptions()
+	o.GPUsPerInstance = 0
+	for _, inst := range o.InstanceList() {
+		if inst.GPUs != nil {
+			t.Errorf("CPU instance %s got GPUs %v", inst.Name, inst.GPUs)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, change := range map[string]func(*Options){
+		"bad name":     func(o *Options) { o.Name = "My Swarm" },
+		"no image":     func(o *Options) { o.Image = "" },
+		"no instances": func(o *Options) { o.Instances = 0 },
+		"negative GPU": func(o *Options) { o.GPUsPerInstance = -1 },
+		"port range":   func(o *Options) { o.ModalPort = 65535 },
+	} {
+		o := testOptions()
+		change(&o)
+		if err := o.Validate(); err == nil {
+			t.Errorf("%s: Validate() succeeded", name)
+		}
+	}
+	if err := testOptions().Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestEnvFile(t *testing.T) {
+	got := string(EnvFile("shared settings\nkeep secret", []EnvVar{
+		{Name: "GSWARM_MODEL_SIZE", Value: "7"},
+		{Name: "GSWARM_PAUSE_WINDOW", Value: "08:00-18:00 weekdays"},
+		{Name: "GSWARM_HUB_TOKEN", Value: "a$b"},
+		{Name: "GSWARM_NOTE", Value: "it's"},
+		{Name: "GSWARM_EMPTY", Value: ""},
+	}))
+	want := `# shared settings
+# keep secret
+GSWARM_EMPTY=''
+GSWARM_HUB_TOKEN='a$b'
+GSWARM_MODEL_SIZE=7
+GSWARM_NOTE="it's"
+GSWARM_PAUSE_WINDOW='08:00-18:00 weekdays'
+`
+	if got != want {
+		t.Errorf("EnvFile() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	o := testOptions()
+	o.ConfigFile = "gswarm.json"
+	o.Monitor = true
+	o.TelegramConfig = "/etc/gswarm/telegram-config.json"
+	out, err := Compose(o, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := parseYAML(t, out)
+	services := doc["services"].(map[string]interface{})
+	if len(services) != 3 {
+		t.Fatalf("got services %v, want 2 instances and the monitor", keys(services))
+	}
+	second := services["gswarm-2"].(map[string]interface{})
+	if got := second["ports"].([]interface{}); len(got) != 1 || got[0] != "3001:3000" {
+		t.Errorf("gswarm-2 ports = %v", got)
+	}
+	volumes := second["volumes"].([]interface{})
+	if len(volumes) != 3 || volumes[0] != "gswarm-2-data:/data" || volumes[2] != "./gswarm.json:/data/gswarm.json:ro" {
+		t.Errorf("gswarm-2 volumes = %v", volumes)
+	}
+	if got := second["environment"].(map[string]interface{})["GSWARM_NODE_NAME"]; got != "gswarm-2" {
+		t.Errorf("gswarm-2 node name = %v", got)
+	}
+	if !strings.Contains(string(out), `device_ids: ["2","3"]`) {
+		t.Errorf("gswarm-2 doesn't reserve GPUs 2 and 3:\n%s", out)
+	}
+	monitor := services["gswarm-monitor"].(map[string]interface{})
+	if got := monitor["volumes"].([]interface{}); len(got) != 2 || got[1] != "/etc/gswarm/telegram-config.json:/data/telegram-config.json" {
+		t.Errorf("monitor volumes = %v", got)
+	}
+	if got := keys(doc["volumes"].(map[string]interface{})); !reflect.DeepEqual(got, []string{"gswarm-1-data", "gswarm-2-data", "gswarm-monitor-data", "hf-cache"}) {
+		t.Errorf("volumes = %v", got)
+	}
+}
+
+func TestComposeCPU(t *testing.T) {
+	o := testOptions()
+	o.GPUsPerInstance = 0
+	out, err := Compose(o, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "nvidia") {
+		t.Errorf("CPU instances reserve GPUs:\n%s", out)
+	}
+	parseYAML(t, out)
+}
+
+func TestComposeTemplate(t *testing.T) {
+	out, err := Compose(testOptions(), "{{range .Instances}}{{.Name}} {{end}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "gswarm-1 gswarm-2 " {
+		t.Errorf("Compose() with a custom template = %q", out)
+	}
+	if _, err := Compose(testOptions(), "{{.Missing"); err == nil {
+		t.Error("Compose() accepted a broken template")
+	}
+}
+
+func parseYAML(t *testing.T, out []byte) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("generated YAML doesn't parse: %v\n%s", err, out)
+	}
+	return doc
+}
+
+func keys(m map[string]interface{}) []string {
+	var list []string
+	for k := range m {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestKubernetes(t *testing.T) {
+	o := KubernetesOptions{Options: testOptions(), Namespace: "swarm", ConfigJSON: "{\n  \"timezone\": \"UTC\"\n}\n", TelegramJSON: `{"bot_token": "123:abc"}`}
+	o.Monitor = true
+	o.Env = []EnvVar{{Name: "GSWARM_MODEL_SIZE", Value: "7"}, {Name: "GSWARM_HUB_TOKEN", Value: "s3cret", Secret: true}}
+	out, err := Kubernetes(o, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := map[string]map[string]interface{}{}
+	for _, part := range strings.Split(string(out), "\n---\n")[1:] {
+		doc := parseYAML(t, []byte(part))
+		meta := doc["metadata"].(map[string]interface{})
+		if meta["namespace"] != "swarm" {
+			t.Errorf("%s %s isn't in the namespace", doc["kind"], meta["name"])
+		}
+		docs[doc["kind"].(string)+"/"+meta["name"].(string)] = doc
+	}
+	want := []string{
+		"ConfigMap/gswarm-config", "ConfigMap/gswarm-env", "Deployment/gswarm-monitor",
+		"PersistentVolumeClaim/gswarm-monitor-data", "Secret/gswarm-env", "Secret/gswarm-telegram",
+		"Service/gswarm", "StatefulSet/gswarm",
+	}
+	var got []string
+	for k := range docs {
+		got = append(got, k)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resources = %v, want %v", got, want)
+	}
+
+	if env := docs["ConfigMap/gswarm-env"]["data"].(map[string]interface{}); env["GSWARM_MODEL_SIZE"] != "7" || env["GSWARM_HUB_TOKEN"] != nil {
+		t.Errorf("ConfigMap env = %v", env)
+	}
+	if env := docs["Secret/gswarm-env"]["stringData"].(map[string]interface{}); env["GSWARM_HUB_TOKEN"] != "s3cret" {
+		t.Errorf("Secret env = %v", env)
+	}
+	if cfg := docs["ConfigMap/gswarm-config"]["data"].(map[string]interface{}); cfg["gswarm.json"] != o.ConfigJSON {
+		t.Errorf("gswarm.json = %q, want %q", cfg["gswarm.json"], o.ConfigJSON)
+	}
+
+	spec := docs["StatefulSet/gswarm"]["spec"].(map[string]interface{})
+	if spec["replicas"] != 2 {
+		t.Errorf("replicas = %v", spec["replicas"])
+	}
+	pod := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	container := pod["containers"].([]interface{})[0].(map[string]interface{})
+	for _, probe := range []string{"startupProbe", "livenessProbe"} {
+		exec := container[probe].(map[string]interface{})["exec"].(map[string]interface{})
+		if !reflect.DeepEqual(exec["command"], []interface{}{"gswarm", "healthcheck"}) {
+			t.Errorf("%s = %v", probe, exec)
+		}
+	}
+	if env := docs["ConfigMap/gswarm-env"]["data"].(map[string]interface{}); env["GSWARM_API_LISTEN"] != "127.0.0.1:8686" {
+		t.Errorf("GSWARM_API_LISTEN = %v, want loopback", env["GSWARM_API_LISTEN"])
+	}
+
+	// the monitor saves into its Telegram config, so it gets a copy on its volume
+	monitor := docs["Deployment/gswarm-monitor"]["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	if init, ok := monitor["initContainers"].([]interface{}); !ok || len(init) != 1 {
+		t.Errorf("monitor initContainers = %v, want the Telegram config copy", monitor["initContainers"])
+	}
+	mounts := monitor["containers"].([]interface{})[0].(map[string]interface{})["volumeMounts"].([]interface{})
+	if len(mounts) != 1 || mounts[0].(map[string]interface{})["name"] != "data" {
+		t.Errorf("monitor volumeMounts = %v, want only its data volume", mounts)
+	}
+	limits := container["resources"].(map[string]interface{})["limits"].(map[string]interface{})
+	if limits["nvidia.com/gpu"] != 2 {
+		t.Errorf("GPU limit = %v", limits)
+	}
+}
+
+func TestKubernetesMinimal(t *testing.T) {
+	o := KubernetesOptions{Options: testOptions()}
+	o.GPUsPerInstance = 0
+	out, err := Kubernetes(o, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unwanted := range []string{"nvidia.com/gpu", "namespace:", "monitor", "gswarm-config"} {
+		if strings.Contains(string(out), unwanted) {
+			t.Errorf("manifests contain %q:\n%s", unwanted, out)
+		}
+	}
+	for _, part := range strings.Split(string(out), "\n---\n")[1:] {
+		parseYAML(t, []byte(part))
+	}
+
+	o.Storage = "lots"
+	if _, err := Kubernetes(o, ""); err == nil {
+		t.Error("Kubernetes() accepted an invalid storage size")
+	}
+}
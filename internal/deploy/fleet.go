@@ -0,0 +1,228 @@
+package deploy
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/Deep-Commit/gswarm/internal/contracts"
+)
+
+// Files fleet init writes: the shared env file in the fleet directory,
+// each instance's config file in its directory, and the units in
+// FleetUnitDir
+const (
+	FleetEnvFile    = "fleet.env"
+	FleetConfigFile = "gswarm.json"
+	FleetUnitDir    = "systemd"
+)
+
+// DefaultFleetAPIPort is the status API port of the first instance
+const DefaultFleetAPIPort = APIPort
+
+//go:embed fleet.service.tmpl
+var defaultUnitTemplate string
+
+// UnitTemplate is the default systemd unit template, a Go text/template
+// executed with UnitData
+func UnitTemplate() string {
+	return defaultUnitTemplate
+}
+
+// FleetOptions describes instances run side by side on this machine as
+// systemd services, each in its own directory with its own identity,
+// state, ports and wallet
+type FleetOptions struct {
+	// Name prefixes the instances' names and units
+	Name      string
+	Instances int
+	// Dir is the absolute directory the instances' directories are
+	// created in
+	Dir string
+	// ModalPort and APIPort are the first instance's modal-login and
+	// status API ports; the others follow them
+	ModalPort       int
+	APIPort         int
+	GPUsPerInstance int
+	// EOAs are the instances' wallets in order, or empty
+	EOAs []string
+	// Executable is the absolute path of the gswarm binary the units run
+	Executable string
+	// User runs the services, or "" for systemd's default
+	User string
+}
+
+// FleetInstance is one instance of a fleet
+type FleetInstance struct {
+	Name         string
+	Unit         string
+	Dir          string
+	ConfigFile   string
+	StateDir     string
+	IdentityPath string
+	EOA          string
+	ModalPort    int
+	APIPort      int
+	GPUs         []string
+}
+
+// Validate reports options the fleet couldn't be generated from
+func (o FleetOptions) Validate() error {
+	if !namePattern.MatchString(o.Name) {
+		return fmt.Errorf("invalid name %q: use lowercase letters, digits and dashes", o.Name)
+	}
+	if o.Instances < 1 {
+		return fmt.Errorf("need at least one instance, got %d", o.Instances)
+	}
+	if len(o.EOAs) > 0 && len(o.EOAs) != o.Instances {
+		return fmt.Errorf("%d wallets given for %d instances; give one per instance", len(o.EOAs), o.Instances)
+	}
+	if o.GPUsPerInstance < 0 {
+		return fmt.Errorf("invalid GPU count %d", o.GPUsPerInstance)
+	}
+	if !filepath.IsAbs(o.Dir) || !filepath.IsAbs(o.Executable) {
+		return fmt.Errorf("the fleet directory and gswarm executable must be absolute paths")
+	}
+	for _, p := range []struct {
+		name  string
+		first int
+	}{{"modal-login", o.ModalPort}, {"status API", o.APIPort}} {
+		if p.first < 1 || p.first+o.Instances-1 > 65535 {
+			return fmt.Errorf("%s ports %d-%d are out of range", p.name, p.first, p.first+o.Instances-1)
+		}
+	}
+	return nil
+}
+
+// CheckGPUs reports instances that would be given GPUs beyond the present
+// ones, which are numbered from 0
+func (o FleetOptions) CheckGPUs(present int) error {
+	if need := o.Instances * o.GPUsPerInstance; need > present {
+		return fmt.Errorf("%d instances with %d GPUs each need %d GPUs, but %d are present; lower --count or --gpus-per-instance",
+			o.Instances, o.GPUsPerInstance, need, present)
+	}
+	return nil
+}
+
+// InstanceList returns the instances, numbered from 1 like the compose
+// services
+func (o FleetOptions) InstanceList() []FleetInstance {
+	list := make([]FleetInstance, o.Instances)
+	for i := range list {
+		name := fmt.Sprintf("%s-%d", o.Name, i+1)
+		dir := filepath.Join(o.Dir, name)
+		inst := FleetInstance{
+			Name:         name,
+			Unit:         name + ".service",
+			Dir:          dir,
+			ConfigFile:   filepath.Join(dir, FleetConfigFile),
+			StateDir:     filepath.Join(dir, ".gswarm"),
+			IdentityPath: filepath.Join(dir, "swarm.pem"),
+			ModalPort:    o.ModalPort + i,
+			APIPort:      o.APIPort + i,
+		}
+		if len(o.EOAs) > 0 {
+			inst.EOA = o.EOAs[i]
+		}
+		for g := 0; g < o.GPUsPerInstance; g++ {
+			inst.GPUs = append(inst.GPUs, fmt.Sprint(i*o.GPUsPerInstance+g))
+		}
+		list[i] = inst
+	}
+	return list
+}
+
+// CheckUnique reports instances that would share a name, directory,
+// identity, state directory or port. peerIDs maps the identity files that
+// already exist to their peer IDs, so two copies of one key are caught
+// too. Every clash is listed, not just the first.
+func CheckUnique(list []FleetInstance, peerIDs map[string]string) error {
+	seen := map[string]string{}
+	var problems []string
+	claim := func(kind, value, owner string) {
+		key := kind + " " + value
+		if prev, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s and %s share %s %s", prev, owner, kind, value))
+			return
+		}
+		seen[key] = owner
+	}
+	for _, inst := range list {
+		claim("name", inst.Name, inst.Name)
+		claim("unit", inst.Unit, inst.Name)
+		claim("directory", inst.Dir, inst.Name)
+		claim("state directory", inst.StateDir, inst.Name)
+		claim("identity file", inst.IdentityPath, inst.Name)
+		if id := peerIDs[inst.IdentityPath]; id != "" {
+			claim("peer ID", id, inst.Name)
+		}
+		// modal-login and the status API can't share a port either
+		claim("port", fmt.Sprint(inst.ModalPort), inst.Name)
+		claim("port", fmt.Sprint(inst.APIPort), inst.Name)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("instances clash: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ReadEOAList parses a wallet list: one address per line, with blank lines
+// and # comments ignored. Several instances may use one wallet, so
+// repeats are kept.
+func ReadEOAList(data []byte) ([]string, error) {
+	var eoas []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !contracts.IsAddress(line) {
+			return nil, fmt.Errorf("line %d: invalid EOA address %q", n, line)
+		}
+		eoas = append(eoas, line)
+	}
+	if len(eoas) == 0 {
+		return nil, fmt.Errorf("no EOA addresses found")
+	}
+	return eoas, scanner.Err()
+}
+
+// UnitData is what the unit template is executed with
+type UnitData struct {
+	FleetOptions
+	Instance FleetInstance
+	// EnvFile is the absolute path of the shared env file
+	EnvFile string
+}
+
+// Unit renders the systemd unit of inst from tmpl, or from the default
+// template when tmpl is empty
+func Unit(o FleetOptions, inst FleetInstance, tmpl string) ([]byte, error) {
+	if tmpl == "" {
+		tmpl = defaultUnitTemplate
+	}
+	data := UnitData{FleetOptions: o, Instance: inst, EnvFile: filepath.Join(o.Dir, FleetEnvFile)}
+	return render("unit", tmpl, unitFuncs, data)
+}
+
+var unitFuncs = template.FuncMap{
+	"arg":  systemdQuote,
+	"join": strings.Join,
+}
+
+// systemdQuote makes s one ExecStart argument. systemd expands % and $
+// even in quotes, so they are doubled.
+func systemdQuote(s string) string {
+	s = strings.NewReplacer("%", "%%", "$", "$$").Replace(s)
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;\n") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,137 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+)
+
+func testFleet() FleetOptions {
+	return FleetOptions{
+		Name: "gswarm", Instances: 2, Dir: "/srv/fleet", ModalPort: 3000, APIPort: 8686, GPUsPerInstance: 1,
+		EOAs:       []string{"0x1111111111111111111111111111111111111111", "0x2222222222222222222222222222222222222222"},
+		Executable: "/usr/local/bin/gswarm",
+	}
+}
+
+func TestFleetInstanceList(t *testing.T) {
+	list := testFleet().InstanceList()
+	if len(list) != 2 {
+		t.Fatalf("InstanceList() returned %d instances", len(list))
+	}
+	second := list[1]
+	if second.Name != "gswarm-2" || second.Unit != "gswarm-2.service" || second.Dir != "/srv/fleet/gswarm-2" ||
+		second.IdentityPath != "/srv/fleet/gswarm-2/swarm.pem" || second.StateDir != "/srv/fleet/gswarm-2/.gswarm" ||
+		second.EOA != "0x2222222222222222222222222222222222222222" || second.ModalPort != 3001 || second.APIPort != 8687 ||
+		strings.Join(second.GPUs, ",") != "1" {
+		t.Errorf("second instance = %+v", second)
+	}
+	if err := CheckUnique(list, nil); err != nil {
+		t.Errorf("CheckUnique() = %v", err)
+	}
+}
+
+func TestFleetValidate(t *testing.T) {
+	for name, change := range map[string]func(*FleetOptions){
+		"bad name":        func(o *FleetOptions) { o.Name = "My Fleet" },
+		"no instances":    func(o *FleetOptions) { o.Instances = 0; o.EOAs = nil },
+		"wallet count":    func(o *FleetOptions) { o.Instances = 3 },
+		"relative dir":    func(o *FleetOptions) { o.Dir = "fleet" },
+		"API port range":  func(o *FleetOptions) { o.APIPort = 65535 },
+		"modal port zero": func(o *FleetOptions) { o.ModalPort = 0 },
+	} {
+		o := testFleet()
+		change(&o)
+		if err := o.Validate(); err == nil {
+			t.Errorf("%s: Validate() succeeded", name)
+		}
+	}
+	if err := testFleet().Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestFleetCheckGPUs(t *testing.T) {
+	o := testFleet()
+	if err := o.CheckGPUs(2); err != nil {
+		t.Errorf("CheckGPUs(2) = %v", err)
+	}
+	if err := o.CheckGPUs(1); err == nil {
+		t.Error("CheckGPUs(1) accepted 2 instances with a GPU each")
+	}
+	o.GPUsPerInstance = 0
+	if err := o.CheckGPUs(0); err != nil {
+		t.Errorf("CheckGPUs(0) for CPU instances = %v", err)
+	}
+}
+
+func TestCheckUnique(t *testing.T) {
+	o := testFleet()
+	o.Instances, o.EOAs = 3, nil
+	// the status API ports run into the third instance's modal-login port
+	o.APIPort = 2999
+	list := o.InstanceList()
+	// two instances were given copies of one key
+	peerIDs := map[string]string{list[0].IdentityPath: "QmSame", list[2].IdentityPath: "QmSame"}
+	err := CheckUnique(list, peerIDs)
+	if err == nil {
+		t.Fatal("CheckUnique() accepted clashing instances")
+	}
+	for _, want := range []string{"gswarm-1 and gswarm-3 share peer ID QmSame", "gswarm-2 and gswarm-3 share port 3001"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+}
+
+func TestReadEOAList(t *testing.T) {
+	eoas, err := ReadEOAList([]byte("# wallets\n0x1111111111111111111111111111111111111111\n\n" +
+		"0x2222222222222222222222222222222222222222 # spare box\n0x1111111111111111111111111111111111111111\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eoas) != 3 || eoas[1] != "0x2222222222222222222222222222222222222222" {
+		t.Errorf("ReadEOAList() = %v", eoas)
+	}
+	if _, err := ReadEOAList([]byte("0x1111111111111111111111111111111111111111\nnot-a-wallet\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("invalid line: err = %v", err)
+	}
+	if _, err := ReadEOAList([]byte("# nothing\n")); err == nil {
+		t.Error("empty list accepted")
+	}
+}
+
+func TestUnit(t *testing.T) {
+	o := testFleet()
+	o.Dir, o.User = "/srv/my fleet", "gswarm"
+	inst := o.InstanceList()[0]
+	out, err := Unit(o, inst, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit := string(out)
+	for _, want := range []string{
+		"User=gswarm\n",
+		"WorkingDirectory=/srv/my fleet/gswarm-1\n",
+		"EnvironmentFile=/srv/my fleet/fleet.env\n",
+		`ExecStart=/usr/local/bin/gswarm --config-file "/srv/my fleet/gswarm-1/gswarm.json" --state-dir "/srv/my fleet/gswarm-1/.gswarm"`,
+		"--node-name gswarm-1 --modal-port 3000 --api-listen 127.0.0.1:8686 --gpu 0\n",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit missing %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"/srv/fleet":   "/srv/fleet",
+		"/srv/a b":     `"/srv/a b"`,
+		"/srv/100%":    "/srv/100%%",
+		`/srv/"x" $HO`: `"/srv/\"x\" $$HO"`,
+	} {
+		if got := systemdQuote(in); got != want {
+			t.Errorf("systemdQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
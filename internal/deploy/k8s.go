@@ -0,0 +1,107 @@
+package deploy
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// KubernetesFile is the manifest file name suggested to users
+const KubernetesFile = "gswarm-k8s.yaml"
+
+// APIPort is the status API port on a pod's loopback interface, where
+// gswarm healthcheck asks for /healthz
+const APIPort = 8686
+
+// DefaultStorage is the default size of each instance's volume, which
+// holds the rl-swarm checkout, its venv and the Hugging Face cache
+const DefaultStorage = "50Gi"
+
+//go:embed k8s.yaml.tmpl
+var defaultKubernetesTemplate string
+
+// KubernetesTemplate is the default manifest template, a Go text/template
+// executed with KubernetesData
+func KubernetesTemplate() string {
+	return defaultKubernetesTemplate
+}
+
+// KubernetesOptions describes a deployment to Kubernetes. The instances
+// are the replicas of a StatefulSet, so ModalPort and GPU numbering don't
+// apply; each pod reserves GPUsPerInstance GPUs from the device plugin.
+type KubernetesOptions struct {
+	Options
+	Namespace    string
+	Storage      string
+	StorageClass string
+	// ConfigJSON is the gswarm config file's content, or ""
+	ConfigJSON string
+	// TelegramJSON is the monitor's Telegram config, or ""; it holds the
+	// bot token, so it is stored in a Secret
+	TelegramJSON string
+}
+
+// KubernetesData is what the manifest template is executed with
+type KubernetesData struct {
+	KubernetesOptions
+	ConfigEnv  []EnvVar
+	SecretEnv  []EnvVar
+	DataDir    string
+	HFCacheDir string
+	ModalPort  int
+	APIPort    int
+}
+
+var quantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([KMGTPE]i?)?$`)
+
+// Kubernetes renders the manifests for o from tmpl, or from the default
+// template when tmpl is empty
+func Kubernetes(o KubernetesOptions, tmpl string) ([]byte, error) {
+	// Ports aren't published, so any valid one passes
+	o.ModalPort = ModalPort
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if o.Namespace != "" && !namePattern.MatchString(o.Namespace) {
+		return nil, fmt.Errorf("invalid namespace %q", o.Namespace)
+	}
+	if o.Storage == "" {
+		o.Storage = DefaultStorage
+	}
+	if !quantityPattern.MatchString(o.Storage) {
+		return nil, fmt.Errorf("invalid storage size %q: use a Kubernetes quantity such as 50Gi", o.Storage)
+	}
+	if tmpl == "" {
+		tmpl = defaultKubernetesTemplate
+	}
+	data := KubernetesData{KubernetesOptions: o, DataDir: DataDir, HFCacheDir: HFCacheDir, ModalPort: ModalPort, APIPort: APIPort}
+	env := append([]EnvVar{}, o.Env...)
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+	for _, e := range env {
+		if e.Secret {
+			data.SecretEnv = append(data.SecretEnv, e)
+		} else {
+			data.ConfigEnv = append(data.ConfigEnv, e)
+		}
+	}
+	return render("kubernetes", tmpl, kubernetesFuncs, data)
+}
+
+var kubernetesFuncs = template.FuncMap{
+	"quote":     yamlQuote,
+	"quoteList": yamlQuoteList,
+	// indent prefixes every line of s with n spaces, for block scalars
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+		for i, l := range lines {
+			if l != "" {
+				lines[i] = pad + l
+			}
+		}
+		return strings.Join(lines, "\n")
+	},
+}
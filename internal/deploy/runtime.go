@@ -0,0 +1,129 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Container engines the generated files can run on
+const (
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+)
+
+// NVIDIAConfigFile is the NVIDIA Container Toolkit's config, which
+// rootless Docker needs no-cgroups set in
+const NVIDIAConfigFile = "/etc/nvidia-container-runtime/config.toml"
+
+// infoTimeout bounds docker info, which waits for the daemon
+const infoTimeout = 10 * time.Second
+
+// Runtime is the container engine that runs the generated files and how
+// it runs
+type Runtime struct {
+	Engine   string
+	Rootless bool
+}
+
+// String describes r, e.g. "rootless Podman"
+func (r Runtime) String() string {
+	name := "Docker"
+	if r.Engine == EnginePodman {
+		name = "Podman"
+	}
+	if r.Rootless {
+		name = "rootless " + name
+	}
+	return name
+}
+
+// CDI reports whether GPUs are passed as CDI devices, nvidia.com/gpu=N,
+// rather than as device requests to the NVIDIA runtime. Podman only
+// supports the former.
+func (r Runtime) CDI() bool {
+	return r.Engine == EnginePodman
+}
+
+// NoCgroups reports whether the NVIDIA Container Toolkit must leave device
+// cgroups alone, as it must under rootless Docker, which can't write them.
+// It is the only cgroup setting that depends on the engine, and it lives
+// in the host's toolkit config, which the generated files can't set.
+func (r Runtime) NoCgroups() bool {
+	return r.Engine == EngineDocker && r.Rootless
+}
+
+// ComposeCommand is the command that runs a compose file on r
+func (r Runtime) ComposeCommand() string {
+	return r.Engine + " compose"
+}
+
+// ParseInfo reads the output of `docker info` or `podman info` formatted
+// as JSON. Podman's docker shim answers to docker with Podman's output,
+// so the engine is told by the output, not the command.
+func ParseInfo(data []byte) (Runtime, error) {
+	var info struct {
+		// Docker
+		SecurityOptions []string
+		// Podman
+		Host *struct {
+			Security struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return Runtime{}, fmt.Errorf("unreadable engine info: %w", err)
+	}
+	if info.Host != nil {
+		return Runtime{Engine: EnginePodman, Rootless: info.Host.Security.Rootless}, nil
+	}
+	r := Runtime{Engine: EngineDocker}
+	for _, opt := range info.SecurityOptions {
+		if opt == "name=rootless" {
+			r.Rootless = true
+		}
+	}
+	return r, nil
+}
+
+// DetectRuntime asks engine how it runs containers. With engine "",
+// docker is tried before podman.
+func DetectRuntime(engine string) (Runtime, error) {
+	engines := []string{engine}
+	if engine == "" {
+		engines = []string{EngineDocker, EnginePodman}
+	}
+	var lastErr error
+	for _, e := range engines {
+		if _, err := exec.LookPath(e); err != nil {
+			lastErr = fmt.Errorf("%s not found", e)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+		out, err := exec.CommandContext(ctx, e, "info", "--format", "{{json .}}").Output()
+		cancel()
+		if err != nil {
+			lastErr = fmt.Errorf("%s info failed: %w", e, err)
+			continue
+		}
+		return ParseInfo(out)
+	}
+	return Runtime{}, lastErr
+}
+
+// NoCgroupsSet reports whether an NVIDIA Container Toolkit config sets
+// no-cgroups = true
+func NoCgroupsSet(config []byte) bool {
+	for _, line := range strings.Split(string(config), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) == "no-cgroups" && strings.TrimSpace(value) == "true" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseInfo(t *testing.T) {
+	cases := []struct {
+		name string
+		info string
+		want Runtime
+	}{
+		{"rootful docker", `{"CgroupVersion":"2","SecurityOptions":["name=seccomp,profile=builtin","name=cgroupns"]}`,
+			Runtime{Engine: EngineDocker}},
+		{"rootless docker", `{"CgroupVersion":"1","SecurityOptions":["name=seccomp,profile=builtin","name=rootless"]}`,
+			Runtime{Engine: EngineDocker, Rootless: true}},
+		// podman-docker answers to docker with Podman's output
+		{"rootless podman", `{"host":{"cgroupVersion":"v2","security":{"rootless":true}},"version":{"Version":"4.9.3"}}`,
+			Runtime{Engine: EnginePodman, Rootless: true}},
+	}
+	for _, c := range cases {
+		got, err := ParseInfo([]byte(c.info))
+		if err != nil || got != c.want {
+			t.Errorf("%s: ParseInfo() = %+v, %v, want %+v", c.name, got, err, c.want)
+		}
+	}
+	if _, err := ParseInfo([]byte("Cannot connect to the Docker daemon")); err == nil {
+		t.Error("ParseInfo accepted an error message")
+	}
+
+	rt := Runtime{Engine: EnginePodman, Rootless: true}
+	if got := rt.String(); got != "rootless Podman" {
+		t.Errorf("String() = %q", got)
+	}
+	if !rt.CDI() || rt.NoCgroups() || rt.ComposeCommand() != "podman compose" {
+		t.Errorf("podman runtime: CDI %v, NoCgroups %v, %q", rt.CDI(), rt.NoCgroups(), rt.ComposeCommand())
+	}
+	if rt := (Runtime{Engine: EngineDocker, Rootless: true}); rt.CDI() || !rt.NoCgroups() {
+		t.Errorf("rootless docker: CDI %v, NoCgroups %v", rt.CDI(), rt.NoCgroups())
+	}
+}
+
+func TestNoCgroupsSet(t *testing.T) {
+	for config, want := range map[string]bool{
+		"[nvidia-container-cli]\nno-cgroups = true\n":          true,
+		"[nvidia-container-cli]\n#no-cgroups = true\n":         false,
+		"[nvidia-container-cli]\nno-cgroups = false # default": false,
+		"": false,
+	} {
+		if got := NoCgroupsSet([]byte(config)); got != want {
+			t.Errorf("NoCgroupsSet(%q) = %v, want %v", config, got, want)
+		}
+	}
+}
+
+func TestComposePodman(t *testing.T) {
+	o := testOptions()
+	o.Runtime = Runtime{Engine: EnginePodman, Rootless: true}
+	out, err := Compose(o, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	services := parseYAML(t, out)["services"].(map[string]interface{})
+	second := services["gswarm-2"].(map[string]interface{})
+	if got := second["devices"].([]interface{}); len(got) != 2 || got[0] != "nvidia.com/gpu=2" || got[1] != "nvidia.com/gpu=3" {
+		t.Errorf("gswarm-2 devices = %v", got)
+	}
+	if _, ok := second["deploy"]; ok || strings.Contains(string(out), "driver: nvidia") {
+		t.Errorf("Podman instances use Docker's device requests:\n%s", out)
+	}
+}
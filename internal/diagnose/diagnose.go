@@ -0,0 +1,232 @@
+// Package diagnose recognises known trainer failures in their output and
+// explains them, with remediation steps, from a YAML knowledge base.
+package diagnose
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Deep-Commit/gswarm/internal/lines"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed knowledge.yaml
+var builtin []byte
+
+// maxFindings caps how many different problems are reported for one run
+const maxFindings = 3
+
+// Entry is a known error fingerprint and how to deal with it
+type Entry struct {
+	ID          string
+	Match       *regexp.Regexp
+	Title       string
+	Explanation string
+	Fix         []string
+}
+
+// Text renders the entry for the console and notifications
+func (e Entry) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", e.Title, strings.TrimSpace(e.Explanation))
+	if len(e.Fix) > 0 {
+		b.WriteString("\nHow to fix:")
+		for i, step := range e.Fix {
+			fmt.Fprintf(&b, "\n  %d. %s", i+1, step)
+		}
+	}
+	return b.String()
+}
+
+// KnowledgeBase is an ordered list of entries; the first match wins
+type KnowledgeBase []Entry
+
+// Parse reads a knowledge base: a YAML list of entries with id, match (a
+// regular expression), title, explanation and fix (a list of steps)
+func Parse(data []byte) (KnowledgeBase, error) {
+	var doc interface{}
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	items, ok := doc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("knowledge base must be a list of entries")
+	}
+
+	kb := make(KnowledgeBase, 0, len(items))
+	for i, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("entry %d: expected a mapping", i+1)
+		}
+		e := Entry{
+			ID:          str(fields["id"]),
+			Title:       str(fields["title"]),
+			Explanation: str(fields["explanation"]),
+		}
+		if e.ID == "" {
+			return nil, fmt.Errorf("entry %d: missing id", i+1)
+		}
+		pattern := str(fields["match"])
+		if pattern == "" {
+			return nil, fmt.Errorf("entry %q: missing match", e.ID)
+		}
+		if e.Match, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("entry %q: invalid match: %w", e.ID, err)
+		}
+		if e.Title == "" {
+			e.Title = e.ID
+		}
+		switch fix := fields["fix"].(type) {
+		case nil:
+		case []interface{}:
+			for _, step := range fix {
+				e.Fix = append(e.Fix, str(step))
+			}
+		default:
+			e.Fix = []string{str(fix)}
+		}
+		kb = append(kb, e)
+	}
+	return kb, nil
+}
+
+func str(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+// Default returns the built-in knowledge base
+func Default() KnowledgeBase {
+	kb, err := Parse(builtin)
+	if err != nil {
+		panic(fmt.Sprintf("built-in knowledge base: %v", err))
+	}
+	return kb
+}
+
+// Load returns the built-in knowledge base extended with the entries in path
+func Load(path string) (KnowledgeBase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return Default().Merge(extra), nil
+}
+
+// Merge returns kb with extra's entries taking precedence; entries with the
+// same id replace the original
+func (kb KnowledgeBase) Merge(extra KnowledgeBase) KnowledgeBase {
+	ids := map[string]bool{}
+	for _, e := range extra {
+		ids[e.ID] = true
+	}
+	merged := append(KnowledgeBase{}, extra...)
+	for _, e := range kb {
+		if !ids[e.ID] {
+			merged = append(merged, e)
+		}
+	}
+	return merged
+}
+
+// Match returns the first entry matching line, or nil
+func (kb KnowledgeBase) Match(line string) *Entry {
+	for i := range kb {
+		if kb[i].Match.MatchString(line) {
+			return &kb[i]
+		}
+	}
+	return nil
+}
+
+// Detector is an io.Writer that watches trainer output for known errors
+type Detector struct {
+	kb KnowledgeBase
+
+	mu       sync.Mutex
+	lines    *lines.Splitter
+	findings []Entry
+}
+
+// NewDetector creates a detector using kb
+func NewDetector(kb KnowledgeBase) *Detector {
+	return &Detector{kb: kb}
+}
+
+// Write implements io.Writer
+func (d *Detector) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.lines == nil {
+		d.lines = lines.New(func(line []byte) { d.scan(string(line)) })
+	}
+	return d.lines.Write(p)
+}
+
+// CutLines returns the number of output lines too long to scan whole; only
+// their start was checked
+func (d *Detector) CutLines() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.lines == nil {
+		return 0
+	}
+	return d.lines.Cut()
+}
+
+// Scan checks text that didn't pass through the trainer output, such as
+// the supervisor's own error for the run
+func (d *Detector) Scan(text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, line := range strings.Split(text, "\n") {
+		d.scan(line)
+	}
+}
+
+func (d *Detector) scan(line string) {
+	if len(d.findings) >= maxFindings || strings.TrimSpace(line) == "" {
+		return
+	}
+	e := d.kb.Match(line)
+	if e == nil {
+		return
+	}
+	for _, f := range d.findings {
+		if f.ID == e.ID {
+			return
+		}
+	}
+	d.findings = append(d.findings, *e)
+}
+
+// Findings returns the distinct known errors seen so far, in order
+func (d *Detector) Findings() []Entry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Entry(nil), d.findings...)
+}
+
+// Text renders all findings, or "" if there are none
+func (d *Detector) Text() string {
+	var parts []string
+	for _, f := range d.Findings() {
+		parts = append(parts, f.Text())
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,104 @@
+package diagnose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	kb := Default()
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"torch.OutOfMemoryError: CUDA out of memory. Tried to allocate 20.00 MiB", "cuda-oom"},
+		{"Killed", "oom-killed"},
+		{"OSError: [Errno 98] Address already in use", "address-in-use"},
+		{"requests.exceptions.HTTPError: 401 Client Error: Unauthorized", "hf-auth"},
+		{"ModuleNotFoundError: No module named 'trl'", "missing-module"},
+		{"training process hung: no output or GPU activity for 30m0s", "hung"},
+		{"INFO: Starting round: 1234/1000000", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			got := ""
+			if e := kb.Match(c.line); e != nil {
+				got = e.ID
+			}
+			if got != c.want {
+				t.Errorf("Match(%q) = %q, want %q", c.line, got, c.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.yaml")
+	os.WriteFile(path, []byte(`
+- id: cuda-oom
+  match: 'CUDA out of memory'
+  title: Our GPUs are too small
+  explanation: Use the 0.5B model on this fleet.
+- id: custom
+  match: 'flaky mirror'
+  explanation: The package mirror is down again.
+  fix: Wait an hour.
+`), 0o644)
+
+	kb, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if e := kb.Match("CUDA out of memory"); e == nil || e.Title != "Our GPUs are too small" {
+		t.Errorf("Match() = %+v, want the overriding entry", e)
+	}
+	if e := kb.Match("flaky mirror"); e == nil || e.Title != "custom" || len(e.Fix) != 1 {
+		t.Errorf("Match() = %+v, want the custom entry", e)
+	}
+	if e := kb.Match("No space left on device"); e == nil {
+		t.Error("Match() = nil, want the built-in entries to remain")
+	}
+
+	os.WriteFile(path, []byte("- id: broken\n  match: '('\n"), 0o644)
+	if _, err := Load(path); err == nil {
+		t.Error("Load() with an invalid regexp error = nil, want an error")
+	}
+}
+
+func TestDetector(t *testing.T) {
+	d := NewDetector(Default())
+	fmt.Fprint(d, "Traceback (most recent call last):\n  File \"train.py\"\n")
+	fmt.Fprint(d, "torch.OutOfMemoryError: CUDA out of mem")
+	fmt.Fprint(d, "ory\nCUDA out of memory again\n")
+	d.Scan("exit status 137")
+
+	findings := d.Findings()
+	if len(findings) != 2 || findings[0].ID != "cuda-oom" || findings[1].ID != "oom-killed" {
+		t.Fatalf("Findings() = %+v, want cuda-oom then oom-killed", findings)
+	}
+	if text := d.Text(); !strings.Contains(text, "GPU out of memory: ") || !strings.Contains(text, "How to fix:\n  1. ") {
+		t.Errorf("Text() = %q", text)
+	}
+}
+
+func TestDetector_LongLine(t *testing.T) {
+	d := NewDetector(Default())
+	// an error printed with a 1 MB tensor on the same line is still found,
+	// and the detector keeps working afterwards
+	fmt.Fprint(d, "torch.OutOfMemoryError: CUDA out of memory. tensor([")
+	for i := 0; i < 100000; i++ {
+		fmt.Fprint(d, "0.123456, ")
+	}
+	fmt.Fprint(d, "])\nexit status 137\n")
+
+	findings := d.Findings()
+	if len(findings) != 2 || findings[0].ID != "cuda-oom" || findings[1].ID != "oom-killed" {
+		t.Fatalf("Findings() = %+v, want cuda-oom then oom-killed", findings)
+	}
+	if d.CutLines() != 1 {
+		t.Errorf("CutLines() = %d, want 1", d.CutLines())
+	}
+}
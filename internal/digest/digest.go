@@ -0,0 +1,218 @@
+// Package digest schedules the monitor's daily summary at a wall-clock time
+// in a timezone, e.g. 09:00 in Europe/Berlin, across DST changes. The slot
+// last sent is persisted so restarts neither repeat nor skip a digest.
+package digest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/history"
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+)
+
+// StateFile is where the last sent slot is kept, relative to the state
+// directory
+const StateFile = "digest.json"
+
+// Schedule is a daily time in a timezone
+type Schedule struct {
+	Hour, Minute int
+	Location     *time.Location
+}
+
+// Parse reads "HH:MM" or "HH:MM Zone", e.g. "09:00 Europe/Berlin". Without
+// a zone loc is used, normally the configured timezone (see --timezone), or
+// the system timezone when loc is nil.
+func Parse(spec string, loc *time.Location) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid digest time %q, expected HH:MM [timezone]", spec)
+	}
+	h, m, ok := strings.Cut(fields[0], ":")
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hh < 0 || hh > 23 || mm < 0 || mm > 59 {
+		return nil, fmt.Errorf("invalid digest time %q, expected HH:MM [timezone]", spec)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	s := &Schedule{Hour: hh, Minute: mm, Location: loc}
+	if len(fields) == 2 {
+		loc, err := time.LoadLocation(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid digest timezone %q: %w", fields[1], err)
+		}
+		s.Location = loc
+	}
+	return s, nil
+}
+
+// String renders the schedule, e.g. "09:00 Europe/Berlin"
+func (s *Schedule) String() string {
+	return fmt.Sprintf("%02d:%02d %s", s.Hour, s.Minute, s.Location)
+}
+
+// On returns the slot on the given calendar day. A time repeated when the
+// clocks go back is its first occurrence; a time skipped when they go
+// forward is moved forward by the gap, e.g. 02:30 becomes 03:30.
+func (s *Schedule) On(year int, month time.Month, day int) time.Time {
+	wall := time.Date(year, month, day, s.Hour, s.Minute, 0, 0, time.UTC)
+	_, before := wall.Add(-12 * time.Hour).In(s.Location).Zone()
+	_, after := wall.Add(12 * time.Hour).In(s.Location).Zone()
+	first := wall.Add(-time.Duration(before) * time.Second).In(s.Location)
+	if s.matches(first) {
+		return first
+	}
+	if second := wall.Add(-time.Duration(after) * time.Second).In(s.Location); s.matches(second) {
+		return second
+	}
+	return first
+}
+
+func (s *Schedule) matches(t time.Time) bool {
+	return t.Hour() == s.Hour && t.Minute() == s.Minute
+}
+
+// Next returns the first slot after now
+func (s *Schedule) Next(now time.Time) time.Time {
+	local := now.In(s.Location)
+	for i := 0; ; i++ {
+		d := local.AddDate(0, 0, i)
+		if slot := s.On(d.Year(), d.Month(), d.Day()); slot.After(now) {
+			return slot
+		}
+	}
+}
+
+// Prev returns the latest slot at or before now
+func (s *Schedule) Prev(now time.Time) time.Time {
+	local := now.In(s.Location)
+	for i := 0; ; i-- {
+		d := local.AddDate(0, 0, i)
+		if slot := s.On(d.Year(), d.Month(), d.Day()); !slot.After(now) {
+			return slot
+		}
+	}
+}
+
+type state struct {
+	Last time.Time `json:"last"`
+}
+
+// Scheduler tracks which slot was last sent
+type Scheduler struct {
+	Schedule *Schedule
+	path     string
+	last     time.Time
+}
+
+// Load creates a scheduler continuing from the state saved at path
+func Load(path string, s *Schedule) (*Scheduler, error) {
+	sc := &Scheduler{Schedule: s, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return sc, nil
+	}
+	if err != nil {
+		return sc, fmt.Errorf("failed to read digest state: %w", err)
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return sc, fmt.Errorf("failed to parse digest state: %w", err)
+	}
+	sc.last = st.Last
+	return sc, nil
+}
+
+// Due returns the latest slot at or before now when it hasn't been sent
+// yet. Only that slot is returned, so a node that was down for days sends
+// one digest rather than one per missed day. Without saved state the
+// latest slot counts as sent, so a fresh install waits for the next one.
+func (sc *Scheduler) Due(now time.Time) (time.Time, bool) {
+	slot := sc.Schedule.Prev(now)
+	// A last slot in the future was sent while the clock was ahead; the
+	// digest waits for the next slot rather than until that one
+	if sc.last.IsZero() || sc.last.After(now) {
+		sc.last = slot
+		return time.Time{}, false
+	}
+	if !slot.After(sc.last) {
+		return time.Time{}, false
+	}
+	return slot, true
+}
+
+// Mark records slot as sent without saving the state, for state
+// directories that are read-only
+func (sc *Scheduler) Mark(slot time.Time) {
+	sc.last = slot
+}
+
+// Sent records slot as sent and saves the state. Callers record the slot
+// before sending, so a crash while sending can't repeat the digest.
+func (sc *Scheduler) Sent(slot time.Time) error {
+	sc.Mark(slot)
+	data, err := json.MarshalIndent(state{Last: slot}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := sc.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write digest state: %w", err)
+	}
+	return os.Rename(tmp, sc.path)
+}
+
+// Text summarizes the EOA's totals and each peer's change over the last
+// day
+func Text(s *history.Summary, f humanize.Format) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "EOA %s, %d %s\n", s.EOA, len(s.Peers), plural(len(s.Peers), "peer", "peers"))
+	fmt.Fprintf(&b, "Rewards: %s (%s)\n", f.Int(s.Rewards), signed(f, s.RewardsDelta))
+	fmt.Fprintf(&b, "Votes: %s (%s)", humanize.Int(s.Votes), signed(humanize.Format{}, s.VotesDelta))
+	var idle int
+	for _, p := range s.Peers {
+		fmt.Fprintf(&b, "\n%s: %s rewards, %s votes", p.PeerID, signed(f, p.RewardsDelta), signed(humanize.Format{}, p.VotesDelta))
+		if p.RewardsDelta.Sign() == 0 {
+			idle++
+		}
+	}
+	if idle > 0 {
+		fmt.Fprintf(&b, "\n%d %s earned nothing in the last day.", idle, plural(idle, "peer", "peers"))
+	}
+	return b.String()
+}
+
+// CSV is the digest as a spreadsheet: each peer's totals and their change
+// over the last day in base units, then the EOA's
+func CSV(s *history.Summary) []byte {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"peer_id", "votes", "rewards", "votes_24h", "rewards_24h", "since"})
+	for _, p := range s.Peers {
+		w.Write([]string{p.PeerID, p.Votes.String(), p.Rewards.String(), p.VotesDelta.String(), p.RewardsDelta.String(), p.Since.UTC().Format(time.RFC3339)})
+	}
+	w.Write([]string{"total", s.Votes.String(), s.Rewards.String(), s.VotesDelta.String(), s.RewardsDelta.String(), ""})
+	w.Flush()
+	return b.Bytes()
+}
+
+func signed(f humanize.Format, d *big.Int) string {
+	return f.Delta(new(big.Int), d)
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,189 @@
+package digest
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/history"
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+)
+
+func berlin(t *testing.T, spec string) *Schedule {
+	t.Helper()
+	s, err := Parse(spec+" Europe/Berlin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	for _, spec := range []string{"", "9", "24:00", "09:60", "09:00 Mars/Olympus", "09:00 UTC extra"} {
+		if _, err := Parse(spec, nil); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+	s, err := Parse("09:05 Europe/Berlin", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.String(); got != "09:05 Europe/Berlin" {
+		t.Errorf("String() = %q", got)
+	}
+	if s, _ := Parse("07:30", nil); s.Location != time.Local {
+		t.Errorf("Parse without a zone used %v, want the local timezone", s.Location)
+	}
+	if s, _ := Parse("07:30", time.UTC); s.Location != time.UTC {
+		t.Errorf("Parse without a zone used %v, want the given timezone", s.Location)
+	}
+}
+
+func TestOn_DST(t *testing.T) {
+	cases := []struct {
+		name  string
+		spec  string
+		month time.Month
+		day   int
+		want  string
+	}{
+		{"winter", "09:00", time.January, 15, "2025-01-15T08:00:00Z"},
+		{"summer", "09:00", time.July, 15, "2025-07-15T07:00:00Z"},
+		{"spring forward day", "09:00", time.March, 30, "2025-03-30T07:00:00Z"},
+		{"fall back day", "09:00", time.October, 26, "2025-10-26T08:00:00Z"},
+		// 02:30 doesn't exist on 2025-03-30; it is sent at 03:30 CEST
+		{"skipped time", "02:30", time.March, 30, "2025-03-30T01:30:00Z"},
+		// 02:30 happens twice on 2025-10-26; the first, CEST, one is used
+		{"repeated time", "02:30", time.October, 26, "2025-10-26T00:30:00Z"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := berlin(t, c.spec).On(2025, c.month, c.day).UTC().Format(time.RFC3339)
+			if got != c.want {
+				t.Errorf("On() = %s, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestNextPrev(t *testing.T) {
+	s := berlin(t, "09:00")
+	// 2025-03-29 10:00 CET, the day before the clocks go forward
+	now := time.Date(2025, 3, 29, 9, 0, 0, 0, time.UTC)
+	if got, want := s.Next(now), time.Date(2025, 3, 30, 7, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v", got.UTC(), want)
+	}
+	if got, want := s.Prev(now), time.Date(2025, 3, 29, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Prev() = %v, want %v", got.UTC(), want)
+	}
+	// a slot exactly at now is the previous one, not the next
+	slot := time.Date(2025, 3, 29, 8, 0, 0, 0, time.UTC)
+	if !s.Prev(slot).Equal(slot) || s.Next(slot).Equal(slot) {
+		t.Errorf("slot at now: Prev() = %v, Next() = %v", s.Prev(slot), s.Next(slot))
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	s := berlin(t, "09:00")
+	sc, err := Load(path, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a fresh install waits for the next slot
+	start := time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)
+	if _, due := sc.Due(start); due {
+		t.Fatal("fresh scheduler is due")
+	}
+	next := time.Date(2025, 6, 3, 7, 0, 0, 0, time.UTC)
+	if _, due := sc.Due(next.Add(-time.Minute)); due {
+		t.Fatal("due before the slot")
+	}
+	slot, due := sc.Due(next.Add(time.Second))
+	if !due || !slot.Equal(next) {
+		t.Fatalf("Due() = %v, %v, want %v", slot, due, next)
+	}
+	if err := sc.Sent(slot); err != nil {
+		t.Fatal(err)
+	}
+
+	// a restart after sending doesn't repeat the digest
+	sc, err = Load(path, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, due := sc.Due(next.Add(time.Hour)); due {
+		t.Error("sent slot is due again after a restart")
+	}
+
+	// a restart after missing several slots sends the latest one once
+	later := time.Date(2025, 6, 6, 12, 0, 0, 0, time.UTC)
+	slot, due = sc.Due(later)
+	if want := time.Date(2025, 6, 6, 7, 0, 0, 0, time.UTC); !due || !slot.Equal(want) {
+		t.Errorf("Due() after downtime = %v, %v, want %v", slot, due, want)
+	}
+
+	// a slot sent while the clock was a week ahead doesn't hold up the
+	// digest once the clock is corrected
+	if err := sc.Sent(time.Date(2025, 6, 13, 7, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if _, due := sc.Due(later); due {
+		t.Error("due right after the clock went back")
+	}
+	slot, due = sc.Due(time.Date(2025, 6, 7, 7, 1, 0, 0, time.UTC))
+	if want := time.Date(2025, 6, 7, 7, 0, 0, 0, time.UTC); !due || !slot.Equal(want) {
+		t.Errorf("Due() after the clock went back = %v, %v, want %v", slot, due, want)
+	}
+}
+
+func TestLoad_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	if err := (&Scheduler{path: path}).Sent(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, berlin(t, "09:00")); err == nil {
+		t.Error("Load accepted a corrupt state file")
+	}
+}
+
+func TestText(t *testing.T) {
+	s := &history.Summary{
+		EOA:     "0xabc",
+		Rewards: big.NewInt(1500), RewardsDelta: big.NewInt(120),
+		Votes: big.NewInt(40), VotesDelta: big.NewInt(3),
+		Peers: []history.PeerSummary{
+			{PeerID: "QmA", RewardsDelta: big.NewInt(120), VotesDelta: big.NewInt(3)},
+			{PeerID: "QmB", RewardsDelta: big.NewInt(0), VotesDelta: big.NewInt(0)},
+		},
+	}
+	text := Text(s, humanize.Format{Unit: "GSWARM"})
+	for _, want := range []string{"2 peers", "Rewards: 1,500 GSWARM (+120 GSWARM)", "Votes: 40 (+3)", "QmA: +120 GSWARM rewards", "1 peer earned nothing"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	since := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	s := &history.Summary{
+		Votes: big.NewInt(40), Rewards: big.NewInt(1500), VotesDelta: big.NewInt(3), RewardsDelta: big.NewInt(-20),
+		Peers: []history.PeerSummary{
+			{PeerID: "QmA", Votes: big.NewInt(40), Rewards: big.NewInt(1500), VotesDelta: big.NewInt(3), RewardsDelta: big.NewInt(-20), Since: since},
+		},
+	}
+	want := "peer_id,votes,rewards,votes_24h,rewards_24h,since\n" +
+		"QmA,40,1500,3,-20,2025-06-01T09:00:00Z\n" +
+		"total,40,1500,3,-20,\n"
+	if got := string(CSV(s)); got != want {
+		t.Errorf("CSV() =\n%s\nwant\n%s", got, want)
+	}
+}
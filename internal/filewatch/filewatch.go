@@ -0,0 +1,49 @@
+// Package filewatch waits for a file to appear, such as the userData.json
+// the modal login writes. On Linux it watches the file's directory with
+// inotify, so the file is seen as soon as it is written; elsewhere it
+// polls.
+package filewatch
+
+import (
+	"os"
+	"time"
+)
+
+// pollInterval is how often the file is looked for without inotify
+const pollInterval = time.Second
+
+// Wait reports whether path exists or appears within timeout
+func Wait(path string, timeout time.Duration) bool {
+	return WaitValid(path, timeout, nil)
+}
+
+// WaitValid is Wait for a file whose content must also pass valid, as one
+// seen while its writer is still writing it may be empty or cut short. A
+// file that fails is read again when it changes.
+func WaitValid(path string, timeout time.Duration, valid func([]byte) bool) bool {
+	return wait(path, time.Now().Add(timeout), valid)
+}
+
+// poll looks for path every pollInterval until deadline
+func poll(path string, deadline time.Time, valid func([]byte) bool) bool {
+	for {
+		if ready(path, valid) {
+			return true
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			return false
+		}
+		time.Sleep(min(left, pollInterval))
+	}
+}
+
+// ready reports whether path exists and, with valid, passes it
+func ready(path string, valid func([]byte) bool) bool {
+	if valid == nil {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	data, err := os.ReadFile(path)
+	return err == nil && valid(data)
+}
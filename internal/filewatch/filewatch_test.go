@@ -0,0 +1,67 @@
+package filewatch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	// temp-data doesn't exist until the first login
+	path := filepath.Join(t.TempDir(), "temp-data", "userData.json")
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		os.Mkdir(filepath.Dir(path), 0o755)
+		time.Sleep(100 * time.Millisecond)
+		os.WriteFile(path, []byte("{}"), 0o600)
+	}()
+	start := time.Now()
+	if !Wait(path, time.Minute) {
+		t.Fatal("Wait() = false for a file that was written")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Wait() took %s to see the file", elapsed)
+	}
+	if !Wait(path, 0) {
+		t.Error("Wait() = false for an existing file")
+	}
+}
+
+func TestWait_Timeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "userData.json")
+	start := time.Now()
+	if Wait(path, 200*time.Millisecond) {
+		t.Fatal("Wait() = true for a missing file")
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Wait() gave up after %s", elapsed)
+	}
+}
+
+func TestWaitValid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "userData.json")
+	// The writer creates the file, then fills it
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		f.WriteString(`{"orgId":`)
+		time.Sleep(100 * time.Millisecond)
+		f.WriteString(`"org"}`)
+		f.Close()
+	}()
+	if WaitValid(path, 50*time.Millisecond, json.Valid) {
+		t.Fatal("WaitValid() = true for an empty file")
+	}
+	if !WaitValid(path, time.Minute, json.Valid) {
+		t.Fatal("WaitValid() = false for a file that was finished")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != `{"orgId":"org"}` {
+		t.Errorf("WaitValid() returned with %q in the file", data)
+	}
+}
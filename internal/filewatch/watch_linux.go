@@ -0,0 +1,54 @@
+package filewatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// recheckInterval is how often the file is looked for between inotify
+// events, in case the directory is on a file system, such as a network
+// share, whose changes inotify doesn't see
+const recheckInterval = 30 * time.Second
+
+// wait watches path's directory, and its parent until the directory
+// exists, since the modal login creates temp-data with the first login.
+// Without inotify it polls. A file failing valid is looked at again once
+// its writer closes it.
+func wait(path string, deadline time.Time, valid func([]byte) bool) bool {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return poll(path, deadline, valid)
+	}
+	// A non-blocking descriptor goes to the runtime poller, so reads can
+	// have deadlines
+	events := os.NewFile(uintptr(fd), "inotify")
+	defer events.Close()
+
+	dir := filepath.Dir(path)
+	syscall.InotifyAddWatch(fd, filepath.Dir(dir), syscall.IN_CREATE|syscall.IN_MOVED_TO)
+	watching := false
+	buf := make([]byte, 4096)
+	for {
+		if !watching {
+			_, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CREATE|syscall.IN_MOVED_TO|syscall.IN_CLOSE_WRITE)
+			watching = err == nil
+		}
+		// Looking after adding the watch doesn't miss a file written in
+		// between
+		if ready(path, valid) {
+			return true
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			return false
+		}
+		events.SetReadDeadline(time.Now().Add(min(left, recheckInterval)))
+		// Any event or the deadline means looking again
+		if _, err := events.Read(buf); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			return poll(path, deadline, valid)
+		}
+	}
+}
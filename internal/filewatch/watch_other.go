@@ -0,0 +1,10 @@
+//go:build !linux
+
+package filewatch
+
+import "time"
+
+// wait polls, for want of inotify
+func wait(path string, deadline time.Time, valid func([]byte) bool) bool {
+	return poll(path, deadline, valid)
+}
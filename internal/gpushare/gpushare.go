@@ -0,0 +1,196 @@
+// Package gpushare lets several gswarm instances share one GPU. Each can
+// train in its own time slice, CUDA MPS can split the GPU's compute between
+// them, and the memory-hungry start of a run, while the model loads, is
+// serialized across instances with a lock file per GPU. On a machine with
+// several GPUs, instances can instead be placed on the least busy one.
+package gpushare
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSlice is how long each instance trains before the next one's turn
+const DefaultSlice = time.Hour
+
+// MPSThreadPercentageEnv limits the share of the GPU's SMs a process gets
+// under CUDA MPS
+const MPSThreadPercentageEnv = "CUDA_MPS_ACTIVE_THREAD_PERCENTAGE"
+
+// Share is this instance's turn on a time-sliced GPU: instance Index
+// (1-based) of Count trains during every Count-th slice of the day
+type Share struct {
+	Index int
+	Count int
+	Slice time.Duration
+}
+
+// ParseShare parses "i/n", e.g. "2/3" for the second of three instances.
+// An empty spec means the GPU isn't time-sliced.
+func ParseShare(spec string, slice time.Duration) (Share, error) {
+	if spec == "" {
+		return Share{}, nil
+	}
+	i, n, ok := strings.Cut(spec, "/")
+	index, err1 := strconv.Atoi(strings.TrimSpace(i))
+	count, err2 := strconv.Atoi(strings.TrimSpace(n))
+	if !ok || err1 != nil || err2 != nil || count < 1 || index < 1 || index > count {
+		return Share{}, fmt.Errorf("invalid GPU share %q, expected i/n such as 1/2", spec)
+	}
+	if slice < time.Minute || slice%time.Minute != 0 || (24*time.Hour)%slice != 0 {
+		return Share{}, fmt.Errorf("invalid GPU slice %s: must be whole minutes and divide 24h evenly", slice)
+	}
+	if slots := int(24 * time.Hour / slice); slots%count != 0 {
+		return Share{}, fmt.Errorf("invalid GPU slice %s: the day's %d slices can't be shared evenly by %d instances", slice, slots, count)
+	}
+	return Share{Index: index, Count: count, Slice: slice}, nil
+}
+
+// Enabled reports whether the GPU is time-sliced
+func (s Share) Enabled() bool {
+	return s.Count > 1
+}
+
+// PauseWindows returns daily pause windows covering the other instances'
+// slices, in the format the schedule package parses
+func (s Share) PauseWindows() []string {
+	if !s.Enabled() {
+		return nil
+	}
+	var windows []string
+	minutes := int(s.Slice / time.Minute)
+	for slot := 0; slot*minutes < 24*60; slot++ {
+		if slot%s.Count == s.Index-1 {
+			continue
+		}
+		start := slot * minutes
+		windows = append(windows, fmt.Sprintf("%s-%s", clock(start), clock(start+minutes)))
+	}
+	return windows
+}
+
+func clock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// Device names the GPUs a trainer with env will use, from
+// CUDA_VISIBLE_DEVICES, so instances pinned to different GPUs don't wait
+// for each other. The last setting in env wins, as it does for exec. The
+// GPUs are sorted, so "1,0" names the same ones as "0,1".
+func Device(env []string) string {
+	var devices []string
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "CUDA_VISIBLE_DEVICES="); ok && strings.TrimSpace(v) != "" {
+			devices = devices[:0]
+			for _, d := range strings.Split(v, ",") {
+				if d = strings.TrimSpace(d); d != "" && !slices.Contains(devices, d) {
+					devices = append(devices, d)
+				}
+			}
+		}
+	}
+	if len(devices) == 0 {
+		return "all"
+	}
+	sort.Strings(devices)
+	return strings.Join(devices, ",")
+}
+
+// LockDir is where the per-GPU lock files and the placements live. It is
+// private to the user, so the instances coordinating must run as the same
+// user, as a fleet's do; without a user cache directory it falls back to
+// stateDir.
+func LockDir(stateDir string) string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "gswarm", "gpu")
+	}
+	return filepath.Join(stateDir, "gpu")
+}
+
+// Lock is held by the instance in its heavy phase on a GPU
+type Lock struct {
+	files []*os.File
+	once  sync.Once
+}
+
+// pollInterval is how often a waiting instance retries the lock
+var pollInterval = time.Second
+
+// Acquire takes the lock for each GPU of device, as Device names them, in
+// dir, waiting until no other instance holds any of them or ctx is done.
+// waiting is called once if a lock is busy.
+func Acquire(ctx context.Context, dir, device string, waiting func()) (*Lock, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	// Locks are taken in sorted order, so instances sharing some of their
+	// GPUs can't each hold one the other waits for
+	ids := strings.Split(device, ",")
+	sort.Strings(ids)
+	lock := &Lock{}
+	notified := false
+	for _, id := range ids {
+		name := "gpu-" + strings.NewReplacer("/", "_", " ", "").Replace(id) + ".lock"
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			lock.Release()
+			return nil, err
+		}
+		for {
+			ok, err := tryLock(f)
+			if err != nil {
+				f.Close()
+				lock.Release()
+				return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+			}
+			if ok {
+				lock.files = append(lock.files, f)
+				break
+			}
+			if !notified && waiting != nil {
+				waiting()
+				notified = true
+			}
+			select {
+			case <-ctx.Done():
+				f.Close()
+				lock.Release()
+				return nil, ctx.Err()
+			case <-time.After(pollInterval):
+			}
+		}
+	}
+	return lock, nil
+}
+
+// Release lets the next instance start its heavy phase; it is safe to
+// call more than once
+func (l *Lock) Release() {
+	l.once.Do(func() {
+		for _, f := range l.files {
+			unlock(f)
+			f.Close()
+		}
+	})
+}
+
+// MPSRunning reports whether a CUDA MPS control daemon appears to be
+// running, judged by its pipe directory
+func MPSRunning() bool {
+	dir := os.Getenv("CUDA_MPS_PIPE_DIRECTORY")
+	if dir == "" {
+		dir = "/tmp/nvidia-mps"
+	}
+	_, err := os.Stat(filepath.Join(dir, "control"))
+	return err == nil
+}
@@ -0,0 +1,122 @@
+package gpushare
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseShare(t *testing.T) {
+	cases := []struct {
+		spec    string
+		slice   time.Duration
+		want    Share
+		wantErr bool
+	}{
+		{"", time.Hour, Share{}, false},
+		{"2/3", time.Hour, Share{Index: 2, Count: 3, Slice: time.Hour}, false},
+		{"1/2", 30 * time.Minute, Share{Index: 1, Count: 2, Slice: 30 * time.Minute}, false},
+		{"3/2", time.Hour, Share{}, true},
+		{"0/2", time.Hour, Share{}, true},
+		{"two", time.Hour, Share{}, true},
+		{"1/2", 7 * time.Hour, Share{}, true},
+		{"1/5", time.Hour, Share{}, true},
+		{"1/2", 90 * time.Second, Share{}, true},
+	}
+	for _, c := range cases {
+		got, err := ParseShare(c.spec, c.slice)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("ParseShare(%q, %s) = %+v, %v; want %+v, error %v", c.spec, c.slice, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestShare_PauseWindows(t *testing.T) {
+	s := Share{Index: 2, Count: 2, Slice: 6 * time.Hour}
+	want := []string{"00:00-06:00", "12:00-18:00"}
+	if got := s.PauseWindows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PauseWindows() = %v, want %v", got, want)
+	}
+	s = Share{Index: 1, Count: 3, Slice: 8 * time.Hour}
+	want = []string{"08:00-16:00", "16:00-24:00"}
+	if got := s.PauseWindows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PauseWindows() = %v, want %v", got, want)
+	}
+	if got := (Share{}).PauseWindows(); got != nil {
+		t.Errorf("PauseWindows() without sharing = %v, want none", got)
+	}
+}
+
+func TestDevice(t *testing.T) {
+	if got := Device([]string{"PATH=/bin"}); got != "all" {
+		t.Errorf("Device() = %q, want all", got)
+	}
+	if got := Device([]string{"CUDA_VISIBLE_DEVICES=0", "CUDA_VISIBLE_DEVICES=1"}); got != "1" {
+		t.Errorf("Device() = %q, want the last setting", got)
+	}
+	if got := Device([]string{"CUDA_VISIBLE_DEVICES= 1,0,1 "}); got != "0,1" {
+		t.Errorf("Device() = %q, want 0,1", got)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	pollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	first, err := Acquire(ctx, dir, "0", nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	// A different GPU doesn't wait
+	other, err := Acquire(ctx, dir, "1", func() { t.Error("GPU 1 waited for GPU 0") })
+	if err != nil {
+		t.Fatalf("Acquire(1) error = %v", err)
+	}
+	other.Release()
+
+	waited := make(chan struct{})
+	acquired := make(chan *Lock)
+	go func() {
+		l, err := Acquire(ctx, dir, "0", func() { close(waited) })
+		if err != nil {
+			t.Errorf("second Acquire() error = %v", err)
+		}
+		acquired <- l
+	}()
+	<-waited
+	first.Release()
+	first.Release()
+	select {
+	case l := <-acquired:
+		l.Release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("second instance never got the lock")
+	}
+
+	// Instances sharing one of their GPUs wait for each other
+	pair, err := Acquire(ctx, dir, "0,1", nil)
+	if err != nil {
+		t.Fatalf("Acquire(0,1) error = %v", err)
+	}
+	busy, cancelBusy := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelBusy()
+	if _, err := Acquire(busy, dir, "1", nil); err == nil {
+		t.Error("Acquire(1) while 0,1 is held expected error")
+	}
+	pair.Release()
+	if info, err := os.Stat(filepath.Join(dir, "gpu-1.lock")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("lock file mode = %v (%v), want 0600", info, err)
+	}
+
+	held, _ := Acquire(ctx, dir, "0", nil)
+	defer held.Release()
+	cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := Acquire(cctx, dir, "0", nil); err == nil {
+		t.Error("Acquire() with a cancelled context expected error")
+	}
+}
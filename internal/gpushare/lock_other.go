@@ -0,0 +1,12 @@
+//go:build !unix
+
+package gpushare
+
+import "os"
+
+// Without flock, instances don't wait for each other
+func tryLock(*os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(*os.File) {}
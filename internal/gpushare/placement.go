@@ -0,0 +1,206 @@
+package gpushare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/atomicfile"
+)
+
+// Auto asks for the instance to be placed on a GPU automatically
+const Auto = "auto"
+
+// PinFile records the GPU an instance was placed on, in its state directory
+const PinFile = "gpu.json"
+
+// placementsFile lists every instance's pinned GPU, next to the lock files
+const placementsFile = "placements.json"
+
+// GPU is one NVIDIA GPU as nvidia-smi reports it
+type GPU struct {
+	Index    int
+	UUID     string
+	Name     string
+	FreeMiB  int
+	TotalMiB int
+}
+
+// Pin is the GPU an instance was placed on. The UUID identifies it, since
+// indexes can change when GPUs are added or removed.
+type Pin struct {
+	UUID   string    `json:"uuid"`
+	Index  int       `json:"index"`
+	Name   string    `json:"name"`
+	Placed time.Time `json:"placed"`
+}
+
+// Placement is the outcome of Place
+type Placement struct {
+	GPU GPU
+	// Reused is set when the instance went back to its pinned GPU
+	Reused bool
+	// Shared is set when every GPU already had an instance and this one
+	// was added to the least busy
+	Shared bool
+	// Lost is the pinned GPU that is no longer present, if any
+	Lost *Pin
+}
+
+// QueryGPUs lists the machine's GPUs with their free memory
+func QueryGPUs() ([]GPU, error) {
+	out, err := exec.Command("nvidia-smi",
+		"--query-gpu=index,uuid,name,memory.free,memory.total",
+		"--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi failed: %w", err)
+	}
+	return ParseGPUs(string(out))
+}
+
+// ParseGPUs parses nvidia-smi's CSV output for QueryGPUs
+func ParseGPUs(out string) ([]GPU, error) {
+	var gpus []GPU
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected nvidia-smi output %q", line)
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		index, err1 := strconv.Atoi(fields[0])
+		free, err2 := strconv.Atoi(fields[3])
+		total, err3 := strconv.Atoi(fields[4])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("unexpected nvidia-smi output %q", line)
+		}
+		gpus = append(gpus, GPU{Index: index, UUID: fields[1], Name: fields[2], FreeMiB: free, TotalMiB: total})
+	}
+	if len(gpus) == 0 {
+		return nil, fmt.Errorf("nvidia-smi found no GPUs")
+	}
+	return gpus, nil
+}
+
+// Place chooses the GPU for the instance whose state lives in stateDir. A
+// GPU pinned by an earlier start is reused while it is present, so a
+// restarted instance finds its model cache and memory where it left them.
+// Otherwise the instance goes to the GPU with the fewest other instances
+// and then the most free memory, and the choice is pinned. Every instance's
+// pin is also listed in dir, under a lock, so instances starting at the
+// same time don't all pick the same idle GPU.
+func Place(ctx context.Context, dir, stateDir string, gpus []GPU) (Placement, error) {
+	if len(gpus) == 0 {
+		return Placement{}, fmt.Errorf("no GPUs to place the instance on")
+	}
+	stateDir, err := filepath.Abs(stateDir)
+	if err != nil {
+		return Placement{}, err
+	}
+	lock, err := Acquire(ctx, dir, "placement", nil)
+	if err != nil {
+		return Placement{}, fmt.Errorf("failed to lock GPU placement: %w", err)
+	}
+	defer lock.Release()
+
+	var placement Placement
+	pin, err := ReadPin(stateDir)
+	if err != nil {
+		return Placement{}, err
+	}
+	if pin != nil {
+		for _, gpu := range gpus {
+			if gpu.UUID == pin.UUID {
+				placement = Placement{GPU: gpu, Reused: true}
+				break
+			}
+		}
+		if !placement.Reused {
+			placement.Lost = pin
+		}
+	}
+
+	placements := readPlacements(dir)
+	if !placement.Reused {
+		// Count the other instances on each GPU, forgetting those whose
+		// state directory no longer pins it
+		busy := make(map[string]int)
+		for other, uuid := range placements {
+			if other == stateDir {
+				continue
+			}
+			if p, err := ReadPin(other); err != nil || p == nil || p.UUID != uuid {
+				delete(placements, other)
+				continue
+			}
+			busy[uuid]++
+		}
+		candidates := append([]GPU(nil), gpus...)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if busy[a.UUID] != busy[b.UUID] {
+				return busy[a.UUID] < busy[b.UUID]
+			}
+			if a.FreeMiB != b.FreeMiB {
+				return a.FreeMiB > b.FreeMiB
+			}
+			return a.Index < b.Index
+		})
+		placement.GPU = candidates[0]
+		placement.Shared = busy[placement.GPU.UUID] > 0
+
+		gpu := placement.GPU
+		err := atomicfile.WriteJSON(filepath.Join(stateDir, PinFile), Pin{UUID: gpu.UUID, Index: gpu.Index, Name: gpu.Name, Placed: time.Now()}, 0o644)
+		if err != nil {
+			return Placement{}, fmt.Errorf("failed to pin GPU: %w", err)
+		}
+	}
+
+	placements[stateDir] = placement.GPU.UUID
+	if err := atomicfile.WriteJSON(filepath.Join(dir, placementsFile), placements, 0o600); err != nil {
+		return Placement{}, fmt.Errorf("failed to record GPU placement: %w", err)
+	}
+	return placement, nil
+}
+
+// ReadPin returns the GPU pinned in stateDir, or nil if there is none
+func ReadPin(stateDir string) (*Pin, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, PinFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GPU pin: %w", err)
+	}
+	var pin Pin
+	if err := json.Unmarshal(data, &pin); err != nil || pin.UUID == "" {
+		// A damaged pin is replaced by a new placement
+		return nil, nil
+	}
+	return &pin, nil
+}
+
+// readPlacements loads the instances' pins, keyed by state directory; a
+// missing or damaged list starts empty
+func readPlacements(dir string) map[string]string {
+	placements := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(dir, placementsFile))
+	if err == nil {
+		json.Unmarshal(data, &placements)
+	}
+	if placements == nil {
+		placements = make(map[string]string)
+	}
+	return placements
+}
@@ -0,0 +1,80 @@
+package gpushare
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGPUs(t *testing.T) {
+	out := "0, GPU-aaa, NVIDIA GeForce RTX 3090, 1200, 24576\n1, GPU-bbb, NVIDIA GeForce RTX 3090, 23000, 24576\n"
+	gpus, err := ParseGPUs(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GPU{
+		{Index: 0, UUID: "GPU-aaa", Name: "NVIDIA GeForce RTX 3090", FreeMiB: 1200, TotalMiB: 24576},
+		{Index: 1, UUID: "GPU-bbb", Name: "NVIDIA GeForce RTX 3090", FreeMiB: 23000, TotalMiB: 24576},
+	}
+	if len(gpus) != len(want) || gpus[0] != want[0] || gpus[1] != want[1] {
+		t.Errorf("ParseGPUs() = %+v, want %+v", gpus, want)
+	}
+
+	for _, bad := range []string{"", "0, GPU-aaa, RTX, [N/A], 24576", "0, GPU-aaa"} {
+		if _, err := ParseGPUs(bad); err == nil {
+			t.Errorf("ParseGPUs(%q) should fail", bad)
+		}
+	}
+}
+
+func TestPlace(t *testing.T) {
+	dir := t.TempDir()
+	lockDir := filepath.Join(dir, "locks")
+	gpus := []GPU{
+		{Index: 0, UUID: "GPU-aaa", FreeMiB: 1000},
+		{Index: 1, UUID: "GPU-bbb", FreeMiB: 20000},
+		{Index: 2, UUID: "GPU-ccc", FreeMiB: 10000},
+	}
+	place := func(instance string, gpus []GPU) Placement {
+		t.Helper()
+		p, err := Place(context.Background(), lockDir, filepath.Join(dir, instance), gpus)
+		if err != nil {
+			t.Fatalf("Place(%s) error: %v", instance, err)
+		}
+		return p
+	}
+
+	// Instances spread over the GPUs by free memory
+	if p := place("a", gpus); p.GPU.UUID != "GPU-bbb" || p.Reused || p.Shared {
+		t.Errorf("first instance = %+v, want GPU-bbb", p)
+	}
+	if p := place("b", gpus); p.GPU.UUID != "GPU-ccc" || p.Shared {
+		t.Errorf("second instance = %+v, want GPU-ccc", p)
+	}
+	if p := place("c", gpus); p.GPU.UUID != "GPU-aaa" || p.Shared {
+		t.Errorf("third instance = %+v, want GPU-aaa", p)
+	}
+	if p := place("d", gpus); p.GPU.UUID != "GPU-bbb" || !p.Shared {
+		t.Errorf("fourth instance = %+v, want to share GPU-bbb", p)
+	}
+
+	// A restart goes back to the pinned GPU even if another has more memory
+	gpus[2].FreeMiB = 40000
+	if p := place("a", gpus); p.GPU.UUID != "GPU-bbb" || !p.Reused {
+		t.Errorf("restarted instance = %+v, want to reuse GPU-bbb", p)
+	}
+
+	// A removed instance no longer counts, and a lost GPU is replaced
+	if err := os.RemoveAll(filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+	p := place("c", gpus[1:])
+	if p.Lost == nil || p.Lost.UUID != "GPU-aaa" || p.GPU.UUID != "GPU-ccc" || p.Shared {
+		t.Errorf("instance on a removed GPU = %+v, want GPU-ccc", p)
+	}
+	pin, err := ReadPin(filepath.Join(dir, "c"))
+	if err != nil || pin == nil || pin.UUID != "GPU-ccc" {
+		t.Errorf("ReadPin() = %+v, %v; want GPU-ccc", pin, err)
+	}
+}
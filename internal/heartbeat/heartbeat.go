@@ -0,0 +1,62 @@
+// Package heartbeat pings a dead-man's-switch endpoint, such as a
+// healthchecks.io check, so an external service can alert when a node goes
+// silent because the whole machine or VM died.
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/httpclient"
+)
+
+// DefaultInterval is how often heartbeats are sent
+const DefaultInterval = time.Minute
+
+// Pinger sends heartbeats to URL
+type Pinger struct {
+	URL string
+	// Client defaults to the shared gswarm client
+	Client *http.Client
+}
+
+// Ping sends one heartbeat
+func (p *Pinger) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return err
+	}
+	client := p.Client
+	if client == nil {
+		client = httpclient.Default()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("heartbeat failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("heartbeat failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// Run pings every interval until ctx is done, passing failures to onError
+func (p *Pinger) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Ping(ctx); err != nil && ctx.Err() == nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPinger_Ping(t *testing.T) {
+	pings := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/abc" {
+			http.NotFound(w, r)
+			return
+		}
+		pings++
+	}))
+	defer srv.Close()
+
+	if err := (&Pinger{URL: srv.URL + "/abc"}).Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if pings != 1 {
+		t.Errorf("pings = %d, want 1", pings)
+	}
+	if err := (&Pinger{URL: srv.URL + "/missing"}).Ping(context.Background()); err == nil {
+		t.Error("Ping() to a 404 error = nil, want an error")
+	}
+}
@@ -0,0 +1,189 @@
+// Package hfpush paces the trainer's pushes to the Hugging Face Hub. With a
+// token set the trainer pushes the model every few rounds, which can
+// saturate a slow uplink and trip Hugging Face's rate limits mid-training.
+//
+// The trainer has no option for this, so a sitecustomize module is put on
+// its PYTHONPATH. It wraps huggingface_hub's commit call and skips pushes
+// outside the allowed windows, within the minimum interval of the last
+// one, or during a backoff after a rate limit. Each attempt is reported on
+// stderr, where Monitor picks it up.
+package hfpush
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/lines"
+	"github.com/Deep-Commit/gswarm/internal/schedule"
+)
+
+// Dir holds the shim and the push state, relative to the state directory
+const Dir = "hfpush"
+
+// StateFile records the last push, relative to Dir
+const StateFile = "state.json"
+
+// DefaultBackoff is how long pushes wait after a rate limit
+const DefaultBackoff = time.Hour
+
+// FailureAlert is the number of failed pushes in a row worth an alert
+const FailureAlert = 3
+
+//go:embed sitecustomize.py
+var shim []byte
+
+// Policy is when pushes may happen
+type Policy struct {
+	// MinInterval is the least time between pushes
+	MinInterval time.Duration
+	// Windows are when pushes are allowed; empty allows any time
+	Windows schedule.Schedule
+	// Backoff is how long pushes wait after Hugging Face rate-limited one
+	Backoff time.Duration
+}
+
+// Enabled reports whether pushes are paced at all
+func (p Policy) Enabled() bool {
+	return p.MinInterval > 0 || len(p.Windows) > 0
+}
+
+// Install writes the shim into dir and returns the environment that makes
+// the trainer load it; env is the trainer's environment so far, for its
+// PYTHONPATH. A sitecustomize already on the path is shadowed.
+func (p Policy) Install(dir string, env []string) ([]string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to install the Hugging Face push shim: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(abs, "sitecustomize.py"), shim, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to install the Hugging Face push shim: %w", err)
+	}
+
+	path := abs
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "PYTHONPATH="); ok && v != "" {
+			path += string(os.PathListSeparator) + v
+		}
+	}
+	return []string{
+		"PYTHONPATH=" + path,
+		fmt.Sprintf("GSWARM_HF_PUSH_MIN_INTERVAL=%d", int64(p.MinInterval.Seconds())),
+		fmt.Sprintf("GSWARM_HF_PUSH_BACKOFF=%d", int64(p.Backoff.Seconds())),
+		"GSWARM_HF_PUSH_WINDOWS=" + encodeWindows(p.Windows),
+		"GSWARM_HF_PUSH_STATE=" + filepath.Join(abs, StateFile),
+	}, nil
+}
+
+// encodeWindows passes windows to the shim as start-end-days, with days a
+// bitmask from Sunday
+func encodeWindows(s schedule.Schedule) string {
+	parts := make([]string, len(s))
+	for i, w := range s {
+		days := 0
+		for d, on := range w.Days {
+			if on {
+				days |= 1 << d
+			}
+		}
+		parts[i] = fmt.Sprintf("%d-%d-%d", w.Start, w.End, days)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Describe summarises the policy for the console
+func (p Policy) Describe() string {
+	var parts []string
+	if p.MinInterval > 0 {
+		parts = append(parts, fmt.Sprintf("at most every %s", p.MinInterval))
+	}
+	if len(p.Windows) > 0 {
+		parts = append(parts, "only during "+strings.Join(p.Windows.Specs(), ", "))
+	}
+	if p.Backoff > 0 {
+		parts = append(parts, fmt.Sprintf("waiting %s after a rate limit", p.Backoff))
+	}
+	return "Hugging Face pushes " + strings.Join(parts, ", ")
+}
+
+var linePattern = regexp.MustCompile(`\[gswarm-hf-push\] (ok|skipped|failed)(?:: (.*))?`)
+
+// Stats counts the push attempts the shim reported
+type Stats struct {
+	Pushed, Skipped, Failed int
+	// Consecutive is the number of failures since the last success
+	Consecutive int
+	LastError   string
+	LastPush    time.Time
+}
+
+// Monitor reads the trainer's output for the shim's reports
+type Monitor struct {
+	// OnFailure, if set, is called after each failed push
+	OnFailure func(Stats)
+
+	mu       sync.Mutex
+	lines    *lines.Splitter
+	failures []Stats
+	stats    Stats
+}
+
+// Write implements io.Writer
+func (m *Monitor) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	if m.lines == nil {
+		m.lines = lines.New(func(line []byte) {
+			if m.scan(line) {
+				m.failures = append(m.failures, m.stats)
+			}
+		})
+	}
+	m.lines.Write(p)
+	failures := m.failures
+	m.failures = nil
+	m.mu.Unlock()
+
+	if m.OnFailure != nil {
+		for _, s := range failures {
+			m.OnFailure(s)
+		}
+	}
+	return len(p), nil
+}
+
+// scan counts a report line, returning true for a failure
+func (m *Monitor) scan(line []byte) bool {
+	match := linePattern.FindSubmatch(line)
+	if match == nil {
+		return false
+	}
+	switch string(match[1]) {
+	case "ok":
+		m.stats.Pushed++
+		m.stats.Consecutive = 0
+		m.stats.LastPush = time.Now()
+	case "skipped":
+		m.stats.Skipped++
+	case "failed":
+		m.stats.Failed++
+		m.stats.Consecutive++
+		m.stats.LastError = string(match[2])
+		return true
+	}
+	return false
+}
+
+// Stats returns the counts so far
+func (m *Monitor) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
@@ -0,0 +1,229 @@
+package hfpush
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/schedule"
+)
+
+func TestInstallEnv(t *testing.T) {
+	sched, err := schedule.Parse([]string{"01:00-06:00 weekdays"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Policy{MinInterval: time.Hour, Windows: sched, Backoff: 2 * time.Hour}
+	dir := t.TempDir()
+	env, err := p.Install(dir, []string{"PYTHONPATH=/opt/lib", "HOME=/root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"PYTHONPATH":                  dir + string(os.PathListSeparator) + "/opt/lib",
+		"GSWARM_HF_PUSH_MIN_INTERVAL": "3600",
+		"GSWARM_HF_PUSH_BACKOFF":      "7200",
+		"GSWARM_HF_PUSH_WINDOWS":      "60-360-62",
+		"GSWARM_HF_PUSH_STATE":        filepath.Join(dir, StateFile),
+	}
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		if want[k] != v {
+			t.Errorf("%s = %q, want %q", k, v, want[k])
+		}
+		delete(want, k)
+	}
+	if len(want) > 0 {
+		t.Errorf("missing %v", want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sitecustomize.py")); err != nil {
+		t.Error(err)
+	}
+	if !p.Enabled() || (Policy{Backoff: time.Hour}).Enabled() {
+		t.Error("Enabled() is wrong")
+	}
+}
+
+// fakeHub is a minimal huggingface_hub laid out like the real one: the
+// module-level create_commit is bound to a shared HfApi at import time,
+// CommitInfo is a str as in recent releases, and upload_file and
+// upload_folder use the commit create_commit returns the way the real ones do
+const fakeHub = `
+class HTTPError(Exception):
+    def __init__(self, msg, status):
+        super().__init__(msg)
+        self.response = type("R", (), {"status_code": status})()
+
+class CommitInfo(str):
+    def __new__(cls, *args, commit_url, _url=None, **kwargs):
+        return str.__new__(cls, _url or commit_url)
+    def __init__(self, *args, commit_url, commit_message, commit_description, oid, pr_url=None, _url=None):
+        self.commit_url = commit_url
+        self.commit_message = commit_message
+        self.commit_description = commit_description
+        self.oid = oid
+        self.pr_url = pr_url
+
+class HfApi:
+    endpoint = "https://huggingface.co"
+    fail = None
+    def create_commit(self, repo_id, operations=None, *, commit_message="", **kwargs):
+        if HfApi.fail:
+            raise HTTPError("429 Client Error: Too Many Requests", HfApi.fail)
+        print("committed", repo_id)
+        return CommitInfo(commit_url=self.endpoint + "/" + repo_id + "/commit/abc", commit_message=commit_message, commit_description="", oid="abc")
+    def upload_file(self, *, path_or_fileobj, path_in_repo, repo_id, commit_message=None):
+        info = self.create_commit(repo_id=repo_id, operations=[path_in_repo], commit_message=commit_message or "Upload " + path_in_repo)
+        revision = info.pr_url.rsplit("/", 1)[-1] if info.pr_url is not None else "main"
+        return CommitInfo(commit_url=info.commit_url, commit_message=info.commit_message, commit_description=info.commit_description,
+            oid=info.oid, pr_url=info.pr_url, _url=self.endpoint + "/" + repo_id + "/blob/" + revision + "/" + path_in_repo)
+    def upload_folder(self, *, repo_id, folder_path, commit_message=None):
+        info = self.create_commit(repo_id=repo_id, operations=[folder_path], commit_message=commit_message or "Upload folder using huggingface_hub")
+        return CommitInfo(commit_url=info.commit_url, commit_message=info.commit_message, commit_description=info.commit_description,
+            oid=info.oid, pr_url=info.pr_url, _url=self.endpoint + "/" + repo_id + "/tree/main/")
+
+api = HfApi()
+create_commit = api.create_commit
+`
+
+func TestShim(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	dir := t.TempDir()
+	hub := filepath.Join(dir, "lib", "huggingface_hub")
+	os.MkdirAll(hub, 0o755)
+	os.WriteFile(filepath.Join(hub, "hf_api.py"), []byte(fakeHub), 0o644)
+	os.WriteFile(filepath.Join(hub, "__init__.py"), []byte("from .hf_api import create_commit\n"), 0o644)
+
+	run := func(p Policy, script string) string {
+		env, err := p.Install(filepath.Join(dir, Dir), []string{"PYTHONPATH=" + filepath.Join(dir, "lib")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(python, "-c", script)
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("python: %v\n%s", err, out)
+		}
+		return string(out)
+	}
+
+	p := Policy{MinInterval: time.Hour, Backoff: time.Hour}
+	out := run(p, `
+import huggingface_hub
+from huggingface_hub import hf_api
+print(huggingface_hub.create_commit("a"))
+info = hf_api.HfApi().upload_folder(repo_id="b", folder_path="out")
+print("folder", info, info.commit_url, repr(info.oid))
+info = hf_api.HfApi().upload_file(path_or_fileobj=b"x", path_in_repo="x.txt", repo_id="c")
+print("file", info.commit_url, info.pr_url)
+`)
+	for _, want := range []string{
+		"committed a",
+		"skipped: next push allowed in 60m",
+		"folder https://huggingface.co/b/tree/main/ https://huggingface.co/b ''",
+		"file https://huggingface.co/c None",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "committed b") || strings.Contains(out, "committed c") {
+		t.Errorf("skipped pushes were committed:\n%s", out)
+	}
+
+	m := &Monitor{}
+	m.Write([]byte(out))
+	if s := m.Stats(); s.Pushed != 1 || s.Skipped != 2 {
+		t.Errorf("Stats() = %+v", s)
+	}
+
+	// A rate limit backs off even without a minimum interval
+	os.Remove(filepath.Join(dir, Dir, StateFile))
+	out = run(Policy{Windows: schedule.Schedule{{Start: 0, End: 0, Days: [7]bool{true, true, true, true, true, true, true}}}, Backoff: time.Hour}, `
+from huggingface_hub import hf_api
+hf_api.HfApi.fail = 429
+try:
+    hf_api.create_commit("a")
+except Exception as e:
+    print("raised", e)
+hf_api.HfApi.fail = None
+print(hf_api.create_commit("b"))
+`)
+	if !strings.Contains(out, "failed: 429 Client Error") || !strings.Contains(out, "raised 429") || strings.Contains(out, "committed b") {
+		t.Errorf("output:\n%s", out)
+	}
+
+	// Outside every window nothing is pushed
+	out = run(Policy{Windows: schedule.Schedule{{Start: 0, End: 0}}}, `
+from huggingface_hub import hf_api
+print(hf_api.create_commit("a").commit_url)
+`)
+	if !strings.Contains(out, "skipped: outside the push window") || !strings.Contains(out, "https://huggingface.co/a") {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+// TestShim_HuggingFaceHub runs skipped pushes through the installed
+// huggingface_hub's upload_file and upload_folder, which read the commit
+// create_commit returns
+func TestShim_HuggingFaceHub(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	if err := exec.Command(python, "-c", "import huggingface_hub").Run(); err != nil {
+		t.Skip("huggingface_hub not installed")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "model.txt"), []byte("weights"), 0o644)
+	env, err := Policy{Windows: schedule.Schedule{{Start: 0, End: 0}}}.Install(filepath.Join(dir, Dir), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(python, "-c", `
+import sys
+from huggingface_hub import HfApi
+api = HfApi(token="hf_gswarm_test")
+print("file", api.upload_file(path_or_fileobj=b"x", path_in_repo="x.txt", repo_id="gswarm/test").commit_url)
+print("folder", api.upload_folder(repo_id="gswarm/test", folder_path=sys.argv[1]).commit_url)
+`, dir)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("python: %v\n%s", err, out)
+	}
+	if strings.Count(string(out), "skipped: outside the push window") != 2 ||
+		!strings.Contains(string(out), "file https://huggingface.co/gswarm/test") || !strings.Contains(string(out), "folder https://huggingface.co/gswarm/test") {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	var alerts []Stats
+	m := &Monitor{OnFailure: func(s Stats) { alerts = append(alerts, s) }}
+	m.Write([]byte("round 3\n[gswarm-hf-push] failed: 503 Server Error\n[gswarm-hf-push] fail"))
+	m.Write([]byte("ed: timed out\r\n"))
+	if len(alerts) != 2 || alerts[1].Consecutive != 2 || alerts[1].LastError != "timed out" {
+		t.Errorf("alerts = %+v", alerts)
+	}
+	m.Write([]byte("[gswarm-hf-push] ok\n"))
+	if s := m.Stats(); s.Pushed != 1 || s.Failed != 2 || s.Consecutive != 0 || s.LastPush.IsZero() {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	sched, _ := schedule.Parse([]string{"01:00-06:00"})
+	got := Policy{MinInterval: 2 * time.Hour, Windows: sched, Backoff: time.Hour}.Describe()
+	want := "Hugging Face pushes at most every 2h0m0s, only during 01:00-06:00, waiting 1h0m0s after a rate limit"
+	if got != want {
+		t.Errorf("Describe() = %q, want %q", got, want)
+	}
+}
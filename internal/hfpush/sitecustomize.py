@@ -0,0 +1,142 @@
+# Written by gswarm to pace the trainer's pushes to the Hugging Face Hub.
+# It is rewritten on every start; change the --hf-push-* flags instead.
+#
+# Every upload goes through HfApi.create_commit, so wrapping it covers
+# push_to_hub, upload_folder and upload_file. A push outside the allowed
+# windows, too soon after the previous one, or during a rate limit backoff
+# is skipped: the caller gets a placeholder commit pointing at the repo, and
+# the trainer carries on with the next round.
+import functools
+import json
+import os
+import sys
+import time
+
+
+def _gswarm_log(msg):
+    print("[gswarm-hf-push] " + msg, file=sys.stderr, flush=True)
+
+
+def _gswarm_windows(spec):
+    windows = []
+    for part in filter(None, spec.split(",")):
+        start, end, days = (int(v) for v in part.split("-"))
+        windows.append((start, end, days))
+    return windows
+
+
+def _gswarm_in_window(windows, now):
+    if not windows:
+        return True
+    t = time.localtime(now)
+    minute = t.tm_hour * 60 + t.tm_min
+    # Days are a bitmask from Sunday, as in Go
+    day = (t.tm_wday + 1) % 7
+    prev = (day + 6) % 7
+    for start, end, days in windows:
+        today, yesterday = days >> day & 1, days >> prev & 1
+        if start == end:
+            ok = today
+        elif start < end:
+            ok = today and start <= minute < end
+        else:
+            ok = (today and minute >= start) or (yesterday and minute < end)
+        if ok:
+            return True
+    return False
+
+
+class _GswarmSkippedCommit(str):
+    # Stands in for CommitInfo where it can't be built; like the CommitInfo
+    # of recent huggingface_hub releases, it is also the URL as a string
+    def __new__(cls, url, **fields):
+        commit = str.__new__(cls, url)
+        commit.__dict__.update(fields)
+        commit.repo_url = url
+        commit.pr_revision = None
+        commit.pr_num = None
+        return commit
+
+
+def _gswarm_skipped_commit(hf_api, api, args, kwargs):
+    repo_id = kwargs.get("repo_id", args[0] if args else "")
+    endpoint = getattr(api, "endpoint", None) or "https://huggingface.co"
+    url = "%s/%s" % (endpoint.rstrip("/"), repo_id)
+    fields = {
+        "commit_url": url,
+        "commit_message": kwargs.get("commit_message") or "",
+        "commit_description": "",
+        "oid": "",
+        "pr_url": None,
+    }
+    try:
+        return hf_api.CommitInfo(**fields)
+    except Exception:
+        return _GswarmSkippedCommit(url, **fields)
+
+
+def _gswarm_pace():
+    try:
+        from huggingface_hub import hf_api
+    except Exception:
+        return
+
+    min_interval = float(os.environ.get("GSWARM_HF_PUSH_MIN_INTERVAL") or 0)
+    backoff = float(os.environ.get("GSWARM_HF_PUSH_BACKOFF") or 0)
+    windows = _gswarm_windows(os.environ.get("GSWARM_HF_PUSH_WINDOWS") or "")
+    state_path = os.environ.get("GSWARM_HF_PUSH_STATE")
+
+    def load():
+        try:
+            with open(state_path) as f:
+                return json.load(f)
+        except Exception:
+            return {}
+
+    def save(state):
+        try:
+            with open(state_path + ".tmp", "w") as f:
+                json.dump(state, f)
+            os.replace(state_path + ".tmp", state_path)
+        except Exception as e:
+            _gswarm_log("state not saved: %s" % e)
+
+    original = hf_api.HfApi.create_commit
+
+    @functools.wraps(original)
+    def create_commit(self, *args, **kwargs):
+        now = time.time()
+        state = load()
+        if not _gswarm_in_window(windows, now):
+            _gswarm_log("skipped: outside the push window")
+            return _gswarm_skipped_commit(hf_api, self, args, kwargs)
+        wait = max(state.get("last", 0) + min_interval, state.get("retry_after", 0)) - now
+        if wait > 0:
+            _gswarm_log("skipped: next push allowed in %dm" % (wait // 60 + 1))
+            return _gswarm_skipped_commit(hf_api, self, args, kwargs)
+        try:
+            result = original(self, *args, **kwargs)
+        except Exception as e:
+            response = getattr(e, "response", None)
+            if getattr(response, "status_code", None) == 429 and backoff > 0:
+                state["retry_after"] = now + backoff
+                save(state)
+            lines = str(e).strip().splitlines()
+            _gswarm_log("failed: %s" % (lines[0] if lines else type(e).__name__))
+            raise
+        save({"last": now})
+        _gswarm_log("ok")
+        return result
+
+    hf_api.HfApi.create_commit = create_commit
+    # The module-level helpers are bound to a shared HfApi at import time
+    hf_api.create_commit = hf_api.api.create_commit
+    import huggingface_hub
+
+    huggingface_hub.create_commit = hf_api.create_commit
+
+
+try:
+    _gswarm_pace()
+except Exception as e:
+    _gswarm_log("pacing disabled: %s" % e)
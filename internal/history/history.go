@@ -0,0 +1,228 @@
+// Package history stores reward samples over time and derives
+// rewards-per-day trends from them.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+	"github.com/Deep-Commit/gswarm/internal/int256"
+)
+
+// Retention is how long samples are kept
+const Retention = 30 * 24 * time.Hour
+
+// pruneSlack is how far past Retention the oldest sample may be before
+// Append prunes the file, so it is rewritten about once a day rather than
+// at every check
+const pruneSlack = 24 * time.Hour
+
+// Sample is the reward total observed at a point in time
+type Sample struct {
+	Time    time.Time `json:"time"`
+	Rewards *big.Int  `json:"rewards"`
+	Votes   *big.Int  `json:"votes,omitempty"`
+}
+
+// Store is a JSONL file of samples, appended to and pruned of samples
+// past Retention
+type Store struct {
+	Path string
+}
+
+// Append records a sample, pruning the file first when its oldest sample
+// is well past Retention
+func (s *Store) Append(sample Sample) error {
+	if oldest, ok := s.oldest(); ok && sample.Time.Sub(oldest) > Retention+pruneSlack {
+		if err := s.Prune(sample.Time); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// oldest returns the time of the file's first sample. A corrupt first line
+// counts as very old, so pruning drops it.
+func (s *Store) oldest() (time.Time, bool) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return time.Time{}, false
+	}
+	var sample Sample
+	if json.Unmarshal(scanner.Bytes(), &sample) != nil {
+		return time.Time{}, true
+	}
+	return sample.Time, true
+}
+
+// Prune rewrites the file with only the samples inside Retention of now,
+// dropping corrupt lines. The file is replaced via a temp file, so a crash
+// leaves the old or the new history.
+func (s *Store) Prune(now time.Time) error {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	cutoff := now.Add(-Retention)
+	var kept []byte
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil || sample.Rewards == nil {
+			continue
+		}
+		if sample.Time.After(cutoff) {
+			kept = append(append(kept, scanner.Bytes()...), '\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, kept, 0o644); err != nil {
+		return fmt.Errorf("failed to prune rewards history: %w", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to prune rewards history: %w", err)
+	}
+	return nil
+}
+
+// Load reads samples newer than the retention period, skipping corrupt lines
+func (s *Store) Load() ([]Sample, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	cutoff := time.Now().Add(-Retention)
+	var samples []Sample
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil || sample.Rewards == nil {
+			continue
+		}
+		if sample.Time.After(cutoff) {
+			int256.Repair(sample.Rewards)
+			samples = append(samples, sample)
+		}
+	}
+	return samples, scanner.Err()
+}
+
+// Estimate is a rewards trend derived from history
+type Estimate struct {
+	// PerDay is the average rewards per day over the trend window
+	PerDay *big.Float
+	// RecentPerDay is the rate over the last day, when enough data exists
+	RecentPerDay *big.Float
+	// WeekProjection is PerDay extrapolated over seven days
+	WeekProjection *big.Float
+	// Span is how much history the estimate is based on
+	Span time.Duration
+}
+
+// minSpan is the least history needed for a meaningful rate
+const minSpan = time.Hour
+
+// Trend estimates the rewards rate from samples up to now, using the last
+// seven days of history. It returns nil when there isn't enough data.
+func Trend(samples []Sample, current *big.Int, now time.Time) *Estimate {
+	week := rate(samples, current, now, 7*24*time.Hour)
+	if week == nil {
+		return nil
+	}
+	est := &Estimate{PerDay: week.perDay, Span: week.span}
+	est.WeekProjection = new(big.Float).Mul(week.perDay, big.NewFloat(7))
+	if day := rate(samples, current, now, 24*time.Hour); day != nil && day.span >= 12*time.Hour {
+		est.RecentPerDay = day.perDay
+	}
+	return est
+}
+
+type rateResult struct {
+	perDay *big.Float
+	span   time.Duration
+}
+
+// rate computes rewards per day between the oldest sample inside window and now
+func rate(samples []Sample, current *big.Int, now time.Time, window time.Duration) *rateResult {
+	var base *Sample
+	for i := range samples {
+		if now.Sub(samples[i].Time) <= window {
+			base = &samples[i]
+			break
+		}
+	}
+	if base == nil {
+		return nil
+	}
+	span := now.Sub(base.Time)
+	if span < minSpan {
+		return nil
+	}
+	delta := new(big.Float).SetInt(new(big.Int).Sub(current, base.Rewards))
+	days := big.NewFloat(span.Hours() / 24)
+	return &rateResult{perDay: new(big.Float).Quo(delta, days), span: span}
+}
+
+// Text renders the estimate for notifications
+func (e *Estimate) Text() string {
+	return e.TextFormat(humanize.Format{})
+}
+
+// TextFormat renders the estimate with amounts shown in format f
+func (e *Estimate) TextFormat(f humanize.Format) string {
+	text := fmt.Sprintf("~%s/day (%s trend), ~%s projected this week",
+		f.Float(e.PerDay, 1), formatSpan(e.Span), f.Float(e.WeekProjection, 0))
+	if e.RecentPerDay != nil {
+		arrow := "→"
+		switch e.RecentPerDay.Cmp(e.PerDay) {
+		case 1:
+			arrow = "↑"
+		case -1:
+			arrow = "↓"
+		}
+		text += fmt.Sprintf("; last 24h %s ~%s/day", arrow, f.Float(e.RecentPerDay, 1))
+	}
+	return text
+}
+
+func formatSpan(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
@@ -0,0 +1,108 @@
+package history
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_AppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	s := &Store{Path: path}
+
+	now := time.Now()
+	s.Append(Sample{Time: now.Add(-40 * 24 * time.Hour), Rewards: big.NewInt(1)})
+	s.Append(Sample{Time: now.Add(-time.Hour), Rewards: big.NewInt(10)})
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("not json\n")
+	f.Close()
+
+	samples, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(samples) != 1 || samples[0].Rewards.Int64() != 10 {
+		t.Errorf("Load() = %+v, want only the sample inside retention", samples)
+	}
+
+	if samples, err := (&Store{Path: filepath.Join(t.TempDir(), "missing")}).Load(); err != nil || samples != nil {
+		t.Errorf("Load() of a missing file = %v, %v; want nil, nil", samples, err)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	s := &Store{Path: path}
+	now := time.Now()
+
+	s.Append(Sample{Time: now.Add(-29 * 24 * time.Hour), Rewards: big.NewInt(1)})
+	s.Append(Sample{Time: now.Add(-time.Hour), Rewards: big.NewInt(2)})
+	// A day later the first sample is past Retention, but not far enough
+	// past it to rewrite the file yet
+	s.Append(Sample{Time: now.Add(24 * time.Hour), Rewards: big.NewInt(3)})
+	if data, _ := os.ReadFile(path); strings.Count(string(data), "\n") != 3 {
+		t.Errorf("history pruned too early:\n%s", data)
+	}
+
+	s.Append(Sample{Time: now.Add(3 * 24 * time.Hour), Rewards: big.NewInt(4)})
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), "\n") != 3 || strings.Contains(string(data), `"rewards":1}`) {
+		t.Errorf("history after pruning:\n%s", data)
+	}
+	samples, _ := s.Load()
+	if len(samples) != 3 || samples[0].Rewards.Int64() != 2 {
+		t.Errorf("Load() after pruning = %+v", samples)
+	}
+
+	if err := (&Store{Path: filepath.Join(t.TempDir(), "missing")}).Prune(now); err != nil {
+		t.Errorf("Prune() of a missing file = %v", err)
+	}
+}
+
+func TestStore_LoadUnsigned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	// -20 as saved by versions that decoded int256 as uint256
+	line := `{"time":"` + time.Now().Add(-time.Hour).Format(time.RFC3339) + `","rewards":` +
+		"115792089237316195423570985008687907853269984665640564039457584007913129639916}\n"
+	if err := os.WriteFile(path, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+	samples, err := (&Store{Path: path}).Load()
+	if err != nil || len(samples) != 1 || samples[0].Rewards.Int64() != -20 {
+		t.Errorf("Load() = %+v, %v; want one sample of -20", samples, err)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	now := time.Date(2025, 7, 8, 12, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Time: now.Add(-10 * 24 * time.Hour), Rewards: big.NewInt(0)},
+		{Time: now.Add(-4 * 24 * time.Hour), Rewards: big.NewInt(100)},
+		{Time: now.Add(-20 * time.Hour), Rewards: big.NewInt(300)},
+	}
+
+	est := Trend(samples, big.NewInt(400), now)
+	if est == nil {
+		t.Fatal("Trend() = nil, want an estimate")
+	}
+	// 300 over the 4 days since the oldest sample in the week window
+	if got, _ := est.PerDay.Float64(); got != 75 {
+		t.Errorf("PerDay = %v, want 75", got)
+	}
+	if got, _ := est.WeekProjection.Float64(); got != 525 {
+		t.Errorf("WeekProjection = %v, want 525", got)
+	}
+	if est.RecentPerDay == nil {
+		t.Fatal("RecentPerDay = nil, want the last-24h rate")
+	}
+	if text := est.Text(); !strings.Contains(text, "~75.0/day (4d trend)") || !strings.Contains(text, "↑") {
+		t.Errorf("Text() = %q", text)
+	}
+
+	if est := Trend(samples[2:], big.NewInt(300), samples[2].Time.Add(time.Minute)); est != nil {
+		t.Errorf("Trend() with under an hour of history = %+v, want nil", est)
+	}
+}
@@ -0,0 +1,187 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/int256"
+)
+
+// PeersRetention is how long per-peer samples are kept; a little over a
+// day, so there is always a sample to compute the 24h change from
+const PeersRetention = 25 * time.Hour
+
+// DeltaWindow is the period the per-peer changes cover
+const DeltaWindow = 24 * time.Hour
+
+// PeerStats are a peer's totals at one check
+type PeerStats struct {
+	Votes   *big.Int `json:"votes"`
+	Rewards *big.Int `json:"rewards"`
+}
+
+type peerSample struct {
+	Time time.Time `json:"time"`
+	PeerStats
+}
+
+// Peers stores each peer's recent totals in a JSON file, for the rewards
+// endpoint of the status API
+type Peers struct {
+	Path string
+}
+
+type peersFile struct {
+	EOA     string                  `json:"eoa"`
+	Balance *big.Int                `json:"balance,omitempty"`
+	Updated time.Time               `json:"updated"`
+	Peers   map[string][]peerSample `json:"peers"`
+}
+
+// Record adds the totals of the peers checked at now, and the EOA's
+// balance in wei when it is known. Registered peers whose check failed
+// keep their earlier samples; other peers are dropped, as they are no
+// longer registered to the EOA.
+func (p *Peers) Record(now time.Time, eoa string, balance *big.Int, registered []string, stats map[string]PeerStats) error {
+	data, err := p.load()
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-PeersRetention)
+	peers := make(map[string][]peerSample, len(registered))
+	for _, id := range registered {
+		var kept []peerSample
+		for _, sample := range data.Peers[id] {
+			if sample.Time.After(cutoff) {
+				kept = append(kept, sample)
+			}
+		}
+		if s, ok := stats[id]; ok {
+			kept = append(kept, peerSample{Time: now, PeerStats: s})
+		}
+		if len(kept) > 0 {
+			peers[id] = kept
+		}
+	}
+
+	if balance == nil && eoa == data.EOA {
+		balance = data.Balance
+	}
+	out, err := json.Marshal(peersFile{EOA: eoa, Balance: balance, Updated: now, Peers: peers})
+	if err != nil {
+		return err
+	}
+	tmp := p.Path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.Path)
+}
+
+// PeerSummary is a peer's current totals and their change over the last
+// DeltaWindow, or over the history there is when it is shorter
+type PeerSummary struct {
+	PeerID       string    `json:"peer_id"`
+	Votes        *big.Int  `json:"votes"`
+	Rewards      *big.Int  `json:"rewards"`
+	VotesDelta   *big.Int  `json:"votes_24h"`
+	RewardsDelta *big.Int  `json:"rewards_24h"`
+	Since        time.Time `json:"since"`
+}
+
+// Summary is the per-peer and combined stats of an EOA
+type Summary struct {
+	EOA string `json:"eoa"`
+	// Balance is the EOA's balance in wei, when the monitor could read it
+	Balance      *big.Int      `json:"balance,omitempty"`
+	Updated      time.Time     `json:"updated"`
+	Votes        *big.Int      `json:"votes"`
+	Rewards      *big.Int      `json:"rewards"`
+	VotesDelta   *big.Int      `json:"votes_24h"`
+	RewardsDelta *big.Int      `json:"rewards_24h"`
+	Peers        []PeerSummary `json:"peers"`
+}
+
+// Summary reads the stored totals and computes each peer's change since
+// its newest sample at least DeltaWindow older than its latest. It returns
+// an os.IsNotExist error until the monitor has recorded a check.
+func (p *Peers) Summary() (*Summary, error) {
+	if _, err := os.Stat(p.Path); err != nil {
+		return nil, err
+	}
+	data, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Summary{
+		EOA: data.EOA, Balance: data.Balance, Updated: data.Updated,
+		Votes: new(big.Int), Rewards: new(big.Int), VotesDelta: new(big.Int), RewardsDelta: new(big.Int),
+		Peers: []PeerSummary{},
+	}
+	ids := make([]string, 0, len(data.Peers))
+	for id := range data.Peers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		samples := data.Peers[id]
+		if len(samples) == 0 {
+			continue
+		}
+		latest := samples[len(samples)-1]
+		base := samples[0]
+		for _, sample := range samples {
+			if latest.Time.Sub(sample.Time) < DeltaWindow {
+				break
+			}
+			base = sample
+		}
+		peer := PeerSummary{
+			PeerID:       id,
+			Votes:        orZero(latest.Votes),
+			Rewards:      orZero(latest.Rewards),
+			VotesDelta:   new(big.Int).Sub(orZero(latest.Votes), orZero(base.Votes)),
+			RewardsDelta: new(big.Int).Sub(orZero(latest.Rewards), orZero(base.Rewards)),
+			Since:        base.Time,
+		}
+		s.Votes.Add(s.Votes, peer.Votes)
+		s.Rewards.Add(s.Rewards, peer.Rewards)
+		s.VotesDelta.Add(s.VotesDelta, peer.VotesDelta)
+		s.RewardsDelta.Add(s.RewardsDelta, peer.RewardsDelta)
+		s.Peers = append(s.Peers, peer)
+	}
+	return s, nil
+}
+
+// load reads the file, starting empty if it is missing or unreadable
+func (p *Peers) load() (*peersFile, error) {
+	data := &peersFile{Peers: map[string][]peerSample{}}
+	raw, err := os.ReadFile(p.Path)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read peer history: %w", err)
+	}
+	if err := json.Unmarshal(raw, data); err != nil || data.Peers == nil {
+		return &peersFile{Peers: map[string][]peerSample{}}, nil
+	}
+	for _, samples := range data.Peers {
+		for _, s := range samples {
+			int256.Repair(s.Rewards)
+		}
+	}
+	return data, nil
+}
+
+func orZero(n *big.Int) *big.Int {
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
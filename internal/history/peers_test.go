@@ -0,0 +1,64 @@
+package history
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPeers_Summary(t *testing.T) {
+	p := &Peers{Path: filepath.Join(t.TempDir(), "peers.json")}
+	if _, err := p.Summary(); !os.IsNotExist(err) {
+		t.Fatalf("Summary() before any check: err = %v, want not exist", err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats := func(votes, rewards int64) PeerStats {
+		return PeerStats{Votes: big.NewInt(votes), Rewards: big.NewInt(rewards)}
+	}
+	peers := []string{"QmA", "QmB"}
+	for h := 0; h <= 30; h++ {
+		now := start.Add(time.Duration(h) * time.Hour)
+		checked := map[string]PeerStats{"QmA": stats(int64(h), int64(10*h))}
+		if h < 20 {
+			// QmB's checks fail from hour 20 on
+			checked["QmB"] = stats(5, 100)
+		}
+		if err := p.Record(now, "0xabc", big.NewInt(7e17), peers, checked); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := p.Summary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.EOA != "0xabc" || len(s.Peers) != 2 {
+		t.Fatalf("Summary() = %+v, want 2 peers of 0xabc", s)
+	}
+	a := s.Peers[0]
+	if a.PeerID != "QmA" || a.Rewards.Int64() != 300 || a.RewardsDelta.Int64() != 240 || a.VotesDelta.Int64() != 24 {
+		t.Errorf("QmA = %+v, want 300 rewards, +240 in 24h, +24 votes", a)
+	}
+	if b := s.Peers[1]; b.Rewards.Int64() != 100 || b.RewardsDelta.Sign() != 0 {
+		t.Errorf("QmB = %+v, want its last totals and no change", b)
+	}
+	if s.Rewards.Int64() != 400 || s.RewardsDelta.Int64() != 240 {
+		t.Errorf("totals = %s (+%s), want 400 (+240)", s.Rewards, s.RewardsDelta)
+	}
+
+	// A peer no longer registered to the EOA is dropped
+	if err := p.Record(start.Add(31*time.Hour), "0xabc", nil, []string{"QmA"}, map[string]PeerStats{"QmA": stats(31, 310)}); err != nil {
+		t.Fatal(err)
+	}
+	s, _ = p.Summary()
+	if len(s.Peers) != 1 {
+		t.Errorf("Summary() peers = %+v, want only QmA", s.Peers)
+	}
+	// a check that couldn't read the balance keeps the last one
+	if s.Balance == nil || s.Balance.Int64() != 7e17 {
+		t.Errorf("Balance = %v, want the last known 7e17", s.Balance)
+	}
+}
@@ -0,0 +1,463 @@
+// Package hub aggregates the status reports pushed by many gswarm agents
+// into one dashboard and one notification stream, for operators running a
+// fleet of nodes.
+package hub
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/clockjump"
+	"github.com/Deep-Commit/gswarm/internal/console"
+	"github.com/Deep-Commit/gswarm/internal/notify"
+	"github.com/Deep-Commit/gswarm/internal/rewards"
+	"github.com/Deep-Commit/gswarm/internal/status"
+	"github.com/Deep-Commit/gswarm/internal/timefmt"
+)
+
+// API paths served by the hub
+const (
+	ReportPath = "/api/v1/report"
+	NodesPath  = "/api/v1/nodes"
+)
+
+// DefaultListen is the default hub address; nodes on other machines need
+// it to listen on a reachable address
+const DefaultListen = "127.0.0.1:8687"
+
+// DefaultPushInterval is how often agents report to the hub
+const DefaultPushInterval = time.Minute
+
+// DefaultStaleAfter is how long a node can go without reporting before the
+// hub alerts that it is missing
+const DefaultStaleAfter = 5 * time.Minute
+
+// DefaultForgetAfter is how long a node that stopped reporting stays on the
+// dashboard
+const DefaultForgetAfter = 7 * 24 * time.Hour
+
+// DefaultMaxNodes bounds how many nodes the hub keeps reports for
+const DefaultMaxNodes = 1000
+
+// ErrFull is returned for a report from a new node once the hub holds
+// MaxNodes
+var ErrFull = errors.New("the hub is tracking as many nodes as it can")
+
+// maxReportSize bounds the body of a pushed report
+const maxReportSize = 64 << 10
+
+// Report is the status an agent pushes to the hub
+type Report struct {
+	Node    string          `json:"node"`
+	Version string          `json:"version,omitempty"`
+	Status  status.Snapshot `json:"status"`
+	// Rewards is the node's formatted reward total, when known
+	Rewards string    `json:"rewards,omitempty"`
+	Sent    time.Time `json:"sent"`
+}
+
+// Node is the latest report from a node as seen by the hub
+type Node struct {
+	Report
+	ReceivedAt time.Time `json:"received_at"`
+	Remote     string    `json:"remote,omitempty"`
+	// Missing is set once the node has gone StaleAfter without reporting
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Hub receives agent reports and turns node state changes into
+// notifications
+type Hub struct {
+	// Token must be presented by pushing agents, and by anyone reading the
+	// dashboard or API; empty leaves the hub open
+	Token string
+	// Notifier receives events for the whole fleet; nil disables them
+	Notifier notify.Notifier
+	// StaleAfter is how long a node can go without reporting before it is
+	// reported missing; 0 disables the check
+	StaleAfter time.Duration
+	// ForgetAfter is how long a node can go without reporting before the
+	// hub drops it; 0 keeps it
+	ForgetAfter time.Duration
+	// MaxNodes is how many nodes the hub tracks; reports from further
+	// nodes are refused
+	MaxNodes int
+	// Peers serves peer votes and rewards to monitors using the hub as
+	// their rewards source; nil disables the endpoint
+	Peers rewards.Source
+	// Time renders the times on the dashboard
+	Time timefmt.Formatter
+
+	mu    sync.RWMutex
+	nodes map[string]*Node
+}
+
+// New creates an empty hub
+func New(token string, notifier notify.Notifier) *Hub {
+	return &Hub{
+		Token:       token,
+		Notifier:    notifier,
+		StaleAfter:  DefaultStaleAfter,
+		ForgetAfter: DefaultForgetAfter,
+		MaxNodes:    DefaultMaxNodes,
+		nodes:       map[string]*Node{},
+	}
+}
+
+// Receive records a report and notifies about what changed since the
+// node's previous one. A report from a new node is refused with ErrFull
+// once the hub holds MaxNodes.
+func (h *Hub) Receive(r Report, remote string) error {
+	h.mu.Lock()
+	prev := h.nodes[r.Node]
+	if prev == nil && h.MaxNodes > 0 && len(h.nodes) >= h.MaxNodes {
+		h.mu.Unlock()
+		return ErrFull
+	}
+	h.nodes[r.Node] = &Node{Report: r, ReceivedAt: time.Now(), Remote: remote}
+	h.mu.Unlock()
+
+	h.notify(changes(prev, r))
+	return nil
+}
+
+// CheckStale reports nodes that haven't been heard from for StaleAfter,
+// once per outage; a dead machine can't send its own crash notification.
+// Nodes that reported stopping aren't expected to report again. Nodes
+// silent for ForgetAfter are dropped.
+func (h *Hub) CheckStale(now time.Time) {
+	if h.StaleAfter <= 0 {
+		return
+	}
+	var events []notify.Event
+	h.mu.Lock()
+	for name, n := range h.nodes {
+		if h.ForgetAfter > 0 && now.Sub(n.ReceivedAt) >= h.ForgetAfter {
+			delete(h.nodes, name)
+			continue
+		}
+		if n.Missing || n.Status.State == status.StateStopped || now.Sub(n.ReceivedAt) < h.StaleAfter {
+			continue
+		}
+		n.Missing = true
+		events = append(events, notify.Event{
+			Type:  notify.EventCrash,
+			Title: "Node Stopped Reporting",
+			Message: fmt.Sprintf("<b>%s</b> hasn't reported for %s (last state: %s). The machine or VM may be down.",
+				html.EscapeString(n.Node), now.Sub(n.ReceivedAt).Round(time.Second), n.Status.State),
+			Time: now,
+		})
+	}
+	h.mu.Unlock()
+	sort.Slice(events, func(i, j int) bool { return events[i].Message < events[j].Message })
+	h.notify(events)
+}
+
+// Watch checks for missing nodes until ctx is done
+func (h *Hub) Watch(ctx context.Context) {
+	if h.StaleAfter <= 0 {
+		return
+	}
+	ticker := time.NewTicker(h.StaleAfter / 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.CheckStale(now)
+		}
+	}
+}
+
+func (h *Hub) notify(events []notify.Event) {
+	if h.Notifier == nil {
+		return
+	}
+	for _, ev := range events {
+		if err := h.Notifier.Notify(ev); err != nil {
+			console.Warnf("failed to send hub notification: %v", err)
+		}
+	}
+}
+
+// Nodes returns the latest report of every node, sorted by name
+func (h *Hub) Nodes() []Node {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	nodes := make([]Node, 0, len(h.nodes))
+	for _, n := range h.nodes {
+		nodes = append(nodes, *n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+	return nodes
+}
+
+// Node returns the latest report from one node
+func (h *Hub) Node(name string) (Node, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n, ok := h.nodes[name]
+	if !ok {
+		return Node{}, false
+	}
+	return *n, true
+}
+
+// changes returns the events for a node going from prev to r
+func changes(prev *Node, r Report) []notify.Event {
+	node := html.EscapeString(r.Node)
+	now := time.Now()
+	if prev == nil {
+		return []notify.Event{{
+			Type:    notify.EventInfo,
+			Title:   "Node Joined the Hub",
+			Message: fmt.Sprintf("<b>%s</b> is reporting (%s)", node, r.Status.State),
+			Time:    now,
+		}}
+	}
+
+	var events []notify.Event
+	if prev.Missing {
+		events = append(events, notify.Event{
+			Type:    notify.EventInfo,
+			Title:   "Node Reporting Again",
+			Message: fmt.Sprintf("<b>%s</b> is reporting again after %s (%s)", node, time.Since(prev.ReceivedAt).Round(time.Second), r.Status.State),
+			Time:    now,
+		})
+	}
+	if r.Status.StartedAt.After(prev.Status.StartedAt) {
+		events = append(events, notify.Event{
+			Type:    notify.EventInfo,
+			Title:   "Node Supervisor Restarted",
+			Message: fmt.Sprintf("<b>%s</b> restarted gswarm", node),
+			Time:    now,
+		})
+	} else if r.Status.Restarts > prev.Status.Restarts {
+		msg := fmt.Sprintf("<b>%s</b> restarted its trainer (%d restarts)", node, r.Status.Restarts)
+		if r.Status.LastError != "" {
+			msg += "\n<code>" + html.EscapeString(r.Status.LastError) + "</code>"
+		}
+		events = append(events, notify.Event{Type: notify.EventCrash, Title: "Node Crashed", Message: msg, Time: now})
+	}
+	if r.Status.State != prev.Status.State && (r.Status.State == status.StateStopped || prev.Status.State == status.StateStopped) {
+		events = append(events, notify.Event{
+			Type:    notify.EventInfo,
+			Title:   "Node State Changed",
+			Message: fmt.Sprintf("<b>%s</b> is now %s (was %s)", node, r.Status.State, prev.Status.State),
+			Time:    now,
+		})
+	}
+	return events
+}
+
+// authorized reports whether r carries the token, as a bearer token or, for
+// browsers, as the password of basic auth
+func (h *Hub) authorized(r *http.Request) bool {
+	if h.Token == "" {
+		return true
+	}
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if _, password, ok := r.BasicAuth(); ok {
+		got = password
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(h.Token)) == 1
+}
+
+// private requires the token for next, which shows nodes' addresses and
+// errors
+func (h *Hub) private(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="G-Swarm hub"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Handler serves the push endpoint, the JSON API and the dashboard
+func (h *Hub) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc(ReportPath, h.handleReport)
+	// Each answer may cost the hub RPC calls, so only its agents may ask
+	if h.Peers != nil {
+		mux.HandleFunc(rewards.PeersPath, h.private(rewards.Handler(h.Peers).ServeHTTP))
+	}
+	mux.HandleFunc(NodesPath, h.private(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, h.Nodes())
+	}))
+	mux.HandleFunc(NodesPath+"/", h.private(func(w http.ResponseWriter, r *http.Request) {
+		n, ok := h.Node(strings.TrimPrefix(r.URL.Path, NodesPath+"/"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, n)
+	}))
+	mux.HandleFunc("/nodes/", h.private(func(w http.ResponseWriter, r *http.Request) {
+		n, ok := h.Node(strings.TrimPrefix(r.URL.Path, "/nodes/"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		h.renderHTML(w, nodeTemplate, n)
+	}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		h.private(func(w http.ResponseWriter, _ *http.Request) {
+			h.renderHTML(w, dashboardTemplate, h.Nodes())
+		})(w, r)
+	})
+	return mux
+}
+
+func (h *Hub) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var report Report
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxReportSize)).Decode(&report); err != nil {
+		http.Error(w, "invalid report: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if report.Node == "" || strings.Contains(report.Node, "/") {
+		http.Error(w, "invalid report: missing or invalid node name", http.StatusBadRequest)
+		return
+	}
+	if err := h.Receive(report, r.RemoteAddr); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// renderHTML executes a copy of t with timestamps in the hub's timezone
+func (h *Hub) renderHTML(w http.ResponseWriter, t *template.Template, data interface{}) {
+	t, err := t.Clone()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	t.Funcs(template.FuncMap{"timestamp": h.Time.Format})
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(b.Bytes())
+}
+
+// Push sends a report to the hub at hubURL
+func Push(ctx context.Context, client *http.Client, hubURL, token string, r Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(hubURL, "/")+ReportPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push report to hub: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub rejected report: %s %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// templateFuncs are the functions the templates are parsed with; renderHTML
+// replaces timestamp with the hub's
+var templateFuncs = template.FuncMap{
+	"timestamp": timefmt.Formatter{}.Format,
+	"ago": func(t time.Time) string {
+		return clockjump.Since(t, time.Now()).Round(time.Second).String()
+	},
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="60">
+<title>G-Swarm fleet</title>
+<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse}td,th{padding:.3em 1em;text-align:left;border-bottom:1px solid #ddd}</style>
+</head>
+<body>
+<h1>G-Swarm fleet ({{len .}} nodes)</h1>
+<table>
+<tr><th>Node</th><th>State</th><th>Run</th><th>Last round</th><th>Restarts</th><th>Rewards</th><th>Last report</th></tr>
+{{range .}}<tr><td><a href="/nodes/{{.Node}}">{{.Node}}</a></td><td>{{if .Missing}}<b>not reporting</b>{{else}}{{.Status.State}}{{end}}</td><td>{{.Status.RunNumber}}</td><td>{{.Status.LastRound}}</td><td>{{.Status.Restarts}}</td><td>{{.Rewards}}</td><td>{{ago .ReceivedAt}} ago</td></tr>
+{{else}}<tr><td colspan="7">No agents have reported yet.</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+var nodeTemplate = template.Must(template.New("node").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="60">
+<title>{{.Node}} – G-Swarm fleet</title>
+<style>body{font-family:sans-serif;max-width:40em;margin:2em auto}td{padding:.3em 1em}</style>
+</head>
+<body>
+<p><a href="/">← fleet</a></p>
+<h1>{{.Node}}</h1>
+<table>
+<tr><td>State</td><td>{{if .Missing}}<b>not reporting</b> (last state: {{.Status.State}}){{else}}{{.Status.State}}{{end}}</td></tr>
+{{with .Status.Versions}}<tr><td>Version</td><td>{{.}}</td></tr>{{else}}{{if .Version}}<tr><td>Version</td><td>{{.Version}}</td></tr>{{end}}{{end}}
+<tr><td>Supervisor started</td><td>{{timestamp .Status.StartedAt}}</td></tr>
+<tr><td>Run</td><td>#{{.Status.RunNumber}}{{if not .Status.RunStartedAt.IsZero}} since {{timestamp .Status.RunStartedAt}}{{end}}</td></tr>
+{{if .Status.LastRound}}<tr><td>Last round</td><td>{{.Status.LastRound}}</td></tr>{{end}}
+<tr><td>Restarts</td><td>{{.Status.Restarts}}</td></tr>
+{{if .Rewards}}<tr><td>Total rewards</td><td>{{.Rewards}}</td></tr>{{end}}
+{{if .Status.LastError}}<tr><td>Last error</td><td><code>{{.Status.LastError}}</code>{{if not .Status.LastExit.IsZero}} at {{timestamp .Status.LastExit}}{{end}}</td></tr>{{end}}
+{{with .Status.Schedule}}<tr><td>Schedule</td><td>{{if .Paused}}paused{{else}}active{{end}}{{if not .NextChange.IsZero}} until {{timestamp .NextChange}}{{end}}</td></tr>{{end}}
+<tr><td>Last report</td><td>{{timestamp .ReceivedAt}} from {{.Remote}}</td></tr>
+</table>
+</body>
+</html>
+`))
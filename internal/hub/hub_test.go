@@ -0,0 +1,225 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/notify"
+	"github.com/Deep-Commit/gswarm/internal/rewards"
+	"github.com/Deep-Commit/gswarm/internal/status"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Notify(ev notify.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	return nil
+}
+
+func (r *recorder) titles() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var titles []string
+	for _, ev := range r.events {
+		titles = append(titles, ev.Title)
+	}
+	return titles
+}
+
+func TestHub_PushAndNotify(t *testing.T) {
+	rec := &recorder{}
+	h := New("secret", rec)
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+	ctx := context.Background()
+
+	started := time.Now().Add(-time.Hour)
+	report := Report{Node: "gpu-a", Status: status.Snapshot{State: status.StateRunning, StartedAt: started}, Rewards: "1,200"}
+	if err := Push(ctx, srv.Client(), srv.URL, "wrong", report); err == nil {
+		t.Fatal("Push() with a wrong token error = nil, want an error")
+	}
+	if err := Push(ctx, srv.Client(), srv.URL, "secret", report); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	report.Status.Restarts = 1
+	report.Status.State = status.StateBackoff
+	report.Status.LastError = "exit status 1"
+	if err := Push(ctx, srv.Client(), srv.URL+"/", "secret", report); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	report.Status.State = status.StateStopped
+	h.Receive(report, "test")
+
+	want := []string{"Node Joined the Hub", "Node Crashed", "Node State Changed"}
+	if got := rec.titles(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	// Reading the fleet's state takes the token too
+	for _, path := range []string{"/", "/nodes/gpu-a", NodesPath, NodesPath + "/gpu-a"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s without the token = %d, want 401", path, resp.StatusCode)
+		}
+	}
+	page, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+	page.SetBasicAuth("", "secret")
+	if resp, err := http.DefaultClient.Do(page); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("GET / with basic auth = %v, %v", resp, err)
+	} else {
+		resp.Body.Close()
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+NodesPath, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET nodes error = %v", err)
+	}
+	defer resp.Body.Close()
+	var nodes []Node
+	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+		t.Fatalf("decode nodes error = %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Node != "gpu-a" || nodes[0].Status.Restarts != 1 {
+		t.Errorf("nodes = %+v", nodes)
+	}
+}
+
+type peerSource struct{}
+
+func (peerSource) Name() string { return "test" }
+
+func (peerSource) Peer(context.Context, string) (rewards.Totals, error) {
+	return rewards.Totals{Votes: big.NewInt(1), Rewards: big.NewInt(2)}, nil
+}
+
+func TestHub_PeersNeedToken(t *testing.T) {
+	h := New("secret", nil)
+	h.Peers = peerSource{}
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + rewards.PeersPath + "QmPeer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET without the token status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	src := &rewards.Hub{URL: srv.URL, Token: "secret", Client: http.DefaultClient}
+	if got, err := src.Peer(context.Background(), "QmPeer"); err != nil || got.Rewards.Int64() != 2 {
+		t.Errorf("Peer() with the token = %+v, %v", got, err)
+	}
+}
+
+func TestHub_Dashboard(t *testing.T) {
+	h := New("", nil)
+	h.Receive(Report{Node: "cpu-<b>", Status: status.Snapshot{State: status.StateRunning, LastError: "boom"}}, "10.0.0.2:1234")
+	h.Receive(Report{Node: "gpu-a", Status: status.Snapshot{State: status.StatePaused}}, "10.0.0.3:1234")
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	cases := []struct {
+		path   string
+		code   int
+		expect string
+	}{
+		{"/", http.StatusOK, "2 nodes"},
+		{"/nodes/gpu-a", http.StatusOK, "paused"},
+		{"/nodes/cpu-%3Cb%3E", http.StatusOK, "cpu-&lt;b&gt;"},
+		{"/nodes/missing", http.StatusNotFound, ""},
+		{NodesPath + "/gpu-a", http.StatusOK, `"node": "gpu-a"`},
+	}
+	for _, c := range cases {
+		resp, err := http.Get(srv.URL + c.path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", c.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("GET %s status = %d, want %d", c.path, resp.StatusCode, c.code)
+		}
+		if !strings.Contains(string(body), c.expect) {
+			t.Errorf("GET %s body doesn't contain %q:\n%s", c.path, c.expect, body)
+		}
+	}
+}
+
+func TestHub_CheckStale(t *testing.T) {
+	rec := &recorder{}
+	h := New("", rec)
+	h.StaleAfter = time.Minute
+	h.Receive(Report{Node: "gpu-a", Status: status.Snapshot{State: status.StateRunning}}, "")
+	h.Receive(Report{Node: "gpu-b", Status: status.Snapshot{State: status.StateRunning}}, "")
+	h.Receive(Report{Node: "gpu-c", Status: status.Snapshot{State: status.StateStopped}}, "")
+
+	h.CheckStale(time.Now())
+	h.CheckStale(time.Now().Add(2 * time.Minute))
+	h.CheckStale(time.Now().Add(3 * time.Minute))
+	if n, _ := h.Node("gpu-a"); !n.Missing {
+		t.Error("gpu-a not marked missing")
+	}
+	if n, _ := h.Node("gpu-c"); n.Missing {
+		t.Error("stopped gpu-c marked missing")
+	}
+
+	h.Receive(Report{Node: "gpu-a", Status: status.Snapshot{State: status.StateRunning}}, "")
+	if n, _ := h.Node("gpu-a"); n.Missing {
+		t.Error("gpu-a still marked missing after reporting")
+	}
+
+	want := "Node Joined the Hub|Node Joined the Hub|Node Joined the Hub|Node Stopped Reporting|Node Stopped Reporting|Node Reporting Again"
+	if got := strings.Join(rec.titles(), "|"); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestHub_Bounded(t *testing.T) {
+	h := New("", nil)
+	h.MaxNodes = 2
+	h.StaleAfter = time.Minute
+	h.ForgetAfter = time.Hour
+	for _, name := range []string{"a", "b"} {
+		if err := h.Receive(Report{Node: name}, ""); err != nil {
+			t.Fatalf("Receive(%s) error = %v", name, err)
+		}
+	}
+	if err := h.Receive(Report{Node: "c"}, ""); err != ErrFull {
+		t.Errorf("Receive() past MaxNodes error = %v, want ErrFull", err)
+	}
+	if err := h.Receive(Report{Node: "a"}, ""); err != nil {
+		t.Errorf("Receive() from a known node error = %v", err)
+	}
+
+	h.CheckStale(time.Now().Add(2 * time.Hour))
+	if nodes := h.Nodes(); len(nodes) != 0 {
+		t.Errorf("nodes after ForgetAfter = %+v", nodes)
+	}
+	if err := h.Receive(Report{Node: "c"}, ""); err != nil {
+		t.Errorf("Receive() after forgetting error = %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package humanize renders big reward and vote counts for people: thousands
+// separators, fixed-point decimals and signed deltas, never exponent notation.
+package humanize
+
+import (
+	"math/big"
+	"strings"
+)
+
+// DefaultPrecision is the number of fraction digits shown when values are
+// scaled by decimals
+const DefaultPrecision = 2
+
+// MaxDecimals is the most decimals a Format scales by; an int256 has at
+// most 77 digits, so more would only add leading zeros
+const MaxDecimals = 77
+
+// Format describes how a quantity is shown
+type Format struct {
+	// Decimals scales raw values down, e.g. 18 to show wei as whole tokens
+	Decimals int
+	// Precision is the maximum number of fraction digits; trailing zeros
+	// are trimmed. Zero uses DefaultPrecision.
+	Precision int
+	// Unit is appended after the number, e.g. "GSWARM"
+	Unit string
+}
+
+// Int renders n, e.g. "1,234,567" or "1.5 GSWARM"
+func (f Format) Int(n *big.Int) string {
+	if n == nil {
+		return "-"
+	}
+	return f.withUnit(f.number(n))
+}
+
+// Delta renders the change from prev to cur with its sign, e.g. "+12"
+func (f Format) Delta(prev, cur *big.Int) string {
+	if prev == nil || cur == nil {
+		return "-"
+	}
+	d := new(big.Int).Sub(cur, prev)
+	s := f.number(d)
+	switch d.Sign() {
+	case 1:
+		s = "+" + s
+	case 0:
+		s = "±" + s
+	}
+	return f.withUnit(s)
+}
+
+func (f Format) withUnit(s string) string {
+	if f.Unit == "" {
+		return s
+	}
+	return s + " " + f.Unit
+}
+
+func (f Format) number(n *big.Int) string {
+	abs := new(big.Int).Abs(n)
+	intPart, frac := abs, ""
+	if f.Decimals > 0 {
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(f.Decimals)), nil)
+		rem := new(big.Int)
+		intPart, rem = new(big.Int).QuoRem(abs, scale, rem)
+		precision := f.Precision
+		if precision <= 0 {
+			precision = DefaultPrecision
+		}
+		digits := rem.String()
+		digits = strings.Repeat("0", f.Decimals-len(digits)) + digits
+		if len(digits) > precision {
+			digits = digits[:precision]
+		}
+		frac = strings.TrimRight(digits, "0")
+	}
+
+	s := group(intPart.String())
+	if frac != "" {
+		s += "." + frac
+	}
+	if n.Sign() < 0 && s != "0" {
+		s = "-" + s
+	}
+	return s
+}
+
+// Float renders x scaled by Decimals with exactly precision fraction
+// digits, e.g. for averages such as rewards per day
+func (f Format) Float(x *big.Float, precision int) string {
+	if x == nil {
+		return "-"
+	}
+	scaled := new(big.Float).Set(x)
+	if f.Decimals > 0 {
+		scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(f.Decimals)), nil))
+		scaled.Quo(scaled, scale)
+	}
+	s := scaled.Text('f', precision)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	s = sign + group(intPart)
+	if frac != "" {
+		s += "." + frac
+	}
+	return f.withUnit(s)
+}
+
+// group inserts thousands separators into a string of digits
+func group(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// Int renders n with thousands separators and no scaling
+func Int(n *big.Int) string {
+	return Format{}.Int(n)
+}
@@ -0,0 +1,62 @@
+package humanize
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormat_Int(t *testing.T) {
+	wei, _ := new(big.Int).SetString("1234567890000000000000", 10)
+	cases := []struct {
+		name string
+		f    Format
+		n    *big.Int
+		want string
+	}{
+		{"small", Format{}, big.NewInt(999), "999"},
+		{"thousands", Format{}, big.NewInt(1234567), "1,234,567"},
+		{"negative", Format{}, big.NewInt(-1234), "-1,234"},
+		{"wei as tokens", Format{Decimals: 18, Unit: "ETH"}, wei, "1,234.56 ETH"},
+		{"precision", Format{Decimals: 18, Precision: 4}, wei, "1,234.5678"},
+		{"trailing zeros", Format{Decimals: 3}, big.NewInt(1500), "1.5"},
+		{"rounds to zero", Format{Decimals: 3}, big.NewInt(-5), "0"},
+		{"nil", Format{}, nil, "-"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.f.Int(c.n); got != c.want {
+				t.Errorf("Int() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFormat_Float(t *testing.T) {
+	if got := (Format{}).Float(big.NewFloat(1234567.25), 1); got != "1,234,567.2" {
+		t.Errorf("Float() = %q, want 1,234,567.2", got)
+	}
+	if got := (Format{Decimals: 3, Unit: "pts"}).Float(big.NewFloat(-2400), 0); got != "-2 pts" {
+		t.Errorf("Float() = %q, want -2 pts", got)
+	}
+	if got := (Format{}).Float(big.NewFloat(1e21), 0); got != "1,000,000,000,000,000,000,000" {
+		t.Errorf("Float() = %q, want no exponent notation", got)
+	}
+}
+
+func TestFormat_Delta(t *testing.T) {
+	cases := []struct {
+		prev, cur int64
+		want      string
+	}{
+		{100, 112, "+12 pts"},
+		{112, 100, "-12 pts"},
+		{100, 100, "±0 pts"},
+		{0, 1500, "+1,500 pts"},
+	}
+	f := Format{Unit: "pts"}
+	for _, c := range cases {
+		if got := f.Delta(big.NewInt(c.prev), big.NewInt(c.cur)); got != c.want {
+			t.Errorf("Delta(%d, %d) = %q, want %q", c.prev, c.cur, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package identity reads the libp2p identity file (swarm.pem) used by the
+// trainer and derives its peer ID.
+package identity
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"runtime"
+)
+
+// Identity file permission modes
+const (
+	PermsFix  = "fix"
+	PermsWarn = "warn"
+	PermsOff  = "off"
+)
+
+// PrivateMode is what identity files are restricted to: readable by their
+// owner only, since anyone who copies the key can run as this peer
+const PrivateMode os.FileMode = 0o600
+
+// Duplicate identity guard modes
+const (
+	ModeOff  = "off"
+	ModeWarn = "warn"
+	ModeFail = "fail"
+)
+
+// libp2p key types, from the libp2p crypto protobuf
+const (
+	keyTypeRSA     = 0
+	keyTypeEd25519 = 1
+)
+
+// maxInlineKeyLen is the largest marshaled public key embedded in a peer ID
+// as an identity multihash instead of being hashed
+const maxInlineKeyLen = 42
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// PeerID returns the peer ID of the identity file at path
+func PeerID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return PeerIDFromKey(data)
+}
+
+// Info describes a checked identity file
+type Info struct {
+	PeerID string
+	// Mode is the file's permissions as found
+	Mode os.FileMode
+	// Loose is true when other users could read or change the file
+	Loose bool
+	// Fixed is true when Check restricted the permissions to PrivateMode
+	Fixed bool
+}
+
+// Check verifies that the identity file at path holds a valid libp2p
+// private key and that only its owner can access it. With fix, loose
+// permissions are restricted to PrivateMode. A missing file is reported
+// with an error satisfying os.IsNotExist.
+func Check(path string, fix bool) (Info, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Info{}, err
+	}
+	if fi.IsDir() {
+		return Info{}, fmt.Errorf("%s is a directory", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	peerID, err := PeerIDFromKey(data)
+	if err != nil {
+		return Info{}, err
+	}
+
+	info := Info{PeerID: peerID, Mode: fi.Mode().Perm()}
+	// Windows only reports a read-only bit, not who can read the file
+	if runtime.GOOS != "windows" && info.Mode&0o077 != 0 {
+		info.Loose = true
+		if fix {
+			if err := os.Chmod(path, PrivateMode); err != nil {
+				return info, fmt.Errorf("failed to restrict permissions of %s: %w", path, err)
+			}
+			info.Fixed = true
+		}
+	}
+	return info, nil
+}
+
+// PeerIDFromKey derives the peer ID from a protobuf-marshaled libp2p private
+// key, as written by hivemind
+func PeerIDFromKey(data []byte) (string, error) {
+	keyType, keyData, err := unmarshalKey(data)
+	if err != nil {
+		return "", err
+	}
+
+	var pub []byte
+	switch keyType {
+	case keyTypeRSA:
+		priv, err := x509.ParsePKCS1PrivateKey(keyData)
+		if err != nil {
+			return "", fmt.Errorf("invalid RSA identity key: %w", err)
+		}
+		if pub, err = x509.MarshalPKIXPublicKey(&priv.PublicKey); err != nil {
+			return "", fmt.Errorf("invalid RSA identity key: %w", err)
+		}
+	case keyTypeEd25519:
+		// The private key is the 32-byte seed followed by the public key
+		if len(keyData) != 64 {
+			return "", fmt.Errorf("invalid Ed25519 identity key length %d", len(keyData))
+		}
+		pub = keyData[32:]
+	default:
+		return "", fmt.Errorf("unsupported identity key type %d", keyType)
+	}
+
+	pubProto := marshalKey(keyType, pub)
+	var mh []byte
+	if len(pubProto) <= maxInlineKeyLen {
+		mh = append([]byte{0x00, byte(len(pubProto))}, pubProto...)
+	} else {
+		sum := sha256.Sum256(pubProto)
+		mh = append([]byte{0x12, 0x20}, sum[:]...)
+	}
+	return base58Encode(mh), nil
+}
+
+// unmarshalKey decodes the libp2p PrivateKey protobuf: field 1 is the key
+// type (varint) and field 2 the key bytes
+func unmarshalKey(data []byte) (int, []byte, error) {
+	keyType, keyData := -1, []byte(nil)
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, nil, errors.New("identity file is not a libp2p private key")
+		}
+		data = data[n:]
+		switch tag {
+		case 1<<3 | 0: // Type, varint
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return 0, nil, errors.New("identity file has a malformed key type")
+			}
+			keyType, data = int(v), data[n:]
+		case 2<<3 | 2: // Data, bytes
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return 0, nil, errors.New("identity file has truncated key data")
+			}
+			keyData, data = data[n:n+int(l)], data[n+int(l):]
+		default:
+			return 0, nil, errors.New("identity file is not a libp2p private key")
+		}
+	}
+	if keyType < 0 || keyData == nil {
+		return 0, nil, errors.New("identity file is missing the key type or data")
+	}
+	return keyType, keyData, nil
+}
+
+// marshalKey encodes a libp2p PublicKey protobuf
+func marshalKey(keyType int, data []byte) []byte {
+	out := []byte{1<<3 | 0}
+	out = binary.AppendUvarint(out, uint64(keyType))
+	out = append(out, 2<<3|2)
+	out = binary.AppendUvarint(out, uint64(len(data)))
+	return append(out, data...)
+}
+
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	radix, mod := big.NewInt(58), new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
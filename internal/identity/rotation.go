@@ -0,0 +1,93 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RotationFile records the switches to spare identities, in the state
+// directory
+const RotationFile = "identity_rotation.json"
+
+// Rotation is one switch from a conflicting identity to a spare
+type Rotation struct {
+	From     string    `json:"from"`
+	FromPeer string    `json:"from_peer_id,omitempty"`
+	To       string    `json:"to"`
+	ToPeer   string    `json:"to_peer_id"`
+	At       time.Time `json:"at"`
+	Reason   string    `json:"reason"`
+}
+
+// Rotations is the record of every switch, oldest first
+type Rotations struct {
+	Rotations []Rotation `json:"rotations"`
+}
+
+// LoadRotations reads the record from stateDir; a missing file is an
+// empty record
+func LoadRotations(stateDir string) (*Rotations, error) {
+	r := &Rotations{}
+	data, err := os.ReadFile(filepath.Join(stateDir, RotationFile))
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", RotationFile, err)
+	}
+	return r, nil
+}
+
+// Current returns the identity the node was last moved to, following the
+// switches from configured; "" means configured is still in use. Switches
+// made from other identities, such as an earlier --identity-path, are
+// skipped, so changing the configured identity starts over.
+func (r *Rotations) Current(configured string) string {
+	current := configured
+	for _, rot := range r.Rotations {
+		if rot.From == current {
+			current = rot.To
+		}
+	}
+	if current == configured {
+		return ""
+	}
+	return current
+}
+
+// Unused returns the spares that have never been used, leaving out current
+func (r *Rotations) Unused(spares []string, current string) []string {
+	used := map[string]bool{current: true}
+	for _, rot := range r.Rotations {
+		used[rot.From] = true
+		used[rot.To] = true
+	}
+	var unused []string
+	for _, s := range spares {
+		if !used[s] {
+			unused = append(unused, s)
+			used[s] = true
+		}
+	}
+	return unused
+}
+
+// Record appends rot and writes the record to stateDir atomically
+func (r *Rotations) Record(stateDir string, rot Rotation) error {
+	r.Rotations = append(r.Rotations, rot)
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(stateDir, RotationFile)
+	if err := os.WriteFile(file+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
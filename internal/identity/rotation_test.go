@@ -0,0 +1,52 @@
+package identity
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRotations(t *testing.T) {
+	dir := t.TempDir()
+	r, err := LoadRotations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spares := []string{"/ids/b.pem", "/ids/c.pem", "/ids/b.pem", "/ids/a.pem"}
+	if got := r.Unused(spares, "/ids/a.pem"); !reflect.DeepEqual(got, []string{"/ids/b.pem", "/ids/c.pem"}) {
+		t.Errorf("Unused() = %v", got)
+	}
+	if got := r.Current("/ids/a.pem"); got != "" {
+		t.Errorf("Current() before any rotation = %q", got)
+	}
+
+	if err := r.Record(dir, Rotation{From: "/ids/a.pem", To: "/ids/b.pem", ToPeer: "QmB", At: time.Now(), Reason: "conflicts"}); err != nil {
+		t.Fatal(err)
+	}
+	r, err = LoadRotations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Current("/ids/a.pem"); got != "/ids/b.pem" {
+		t.Errorf("Current() = %q, want the spare after a restart", got)
+	}
+	if got := r.Current("/ids/other.pem"); got != "" {
+		t.Errorf("Current() for a newly configured identity = %q, want it used", got)
+	}
+	if got := r.Unused(spares, "/ids/b.pem"); !reflect.DeepEqual(got, []string{"/ids/c.pem"}) {
+		t.Errorf("Unused() after a rotation = %v, want only c", got)
+	}
+
+	// A later --identity-path rotates on its own; a's chain carries on
+	for _, rot := range []Rotation{{From: "/ids/x.pem", To: "/ids/y.pem"}, {From: "/ids/b.pem", To: "/ids/c.pem"}} {
+		if err := r.Record(dir, rot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := r.Current("/ids/a.pem"); got != "/ids/c.pem" {
+		t.Errorf("Current() = %q, want the end of a's switches", got)
+	}
+	if got := r.Current("/ids/x.pem"); got != "/ids/y.pem" {
+		t.Errorf("Current() for another configured identity = %q, want its own spare", got)
+	}
+}
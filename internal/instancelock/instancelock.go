@@ -0,0 +1,151 @@
+// Package instancelock stops two supervisors from running the same node.
+// Both would train with one identity, which the swarm treats as a
+// duplicate, and would overwrite each other's state. The supervisor takes
+// an advisory lock on its state directory and on its identity PEM; a
+// second one pointed at either refuses to start and names the first.
+package instancelock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/timefmt"
+)
+
+// StateFile is the lock file in the state directory
+const StateFile = "supervisor.lock"
+
+// IdentityPath returns the lock file for the identity PEM at pem. The PEM
+// itself isn't locked, as the trainer replaces it when creating one.
+func IdentityPath(pem string) string {
+	return pem + ".lock"
+}
+
+// Owner describes the supervisor holding a lock
+type Owner struct {
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	Started  time.Time `json:"started"`
+	StateDir string    `json:"state_dir,omitempty"`
+	Identity string    `json:"identity,omitempty"`
+}
+
+// Self describes this process as an owner
+func Self(stateDir, identity string) Owner {
+	host, _ := os.Hostname()
+	abs := func(p string) string {
+		if a, err := filepath.Abs(p); err == nil {
+			return a
+		}
+		return p
+	}
+	return Owner{PID: os.Getpid(), Host: host, Started: time.Now(), StateDir: abs(stateDir), Identity: abs(identity)}
+}
+
+// HeldError reports a lock held by another supervisor
+type HeldError struct {
+	Path string
+	// Owner is who holds it, when the lock file could be read
+	Owner *Owner
+	// Time renders when the owner started
+	Time timefmt.Formatter
+}
+
+func (e *HeldError) Error() string {
+	what := "another gswarm supervisor"
+	if o := e.Owner; o != nil {
+		what = fmt.Sprintf("another gswarm supervisor (PID %d on %s, started %s)", o.PID, o.Host, e.Time.Format(o.Started))
+	}
+	return fmt.Sprintf("%s is already running with %s", what, strings.TrimSuffix(e.Path, ".lock"))
+}
+
+// Lock holds the lock files until released
+type Lock struct {
+	files []*os.File
+	once  sync.Once
+}
+
+// Acquire locks each path without waiting and records owner in it. When
+// one is held by another process it releases the rest and returns a
+// *HeldError. The kernel drops the locks when the process exits, so a
+// crashed supervisor never blocks the next one.
+func Acquire(owner Owner, paths ...string) (*Lock, error) {
+	l := &Lock{}
+	for _, path := range paths {
+		f, err := lockFile(path)
+		if err != nil {
+			l.Release()
+			return nil, err
+		}
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			l.Release()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if !ok {
+			held := &HeldError{Path: path, Owner: readOwner(f)}
+			f.Close()
+			l.Release()
+			return nil, held
+		}
+		l.files = append(l.files, f)
+		writeOwner(f, owner)
+	}
+	return l, nil
+}
+
+func lockFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create lock file %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func readOwner(f *os.File) *Owner {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+	var o Owner
+	if err := json.NewDecoder(f).Decode(&o); err != nil || o.PID == 0 {
+		return nil
+	}
+	return &o
+}
+
+// writeOwner is best-effort: the lock holds without it, only the message
+// for the next supervisor is less helpful
+func writeOwner(f *os.File, o Owner) {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return
+	}
+	if f.Truncate(0) == nil {
+		f.WriteAt(append(data, '\n'), 0)
+	}
+}
+
+// Release unlocks and closes the lock files. It is safe to call more than
+// once, and on nil. The files are left in place, since removing them would
+// race with a supervisor starting.
+func (l *Lock) Release() {
+	if l == nil {
+		return
+	}
+	l.once.Do(func() {
+		for _, f := range l.files {
+			unlock(f)
+			f.Close()
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package instancelock
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	if !Supported {
+		t.Skip("locking isn't supported on this platform")
+	}
+	dir := t.TempDir()
+	state := filepath.Join(dir, "state", StateFile)
+	pem := IdentityPath(filepath.Join(dir, "swarm.pem"))
+
+	first, err := Acquire(Owner{PID: 42, Host: "gpu-a"}, state, pem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another supervisor with the same identity but its own state dir
+	other := filepath.Join(dir, "other", StateFile)
+	_, err = Acquire(Owner{PID: 43}, other, pem)
+	var held *HeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("Acquire() = %v, want a HeldError", err)
+	}
+	if held.Path != pem || held.Owner == nil || held.Owner.PID != 42 || held.Owner.Host != "gpu-a" {
+		t.Errorf("HeldError = %+v", held)
+	}
+	if msg := held.Error(); !strings.Contains(msg, "PID 42 on gpu-a") || !strings.HasSuffix(msg, "swarm.pem") {
+		t.Errorf("Error() = %q", msg)
+	}
+
+	// The failed attempt released the state dir lock it took first
+	second, err := Acquire(Owner{PID: 44}, other)
+	if err != nil {
+		t.Fatalf("Acquire() after a failed attempt = %v", err)
+	}
+	second.Release()
+
+	first.Release()
+	first.Release()
+	third, err := Acquire(Owner{PID: 45}, state, pem)
+	if err != nil {
+		t.Fatalf("Acquire() after Release() = %v", err)
+	}
+	third.Release()
+
+	var none *Lock
+	none.Release()
+}
+
+func TestSelf(t *testing.T) {
+	o := Self(".gswarm", "swarm.pem")
+	if o.PID == 0 || !filepath.IsAbs(o.StateDir) || !filepath.IsAbs(o.Identity) {
+		t.Errorf("Self() = %+v", o)
+	}
+}
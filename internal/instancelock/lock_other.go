@@ -0,0 +1,15 @@
+//go:build !unix
+
+package instancelock
+
+import "os"
+
+// Supported reports whether supervisors can be locked out here
+const Supported = false
+
+// Without flock, a second supervisor isn't detected
+func tryLock(*os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(*os.File) {}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package instancelock
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Supported reports whether supervisors can be locked out here
+const Supported = true
+
+// tryLock takes an exclusive flock on f without blocking
+func tryLock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
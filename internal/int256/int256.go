@@ -0,0 +1,27 @@
+// Package int256 reads Solidity int256 values, which the chain returns as
+// 256-bit two's complement words.
+package int256
+
+import "math/big"
+
+// twoTo256 is 2^256, the modulus of two's complement int256 values
+var twoTo256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// FromUint256 reinterprets the uint256 n as an int256, in place
+func FromUint256(n *big.Int) *big.Int {
+	if n.Bit(255) == 1 {
+		n.Sub(n, twoTo256)
+	}
+	return n
+}
+
+// Repair fixes, in place, a reward total saved by gswarm versions that
+// read getTotalRewards as uint256, where a penalty became a number of
+// 2^255 or more, and a sum over peers could carry extra multiples of
+// 2^256. Real totals are far below 2^255 and are returned unchanged.
+func Repair(n *big.Int) *big.Int {
+	if n == nil || n.Sign() <= 0 || n.BitLen() <= 255 {
+		return n
+	}
+	return FromUint256(n.Mod(n, twoTo256))
+}
@@ -0,0 +1,41 @@
+package int256
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFromUint256(t *testing.T) {
+	if got := FromUint256(new(big.Int).Sub(twoTo256, big.NewInt(3))); got.Int64() != -3 {
+		t.Errorf("FromUint256(2^256-3) = %s, want -3", got)
+	}
+	if got := FromUint256(big.NewInt(500)); got.Int64() != 500 {
+		t.Errorf("FromUint256(500) = %s, want 500", got)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	unsigned := func(n int64) *big.Int {
+		return new(big.Int).Add(twoTo256, big.NewInt(n))
+	}
+	cases := []struct {
+		name string
+		in   *big.Int
+		want int64
+	}{
+		{"positive", big.NewInt(1500), 1500},
+		{"negative", big.NewInt(-20), -20},
+		{"unsigned penalty", unsigned(-20), -20},
+		// two peers' unsigned penalties summed
+		{"summed penalties", new(big.Int).Add(unsigned(-20), unsigned(-5)), -25},
+		{"penalty and reward", new(big.Int).Add(unsigned(-20), big.NewInt(50)), 30},
+	}
+	for _, c := range cases {
+		if got := Repair(c.in); got.Cmp(big.NewInt(c.want)) != 0 {
+			t.Errorf("%s: Repair() = %s, want %d", c.name, got, c.want)
+		}
+	}
+	if Repair(nil) != nil {
+		t.Error("Repair(nil) != nil")
+	}
+}
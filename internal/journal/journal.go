@@ -0,0 +1,110 @@
+// Package journal provides an append-only event journal stored as JSON lines
+// in the supervisor state directory.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the journal file name inside the state directory
+const FileName = "journal.jsonl"
+
+// Entry is a single journal record. Run is the ID of the training run that
+// was current when it was written.
+type Entry struct {
+	Time time.Time       `json:"time"`
+	Type string          `json:"type"`
+	Run  string          `json:"run,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Journal appends entries to a JSON lines file
+type Journal struct {
+	Path string
+	// Location is the timezone entries are stamped in; nil is the system's
+	Location *time.Location
+	mu       sync.Mutex
+	run      string
+}
+
+// Open returns a journal stored in stateDir, creating the directory if needed
+func Open(stateDir string) (*Journal, error) {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return &Journal{Path: filepath.Join(stateDir, FileName)}, nil
+}
+
+// SetRun sets the run ID recorded with the entries appended from now on
+func (j *Journal) SetRun(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.run = id
+}
+
+// Append writes an entry of the given type with data marshalled as JSON
+func (j *Journal) Append(entryType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal data: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	if j.Location != nil {
+		now = now.In(j.Location)
+	}
+	line, err := json.Marshal(Entry{Time: now, Type: entryType, Run: j.run, Data: raw})
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+
+	f, err := os.OpenFile(j.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Read returns all entries in the journal, skipping lines that don't parse
+func (j *Journal) Read() ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return entries, nil
+}
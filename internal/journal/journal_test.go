@@ -0,0 +1,84 @@
+package journal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal_AppendAndRead(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	j, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if err := j.Append("run_report", map[string]int{"run": 1}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := j.Append("run_report", map[string]int{"run": 2}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	entries, err := j.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Read() returned %d entries, want 2", len(entries))
+	}
+
+	var data map[string]int
+	if err := json.Unmarshal(entries[1].Data, &data); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if data["run"] != 2 {
+		t.Errorf("entries[1].Data run = %d, want 2", data["run"])
+	}
+}
+
+func TestJournal_ReadSkipsCorruptLines(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := os.WriteFile(j.Path, []byte("not json\n{\"type\":\"info\"}\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	entries, err := j.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Type != "info" {
+		t.Errorf("Read() = %+v, want one info entry", entries)
+	}
+}
+
+func TestJournal_ReadMissingFile(t *testing.T) {
+	j := &Journal{Path: filepath.Join(t.TempDir(), FileName)}
+	entries, err := j.Read()
+	if err != nil || entries != nil {
+		t.Errorf("Read() = %v, %v; want nil, nil", entries, err)
+	}
+}
+
+func TestJournal_SetRun(t *testing.T) {
+	j, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Append("clock_jump", nil)
+	j.SetRun("20250701-100000-3f9a2c")
+	j.Append("run_report", map[string]int{"run": 1})
+
+	entries, err := j.Read()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Read() = %v, %v", entries, err)
+	}
+	if entries[0].Run != "" || entries[1].Run != "20250701-100000-3f9a2c" {
+		t.Errorf("runs = %q, %q", entries[0].Run, entries[1].Run)
+	}
+}
@@ -0,0 +1,75 @@
+// Package lines splits the trainer's output into lines for the writers that
+// watch it, however long a line gets.
+package lines
+
+import "bytes"
+
+// DefaultMax is the longest line a Splitter passes on whole
+const DefaultMax = 64 * 1024
+
+// Splitter is an io.Writer that calls a function with each line written to
+// it. Lines end at '\n' or '\r', so each redraw of a progress bar counts as
+// a line.
+//
+// A line longer than Max is cut: its first Max bytes are passed on as soon
+// as they arrive and the rest is skipped up to the next line end. The start
+// of a traceback line that prints a whole tensor is still seen, and memory
+// stays bounded however long the line gets. Cut lines are counted.
+//
+// A Splitter is not safe for concurrent use.
+type Splitter struct {
+	// Max is the longest line passed on whole; 0 means DefaultMax
+	Max int
+
+	line     func(line []byte)
+	partial  []byte
+	skipping bool
+	cut      int
+}
+
+// New creates a splitter that calls line with each line, without its line
+// end. The slice is only valid during the call.
+func New(line func([]byte)) *Splitter {
+	return &Splitter{line: line}
+}
+
+// Write implements io.Writer
+func (s *Splitter) Write(p []byte) (int, error) {
+	max := s.Max
+	if max <= 0 {
+		max = DefaultMax
+	}
+	n := len(p)
+	for len(p) > 0 {
+		end := bytes.IndexAny(p, "\r\n")
+		chunk := p
+		if end >= 0 {
+			chunk = p[:end]
+		}
+		if !s.skipping {
+			if room := max - len(s.partial); len(chunk) > room {
+				s.partial = append(s.partial, chunk[:room]...)
+				s.skipping = true
+				s.cut++
+				s.line(s.partial)
+			} else {
+				s.partial = append(s.partial, chunk...)
+			}
+		}
+		if end < 0 {
+			break
+		}
+		if !s.skipping {
+			s.line(s.partial)
+		}
+		s.partial = s.partial[:0]
+		s.skipping = false
+		p = p[end+1:]
+	}
+	return n, nil
+}
+
+// Cut returns the number of lines that were longer than Max
+func (s *Splitter) Cut() int {
+	return s.cut
+}
@@ -0,0 +1,53 @@
+package lines
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitter(t *testing.T) {
+	var got []string
+	s := New(func(l []byte) { got = append(got, string(l)) })
+	for _, chunk := range []string{"first\nsec", "ond\r\n", "progress 1\rprogress 2\r", "tail"} {
+		s.Write([]byte(chunk))
+	}
+	want := []string{"first", "second", "", "progress 1", "progress 2"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	if s.Cut() != 0 {
+		t.Errorf("Cut() = %d, want 0", s.Cut())
+	}
+}
+
+func TestSplitter_LongLine(t *testing.T) {
+	var got []string
+	s := New(func(l []byte) { got = append(got, string(l)) })
+	s.Max = 16
+
+	// a line far longer than Max, written in pieces, is passed on once,
+	// cut to its start, and the line after it is unaffected
+	s.Write([]byte("RuntimeError: CUDA out of memory, tensor(["))
+	for i := 0; i < 1000; i++ {
+		s.Write([]byte("0.1234, "))
+	}
+	s.Write([]byte("])\nnext line\n"))
+
+	want := []string{"RuntimeError: CU", "next line"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	if s.Cut() != 1 {
+		t.Errorf("Cut() = %d, want 1", s.Cut())
+	}
+	if cap(s.partial) > 64 {
+		t.Errorf("buffer grew to %d bytes", cap(s.partial))
+	}
+
+	// a line of exactly Max bytes is whole
+	got = nil
+	s.Write([]byte(strings.Repeat("x", 16) + "\n"))
+	if len(got) != 1 || len(got[0]) != 16 || s.Cut() != 1 {
+		t.Errorf("line of Max bytes: %q, Cut() = %d", got, s.Cut())
+	}
+}
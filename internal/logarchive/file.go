@@ -0,0 +1,87 @@
+package logarchive
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// File is a log opened for appending that rotates itself with Rotate once
+// it grows past its maximum size, so a process running for weeks doesn't
+// grow it without bound
+type File struct {
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// OpenFile opens the log at path for appending. It is rotated while
+// written once it holds more than maxSize bytes.
+func OpenFile(path string, maxSize int64) (*File, error) {
+	l := &File{path: path, maxSize: maxSize}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *File) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+// Write appends p, rotating the log afterwards when it has grown too big
+func (l *File) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		if err := l.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	if err == nil && l.size > l.maxSize {
+		l.rotate()
+	}
+	return n, err
+}
+
+// rotate archives the log and starts a new one. When that fails, the log
+// goes on growing and is tried again after another maxSize bytes.
+func (l *File) rotate() {
+	l.f.Close()
+	l.f = nil
+	_, rotateErr := Rotate(l.path, l.maxSize, time.Now())
+	if err := l.open(); err != nil {
+		return
+	}
+	if rotateErr != nil {
+		l.size = 0
+	}
+}
+
+// Close closes the log
+func (l *File) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
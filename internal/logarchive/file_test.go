@@ -0,0 +1,40 @@
+package logarchive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	withGzip(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gensyn_rl_swarm_go.log")
+	if err := os.WriteFile(path, []byte("from the last start\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := OpenFile(path, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	line := strings.Repeat("x", 19) + "\n"
+	for i := 0; i < 3; i++ {
+		if _, err := l.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The earlier content counts towards the size: 20 + 3*20 > 64
+	archives, _ := filepath.Glob(filepath.Join(dir, "gensyn_rl_swarm_go-*.log.gz"))
+	if len(archives) != 1 {
+		t.Fatalf("archives = %v, want one", archives)
+	}
+	if _, err := l.Write([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != line {
+		t.Errorf("log after rotation = %q, want only the new line", data)
+	}
+}
@@ -0,0 +1,239 @@
+// Package logarchive compresses rotated logs and run records and prunes the
+// archives to a total size budget, so a node left running for months
+// doesn't fill its disk.
+package logarchive
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRetention is the default size budget for logs and their archives
+const DefaultRetention = "2GB"
+
+// Archive file extensions
+const (
+	ExtZstd = ".zst"
+	ExtGzip = ".gz"
+)
+
+// zstdCommand finds the zstd command. The standard library has no zstd
+// encoder, so files are compressed with gzip when it isn't installed.
+var zstdCommand = func() (string, error) {
+	return exec.LookPath("zstd")
+}
+
+// IsArchive reports whether name was written by Compress
+func IsArchive(name string) bool {
+	return strings.HasSuffix(name, ExtZstd) || strings.HasSuffix(name, ExtGzip)
+}
+
+// Compress replaces the file at path with a compressed copy and returns the
+// copy's path. zstd is used when the zstd command is installed, gzip
+// otherwise. The original is only removed once the copy is complete.
+func Compress(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if zstd, err := zstdCommand(); err == nil {
+		out := path + ExtZstd
+		cmd := exec.Command(zstd, "-q", "-f", "-3", "-o", out, path)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			os.Remove(out)
+			return "", fmt.Errorf("zstd failed: %v: %s", err, strings.TrimSpace(string(output)))
+		}
+		return out, finish(path, out, info)
+	}
+
+	out := path + ExtGzip
+	if err := gzipFile(path, out); err != nil {
+		os.Remove(out)
+		return "", err
+	}
+	return out, finish(path, out, info)
+}
+
+func gzipFile(path, out string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	return nil
+}
+
+// Decompress writes the original content of the archive at path to w
+func Decompress(path string, w io.Writer) error {
+	if strings.HasSuffix(path, ExtZstd) {
+		zstd, err := zstdCommand()
+		if err != nil {
+			return fmt.Errorf("reading %s needs the zstd command: %w", path, err)
+		}
+		var stderr strings.Builder
+		cmd := exec.Command(zstd, "-q", "-d", "-c", path)
+		cmd.Stdout, cmd.Stderr = w, &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("zstd failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	if _, err := io.Copy(w, zr); err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	return nil
+}
+
+// finish gives the archive the original's time, which Prune sorts by, and
+// removes the original
+func finish(path, out string, info os.FileInfo) error {
+	os.Chtimes(out, info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
+
+// Rotate archives the file at path once it has grown past maxSize: it is
+// renamed with now in its name, e.g. app-20250102-150405.log, and
+// compressed. It returns the archive's path, or "" when the file is
+// smaller or missing. The caller reopens path afterwards.
+func Rotate(path string, maxSize int64, now time.Time) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Size() <= maxSize {
+		return "", nil
+	}
+	ext := filepath.Ext(path)
+	rotated := strings.TrimSuffix(path, ext) + "-" + now.Format("20060102-150405") + ext
+	if err := os.Rename(path, rotated); err != nil {
+		return "", fmt.Errorf("failed to rotate %s: %w", path, err)
+	}
+	return Compress(rotated)
+}
+
+// CompressOld compresses the files in dir matching pattern, except the
+// newest keep of them. Their names must embed their start time, as run
+// logs' do, so that name order is age order. It returns the archives made.
+func CompressOld(dir, pattern string, keep int) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	if keep > len(matches) {
+		keep = len(matches)
+	}
+	var archived []string
+	for _, path := range matches[:len(matches)-keep] {
+		out, err := Compress(path)
+		if err != nil {
+			return archived, err
+		}
+		archived = append(archived, out)
+	}
+	return archived, nil
+}
+
+// Prune deletes the oldest archives in dirs until the logs and archives
+// there, together, fit in budget bytes. Files that aren't archives are
+// counted but never deleted. It returns the deleted paths.
+func Prune(budget int64, dirs ...string) ([]string, error) {
+	type file struct {
+		path string
+		size int64
+		mod  time.Time
+	}
+	var total int64
+	var archives []file
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			total += info.Size()
+			if IsArchive(e.Name()) {
+				archives = append(archives, file{filepath.Join(dir, e.Name()), info.Size(), info.ModTime()})
+			}
+		}
+	}
+
+	sort.Slice(archives, func(i, j int) bool { return archives[i].mod.Before(archives[j].mod) })
+	var removed []string
+	for _, a := range archives {
+		if total <= budget {
+			break
+		}
+		if err := os.Remove(a.path); err != nil {
+			return removed, fmt.Errorf("failed to prune %s: %w", a.path, err)
+		}
+		total -= a.size
+		removed = append(removed, a.path)
+	}
+	return removed, nil
+}
+
+// ParseSize parses a size such as 2GB, 500MiB or 1.5G; units are powers
+// of 1024 and a plain number is bytes
+func ParseSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	num := strings.TrimRightFunc(t, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+	unit := strings.TrimSpace(t[len(num):])
+	num = strings.TrimSpace(num)
+	shift := map[string]uint{"": 0, "B": 0, "K": 10, "KB": 10, "KIB": 10, "M": 20, "MB": 20, "MIB": 20, "G": 30, "GB": 30, "GIB": 30, "T": 40, "TB": 40, "TIB": 40}
+	n, ok := shift[unit]
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid size %q: use a number with an optional unit, e.g. 2GB or 500MB", s)
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q: use a number with an optional unit, e.g. 2GB or 500MB", s)
+	}
+	return int64(v * float64(uint64(1)<<n)), nil
+}
@@ -0,0 +1,189 @@
+package logarchive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withGzip makes Compress use gzip, as on a machine without zstd
+func withGzip(t *testing.T) {
+	orig := zstdCommand
+	zstdCommand = func() (string, error) { return "", errors.New("not installed") }
+	t.Cleanup(func() { zstdCommand = orig })
+}
+
+func TestCompress_Gzip(t *testing.T) {
+	withGzip(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run-20250102-150405.log")
+	data := []byte(strings.Repeat("step 1 loss 0.5\n", 1000))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Compress(path)
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	if out != path+ExtGzip {
+		t.Errorf("Compress() = %s, want %s", out, path+ExtGzip)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("original still exists: %v", err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("archive holds %d bytes (%v), want the original %d", len(got), err, len(data))
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	withGzip(t)
+	path := filepath.Join(t.TempDir(), "run-20250102-150405.log")
+	os.WriteFile(path, []byte("step 1\nstep 2\n"), 0o644)
+	out, err := Compress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := Decompress(out, &b); err != nil || b.String() != "step 1\nstep 2\n" {
+		t.Errorf("Decompress() = %q, %v", b.String(), err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	withGzip(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gensyn_rl_swarm_go.log")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := Rotate(path, 100, time.Now()); err != nil || out != "" {
+		t.Fatalf("Rotate() of a small file = %q, %v, want no rotation", out, err)
+	}
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local)
+	out, err := Rotate(path, 5, now)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if want := filepath.Join(dir, "gensyn_rl_swarm_go-20250102-150405.log.gz"); out != want {
+		t.Errorf("Rotate() = %s, want %s", out, want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("rotated log still exists: %v", err)
+	}
+	if out, err := Rotate(path, 5, now); err != nil || out != "" {
+		t.Errorf("Rotate() of a missing file = %q, %v", out, err)
+	}
+}
+
+func TestCompressOld(t *testing.T) {
+	withGzip(t)
+	dir := t.TempDir()
+	for _, name := range []string{"run-20250101-000000.log", "run-20250102-000000.log", "run-20250103-000000.log", "other.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archived, err := CompressOld(dir, "run-*.log", 1)
+	if err != nil {
+		t.Fatalf("CompressOld() error = %v", err)
+	}
+	if len(archived) != 2 {
+		t.Errorf("CompressOld() archived %v, want the two older run logs", archived)
+	}
+	for _, name := range []string{"run-20250103-000000.log", "other.log", "run-20250101-000000.log.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	write := func(name string, size int, age int) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mod := base.Add(time.Duration(age) * time.Minute)
+		os.Chtimes(path, mod, mod)
+	}
+	write("run-1.log.zst", 400, 1)
+	write("run-2.log.gz", 400, 2)
+	write("run-3.log.zst", 400, 3)
+	write("run-4.log", 500, 4)
+
+	removed, err := Prune(1000, dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "run-1.log.zst"), filepath.Join(dir, "run-2.log.gz")}
+	if strings.Join(removed, ",") != strings.Join(want, ",") {
+		t.Errorf("Prune() removed %v, want %v", removed, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-4.log")); err != nil {
+		t.Errorf("Prune() removed a live log: %v", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"2GB", 2 << 30},
+		{"500mb", 500 << 20},
+		{"1.5G", 3 << 29},
+		{"64 KiB", 64 << 10},
+		{"1024", 1024},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		if got, err := ParseSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "GB", "2XB", "-1GB", "two"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("ParseSize(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestCompress_Zstd(t *testing.T) {
+	if _, err := zstdCommand(); err != nil {
+		t.Skip("zstd isn't installed")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Repeat("{}\n", 100)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Compress(path)
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || out != path+ExtZstd || !bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		t.Errorf("Compress() = %s (%v), want a zstd frame in %s", out, err, path+ExtZstd)
+	}
+}
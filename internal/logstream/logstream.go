@@ -0,0 +1,194 @@
+// Package logstream serves the trainer's output to status API clients that
+// follow it live. Each client gets its own bounded buffer that drops its
+// oldest lines when the client falls behind, so a slow or stalled client
+// never holds up the goroutines copying the trainer's output.
+package logstream
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Deep-Commit/gswarm/internal/lines"
+)
+
+const (
+	// Backlog is the number of recent lines kept for new clients
+	Backlog = 200
+	// ClientLines bounds the lines queued for one client
+	ClientLines = 1000
+	// MaxLine bounds each line; longer ones are cut
+	MaxLine = 4096
+)
+
+// Ring is a fixed-size queue of lines that drops the oldest line to make
+// room for a new one when it is full
+type Ring struct {
+	lines   []string
+	start   int
+	count   int
+	dropped int
+}
+
+// NewRing creates a ring holding up to size lines
+func NewRing(size int) *Ring {
+	return &Ring{lines: make([]string, size)}
+}
+
+// Push adds a line, dropping the oldest one if the ring is full
+func (r *Ring) Push(line string) {
+	if r.count == len(r.lines) {
+		r.lines[r.start] = ""
+		r.start = (r.start + 1) % len(r.lines)
+		r.count--
+		r.dropped++
+	}
+	r.lines[(r.start+r.count)%len(r.lines)] = line
+	r.count++
+}
+
+// Lines returns the queued lines, oldest first, leaving them queued
+func (r *Ring) Lines() []string {
+	out := make([]string, r.count)
+	for i := range out {
+		out[i] = r.lines[(r.start+i)%len(r.lines)]
+	}
+	return out
+}
+
+// Drain removes and returns the queued lines and the number of lines
+// dropped since the last Drain
+func (r *Ring) Drain() (lines []string, dropped int) {
+	lines, dropped = r.Lines(), r.dropped
+	for i := range r.lines {
+		r.lines[i] = ""
+	}
+	r.start, r.count, r.dropped = 0, 0, 0
+	return lines, dropped
+}
+
+// Stream is an io.Writer that passes the lines written to it on to its
+// subscribers
+type Stream struct {
+	mu      sync.Mutex
+	lines   *lines.Splitter
+	recent  *Ring
+	clients map[*Subscription]struct{}
+}
+
+// New creates a stream with no subscribers
+func New() *Stream {
+	s := &Stream{recent: NewRing(Backlog), clients: map[*Subscription]struct{}{}}
+	s.lines = lines.New(s.publish)
+	s.lines.Max = MaxLine
+	return s
+}
+
+// Write implements io.Writer. It never blocks on subscribers.
+func (s *Stream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lines.Write(p)
+}
+
+func (s *Stream) publish(line []byte) {
+	text := string(line)
+	s.recent.Push(text)
+	for c := range s.clients {
+		c.queue.Push(text)
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Recent returns the last Backlog lines
+func (s *Stream) Recent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recent.Lines()
+}
+
+// Subscription receives the lines written to a stream after it was
+// created, starting with the recent backlog
+type Subscription struct {
+	stream *Stream
+	queue  *Ring
+	wake   chan struct{}
+}
+
+// Subscribe starts a subscription; Close it when done
+func (s *Stream) Subscribe() *Subscription {
+	c := &Subscription{stream: s, queue: NewRing(ClientLines), wake: make(chan struct{}, 1)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, line := range s.recent.Lines() {
+		c.queue.Push(line)
+	}
+	s.clients[c] = struct{}{}
+	c.wake <- struct{}{}
+	return c
+}
+
+// Ready is signalled when lines are queued
+func (c *Subscription) Ready() <-chan struct{} {
+	return c.wake
+}
+
+// Next removes and returns the queued lines and how many were dropped
+// because the subscriber fell behind
+func (c *Subscription) Next() (lines []string, dropped int) {
+	c.stream.mu.Lock()
+	defer c.stream.mu.Unlock()
+	return c.queue.Drain()
+}
+
+// Close ends the subscription
+func (c *Subscription) Close() {
+	c.stream.mu.Lock()
+	defer c.stream.mu.Unlock()
+	delete(c.stream.clients, c)
+}
+
+// Handler serves the recent lines as plain text, and with ?follow=1 keeps
+// the response open and streams new lines as they are written
+func (s *Stream) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		follow := r.URL.Query().Get("follow")
+		if follow == "" || follow == "0" || follow == "false" {
+			for _, line := range s.Recent() {
+				fmt.Fprintln(w, line)
+			}
+			return
+		}
+
+		flusher, _ := w.(http.Flusher)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		sub := s.Subscribe()
+		defer sub.Close()
+		for {
+			select {
+			case <-sub.Ready():
+			case <-r.Context().Done():
+				return
+			}
+			lines, dropped := sub.Next()
+			if dropped > 0 {
+				if _, err := fmt.Fprintf(w, "[gswarm: %d lines dropped; this client fell behind]\n", dropped); err != nil {
+					return
+				}
+			}
+			for _, line := range lines {
+				if _, err := fmt.Fprintln(w, line); err != nil {
+					return
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package logstream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	r := NewRing(3)
+	for i := 1; i <= 5; i++ {
+		r.Push(fmt.Sprint(i))
+	}
+	lines, dropped := r.Drain()
+	if strings.Join(lines, ",") != "3,4,5" || dropped != 2 {
+		t.Errorf("Drain() = %v, %d, want [3 4 5], 2", lines, dropped)
+	}
+	if lines, dropped := r.Drain(); len(lines) != 0 || dropped != 0 {
+		t.Errorf("second Drain() = %v, %d", lines, dropped)
+	}
+}
+
+func TestStream_SlowSubscriber(t *testing.T) {
+	s := New()
+	fmt.Fprint(s, "before\n")
+	sub := s.Subscribe()
+	defer sub.Close()
+
+	// nobody reads the subscription; writes must neither block nor grow
+	// its queue past ClientLines
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < ClientLines*3; i++ {
+			fmt.Fprintf(s, "line %d\n", i)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write blocked on a subscriber that isn't reading")
+	}
+
+	lines, dropped := sub.Next()
+	if len(lines) != ClientLines || dropped != ClientLines*2+1 {
+		t.Fatalf("Next() = %d lines, %d dropped", len(lines), dropped)
+	}
+	if want := fmt.Sprintf("line %d", ClientLines*3-1); lines[len(lines)-1] != want {
+		t.Errorf("last line = %q, want %q", lines[len(lines)-1], want)
+	}
+	if got := s.Recent(); len(got) != Backlog {
+		t.Errorf("Recent() has %d lines, want %d", len(got), Backlog)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s := New()
+	fmt.Fprint(s, "one\ntwo\n")
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "one\ntwo\n" {
+		t.Errorf("recent lines = %q", body)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?follow=1", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	fmt.Fprint(s, "three\n")
+	r := bufio.NewReader(resp.Body)
+	for _, want := range []string{"one", "two", "three"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSuffix(line, "\n") != want {
+			t.Errorf("streamed %q, want %q", line, want)
+		}
+	}
+}
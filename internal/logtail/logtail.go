@@ -0,0 +1,390 @@
+// Package logtail finds and tails gswarm's logs for `gswarm logs`: the
+// supervisor log and the per-run trainer logs written with --run-logs,
+// including those since compressed by logarchive.
+package logtail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/logarchive"
+)
+
+// SupervisorLog is the supervisor's log file name in the log directory
+const SupervisorLog = "gensyn_rl_swarm_go.log"
+
+// Latest selects the newest run log
+const Latest = "latest"
+
+// pollInterval is how often Follow checks the file for new output
+var pollInterval = 500 * time.Millisecond
+
+// RunLogs lists the per-run logs in dir, compressed or not, oldest first.
+// Their names embed the start time, so name order is start order.
+func RunLogs(dir string) ([]string, error) {
+	var matches []string
+	for _, pattern := range []string{"run-*.log", "run-*.log" + logarchive.ExtZstd, "run-*.log" + logarchive.ExtGzip} {
+		m, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m...)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// Resolve picks the log to show: the supervisor log for an empty run,
+// the newest run log for Latest, or the run log whose ID (its start time,
+// e.g. 20250102-150405) or file name starts with run
+func Resolve(dir, run string) (string, error) {
+	if run == "" {
+		return filepath.Join(dir, SupervisorLog), nil
+	}
+	logs, err := RunLogs(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(logs) == 0 {
+		return "", fmt.Errorf("no run logs in %s; start gswarm with --run-logs to write them", dir)
+	}
+	if run == Latest {
+		return logs[len(logs)-1], nil
+	}
+
+	var found []string
+	for _, path := range logs {
+		name := filepath.Base(path)
+		if strings.HasPrefix(strings.TrimPrefix(name, "run-"), run) || strings.HasPrefix(name, run) {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no run log matches %q in %s", run, dir)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%q matches %d run logs; give more of the run ID", run, len(found))
+	}
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)`)
+
+// StripANSI removes terminal colours and cursor movement from a line. Of a
+// progress bar redrawn with carriage returns, only the final state is kept.
+func StripANSI(s string) string {
+	s = ansiPattern.ReplaceAllString(s, "")
+	if strings.Contains(s, "\r") {
+		parts := strings.Split(s, "\r")
+		for i := len(parts) - 1; i >= 0; i-- {
+			if strings.TrimSpace(parts[i]) != "" {
+				return parts[i]
+			}
+		}
+		return ""
+	}
+	return s
+}
+
+var errorPattern = regexp.MustCompile(`(?i)\b(error|exception|traceback|fatal|panic|failed|killed)\b`)
+
+// IsError reports whether line looks like part of an error
+func IsError(line string) bool {
+	return errorPattern.MatchString(line)
+}
+
+// Filter decides which lines are shown; nil shows everything
+type Filter func(line string) bool
+
+// Last returns up to n of the final lines of the file at path, and the
+// offset just past them for Follow to continue from. An archive is read
+// decompressed, as is the archive of a log that has been compressed since
+// its path was recorded; there is nothing to follow in those.
+func Last(path string, n int, keep Filter) ([]string, int64, error) {
+	if !logarchive.IsArchive(path) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			for _, ext := range []string{logarchive.ExtZstd, logarchive.ExtGzip} {
+				if _, err := os.Stat(path + ext); err == nil {
+					path += ext
+					break
+				}
+			}
+		}
+	}
+	if logarchive.IsArchive(path) {
+		return lastArchived(path, n, keep)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n <= 0 {
+		return nil, size, nil
+	}
+
+	// Read backwards in chunks until there are enough matching lines. A
+	// partial last line is left for Follow to complete. Each chunk is split
+	// once; the start of a line that began in an earlier chunk is carried
+	// over to it.
+	const chunk = 64 << 10
+	var (
+		// groups holds each chunk's matching lines, newest chunk first
+		groups [][]string
+		count  int
+		carry  []byte
+		found  bool
+		end    int64
+	)
+	for pos := size; pos > 0 && count < n; {
+		step := min(int64(chunk), pos)
+		pos -= step
+		data := make([]byte, step, step+int64(len(carry)))
+		if _, err := f.ReadAt(data, pos); err != nil {
+			return nil, 0, err
+		}
+		data = append(data, carry...)
+
+		if !found {
+			last := bytes.LastIndexByte(data, '\n')
+			if last < 0 {
+				carry = data
+				continue
+			}
+			found = true
+			end = pos + int64(last) + 1
+			data = data[:last]
+		}
+		carry = nil
+		if pos > 0 {
+			// The first line may have started before this chunk
+			first := bytes.IndexByte(data, '\n')
+			if first < 0 {
+				carry = data
+				continue
+			}
+			carry, data = data[:first], data[first+1:]
+		}
+		var kept []string
+		for _, line := range strings.Split(string(data), "\n") {
+			line = StripANSI(line)
+			if keep == nil || keep(line) {
+				kept = append(kept, line)
+			}
+		}
+		groups = append(groups, kept)
+		count += len(kept)
+	}
+
+	lines := make([]string, 0, count)
+	for i := len(groups) - 1; i >= 0; i-- {
+		lines = append(lines, groups[i]...)
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, end, nil
+}
+
+// lastArchived is Last for an archive, which is decompressed to a temp file
+func lastArchived(path string, n int, keep Filter) ([]string, int64, error) {
+	tmp, err := os.CreateTemp("", "gswarm-log-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer os.Remove(tmp.Name())
+	err = logarchive.Decompress(path, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return Last(tmp.Name(), n, keep)
+}
+
+// ReadTail returns up to max bytes from the end of the file at path,
+// starting at a line boundary when the file is longer
+func ReadTail(path string, max int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	start := size - max
+	if start < 0 {
+		start = 0
+	}
+	data := make([]byte, size-start)
+	if _, err := f.ReadAt(data, start); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if start > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return data, nil
+}
+
+// Follow calls emit for each complete line added to the file at path after
+// offset, until ctx is done. A file that is truncated or replaced, as by
+// log rotation, is read again from the start.
+func Follow(ctx context.Context, path string, offset int64, keep Filter, emit func(string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(f)
+	// partial holds a line whose newline hasn't been written yet
+	var partial []byte
+
+	for {
+		for {
+			chunk, err := reader.ReadBytes('\n')
+			partial = append(partial, chunk...)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			offset += int64(len(partial))
+			line := StripANSI(strings.TrimRight(string(partial), "\n"))
+			partial = partial[:0]
+			if keep == nil || keep(line) {
+				emit(line)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+
+		if replacedOrTruncated(f, path, offset+int64(len(partial))) {
+			next, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			f.Close()
+			f, offset, partial = next, 0, partial[:0]
+			reader.Reset(f)
+		}
+	}
+}
+
+// replacedOrTruncated reports whether path is no longer the open file or
+// has shrunk below what was read from it. While a rotated file is being
+// replaced, path may briefly not exist; that is checked again next time.
+func replacedOrTruncated(f *os.File, path string, read int64) bool {
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	open, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return !os.SameFile(current, open) || current.Size() < read
+}
+
+// maxLineLen bounds each line Lines keeps, so a traceback printing a huge
+// tensor can't hold on to megabytes
+const maxLineLen = 1000
+
+// Lines keeps the last lines written to it, without terminal escapes, to
+// show the end of a command's output such as in a crash alert
+type Lines struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+// NewLines keeps up to n lines
+func NewLines(n int) *Lines {
+	return &Lines{max: n}
+}
+
+func (l *Lines) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max <= 0 {
+		return len(p), nil
+	}
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			l.partial = appendCapped(l.partial, data)
+			break
+		}
+		l.partial = appendCapped(l.partial, data[:i])
+		l.add(string(l.partial))
+		l.partial = l.partial[:0]
+		data = data[i+1:]
+	}
+	return len(p), nil
+}
+
+// Last returns the kept lines, oldest first, including a final line that
+// hasn't ended yet
+func (l *Lines) Last() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := append(make([]string, 0, len(l.lines)+1), l.lines...)
+	if line := StripANSI(string(l.partial)); strings.TrimSpace(line) != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) > l.max {
+		lines = lines[len(lines)-l.max:]
+	}
+	return lines
+}
+
+func (l *Lines) add(line string) {
+	line = strings.TrimRight(StripANSI(line), "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	l.lines = append(l.lines, line)
+	if over := len(l.lines) - l.max; over > 0 {
+		l.lines = append(l.lines[:0], l.lines[over:]...)
+	}
+}
+
+func appendCapped(buf, data []byte) []byte {
+	if room := maxLineLen - len(buf); room < len(data) {
+		if room <= 0 {
+			return buf
+		}
+		data = data[:room]
+	}
+	return append(buf, data...)
+}
@@ -0,0 +1,217 @@
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/logarchive"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"run-20250101-090000.log", "run-20250102-150405.log", "run-20250102-180000.log"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0o644)
+	}
+
+	cases := []struct {
+		run     string
+		want    string
+		wantErr bool
+	}{
+		{"", SupervisorLog, false},
+		{Latest, "run-20250102-180000.log", false},
+		{"20250101", "run-20250101-090000.log", false},
+		{"run-20250102-15", "run-20250102-150405.log", false},
+		{"20250102", "", true},
+		{"2024", "", true},
+	}
+	for _, c := range cases {
+		got, err := Resolve(dir, c.run)
+		if (err != nil) != c.wantErr {
+			t.Errorf("Resolve(%q) error = %v, wantErr %v", c.run, err, c.wantErr)
+			continue
+		}
+		if err == nil && filepath.Base(got) != c.want {
+			t.Errorf("Resolve(%q) = %s, want %s", c.run, filepath.Base(got), c.want)
+		}
+	}
+
+	// Compressed run logs are found too
+	os.WriteFile(filepath.Join(dir, "run-20250103-080000.log.gz"), nil, 0o644)
+	if got, err := Resolve(dir, "20250103"); err != nil || filepath.Base(got) != "run-20250103-080000.log.gz" {
+		t.Errorf("Resolve() of an archived run = %s, %v", got, err)
+	}
+
+	if _, err := Resolve(t.TempDir(), Latest); err == nil || !strings.Contains(err.Error(), "--run-logs") {
+		t.Errorf("Resolve() without run logs error = %v, want a --run-logs hint", err)
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	cases := map[string]string{
+		"\x1b[32mINFO\x1b[0m started":               "INFO started",
+		" 10%|#  |\r 50%|##  |\r100%|####|":         "100%|####|",
+		"\x1b]0;title\x07plain":                     "plain",
+		"no escapes":                                "no escapes",
+		"\x1b[2K\x1b[1Gstep 3\r":                    "step 3",
+		"Traceback (most recent call last):\x1b[0m": "Traceback (most recent call last):",
+	}
+	for in, want := range cases {
+		if got := StripANSI(in); got != want {
+			t.Errorf("StripANSI(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLast(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.log")
+	var b strings.Builder
+	for i := 1; i <= 5000; i++ {
+		if i%1000 == 0 {
+			fmt.Fprintf(&b, "line %d ERROR boom\n", i)
+		} else {
+			fmt.Fprintf(&b, "line %d %s\n", i, strings.Repeat("x", 40))
+		}
+	}
+	b.WriteString("partial")
+	os.WriteFile(path, []byte(b.String()), 0o644)
+
+	lines, offset, err := Last(path, 2, nil)
+	if err != nil {
+		t.Fatalf("Last() error = %v", err)
+	}
+	want := []string{"line 4999 " + strings.Repeat("x", 40), "line 5000 ERROR boom"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Last() = %q, want %q", lines, want)
+	}
+	if offset != int64(b.Len()-len("partial")) {
+		t.Errorf("Last() offset = %d, want the start of the partial line %d", offset, b.Len()-len("partial"))
+	}
+
+	// Filtered lines are searched for across chunks
+	lines, _, err = Last(path, 3, IsError)
+	if err != nil {
+		t.Fatalf("Last(IsError) error = %v", err)
+	}
+	if want := []string{"line 3000 ERROR boom", "line 4000 ERROR boom", "line 5000 ERROR boom"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("Last(IsError) = %q, want %q", lines, want)
+	}
+
+	// Lines cut by chunk boundaries come out whole
+	all, _, err := Last(path, 10000, nil)
+	if err != nil {
+		t.Fatalf("Last(all) error = %v", err)
+	}
+	if len(all) != 5000 || all[0] != "line 1 "+strings.Repeat("x", 40) || all[2999] != "line 3000 ERROR boom" {
+		t.Errorf("Last(all) = %d lines, first %q", len(all), all[0])
+	}
+	for i, line := range all {
+		if !strings.HasPrefix(line, fmt.Sprintf("line %d ", i+1)) {
+			t.Fatalf("line %d = %q", i+1, line)
+		}
+	}
+
+	// A line longer than a chunk
+	long := filepath.Join(t.TempDir(), "long.log")
+	huge := strings.Repeat("y", 200<<10)
+	os.WriteFile(long, []byte("first\n"+huge+"\nlast\n"), 0o644)
+	if lines, _, _ := Last(long, 3, nil); !reflect.DeepEqual(lines, []string{"first", huge, "last"}) {
+		t.Errorf("Last(long) = %d lines", len(lines))
+	}
+
+	short := filepath.Join(t.TempDir(), "short.log")
+	os.WriteFile(short, []byte("a\nb\n"), 0o644)
+	if lines, _, _ := Last(short, 10, nil); !reflect.DeepEqual(lines, []string{"a", "b"}) {
+		t.Errorf("Last(short) = %q", lines)
+	}
+}
+
+func TestLast_Archive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run-20250102-150405.log")
+	os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0o644)
+	archive, err := logarchive.Compress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// By the archive's name, or by the name recorded before compression
+	for _, p := range []string{archive, path} {
+		lines, _, err := Last(p, 2, nil)
+		if err != nil || !reflect.DeepEqual(lines, []string{"two", "three"}) {
+			t.Errorf("Last(%s) = %q, %v", filepath.Base(p), lines, err)
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+	pollInterval = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "x.log")
+	os.WriteFile(path, []byte("old\n"), 0o644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() { done <- Follow(ctx, path, 4, nil, func(line string) { got <- line }) }()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case line := <-got:
+			if line != want {
+				t.Errorf("Follow() emitted %q, want %q", line, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Follow() didn't emit %q", want)
+		}
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString("first ")
+	time.Sleep(30 * time.Millisecond)
+	f.WriteString("line\n")
+	f.Close()
+	expect("first line")
+
+	// Rotation replaces the file
+	os.Rename(path, path+".1")
+	os.WriteFile(path, []byte("rotated\n"), 0o644)
+	expect("rotated")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Follow() error = %v", err)
+	}
+}
+
+func TestLines(t *testing.T) {
+	l := NewLines(3)
+	l.Write([]byte("one\ntwo\n\x1b[31mthree\x1b[0m\n"))
+	l.Write([]byte("\nfour\nfi"))
+	l.Write([]byte("ve"))
+	want := []string{"three", "four", "five"}
+	if got := l.Last(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Last() = %q, want %q", got, want)
+	}
+
+	l.Write([]byte("\n" + strings.Repeat("x", 5000) + "\n"))
+	if got := l.Last(); len(got) != 3 || len(got[2]) != maxLineLen {
+		t.Errorf("long line kept as %d bytes, want %d", len(got[len(got)-1]), maxLineLen)
+	}
+}
+
+func TestReadTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	os.WriteFile(path, []byte("first line\nsecond\nthird\n"), 0o644)
+	got, err := ReadTail(path, 12)
+	if err != nil || string(got) != "third\n" {
+		t.Errorf("ReadTail(12) = %q, %v; want the last whole line", got, err)
+	}
+	if got, _ := ReadTail(path, 1000); len(got) != 24 {
+		t.Errorf("ReadTail(1000) = %q, want the whole file", got)
+	}
+}
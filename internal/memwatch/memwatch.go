@@ -0,0 +1,415 @@
+// Package memwatch samples the trainer's RAM and the GPU's memory, and
+// warns when either is heading for its limit, so a smaller model can be
+// chosen before the run dies of an out-of-memory error.
+package memwatch
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommandRunner is a package-level variable that can be replaced in tests
+var CommandRunner = exec.Command
+
+const (
+	// DefaultInterval is how often the trainer's memory is sampled
+	DefaultInterval = time.Minute
+	// DefaultHorizon is how far ahead a limit is predicted to be reached
+	// for a warning
+	DefaultHorizon = 30 * time.Minute
+	// DefaultThreshold is the share of a limit that warns whatever the trend
+	DefaultThreshold = 0.9
+
+	// window is how far back the trend looks
+	window = 20 * time.Minute
+	// minPoints is how many samples a trend needs before it predicts
+	minPoints = 5
+)
+
+// procRoot is where process information is read, replaced in tests
+var procRoot = "/proc"
+
+// cgroupRoot holds the supervisor's cgroup v2 files, replaced in tests
+var cgroupRoot = "/sys/fs/cgroup"
+
+// Usage is one sample of the trainer's memory. Fields that couldn't be
+// read are zero: RSS and RAMLimit need Linux, VRAM needs nvidia-smi.
+type Usage struct {
+	// RSS is the resident memory of the trainer and its child processes
+	RSS uint64 `json:"rss_bytes,omitempty"`
+	// RAMLimit is the cgroup's memory limit, or the machine's memory
+	RAMLimit uint64 `json:"ram_limit_bytes,omitempty"`
+	// VRAMUsed is the trainer's memory on the GPU it fills the most, and
+	// VRAMTotal that GPU's memory
+	VRAMUsed  uint64    `json:"vram_used_bytes,omitempty"`
+	VRAMTotal uint64    `json:"vram_total_bytes,omitempty"`
+	Sampled   time.Time `json:"sampled"`
+}
+
+// String renders the usage, e.g. "RAM 11.2 GiB of 15.5 GiB, VRAM 21.9 GiB
+// of 24.0 GiB"
+func (u Usage) String() string {
+	var parts []string
+	if u.RSS > 0 {
+		parts = append(parts, "RAM "+of(u.RSS, u.RAMLimit))
+	}
+	if u.VRAMTotal > 0 {
+		parts = append(parts, "VRAM "+of(u.VRAMUsed, u.VRAMTotal))
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func of(used, limit uint64) string {
+	if limit == 0 {
+		return GiB(used)
+	}
+	return GiB(used) + " of " + GiB(limit)
+}
+
+// GiB formats a byte count in GiB with one decimal
+func GiB(n uint64) string {
+	return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+}
+
+// Sample reads the memory of pid's process tree and of the GPUs given by
+// index; no GPUs means all of them
+func Sample(pid int, gpus []int) Usage {
+	u := Usage{Sampled: time.Now()}
+	pids := treePIDs(pid)
+	u.RSS = treeRSS(pids)
+	u.RAMLimit = ramLimit()
+	u.VRAMUsed, u.VRAMTotal = vram(pids, gpus)
+	return u
+}
+
+// treeRSS adds up the resident memory of pids
+func treeRSS(pids []int) uint64 {
+	var total uint64
+	for _, p := range pids {
+		total += rss(p)
+	}
+	return total
+}
+
+// treePIDs returns pid and its descendants, which include data loader
+// workers. Without /proc it returns pid alone.
+func treePIDs(pid int) []int {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return []int{pid}
+	}
+	children := map[int][]int{}
+	for _, e := range entries {
+		child, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if parent := ppid(child); parent > 0 {
+			children[parent] = append(children[parent], child)
+		}
+	}
+	var pids []int
+	queue := []int{pid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		pids = append(pids, p)
+		queue = append(queue, children[p]...)
+	}
+	return pids
+}
+
+// ppid reads a process's parent from /proc/<pid>/stat. The command name
+// in parentheses may contain spaces, so fields are counted after it.
+func ppid(pid int) int {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0
+	}
+	s := string(data)
+	fields := strings.Fields(s[strings.LastIndexByte(s, ')')+1:])
+	if len(fields) < 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(fields[1])
+	return n
+}
+
+// rss reads VmRSS from /proc/<pid>/status
+func rss(pid int) uint64 {
+	kb, _ := field(filepath.Join(procRoot, strconv.Itoa(pid), "status"), "VmRSS:")
+	return kb * 1024
+}
+
+// field reads the number after name in a /proc key: value file
+func field(path, name string) (uint64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rest, ok := strings.CutPrefix(scanner.Text(), name); ok {
+			if fields := strings.Fields(rest); len(fields) > 0 {
+				n, err := strconv.ParseUint(fields[0], 10, 64)
+				return n, err == nil
+			}
+		}
+	}
+	return 0, false
+}
+
+// ramLimit returns the cgroup v2 memory limit when one is set, as in
+// containers, and the machine's memory otherwise
+func ramLimit() uint64 {
+	if data, err := os.ReadFile(filepath.Join(cgroupRoot, "memory.max")); err == nil {
+		if n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			return n
+		}
+	}
+	kb, _ := field(filepath.Join(procRoot, "meminfo"), "MemTotal:")
+	return kb * 1024
+}
+
+// vram returns the memory the processes in pids use on the GPU in gpus
+// they fill the most, and that GPU's memory, so other users of a shared
+// GPU don't count. When nvidia-smi lists no processes at all, as in
+// containers that can't see them, the GPU's whole use counts instead.
+func vram(pids, gpus []int) (used, total uint64) {
+	out, err := CommandRunner("nvidia-smi", "--query-gpu=index,uuid,memory.used,memory.total", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return 0, 0
+	}
+	apps, _ := CommandRunner("nvidia-smi", "--query-compute-apps=pid,gpu_uuid,used_memory", "--format=csv,noheader,nounits").Output()
+	return parseVRAM(string(out), string(apps), pids, gpus)
+}
+
+// parseVRAM picks the GPU from nvidia-smi's --query-gpu output, and the
+// trainer's use of it from its --query-compute-apps output
+func parseVRAM(gpuOut, appOut string, pids, gpus []int) (used, total uint64) {
+	const mib = 1 << 20
+	mine := map[int]bool{}
+	for _, p := range pids {
+		mine[p] = true
+	}
+	listed := false
+	trainer := map[string]uint64{}
+	for _, fields := range csvLines(appOut, 3) {
+		pid, err1 := strconv.Atoi(fields[0])
+		u, err2 := strconv.ParseUint(fields[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		listed = true
+		if mine[pid] {
+			trainer[fields[1]] += u
+		}
+	}
+
+	fullest := -1.0
+	for _, fields := range csvLines(gpuOut, 4) {
+		index, err1 := strconv.Atoi(fields[0])
+		u, err2 := strconv.ParseUint(fields[2], 10, 64)
+		t, err3 := strconv.ParseUint(fields[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || t == 0 || !selected(index, gpus) {
+			continue
+		}
+		if listed {
+			u = trainer[fields[1]]
+		}
+		if share := float64(u) / float64(t); share > fullest {
+			fullest, used, total = share, u*mib, t*mib
+		}
+	}
+	return used, total
+}
+
+// csvLines splits nvidia-smi's CSV output into lines of n trimmed fields,
+// skipping the others
+func csvLines(out string, n int) [][]string {
+	var lines [][]string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != n {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func selected(index int, gpus []int) bool {
+	if len(gpus) == 0 {
+		return true
+	}
+	for _, g := range gpus {
+		if g == index {
+			return true
+		}
+	}
+	return false
+}
+
+// GPUIndexes parses a CUDA_VISIBLE_DEVICES list of indexes; GPUs given by
+// UUID can't be matched to nvidia-smi's indexes, so they select all GPUs
+func GPUIndexes(devices string) []int {
+	var gpus []int
+	for _, d := range strings.Split(devices, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(d))
+		if err != nil {
+			return nil
+		}
+		gpus = append(gpus, n)
+	}
+	return gpus
+}
+
+// Trend fits a line to recent samples of a resource's use
+type Trend struct {
+	points []point
+}
+
+type point struct {
+	at   time.Time
+	used float64
+}
+
+// Add records a sample, forgetting those older than the trend's window
+func (t *Trend) Add(at time.Time, used uint64) {
+	t.points = append(t.points, point{at, float64(used)})
+	for len(t.points) > 0 && at.Sub(t.points[0].at) > window {
+		t.points = t.points[1:]
+	}
+}
+
+// ETA returns how long until use reaches limit at its current rate of
+// growth. ok is false until there are enough samples, and while use isn't
+// growing.
+func (t *Trend) ETA(limit uint64) (eta time.Duration, ok bool) {
+	if len(t.points) < minPoints || limit == 0 {
+		return 0, false
+	}
+	// least squares slope, in bytes per second
+	origin := t.points[0].at
+	var sx, sy, sxx, sxy float64
+	for _, p := range t.points {
+		x := p.at.Sub(origin).Seconds()
+		sx += x
+		sy += p.used
+		sxx += x * x
+		sxy += x * p.used
+	}
+	n := float64(len(t.points))
+	denom := n*sxx - sx*sx
+	if denom == 0 {
+		return 0, false
+	}
+	slope := (n*sxy - sx*sy) / denom
+	last := t.points[len(t.points)-1].used
+	if slope <= 0 || last >= float64(limit) {
+		return 0, slope > 0
+	}
+	seconds := (float64(limit) - last) / slope
+	if seconds > math.MaxInt64/float64(time.Second) {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// Warning is a resource heading for its limit
+type Warning struct {
+	// Resource is "RAM" or "VRAM"
+	Resource    string
+	Used, Limit uint64
+	// ETA is when the limit is expected to be reached; zero when use is
+	// already past the threshold
+	ETA time.Duration
+}
+
+// String describes the warning
+func (w Warning) String() string {
+	percent := float64(w.Used) * 100 / float64(w.Limit)
+	if w.ETA == 0 {
+		return fmt.Sprintf("%s use is at %.0f%% (%s)", w.Resource, percent, of(w.Used, w.Limit))
+	}
+	return fmt.Sprintf("%s use is at %.0f%% (%s) and growing; at this rate it runs out in about %s",
+		w.Resource, percent, of(w.Used, w.Limit), w.ETA.Round(time.Minute))
+}
+
+// Watcher follows one run's samples and warns once per resource
+type Watcher struct {
+	// Horizon and Threshold set when to warn: the limit is predicted
+	// within Horizon, or use is above Threshold of the limit
+	Horizon   time.Duration
+	Threshold float64
+
+	ram, vram Trend
+	warned    map[string]bool
+	peak      Usage
+}
+
+// NewWatcher returns a watcher with the default horizon and threshold
+func NewWatcher() *Watcher {
+	return &Watcher{Horizon: DefaultHorizon, Threshold: DefaultThreshold}
+}
+
+// Check records u and returns warnings for resources newly heading for
+// their limit
+func (w *Watcher) Check(u Usage) []Warning {
+	if w.warned == nil {
+		w.warned = map[string]bool{}
+	}
+	if u.RSS > w.peak.RSS {
+		w.peak.RSS, w.peak.RAMLimit = u.RSS, u.RAMLimit
+	}
+	if u.VRAMUsed > w.peak.VRAMUsed {
+		w.peak.VRAMUsed, w.peak.VRAMTotal = u.VRAMUsed, u.VRAMTotal
+	}
+	w.peak.Sampled = u.Sampled
+
+	var warnings []Warning
+	for _, r := range []struct {
+		name        string
+		trend       *Trend
+		used, limit uint64
+	}{
+		{"RAM", &w.ram, u.RSS, u.RAMLimit},
+		{"VRAM", &w.vram, u.VRAMUsed, u.VRAMTotal},
+	} {
+		if r.limit == 0 {
+			continue
+		}
+		r.trend.Add(u.Sampled, r.used)
+		if w.warned[r.name] {
+			continue
+		}
+		if float64(r.used) >= w.Threshold*float64(r.limit) {
+			warnings = append(warnings, Warning{Resource: r.name, Used: r.used, Limit: r.limit})
+		} else if eta, ok := r.trend.ETA(r.limit); ok && eta > 0 && eta <= w.Horizon {
+			warnings = append(warnings, Warning{Resource: r.name, Used: r.used, Limit: r.limit, ETA: eta})
+		} else {
+			continue
+		}
+		w.warned[r.name] = true
+	}
+	return warnings
+}
+
+// Peak returns the highest use seen so far
+func (w *Watcher) Peak() Usage {
+	return w.peak
+}
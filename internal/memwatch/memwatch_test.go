@@ -0,0 +1,117 @@
+package memwatch
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTreeRSS(t *testing.T) {
+	procRoot = t.TempDir()
+	cgroupRoot = t.TempDir()
+	defer func() { procRoot, cgroupRoot = "/proc", "/sys/fs/cgroup" }()
+	proc := func(pid, parent int, rssKB int) {
+		dir := filepath.Join(procRoot, strconv.Itoa(pid))
+		os.MkdirAll(dir, 0o755)
+		os.WriteFile(filepath.Join(dir, "stat"), []byte(strconv.Itoa(pid)+" (pt main) S "+strconv.Itoa(parent)+" 1 1\n"), 0o644)
+		os.WriteFile(filepath.Join(dir, "status"), []byte("Name:\tpython\nVmRSS:\t  "+strconv.Itoa(rssKB)+" kB\n"), 0o644)
+	}
+	proc(100, 1, 1000)
+	proc(101, 100, 200) // data loader worker
+	proc(102, 101, 30)
+	proc(200, 1, 5000) // unrelated
+	os.WriteFile(filepath.Join(procRoot, "meminfo"), []byte("MemTotal:       16000 kB\n"), 0o644)
+
+	if got := treeRSS(treePIDs(100)); got != 1230*1024 {
+		t.Errorf("treeRSS() = %d, want the trainer and its workers, %d", got, 1230*1024)
+	}
+	if got := ramLimit(); got != 16000*1024 {
+		t.Errorf("ramLimit() = %d, want MemTotal", got)
+	}
+	os.WriteFile(filepath.Join(cgroupRoot, "memory.max"), []byte("8192000\n"), 0o644)
+	if got := ramLimit(); got != 8192000 {
+		t.Errorf("ramLimit() = %d, want the cgroup limit", got)
+	}
+	os.WriteFile(filepath.Join(cgroupRoot, "memory.max"), []byte("max\n"), 0o644)
+	if got := ramLimit(); got != 16000*1024 {
+		t.Errorf("ramLimit() with no cgroup limit = %d, want MemTotal", got)
+	}
+}
+
+func TestParseVRAM(t *testing.T) {
+	out := "0, GPU-a, 1000, 24000\n1, GPU-b, 20000, 24000\n"
+	// nvidia-smi lists no processes, as in a container
+	if used, total := parseVRAM(out, "", []int{100}, nil); used != 20000<<20 || total != 24000<<20 {
+		t.Errorf("all GPUs: %d of %d, want the fullest", used, total)
+	}
+	if used, _ := parseVRAM(out, "", []int{100}, []int{0}); used != 1000<<20 {
+		t.Errorf("GPU 0: used %d", used)
+	}
+
+	// another user fills GPU 1; only the trainer's tree counts
+	apps := "100, GPU-a, 600\n101, GPU-a, 300\n555, GPU-b, 20000\n"
+	if used, total := parseVRAM(out, apps, []int{100, 101}, nil); used != 900<<20 || total != 24000<<20 {
+		t.Errorf("shared GPUs: %d of %d, want the trainer's 900 MiB on GPU 0", used, total)
+	}
+	if used, total := parseVRAM(out, apps, []int{100, 101}, []int{1}); used != 0 || total != 24000<<20 {
+		t.Errorf("GPU 1: %d of %d, want none of it the trainer's", used, total)
+	}
+	if got := GPUIndexes("0, 2"); len(got) != 2 || got[1] != 2 {
+		t.Errorf("GPUIndexes() = %v", got)
+	}
+	if got := GPUIndexes("GPU-8f0e"); got != nil {
+		t.Errorf("GPUIndexes(uuid) = %v, want all GPUs", got)
+	}
+}
+
+func TestTrend_ETA(t *testing.T) {
+	var tr Trend
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		tr.Add(start.Add(time.Duration(i)*time.Minute), uint64(100+10*i))
+	}
+	if _, ok := tr.ETA(1000); ok {
+		t.Error("ETA with too few samples")
+	}
+	tr.Add(start.Add(4*time.Minute), 140)
+	// 10 per minute from 140 to 1000
+	if eta, ok := tr.ETA(1000); !ok || eta != 86*time.Minute {
+		t.Errorf("ETA() = %s, %t, want 86m", eta, ok)
+	}
+
+	var flat Trend
+	for i := 0; i < 10; i++ {
+		flat.Add(start.Add(time.Duration(i)*time.Minute), 500)
+	}
+	if _, ok := flat.ETA(1000); ok {
+		t.Error("ETA for flat use")
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	w := NewWatcher()
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	const gib = 1 << 30
+	var got []Warning
+	for i := 0; i < 10; i++ {
+		// VRAM grows 0.5 GiB a minute towards 24 GiB; RAM is steady
+		u := Usage{RSS: 4 * gib, RAMLimit: 16 * gib, VRAMUsed: uint64(8*gib + i*gib/2), VRAMTotal: 24 * gib,
+			Sampled: start.Add(time.Duration(i) * time.Minute)}
+		got = append(got, w.Check(u)...)
+	}
+	// from the fifth sample, 10 GiB with 14 GiB to go is 28 minutes away
+	if len(got) != 1 || got[0].Resource != "VRAM" || got[0].ETA == 0 || got[0].ETA > DefaultHorizon {
+		t.Fatalf("warnings = %+v, want one VRAM prediction", got)
+	}
+	if w.Check(Usage{RSS: 15 * gib, RAMLimit: 16 * gib, Sampled: start.Add(10 * time.Minute)}) == nil {
+		t.Error("no warning for RAM past the threshold")
+	}
+	if peak := w.Peak(); peak.RSS != 15*gib || peak.VRAMUsed != 8*gib+9*gib/2 {
+		t.Errorf("Peak() = %+v", peak)
+	}
+	if s := w.Peak().String(); s != "RAM 15.0 GiB of 16.0 GiB, VRAM 12.5 GiB of 24.0 GiB" {
+		t.Errorf("String() = %q", s)
+	}
+}
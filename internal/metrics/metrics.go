@@ -0,0 +1,91 @@
+// Package metrics writes gauges in the Prometheus text exposition format,
+// so alerting rules such as "no reward growth in 6h" can live in
+// Prometheus and Alertmanager rather than in gswarm's notifiers.
+//
+// Only gauges are needed, so there is no registry or client library: the
+// status API builds the gauges from the state files on each scrape.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Gauge is one metric family and its samples
+type Gauge struct {
+	Name    string
+	Help    string
+	samples []sample
+}
+
+type sample struct {
+	labels string
+	value  string
+}
+
+// NewGauge returns an empty gauge
+func NewGauge(name, help string) *Gauge {
+	return &Gauge{Name: name, Help: help}
+}
+
+// Set adds a sample with the labels, given as name, value pairs
+func (g *Gauge) Set(value float64, labels ...string) {
+	g.add(strconv.FormatFloat(value, 'g', -1, 64), labels)
+}
+
+// SetInt adds a sample with an integer value, written in full so token
+// amounts in wei keep every digit. A nil value adds nothing.
+func (g *Gauge) SetInt(value *big.Int, labels ...string) {
+	if value == nil {
+		return
+	}
+	g.add(value.String(), labels)
+}
+
+func (g *Gauge) add(value string, labels []string) {
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf("metrics: %s: odd number of label arguments", g.Name))
+	}
+	var b strings.Builder
+	for i := 0; i < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, labels[i], escape(labels[i+1]))
+	}
+	g.samples = append(g.samples, sample{labels: b.String(), value: value})
+}
+
+// escape escapes a label value as the format requires
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// Write writes the gauges that have samples, each sorted by its labels
+func Write(w io.Writer, gauges ...*Gauge) error {
+	var b strings.Builder
+	for _, g := range gauges {
+		if len(g.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", g.Name, strings.ReplaceAll(g.Help, "\n", " "), g.Name)
+		samples := append([]sample(nil), g.samples...)
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+		for _, s := range samples {
+			if s.labels == "" {
+				fmt.Fprintf(&b, "%s %s\n", g.Name, s.value)
+			} else {
+				fmt.Fprintf(&b, "%s{%s} %s\n", g.Name, s.labels, s.value)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
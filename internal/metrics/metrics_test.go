@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	rewards := NewGauge("gswarm_peer_rewards", "Rewards of the peer")
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	rewards.SetInt(huge, "peer_id", "QmB", "eoa", "0xabc")
+	rewards.SetInt(big.NewInt(5), "peer_id", "QmA", "eoa", "0xabc")
+	rewards.SetInt(nil, "peer_id", "QmC", "eoa", "0xabc")
+	info := NewGauge("gswarm_build_info", "Versions")
+	info.Set(1, "version", `1.0 "dev"`+"\n")
+	empty := NewGauge("gswarm_unused", "Nothing recorded")
+	updated := NewGauge("gswarm_updated_timestamp_seconds", "Last check")
+	updated.Set(1.7e9)
+
+	var b strings.Builder
+	if err := Write(&b, rewards, info, empty, updated); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP gswarm_peer_rewards Rewards of the peer
+# TYPE gswarm_peer_rewards gauge
+gswarm_peer_rewards{peer_id="QmA",eoa="0xabc"} 5
+gswarm_peer_rewards{peer_id="QmB",eoa="0xabc"} 123456789012345678901234567890
+# HELP gswarm_build_info Versions
+# TYPE gswarm_build_info gauge
+gswarm_build_info{version="1.0 \"dev\"\n"} 1
+# HELP gswarm_updated_timestamp_seconds Last check
+# TYPE gswarm_updated_timestamp_seconds gauge
+gswarm_updated_timestamp_seconds 1.7e+09
+`
+	if got := b.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
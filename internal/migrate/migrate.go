@@ -0,0 +1,354 @@
+// Package migrate converts the state files older gswarm releases left in
+// the working directory into the current layout: the monitor's totals and
+// rewards history move into the state directory, telegram-config.json is
+// rewritten with its canonical keys and the EOA moves into the gswarm
+// config file.
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/atomicfile"
+	"github.com/Deep-Commit/gswarm/internal/telegram"
+)
+
+// Options locate the legacy files and the new layout. Relative paths are
+// taken relative to Dir.
+type Options struct {
+	// Dir is the working directory the legacy files were written to
+	Dir            string
+	StateDir       string
+	TelegramConfig string
+	ConfigFile     string
+	LogDir         string
+	// DryRun reports the steps without changing anything
+	DryRun bool
+}
+
+// telegramKeys maps the canonical telegram-config.json keys to the
+// variants seen in hand-written and older configs
+var telegramKeys = map[string][]string{
+	"bot_token":    {"bot_token", "botToken", "BotToken", "token"},
+	"chat_id":      {"chat_id", "chatID", "chatId", "ChatID"},
+	"welcome_sent": {"welcome_sent", "welcomeSent", "WelcomeSent"},
+	"proxy":        {"proxy", "Proxy"},
+	"eoa":          {"eoa", "eoaAddress", "eoa_address", "EOAAddress", "userEOAAddress"},
+}
+
+// Run migrates the legacy files it finds and returns a description of each
+// step taken, or that would be taken in a dry run
+func Run(opts Options) ([]string, error) {
+	var steps []string
+	step := func(format string, args ...interface{}) {
+		steps = append(steps, fmt.Sprintf(format, args...))
+	}
+
+	eoa, err := migrateTelegramConfig(opts, step)
+	if err != nil {
+		return steps, err
+	}
+	if eoa != "" {
+		if err := moveEOA(opts, eoa, step); err != nil {
+			return steps, err
+		}
+	}
+	if err := migratePreviousData(opts, step); err != nil {
+		return steps, err
+	}
+	if err := moveFile(opts, telegram.RewardsHistoryPath, filepath.Join(opts.StateDir, telegram.RewardsHistoryPath), step); err != nil {
+		return steps, err
+	}
+	if err := moveLogs(opts, step); err != nil {
+		return steps, err
+	}
+	return steps, nil
+}
+
+// Pending lists the legacy files in the working directory that Run would
+// move into the state directory or rewrite
+func Pending(opts Options) []string {
+	var pending []string
+	if data, err := os.ReadFile(opts.path(opts.TelegramConfig)); err == nil {
+		if raw, err := decode(data); err == nil {
+			if _, canonical := telegramValues(raw); !canonical {
+				pending = append(pending, opts.TelegramConfig)
+			}
+		}
+	}
+	for _, name := range []string{telegram.PreviousDataPath, telegram.RewardsHistoryPath} {
+		if exists(opts.path(name)) && !exists(opts.path(filepath.Join(opts.StateDir, name))) {
+			pending = append(pending, name)
+		}
+	}
+	return pending
+}
+
+func (o Options) path(p string) string {
+	if filepath.IsAbs(p) || o.Dir == "" {
+		return p
+	}
+	return filepath.Join(o.Dir, p)
+}
+
+// migrateTelegramConfig rewrites telegram-config.json with canonical keys,
+// keeping a .bak copy, and returns any EOA it held
+func migrateTelegramConfig(opts Options, step func(string, ...interface{})) (string, error) {
+	path := opts.path(opts.TelegramConfig)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	raw, err := decode(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	values, canonical := telegramValues(raw)
+	if canonical {
+		return "", nil
+	}
+	unknown := make([]string, 0, len(raw))
+	for key := range raw {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		step("dropping unknown key %q from %s", key, opts.TelegramConfig)
+	}
+
+	cfg := telegram.TelegramConfig{
+		BotToken:    stringValue(values["bot_token"]),
+		ChatID:      stringValue(values["chat_id"]),
+		WelcomeSent: boolValue(values["welcome_sent"]),
+		Proxy:       stringValue(values["proxy"]),
+	}
+	step("rewriting %s with canonical keys (backup in %s.bak)", opts.TelegramConfig, opts.TelegramConfig)
+	if !opts.DryRun {
+		if err := os.WriteFile(path+".bak", data, 0o600); err != nil {
+			return "", err
+		}
+		if err := atomicfile.WriteJSON(path, cfg, 0o600); err != nil {
+			return "", err
+		}
+	}
+	return stringValue(values["eoa"]), nil
+}
+
+// telegramValues takes the known keys out of a decoded telegram config,
+// returning their values by canonical key and whether the config already
+// uses the canonical keys only. The keys left in raw are unknown.
+func telegramValues(raw map[string]interface{}) (map[string]interface{}, bool) {
+	values := map[string]interface{}{}
+	canonical := true
+	for key, variants := range telegramKeys {
+		for _, v := range variants {
+			if value, ok := raw[v]; ok {
+				values[key] = value
+				if v != key || key == "eoa" {
+					canonical = false
+				}
+				delete(raw, v)
+				break
+			}
+		}
+	}
+	return values, canonical
+}
+
+// moveEOA records the EOA in the gswarm config file unless one is set. It
+// is added after the other settings, which keep their order and layout.
+func moveEOA(opts Options, eoa string, step func(string, ...interface{})) error {
+	path := opts.path(opts.ConfigFile)
+	raw := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if _, ok := raw["eoa"]; ok {
+		step("keeping the eoa already in %s", opts.ConfigFile)
+		return nil
+	}
+
+	step("moving the EOA %s to \"eoa\" in %s", eoa, opts.ConfigFile)
+	if opts.DryRun {
+		return nil
+	}
+	return atomicfile.WriteFile(path, appendKey(data, "eoa", eoa), 0o600)
+}
+
+// appendKey adds key to the end of a JSON object, or starts a new object
+// when data is empty. data must hold a valid object.
+func appendKey(data []byte, key, value string) []byte {
+	k, _ := json.Marshal(key)
+	v, _ := json.Marshal(value)
+	entry := fmt.Sprintf("  %s: %s\n}\n", k, v)
+	object := bytes.TrimRight(data, " \t\r\n")
+	if len(object) == 0 {
+		return []byte("{\n" + entry)
+	}
+	body := bytes.TrimRight(object[:len(object)-1], " \t\r\n")
+	sep := ",\n"
+	if body[len(body)-1] == '{' {
+		sep = "\n"
+	}
+	return append(append(body, sep...), entry...)
+}
+
+// migratePreviousData moves the monitor's totals into the state directory,
+// converting numeric values and alternative key names to the current format
+func migratePreviousData(opts Options, step func(string, ...interface{})) error {
+	src := opts.path(telegram.PreviousDataPath)
+	dst := opts.path(filepath.Join(opts.StateDir, telegram.PreviousDataPath))
+	data, err := os.ReadFile(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if exists(dst) {
+		step("keeping %s; %s already exists", telegram.PreviousDataPath, dst)
+		return nil
+	}
+
+	raw, err := decode(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", src, err)
+	}
+	prev := &telegram.PreviousData{
+		Votes:     bigValue(first(raw, "votes", "Votes", "total_votes")),
+		Rewards:   bigValue(first(raw, "rewards", "Rewards", "total_rewards")),
+		LastCheck: timeValue(first(raw, "last_check", "lastCheck", "LastCheck", "last_checked")),
+	}
+
+	step("moving %s to %s", telegram.PreviousDataPath, dst)
+	if opts.DryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := telegram.WritePreviousData(dst, prev); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// moveFile moves name from the working directory to dst unchanged
+func moveFile(opts Options, name, dst string, step func(string, ...interface{})) error {
+	src := opts.path(name)
+	dst = opts.path(dst)
+	if !exists(src) {
+		return nil
+	}
+	if exists(dst) {
+		step("keeping %s; %s already exists", name, dst)
+		return nil
+	}
+	step("moving %s to %s", name, dst)
+	if opts.DryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// moveLogs moves *.log files written to the working directory by older
+// releases into the log directory
+func moveLogs(opts Options, step func(string, ...interface{})) error {
+	matches, err := filepath.Glob(opts.path("*.log"))
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		name := filepath.Base(m)
+		if err := moveFile(opts, name, filepath.Join(opts.LogDir, name), step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode parses a JSON object, keeping numbers exact so large chat IDs
+// and wei amounts survive
+func decode(data []byte) (map[string]interface{}, error) {
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&raw)
+	return raw, err
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func first(raw map[string]interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		if v, ok := raw[k]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// stringValue accepts strings and numbers, since chat IDs are often
+// written unquoted
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+func boolValue(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+func bigValue(v interface{}) *big.Int {
+	n, ok := new(big.Int).SetString(stringValue(v), 10)
+	if !ok {
+		return big.NewInt(0)
+	}
+	return n
+}
+
+// timeValue accepts RFC 3339 strings and Unix seconds, falling back to now
+func timeValue(v interface{}) time.Time {
+	s := stringValue(v)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0)
+	}
+	return time.Now()
+}
@@ -0,0 +1,133 @@
+package migrate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Deep-Commit/gswarm/internal/telegram"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("telegram-config.json", `{"botToken":"123:abc","chatID":-1001234567890123,"welcomeSent":true,"eoaAddress":"0x1111111111111111111111111111111111111111"}`)
+	write("gswarm.json", "{\n  \"timezone\": \"Europe/Berlin\",\n  \"logRetention\": \"2GB\"\n}\n")
+	write(telegram.PreviousDataPath, `{"votes":42,"rewards":"1000000000000000000000","lastCheck":1700000000}`)
+	write(telegram.RewardsHistoryPath, `{"time":"2024-01-01T00:00:00Z","rewards":"1","votes":"1"}`+"\n")
+	write("gensyn_rl_swarm_go.log", "old log\n")
+
+	opts := Options{Dir: dir, StateDir: ".gswarm", TelegramConfig: "telegram-config.json", ConfigFile: "gswarm.json", LogDir: "logs"}
+	if got := Pending(opts); len(got) != 3 {
+		t.Errorf("Pending() = %v, want the telegram config, the totals and the rewards history", got)
+	}
+
+	dry := opts
+	dry.DryRun = true
+	steps, err := Run(dry)
+	if err != nil {
+		t.Fatalf("Run(dry) error = %v", err)
+	}
+	if len(steps) != 5 {
+		t.Errorf("Run(dry) steps = %q, want 5", steps)
+	}
+	if _, err := os.Stat(filepath.Join(dir, telegram.PreviousDataPath)); err != nil {
+		t.Errorf("dry run moved files: %v", err)
+	}
+
+	if _, err := Run(opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	cfg, err := telegram.LoadConfig(filepath.Join(dir, "telegram-config.json"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.BotToken != "123:abc" || cfg.ChatID != "-1001234567890123" || !cfg.WelcomeSent {
+		t.Errorf("telegram config = %+v, want the token, exact chat ID and WelcomeSent kept", cfg)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "telegram-config.json.bak")); err != nil {
+		t.Errorf("no backup of the telegram config: %v", err)
+	}
+
+	var file map[string]string
+	data, _ := os.ReadFile(filepath.Join(dir, "gswarm.json"))
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"timezone": "Europe/Berlin", "logRetention": "2GB", "eoa": "0x1111111111111111111111111111111111111111"}
+	if !reflect.DeepEqual(file, want) {
+		t.Errorf("gswarm.json = %v, want %v", file, want)
+	}
+	if tz, lr, eoa := strings.Index(string(data), "timezone"), strings.Index(string(data), "logRetention"), strings.Index(string(data), "eoa"); tz > lr || lr > eoa {
+		t.Errorf("gswarm.json keys reordered:\n%s", data)
+	}
+
+	prev, err := telegram.ReadPreviousData(filepath.Join(dir, ".gswarm", telegram.PreviousDataPath))
+	if err != nil {
+		t.Fatalf("ReadPreviousData() error = %v", err)
+	}
+	if prev.Votes.String() != "42" || prev.Rewards.String() != "1000000000000000000000" || prev.LastCheck.Unix() != 1700000000 {
+		t.Errorf("previous data = %+v", prev)
+	}
+	for _, p := range []string{".gswarm/" + telegram.RewardsHistoryPath, "logs/gensyn_rl_swarm_go.log"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			t.Errorf("%s not moved: %v", p, err)
+		}
+	}
+	if got := Pending(opts); len(got) != 0 {
+		t.Errorf("Pending() after Run = %v", got)
+	}
+
+	// A second run has nothing left to do
+	steps, err = Run(opts)
+	if err != nil || len(steps) != 0 {
+		t.Errorf("second Run() = %q, %v", steps, err)
+	}
+}
+
+func TestAppendKey(t *testing.T) {
+	cases := []struct {
+		data string
+		want string
+	}{
+		{"", "{\n  \"eoa\": \"0x1\"\n}\n"},
+		{"{}", "{\n  \"eoa\": \"0x1\"\n}\n"},
+		{"{ }\n", "{\n  \"eoa\": \"0x1\"\n}\n"},
+		{`{"b":1,"a":2}`, "{\"b\":1,\"a\":2,\n  \"eoa\": \"0x1\"\n}\n"},
+	}
+	for _, c := range cases {
+		got := string(appendKey([]byte(c.data), "eoa", "0x1"))
+		if got != c.want {
+			t.Errorf("appendKey(%q) = %q, want %q", c.data, got, c.want)
+		}
+		if !json.Valid([]byte(got)) {
+			t.Errorf("appendKey(%q) isn't valid JSON", c.data)
+		}
+	}
+}
+
+func TestRun_CanonicalConfigUntouched(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "telegram-config.json")
+	content := `{"bot_token":"vault:secret/gswarm#bot","chat_id":"42","welcome_sent":false}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	steps, err := Run(Options{Dir: dir, StateDir: ".gswarm", TelegramConfig: "telegram-config.json", ConfigFile: "gswarm.json", LogDir: "logs"})
+	if err != nil || len(steps) != 0 {
+		t.Fatalf("Run() = %q, %v, want nothing to do", steps, err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "vault:") {
+		t.Errorf("config rewritten: %s", data)
+	}
+}
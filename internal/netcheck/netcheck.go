@@ -0,0 +1,138 @@
+// Package netcheck tests TCP reachability and latency to the peers and
+// endpoints the trainer depends on, so blocked ports show up before
+// training starts instead of as opaque DHT errors.
+package netcheck
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/ports"
+)
+
+// Check modes
+const (
+	ModeFail = "fail"
+	ModeWarn = "warn"
+	ModeOff  = "off"
+)
+
+// DefaultTimeout bounds each connection attempt
+const DefaultTimeout = 5 * time.Second
+
+// Target is an endpoint to dial
+type Target struct {
+	Name string
+	Host string
+	Port int
+	// Optional targets only produce warnings when unreachable
+	Optional bool
+}
+
+// Result is the outcome of dialing a Target
+type Result struct {
+	Target
+	Latency time.Duration
+	Err     error
+}
+
+// OK reports whether the target was reachable
+func (r Result) OK() bool { return r.Err == nil }
+
+// String formats the result for display
+func (r Result) String() string {
+	addr := net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+	if r.OK() {
+		return fmt.Sprintf("✓ %s %s (%dms)", r.Name, addr, r.Latency.Milliseconds())
+	}
+	return fmt.Sprintf("✗ %s %s: %v", r.Name, addr, r.Err)
+}
+
+// FromMaddr builds a Target from a multiaddr such as /ip4/1.2.3.4/tcp/30002/p2p/Qm...
+func FromMaddr(name, maddr string) (Target, error) {
+	host, port, err := ports.MaddrHostPort(maddr)
+	if err != nil {
+		return Target{}, err
+	}
+	return Target{Name: name, Host: host, Port: port}, nil
+}
+
+// FromURL builds a Target from an http(s) URL
+func FromURL(name, rawURL string) (Target, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Target{}, err
+	}
+	port := 80
+	if u.Scheme == "https" {
+		port = 443
+	}
+	if p := u.Port(); p != "" {
+		if port, err = strconv.Atoi(p); err != nil {
+			return Target{}, fmt.Errorf("invalid port in %s: %w", rawURL, err)
+		}
+	}
+	if u.Hostname() == "" {
+		return Target{}, fmt.Errorf("no host in %s", rawURL)
+	}
+	return Target{Name: name, Host: u.Hostname(), Port: port}, nil
+}
+
+// Run dials all targets concurrently and returns the results in order
+func Run(targets []Target, timeout time.Duration) []Result {
+	results := make([]Result, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			results[i] = Dial(t, timeout)
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// Dial opens and closes a TCP connection to t, measuring the handshake time
+func Dial(t Target, timeout time.Duration) Result {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(t.Host, strconv.Itoa(t.Port)), timeout)
+	if err != nil {
+		return Result{Target: t, Err: explain(t, err, timeout)}
+	}
+	conn.Close()
+	return Result{Target: t, Latency: time.Since(start)}
+}
+
+// Failed returns the results of required targets that could not be reached
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if !r.OK() && !r.Optional {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// explain turns a dial error into an actionable message
+func explain(t Target, err error, timeout time.Duration) error {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("cannot resolve host %s (DNS failure)", t.Host)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("port %d outbound blocked or host unreachable (no response in %s)", t.Port, timeout)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("connection refused on port %d (nothing listening)", t.Port)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,69 @@
+package netcheck
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer l.Close()
+	open := l.Addr().(*net.TCPAddr).Port
+
+	// Grab a port and release it so nothing is listening there
+	l2, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := l2.Addr().(*net.TCPAddr).Port
+	l2.Close()
+
+	results := Run([]Target{
+		{Name: "open", Host: "127.0.0.1", Port: open},
+		{Name: "closed", Host: "127.0.0.1", Port: closed},
+		{Name: "optional", Host: "127.0.0.1", Port: closed, Optional: true},
+	}, time.Second)
+
+	if !results[0].OK() {
+		t.Errorf("open port result = %v, want OK", results[0])
+	}
+	if results[1].OK() || !strings.Contains(results[1].Err.Error(), "refused") {
+		t.Errorf("closed port result = %v, want connection refused", results[1])
+	}
+	if failed := Failed(results); len(failed) != 1 || failed[0].Name != "closed" {
+		t.Errorf("Failed() = %v, want only the required closed target", failed)
+	}
+}
+
+func TestFromMaddrAndURL(t *testing.T) {
+	cases := []struct {
+		name     string
+		build    func() (Target, error)
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"peer maddr", func() (Target, error) {
+			return FromMaddr("peer", "/ip4/38.101.215.13/tcp/30002/p2p/QmQ2")
+		}, "38.101.215.13", 30002, false},
+		{"bad maddr", func() (Target, error) { return FromMaddr("peer", "/ip4/1.2.3.4/udp/1") }, "", 0, true},
+		{"https url", func() (Target, error) {
+			return FromURL("rpc", "https://gensyn-testnet.g.alchemy.com/public")
+		}, "gensyn-testnet.g.alchemy.com", 443, false},
+		{"url with port", func() (Target, error) { return FromURL("rpc", "http://localhost:8545") }, "localhost", 8545, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.build()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, c.wantErr)
+			}
+			if got.Host != c.wantHost || got.Port != c.wantPort {
+				t.Errorf("target = %s:%d, want %s:%d", got.Host, got.Port, c.wantHost, c.wantPort)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Deep-Commit/gswarm/internal/logtail"
+)
+
+// MaxDocumentSize is the largest file the Telegram Bot API accepts from bots
+const MaxDocumentSize = 50 << 20
+
+// maxCaptionLen is Telegram's limit on a document caption
+const maxCaptionLen = 1024
+
+// DefaultCaption is the caption template for documents sent with events
+const DefaultCaption = "{{.Title}} · {{.Node}} · {{.File}}"
+
+// Document is a file delivered to a chat, such as a run log, a report or a
+// config snapshot. The file is read when it is sent, so an event waiting in
+// the outbox keeps its path rather than its contents.
+type Document struct {
+	Name string
+	Path string
+	// Tail sends only the file's last Tail bytes, from a line boundary;
+	// 0 sends the whole file
+	Tail    int64
+	Caption string
+}
+
+// read returns the part of the file to send
+func (d Document) read() ([]byte, error) {
+	if d.Tail > 0 {
+		return logtail.ReadTail(d.Path, d.Tail)
+	}
+	info, err := os.Stat(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxDocumentSize {
+		return nil, fmt.Errorf("document %s is %d bytes; Telegram bots can send at most %d", d.Name, info.Size(), MaxDocumentSize)
+	}
+	return os.ReadFile(d.Path)
+}
+
+// DocumentError means an event's message was delivered but its document
+// wasn't, so sending the event again would repeat the message
+type DocumentError struct {
+	Err error
+}
+
+func (e *DocumentError) Error() string { return "message sent without its document: " + e.Err.Error() }
+
+func (e *DocumentError) Unwrap() error { return e.Err }
+
+// CaptionData is what caption templates can refer to
+type CaptionData struct {
+	// Node is the node's name, Title the event's and File the document's
+	Node  string
+	Title string
+	File  string
+	Time  time.Time
+}
+
+// ParseCaption parses a caption template, a Go text/template such as
+// "{{.Title}} on {{.Node}} at {{.Time.Format \"15:04\"}}"
+func ParseCaption(text string) (*template.Template, error) {
+	tmpl, err := template.New("caption").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid caption template: %w", err)
+	}
+	if _, err := RenderCaption(tmpl, CaptionData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// RenderCaption executes a caption template. The caption is sent as HTML,
+// so the data is escaped.
+func RenderCaption(tmpl *template.Template, data CaptionData) (string, error) {
+	data.Node, data.Title, data.File = html.EscapeString(data.Node), html.EscapeString(data.Title), html.EscapeString(data.File)
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid caption template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
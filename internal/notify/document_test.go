@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTelegram_NotifyWithDocument(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/botTOKEN/sendDocument" {
+			if got := r.FormValue("caption"); got != "Crash · a&amp;b" {
+				t.Errorf("caption = %q", got)
+			}
+			f, header, err := r.FormFile("document")
+			if err != nil {
+				t.Fatalf("no document: %v", err)
+			}
+			data, _ := io.ReadAll(f)
+			if header.Filename != "run.log" || string(data) != "traceback" {
+				t.Errorf("document = %s %q", header.Filename, data)
+			}
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	tmpl, err := ParseCaption("{{.Title}} · {{.Node}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caption, _ := RenderCaption(tmpl, CaptionData{Title: "Crash", Node: "a&b"})
+	tg := &Telegram{BotToken: "TOKEN", ChatID: "42", APIBase: srv.URL}
+	path := filepath.Join(t.TempDir(), "run.log")
+	if err := os.WriteFile(path, []byte("startup\ntraceback"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ev := Event{Title: "Crash", Message: "failed", Document: &Document{Name: "run.log", Path: path, Tail: 12, Caption: caption}}
+	if err := tg.Notify(ev); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[1] != "/botTOKEN/sendDocument" {
+		t.Errorf("requests = %v, want sendMessage then sendDocument", paths)
+	}
+}
+
+func TestTelegram_DocumentFailureIsNotRetried(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/botTOKEN/sendDocument" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{BotToken: "TOKEN", ChatID: "42", APIBase: srv.URL, Client: http.DefaultClient}
+	path := filepath.Join(t.TempDir(), "run.log")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := tg.Notify(Event{Message: "failed", Document: &Document{Name: "run.log", Path: path}, Time: time.Now()})
+	var docErr *DocumentError
+	if !errors.As(err, &docErr) {
+		t.Errorf("Notify() error = %v, want a DocumentError", err)
+	}
+}
+
+func TestParseCaption(t *testing.T) {
+	for _, bad := range []string{"{{.Title", "{{.Missing}}"} {
+		if _, err := ParseCaption(bad); err == nil {
+			t.Errorf("ParseCaption(%q) should fail", bad)
+		}
+	}
+	if _, err := ParseCaption(DefaultCaption); err != nil {
+		t.Errorf("DefaultCaption: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"html"
+	"sync"
+)
+
+// Footer adds a line in italics under every event's message, such as the
+// versions the node runs, followed by the event's run ID
+type Footer struct {
+	Next Notifier
+	Text string
+}
+
+// Name implements Notifier
+func (f *Footer) Name() string { return f.Next.Name() }
+
+// Notify implements Notifier
+func (f *Footer) Notify(ev Event) error {
+	text := f.Text
+	if ev.RunID != "" {
+		if text != "" {
+			text += " · "
+		}
+		text += "run " + ev.RunID
+	}
+	if text != "" {
+		ev.Message += "\n\n<i>" + html.EscapeString(text) + "</i>"
+	}
+	return f.Next.Notify(ev)
+}
+
+// Flush implements Flusher when the wrapped notifier holds events back
+func (f *Footer) Flush() {
+	if fl, ok := f.Next.(Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// RunStamp sets the current training run's ID on events that don't have
+// one, so a notification can be matched with the run's log, journal
+// entries and metrics
+type RunStamp struct {
+	Next Notifier
+
+	mu  sync.Mutex
+	run string
+}
+
+// SetRun sets the run ID given to events from now on
+func (r *RunStamp) SetRun(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.run = id
+}
+
+// Name implements Notifier
+func (r *RunStamp) Name() string { return r.Next.Name() }
+
+// Notify implements Notifier
+func (r *RunStamp) Notify(ev Event) error {
+	if ev.RunID == "" {
+		r.mu.Lock()
+		ev.RunID = r.run
+		r.mu.Unlock()
+	}
+	return r.Next.Notify(ev)
+}
+
+// Flush implements Flusher when the wrapped notifier holds events back
+func (r *RunStamp) Flush() {
+	if fl, ok := r.Next.(Flusher); ok {
+		fl.Flush()
+	}
+}
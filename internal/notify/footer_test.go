@@ -0,0 +1,37 @@
+package notify
+
+import "testing"
+
+func TestFooter(t *testing.T) {
+	next := &recordingNotifier{name: "telegram"}
+	f := &Footer{Next: next, Text: "gswarm 1.0.0, rl-swarm v0.5.3 <dirty>"}
+	if err := f.Notify(Event{Title: "Crash", Message: "<b>exit 1</b>"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "<b>exit 1</b>\n\n<i>gswarm 1.0.0, rl-swarm v0.5.3 &lt;dirty&gt;</i>"
+	if got := next.events[0].Message; got != want {
+		t.Errorf("Message = %q, want %q", got, want)
+	}
+	if f.Name() != "telegram" {
+		t.Errorf("Name() = %q", f.Name())
+	}
+}
+
+func TestRunStamp(t *testing.T) {
+	next := &recordingNotifier{name: "telegram"}
+	r := &RunStamp{Next: &Footer{Next: next, Text: "gswarm 1.0.0"}}
+	r.Notify(Event{Message: "started"})
+	r.SetRun("20250701-100000-3f9a2c")
+	r.Notify(Event{Message: "crashed"})
+	r.Notify(Event{Message: "old", RunID: "20250630-090000-0b1c7e"})
+
+	for i, want := range []string{
+		"started\n\n<i>gswarm 1.0.0</i>",
+		"crashed\n\n<i>gswarm 1.0.0 · run 20250701-100000-3f9a2c</i>",
+		"old\n\n<i>gswarm 1.0.0 · run 20250630-090000-0b1c7e</i>",
+	} {
+		if got := next.events[i].Message; got != want {
+			t.Errorf("event %d: Message = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/httpclient"
+	"github.com/Deep-Commit/gswarm/internal/redact"
+)
+
+var htmlTagRe = regexp.MustCompile(`<[^>]+>`)
+
+// Matrix sends events to a Matrix room through the client-server API
+type Matrix struct {
+	HomeserverURL string
+	AccessToken   string
+	RoomID        string
+	// Client defaults to the shared gswarm client
+	Client *http.Client
+
+	txn uint64
+}
+
+// NewMatrix creates a Matrix notifier
+func NewMatrix(homeserverURL, accessToken, roomID string) *Matrix {
+	return &Matrix{
+		HomeserverURL: strings.TrimRight(homeserverURL, "/"),
+		AccessToken:   accessToken,
+		RoomID:        roomID,
+	}
+}
+
+// Name implements Notifier
+func (m *Matrix) Name() string { return "matrix" }
+
+// Notify implements Notifier. Messages use the same HTML subset as the
+// Telegram notifier, which Matrix clients render natively.
+func (m *Matrix) Notify(ev Event) error {
+	formatted := ev.Message
+	if ev.Title != "" {
+		formatted = fmt.Sprintf("<b>%s</b>\n\n%s", html.EscapeString(ev.Title), ev.Message)
+	}
+	return m.SendHTML(formatted)
+}
+
+// SendHTML posts an HTML-formatted message with a plain-text fallback body
+func (m *Matrix) SendHTML(formatted string) error {
+	formatted = redact.String(formatted)
+	content := map[string]string{
+		"msgtype":        "m.text",
+		"body":           html.UnescapeString(htmlTagRe.ReplaceAllString(formatted, "")),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": strings.ReplaceAll(formatted, "\n", "<br>"),
+	}
+	payload, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Matrix message: %w", err)
+	}
+
+	// Transaction IDs make the PUT idempotent if a retry re-sends it
+	txnID := fmt.Sprintf("gswarm-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&m.txn, 1))
+	apiURL := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.HomeserverURL, url.PathEscape(m.RoomID), txnID)
+
+	req, err := http.NewRequest(http.MethodPut, apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create Matrix request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.AccessToken)
+
+	client := m.Client
+	if client == nil {
+		client = httpclient.Default()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Matrix message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{Service: "Matrix", StatusCode: resp.StatusCode, Status: resp.Status, Description: string(body)}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatrix_Notify(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer srv.Close()
+
+	m := NewMatrix(srv.URL+"/", "secret", "!room:example.org")
+	err := m.Notify(Event{Type: EventRewards, Title: "Update", Message: "Votes: <b>5</b>\nRewards: 3"})
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if !strings.HasPrefix(gotPath, "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/gswarm-") {
+		t.Errorf("request path = %s", gotPath)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", gotAuth)
+	}
+	if gotBody["body"] != "Update\n\nVotes: 5\nRewards: 3" {
+		t.Errorf("body = %q", gotBody["body"])
+	}
+	if !strings.Contains(gotBody["formatted_body"], "<b>5</b><br>") {
+		t.Errorf("formatted_body = %q", gotBody["formatted_body"])
+	}
+}
+
+func TestMatrix_NotifyError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errcode":"M_FORBIDDEN"}`))
+	}))
+	defer srv.Close()
+
+	m := NewMatrix(srv.URL, "bad", "!room:example.org")
+	if err := m.Notify(Event{Message: "hi"}); err == nil {
+		t.Error("Notify() expected error on 403")
+	}
+}
@@ -0,0 +1,312 @@
+// Package notify provides the notifier pipeline used to deliver supervisor
+// and monitor events to chat services.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Deep-Commit/gswarm/internal/httpclient"
+	"github.com/Deep-Commit/gswarm/internal/redact"
+)
+
+// Event types emitted by the supervisor and monitor
+const (
+	EventRunReport = "run_report"
+	EventCrash     = "crash"
+	EventRewards   = "rewards"
+	EventInfo      = "info"
+	// EventThroughput is a drop in training throughput; unlike a crash it
+	// doesn't hold back the crash notifications that may follow
+	EventThroughput = "throughput"
+)
+
+// Event is a single notification
+type Event struct {
+	Type    string
+	Title   string
+	Message string
+	Time    time.Time
+	// RunID is the training run the event happened in, shown in the footer
+	RunID string `json:",omitempty"`
+	// Document is a file sent after the message, such as a crash log.
+	// Only Telegram delivers it; other services get the message alone.
+	Document *Document `json:",omitempty"`
+}
+
+// Notifier delivers events to a destination
+type Notifier interface {
+	Name() string
+	Notify(ev Event) error
+}
+
+// Multi fans an event out to several notifiers
+type Multi []Notifier
+
+// Name implements Notifier
+func (m Multi) Name() string {
+	names := make([]string, 0, len(m))
+	for _, n := range m {
+		names = append(names, n.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// Notify sends ev to every notifier, returning the first error after
+// attempting all of them
+func (m Multi) Notify(ev Event) error {
+	var firstErr error
+	for _, n := range m {
+		if err := n.Notify(ev); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", n.Name(), err)
+		}
+	}
+	return firstErr
+}
+
+// Flush implements Flusher for the notifiers that hold events back
+func (m Multi) Flush() {
+	for _, n := range m {
+		if f, ok := n.(Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// TelegramAPI is the Telegram Bot API base URL
+const TelegramAPI = "https://api.telegram.org"
+
+// Telegram sends events through the Telegram Bot API
+type Telegram struct {
+	BotToken string
+	ChatID   string
+	// Client defaults to the shared gswarm client
+	Client *http.Client
+	// APIBase overrides TelegramAPI, e.g. for a local Bot API server
+	APIBase string
+}
+
+// NewTelegram creates a Telegram notifier
+func NewTelegram(botToken, chatID string) *Telegram {
+	return &Telegram{
+		BotToken: botToken,
+		ChatID:   chatID,
+	}
+}
+
+// Name implements Notifier
+func (t *Telegram) Name() string { return "telegram" }
+
+// Notify implements Notifier
+func (t *Telegram) Notify(ev Event) error {
+	text := ev.Message
+	if ev.Title != "" {
+		text = fmt.Sprintf("<b>%s</b>\n\n%s", html.EscapeString(ev.Title), ev.Message)
+	}
+	if err := t.SendHTML(text); err != nil {
+		return err
+	}
+	if ev.Document != nil {
+		if err := t.SendDocument(*ev.Document); err != nil {
+			return &DocumentError{Err: err}
+		}
+	}
+	return nil
+}
+
+// SendHTML sends a message using HTML formatting
+func (t *Telegram) SendHTML(text string) error {
+	return t.send(text, "HTML")
+}
+
+// SendMarkdown sends a message using MarkdownV2 formatting
+func (t *Telegram) SendMarkdown(text string) error {
+	return t.send(text, "MarkdownV2")
+}
+
+// SendText sends a plain text message
+func (t *Telegram) SendText(text string) error {
+	return t.send(text, "")
+}
+
+// maxMessageLen is Telegram's limit on a message
+const maxMessageLen = 4096
+
+// htmlTag matches the tags of a message formatted with HTML
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+func (t *Telegram) send(text, parseMode string) error {
+	apiURL := t.method("sendMessage")
+
+	// Telegram refuses a longer message for good. Cutting it could leave a
+	// tag open, so it goes out shortened as plain text instead
+	text = redact.String(text)
+	if utf8.RuneCountInString(text) > maxMessageLen {
+		if parseMode == "HTML" {
+			text = html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+		}
+		text, parseMode = truncate(text, maxMessageLen), ""
+	}
+
+	// Prepare the request data
+	data := url.Values{}
+	data.Set("chat_id", t.ChatID)
+	data.Set("text", text)
+	if parseMode != "" {
+		data.Set("parse_mode", parseMode)
+	}
+
+	// Make the HTTP request
+	resp, err := t.client().PostForm(apiURL, data)
+	if err != nil {
+		return fmt.Errorf("failed to send Telegram message: %w", err)
+	}
+	return t.result(resp)
+}
+
+// Broadcast sends the same messages to several Telegram chats, so one
+// monitor can serve a team without each member polling the chain
+type Broadcast []*Telegram
+
+// NewBroadcast creates a Broadcast through one bot to chatIDs, skipping
+// empty and repeated IDs
+func NewBroadcast(botToken string, client *http.Client, chatIDs ...string) Broadcast {
+	var b Broadcast
+	seen := make(map[string]bool)
+	for _, id := range chatIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		tg := NewTelegram(botToken, id)
+		tg.Client = client
+		b = append(b, tg)
+	}
+	return b
+}
+
+// Name implements Notifier
+func (b Broadcast) Name() string { return "telegram" }
+
+// Notify implements Notifier
+func (b Broadcast) Notify(ev Event) error {
+	return b.each(func(t *Telegram) error { return t.Notify(ev) })
+}
+
+// SendHTML sends a message using HTML formatting to every chat
+func (b Broadcast) SendHTML(text string) error {
+	return b.each(func(t *Telegram) error { return t.SendHTML(text) })
+}
+
+// SendMarkdown sends a message using MarkdownV2 formatting to every chat
+func (b Broadcast) SendMarkdown(text string) error {
+	return b.each(func(t *Telegram) error { return t.SendMarkdown(text) })
+}
+
+// SendText sends a plain text message to every chat
+func (b Broadcast) SendText(text string) error {
+	return b.each(func(t *Telegram) error { return t.SendText(text) })
+}
+
+// SendDocument uploads a file to every chat
+func (b Broadcast) SendDocument(doc Document) error {
+	return b.each(func(t *Telegram) error { return t.SendDocument(doc) })
+}
+
+// each sends to every chat, returning the first error after attempting
+// all of them, so one chat that removed the bot doesn't silence the rest
+func (b Broadcast) each(send func(*Telegram) error) error {
+	var firstErr error
+	for _, t := range b {
+		if err := send(t); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("chat %s: %w", t.ChatID, err)
+		}
+	}
+	return firstErr
+}
+
+// SendDocument uploads a file to the chat with its caption, which may use
+// HTML formatting
+func (t *Telegram) SendDocument(doc Document) error {
+	data, err := doc.read()
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("chat_id", t.ChatID)
+	if doc.Caption != "" {
+		form.WriteField("caption", truncate(redact.String(doc.Caption), maxCaptionLen))
+		form.WriteField("parse_mode", "HTML")
+	}
+	part, err := form.CreateFormFile("document", doc.Name)
+	if err != nil {
+		return err
+	}
+	part.Write(data)
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	resp, err := t.client().Post(t.method("sendDocument"), form.FormDataContentType(), bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return fmt.Errorf("failed to send Telegram document: %w", err)
+	}
+	return t.result(resp)
+}
+
+// result checks a Bot API response for errors
+func (t *Telegram) result(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	// Read the response
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Check if the request was successful
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{Service: "Telegram", StatusCode: resp.StatusCode, Status: resp.Status, Description: string(body)}
+	}
+
+	// Parse the response to check for Telegram API errors
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		// A non-JSON body with a 200 status is treated as success
+		return nil
+	}
+
+	if val, ok := result["ok"].(bool); !ok || !val {
+		return &APIError{Service: "Telegram", Description: fmt.Sprint(result["description"])}
+	}
+
+	return nil
+}
+
+// method returns the URL of a Bot API method
+func (t *Telegram) method(name string) string {
+	base := t.APIBase
+	if base == "" {
+		base = TelegramAPI
+	}
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimRight(base, "/"), t.BotToken, name)
+}
+
+func (t *Telegram) client() *http.Client {
+	if t.Client == nil {
+		return httpclient.Default()
+	}
+	return t.Client
+}
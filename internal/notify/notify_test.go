@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type recordingNotifier struct {
+	name   string
+	events []Event
+	err    error
+}
+
+func (r *recordingNotifier) Name() string { return r.name }
+
+func (r *recordingNotifier) Notify(ev Event) error {
+	r.events = append(r.events, ev)
+	return r.err
+}
+
+func TestMulti_Notify(t *testing.T) {
+	failing := &recordingNotifier{name: "failing", err: errors.New("boom")}
+	ok := &recordingNotifier{name: "ok"}
+	m := Multi{failing, ok}
+
+	err := m.Notify(Event{Type: EventInfo, Message: "hello"})
+	if err == nil {
+		t.Fatal("Multi.Notify() expected error from failing notifier")
+	}
+	if len(ok.events) != 1 {
+		t.Errorf("ok notifier got %d events, want 1 even after an earlier failure", len(ok.events))
+	}
+	if m.Name() != "failing,ok" {
+		t.Errorf("Multi.Name() = %q, want %q", m.Name(), "failing,ok")
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	var chats []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chat := r.FormValue("chat_id")
+		chats = append(chats, chat)
+		if chat == "2" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"ok":false,"error_code":403,"description":"Forbidden: bot was kicked"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	b := NewBroadcast("TOKEN", nil, "1", " 2", "", "1", "3")
+	for _, tg := range b {
+		tg.APIBase = srv.URL
+	}
+	err := b.SendHTML("<b>update</b>")
+	if err == nil || !strings.Contains(err.Error(), "chat 2") {
+		t.Errorf("SendHTML() error = %v, want the failing chat named", err)
+	}
+	if got := strings.Join(chats, ","); got != "1,2,3" {
+		t.Errorf("sent to chats %s, want 1,2,3 once each", got)
+	}
+}
+
+func TestTelegram_LongMessage(t *testing.T) {
+	var text, parseMode string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		text, parseMode = r.FormValue("text"), r.FormValue("parse_mode")
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	tg := NewTelegram("TOKEN", "1")
+	tg.APIBase = srv.URL
+	if err := tg.SendHTML("<b>Crash</b>\n<pre>" + strings.Repeat("a &lt; b\n", 1000) + "</pre>"); err != nil {
+		t.Fatal(err)
+	}
+	if n := utf8.RuneCountInString(text); n != maxMessageLen {
+		t.Errorf("sent %d characters, want %d", n, maxMessageLen)
+	}
+	if parseMode != "" || !strings.HasPrefix(text, "Crash\na < b\n") {
+		t.Errorf("sent %q with parse mode %q, want plain text", text[:20], parseMode)
+	}
+
+	if err := tg.SendHTML("<b>short</b>"); err != nil {
+		t.Fatal(err)
+	}
+	if text != "<b>short</b>" || parseMode != "HTML" {
+		t.Errorf("sent %q with parse mode %q, want it unchanged", text, parseMode)
+	}
+}
@@ -0,0 +1,307 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox defaults
+const (
+	DefaultOutboxMaxAge = 24 * time.Hour
+	DefaultOutboxMaxLen = 200
+)
+
+// flushTimeout bounds how long Flush keeps trying to deliver on shutdown;
+// whatever is left stays in the outbox file for the next start
+var flushTimeout = 10 * time.Second
+
+// Retry delays after a failed delivery
+var (
+	outboxInitialBackoff = 5 * time.Second
+	outboxMaxBackoff     = 5 * time.Minute
+)
+
+// Flusher is implemented by notifiers that hold events back and can be
+// asked to send them before the process exits
+type Flusher interface {
+	Flush()
+}
+
+// APIError is a chat service's refusal of a message
+type APIError struct {
+	Service     string
+	StatusCode  int
+	Status      string
+	Description string
+}
+
+func (e *APIError) Error() string {
+	if e.Status == "" {
+		return fmt.Sprintf("%s API error: %s", e.Service, e.Description)
+	}
+	return fmt.Sprintf("%s API error: %s - %s", e.Service, e.Status, e.Description)
+}
+
+// Permanent reports whether resending the message can't help: the service
+// rejected the request itself rather than being rate limited or down
+func (e *APIError) Permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 && e.StatusCode != 408 && e.StatusCode != 429
+}
+
+// Outbox queues events for one destination in a file and delivers them in
+// order, retrying with backoff while the destination is unreachable, so a
+// network blip or API outage doesn't lose notifications. Identical events
+// queued during an outage are sent once, with a count. NewOutbox sets the
+// fields, which don't change once delivery has started.
+type Outbox struct {
+	Next Notifier
+	Path string
+	// MaxAge drops events that couldn't be delivered for this long
+	MaxAge time.Duration
+	// MaxLen drops the oldest events beyond this many
+	MaxLen int
+	// Logf reports dropped events; nil discards the reports
+	Logf func(format string, args ...interface{})
+
+	mu    sync.Mutex
+	queue []outboxItem
+	wake  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+type outboxItem struct {
+	Event    Event     `json:"event"`
+	Queued   time.Time `json:"queued"`
+	Repeats  int       `json:"repeats,omitempty"`
+	Attempts int       `json:"attempts,omitempty"`
+}
+
+// OutboxOptions configures an outbox; zero values take the defaults
+type OutboxOptions struct {
+	MaxAge time.Duration
+	MaxLen int
+	Logf   func(format string, args ...interface{})
+}
+
+// NewOutbox creates an outbox for next stored in stateDir, loads any events
+// left from a previous run and starts delivering them. The options are
+// fixed from then on, since delivery reads them in the background.
+func NewOutbox(next Notifier, stateDir string, opts OutboxOptions) (*Outbox, error) {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultOutboxMaxAge
+	}
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = DefaultOutboxMaxLen
+	}
+	o := &Outbox{
+		Next:   next,
+		Path:   filepath.Join(stateDir, "outbox-"+next.Name()+".json"),
+		MaxAge: opts.MaxAge,
+		MaxLen: opts.MaxLen,
+		Logf:   opts.Logf,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	data, err := os.ReadFile(o.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &o.queue); err != nil {
+			o.logf("Discarding unreadable outbox %s: %v", o.Path, err)
+			o.queue = nil
+		}
+	}
+	go o.run()
+	if len(o.queue) > 0 {
+		o.kick()
+	}
+	return o, nil
+}
+
+// Name implements Notifier
+func (o *Outbox) Name() string { return o.Next.Name() }
+
+// Notify implements Notifier. The event is queued and sent in the
+// background; the error only reports a failure to queue it.
+func (o *Outbox) Notify(ev Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	// An event identical to one still waiting is only counted
+	for i := range o.queue {
+		q := &o.queue[i]
+		if q.Event.Type == ev.Type && q.Event.Title == ev.Title && q.Event.Message == ev.Message {
+			q.Repeats++
+			return o.save()
+		}
+	}
+	o.queue = append(o.queue, outboxItem{Event: ev, Queued: time.Now()})
+	if over := len(o.queue) - o.MaxLen; o.MaxLen > 0 && over > 0 {
+		o.logf("%s outbox full; dropping %d oldest notifications", o.Name(), over)
+		o.queue = append(o.queue[:0], o.queue[over:]...)
+	}
+	err := o.save()
+	o.kick()
+	return err
+}
+
+// Pending returns the number of queued events
+func (o *Outbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.queue)
+}
+
+// Flush stops background delivery and makes a last attempt to send what is
+// queued. Events that still can't be sent are kept for the next start.
+func (o *Outbox) Flush() {
+	o.once.Do(func() { close(o.stop) })
+	<-o.done
+	deadline := time.Now().Add(flushTimeout)
+	for time.Now().Before(deadline) {
+		if sent, _ := o.deliver(); !sent {
+			return
+		}
+	}
+}
+
+func (o *Outbox) kick() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued events until Flush, backing off while the
+// destination fails
+func (o *Outbox) run() {
+	defer close(o.done)
+	backoff := outboxInitialBackoff
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-o.wake:
+		case <-retry:
+		}
+		retry = nil
+		for {
+			sent, err := o.deliver()
+			if err != nil {
+				retry = time.After(backoff)
+				backoff = minDuration(backoff*2, outboxMaxBackoff)
+				break
+			}
+			if !sent {
+				backoff = outboxInitialBackoff
+				break
+			}
+			select {
+			case <-o.stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// deliver tries to send the oldest queued event. It reports whether an
+// event left the queue, and the error of a failed attempt worth retrying.
+func (o *Outbox) deliver() (bool, error) {
+	o.mu.Lock()
+	for len(o.queue) > 0 && o.MaxAge > 0 && time.Since(o.queue[0].Queued) > o.MaxAge {
+		o.logf("Dropping %s notification %q queued since %s", o.Name(), o.queue[0].Event.Title, o.queue[0].Queued.Format(time.RFC3339))
+		o.queue = o.queue[1:]
+		o.save()
+	}
+	if len(o.queue) == 0 {
+		o.mu.Unlock()
+		return false, nil
+	}
+	item := o.queue[0]
+	o.mu.Unlock()
+
+	ev := item.Event
+	if item.Repeats > 0 {
+		ev.Message += fmt.Sprintf("\n\n<i>Repeated %d more %s while notifications couldn't be delivered.</i>", item.Repeats, plural(item.Repeats, "time", "times"))
+	}
+	err := o.Next.Notify(ev)
+
+	var apiErr *APIError
+	var docErr *DocumentError
+	permanent := errors.As(err, &apiErr) && apiErr.Permanent() || errors.As(err, &docErr)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err != nil && !permanent {
+		if len(o.queue) > 0 && o.queue[0].Queued.Equal(item.Queued) {
+			o.queue[0].Attempts++
+			o.save()
+		}
+		return false, err
+	}
+	if permanent {
+		o.logf("Dropping %s notification %q: %v", o.Name(), item.Event.Title, err)
+	}
+	// Repeats counted while sending stay queued as a new event
+	if len(o.queue) > 0 && o.queue[0].Queued.Equal(item.Queued) {
+		if extra := o.queue[0].Repeats - item.Repeats; extra > 0 {
+			o.queue[0].Repeats = extra - 1
+			o.queue[0].Queued = time.Now()
+		} else {
+			o.queue = o.queue[1:]
+		}
+	}
+	o.save()
+	return true, nil
+}
+
+// save writes the queue atomically; callers hold o.mu
+func (o *Outbox) save() error {
+	if len(o.queue) == 0 {
+		if err := os.Remove(o.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear outbox: %w", err)
+		}
+		return nil
+	}
+	data, err := json.Marshal(o.queue)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox: %w", err)
+	}
+	tmp := o.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	if err := os.Rename(tmp, o.Path); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return nil
+}
+
+func (o *Outbox) logf(format string, args ...interface{}) {
+	if o.Logf != nil {
+		o.Logf(format, args...)
+	}
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flaky fails every send while down is set
+type flaky struct {
+	recorder
+	mu   sync.Mutex
+	down error
+}
+
+func (f *flaky) Notify(ev Event) error {
+	f.mu.Lock()
+	err := f.down
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return f.recorder.Notify(ev)
+}
+
+func (f *flaky) setDown(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = err
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the outbox")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOutbox_RetriesInOrderAndDeduplicates(t *testing.T) {
+	outboxInitialBackoff, outboxMaxBackoff = 10*time.Millisecond, 20*time.Millisecond
+	dest := &flaky{down: errors.New("connection refused")}
+	dir := t.TempDir()
+	o, err := NewOutbox(dest, dir, OutboxOptions{})
+	if err != nil {
+		t.Fatalf("NewOutbox() error = %v", err)
+	}
+
+	o.Notify(Event{Type: EventCrash, Title: "Crash", Message: "exit 1"})
+	o.Notify(Event{Type: EventInfo, Title: "Info", Message: "restarted"})
+	o.Notify(Event{Type: EventCrash, Title: "Crash", Message: "exit 1"})
+	if got := o.Pending(); got != 2 {
+		t.Fatalf("Pending() = %d, want 2 with the duplicate merged", got)
+	}
+
+	dest.setDown(nil)
+	waitFor(t, func() bool { return o.Pending() == 0 })
+	events := dest.Events()
+	if len(events) != 2 || events[0].Title != "Crash" || events[1].Title != "Info" {
+		t.Fatalf("delivered %+v, want Crash then Info", events)
+	}
+	if !strings.Contains(events[0].Message, "Repeated 1 more time") {
+		t.Errorf("merged event message = %q, want a repeat count", events[0].Message)
+	}
+	o.Flush()
+}
+
+func TestOutbox_PersistsAcrossRestarts(t *testing.T) {
+	outboxInitialBackoff, outboxMaxBackoff = time.Hour, time.Hour
+	flushTimeout = 50 * time.Millisecond
+	dest := &flaky{down: errors.New("timeout")}
+	dir := t.TempDir()
+	o, _ := NewOutbox(dest, dir, OutboxOptions{})
+	doc := &Document{Name: "run.log", Path: "/var/log/gswarm/run.log", Tail: 1 << 20}
+	o.Notify(Event{Type: EventRunReport, Title: "Run 1", Message: "done", Document: doc})
+	o.Flush()
+
+	// the outbox keeps the document's path, not its contents
+	data, err := os.ReadFile(o.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Path":"/var/log/gswarm/run.log"`) {
+		t.Errorf("outbox = %s, want the document's path", data)
+	}
+
+	dest.setDown(nil)
+	o, err = NewOutbox(dest, dir, OutboxOptions{})
+	if err != nil {
+		t.Fatalf("NewOutbox() error = %v", err)
+	}
+	waitFor(t, func() bool { return len(dest.Events()) == 1 })
+	if got := dest.Events()[0]; got.Title != "Run 1" || got.Document == nil || *got.Document != *doc {
+		t.Errorf("delivered %+v after restart, want Run 1 with its document", got)
+	}
+	o.Flush()
+}
+
+func TestOutbox_DropsRejectedAndExpired(t *testing.T) {
+	outboxInitialBackoff, outboxMaxBackoff = 10*time.Millisecond, 20*time.Millisecond
+	dest := &flaky{down: &APIError{Service: "Telegram", StatusCode: 400, Status: "400 Bad Request", Description: "chat not found"}}
+	var dropped []string
+	var mu sync.Mutex
+	logf := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped = append(dropped, format)
+	}
+	o, _ := NewOutbox(dest, t.TempDir(), OutboxOptions{Logf: logf})
+	o.Notify(Event{Type: EventInfo, Title: "Rejected"})
+	waitFor(t, func() bool { return o.Pending() == 0 })
+	o.Flush()
+
+	dest.setDown(errors.New("down"))
+	o, _ = NewOutbox(dest, t.TempDir(), OutboxOptions{MaxAge: time.Nanosecond, Logf: logf})
+	o.Notify(Event{Type: EventInfo, Title: "Expired"})
+	waitFor(t, func() bool { return o.Pending() == 0 })
+	o.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) != 2 || len(dest.Events()) != 0 {
+		t.Errorf("dropped %d and delivered %d events, want 2 dropped", len(dropped), len(dest.Events()))
+	}
+}
+
+func TestAPIError_Permanent(t *testing.T) {
+	cases := map[int]bool{400: true, 403: true, 429: false, 502: false, 0: false}
+	for code, want := range cases {
+		if got := (&APIError{StatusCode: code}).Permanent(); got != want {
+			t.Errorf("Permanent() for %d = %v, want %v", code, got, want)
+		}
+	}
+}
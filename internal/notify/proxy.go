@@ -0,0 +1,13 @@
+package notify
+
+import (
+	"net/http"
+
+	"github.com/Deep-Commit/gswarm/internal/httpclient"
+)
+
+// ProxyClient returns an HTTP client that sends every request through proxy,
+// regardless of the proxy used by the rest of gswarm
+func ProxyClient(proxy string) (*http.Client, error) {
+	return httpclient.WithProxy(proxy)
+}
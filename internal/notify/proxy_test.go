@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestProxyClient_SOCKS5(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer l.Close()
+	creds := make(chan string, 1)
+	go serveSOCKS5(l, creds)
+
+	client, err := ProxyClient("socks5://alice:secret@" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("ProxyClient() error = %v", err)
+	}
+	resp, err := client.Get(target.URL)
+	if err != nil {
+		t.Fatalf("Get() through proxy error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("body = %q, want ok", body)
+	}
+	if got := <-creds; got != "alice:secret" {
+		t.Errorf("proxy saw credentials %q, want alice:secret", got)
+	}
+}
+
+// serveSOCKS5 accepts one connection and handles a username/password
+// SOCKS5 CONNECT to an IPv4 address
+func serveSOCKS5(l net.Listener, creds chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 2)
+	io.ReadFull(conn, buf)
+	io.ReadFull(conn, make([]byte, buf[1]))
+	conn.Write([]byte{5, 2})
+
+	io.ReadFull(conn, buf)
+	user := make([]byte, buf[1])
+	io.ReadFull(conn, user)
+	io.ReadFull(conn, buf[:1])
+	pass := make([]byte, buf[0])
+	io.ReadFull(conn, pass)
+	creds <- string(user) + ":" + string(pass)
+	conn.Write([]byte{1, 0})
+
+	req := make([]byte, 10)
+	io.ReadFull(conn, req)
+	addr := net.JoinHostPort(net.IP(req[4:8]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(req[8:]))))
+	upstream, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
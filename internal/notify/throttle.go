@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/timefmt"
+)
+
+// DefaultDedupeWindow is how long an identical event is suppressed after it was sent
+const DefaultDedupeWindow = time.Hour
+
+// Throttle wraps a Notifier with per-event-type cooldowns and deduplication,
+// so a crash-looping node doesn't flood the chat. Suppressed events are
+// counted and summarized in a rollup once the cooldown expires.
+type Throttle struct {
+	Next Notifier
+	// Cooldowns is the minimum time between events of each type; types
+	// without an entry are never rate limited
+	Cooldowns map[string]time.Duration
+	// DedupeWindow suppresses an event identical to the last one sent for
+	// its type. Only types with a cooldown are deduplicated; informational
+	// events such as a resume or recovery repeat legitimately.
+	DedupeWindow time.Duration
+	// Time renders the times in rollups
+	Time timefmt.Formatter
+
+	mu    sync.Mutex
+	state map[string]*throttleState
+}
+
+type throttleState struct {
+	lastSent    time.Time
+	last        Event
+	suppressed  int
+	firstMissed time.Time
+	pending     Event
+	timer       *time.Timer
+}
+
+// NewThrottle creates a Throttle with the default dedupe window
+func NewThrottle(next Notifier, cooldowns map[string]time.Duration) *Throttle {
+	return &Throttle{Next: next, Cooldowns: cooldowns, DedupeWindow: DefaultDedupeWindow}
+}
+
+// Configure replaces the cooldowns and dedupe window, e.g. when they are
+// changed at run time. Events already held back are sent when their old
+// cooldown runs out.
+func (t *Throttle) Configure(cooldowns map[string]time.Duration, dedupeWindow time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Cooldowns, t.DedupeWindow = cooldowns, dedupeWindow
+}
+
+// Name implements Notifier
+func (t *Throttle) Name() string { return t.Next.Name() }
+
+// Notify implements Notifier
+func (t *Throttle) Notify(ev Event) error {
+	now := time.Now()
+	t.mu.Lock()
+	if t.state == nil {
+		t.state = make(map[string]*throttleState)
+	}
+	st := t.state[ev.Type]
+	if st == nil {
+		st = &throttleState{}
+		t.state[ev.Type] = st
+	}
+
+	cooldown, limited := t.Cooldowns[ev.Type]
+	inCooldown := cooldown > 0 && now.Sub(st.lastSent) < cooldown
+	duplicate := limited && t.DedupeWindow > 0 && !st.lastSent.IsZero() && now.Sub(st.lastSent) < t.DedupeWindow &&
+		ev.Title == st.last.Title && ev.Message == st.last.Message
+	if inCooldown || duplicate {
+		if st.suppressed == 0 {
+			st.firstMissed = now
+		}
+		st.suppressed++
+		st.pending = ev
+		// Send the rollup when the cooldown runs out; duplicates outside a
+		// cooldown are rolled into the next event that goes through
+		if inCooldown && st.timer == nil {
+			st.timer = time.AfterFunc(st.lastSent.Add(cooldown).Sub(now), func() { t.flush(ev.Type) })
+		}
+		t.mu.Unlock()
+		return nil
+	}
+
+	st.lastSent, st.last = now, ev
+	ev = t.withRollup(ev, st)
+	t.mu.Unlock()
+	return t.Next.Notify(ev)
+}
+
+// Flush sends any pending rollups immediately, e.g. on shutdown, then
+// flushes the wrapped notifier
+func (t *Throttle) Flush() {
+	t.mu.Lock()
+	types := make([]string, 0, len(t.state))
+	for typ := range t.state {
+		types = append(types, typ)
+	}
+	t.mu.Unlock()
+	for _, typ := range types {
+		t.flush(typ)
+	}
+	if f, ok := t.Next.(Flusher); ok {
+		f.Flush()
+	}
+}
+
+// flush sends the rollup for an event type if events were suppressed
+func (t *Throttle) flush(eventType string) {
+	t.mu.Lock()
+	st := t.state[eventType]
+	if st == nil || st.suppressed == 0 {
+		if st != nil {
+			st.timer = nil
+		}
+		t.mu.Unlock()
+		return
+	}
+	if st.timer != nil {
+		st.timer.Stop()
+		st.timer = nil
+	}
+	ev := st.pending
+	count, since := st.suppressed, st.firstMissed
+	st.suppressed = 0
+	st.lastSent, st.last = time.Now(), ev
+	t.mu.Unlock()
+
+	ev.Message += fmt.Sprintf("\n\n<i>This happened %d more %s since %s.</i>", count, plural(count, "time", "times"), t.Time.Clock(since))
+	t.Next.Notify(ev)
+}
+
+// withRollup notes suppressed duplicates on the next event that is sent
+func (t *Throttle) withRollup(ev Event, st *throttleState) Event {
+	if st.suppressed == 0 {
+		return ev
+	}
+	ev.Message += fmt.Sprintf("\n\n<i>%d similar %s suppressed since %s.</i>", st.suppressed,
+		plural(st.suppressed, "notification", "notifications"), t.Time.Clock(st.firstMissed))
+	st.suppressed = 0
+	return ev
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
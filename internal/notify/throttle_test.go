@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Notify(ev Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	return nil
+}
+
+func (r *recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+func TestThrottle_CooldownRollup(t *testing.T) {
+	rec := &recorder{}
+	th := NewThrottle(rec, map[string]time.Duration{EventCrash: 100 * time.Millisecond})
+
+	for i := 0; i < 5; i++ {
+		th.Notify(Event{Type: EventCrash, Title: "Crash", Message: "exit 1"})
+	}
+	// Other types aren't rate limited
+	th.Notify(Event{Type: EventInfo, Message: "started"})
+
+	if got := len(rec.Events()); got != 2 {
+		t.Fatalf("sent %d events during cooldown, want 2", got)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	events := rec.Events()
+	if len(events) != 3 {
+		t.Fatalf("sent %d events after cooldown, want 3 (with rollup)", len(events))
+	}
+	if !strings.Contains(events[2].Message, "This happened 4 more times") {
+		t.Errorf("rollup message = %q, want suppressed count", events[2].Message)
+	}
+}
+
+func TestThrottle_Dedupe(t *testing.T) {
+	rec := &recorder{}
+	th := NewThrottle(rec, map[string]time.Duration{EventRunReport: time.Millisecond})
+
+	th.Notify(Event{Type: EventRunReport, Message: "Votes: 5"})
+	time.Sleep(5 * time.Millisecond)
+	th.Notify(Event{Type: EventRunReport, Message: "Votes: 5"})
+	th.Notify(Event{Type: EventRunReport, Message: "Votes: 6"})
+	// Types without a cooldown aren't deduplicated
+	th.Notify(Event{Type: EventInfo, Message: "Training resumed"})
+	th.Notify(Event{Type: EventInfo, Message: "Training resumed"})
+
+	events := rec.Events()
+	if len(events) != 4 {
+		t.Fatalf("sent %d events, want 4", len(events))
+	}
+	if !strings.Contains(events[1].Message, "1 similar notification suppressed") {
+		t.Errorf("message = %q, want suppressed duplicate note", events[1].Message)
+	}
+}
+
+func TestThrottle_Configure(t *testing.T) {
+	rec := &recorder{}
+	th := NewThrottle(rec, map[string]time.Duration{EventCrash: time.Hour})
+	th.Configure(nil, 0)
+	for i := 0; i < 3; i++ {
+		th.Notify(Event{Type: EventCrash, Title: "Crash", Message: "exit 1"})
+	}
+	if got := len(rec.Events()); got != 3 {
+		t.Errorf("sent %d of 3 events without a cooldown", got)
+	}
+}
+
+func TestThrottle_Flush(t *testing.T) {
+	rec := &recorder{}
+	th := NewThrottle(rec, map[string]time.Duration{EventRunReport: time.Hour})
+
+	th.Notify(Event{Type: EventRunReport, Message: "run 1"})
+	th.Notify(Event{Type: EventRunReport, Message: "run 2"})
+	th.Flush()
+
+	events := rec.Events()
+	if len(events) != 2 || !strings.HasPrefix(events[1].Message, "run 2") {
+		t.Errorf("events after Flush() = %+v, want the pending run 2 rollup", events)
+	}
+}
@@ -0,0 +1,196 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/console"
+	"github.com/Deep-Commit/gswarm/internal/redact"
+)
+
+// commandPollWait is how long each getUpdates call waits for new messages
+const commandPollWait = 25 * time.Second
+
+// pollMargin is how much longer than its wait a getUpdates call may take
+// before the client gives up on it
+const pollMargin = 10 * time.Second
+
+// Update is an incoming Bot API update
+type Update struct {
+	ID            int            `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+// Message is an incoming chat message
+type Message struct {
+	ID   int `json:"message_id"`
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	From *User  `json:"from,omitempty"`
+	Text string `json:"text"`
+}
+
+// User is a Telegram account
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// CallbackQuery is a press of an inline keyboard button
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data"`
+}
+
+// Button is an inline keyboard button; Data comes back in the
+// CallbackQuery when it is pressed
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"callback_data"`
+}
+
+// Command returns the bot command in the message, e.g. "refresh" for
+// "/refresh" or "/refresh@my_bot", or "" if it isn't a command
+func (m *Message) Command() string {
+	if !strings.HasPrefix(m.Text, "/") {
+		return ""
+	}
+	cmd, _, _ := strings.Cut(strings.Fields(m.Text)[0][1:], "@")
+	return strings.ToLower(cmd)
+}
+
+// GetUpdates long-polls for updates after offset, waiting up to wait. The
+// client's timeout is extended to cover the wait, so a short --http-timeout
+// doesn't cut every poll off before Telegram answers.
+func (t *Telegram) GetUpdates(ctx context.Context, offset int, wait time.Duration) ([]Update, error) {
+	query := url.Values{}
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("timeout", strconv.Itoa(int(wait.Seconds())))
+	query.Set("allowed_updates", `["message","callback_query"]`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.method("getUpdates")+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := *t.client()
+	if client.Timeout > 0 && client.Timeout < wait+pollMargin {
+		client.Timeout = wait + pollMargin
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Telegram updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool     `json:"ok"`
+		Description string   `json:"description"`
+		Result      []Update `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse Telegram updates: %w", err)
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("Telegram API error: %s", result.Description)
+	}
+	return result.Result, nil
+}
+
+// SendButtons sends an HTML message with a row of inline keyboard buttons
+// and returns its message ID
+func (t *Telegram) SendButtons(text string, buttons []Button) (int, error) {
+	markup, err := json.Marshal(map[string]interface{}{"inline_keyboard": [][]Button{buttons}})
+	if err != nil {
+		return 0, err
+	}
+	data := url.Values{}
+	data.Set("chat_id", t.ChatID)
+	data.Set("text", redact.String(text))
+	data.Set("parse_mode", "HTML")
+	data.Set("reply_markup", string(markup))
+	resp, err := t.client().PostForm(t.method("sendMessage"), data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send Telegram message: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		OK          bool    `json:"ok"`
+		Description string  `json:"description"`
+		Result      Message `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to parse Telegram response: %w", err)
+	}
+	if !result.OK {
+		return 0, &APIError{Service: "Telegram", StatusCode: resp.StatusCode, Status: resp.Status, Description: result.Description}
+	}
+	return result.Result.ID, nil
+}
+
+// EditHTML replaces the text of a message sent to the chat, removing its
+// buttons
+func (t *Telegram) EditHTML(messageID int, text string) error {
+	data := url.Values{}
+	data.Set("chat_id", t.ChatID)
+	data.Set("message_id", strconv.Itoa(messageID))
+	data.Set("text", redact.String(text))
+	data.Set("parse_mode", "HTML")
+	resp, err := t.client().PostForm(t.method("editMessageText"), data)
+	if err != nil {
+		return fmt.Errorf("failed to edit Telegram message: %w", err)
+	}
+	return t.result(resp)
+}
+
+// AnswerCallback acknowledges a button press, showing text to the user
+// who pressed it
+func (t *Telegram) AnswerCallback(id, text string) error {
+	data := url.Values{}
+	data.Set("callback_query_id", id)
+	if text != "" {
+		data.Set("text", text)
+	}
+	resp, err := t.client().PostForm(t.method("answerCallbackQuery"), data)
+	if err != nil {
+		return fmt.Errorf("failed to answer Telegram callback: %w", err)
+	}
+	return t.result(resp)
+}
+
+// Commands polls for bot commands sent to the configured chat and calls
+// handle with each command name until ctx is done. Commands from other
+// chats are ignored.
+func (t *Telegram) Commands(ctx context.Context, handle func(cmd string)) {
+	offset := 0
+	for ctx.Err() == nil {
+		updates, err := t.GetUpdates(ctx, offset, commandPollWait)
+		if err != nil {
+			if ctx.Err() == nil {
+				console.Warnf("%v", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(30 * time.Second):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.ID + 1
+			if u.Message == nil || strconv.FormatInt(u.Message.Chat.ID, 10) != t.ChatID {
+				continue
+			}
+			if cmd := u.Message.Command(); cmd != "" {
+				handle(cmd)
+			}
+		}
+	}
+}
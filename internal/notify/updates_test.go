@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMessage_Command(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"/refresh", "refresh"},
+		{"/Refresh@gswarm_bot now", "refresh"},
+		{"refresh", ""},
+		{"/", ""},
+	}
+	for _, c := range cases {
+		if got := (&Message{Text: c.text}).Command(); got != c.want {
+			t.Errorf("Command(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestTelegram_Commands(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/getUpdates" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		calls++
+		if calls == 1 {
+			fmt.Fprint(w, `{"ok":true,"result":[
+				{"update_id":7,"message":{"chat":{"id":42},"text":"/refresh"}},
+				{"update_id":8,"message":{"chat":{"id":99},"text":"/refresh"}},
+				{"update_id":9,"message":{"chat":{"id":42},"text":"hello"}}]}`)
+			return
+		}
+		if got := r.URL.Query().Get("offset"); got != "10" {
+			t.Errorf("offset = %s, want 10", got)
+		}
+		cancel()
+		fmt.Fprint(w, `{"ok":true,"result":[]}`)
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{BotToken: "TOKEN", ChatID: "42", APIBase: srv.URL}
+	var got []string
+	tg.Commands(ctx, func(cmd string) { got = append(got, cmd) })
+	if len(got) != 1 || got[0] != "refresh" {
+		t.Errorf("commands = %v, want only the refresh from the configured chat", got)
+	}
+}
+
+func TestTelegram_GetUpdates_OutlastsTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprint(w, `{"ok":true,"result":[{"update_id":7}]}`)
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{BotToken: "TOKEN", APIBase: srv.URL, Client: &http.Client{Timeout: 100 * time.Millisecond}}
+	updates, err := tg.GetUpdates(context.Background(), 0, time.Second)
+	if err != nil {
+		t.Fatalf("GetUpdates() error = %v", err)
+	}
+	if len(updates) != 1 || updates[0].ID != 7 {
+		t.Errorf("GetUpdates() = %+v", updates)
+	}
+	if tg.Client.Timeout != 100*time.Millisecond {
+		t.Errorf("client timeout changed to %s", tg.Client.Timeout)
+	}
+}
+
+func TestTelegram_SendButtons(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/botTOKEN/sendMessage":
+			if got := r.Form.Get("reply_markup"); got != `{"inline_keyboard":[[{"text":"Yes","callback_data":"y"},{"text":"No","callback_data":"n"}]]}` {
+				t.Errorf("reply_markup = %s", got)
+			}
+			fmt.Fprint(w, `{"ok":true,"result":{"message_id":17,"chat":{"id":42},"text":"ok?"}}`)
+		case "/botTOKEN/editMessageText":
+			if r.Form.Get("message_id") != "17" || r.Form.Get("text") != "done" {
+				t.Errorf("edit form = %v", r.Form)
+			}
+			fmt.Fprint(w, `{"ok":true,"result":{}}`)
+		default:
+			t.Errorf("path = %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{BotToken: "TOKEN", ChatID: "42", APIBase: srv.URL}
+	id, err := tg.SendButtons("ok?", []Button{{Text: "Yes", Data: "y"}, {Text: "No", Data: "n"}})
+	if err != nil || id != 17 {
+		t.Fatalf("SendButtons() = %d, %v", id, err)
+	}
+	if err := tg.EditHTML(id, "done"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,235 @@
+// Package overlay merges a user's YAML overlay onto an rl-swarm trainer
+// config, so settings such as the learning rate or batch size survive
+// re-clones of the checkout instead of being edited into it.
+//
+// The config is edited as a YAML node tree and encoded again: only the keys
+// the overlay sets change, and comments, key order and Hydra interpolations
+// elsewhere in the file are kept. Blank lines and indentation are not.
+package overlay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Change is one key the overlay set
+type Change struct {
+	// Key is the dotted path, e.g. "training.learning_rate"
+	Key string
+	// Old is the value in the base config, empty when the key was added
+	Old   string
+	New   string
+	Added bool
+}
+
+// String describes the change, e.g. "training.learning_rate: 5.0e-7 → 1.0e-06"
+func (c Change) String() string {
+	if c.Added {
+		return fmt.Sprintf("%s: %s (added)", c.Key, c.New)
+	}
+	return fmt.Sprintf("%s: %s → %s", c.Key, orEmpty(c.Old), c.New)
+}
+
+func orEmpty(s string) string {
+	if s == "" {
+		return "(section)"
+	}
+	return s
+}
+
+// Apply merges overlay onto base. Mappings in the overlay are merged key
+// by key; any other value, sequences included, replaces the base's value
+// or is added when the base doesn't have the key.
+func Apply(base, overlay []byte) ([]byte, []Change, error) {
+	var ov yaml.Node
+	if err := yaml.Unmarshal(overlay, &ov); err != nil {
+		return nil, nil, fmt.Errorf("invalid overlay: %w", err)
+	}
+	if len(ov.Content) == 0 || isNull(ov.Content[0]) {
+		return base, nil, nil
+	}
+	if ov.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, errors.New("invalid overlay: it must be a mapping of config keys")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(base, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, errors.New("invalid config: it isn't a mapping of keys")
+	}
+
+	var changes []Change
+	if err := merge(doc.Content[0], ov.Content[0], nil, &changes); err != nil {
+		return nil, nil, err
+	}
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, err
+	}
+	return out.Bytes(), changes, nil
+}
+
+// merge sets the overlay mapping's keys in the config mapping, in the
+// overlay's order
+func merge(dst, src *yaml.Node, prefix []string, changes *[]Change) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		path := append(append([]string(nil), prefix...), key.Value)
+		name := strings.Join(path, ".")
+		if err := normalize(value); err != nil {
+			return fmt.Errorf("overlay key %s: %w", name, err)
+		}
+
+		old := lookup(dst, key.Value)
+		if old == nil {
+			dst.Content = append(dst.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.Value}, value)
+			added(path, value, changes)
+			continue
+		}
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			switch {
+			case old.Kind == yaml.MappingNode:
+				if err := merge(old, value, path, changes); err != nil {
+					return err
+				}
+				continue
+			case old.Kind == yaml.SequenceNode:
+				return fmt.Errorf("overlay key %s: the config has a list here; lists can only be replaced whole", name)
+			case !isNull(old):
+				return fmt.Errorf("overlay key %s: %s is set to %s in the config, not a section", name, key.Value, old.Value)
+			}
+			*old = *value
+			added(path, value, changes)
+			continue
+		}
+
+		c := Change{Key: name, New: render(value)}
+		if old.Kind == yaml.ScalarNode && !isNull(old) {
+			c.Old = old.Value
+		}
+		// Comments on the replaced value stay with the key
+		value.LineComment, value.HeadComment, value.FootComment = old.LineComment, old.HeadComment, old.FootComment
+		*old = *value
+		*changes = append(*changes, c)
+	}
+	return nil
+}
+
+// lookup returns the value of key in a mapping, or nil
+func lookup(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// added records the values of a new key, one change per leaf
+func added(path []string, value *yaml.Node, changes *[]Change) {
+	if value.Kind != yaml.MappingNode || len(value.Content) == 0 {
+		*changes = append(*changes, Change{Key: strings.Join(path, "."), New: render(value), Added: true})
+		return
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		added(append(append([]string(nil), path...), value.Content[i].Value), value.Content[i+1], changes)
+	}
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+// normalize rewrites the overlay's values so PyYAML reads them as YAML 1.2
+// does, and rejects what the config can't hold
+func normalize(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return errors.New("aliases aren't supported")
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!float":
+			f, err := strconv.ParseFloat(strings.ReplaceAll(n.Value, "_", ""), 64)
+			if err != nil {
+				// .inf and .nan read the same in both
+				return nil
+			}
+			n.Value, n.Style = formatFloat(f), 0
+		case "!!str":
+			if n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 && renderString(n.Value) != n.Value {
+				n.Style = yaml.DoubleQuotedStyle
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			if item.Kind == yaml.MappingNode || item.Kind == yaml.SequenceNode {
+				return errors.New("lists of sections aren't supported")
+			}
+		}
+		fallthrough
+	case yaml.MappingNode:
+		for _, c := range n.Content {
+			if err := normalize(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// render encodes a value from the overlay as flow-style YAML, e.g. "[wandb]"
+func render(n *yaml.Node) string {
+	if n.Kind == yaml.ScalarNode {
+		return n.Value
+	}
+	flow := *n
+	flow.Style = yaml.FlowStyle
+	out, err := yaml.Marshal(&flow)
+	if err != nil {
+		return "{}"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// formatFloat writes floats with a decimal point, e.g. 1.0e-06 rather
+// than 1e-06, which PyYAML would read as a string
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if strings.Contains(s, ".") {
+		return s
+	}
+	if i := strings.IndexByte(s, 'e'); i >= 0 {
+		return s[:i] + ".0" + s[i:]
+	}
+	return s + ".0"
+}
+
+// renderString returns s when it reads back as the same string unquoted,
+// and s double-quoted otherwise. Anything PyYAML treats specially is quoted
+// first, since it reads some plain scalars differently than YAML 1.2 does.
+func renderString(s string) string {
+	special := strings.ContainsAny(s[:min(1, len(s))], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":")
+	if s != "" && s == strings.TrimSpace(s) && !special {
+		var m map[string]interface{}
+		if err := yaml.Unmarshal([]byte("k: "+s), &m); err == nil && m["k"] == s {
+			return s
+		}
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,116 @@
+package overlay
+
+import (
+	"strings"
+	"testing"
+)
+
+const base = `# GRPO trainer config
+model_name_or_path: Gensyn/Qwen2.5-0.5B-Instruct
+learning_rate: 5.0e-7   # tuned for the swarm
+per_device_train_batch_size: 8
+output_dir: runs/gsm8k
+report_to:
+- tensorboard
+training:
+  num_generations: 2
+  hub:
+    push: false
+
+  # checkpoints
+  save_steps: 100
+log_dir: ${oc.env:ROOT,.}/logs
+`
+
+func TestApply(t *testing.T) {
+	ov := `learning_rate: 1.0e-6
+per_device_train_batch_size: 4
+output_dir: /data/runs
+report_to: [wandb]
+training:
+  num_generations: 4
+  hub:
+    push: true
+    repo: "my org/model"
+  seed: 42
+new_section:
+  enabled: true
+`
+	got, changes, err := Apply([]byte(base), []byte(ov))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# GRPO trainer config
+model_name_or_path: Gensyn/Qwen2.5-0.5B-Instruct
+learning_rate: 1.0e-06 # tuned for the swarm
+per_device_train_batch_size: 4
+output_dir: /data/runs
+report_to: [wandb]
+training:
+  num_generations: 4
+  hub:
+    push: true
+    repo: "my org/model"
+  # checkpoints
+  save_steps: 100
+  seed: 42
+log_dir: ${oc.env:ROOT,.}/logs
+new_section:
+  enabled: true
+`
+	if string(got) != want {
+		t.Errorf("Apply() =\n%s\nwant\n%s", got, want)
+	}
+
+	var text []string
+	for _, c := range changes {
+		text = append(text, c.String())
+	}
+	for _, w := range []string{
+		"learning_rate: 5.0e-7 → 1.0e-06",
+		"report_to: (section) → [wandb]",
+		"training.hub.repo: my org/model (added)",
+		"new_section.enabled: true (added)",
+	} {
+		if !strings.Contains(strings.Join(text, "\n"), w) {
+			t.Errorf("changes missing %q: %q", w, text)
+		}
+	}
+}
+
+func TestApply_Quoting(t *testing.T) {
+	got, _, err := Apply([]byte("a: 1\n"), []byte("a: \"true\"\nb: 'x: y'\nc: \"\"\nd: ['1', b]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a: \"true\"\nb: \"x: y\"\nc: \"\"\nd: [\"1\", b]\n"
+	if string(got) != want {
+		t.Errorf("Apply() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestApply_QuotedKeys(t *testing.T) {
+	got, changes, err := Apply([]byte("\"training\":\n  'seed': 1\n"), []byte("training:\n  seed: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"training\":\n  'seed': 2\n"; string(got) != want {
+		t.Errorf("Apply() =\n%s\nwant\n%s", got, want)
+	}
+	if len(changes) != 1 || changes[0].String() != "training.seed: 1 → 2" {
+		t.Errorf("changes = %q", changes)
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	for name, ov := range map[string]string{
+		"not a mapping":     "- a\n",
+		"scalar as section": "learning_rate:\n  value: 1\n",
+		"into a list":       "report_to:\n  x: 1\n",
+		"invalid yaml":      "a: [1\n",
+	} {
+		if _, _, err := Apply([]byte(base), []byte(ov)); err == nil {
+			t.Errorf("%s: Apply() succeeded", name)
+		}
+	}
+}
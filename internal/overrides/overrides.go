@@ -0,0 +1,291 @@
+// Package overrides holds settings changed on a running supervisor through
+// the status API: the log level, the notification cooldown and how often
+// memory and the hub are polled, and the monitor's check interval on its
+// health listener. They take effect without a restart and are saved in the
+// state directory, so they outlast one.
+package overrides
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/console"
+)
+
+// StateFile holds the supervisor's overrides in the state directory
+const StateFile = "overrides.json"
+
+// MonitorStateFile holds the monitor's overrides, kept apart as the
+// monitor is a process of its own that may share the state directory
+const MonitorStateFile = "monitor-overrides.json"
+
+// Path is where the status API serves the overrides
+const Path = "/api/v1/overrides"
+
+// maxBodySize bounds a change request
+const maxBodySize = 64 << 10
+
+// Duration is a duration written as a string such as "10m"
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("durations are strings such as \"10m\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Or returns the override, or def when there is none
+func (d *Duration) Or(def time.Duration) time.Duration {
+	if d == nil {
+		return def
+	}
+	return time.Duration(*d)
+}
+
+// Settings are the overrides; unset fields keep the value from the flags
+// and config file
+type Settings struct {
+	// LogLevel is quiet, normal or verbose
+	LogLevel string `json:"log_level,omitempty"`
+	// NotifyCooldown is the least time between crash notifications; 0
+	// turns the cooldown off
+	NotifyCooldown *Duration `json:"notify_cooldown,omitempty"`
+	// MemorySample is how often the trainer's memory is sampled
+	MemorySample *Duration `json:"memory_sample,omitempty"`
+	// HubInterval is how often status is pushed to the hub
+	HubInterval *Duration `json:"hub_interval,omitempty"`
+	// CheckInterval is how often the monitor checks votes and rewards
+	CheckInterval *Duration `json:"check_interval,omitempty"`
+}
+
+// Validate checks each override that is set
+func (s Settings) Validate() error {
+	if s.LogLevel != "" {
+		if _, err := console.ParseLevel(s.LogLevel); err != nil {
+			return err
+		}
+	}
+	if s.NotifyCooldown != nil && *s.NotifyCooldown < 0 {
+		return errors.New("notify_cooldown can't be negative")
+	}
+	// Polling can't be switched off or on at run time, as the loops doing
+	// it only run when their flag enables them
+	for name, d := range map[string]*Duration{"memory_sample": s.MemorySample, "hub_interval": s.HubInterval, "check_interval": s.CheckInterval} {
+		if d != nil && *d <= 0 {
+			return fmt.Errorf("%s must be positive; set its flag to 0 to turn it off", name)
+		}
+	}
+	return nil
+}
+
+// merge returns s with the overrides set in patch applied
+func (s Settings) merge(patch Settings) Settings {
+	if patch.LogLevel != "" {
+		s.LogLevel = patch.LogLevel
+	}
+	if patch.NotifyCooldown != nil {
+		s.NotifyCooldown = patch.NotifyCooldown
+	}
+	if patch.MemorySample != nil {
+		s.MemorySample = patch.MemorySample
+	}
+	if patch.HubInterval != nil {
+		s.HubInterval = patch.HubInterval
+	}
+	if patch.CheckInterval != nil {
+		s.CheckInterval = patch.CheckInterval
+	}
+	return s
+}
+
+// Store holds the current overrides and saves every change
+type Store struct {
+	path string
+
+	mu       sync.Mutex
+	settings Settings
+	// changed is closed and replaced whenever the overrides change
+	changed chan struct{}
+}
+
+// Load reads the supervisor's overrides saved in stateDir
+func Load(stateDir string) (*Store, error) {
+	return LoadFile(filepath.Join(stateDir, StateFile))
+}
+
+// LoadFile reads the overrides saved at path. A missing file means none; a
+// damaged one is an error, with an empty store to carry on with.
+func LoadFile(path string) (*Store, error) {
+	s := &Store{path: path, changed: make(chan struct{})}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read overrides: %w", err)
+	}
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return s, fmt.Errorf("ignoring damaged overrides in %s: %w", s.path, err)
+	}
+	if err := settings.Validate(); err != nil {
+		return s, fmt.Errorf("ignoring overrides in %s: %w", s.path, err)
+	}
+	s.settings = settings
+	return s, nil
+}
+
+// Get returns the current overrides; a nil store has none
+func (s *Store) Get() Settings {
+	if s == nil {
+		return Settings{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settings
+}
+
+// Changed returns a channel that is closed at the next change; a nil
+// store never changes
+func (s *Store) Changed() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// Update applies the overrides set in patch, saves the result and returns
+// it
+func (s *Store) Update(patch Settings) (Settings, error) {
+	if err := patch.Validate(); err != nil {
+		return Settings{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replace(s.settings.merge(patch))
+}
+
+// Reset removes every override, returning to the flags' values
+func (s *Store) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.replace(Settings{})
+	return err
+}
+
+// replace saves settings and makes them current. Callers hold s.mu.
+func (s *Store) replace(settings Settings) (Settings, error) {
+	if err := s.save(settings); err != nil {
+		return s.settings, err
+	}
+	s.settings = settings
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return settings, nil
+}
+
+// save writes settings via a temp file, so a crash leaves the old or the
+// new overrides
+func (s *Store) save(settings Settings) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to save overrides: %w", err)
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save overrides: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save overrides: %w", err)
+	}
+	return nil
+}
+
+// Handler serves the overrides: GET returns them, PATCH changes those set
+// in the body and DELETE removes them all. Changes need token when it is
+// set; without one they are only accepted on a loopback listener, where
+// nobody else can reach them. apply is called with the overrides after
+// each change.
+func (s *Store) Handler(token string, loopback bool, apply func(Settings)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && token == "" && !loopback {
+			writeError(w, http.StatusForbidden, "changes are refused on a listener beyond loopback without --control-token")
+			return
+		}
+		if r.Method != http.MethodGet && token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPatch:
+			var patch Settings
+			dec := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&patch); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid overrides: "+err.Error())
+				return
+			}
+			if err := patch.Validate(); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if _, err := s.Update(patch); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			apply(s.Get())
+		case http.MethodDelete:
+			if err := s.Reset(); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			apply(s.Get())
+		default:
+			w.Header().Set("Allow", "GET, PATCH, DELETE")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(s.Get())
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,127 @@
+package overrides
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get().NotifyCooldown.Or(time.Hour); got != time.Hour {
+		t.Errorf("Or() without an override = %s", got)
+	}
+
+	changed := s.Changed()
+	cooldown := Duration(10 * time.Minute)
+	if _, err := s.Update(Settings{LogLevel: "verbose", NotifyCooldown: &cooldown}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("Changed() wasn't closed by the update")
+	}
+	sample := Duration(time.Minute)
+	if _, err := s.Update(Settings{MemorySample: &sample}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The overrides outlast the process
+	again, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := again.Get()
+	if got.LogLevel != "verbose" || got.NotifyCooldown.Or(0) != 10*time.Minute || got.MemorySample.Or(0) != time.Minute || got.HubInterval != nil {
+		t.Errorf("reloaded overrides = %+v", got)
+	}
+
+	if err := again.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Load(dir); got.Get() != (Settings{}) {
+		t.Errorf("overrides after Reset = %+v", got.Get())
+	}
+
+	zero := Duration(0)
+	for _, bad := range []Settings{{LogLevel: "debug"}, {MemorySample: &zero}} {
+		if _, err := s.Update(bad); err == nil {
+			t.Errorf("Update(%+v) accepted it", bad)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, StateFile), []byte("{"), 0o644)
+	if s, err := Load(dir); err == nil || s.Get() != (Settings{}) {
+		t.Errorf("Load(damaged) = %+v, %v", s.Get(), err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, _ := Load(t.TempDir())
+	var applied []Settings
+	h := s.Handler("s3cret", false, func(st Settings) { applied = append(applied, st) })
+	do := func(method, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, Path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPatch, `{"log_level":"quiet"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("PATCH without token = %d", rec.Code)
+	}
+	rec := do(http.MethodPatch, `{"log_level":"quiet","hub_interval":"2m"}`, "s3cret")
+	var got Settings
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK || got.HubInterval.Or(0) != 2*time.Minute {
+		t.Fatalf("PATCH = %d %s", rec.Code, rec.Body)
+	}
+	if len(applied) != 1 || applied[0].LogLevel != "quiet" {
+		t.Errorf("applied = %+v", applied)
+	}
+	if rec := do(http.MethodPatch, `{"poll_interval":"1m"}`, "s3cret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("PATCH with an unknown setting = %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"hub_interval": "2m0s"`) {
+		t.Errorf("GET = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "", "s3cret"); rec.Code != http.StatusOK || s.Get() != (Settings{}) {
+		t.Errorf("DELETE = %d, left %+v", rec.Code, s.Get())
+	}
+}
+
+func TestHandler_NoToken(t *testing.T) {
+	s, _ := LoadFile(filepath.Join(t.TempDir(), MonitorStateFile))
+	do := func(h http.Handler, method, body string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, Path, strings.NewReader(body)))
+		return rec.Code
+	}
+
+	remote := s.Handler("", false, func(Settings) {})
+	for _, method := range []string{http.MethodPatch, http.MethodDelete} {
+		if code := do(remote, method, `{"check_interval":"1m"}`); code != http.StatusForbidden {
+			t.Errorf("%s beyond loopback without a token = %d, want 403", method, code)
+		}
+	}
+	if code := do(remote, http.MethodGet, ""); code != http.StatusOK {
+		t.Errorf("GET beyond loopback = %d", code)
+	}
+
+	local := s.Handler("", true, func(Settings) {})
+	if code := do(local, http.MethodPatch, `{"check_interval":"1m"}`); code != http.StatusOK || s.Get().CheckInterval.Or(0) != time.Minute {
+		t.Errorf("PATCH on loopback = %d, settings %+v", code, s.Get())
+	}
+}
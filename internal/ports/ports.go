@@ -0,0 +1,87 @@
+// Package ports detects port conflicts for the services gswarm launches and
+// selects free replacements.
+package ports
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Conflict handling modes
+const (
+	ModeAuto = "auto"
+	ModeFail = "fail"
+)
+
+// searchRange is how many consecutive ports are tried when picking a replacement
+const searchRange = 100
+
+// IsFree reports whether a TCP port can be bound on host
+func IsFree(host string, port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// FindFree returns the first free port at or after start
+func FindFree(host string, start int) (int, error) {
+	for port := start; port < start+searchRange && port <= 65535; port++ {
+		if IsFree(host, port) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port found in range %d-%d", start, start+searchRange-1)
+}
+
+// Resolve checks port and, if it is taken, either picks a free one (auto
+// mode) or returns an error naming the service (fail mode)
+func Resolve(service, host string, port int, mode string) (int, error) {
+	if IsFree(host, port) {
+		return port, nil
+	}
+	if mode == ModeFail {
+		return 0, fmt.Errorf("port %d for %s is already in use; stop the other process or use --port-conflict=auto", port, service)
+	}
+	free, err := FindFree(host, port+1)
+	if err != nil {
+		return 0, fmt.Errorf("port %d for %s is already in use and %w", port, service, err)
+	}
+	return free, nil
+}
+
+// MaddrHostPort extracts the host and TCP port from a multiaddr such as
+// /ip4/0.0.0.0/tcp/38331
+func MaddrHostPort(maddr string) (string, int, error) {
+	parts := strings.Split(strings.Trim(maddr, "/"), "/")
+	var host string
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "ip4", "ip6", "dns", "dns4", "dns6":
+			host = parts[i+1]
+		case "tcp":
+			port, err := strconv.Atoi(parts[i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid tcp port in multiaddr %s: %w", maddr, err)
+			}
+			return host, port, nil
+		}
+	}
+	return "", 0, fmt.Errorf("no tcp port in multiaddr %s", maddr)
+}
+
+// ReplaceMaddrPort returns maddr with its TCP port replaced
+func ReplaceMaddrPort(maddr string, port int) string {
+	parts := strings.Split(maddr, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "tcp" {
+			parts[i+1] = strconv.Itoa(port)
+			break
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,71 @@
+package ports
+
+import (
+	"net"
+	"testing"
+)
+
+func listen(t *testing.T) (net.Listener, int) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	return l, l.Addr().(*net.TCPAddr).Port
+}
+
+func TestResolve(t *testing.T) {
+	l, port := listen(t)
+	defer l.Close()
+
+	if IsFree("127.0.0.1", port) {
+		t.Fatalf("IsFree(%d) = true for a bound port", port)
+	}
+
+	got, err := Resolve("test", "127.0.0.1", port, ModeAuto)
+	if err != nil {
+		t.Fatalf("Resolve(auto) error = %v", err)
+	}
+	if got == port {
+		t.Errorf("Resolve(auto) = %d, want a different port", got)
+	}
+
+	if _, err := Resolve("test", "127.0.0.1", port, ModeFail); err == nil {
+		t.Error("Resolve(fail) expected error for a bound port")
+	}
+}
+
+func TestMaddrHostPort(t *testing.T) {
+	cases := []struct {
+		name     string
+		maddr    string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"host maddr", "/ip4/0.0.0.0/tcp/38331", "0.0.0.0", 38331, false},
+		{"peer maddr", "/ip4/38.101.215.13/tcp/30002/p2p/QmQ2", "38.101.215.13", 30002, false},
+		{"dns", "/dns4/example.com/tcp/443", "example.com", 443, false},
+		{"no tcp", "/ip4/1.2.3.4/udp/1234", "", 0, true},
+		{"bad port", "/ip4/1.2.3.4/tcp/abc", "", 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			host, port, err := MaddrHostPort(c.maddr)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("MaddrHostPort() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if host != c.wantHost || port != c.wantPort {
+				t.Errorf("MaddrHostPort() = %s, %d; want %s, %d", host, port, c.wantHost, c.wantPort)
+			}
+		})
+	}
+}
+
+func TestReplaceMaddrPort(t *testing.T) {
+	got := ReplaceMaddrPort("/ip4/0.0.0.0/tcp/38331", 38332)
+	if got != "/ip4/0.0.0.0/tcp/38332" {
+		t.Errorf("ReplaceMaddrPort() = %s, want /ip4/0.0.0.0/tcp/38332", got)
+	}
+}
@@ -0,0 +1,139 @@
+// Package privdrop runs the trainer as a less privileged user than the
+// supervisor. gswarm is often run as root under systemd; the trainer, which
+// executes code from the rl-swarm checkout and downloads models, doesn't
+// need to be.
+package privdrop
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// User is the account the trainer runs as
+type User struct {
+	Name   string
+	UID    uint32
+	GID    uint32
+	Groups []uint32
+	Home   string
+}
+
+// Lookup finds the user by name or numeric ID, with its supplementary groups
+func Lookup(name string) (*User, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		if _, numErr := strconv.ParseUint(name, 10, 32); numErr == nil {
+			u, err = user.LookupId(name)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unknown user %q: %w", name, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has no numeric ID", name)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has no numeric group ID", name)
+	}
+	ru := &User{Name: u.Username, UID: uint32(uid), GID: uint32(gid), Home: u.HomeDir}
+	// Group membership, e.g. in video or render for GPU access, is
+	// best-effort: not every platform can list it
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+				ru.Groups = append(ru.Groups, uint32(g))
+			}
+		}
+	}
+	return ru, nil
+}
+
+// Check reports why the supervisor can't run processes as u, if it can't.
+// Only root can switch to another user.
+func (u *User) Check() error {
+	if !Supported {
+		return fmt.Errorf("running the trainer as another user isn't supported on this platform")
+	}
+	if euid := os.Geteuid(); euid != 0 && uint32(euid) != u.UID {
+		return fmt.Errorf("running the trainer as %s requires starting gswarm as root", u.Name)
+	}
+	return nil
+}
+
+// Env returns env with the identity variables set for u, so the trainer's
+// caches and config land in its own home rather than the supervisor's
+func (u *User) Env(env []string) []string {
+	set := map[string]string{"HOME": u.Home, "USER": u.Name, "LOGNAME": u.Name}
+	out := make([]string, 0, len(env)+len(set))
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := set[key]; !ok {
+			out = append(out, kv)
+		}
+	}
+	for _, key := range []string{"HOME", "USER", "LOGNAME"} {
+		if set[key] != "" {
+			out = append(out, key+"="+set[key])
+		}
+	}
+	return out
+}
+
+// Reclaim takes back ownership of each path that exists for the
+// supervisor, recursing into directories, and removes the symlinks other
+// users own in them. Files the supervisor writes there, such as its log,
+// then can't be redirected to another file by the trainer's user.
+func Reclaim(paths ...string) error {
+	uid, gid := os.Getuid(), os.Getgid()
+	for _, root := range paths {
+		if _, err := os.Lstat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if owned(info, uint32(uid), uint32(gid)) {
+				return nil
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				return os.Remove(path)
+			}
+			return os.Lchown(path, uid, gid)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to take back ownership of %s: %w", root, err)
+		}
+	}
+	return nil
+}
+
+// Chown gives u ownership of each path that exists, recursing into
+// directories. Entries u already owns are left alone, so repeating it on
+// every start is cheap. Symlinks are changed rather than followed.
+func (u *User) Chown(paths ...string) error {
+	for _, root := range paths {
+		if _, err := os.Lstat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if owned(info, u.UID, u.GID) {
+				return nil
+			}
+			return os.Lchown(path, int(u.UID), int(u.GID))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to give %s ownership of %s: %w", u.Name, root, err)
+		}
+	}
+	return nil
+}
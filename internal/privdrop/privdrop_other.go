@@ -0,0 +1,18 @@
+//go:build !unix
+
+package privdrop
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Supported reports whether processes can be run as another user here
+const Supported = false
+
+// Apply does nothing; Check refuses other users on this platform
+func (u *User) Apply(*exec.Cmd) {}
+
+func owned(os.FileInfo, uint32, uint32) bool {
+	return true
+}
@@ -0,0 +1,89 @@
+package privdrop
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestLookup_CurrentUser(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	for _, name := range []string{current.Username, current.Uid} {
+		u, err := Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup(%q) error = %v", name, err)
+		}
+		if strconv.FormatUint(uint64(u.UID), 10) != current.Uid || u.Name != current.Username {
+			t.Errorf("Lookup(%q) = %+v, want uid %s", name, u, current.Uid)
+		}
+		if Supported {
+			if err := u.Check(); err != nil {
+				t.Errorf("Check() for the current user = %v", err)
+			}
+		}
+	}
+	if _, err := Lookup("gswarm-no-such-user"); err == nil {
+		t.Error("Lookup() of a missing user succeeded")
+	}
+}
+
+func TestUser_Env(t *testing.T) {
+	u := &User{Name: "swarm", Home: "/home/swarm"}
+	got := u.Env([]string{"HOME=/root", "PATH=/usr/bin", "USER=root"})
+	want := []string{"PATH=/usr/bin", "HOME=/home/swarm", "USER=swarm", "LOGNAME=swarm"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Env() = %v, want %v", got, want)
+	}
+}
+
+func TestUser_ChownOwned(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "venv", "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "swarm.pem"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	u := &User{Name: "self", UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}
+	if err := u.Chown(filepath.Join(dir, "venv"), filepath.Join(dir, "swarm.pem"), filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("Chown() to the owner = %v", err)
+	}
+}
+
+func TestReclaim(t *testing.T) {
+	if !Supported || os.Getuid() != 0 {
+		t.Skip("needs root to hand files to another user")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "supervisor.log")
+	link := filepath.Join(dir, "run.log")
+	if err := os.WriteFile(log, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/passwd", link); err != nil {
+		t.Fatal(err)
+	}
+	nobody := &User{Name: "nobody", UID: 65534, GID: 65534}
+	if err := nobody.Chown(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Reclaim(dir, filepath.Join(dir, "missing")); err != nil {
+		t.Fatalf("Reclaim() error = %v", err)
+	}
+	self := &User{UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}
+	for _, path := range []string{dir, log} {
+		if info, err := os.Lstat(path); err != nil || !owned(info, self.UID, self.GID) {
+			t.Errorf("%s not reclaimed: %v", path, err)
+		}
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("symlink owned by another user kept: %v", err)
+	}
+}
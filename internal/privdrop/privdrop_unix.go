@@ -0,0 +1,25 @@
+//go:build unix
+
+package privdrop
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Supported reports whether processes can be run as another user here
+const Supported = true
+
+// Apply makes cmd run as u when started
+func (u *User) Apply(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: u.UID, Gid: u.GID, Groups: u.Groups}
+}
+
+func owned(info os.FileInfo, uid, gid uint32) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Uid == uid && st.Gid == gid
+}
@@ -0,0 +1,18 @@
+// Package procctl stops the trainer and the processes it started the same
+// way on every platform: interrupt it so it can shut down cleanly, then
+// kill whatever is left of its process tree.
+//
+// On Windows, closing the console, logging off and shutting down reach Go
+// as SIGTERM, like on Unix, but the process is terminated a few seconds
+// later whether or not it has finished, so stops there get a shorter grace.
+package procctl
+
+import "time"
+
+// Grace bounds how long a stopped process gets to exit before it is killed
+func Grace(d time.Duration) time.Duration {
+	if MaxGrace > 0 && d > MaxGrace {
+		return MaxGrace
+	}
+	return d
+}
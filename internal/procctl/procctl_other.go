@@ -0,0 +1,24 @@
+//go:build !windows
+
+package procctl
+
+import (
+	"os"
+	"os/exec"
+)
+
+// MaxGrace is the longest grace a stop gets here; zero means no limit
+const MaxGrace = 0
+
+// Prepare does nothing; signals already reach a single process here
+func Prepare(*exec.Cmd) {}
+
+// Interrupt sends p SIGINT, as Ctrl-C would
+func Interrupt(p *os.Process) error {
+	return p.Signal(os.Interrupt)
+}
+
+// KillTree kills p
+func KillTree(p *os.Process) error {
+	return p.Kill()
+}
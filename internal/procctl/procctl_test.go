@@ -0,0 +1,46 @@
+package procctl
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGrace(t *testing.T) {
+	if got := Grace(time.Second); got != time.Second {
+		t.Errorf("Grace(1s) = %s", got)
+	}
+	want := time.Minute
+	if MaxGrace > 0 {
+		want = MaxGrace
+	}
+	if got := Grace(time.Minute); got != want {
+		t.Errorf("Grace(1m) = %s, want %s", got, want)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("CTRL_BREAK needs a console")
+	}
+	cmd := exec.Command("sleep", "30")
+	Prepare(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	if err := Interrupt(cmd.Process); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("interrupted process exited cleanly")
+		}
+	case <-time.After(5 * time.Second):
+		KillTree(cmd.Process)
+		t.Fatal("process ignored the interrupt")
+	}
+}
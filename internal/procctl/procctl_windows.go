@@ -0,0 +1,47 @@
+//go:build windows
+
+package procctl
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// MaxGrace keeps a stop within the five seconds Windows allows after a
+// console close or logoff
+const MaxGrace = 3 * time.Second
+
+// ctrlBreakEvent is CTRL_BREAK_EVENT, the one console event that can be
+// sent to a single process group
+const ctrlBreakEvent = 1
+
+var generateConsoleCtrlEvent = syscall.NewLazyDLL("kernel32.dll").NewProc("GenerateConsoleCtrlEvent")
+
+// Prepare starts cmd in its own process group, so Interrupt reaches it and
+// its children without interrupting gswarm
+func Prepare(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// Interrupt sends CTRL_BREAK to p's process group, which Prepare created
+func Interrupt(p *os.Process) error {
+	if r, _, err := generateConsoleCtrlEvent.Call(ctrlBreakEvent, uintptr(p.Pid)); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// KillTree kills p and its children with taskkill, as killing p alone
+// leaves them running; it falls back to killing p
+func KillTree(p *os.Process) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run(); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
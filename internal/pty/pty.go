@@ -0,0 +1,108 @@
+// Package pty runs the trainer on a pseudo-terminal, so it keeps its
+// progress bars and colours while gswarm still reads its output for the
+// run log and the error scanners. A pipe makes tqdm and Python fall back
+// to plain, buffered output.
+package pty
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// Session is a command's pseudo-terminal
+type Session struct {
+	// Master carries what the command writes to its terminal
+	Master *os.File
+	slave  *os.File
+	term   *os.File
+	stop   chan struct{}
+}
+
+// Copy copies the command's output to w until every process holding the
+// terminal has closed it
+func (s *Session) Copy(w io.Writer) error {
+	_, err := io.Copy(w, s.Master)
+	// Linux reports the other side closing as EIO rather than EOF
+	if errors.Is(err, syscall.EIO) || errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// Close stops following the terminal's size and closes the terminal
+func (s *Session) Close() error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	s.slave.Close()
+	return s.Master.Close()
+}
+
+// Plain returns a writer that passes what it is given on to w without
+// terminal escape sequences such as colours and cursor movement.
+// Sequences split across writes are handled.
+func Plain(w io.Writer) io.Writer {
+	return &plainWriter{w: w}
+}
+
+// States of plainWriter
+const (
+	stateText = iota
+	stateEscape
+	stateCSI
+	stateOSC
+	stateOSCEscape
+)
+
+type plainWriter struct {
+	w     io.Writer
+	state int
+	buf   []byte
+}
+
+func (p *plainWriter) Write(b []byte) (int, error) {
+	p.buf = p.buf[:0]
+	for _, c := range b {
+		switch p.state {
+		case stateText:
+			if c == 0x1b {
+				p.state = stateEscape
+			} else {
+				p.buf = append(p.buf, c)
+			}
+		case stateEscape:
+			switch c {
+			case '[':
+				p.state = stateCSI
+			case ']':
+				p.state = stateOSC
+			default:
+				// Two-byte sequences such as ESC 7
+				p.state = stateText
+			}
+		case stateCSI:
+			if c >= 0x40 && c <= 0x7e {
+				p.state = stateText
+			}
+		case stateOSC:
+			switch c {
+			case 0x07:
+				p.state = stateText
+			case 0x1b:
+				p.state = stateOSCEscape
+			}
+		case stateOSCEscape:
+			p.state = stateText
+		}
+	}
+	if len(p.buf) > 0 {
+		if _, err := p.w.Write(p.buf); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+package pty
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Supported reports whether commands can be run on a pseudo-terminal here
+const Supported = false
+
+// Open always fails; the trainer's output is piped on this platform
+func Open(*os.File) (*Session, error) {
+	return nil, errors.New("pseudo-terminals aren't supported on this platform")
+}
+
+// Attach does nothing, as Open never returns a session here
+func (s *Session) Attach(*exec.Cmd) {}
+
+// Started does nothing, as Open never returns a session here
+func (s *Session) Started() {}
@@ -0,0 +1,51 @@
+package pty
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	if !Supported {
+		t.Skip("no pseudo-terminals on this platform")
+	}
+	cmd := exec.Command("sh", "-c", `if [ -t 1 ] && [ -t 2 ]; then echo tty; fi; printf 'a\nb\n'; echo err >&2`)
+	s, err := Open(os.Stdout)
+	if err != nil {
+		t.Skipf("no pseudo-terminal available: %v", err)
+	}
+	defer s.Close()
+	s.Attach(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	s.Started()
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() { done <- s.Copy(&out) }()
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if got, want := out.String(), "tty\na\nb\nerr\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	var out bytes.Buffer
+	w := Plain(&out)
+	// a colour code split across writes, a cursor move and a title
+	for _, chunk := range []string{"\x1b[3", "1mred\x1b[0m ", "\x1b[2K\rbar 50%", "\x1b]0;title\x07 done", "\x1b]8;;x\x1b\\link\n"} {
+		if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if got, want := out.String(), "red \rbar 50% donelink\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+//go:build linux || darwin
+
+package pty
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// Supported reports whether commands can be run on a pseudo-terminal here
+const Supported = true
+
+type winsize struct {
+	Rows, Cols, X, Y uint16
+}
+
+// Open creates a pseudo-terminal the size of term. Attach a command to
+// it, start the command and then call Started.
+func Open(term *os.File) (*Session, error) {
+	master, slave, err := open()
+	if err != nil {
+		return nil, err
+	}
+	// Keep newlines as they are, for the run log and the scanners
+	if err := clearONLCR(slave); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, err
+	}
+	resize(master, term)
+	return &Session{Master: master, slave: slave, term: term, stop: make(chan struct{})}, nil
+}
+
+// Attach sends cmd's output to the terminal. Its stdin is left alone. It
+// runs in its own session, so a Ctrl+C in the terminal reaches gswarm,
+// which stops the trainer gracefully, rather than the trainer directly.
+func (s *Session) Attach(cmd *exec.Cmd) {
+	cmd.Stdout, cmd.Stderr = s.slave, s.slave
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 1
+}
+
+// Started closes gswarm's copy of the command's side, so Copy ends when
+// the command does, and follows the terminal when it is resized
+func (s *Session) Started() {
+	s.slave.Close()
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		defer signal.Stop(winch)
+		for {
+			select {
+			case <-winch:
+				resize(s.Master, s.term)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// resize gives the pseudo-terminal term's size
+func resize(master, term *os.File) {
+	var ws winsize
+	if ioctl(term, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)) == nil {
+		ioctl(master, syscall.TIOCSWINSZ, unsafe.Pointer(&ws))
+	}
+}
+
+func clearONLCR(f *os.File) error {
+	var t syscall.Termios
+	if err := ioctl(f, ioctlGetTermios, unsafe.Pointer(&t)); err != nil {
+		return err
+	}
+	t.Oflag &^= syscall.ONLCR
+	return ioctl(f, ioctlSetTermios, unsafe.Pointer(&t))
+}
+
+// ioctl runs an ioctl on f without switching it to blocking mode, so
+// closing the master still interrupts a pending read
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	err = conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
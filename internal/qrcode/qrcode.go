@@ -0,0 +1,426 @@
+// Package qrcode draws short texts, such as the modal-login URL, as QR codes
+// in the terminal, so a login can be finished from a phone when the node
+// has no browser.
+//
+// Only what that needs is implemented: byte mode at error correction level
+// L, versions 1 to 6, which hold up to 134 bytes.
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxLength is the longest text Encode accepts, in bytes
+const MaxLength = 134
+
+// version is the size and error correction layout of one QR version at
+// level L
+type version struct {
+	number     int
+	codewords  int // data and error correction codewords
+	ecPerBlock int
+	blocks     int
+}
+
+var versions = []version{
+	{1, 26, 7, 1},
+	{2, 44, 10, 1},
+	{3, 70, 15, 1},
+	{4, 100, 20, 1},
+	{5, 134, 26, 1},
+	{6, 172, 18, 2},
+}
+
+func (v version) dataCodewords() int {
+	return v.codewords - v.ecPerBlock*v.blocks
+}
+
+// Code is an encoded QR code
+type Code struct {
+	// Size is the number of modules per side
+	Size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// Dark reports whether the module at column x, row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode encodes text in the smallest version that holds it
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	var v version
+	for _, candidate := range versions {
+		// mode indicator and character count take two bytes
+		if len(data)+2 <= candidate.dataCodewords() {
+			v = candidate
+			break
+		}
+	}
+	if v.number == 0 {
+		return nil, fmt.Errorf("text is %d bytes; QR codes here hold at most %d", len(data), MaxLength)
+	}
+
+	c := &Code{Size: 17 + 4*v.number}
+	c.modules = grid(c.Size)
+	c.function = grid(c.Size)
+	c.drawFunctionPatterns(v)
+	c.drawCodewords(interleave(v, encodeData(v, data)))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c, nil
+}
+
+func grid(size int) [][]bool {
+	g := make([][]bool, size)
+	for i := range g {
+		g[i] = make([]bool, size)
+	}
+	return g
+}
+
+// encodeData builds the data codewords: byte mode, the length, the text,
+// a terminator and padding
+func encodeData(v version, data []byte) []byte {
+	var bits []bool
+	put := func(val, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, val>>i&1 == 1)
+		}
+	}
+	put(0b0100, 4)
+	put(len(data), 8)
+	for _, b := range data {
+		put(int(b), 8)
+	}
+	capacity := v.dataCodewords() * 8
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		put(pad, 8)
+	}
+
+	out := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+// interleave splits data into blocks, adds each block's error correction
+// and interleaves the result
+func interleave(v version, data []byte) []byte {
+	per := len(data) / v.blocks
+	divisor := rsDivisor(v.ecPerBlock)
+	var blocks, ecs [][]byte
+	for i := 0; i < v.blocks; i++ {
+		block := data[i*per : (i+1)*per]
+		blocks = append(blocks, block)
+		ecs = append(ecs, rsRemainder(block, divisor))
+	}
+	var out []byte
+	for i := 0; i < per; i++ {
+		for _, b := range blocks {
+			out = append(out, b[i])
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, ec := range ecs {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns(v version) {
+	for i := 0; i < c.Size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+	// Versions 2 to 6 have one alignment pattern, in the bottom right
+	if v.number > 1 {
+		c.drawAlignment(c.Size-7, c.Size-7)
+	}
+	// Reserve the format areas until the mask is chosen
+	c.drawFormat(0)
+}
+
+// drawFinder draws a finder pattern and its separator around the center
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.set(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// formatBits returns the 15 format bits for level L and mask
+func formatBits(mask int) int {
+	data := 1<<3 | mask // level L is 01
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawFormat writes both copies of the format bits and the dark module
+func (c *Code) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true)
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at a
+// time from the bottom right, skipping function modules
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.function[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.modules[y][x] = data[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by mask; applying it twice
+// undoes it
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code is to scan, by the four rules of the
+// standard: long runs, 2x2 blocks, finder-like patterns and imbalance
+func (c *Code) penalty() int {
+	score := 0
+	finderLike := []string{"10111010000", "00001011101"}
+	for _, horizontal := range []bool{true, false} {
+		for a := 0; a < c.Size; a++ {
+			var line strings.Builder
+			for b := 0; b < c.Size; b++ {
+				x, y := b, a
+				if !horizontal {
+					x, y = a, b
+				}
+				if c.modules[y][x] {
+					line.WriteByte('1')
+				} else {
+					line.WriteByte('0')
+				}
+			}
+			s := line.String()
+			run := 1
+			for i := 1; i <= len(s); i++ {
+				if i < len(s) && s[i] == s[i-1] {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			for _, p := range finderLike {
+				for i := 0; i+len(p) <= len(s); i++ {
+					if s[i:i+len(p)] == p {
+						score += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				m := c.modules[y][x]
+				if c.modules[y][x+1] == m && c.modules[y+1][x] == m && c.modules[y+1][x+1] == m {
+					score += 3
+				}
+			}
+		}
+	}
+	percent := dark * 100 / (c.Size * c.Size)
+	return score + abs(percent-50)/5*10
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the degree,
+// leading term omitted
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMul(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Terminal renders the code with a quiet zone for a terminal, two rows of
+// modules per line using half blocks. Colors are set explicitly so it
+// scans on dark and light themes alike.
+func (c *Code) Terminal() string {
+	const quiet = 2
+	dark := func(x, y int) bool {
+		x, y = x-quiet, y-quiet
+		return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y][x]
+	}
+	color := func(isDark bool, fg bool) string {
+		switch {
+		case isDark && fg:
+			return "30"
+		case isDark:
+			return "40"
+		case fg:
+			return "97"
+		default:
+			return "107"
+		}
+	}
+	total := c.Size + 2*quiet
+	var b strings.Builder
+	for y := 0; y < total; y += 2 {
+		for x := 0; x < total; x++ {
+			fmt.Fprintf(&b, "\033[%s;%sm▀", color(dark(x, y), true), color(dark(x, y+1), false))
+		}
+		b.WriteString("\033[0m\n")
+	}
+	return b.String()
+}
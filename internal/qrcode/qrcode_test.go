@@ -0,0 +1,160 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// HELLO WORLD at 1-M, from the worked example in the QR standard's
+	// tutorials
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	want := map[int]int{0: 0b111011111000100, 4: 0b110011000101111, 7: 0b110100101110110}
+	for mask, bits := range want {
+		if got := formatBits(mask); got != bits {
+			t.Errorf("formatBits(%d) = %015b, want %015b", mask, got, bits)
+		}
+	}
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	for _, text := range []string{
+		"http://localhost:3000",
+		"http://203.0.113.7:3000/?node=gpu-box-1",
+		strings.Repeat("x", 100),
+		strings.Repeat("y", MaxLength),
+	} {
+		c, err := Encode(text)
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", len(text), err)
+		}
+		if got := decode(t, c); got != text {
+			t.Errorf("decoded %q, want %q", got, text)
+		}
+	}
+	if _, err := Encode(strings.Repeat("z", MaxLength+1)); err == nil {
+		t.Error("Encode accepted a text that doesn't fit")
+	}
+}
+
+func TestEncode_Patterns(t *testing.T) {
+	c, err := Encode("http://localhost:3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size != 25 {
+		t.Errorf("Size = %d, want version 2's 25", c.Size)
+	}
+	// finder corners are dark, their separators light
+	for _, p := range [][2]int{{0, 0}, {c.Size - 1, 0}, {0, c.Size - 1}} {
+		if !c.Dark(p[0], p[1]) {
+			t.Errorf("finder corner %v is light", p)
+		}
+	}
+	if c.Dark(7, 0) || c.Dark(0, 7) || c.Dark(c.Size-8, 0) {
+		t.Error("finder separator is dark")
+	}
+	for i := 8; i < c.Size-8; i++ {
+		if c.Dark(i, 6) != (i%2 == 0) || c.Dark(6, i) != (i%2 == 0) {
+			t.Errorf("timing pattern wrong at %d", i)
+		}
+	}
+	if !strings.Contains(c.Terminal(), "▀") {
+		t.Error("Terminal() drew nothing")
+	}
+}
+
+// decode reads c back the way a scanner would: the format bits give the
+// mask, the codewords are read in placement order, and each block's error
+// correction is checked before the text is parsed
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	bits := 0
+	for i := 0; i <= 5; i++ {
+		bits |= b2i(c.Dark(8, i)) << i
+	}
+	bits |= b2i(c.Dark(8, 7))<<6 | b2i(c.Dark(8, 8))<<7 | b2i(c.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		bits |= b2i(c.Dark(14-i, 8)) << i
+	}
+	mask := -1
+	for m := 0; m < 8; m++ {
+		if formatBits(m) == bits {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("unreadable format bits %015b", bits)
+	}
+
+	var v version
+	for _, candidate := range versions {
+		if 17+4*candidate.number == c.Size {
+			v = candidate
+		}
+	}
+	c.applyMask(mask)
+	defer c.applyMask(mask)
+	raw := make([]byte, v.codewords)
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.function[y][x] || i >= len(raw)*8 {
+					continue
+				}
+				if c.modules[y][x] {
+					raw[i/8] |= 1 << (7 - i%8)
+				}
+				i++
+			}
+		}
+	}
+
+	per := v.dataCodewords() / v.blocks
+	var data []byte
+	for blk := 0; blk < v.blocks; blk++ {
+		var block, ec []byte
+		for k := 0; k < per; k++ {
+			block = append(block, raw[k*v.blocks+blk])
+		}
+		for k := 0; k < v.ecPerBlock; k++ {
+			ec = append(ec, raw[per*v.blocks+k*v.blocks+blk])
+		}
+		if !bytes.Equal(rsRemainder(block, rsDivisor(v.ecPerBlock)), ec) {
+			t.Fatalf("block %d fails its error correction check", blk)
+		}
+		data = append(data, block...)
+	}
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", data[0]>>4)
+	}
+	n := int(data[0]&0x0F)<<4 | int(data[1]>>4)
+	out := make([]byte, n)
+	for k := 0; k < n; k++ {
+		out[k] = data[1+k]<<4 | data[2+k]>>4
+	}
+	return string(out)
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,104 @@
+// Package redact masks secrets such as API tokens before they reach the
+// console, log files or notifications. Secrets are registered once they
+// are known, and token formats gswarm handles are masked even when they
+// weren't registered, e.g. a bot token inside an HTTP error's URL.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every secret
+const Mask = "[REDACTED]"
+
+// minLength keeps short values such as "0" or "None" from being treated as
+// secrets and masking unrelated output
+const minLength = 8
+
+var (
+	mu      sync.RWMutex
+	secrets = map[string]struct{}{}
+	// replacer is rebuilt when secrets change; nil when there are none
+	replacer *strings.Replacer
+)
+
+// patterns match well-known token formats
+var patterns = []*regexp.Regexp{
+	// HuggingFace access tokens
+	regexp.MustCompile(`\bhf_[A-Za-z0-9]{20,}`),
+	// Telegram bot tokens, including inside api.telegram.org/bot<token>/ URLs
+	regexp.MustCompile(`[0-9]{6,12}:[A-Za-z0-9_-]{30,}`),
+	// Matrix access tokens
+	regexp.MustCompile(`\bsy[tc]_[A-Za-z0-9_=-]{20,}`),
+}
+
+// Add registers values as secrets. Empty and very short values are ignored.
+func Add(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	changed := false
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < minLength {
+			continue
+		}
+		if _, ok := secrets[v]; !ok {
+			secrets[v] = struct{}{}
+			changed = true
+		}
+	}
+	if changed {
+		rebuild()
+	}
+}
+
+// rebuild builds the replacer with longer secrets first, so a secret that
+// contains another is masked whole
+func rebuild() {
+	list := make([]string, 0, len(secrets))
+	for s := range secrets {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+	pairs := make([]string, 0, 2*len(list))
+	for _, s := range list {
+		pairs = append(pairs, s, Mask)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// String masks registered secrets and known token formats in s
+func String(s string) string {
+	mu.RLock()
+	r := replacer
+	mu.RUnlock()
+	if r != nil {
+		s = r.Replace(s)
+	}
+	for _, p := range patterns {
+		s = p.ReplaceAllString(s, Mask)
+	}
+	return s
+}
+
+// Writer returns a writer that masks secrets in everything written to w.
+// Each write is masked on its own, which suits line-oriented writers such
+// as log.Logger.
+func Writer(w io.Writer) io.Writer {
+	return &writer{w: w}
+}
+
+type writer struct {
+	w io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,198 @@
+// Package report builds per-run training summaries for the supervisor.
+package report
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+	"github.com/Deep-Commit/gswarm/internal/lines"
+	"github.com/Deep-Commit/gswarm/internal/timefmt"
+)
+
+// Exit reasons recorded in run reports
+const (
+	ExitClean    = "clean exit"
+	ExitError    = "error"
+	ExitHung     = "hung"
+	ExitShutdown = "shutdown"
+	ExitPaused   = "paused by schedule"
+	ExitSwitched = "switched swarms"
+	ExitAlert    = "stopped by alert"
+)
+
+// NewRunID returns the ID of a run started at start: the start time, as run
+// log names have always had it, and a random suffix so that runs of
+// several nodes reporting to one chat, or a run started after the clock
+// went back, never share an ID. Should the system have no randomness to
+// give, the suffix comes from the start's nanoseconds and the process ID.
+func NewRunID(start time.Time) string {
+	var suffix [3]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		n := uint32(start.Nanosecond()) ^ uint32(os.Getpid())<<4
+		suffix = [3]byte{byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+	return start.Format("20060102-150405") + "-" + hex.EncodeToString(suffix[:])
+}
+
+// RunReport summarizes a single training run
+type RunReport struct {
+	RunNumber  int           `json:"run_number"`
+	RunID      string        `json:"run_id,omitempty"`
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	Duration   time.Duration `json:"duration"`
+	ExitReason string        `json:"exit_reason"`
+	ExitCode   int           `json:"exit_code"`
+	Error      string        `json:"error,omitempty"`
+	// Rounds is the number of rounds completed, or -1 when the trainer
+	// output wasn't observed
+	Rounds int `json:"rounds"`
+	// CutLines is the number of output lines too long to scan whole, such
+	// as tracebacks printing tensors; only their start was checked
+	CutLines int `json:"cut_lines,omitempty"`
+	// RewardsDelta is the change in total rewards during the run, when known
+	RewardsDelta *big.Int `json:"rewards_delta,omitempty"`
+	// LogFile is the run's captured output, if it was written
+	LogFile string `json:"log_file,omitempty"`
+	// Versions is the gswarm build and rl-swarm checkout that ran
+	Versions string `json:"versions,omitempty"`
+	// PeakMemory is the most RAM and VRAM the run used, when sampled
+	PeakMemory string `json:"peak_memory,omitempty"`
+	// Diagnosis explains known errors seen during the run
+	Diagnosis string `json:"diagnosis,omitempty"`
+	// LogTail is the end of the trainer's output for a failed run, for
+	// notifications; the journal has the run log for that
+	LogTail []string `json:"-"`
+	// Format controls how rewards are shown in Text
+	Format humanize.Format `json:"-"`
+	// Time controls how the start and end are shown in Text
+	Time timefmt.Formatter `json:"-"`
+}
+
+// Text renders the report as a human-readable summary
+func (r RunReport) Text() string {
+	var b strings.Builder
+	if r.RunID != "" {
+		fmt.Fprintf(&b, "Run #%d (%s) finished: %s\n", r.RunNumber, r.RunID, r.ExitReason)
+	} else {
+		fmt.Fprintf(&b, "Run #%d finished: %s\n", r.RunNumber, r.ExitReason)
+	}
+	fmt.Fprintf(&b, "Duration: %s (%s – %s)\n", r.Duration.Round(time.Second),
+		r.Time.Format(r.Start), r.Time.Format(r.End))
+	if r.Rounds >= 0 {
+		fmt.Fprintf(&b, "Rounds completed: %d\n", r.Rounds)
+	} else {
+		b.WriteString("Rounds completed: unknown\n")
+	}
+	if r.RewardsDelta != nil {
+		fmt.Fprintf(&b, "Rewards during run: %s\n", r.Format.Delta(new(big.Int), r.RewardsDelta))
+	}
+	if r.ExitCode != 0 {
+		fmt.Fprintf(&b, "Exit code: %d\n", r.ExitCode)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", r.Error)
+	}
+	if r.PeakMemory != "" {
+		fmt.Fprintf(&b, "Peak memory: %s\n", r.PeakMemory)
+	}
+	if r.CutLines > 0 {
+		fmt.Fprintf(&b, "Output lines cut at %d KiB: %d\n", lines.DefaultMax/1024, r.CutLines)
+	}
+	if r.LogFile != "" {
+		fmt.Fprintf(&b, "Log: %s\n", r.LogFile)
+	}
+	if r.Diagnosis != "" {
+		fmt.Fprintf(&b, "\n%s\n", r.Diagnosis)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Tail joins the last lines of LogTail that fit in max bytes, for chat
+// services that limit message length
+func (r RunReport) Tail(max int) string {
+	size := 0
+	start := len(r.LogTail)
+	for start > 0 && size+len(r.LogTail[start-1])+1 <= max {
+		start--
+		size += len(r.LogTail[start]) + 1
+	}
+	return strings.Join(r.LogTail[start:], "\n")
+}
+
+// roundPattern matches the round announcements printed by the trainer,
+// e.g. "Starting round: 1234/1000000" or "round 1234"
+var roundPattern = regexp.MustCompile(`(?i)\bround[:\s]+(\d+)`)
+
+// RoundCounter is an io.Writer that watches trainer output for round numbers
+type RoundCounter struct {
+	// OnRound, if set, is called with each new highest round number
+	OnRound func(round int)
+
+	mu    sync.Mutex
+	lines *lines.Splitter
+	first int
+	last  int
+	seen  bool
+}
+
+// Write implements io.Writer
+func (c *RoundCounter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	before := c.last
+	if c.lines == nil {
+		c.lines = lines.New(c.scan)
+	}
+	c.lines.Write(p)
+	last := c.last
+	c.mu.Unlock()
+
+	if last != before && c.OnRound != nil {
+		c.OnRound(last)
+	}
+	return len(p), nil
+}
+
+func (c *RoundCounter) scan(line []byte) {
+	m := roundPattern.FindSubmatch(line)
+	if m == nil {
+		return
+	}
+	n, err := strconv.Atoi(string(m[1]))
+	if err != nil {
+		return
+	}
+	if !c.seen {
+		c.first = n
+		c.seen = true
+	}
+	if n > c.last {
+		c.last = n
+	}
+}
+
+// Last returns the highest round number seen, or 0 if none
+func (c *RoundCounter) Last() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// Rounds returns the number of rounds completed since the first one seen
+func (c *RoundCounter) Rounds() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.seen {
+		return 0
+	}
+	return c.last - c.first
+}
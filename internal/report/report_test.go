@@ -0,0 +1,93 @@
+package report
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundCounter(t *testing.T) {
+	cases := []struct {
+		name   string
+		chunks []string
+		want   int
+	}{
+		{"no rounds", []string{"loading model\n", "done\n"}, 0},
+		{"several rounds", []string{"Starting round: 10/100\n", "Starting round: 11/100\nStarting round: 13/100\n"}, 3},
+		{"split across writes", []string{"Starting rou", "nd: 5\nround 9\n"}, 4},
+		{"carriage returns", []string{"round 1\rround 2\r"}, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var rc RoundCounter
+			for _, chunk := range c.chunks {
+				rc.Write([]byte(chunk))
+			}
+			if got := rc.Rounds(); got != c.want {
+				t.Errorf("Rounds() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRoundCounter_OnRound(t *testing.T) {
+	var seen []int
+	rc := RoundCounter{OnRound: func(n int) { seen = append(seen, n) }}
+	rc.Write([]byte("round 4\nround 4\n"))
+	rc.Write([]byte("loading\nround 5\n"))
+
+	if len(seen) != 2 || seen[0] != 4 || seen[1] != 5 {
+		t.Errorf("OnRound calls = %v, want [4 5]", seen)
+	}
+	if rc.Last() != 5 {
+		t.Errorf("Last() = %d, want 5", rc.Last())
+	}
+}
+
+func TestRunReport_Text(t *testing.T) {
+	start := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	r := RunReport{
+		RunNumber:    3,
+		RunID:        "20250701-100000-3f9a2c",
+		Start:        start,
+		End:          start.Add(90 * time.Minute),
+		Duration:     90 * time.Minute,
+		ExitReason:   ExitError,
+		ExitCode:     1,
+		Rounds:       -1,
+		RewardsDelta: big.NewInt(12),
+		LogFile:      "logs/run-20250701-100000.log",
+		Diagnosis:    "Disk full: The disk filled up.",
+	}
+
+	text := r.Text()
+	for _, want := range []string{"Run #3 (20250701-100000-3f9a2c) finished: error", "1h30m0s", "Rounds completed: unknown", "+12", "Exit code: 1", "Log: logs/run-20250701-100000.log", "\n\nDisk full: The disk filled up."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() = %q, want it to contain %q", text, want)
+		}
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	start := time.Date(2025, 7, 1, 10, 0, 0, 0, time.Local)
+	a, b := NewRunID(start), NewRunID(start)
+	if !regexp.MustCompile(`^20250701-100000-[0-9a-f]{6}$`).MatchString(a) {
+		t.Errorf("NewRunID() = %q", a)
+	}
+	if a == b {
+		t.Errorf("two runs started in the same second share ID %q", a)
+	}
+}
+
+func TestRunReport_Tail(t *testing.T) {
+	r := RunReport{LogTail: []string{"aaaa", "bbbb", "cccc"}}
+	if got := r.Tail(10); got != "bbbb\ncccc" {
+		t.Errorf("Tail(10) = %q, want the last two lines", got)
+	}
+	if got := r.Tail(3); got != "" {
+		t.Errorf("Tail(3) = %q, want nothing", got)
+	}
+}
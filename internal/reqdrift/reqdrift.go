@@ -0,0 +1,173 @@
+// Package reqdrift records what was installed into the trainer's virtual
+// environment and detects when the requirements file or the installed
+// packages have changed since, e.g. after an upstream rl-swarm update.
+package reqdrift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/atomicfile"
+)
+
+// FileName is the install record inside the state directory
+const FileName = "requirements.json"
+
+// DecisionFile holds the answer to the last reinstall request inside the
+// state directory
+const DecisionFile = "requirements-decision.json"
+
+// Drift handling modes
+const (
+	ModeAuto   = "auto"   // reinstall before restarting the trainer
+	ModePrompt = "prompt" // ask before reinstalling
+	ModeWarn   = "warn"   // only report the drift
+)
+
+// Install records a requirements install
+type Install struct {
+	RequirementsFile string    `json:"requirements_file"`
+	RequirementsHash string    `json:"requirements_hash"`
+	PackagesHash     string    `json:"packages_hash"`
+	InstalledAt      time.Time `json:"installed_at"`
+}
+
+// NewInstall records that requirementsFile was installed, leaving the
+// packages listed by pip freeze
+func NewInstall(requirementsFile string, freeze []byte) (Install, error) {
+	hash, err := HashFile(requirementsFile)
+	if err != nil {
+		return Install{}, err
+	}
+	return Install{
+		RequirementsFile: requirementsFile,
+		RequirementsHash: hash,
+		PackagesHash:     HashPackages(freeze),
+		InstalledAt:      time.Now(),
+	}, nil
+}
+
+// HashFile returns the SHA-256 of a file
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HashPackages hashes pip freeze output independent of line order, case
+// and blank lines
+func HashPackages(freeze []byte) string {
+	var lines []string
+	for _, line := range strings.Split(string(freeze), "\n") {
+		if line = strings.ToLower(strings.TrimSpace(line)); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Load reads the last install record from stateDir
+func Load(stateDir string) (*Install, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	var in Install
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+	return &in, nil
+}
+
+// Save writes the install record to stateDir
+func (in Install) Save(stateDir string) error {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteJSON(filepath.Join(stateDir, FileName), in, 0o644)
+}
+
+// Check compares the recorded install with the current requirements file
+// and pip freeze output, returning why a reinstall is needed, or nil when
+// nothing changed
+func Check(stateDir, requirementsFile string, freeze []byte) ([]string, error) {
+	last, err := Load(stateDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{"no requirements install has been recorded"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+	if last.RequirementsFile != requirementsFile {
+		reasons = append(reasons, fmt.Sprintf("requirements file changed from %s to %s", last.RequirementsFile, requirementsFile))
+	} else {
+		hash, err := HashFile(requirementsFile)
+		if err != nil {
+			return nil, err
+		}
+		if hash != last.RequirementsHash {
+			reasons = append(reasons, fmt.Sprintf("%s changed since it was installed", requirementsFile))
+		}
+	}
+	if freeze != nil && HashPackages(freeze) != last.PackagesHash {
+		reasons = append(reasons, "installed packages changed since the last install")
+	}
+	return reasons, nil
+}
+
+// Decision is an answer to whether to reinstall, which stands until the
+// requirements or the installed packages change again
+type Decision struct {
+	Key       string    `json:"key"`
+	Reinstall bool      `json:"reinstall"`
+	At        time.Time `json:"at"`
+}
+
+// Key identifies requirementsFile's contents and the packages in freeze,
+// which a Decision is for
+func Key(requirementsFile string, freeze []byte) (string, error) {
+	in, err := NewInstall(requirementsFile, freeze)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(in.RequirementsFile + "\n" + in.RequirementsHash + "\n" + in.PackagesHash))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// LoadDecision returns the answer recorded in stateDir for key, if there is
+// one
+func LoadDecision(stateDir, key string) (Decision, bool) {
+	data, err := os.ReadFile(filepath.Join(stateDir, DecisionFile))
+	if err != nil {
+		return Decision{}, false
+	}
+	var d Decision
+	if err := json.Unmarshal(data, &d); err != nil || d.Key != key {
+		return Decision{}, false
+	}
+	return d, true
+}
+
+// Save records the answer in stateDir, replacing the previous one
+// atomically: the supervisor and a background approval may both save
+func (d Decision) Save(stateDir string) error {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteJSON(filepath.Join(stateDir, DecisionFile), d, 0o644)
+}
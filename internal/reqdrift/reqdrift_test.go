@@ -0,0 +1,100 @@
+package reqdrift
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	stateDir := t.TempDir()
+	req := filepath.Join(t.TempDir(), "requirements-gpu.txt")
+	other := filepath.Join(filepath.Dir(req), "requirements-cpu.txt")
+	for _, p := range []string{req, other} {
+		if err := os.WriteFile(p, []byte("torch==2.5.1\ntrl\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	freeze := []byte("torch==2.5.1\ntrl==0.14.0\n")
+
+	reasons, err := Check(stateDir, req, freeze)
+	if err != nil || len(reasons) != 1 || !strings.Contains(reasons[0], "no requirements install") {
+		t.Fatalf("Check() before any install = %v, %v", reasons, err)
+	}
+
+	in, err := NewInstall(req, freeze)
+	if err != nil {
+		t.Fatalf("NewInstall() error = %v", err)
+	}
+	if err := in.Save(stateDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		setup  func()
+		file   string
+		freeze []byte
+		want   []string
+	}{
+		{"unchanged, reordered freeze", func() {}, req, []byte("TRL==0.14.0\n\ntorch==2.5.1\n"), nil},
+		{"freeze skipped", func() {}, req, nil, nil},
+		{"other file", func() {}, other, freeze, []string{"requirements file changed"}},
+		{"packages changed", func() {}, req, []byte("torch==2.6.0\ntrl==0.14.0\n"), []string{"installed packages changed"}},
+		{"requirements changed", func() {
+			os.WriteFile(req, []byte("torch==2.6.0\ntrl\n"), 0o644)
+		}, req, freeze, []string{"changed since it was installed"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.setup()
+			reasons, err := Check(stateDir, c.file, c.freeze)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if len(reasons) != len(c.want) {
+				t.Fatalf("Check() = %v, want %d reasons", reasons, len(c.want))
+			}
+			for i, want := range c.want {
+				if !strings.Contains(reasons[i], want) {
+					t.Errorf("reason %d = %q, want it to contain %q", i, reasons[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecision(t *testing.T) {
+	stateDir := t.TempDir()
+	req := filepath.Join(t.TempDir(), "requirements-gpu.txt")
+	os.WriteFile(req, []byte("torch==2.5.1\n"), 0o644)
+	freeze := []byte("torch==2.5.1\n")
+
+	key, err := Key(req, freeze)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LoadDecision(stateDir, key); ok {
+		t.Fatal("LoadDecision() found an answer before any was saved")
+	}
+	if err := (Decision{Key: key, Reinstall: false}).Save(stateDir); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := LoadDecision(stateDir, key); !ok || d.Reinstall {
+		t.Errorf("LoadDecision() = %+v, %v", d, ok)
+	}
+
+	// A new drift needs a new answer
+	other, _ := Key(req, []byte("torch==2.6.0\n"))
+	os.WriteFile(req, []byte("torch==2.6.0\n"), 0o644)
+	changed, _ := Key(req, freeze)
+	for _, k := range []string{other, changed} {
+		if k == key {
+			t.Error("Key() didn't change with the requirements or packages")
+		}
+		if _, ok := LoadDecision(stateDir, k); ok {
+			t.Error("LoadDecision() applied an answer to other requirements")
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package restart decides how long the supervisor waits before restarting
+// a trainer that exited with an error. A trainer that dies right after
+// launch usually has a configuration or environment problem that a
+// restart won't fix, so those restarts back off quickly and raise an
+// alert, while a crash after a long run is restarted straight away.
+package restart
+
+import "time"
+
+// Policy defaults
+const (
+	DefaultStartupWindow = 30 * time.Second
+	DefaultStableAfter   = time.Hour
+	DefaultAlertAfter    = 3
+)
+
+// Backoff steps for crashes in the middle of a run, and for startup
+// failures, which grow faster and further
+const (
+	crashInitial   = 5 * time.Second
+	crashMax       = 5 * time.Minute
+	startupInitial = 10 * time.Second
+	startupMax     = 30 * time.Minute
+	startupFactor  = 4
+)
+
+// MaxDelay is the longest delay the policy asks for
+const MaxDelay = startupMax
+
+// Kind classifies a failed run by how long it lasted
+type Kind int
+
+const (
+	// StartupFailure is a run that died within the startup window
+	StartupFailure Kind = iota
+	// Crash is a run that failed after starting up
+	Crash
+	// StableCrash is a run that failed after training for a long time
+	StableCrash
+)
+
+func (k Kind) String() string {
+	switch k {
+	case StartupFailure:
+		return "startup failure"
+	case StableCrash:
+		return "crash after a long run"
+	default:
+		return "crash"
+	}
+}
+
+// Decision is what to do after a failed run
+type Decision struct {
+	Kind  Kind
+	Delay time.Duration
+	// StartupFailures counts startup failures in a row
+	StartupFailures int
+	// Alert is set once startup failures reach the policy's AlertAfter
+	Alert bool
+}
+
+// Policy tracks failed runs. The zero value uses the defaults.
+type Policy struct {
+	// StartupWindow is how soon after launch a failure counts as a
+	// startup failure
+	StartupWindow time.Duration
+	// StableAfter is how long a run must last to be restarted at once
+	StableAfter time.Duration
+	// AlertAfter is the number of startup failures in a row that raise
+	// an alert
+	AlertAfter int
+
+	startupFailures int
+	crashes         int
+}
+
+// Failed records a run that failed after lasting runtime and returns how
+// to restart it
+func (p *Policy) Failed(runtime time.Duration) Decision {
+	window, stable, alertAfter := p.StartupWindow, p.StableAfter, p.AlertAfter
+	if window <= 0 {
+		window = DefaultStartupWindow
+	}
+	if stable <= 0 {
+		stable = DefaultStableAfter
+	}
+	if alertAfter <= 0 {
+		alertAfter = DefaultAlertAfter
+	}
+
+	switch {
+	case runtime < window:
+		p.crashes = 0
+		p.startupFailures++
+		delay := startupInitial
+		for i := 1; i < p.startupFailures && delay < startupMax; i++ {
+			delay *= startupFactor
+		}
+		return Decision{
+			Kind:            StartupFailure,
+			Delay:           shorter(delay, startupMax),
+			StartupFailures: p.startupFailures,
+			Alert:           p.startupFailures == alertAfter,
+		}
+	case runtime >= stable:
+		p.Reset()
+		return Decision{Kind: StableCrash}
+	default:
+		p.startupFailures = 0
+		p.crashes++
+		delay := crashInitial
+		for i := 1; i < p.crashes && delay < crashMax; i++ {
+			delay *= 2
+		}
+		return Decision{Kind: Crash, Delay: shorter(delay, crashMax)}
+	}
+}
+
+// Reset forgets earlier failures, e.g. after a clean exit
+func (p *Policy) Reset() {
+	p.startupFailures, p.crashes = 0, 0
+}
+
+func shorter(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,103 @@
+package restart
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPolicy(t *testing.T) {
+	var p Policy
+	steps := []struct {
+		runtime   time.Duration
+		wantKind  Kind
+		wantDelay time.Duration
+		wantAlert bool
+	}{
+		{5 * time.Second, StartupFailure, 10 * time.Second, false},
+		{5 * time.Second, StartupFailure, 40 * time.Second, false},
+		{5 * time.Second, StartupFailure, 160 * time.Second, true},
+		{5 * time.Second, StartupFailure, 640 * time.Second, false},
+		{5 * time.Second, StartupFailure, 30 * time.Minute, false},
+		{10 * time.Minute, Crash, 5 * time.Second, false},
+		{10 * time.Minute, Crash, 10 * time.Second, false},
+		{3 * time.Hour, StableCrash, 0, false},
+		{10 * time.Minute, Crash, 5 * time.Second, false},
+		{5 * time.Second, StartupFailure, 10 * time.Second, false},
+	}
+	for i, s := range steps {
+		d := p.Failed(s.runtime)
+		if d.Kind != s.wantKind || d.Delay != s.wantDelay || d.Alert != s.wantAlert {
+			t.Errorf("step %d: Failed(%s) = %+v, want %s after %s, alert %v", i, s.runtime, d, s.wantKind, s.wantDelay, s.wantAlert)
+		}
+	}
+}
+
+func TestPolicy_Reset(t *testing.T) {
+	p := Policy{StartupWindow: time.Minute, AlertAfter: 1}
+	if d := p.Failed(50 * time.Second); !d.Alert || d.Kind != StartupFailure {
+		t.Fatalf("Failed() = %+v, want an alerting startup failure", d)
+	}
+	p.Reset()
+	if d := p.Failed(50 * time.Second); d.StartupFailures != 1 || d.Delay != startupInitial {
+		t.Errorf("Failed() after Reset = %+v, want a first startup failure", d)
+	}
+}
+
+func TestState_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	if s, err := LoadState(dir); err != nil || s.RunNumber != 0 {
+		t.Fatalf("LoadState() of an empty dir = %+v, %v", s, err)
+	}
+
+	var p Policy
+	p.Failed(time.Second)
+	p.Failed(time.Second)
+	now := time.Now()
+	want := State{RunNumber: 7, LastRunID: "20250102-150405", Restarts: 2, NextStart: now.Add(time.Minute).UTC(), Updated: now.UTC()}
+	p.Export(&want)
+	if err := SaveState(dir, want); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	got, err := LoadState(dir)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if got.RunNumber != 7 || got.StartupFailures != 2 || !got.NextStart.Equal(want.NextStart) || got.LastRunID != want.LastRunID {
+		t.Errorf("LoadState() = %+v, want %+v", got, want)
+	}
+
+	// The restored policy continues the backoff: a third startup failure
+	var restored Policy
+	if !restored.Restore(got, now.Add(time.Minute)) {
+		t.Fatal("Restore() ignored a recent state")
+	}
+	if d := restored.Failed(time.Second); d.StartupFailures != 3 || d.Delay != 160*time.Second {
+		t.Errorf("Failed() after Restore = %+v, want the third startup failure", d)
+	}
+
+	var stale Policy
+	if stale.Restore(got, now.Add(2*time.Hour)) {
+		t.Error("Restore() used a state older than StableAfter")
+	}
+	if d := stale.Failed(time.Second); d.StartupFailures != 1 {
+		t.Errorf("Failed() after a stale Restore = %+v, want a first startup failure", d)
+	}
+
+	// saved while the clock was ahead
+	var ahead Policy
+	if ahead.Restore(got, now.Add(-3*time.Hour)) {
+		t.Error("Restore() used a state saved in the future")
+	}
+}
+
+func TestLoadState_Damaged(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, StateFile), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := LoadState(dir); err != nil || s.RunNumber != 0 {
+		t.Errorf("LoadState() of a damaged file = %+v, %v, want the zero state", s, err)
+	}
+}
@@ -0,0 +1,100 @@
+package restart
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StateFile holds the supervisor's restart state in the state directory
+const StateFile = "supervisor_state.json"
+
+// State is the supervisor's restart bookkeeping. It is saved as runs start
+// and end, so a gswarm restarted by hand or by systemd after a reboot keeps
+// numbering runs and backing off where the previous process left off
+// instead of hammering the network with fresh fast restarts.
+type State struct {
+	RunNumber int `json:"run_number"`
+	// LastRunID is the ID of the latest run, as in its log name
+	LastRunID         string `json:"last_run_id,omitempty"`
+	Restarts          int    `json:"restarts"`
+	StartupFailures   int    `json:"startup_failures"`
+	Crashes           int    `json:"crashes"`
+	IdentityConflicts int    `json:"identity_conflicts"`
+	// NextStart is when a restart that was backing off is due
+	NextStart time.Time `json:"next_start,omitempty"`
+	Updated   time.Time `json:"updated"`
+}
+
+// LoadState reads the state saved in stateDir. A missing or damaged file
+// gives the zero State, as for a first start.
+func LoadState(stateDir string) (State, error) {
+	var s State
+	data, err := os.ReadFile(filepath.Join(stateDir, StateFile))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read supervisor state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return State{}, nil
+	}
+	return s, nil
+}
+
+// SaveState writes s to stateDir. The file is synced before it replaces
+// the previous one, so a power loss leaves either the old or the new state.
+func SaveState(stateDir string, s State) error {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(stateDir, StateFile)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write supervisor state: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write supervisor state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write supervisor state: %w", err)
+	}
+	return nil
+}
+
+// Export copies the policy's failure counts into s
+func (p *Policy) Export(s *State) {
+	s.StartupFailures, s.Crashes = p.startupFailures, p.crashes
+}
+
+// Restore continues from the failure counts in s. Counts saved longer ago
+// than StableAfter are stale, as a run that long would have reset them,
+// and are ignored, as are counts saved "in the future" by a clock that has
+// since gone back; Restore reports whether they were used.
+func (p *Policy) Restore(s State, now time.Time) bool {
+	stable := p.StableAfter
+	if stable <= 0 {
+		stable = DefaultStableAfter
+	}
+	if s.Updated.IsZero() || s.Updated.After(now) || now.Sub(s.Updated) >= stable {
+		return false
+	}
+	p.startupFailures, p.crashes = s.StartupFailures, s.Crashes
+	return true
+}
@@ -0,0 +1,331 @@
+// Package rewards reads a peer's votes and rewards from one of several
+// sources behind one interface: the coordinator contract, the Gensyn
+// dashboard API or a gswarm hub that caches the contract for a fleet. When
+// a contract ABI or an API changes, only the source that reads it needs
+// updating.
+package rewards
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/chain"
+	"github.com/Deep-Commit/gswarm/internal/rpc"
+)
+
+// Source names, as given to --rewards-source
+const (
+	SourceChain     = "chain"
+	SourceDashboard = "dashboard"
+	SourceHub       = "hub"
+)
+
+// DefaultDashboardURL is the Gensyn dashboard's peer endpoint
+const DefaultDashboardURL = "https://dashboard.gensyn.ai/api/v1/peer"
+
+// PeersPath is where a hub serves peer totals, followed by the peer ID
+const PeersPath = "/api/v1/peers/"
+
+// DefaultCacheTTL is how long a hub reuses a peer's totals
+const DefaultCacheTTL = time.Minute
+
+// maxResponseSize bounds the API responses read
+const maxResponseSize = 64 << 10
+
+// Totals are a peer's votes and rewards. Rewards are signed, as penalties
+// can take a peer below zero.
+type Totals struct {
+	Votes   *big.Int `json:"votes"`
+	Rewards *big.Int `json:"rewards"`
+}
+
+// Source reads peer totals
+type Source interface {
+	// Name identifies the source in logs
+	Name() string
+	// Peer returns peerID's totals, zero for a peer the source doesn't know
+	Peer(ctx context.Context, peerID string) (Totals, error)
+}
+
+// Options configure the sources New creates
+type Options struct {
+	// Contracts are the coordinators the chain source reads, in order of
+	// preference; Endpoint is their JSON-RPC URL
+	Contracts []string
+	Endpoint  string
+	RPC       *rpc.Client
+	// URL is the dashboard endpoint or the hub's base URL
+	URL    string
+	Client *http.Client
+	// Token is sent to the hub, which needs its --token
+	Token string
+}
+
+// New creates the named source
+func New(name string, o Options) (Source, error) {
+	switch name {
+	case SourceChain, "":
+		if len(o.Contracts) == 0 {
+			return nil, errors.New("the chain rewards source needs at least one coordinator contract")
+		}
+		return NewChain(o.RPC, o.Endpoint, o.Contracts...), nil
+	case SourceDashboard:
+		u := o.URL
+		if u == "" {
+			u = DefaultDashboardURL
+		}
+		return &Dashboard{URL: u, Client: client(o.Client)}, nil
+	case SourceHub:
+		if o.URL == "" {
+			return nil, errors.New("the hub rewards source needs the hub's URL")
+		}
+		return &Hub{URL: o.URL, Token: o.Token, Client: client(o.Client)}, nil
+	}
+	return nil, fmt.Errorf("unknown rewards source %q; use %s, %s or %s", name, SourceChain, SourceDashboard, SourceHub)
+}
+
+func client(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
+
+// Chain reads totals from the coordinator contracts with eth_call. Peers
+// are looked up in each contract in turn and the first one with data is
+// used, so a peer that moved between swarms isn't counted twice. A peer is
+// only reported as zero when every contract answered both queries; if any
+// query failed, its data may be in the contract that couldn't be read.
+type Chain struct {
+	Readers []*chain.Reader
+}
+
+// NewChain creates a chain source for contracts on endpoint
+func NewChain(client *rpc.Client, endpoint string, contracts ...string) *Chain {
+	if client == nil {
+		client = rpc.NewClient()
+	}
+	if endpoint == "" {
+		endpoint = rpc.GensynTestnetURL
+	}
+	c := &Chain{}
+	for _, contract := range contracts {
+		c.Readers = append(c.Readers, &chain.Reader{Client: client, Endpoint: endpoint, Contract: contract})
+	}
+	return c
+}
+
+// Name implements Source
+func (c *Chain) Name() string { return SourceChain }
+
+// Peer implements Source
+func (c *Chain) Peer(ctx context.Context, peerID string) (Totals, error) {
+	var firstErr error
+	for _, r := range c.Readers {
+		votes, err := r.VoterVoteCount(ctx, peerID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("contract %s votes: %w", r.Contract, err)
+			}
+			continue
+		}
+		rewards, err := r.TotalRewards(ctx, []string{peerID})
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("contract %s rewards: %w", r.Contract, err)
+			}
+			continue
+		}
+		if votes.Sign() != 0 || rewards[0].Sign() != 0 {
+			return Totals{Votes: votes, Rewards: rewards[0]}, nil
+		}
+	}
+	if firstErr != nil {
+		return Totals{}, firstErr
+	}
+	return Totals{Votes: new(big.Int), Rewards: new(big.Int)}, nil
+}
+
+// Dashboard reads totals from the Gensyn dashboard API, which needs no
+// RPC endpoint. Votes are the dashboard's score.
+type Dashboard struct {
+	URL    string
+	Client *http.Client
+}
+
+// Name implements Source
+func (d *Dashboard) Name() string { return SourceDashboard }
+
+// Peer implements Source
+func (d *Dashboard) Peer(ctx context.Context, peerID string) (Totals, error) {
+	var body struct {
+		Reward json.Number `json:"reward"`
+		Score  json.Number `json:"score"`
+	}
+	found, err := getJSON(ctx, d.Client, d.URL+"?id="+url.QueryEscape(peerID), "", &body)
+	if err != nil {
+		return Totals{}, err
+	}
+	if !found {
+		return Totals{Votes: new(big.Int), Rewards: new(big.Int)}, nil
+	}
+	// An error page or changed API answers 200 without the fields, which
+	// mustn't read as a peer that lost everything
+	if body.Reward == "" && body.Score == "" {
+		return Totals{}, errors.New("dashboard response has no reward or score")
+	}
+	t := Totals{}
+	if t.Rewards, err = parseNumber(body.Reward); err != nil {
+		return Totals{}, fmt.Errorf("dashboard reward: %w", err)
+	}
+	if t.Votes, err = parseNumber(body.Score); err != nil {
+		return Totals{}, fmt.Errorf("dashboard score: %w", err)
+	}
+	return t, nil
+}
+
+// parseNumber reads an integer that may be written as a float, e.g. 12.0
+func parseNumber(n json.Number) (*big.Int, error) {
+	if n == "" {
+		return new(big.Int), nil
+	}
+	if i, ok := new(big.Int).SetString(string(n), 10); ok {
+		return i, nil
+	}
+	f, ok := new(big.Float).SetString(string(n))
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", n)
+	}
+	i, _ := f.Int(nil)
+	return i, nil
+}
+
+// Hub reads totals from a gswarm hub, which reads the contract once for
+// every monitor that asks within DefaultCacheTTL
+type Hub struct {
+	URL string
+	// Token is the hub's --token, if it has one
+	Token  string
+	Client *http.Client
+}
+
+// Name implements Source
+func (h *Hub) Name() string { return SourceHub }
+
+// Peer implements Source
+func (h *Hub) Peer(ctx context.Context, peerID string) (Totals, error) {
+	var t Totals
+	found, err := getJSON(ctx, h.Client, strings.TrimRight(h.URL, "/")+PeersPath+url.PathEscape(peerID), h.Token, &t)
+	if err != nil {
+		return Totals{}, err
+	}
+	if !found || t.Votes == nil || t.Rewards == nil {
+		return Totals{}, errors.New("the hub returned no totals; is it a gswarm hub?")
+	}
+	return t, nil
+}
+
+// getJSON decodes the response to a GET into v, reporting false for a 404.
+// A token is sent as a bearer token.
+func getJSON(ctx context.Context, client *http.Client, u, token string, v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return false, fmt.Errorf("invalid response from %s: %w", req.URL.Host, err)
+	}
+	return true, nil
+}
+
+// Cached reuses another source's totals for up to TTL. Errors aren't
+// cached.
+type Cached struct {
+	Source Source
+	TTL    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	totals Totals
+	at     time.Time
+}
+
+// NewCached wraps source in a cache
+func NewCached(source Source, ttl time.Duration) *Cached {
+	return &Cached{Source: source, TTL: ttl, entries: map[string]cacheEntry{}}
+}
+
+// Name implements Source
+func (c *Cached) Name() string { return c.Source.Name() + " (cached)" }
+
+// Peer implements Source
+func (c *Cached) Peer(ctx context.Context, peerID string) (Totals, error) {
+	c.mu.Lock()
+	e, ok := c.entries[peerID]
+	c.mu.Unlock()
+	if ok && time.Since(e.at) < c.TTL {
+		return e.totals, nil
+	}
+	t, err := c.Source.Peer(ctx, peerID)
+	if err != nil {
+		return t, err
+	}
+	c.mu.Lock()
+	// Drop expired entries so peers that stopped being asked for don't pile up
+	for id, old := range c.entries {
+		if time.Since(old.at) >= c.TTL {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[peerID] = cacheEntry{t, time.Now()}
+	c.mu.Unlock()
+	return t, nil
+}
+
+// Handler serves source's totals at PeersPath<peer ID>, for Hub clients
+func Handler(source Source) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peerID, err := url.PathUnescape(strings.TrimPrefix(r.URL.Path, PeersPath))
+		if err != nil || peerID == "" || strings.Contains(peerID, "/") {
+			http.Error(w, "invalid peer ID", http.StatusBadRequest)
+			return
+		}
+		t, err := source.Peer(r.Context(), peerID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	})
+}
@@ -0,0 +1,167 @@
+package rewards
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/rpc"
+)
+
+// fakeChain answers eth_call for two contracts: the first knows nothing,
+// the second has 7 votes and -3 rewards for every peer
+func fakeChain(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpc.Request
+		json.NewDecoder(r.Body).Decode(&req)
+		call := req.Params[0].(map[string]interface{})
+		data, to := call["data"].(string), call["to"].(string)
+		votes, rewards := big.NewInt(0), big.NewInt(0)
+		if to == "0xnew" {
+			votes, rewards = big.NewInt(7), big.NewInt(-3)
+		}
+		var result string
+		switch {
+		case strings.HasPrefix(data, "0xdfb3c7df"):
+			result = fmt.Sprintf("%064x", votes)
+		case strings.HasPrefix(data, "0x80c3d97f"):
+			word := new(big.Int).Set(rewards)
+			if word.Sign() < 0 {
+				word.Add(word, new(big.Int).Lsh(big.NewInt(1), 256))
+			}
+			result = fmt.Sprintf("%064x%064x%064x", 32, 1, word)
+		default:
+			t.Errorf("unexpected call data %s", data)
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x%s"}`, req.ID, result)
+	}))
+}
+
+func TestChain(t *testing.T) {
+	srv := fakeChain(t)
+	defer srv.Close()
+
+	src, err := New(SourceChain, Options{Contracts: []string{"0xold", "0xnew"}, Endpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := src.Peer(context.Background(), "QmPeer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Votes.Int64() != 7 || got.Rewards.Int64() != -3 {
+		t.Errorf("Peer() = %s votes, %s rewards, want 7 and -3", got.Votes, got.Rewards)
+	}
+}
+
+func TestChain_FailedQuery(t *testing.T) {
+	// the votes query works, but the rewards query comes back empty, as
+	// from a node that lost the contract's state
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpc.Request
+		json.NewDecoder(r.Body).Decode(&req)
+		result := "0x"
+		if strings.HasPrefix(req.Params[0].(map[string]interface{})["data"].(string), "0xdfb3c7df") {
+			result = fmt.Sprintf("0x%064x", 0)
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"%s"}`, req.ID, result)
+	}))
+	defer srv.Close()
+
+	src := NewChain(nil, srv.URL, "0xnew")
+	if got, err := src.Peer(context.Background(), "QmPeer"); err == nil {
+		t.Errorf("Peer() = %s votes, %s rewards, want an error rather than zero", got.Votes, got.Rewards)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") != "QmPeer" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"peerId":"QmPeer","peerName":"quick fox","reward":1500,"score":42.0,"online":true}`)
+	}))
+	defer srv.Close()
+
+	src, _ := New(SourceDashboard, Options{URL: srv.URL})
+	got, err := src.Peer(context.Background(), "QmPeer")
+	if err != nil || got.Rewards.Int64() != 1500 || got.Votes.Int64() != 42 {
+		t.Errorf("Peer() = %+v, %v", got, err)
+	}
+	got, err = src.Peer(context.Background(), "QmUnknown")
+	if err != nil || got.Rewards.Sign() != 0 || got.Votes.Sign() != 0 {
+		t.Errorf("unknown peer: Peer() = %+v, %v", got, err)
+	}
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":"maintenance"}`)
+	}))
+	defer empty.Close()
+	src, _ = New(SourceDashboard, Options{URL: empty.URL})
+	if got, err := src.Peer(context.Background(), "QmPeer"); err == nil {
+		t.Errorf("response without totals: Peer() = %+v, want an error", got)
+	}
+}
+
+type countingSource struct {
+	calls atomic.Int32
+}
+
+func (c *countingSource) Name() string { return "counting" }
+
+func (c *countingSource) Peer(_ context.Context, peerID string) (Totals, error) {
+	n := c.calls.Add(1)
+	return Totals{Votes: big.NewInt(int64(n)), Rewards: big.NewInt(-1)}, nil
+}
+
+func TestHub(t *testing.T) {
+	upstream := &countingSource{}
+	mux := http.NewServeMux()
+	cached := Handler(NewCached(upstream, time.Hour))
+	mux.HandleFunc(PeersPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		cached.ServeHTTP(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	if _, err := (&Hub{URL: srv.URL, Client: http.DefaultClient}).Peer(context.Background(), "QmPeer"); err == nil {
+		t.Error("Peer() without the token succeeded")
+	}
+	src, _ := New(SourceHub, Options{URL: srv.URL + "/", Token: "secret"})
+	for i := 0; i < 3; i++ {
+		got, err := src.Peer(context.Background(), "QmPeer")
+		if err != nil || got.Votes.Int64() != 1 || got.Rewards.Int64() != -1 {
+			t.Fatalf("Peer() = %+v, %v", got, err)
+		}
+	}
+	if upstream.calls.Load() != 1 {
+		t.Errorf("upstream read %d times, want once thanks to the cache", upstream.calls.Load())
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		o    Options
+	}{
+		{SourceChain, Options{}},
+		{SourceHub, Options{}},
+		{"graph", Options{}},
+	} {
+		if _, err := New(c.name, c.o); err == nil {
+			t.Errorf("New(%q) succeeded", c.name)
+		}
+	}
+}
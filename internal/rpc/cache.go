@@ -0,0 +1,143 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheFile is the response cache's file name in the state directory
+const CacheFile = "rpc_cache.json"
+
+// DefaultMaxStale is how old a cached result may be and still stand in
+// for a call that failed because the endpoint couldn't be reached
+const DefaultMaxStale = 24 * time.Hour
+
+// Cache keeps the results of calls whose answers rarely change, such as
+// the peers registered to an EOA or the chain ID, in a file. Restarts reuse
+// them instead of querying the public endpoint again, and while the
+// endpoint is unreachable the last known result is used.
+type Cache struct {
+	Path     string
+	MaxStale time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	Result json.RawMessage `json:"result"`
+	Stored time.Time       `json:"stored"`
+}
+
+// NewCache creates a cache stored at path
+func NewCache(path string) *Cache {
+	return &Cache{Path: path, MaxStale: DefaultMaxStale}
+}
+
+// get returns the cached result for key if it was stored within maxAge
+func (c *Cache) get(key string, maxAge time.Duration) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	entry, ok := c.entries[key]
+	if !ok || maxAge <= 0 || time.Since(entry.Stored) > maxAge {
+		return nil, false
+	}
+	var result interface{}
+	if err := json.Unmarshal(entry.Result, &result); err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// put stores result for key, dropping entries too old to be used
+func (c *Cache) put(key string, result interface{}) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The supervisor and the monitor share the file; merge with theirs
+	c.entries = nil
+	c.load()
+	c.entries[key] = cacheEntry{Result: raw, Stored: time.Now()}
+	for k, e := range c.entries {
+		if time.Since(e.Stored) > c.MaxStale {
+			delete(c.entries, k)
+		}
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to write RPC cache: %w", err)
+	}
+	tmp := c.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write RPC cache: %w", err)
+	}
+	return os.Rename(tmp, c.Path)
+}
+
+// load reads the file once; a missing or damaged cache starts empty.
+// Callers hold c.mu.
+func (c *Cache) load() {
+	if c.entries != nil {
+		return
+	}
+	c.entries = make(map[string]cacheEntry)
+	if data, err := os.ReadFile(c.Path); err == nil {
+		if json.Unmarshal(data, &c.entries) != nil {
+			c.entries = make(map[string]cacheEntry)
+		}
+	}
+}
+
+func cacheKey(endpoint, method string, params []interface{}) (string, error) {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return endpoint + " " + method + " " + string(p), nil
+}
+
+// CallCached is Call for results that rarely change. A result cached
+// within ttl is returned without a request; ttl 0 always asks the endpoint
+// but still keeps the result. When the endpoint can't be reached, a result
+// cached within the cache's MaxStale is returned instead of the error.
+// Without a Cache it is the same as Call.
+func (c *Client) CallCached(ctx context.Context, endpoint, method string, params []interface{}, ttl time.Duration) (interface{}, error) {
+	if c.Cache == nil {
+		return c.Call(ctx, endpoint, method, params)
+	}
+	key, err := cacheKey(endpoint, method, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if result, ok := c.Cache.get(key, ttl); ok {
+		return result, nil
+	}
+
+	result, err := c.Call(ctx, endpoint, method, params)
+	if err == nil {
+		// A cache that can't be written only costs a request next time
+		c.Cache.put(key, result)
+		return result, nil
+	}
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) && ctx.Err() == nil {
+		if stale, ok := c.Cache.get(key, c.Cache.MaxStale); ok {
+			return stale, nil
+		}
+	}
+	return nil, err
+}
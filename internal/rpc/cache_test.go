@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallCached(t *testing.T) {
+	var calls int32
+	var down, reverts atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case down.Load():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case reverts.Load():
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted"}}`)
+		default:
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x2a"}`)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), CacheFile)
+	client := newTestClient()
+	client.MaxRetries = 0
+	client.Cache = NewCache(path)
+	call := func(ttl time.Duration) (interface{}, error) {
+		return client.CallCached(context.Background(), srv.URL, "eth_chainId", nil, ttl)
+	}
+
+	if got, err := call(time.Hour); err != nil || got != "0x2a" {
+		t.Fatalf("CallCached() = %v, %v", got, err)
+	}
+
+	// A restarted client reads the cache from the file
+	client.Cache = NewCache(path)
+	if got, err := call(time.Hour); err != nil || got != "0x2a" || calls != 1 {
+		t.Errorf("cached CallCached() = %v, %v after %d requests; want no new request", got, err, calls)
+	}
+
+	// ttl 0 asks again, and an unreachable endpoint falls back to the cache
+	down.Store(true)
+	if got, err := call(0); err != nil || got != "0x2a" || calls != 2 {
+		t.Errorf("CallCached() while down = %v, %v after %d requests; want the stale result", got, err, calls)
+	}
+
+	// Errors from the contract itself aren't hidden
+	down.Store(false)
+	reverts.Store(true)
+	var rpcErr *Error
+	if _, err := call(0); !errors.As(err, &rpcErr) {
+		t.Errorf("CallCached() error = %v, want the RPC error", err)
+	}
+
+	// Nothing older than MaxStale is used
+	client.Cache.MaxStale = time.Nanosecond
+	down.Store(true)
+	if _, err := call(0); err == nil {
+		t.Error("CallCached() should fail once the cached result is too old")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package rpc
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock takes an exclusive flock on f, waiting for other holders
+func lock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+func unlock(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
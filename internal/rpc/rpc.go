@@ -0,0 +1,327 @@
+// Package rpc provides a JSON-RPC client for blockchain queries, including
+// retries with backoff, circuit breaking and per-endpoint metrics.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/httpclient"
+)
+
+// Default retry and circuit breaker settings
+const (
+	DefaultMaxRetries       = 4
+	DefaultInitialBackoff   = 500 * time.Millisecond
+	DefaultMaxBackoff       = 30 * time.Second
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 2 * time.Minute
+)
+
+// GensynTestnetURL is the public Gensyn testnet RPC endpoint
+const GensynTestnetURL = "https://gensyn-testnet.g.alchemy.com/public"
+
+// ErrCircuitOpen is returned when an endpoint has failed too often and
+// requests are being short-circuited until the cooldown expires
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Request represents a JSON-RPC request
+type Request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	ID      int           `json:"id"`
+}
+
+// Response represents a JSON-RPC response
+type Response struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *Error      `json:"error,omitempty"`
+}
+
+// Error is an error object returned by a JSON-RPC endpoint
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("RPC error: %s (code: %d)", e.Message, e.Code)
+}
+
+// EndpointStats holds the counters collected for a single endpoint
+type EndpointStats struct {
+	Requests    uint64        `json:"requests"`
+	Failures    uint64        `json:"failures"`
+	Retries     uint64        `json:"retries"`
+	RateLimited uint64        `json:"rate_limited"`
+	LastLatency time.Duration `json:"last_latency"`
+	BreakerOpen bool          `json:"breaker_open"`
+}
+
+type endpointState struct {
+	stats            EndpointStats
+	consecutiveFails int
+	openUntil        time.Time
+}
+
+// Client is a rate-limit-aware JSON-RPC client shared by all blockchain queries
+type Client struct {
+	// HTTPClient defaults to the shared gswarm client, less its retries
+	HTTPClient       *http.Client
+	MaxRetries       int
+	InitialBackoff   time.Duration
+	MaxBackoff       time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// Cache, if set, serves CallCached
+	Cache *Cache
+	// Usage, if set, counts the requests made to each endpoint
+	Usage *Usage
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointState
+	nextID    int
+}
+
+// NewClient creates a client with the default retry and breaker settings
+func NewClient() *Client {
+	return &Client{
+		MaxRetries:       DefaultMaxRetries,
+		InitialBackoff:   DefaultInitialBackoff,
+		MaxBackoff:       DefaultMaxBackoff,
+		BreakerThreshold: DefaultBreakerThreshold,
+		BreakerCooldown:  DefaultBreakerCooldown,
+		endpoints:        make(map[string]*endpointState),
+	}
+}
+
+// retryableError marks a failure that is worth retrying, optionally with
+// a server-provided delay
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// Call performs a JSON-RPC call against endpoint, retrying on rate limits,
+// server errors and network failures
+func (c *Client) Call(ctx context.Context, endpoint string, method string, params []interface{}) (interface{}, error) {
+	if err := c.allow(endpoint); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.nextID++
+	request := Request{JSONRPC: "2.0", Method: method, Params: params, ID: c.nextID}
+	c.mu.Unlock()
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			c.record(endpoint, func(s *endpointState) { s.stats.Retries++ })
+			delay := c.backoff(attempt)
+			// A server's Retry-After is honoured up to MaxBackoff, so a
+			// long one can't stall the check
+			var re *retryableError
+			if errors.As(lastErr, &re) && re.retryAfter > delay {
+				delay = min(re.retryAfter, c.MaxBackoff)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		start := time.Now()
+		result, err := c.do(ctx, endpoint, body)
+		latency := time.Since(start)
+		if c.Usage != nil {
+			// Counters that can't be saved only make the budget lenient
+			c.Usage.add(endpoint, start)
+		}
+		c.record(endpoint, func(s *endpointState) {
+			s.stats.Requests++
+			s.stats.LastLatency = latency
+		})
+
+		if err == nil {
+			c.success(endpoint)
+			return result, nil
+		}
+
+		lastErr = err
+		var re *retryableError
+		if !errors.As(err, &re) {
+			// RPC-level errors (bad params, reverts) won't improve on retry
+			c.success(endpoint)
+			return nil, err
+		}
+	}
+
+	c.failure(endpoint)
+	return nil, fmt.Errorf("request failed after %d attempts: %w", c.MaxRetries+1, lastErr)
+}
+
+// do performs a single HTTP round trip
+func (c *Client) do(ctx context.Context, endpoint string, body []byte) (interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = httpclient.NoRetry()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &retryableError{err: fmt.Errorf("failed to make request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &retryableError{err: fmt.Errorf("failed to read response: %w", err)}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.record(endpoint, func(s *endpointState) { s.stats.RateLimited++ })
+		return nil, &retryableError{
+			err:        fmt.Errorf("rate limited: %s", resp.Status),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	if resp.StatusCode >= 500 {
+		return nil, &retryableError{err: fmt.Errorf("server error: %s", resp.Status)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s - %s", resp.Status, string(respBody))
+	}
+
+	// Check if response is JSON
+	if !strings.HasPrefix(strings.TrimSpace(string(respBody)), "{") {
+		return nil, fmt.Errorf("non-JSON response: %s", string(respBody))
+	}
+
+	var response Response
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if response.Error != nil {
+		// Some providers report rate limiting inside the JSON-RPC envelope
+		if response.Error.Code == 429 || response.Error.Code == -32005 {
+			c.record(endpoint, func(s *endpointState) { s.stats.RateLimited++ })
+			return nil, &retryableError{err: response.Error}
+		}
+		return nil, response.Error
+	}
+
+	return response.Result, nil
+}
+
+// backoff returns the exponential delay with full jitter for an attempt
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.InitialBackoff << uint(attempt-1)
+	if delay <= 0 || delay > c.MaxBackoff {
+		delay = c.MaxBackoff
+	}
+	// Full jitter keeps many nodes from retrying in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// allow reports whether the circuit breaker lets a request through
+func (c *Client) allow(endpoint string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.state(endpoint)
+	if !s.openUntil.IsZero() && time.Now().Before(s.openUntil) {
+		return fmt.Errorf("%w for %s until %s", ErrCircuitOpen, endpoint, s.openUntil.Format(time.RFC3339))
+	}
+	s.stats.BreakerOpen = false
+	return nil
+}
+
+func (c *Client) success(endpoint string) {
+	c.record(endpoint, func(s *endpointState) {
+		s.consecutiveFails = 0
+		s.openUntil = time.Time{}
+		s.stats.BreakerOpen = false
+	})
+}
+
+func (c *Client) failure(endpoint string) {
+	c.record(endpoint, func(s *endpointState) {
+		s.stats.Failures++
+		s.consecutiveFails++
+		if c.BreakerThreshold > 0 && s.consecutiveFails >= c.BreakerThreshold {
+			s.openUntil = time.Now().Add(c.BreakerCooldown)
+			s.stats.BreakerOpen = true
+		}
+	})
+}
+
+func (c *Client) record(endpoint string, fn func(s *endpointState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(c.state(endpoint))
+}
+
+// state returns the state for endpoint; callers must hold c.mu
+func (c *Client) state(endpoint string) *endpointState {
+	if c.endpoints == nil {
+		c.endpoints = make(map[string]*endpointState)
+	}
+	s, ok := c.endpoints[endpoint]
+	if !ok {
+		s = &endpointState{}
+		c.endpoints[endpoint] = s
+	}
+	return s
+}
+
+// Stats returns a snapshot of the per-endpoint counters
+func (c *Client) Stats() map[string]EndpointStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]EndpointStats, len(c.endpoints))
+	for endpoint, s := range c.endpoints {
+		out[endpoint] = s.stats
+	}
+	return out
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient() *Client {
+	c := NewClient()
+	c.InitialBackoff = time.Millisecond
+	c.MaxBackoff = 5 * time.Millisecond
+	return c
+}
+
+func TestCall_RetriesOnRateLimitAndServerError(t *testing.T) {
+	cases := []struct {
+		name        string
+		failures    int
+		status      int
+		wantErr     bool
+		wantRetries uint64
+	}{
+		{"success first try", 0, http.StatusOK, false, 0},
+		{"rate limited twice", 2, http.StatusTooManyRequests, false, 2},
+		{"server error once", 1, http.StatusBadGateway, false, 1},
+		{"exhausts retries", 10, http.StatusServiceUnavailable, true, DefaultMaxRetries},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if int(atomic.AddInt32(&calls, 1)) <= c.failures {
+					w.WriteHeader(c.status)
+					return
+				}
+				fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x2a"}`)
+			}))
+			defer srv.Close()
+
+			client := newTestClient()
+			result, err := client.Call(context.Background(), srv.URL, "eth_call", nil)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Call() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if !c.wantErr && result != "0x2a" {
+				t.Errorf("Call() = %v, want 0x2a", result)
+			}
+			if got := client.Stats()[srv.URL].Retries; got != c.wantRetries {
+				t.Errorf("Retries = %d, want %d", got, c.wantRetries)
+			}
+		})
+	}
+}
+
+func TestCall_ClampsRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x2a"}`)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := newTestClient().Call(ctx, srv.URL, "eth_call", nil); err != nil {
+		t.Fatalf("Call() error = %v, want the retry after MaxBackoff", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Call() took %s, want Retry-After clamped to MaxBackoff", d)
+	}
+}
+
+func TestCall_DoesNotRetryRPCErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"execution reverted"}}`)
+	}))
+	defer srv.Close()
+
+	client := newTestClient()
+	_, err := client.Call(context.Background(), srv.URL, "eth_call", nil)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("Call() error = %v, want *Error", err)
+	}
+	if calls != 1 {
+		t.Errorf("server called %d times, want 1", calls)
+	}
+}
+
+func TestCall_CircuitBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client := newTestClient()
+	client.MaxRetries = 0
+	client.BreakerThreshold = 2
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Call(context.Background(), srv.URL, "eth_call", nil); err == nil {
+			t.Fatalf("Call() #%d expected error", i)
+		}
+	}
+
+	_, err := client.Call(context.Background(), srv.URL, "eth_call", nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Call() error = %v, want ErrCircuitOpen", err)
+	}
+	if !client.Stats()[srv.URL].BreakerOpen {
+		t.Errorf("BreakerOpen = false, want true")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "3", 3 * time.Second},
+		{"garbage", "soon", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := parseRetryAfter(c.value); got != c.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", c.value, got, c.want)
+			}
+		})
+	}
+}
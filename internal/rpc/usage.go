@@ -0,0 +1,221 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UsageFile is the request counters' file name in the state directory
+const UsageFile = "rpc_usage.json"
+
+// usageWindow is how far back requests count toward the daily budget
+const usageWindow = 24 * time.Hour
+
+// Usage counts the requests made to each endpoint per hour over the last
+// day, in a file the supervisor and the monitor share, so free tier
+// budgets cover both processes and survive restarts. Each attempt counts,
+// retries included, as providers bill them. Endpoints are kept by host,
+// since API keys are often part of the path.
+type Usage struct {
+	Path string
+	// PerHour and PerDay are the budgets; 0 is unlimited
+	PerHour int
+	PerDay  int
+
+	mu    sync.Mutex
+	hours map[string]map[string]int
+}
+
+// NewUsage creates counters stored at path
+func NewUsage(path string) *Usage {
+	return &Usage{Path: path}
+}
+
+// Count is the requests made to one endpoint
+type Count struct {
+	Endpoint string `json:"endpoint"`
+	// Hour is the requests since the start of the current hour, Day those
+	// of the last 24 hours
+	Hour int `json:"hour"`
+	Day  int `json:"day"`
+}
+
+// Exceeded is a budget that ran out
+type Exceeded struct {
+	Endpoint string
+	// Window is "hour" or "day"
+	Window string
+	Used   int
+	Budget int
+	// Until is when enough requests have aged out to poll again
+	Until time.Time
+}
+
+func (e Exceeded) String() string {
+	return fmt.Sprintf("%d of %d requests to %s per %s used", e.Used, e.Budget, e.Endpoint, e.Window)
+}
+
+// add counts a request to endpoint made at now and saves the counters
+func (u *Usage) add(endpoint string, now time.Time) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(u.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to write RPC usage: %w", err)
+	}
+	// The supervisor and the monitor share the file; the lock keeps one
+	// from overwriting the other's count between reading and saving
+	f, err := os.OpenFile(u.Path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to lock RPC usage: %w", err)
+	}
+	defer f.Close()
+	if err := lock(f); err != nil {
+		return fmt.Errorf("failed to lock RPC usage: %w", err)
+	}
+	defer unlock(f)
+
+	u.load()
+	name := endpointHost(endpoint)
+	if u.hours[name] == nil {
+		u.hours[name] = map[string]int{}
+	}
+	u.hours[name][hourKey(now)]++
+	u.prune(now)
+
+	data, err := json.MarshalIndent(u.hours, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := u.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write RPC usage: %w", err)
+	}
+	return os.Rename(tmp, u.Path)
+}
+
+// Counts returns each endpoint's requests at now, sorted by endpoint
+func (u *Usage) Counts(now time.Time) []Count {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.load()
+	current := hourKey(now)
+	counts := make([]Count, 0, len(u.hours))
+	for name, hours := range u.hours {
+		c := Count{Endpoint: name}
+		for hour, n := range hours {
+			if !inWindow(hour, now) {
+				continue
+			}
+			c.Day += n
+			if hour == current {
+				c.Hour += n
+			}
+		}
+		counts = append(counts, c)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Endpoint < counts[j].Endpoint })
+	return counts
+}
+
+// Exceeded reports a budget that has run out at now. The daily budget
+// comes first, as it takes longer to clear.
+func (u *Usage) Exceeded(now time.Time) (Exceeded, bool) {
+	if u.PerHour <= 0 && u.PerDay <= 0 {
+		return Exceeded{}, false
+	}
+	counts := u.Counts(now)
+	if u.PerDay > 0 {
+		for _, c := range counts {
+			if c.Day >= u.PerDay {
+				return Exceeded{Endpoint: c.Endpoint, Window: "day", Used: c.Day, Budget: u.PerDay, Until: u.dayClears(c.Endpoint, now)}, true
+			}
+		}
+	}
+	if u.PerHour > 0 {
+		for _, c := range counts {
+			if c.Hour >= u.PerHour {
+				next := now.UTC().Truncate(time.Hour).Add(time.Hour)
+				return Exceeded{Endpoint: c.Endpoint, Window: "hour", Used: c.Hour, Budget: u.PerHour, Until: next}, true
+			}
+		}
+	}
+	return Exceeded{}, false
+}
+
+// dayClears is when the oldest hours of endpoint's requests have left the
+// window, bringing the day below the budget
+func (u *Usage) dayClears(name string, now time.Time) time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var hours []string
+	total := 0
+	for hour, n := range u.hours[name] {
+		if inWindow(hour, now) {
+			hours = append(hours, hour)
+			total += n
+		}
+	}
+	// RFC 3339 UTC hours sort in time order
+	sort.Strings(hours)
+	for _, hour := range hours {
+		total -= u.hours[name][hour]
+		if total < u.PerDay {
+			t, _ := time.Parse(time.RFC3339, hour)
+			return t.Add(usageWindow)
+		}
+	}
+	return now
+}
+
+// load reads the file; a missing or damaged file starts empty. Callers
+// hold u.mu.
+func (u *Usage) load() {
+	u.hours = make(map[string]map[string]int)
+	if data, err := os.ReadFile(u.Path); err == nil {
+		if json.Unmarshal(data, &u.hours) != nil {
+			u.hours = make(map[string]map[string]int)
+		}
+	}
+}
+
+// prune drops hours that no longer count. Callers hold u.mu.
+func (u *Usage) prune(now time.Time) {
+	for name, hours := range u.hours {
+		for hour := range hours {
+			if !inWindow(hour, now) {
+				delete(hours, hour)
+			}
+		}
+		if len(hours) == 0 {
+			delete(u.hours, name)
+		}
+	}
+}
+
+// hourKey is the start of t's hour in UTC
+func hourKey(t time.Time) string {
+	return t.UTC().Truncate(time.Hour).Format(time.RFC3339)
+}
+
+// inWindow reports whether the hour starting at key is within the last
+// day at now. An hour after now, recorded before the clock went back,
+// still counts.
+func inWindow(key string, now time.Time) bool {
+	t, err := time.Parse(time.RFC3339, key)
+	return err == nil && now.Sub(t) < usageWindow
+}
+
+// endpointHost is the host of endpoint, without a path that may hold an
+// API key
+func endpointHost(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return endpoint
+}
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUsage_Call(t *testing.T) {
+	failures := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x2a"}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), UsageFile)
+	client := newTestClient()
+	client.Usage = NewUsage(path)
+	if _, err := client.Call(context.Background(), srv.URL+"/v2/secret-key", "eth_chainId", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rate limited attempt counts too; another process sees the file
+	counts := NewUsage(path).Counts(time.Now())
+	host := strings.TrimPrefix(srv.URL, "http://")
+	if len(counts) != 1 || counts[0] != (Count{Endpoint: host, Hour: 2, Day: 2}) {
+		t.Errorf("Counts() = %+v, want 2 requests to %s", counts, host)
+	}
+}
+
+func TestUsage_Exceeded(t *testing.T) {
+	u := NewUsage(filepath.Join(t.TempDir(), UsageFile))
+	now := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		u.add("https://rpc.example/v2/key", now.Add(-20*time.Hour))
+	}
+	for i := 0; i < 2; i++ {
+		u.add("https://rpc.example/v2/key", now)
+	}
+	// older than a day, so dropped
+	u.add("https://rpc.example/v2/key", now.Add(-25*time.Hour))
+
+	if _, over := u.Exceeded(now); over {
+		t.Error("Exceeded() without budgets")
+	}
+	u.PerHour, u.PerDay = 2, 10
+	e, over := u.Exceeded(now)
+	if !over || e.Window != "hour" || e.Used != 2 || !e.Until.Equal(time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("hourly Exceeded() = %+v, %v", e, over)
+	}
+	u.PerHour, u.PerDay = 0, 5
+	e, over = u.Exceeded(now)
+	// the three requests of 16:00 the day before leave the window at 16:00
+	if !over || e.Window != "day" || e.Used != 5 || !e.Until.Equal(time.Date(2025, 6, 1, 16, 0, 0, 0, time.UTC)) {
+		t.Errorf("daily Exceeded() = %+v, %v", e, over)
+	}
+	if got := e.String(); got != "5 of 5 requests to rpc.example per day used" {
+		t.Errorf("String() = %q", got)
+	}
+	if _, over := u.Exceeded(now.Add(4 * time.Hour)); over {
+		t.Error("Exceeded() once the old requests left the window")
+	}
+}
+
+func TestUsage_Shared(t *testing.T) {
+	// The supervisor and the monitor each have their own Usage on the file
+	path := filepath.Join(t.TempDir(), UsageFile)
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		u := NewUsage(path)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := u.add("https://rpc.example/key", now); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if counts := NewUsage(path).Counts(now); len(counts) != 1 || counts[0].Hour != 100 {
+		t.Errorf("Counts() = %+v, want 100 requests this hour", counts)
+	}
+}
@@ -0,0 +1,200 @@
+// Package schedule parses pause windows such as "08:00-18:00 weekdays" and
+// decides when the trainer should be paused.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLookahead bounds the search for the next pause/resume boundary
+const maxLookahead = 8 * 24 * time.Hour
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Window is a daily time range during which training is paused. Windows
+// whose end is before their start run past midnight into the next day.
+type Window struct {
+	Spec  string
+	Start int // minutes after midnight
+	End   int
+	Days  [7]bool
+	// Location is the timezone of the times; nil is the one t is in
+	Location *time.Location
+}
+
+// Schedule is a set of pause windows
+type Schedule []Window
+
+// Parse parses pause window specs
+func Parse(specs []string) (Schedule, error) {
+	var s Schedule
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		w, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, w)
+	}
+	return s, nil
+}
+
+// ParseWindow parses "HH:MM-HH:MM [days]". Days may be "daily" (the
+// default), "weekdays", "weekends", a range like "mon-fri" or a list like
+// "sat,sun".
+func ParseWindow(spec string) (Window, error) {
+	w := Window{Spec: strings.TrimSpace(spec)}
+	fields := strings.Fields(strings.ToLower(w.Spec))
+	if len(fields) == 0 || len(fields) > 2 {
+		return w, fmt.Errorf("invalid pause window %q, expected \"HH:MM-HH:MM [days]\"", spec)
+	}
+
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return w, fmt.Errorf("invalid pause window %q: missing '-' between start and end", spec)
+	}
+	var err error
+	if w.Start, err = parseClock(start); err != nil {
+		return w, fmt.Errorf("invalid pause window %q: %w", spec, err)
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return w, fmt.Errorf("invalid pause window %q: %w", spec, err)
+	}
+
+	days := "daily"
+	if len(fields) == 2 {
+		days = fields[1]
+	}
+	if w.Days, err = parseDays(days); err != nil {
+		return w, fmt.Errorf("invalid pause window %q: %w", spec, err)
+	}
+	return w, nil
+}
+
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hh < 0 || mm < 0 || mm > 59 || hh > 24 || (hh == 24 && mm != 0) {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return hh*60 + mm, nil
+}
+
+func parseDays(s string) ([7]bool, error) {
+	var days [7]bool
+	switch s {
+	case "daily", "everyday", "*":
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	case "weekdays":
+		for d := time.Monday; d <= time.Friday; d++ {
+			days[d] = true
+		}
+		return days, nil
+	case "weekends":
+		days[time.Saturday], days[time.Sunday] = true, true
+		return days, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		fd, ok := dayNames[from]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", from)
+		}
+		if !isRange {
+			days[fd] = true
+			continue
+		}
+		td, ok := dayNames[to]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", to)
+		}
+		for d := fd; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == td {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// Contains reports whether t falls inside the window
+func (w Window) Contains(t time.Time) bool {
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	m := t.Hour()*60 + t.Minute()
+	d := t.Weekday()
+	switch {
+	case w.Start == w.End:
+		return w.Days[d]
+	case w.Start < w.End:
+		return w.Days[d] && m >= w.Start && m < w.End
+	default:
+		// Overnight: the window belongs to the day it starts on
+		prev := (d + 6) % 7
+		return (w.Days[d] && m >= w.Start) || (w.Days[prev] && m < w.End)
+	}
+}
+
+// Paused reports whether training should be paused at t
+func (s Schedule) Paused(t time.Time) bool {
+	for _, w := range s {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextChange returns the next time the paused state flips after t, or the
+// zero time if it never does (e.g. no windows, or paused all week)
+func (s Schedule) NextChange(t time.Time) time.Time {
+	if len(s) == 0 {
+		return time.Time{}
+	}
+	current := s.Paused(t)
+	next := t.Truncate(time.Minute)
+	for end := t.Add(maxLookahead); next.Before(end); {
+		next = next.Add(time.Minute)
+		if s.Paused(next) != current {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// In returns the schedule with its windows in the timezone loc
+func (s Schedule) In(loc *time.Location) Schedule {
+	in := make(Schedule, len(s))
+	for i, w := range s {
+		w.Location = loc
+		in[i] = w
+	}
+	return in
+}
+
+// Specs returns the window specs as configured
+func (s Schedule) Specs() []string {
+	specs := make([]string, len(s))
+	for i, w := range s {
+		specs[i] = w.Spec
+	}
+	return specs
+}
@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// 2025-06-02 is a Monday
+func at(day int, hhmm string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", fmt.Sprintf("2025-06-%02d %s", day, hhmm), time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseWindow(t *testing.T) {
+	cases := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{"daily default", "08:00-18:00", false},
+		{"weekdays", "08:00-18:00 weekdays", false},
+		{"range", "22:00-06:00 mon-fri", false},
+		{"list", "00:00-24:00 sat,sun", false},
+		{"bad clock", "8-18", true},
+		{"bad minutes", "08:75-18:00", true},
+		{"bad day", "08:00-18:00 funday", true},
+		{"missing dash", "08:00 18:00", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ParseWindow(c.spec)
+			if (err != nil) != c.wantErr {
+				t.Errorf("ParseWindow(%q) error = %v, wantErr %v", c.spec, err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedule_Paused(t *testing.T) {
+	s, err := Parse([]string{"08:00-18:00 weekdays", "23:00-01:00 sat"})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	cases := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"monday morning", at(2, "09:30"), true},
+		{"monday before window", at(2, "07:59"), false},
+		{"monday end is exclusive", at(2, "18:00"), false},
+		{"saturday daytime", at(7, "12:00"), false},
+		{"saturday late", at(7, "23:30"), true},
+		{"sunday after midnight", at(8, "00:30"), true},
+		{"sunday after window", at(8, "01:00"), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := s.Paused(c.t); got != c.want {
+				t.Errorf("Paused(%s) = %v, want %v", c.t.Format("Mon 15:04"), got, c.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_NextChange(t *testing.T) {
+	s, _ := Parse([]string{"08:00-18:00 weekdays"})
+
+	if got, want := s.NextChange(at(2, "09:30")), at(2, "18:00"); !got.Equal(want) {
+		t.Errorf("NextChange() while paused = %v, want %v", got, want)
+	}
+	if got, want := s.NextChange(at(6, "18:30")), at(9, "08:00"); !got.Equal(want) {
+		t.Errorf("NextChange() over the weekend = %v, want %v", got, want)
+	}
+
+	always, _ := Parse([]string{"00:00-00:00"})
+	if got := always.NextChange(at(2, "09:30")); !got.IsZero() {
+		t.Errorf("NextChange() for an always-paused schedule = %v, want zero", got)
+	}
+	if got := (Schedule{}).NextChange(at(2, "09:30")); !got.IsZero() {
+		t.Errorf("NextChange() for an empty schedule = %v, want zero", got)
+	}
+}
+
+func TestSchedule_In(t *testing.T) {
+	s, err := Parse([]string{"08:00-18:00 weekdays"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plus9 := time.FixedZone("UTC+9", 9*3600)
+	in := s.In(plus9)
+
+	// Monday 00:00 UTC is 09:00 in UTC+9
+	monday := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	if s.Paused(monday) {
+		t.Error("Paused() at 00:00 UTC = true, want false")
+	}
+	if !in.Paused(monday) {
+		t.Error("Paused() in UTC+9 at 09:00 = false, want true")
+	}
+	if got, want := in.NextChange(monday), time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("NextChange() = %v, want %v", got, want)
+	}
+	if s[0].Location != nil {
+		t.Error("In() changed the original schedule")
+	}
+}
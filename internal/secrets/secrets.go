@@ -0,0 +1,160 @@
+// Package secrets resolves configuration values that reference an external
+// secret store, so tokens don't have to be baked into images or flags.
+//
+// Supported references:
+//
+//	vault:secret/gswarm#hf_token   HashiCorp Vault (KV v1 or v2), via VAULT_ADDR and VAULT_TOKEN
+//	ssm:/gswarm/hf_token           AWS SSM Parameter Store, via the aws CLI and its usual credentials
+//
+// Any other value is returned unchanged.
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Deep-Commit/gswarm/internal/httpclient"
+)
+
+const (
+	vaultPrefix = "vault:"
+	ssmPrefix   = "ssm:"
+)
+
+// CommandRunner is used to run the aws CLI; tests can replace it
+var CommandRunner = exec.Command
+
+// HTTPClient is used for Vault requests; nil uses the shared gswarm client
+var HTTPClient *http.Client
+
+// IsRef reports whether value refers to a secret store
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, vaultPrefix) || strings.HasPrefix(value, ssmPrefix)
+}
+
+// Resolve returns the secret value referenced by value, or value itself when
+// it is not a reference
+func Resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, vaultPrefix):
+		return resolveVault(strings.TrimPrefix(value, vaultPrefix))
+	case strings.HasPrefix(value, ssmPrefix):
+		return resolveSSM(strings.TrimPrefix(value, ssmPrefix))
+	default:
+		return value, nil
+	}
+}
+
+// ResolveAll resolves each value in place, naming the first one that fails
+func ResolveAll(values map[string]*string) error {
+	for name, v := range values {
+		if v == nil || !IsRef(*v) {
+			continue
+		}
+		resolved, err := Resolve(*v)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		*v = resolved
+	}
+	return nil
+}
+
+// resolveVault reads path#key from Vault. KV v2 mounts are tried first
+// (secret/gswarm -> secret/data/gswarm), then the path as given.
+func resolveVault(ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %q, expected vault:<path>#<key>", ref)
+	}
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is not set")
+	}
+
+	path = strings.Trim(path, "/")
+	candidates := []string{path}
+	if mount, rest, ok := strings.Cut(path, "/"); ok && !strings.HasPrefix(rest, "data/") {
+		candidates = []string{mount + "/data/" + rest, path}
+	}
+
+	var lastErr error
+	for _, p := range candidates {
+		data, err := vaultRead(addr, token, p)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		// KV v2 nests the secret under data.data
+		if inner, ok := data["data"].(map[string]interface{}); ok {
+			data = inner
+		}
+		v, ok := data[key]
+		if !ok {
+			return "", fmt.Errorf("key %q not found in vault secret %s", key, path)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("vault secret %s#%s is not a string", path, key)
+		}
+		return s, nil
+	}
+	return "", lastErr
+}
+
+func vaultRead(addr, token, path string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, addr+"/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	client := HTTPClient
+	if client == nil {
+		client = httpclient.Default()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return body.Data, nil
+}
+
+// resolveSSM reads a (possibly SecureString) parameter with the aws CLI
+func resolveSSM(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("invalid ssm reference, expected ssm:<parameter-name>")
+	}
+	cmd := CommandRunner("aws", "ssm", "get-parameter", "--name", name,
+		"--with-decryption", "--query", "Parameter.Value", "--output", "text")
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return "", fmt.Errorf("aws ssm get-parameter %s: %s", name, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", fmt.Errorf("aws ssm get-parameter %s: %w", name, err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
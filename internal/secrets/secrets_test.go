@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestResolve_Vault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/gswarm":
+			w.Write([]byte(`{"data":{"data":{"hf_token":"hf_v2"}}}`))
+		case "/v1/kv1/gswarm":
+			w.Write([]byte(`{"data":{"hf_token":"hf_v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	cases := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"kv v2", "vault:secret/gswarm#hf_token", "hf_v2", false},
+		{"kv v1", "vault:kv1/gswarm#hf_token", "hf_v1", false},
+		{"missing key", "vault:secret/gswarm#nope", "", true},
+		{"missing path", "vault:secret/other#hf_token", "", true},
+		{"no key", "vault:secret/gswarm", "", true},
+		{"plain value", "hf_plain", "hf_plain", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Resolve(c.ref)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", c.ref, err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("Resolve(%q) = %q, want %q", c.ref, got, c.want)
+			}
+		})
+	}
+}
+
+func TestResolve_SSM(t *testing.T) {
+	var gotArgs []string
+	origCommandRunner := CommandRunner
+	defer func() { CommandRunner = origCommandRunner }()
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		gotArgs = append([]string{name}, args...)
+		return exec.Command("echo", "ssm-secret")
+	}
+
+	got, err := Resolve("ssm:/gswarm/hf_token")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got != "ssm-secret" {
+		t.Errorf("Resolve() = %q, want ssm-secret", got)
+	}
+	if !strings.Contains(strings.Join(gotArgs, " "), "get-parameter --name /gswarm/hf_token --with-decryption") {
+		t.Errorf("aws args = %v", gotArgs)
+	}
+}
+
+func TestResolveAll(t *testing.T) {
+	origCommandRunner := CommandRunner
+	defer func() { CommandRunner = origCommandRunner }()
+	CommandRunner = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("echo", "resolved")
+	}
+
+	token, org := "ssm:/gswarm/token", "org-123"
+	if err := ResolveAll(map[string]*string{"hf-token": &token, "org-id": &org}); err != nil {
+		t.Fatalf("ResolveAll() error = %v", err)
+	}
+	if token != "resolved" || org != "org-123" {
+		t.Errorf("ResolveAll() = %q, %q; want resolved, org-123", token, org)
+	}
+}
@@ -0,0 +1,142 @@
+// Package sharecard renders a node's peer ID and stats as a card to post
+// in community channels: plain text for chats, or a PNG with a QR code of
+// the peer ID that others can scan to look the node up.
+package sharecard
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/Deep-Commit/gswarm/internal/qrcode"
+)
+
+// Stat is one labeled value on a card, such as the rewards
+type Stat struct {
+	Label string
+	Value string
+}
+
+// Card is what a share card shows
+type Card struct {
+	Node   string
+	PeerID string
+	// Stats are shown under the peer ID, in order
+	Stats []Stat
+}
+
+// Text renders the card as aligned lines
+func (c Card) Text() string {
+	rows := append([]Stat{{Label: "Peer ID", Value: c.PeerID}}, c.Stats...)
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r.Label))
+	}
+	var b strings.Builder
+	b.WriteString(c.title() + "\n")
+	for _, r := range rows {
+		fmt.Fprintf(&b, "%-*s  %s\n", width, r.Label, r.Value)
+	}
+	return b.String()
+}
+
+func (c Card) title() string {
+	return "G-Swarm node " + c.Node
+}
+
+// Layout of the PNG, in pixels
+const (
+	margin     = 32
+	moduleSize = 6
+	quietZone  = 4 * moduleSize
+	titleScale = 4
+	labelScale = 2
+	valueScale = 3
+	rowGap     = 14
+	headerPad  = 24
+	columnGap  = 32
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	header     = color.RGBA{0x1f, 0x5f, 0x4a, 0xff}
+	headerText = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	labelText  = color.RGBA{0x6b, 0x72, 0x80, 0xff}
+	valueText  = color.RGBA{0x11, 0x18, 0x27, 0xff}
+	qrDark     = color.RGBA{0x00, 0x00, 0x00, 0xff}
+)
+
+// PNG draws the card with a QR code of the peer ID and writes it to w
+func (c Card) PNG(w io.Writer) error {
+	code, err := qrcode.Encode(c.PeerID)
+	if err != nil {
+		return fmt.Errorf("failed to encode the peer ID as a QR code: %w", err)
+	}
+	rows := append([]Stat{{Label: "Peer ID", Value: c.PeerID}}, c.Stats...)
+
+	title := c.title()
+	qrBox := code.Size*moduleSize + 2*quietZone
+	rowHeight := (labelScale+valueScale)*glyphHeight + rowGap
+	textWidth := 0
+	for _, r := range rows {
+		textWidth = max(textWidth, textSize(r.Label, labelScale), textSize(r.Value, valueScale))
+	}
+	headerHeight := titleScale*glyphHeight + 2*headerPad
+	width := max(2*margin+qrBox+columnGap+textWidth, 2*margin+textSize(title, titleScale))
+	height := headerHeight + margin + max(qrBox, len(rows)*rowHeight) + margin
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, width, headerHeight), image.NewUniform(header), image.Point{}, draw.Src)
+	drawText(img, margin, headerPad, titleScale, title, headerText)
+
+	// The QR code's quiet zone is the white background around it
+	top := headerHeight + margin
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Dark(x, y) {
+				px, py := margin+quietZone+x*moduleSize, top+quietZone+y*moduleSize
+				draw.Draw(img, image.Rect(px, py, px+moduleSize, py+moduleSize), image.NewUniform(qrDark), image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	left := margin + qrBox + columnGap
+	y := top
+	for _, r := range rows {
+		drawText(img, left, y, labelScale, r.Label, labelText)
+		drawText(img, left, y+labelScale*glyphHeight, valueScale, r.Value, valueText)
+		y += rowHeight
+	}
+	return png.Encode(w, img)
+}
+
+// textSize is the width of s drawn at scale
+func textSize(s string, scale int) int {
+	return len([]rune(s)) * glyphWidth * scale
+}
+
+// drawText draws s with its top left corner at x, y, each font pixel a
+// scale by scale square
+func drawText(img *image.RGBA, x, y, scale int, s string, col color.RGBA) {
+	for _, r := range s {
+		for cx, column := range glyph(r) {
+			for cy := 0; cy < 8; cy++ {
+				if column&(1<<cy) == 0 {
+					continue
+				}
+				px, py := x+cx*scale, y+cy*scale
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						img.SetRGBA(px+dx, py+dy, col)
+					}
+				}
+			}
+		}
+		x += glyphWidth * scale
+	}
+}
@@ -0,0 +1,55 @@
+package sharecard
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+var card = Card{
+	Node:   "gpu-a",
+	PeerID: "QmYyQSo1c1Ym7orWxLYvCrM2EmxFTANf8wXmmE7DWjhx5N",
+	Stats:  []Stat{{"Rewards", "5,400 (+480 in 24h)"}, {"Votes", "120 (+12 in 24h)"}},
+}
+
+func TestText(t *testing.T) {
+	want := "G-Swarm node gpu-a\n" +
+		"Peer ID  QmYyQSo1c1Ym7orWxLYvCrM2EmxFTANf8wXmmE7DWjhx5N\n" +
+		"Rewards  5,400 (+480 in 24h)\n" +
+		"Votes    120 (+12 in 24h)\n"
+	if got := card.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := card.PNG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("the card isn't a PNG: %v", err)
+	}
+	b := img.Bounds()
+	// The peer ID is the widest text, and has to fit beside the QR code
+	if want := textSize(card.PeerID, valueScale); b.Dx() < want || b.Dy() < 200 {
+		t.Errorf("card is %dx%d", b.Dx(), b.Dy())
+	}
+	if r, g, bl, _ := img.At(1, 1).RGBA(); r>>8 != uint32(header.R) || g>>8 != uint32(header.G) || bl>>8 != uint32(header.B) {
+		t.Errorf("header color = %v", img.At(1, 1))
+	}
+
+	long := card
+	long.PeerID = strings.Repeat("Qm", 100)
+	if err := long.PNG(&buf); err == nil {
+		t.Error("PNG() accepted a peer ID too long for a QR code")
+	}
+}
+
+func TestGlyph(t *testing.T) {
+	if glyph('é') != glyph('?') || glyph('A') == glyph('?') {
+		t.Error("characters outside ASCII should be drawn as ?")
+	}
+}
@@ -0,0 +1,127 @@
+// Package smoke supports `gswarm smoke-test`, a short, bounded training run
+// that checks the whole pipeline works before committing to a long run.
+package smoke
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for the smoke test run
+const (
+	DefaultSteps   = 2
+	DefaultTimeout = 20 * time.Minute
+)
+
+// overrides are the trainer config keys rewritten for a smoke test. Only
+// keys already in the config are touched, since the trainer rejects
+// unknown keys.
+var overrides = []struct {
+	key   string
+	value func(steps int) string
+}{
+	{"max_steps", func(steps int) string { return fmt.Sprint(steps) }},
+	{"logging_steps", func(int) string { return "1" }},
+	{"save_strategy", func(int) string { return `"no"` }},
+}
+
+// TinyConfig rewrites a trainer YAML config to stop after steps training
+// steps and skip checkpoint saves
+func TinyConfig(base []byte, steps int) ([]byte, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+	out := base
+	for _, o := range overrides {
+		pattern := regexp.MustCompile(`(?m)^` + o.key + `:.*$`)
+		out = pattern.ReplaceAll(out, []byte(o.key+": "+o.value(steps)))
+	}
+	if !bytes.Contains(out, []byte("max_steps: ")) {
+		return nil, errors.New("trainer config has no top-level max_steps to limit")
+	}
+	return out, nil
+}
+
+// LimitSteps rewrites only the max_steps of a trainer YAML config, for short
+// debugging runs that otherwise train as usual
+func LimitSteps(base []byte, steps int) ([]byte, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+	pattern := regexp.MustCompile(`(?m)^max_steps:.*$`)
+	if !pattern.Match(base) {
+		return nil, errors.New("trainer config has no top-level max_steps to limit")
+	}
+	return pattern.ReplaceAll(base, []byte(fmt.Sprintf("max_steps: %d", steps))), nil
+}
+
+// Counter counts the bytes written to it; the trainer's stdout and stderr
+// may write concurrently
+type Counter struct {
+	n atomic.Int64
+}
+
+// Write implements io.Writer
+func (c *Counter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// Bytes returns the number of bytes written so far
+func (c *Counter) Bytes() int64 {
+	return c.n.Load()
+}
+
+// Result is the outcome of a smoke test run
+type Result struct {
+	Duration    time.Duration
+	OutputBytes int64
+	// Rounds is the number of rounds the trainer announced
+	Rounds int
+	// Err is how the trainer exited, nil for a clean exit
+	Err      error
+	TimedOut bool
+	LogFile  string
+	// Diagnosis explains known errors seen in the output
+	Diagnosis string
+}
+
+// Passed reports whether the trainer produced output and exited cleanly
+// within the time limit
+func (r Result) Passed() bool {
+	return r.Err == nil && !r.TimedOut && r.OutputBytes > 0
+}
+
+// Text renders the result as a human-readable summary
+func (r Result) Text() string {
+	var b strings.Builder
+	if r.Passed() {
+		b.WriteString("Smoke test passed\n")
+	} else {
+		b.WriteString("Smoke test FAILED\n")
+	}
+	fmt.Fprintf(&b, "Duration: %s\n", r.Duration.Round(time.Second))
+	fmt.Fprintf(&b, "Output: %d bytes, %d rounds\n", r.OutputBytes, r.Rounds)
+	switch {
+	case r.TimedOut:
+		b.WriteString("Exit: did not finish within the time limit\n")
+	case r.Err != nil:
+		fmt.Fprintf(&b, "Exit: %v\n", r.Err)
+	case r.OutputBytes == 0:
+		b.WriteString("Exit: clean, but the trainer printed nothing\n")
+	default:
+		b.WriteString("Exit: clean\n")
+	}
+	if r.LogFile != "" {
+		fmt.Fprintf(&b, "Log: %s\n", r.LogFile)
+	}
+	if r.Diagnosis != "" {
+		fmt.Fprintf(&b, "\n%s\n", r.Diagnosis)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,86 @@
+package smoke
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTinyConfig(t *testing.T) {
+	base := `model_name_or_path: Gensyn/Qwen2.5-0.5B-Instruct
+max_steps: 20 # per round
+logging_steps: 2
+save_strategy: "steps"
+nested:
+  max_steps: 99
+`
+	got, err := TinyConfig([]byte(base), 3)
+	if err != nil {
+		t.Fatalf("TinyConfig() error = %v", err)
+	}
+	want := `model_name_or_path: Gensyn/Qwen2.5-0.5B-Instruct
+max_steps: 3
+logging_steps: 1
+save_strategy: "no"
+nested:
+  max_steps: 99
+`
+	if string(got) != want {
+		t.Errorf("TinyConfig() =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := TinyConfig([]byte("model_name_or_path: x\n"), 3); err == nil {
+		t.Error("TinyConfig() without max_steps error = nil, want an error")
+	}
+	if _, err := TinyConfig([]byte(base), 0); err == nil {
+		t.Error("TinyConfig() with 0 steps error = nil, want an error")
+	}
+}
+
+func TestLimitSteps(t *testing.T) {
+	base := "max_steps: 20 # per round\nlogging_steps: 2\nnested:\n  max_steps: 99\n"
+	got, err := LimitSteps([]byte(base), 5)
+	if err != nil {
+		t.Fatalf("LimitSteps() error = %v", err)
+	}
+	if want := "max_steps: 5\nlogging_steps: 2\nnested:\n  max_steps: 99\n"; string(got) != want {
+		t.Errorf("LimitSteps() = %q, want %q", got, want)
+	}
+	if _, err := LimitSteps([]byte("logging_steps: 2\n"), 5); err == nil {
+		t.Error("LimitSteps() without max_steps error = nil, want an error")
+	}
+}
+
+func TestResult(t *testing.T) {
+	cases := []struct {
+		name   string
+		r      Result
+		passed bool
+		text   string
+	}{
+		{"clean", Result{Duration: time.Minute, OutputBytes: 512, Rounds: 1}, true, "Exit: clean"},
+		{"error", Result{OutputBytes: 512, Err: errors.New("exit status 1")}, false, "Exit: exit status 1"},
+		{"timeout", Result{OutputBytes: 512, TimedOut: true}, false, "did not finish"},
+		{"silent", Result{}, false, "printed nothing"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.r.Passed(); got != c.passed {
+				t.Errorf("Passed() = %v, want %v", got, c.passed)
+			}
+			if text := c.r.Text(); !strings.Contains(text, c.text) {
+				t.Errorf("Text() = %q, want it to contain %q", text, c.text)
+			}
+		})
+	}
+}
+
+func TestCounter(t *testing.T) {
+	var c Counter
+	c.Write([]byte("hello "))
+	c.Write([]byte("world"))
+	if got := c.Bytes(); got != 11 {
+		t.Errorf("Bytes() = %d, want 11", got)
+	}
+}
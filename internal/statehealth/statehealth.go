@@ -0,0 +1,94 @@
+// Package statehealth tracks whether a process could save its state
+// files, so a read-only or full disk shows up in notifications and health
+// checks instead of silently causing repeated welcome messages and lost
+// baselines.
+package statehealth
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Failure is a state file that couldn't be saved
+type Failure struct {
+	File  string    `json:"file"`
+	Error string    `json:"error"`
+	Since time.Time `json:"since"`
+	// Count is the number of failed saves in a row
+	Count int `json:"count"`
+}
+
+// Tracker records the outcome of each state file's latest save
+type Tracker struct {
+	mu       sync.Mutex
+	failures map[string]*Failure
+}
+
+// Change is what a save changed about a file's health
+type Change int
+
+// Changes reported by Record
+const (
+	Unchanged Change = iota
+	// Failed is the first failure after the file was saved fine
+	Failed
+	// Recovered is the first save to work after failures
+	Recovered
+)
+
+// Record stores the result of saving file at now and reports whether the
+// file started or stopped failing
+func (t *Tracker) Record(file string, err error, now time.Time) Change {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f := t.failures[file]
+	switch {
+	case err == nil && f == nil:
+		return Unchanged
+	case err == nil:
+		delete(t.failures, file)
+		return Recovered
+	case f != nil:
+		f.Error = err.Error()
+		f.Count++
+		return Unchanged
+	}
+	if t.failures == nil {
+		t.failures = map[string]*Failure{}
+	}
+	t.failures[file] = &Failure{File: file, Error: err.Error(), Since: now, Count: 1}
+	return Failed
+}
+
+// Failures returns the files currently failing, by name
+func (t *Tracker) Failures() []Failure {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Failure, 0, len(t.failures))
+	for _, f := range t.failures {
+		out = append(out, *f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].File < out[j].File })
+	return out
+}
+
+// Handler serves /healthz: "ok" while every state file saves, and 503
+// listing the failing files otherwise
+func (t *Tracker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		failures := t.Failures()
+		if len(failures) == 0 {
+			w.Write([]byte("ok\n"))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, f := range failures {
+			fmt.Fprintf(w, "can't save %s since %s (%d attempts): %s\n", f.File, f.Since.Format(time.RFC3339), f.Count, f.Error)
+		}
+	})
+	return mux
+}
@@ -0,0 +1,56 @@
+package statehealth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	var tr Tracker
+	now := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	readOnly := errors.New("read-only file system")
+	steps := []struct {
+		err  error
+		want Change
+	}{
+		{nil, Unchanged},
+		{readOnly, Failed},
+		{readOnly, Unchanged},
+		{nil, Recovered},
+		{nil, Unchanged},
+	}
+	for i, s := range steps {
+		if got := tr.Record("state.json", s.err, now); got != s.want {
+			t.Errorf("step %d: Record() = %d, want %d", i, got, s.want)
+		}
+		if i == 2 {
+			if f := tr.Failures(); len(f) != 1 || f[0].Count != 2 || !f[0].Since.Equal(now) {
+				t.Errorf("Failures() = %+v, want one failing twice", f)
+			}
+		}
+	}
+	if f := tr.Failures(); len(f) != 0 {
+		t.Errorf("Failures() after recovery = %+v", f)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var tr Tracker
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		tr.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec
+	}
+	if rec := get(); rec.Code != http.StatusOK {
+		t.Errorf("healthy: status %d", rec.Code)
+	}
+	tr.Record("telegram-config.json", errors.New("permission denied"), time.Now())
+	rec := get()
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "telegram-config.json") {
+		t.Errorf("failing: status %d, body %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,207 @@
+// Package status tracks the supervisor's live state, persists it to the state
+// directory and serves it over a local HTTP API.
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/memwatch"
+	"github.com/Deep-Commit/gswarm/internal/statehealth"
+	"github.com/Deep-Commit/gswarm/internal/versions"
+)
+
+// FileName is the status snapshot file inside the state directory
+const FileName = "status.json"
+
+// DefaultListen is the default address of the status API
+const DefaultListen = "127.0.0.1:8686"
+
+// Supervisor states
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StatePaused   = "paused"
+	StateBackoff  = "backoff"
+	StateStopped  = "stopped"
+)
+
+// Schedule describes pause window state
+type Schedule struct {
+	Windows    []string  `json:"windows"`
+	Paused     bool      `json:"paused"`
+	NextChange time.Time `json:"next_change,omitempty"`
+}
+
+// Snapshot is the supervisor state reported by the API
+type Snapshot struct {
+	State        string    `json:"state"`
+	PID          int       `json:"pid"`
+	PeerID       string    `json:"peer_id,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	RunNumber    int       `json:"run_number"`
+	RunID        string    `json:"run_id,omitempty"`
+	RunStartedAt time.Time `json:"run_started_at,omitempty"`
+	LastRound    int       `json:"last_round,omitempty"`
+	Restarts     int       `json:"restarts"`
+	LastError    string    `json:"last_error,omitempty"`
+	LastExit     time.Time `json:"last_exit,omitempty"`
+	Schedule     *Schedule `json:"schedule,omitempty"`
+	// Versions is the gswarm build and rl-swarm checkout being run
+	Versions *versions.Info `json:"versions,omitempty"`
+	// Memory is the running trainer's latest memory sample
+	Memory *memwatch.Usage `json:"memory,omitempty"`
+}
+
+// Tracker holds the current snapshot and mirrors it to disk
+type Tracker struct {
+	mu     sync.RWMutex
+	snap   Snapshot
+	path   string
+	health statehealth.Tracker
+}
+
+// NewTracker creates a tracker that persists to stateDir; an empty stateDir
+// keeps the status in memory only
+func NewTracker(stateDir string) *Tracker {
+	now := time.Now()
+	t := &Tracker{snap: Snapshot{State: StateStarting, PID: os.Getpid(), StartedAt: now, UpdatedAt: now}}
+	if stateDir != "" {
+		t.path = filepath.Join(stateDir, FileName)
+	}
+	return t
+}
+
+// Update applies fn to the snapshot and persists the result
+func (t *Tracker) Update(fn func(*Snapshot)) error {
+	t.mu.Lock()
+	fn(&t.snap)
+	t.snap.UpdatedAt = time.Now()
+	snap := t.snap
+	t.mu.Unlock()
+
+	if t.path == "" {
+		return nil
+	}
+	err := write(t.path, snap)
+	t.health.Record(FileName, err, snap.UpdatedAt)
+	return err
+}
+
+// Snapshot returns a copy of the current state
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.snap
+}
+
+// Handler serves /healthz and /api/v1/status. /healthz fails with 503
+// once the supervisor has stopped or while status.json can't be saved.
+func (t *Tracker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	health := t.health.Handler()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if t.Snapshot().State == StateStopped {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("supervisor stopped\n"))
+			return
+		}
+		health.ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/api/v1/status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(t.Snapshot())
+	})
+	return mux
+}
+
+// Read loads the last persisted snapshot from stateDir
+func Read(stateDir string) (*Snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+	return &snap, nil
+}
+
+// Fetch gets the snapshot from the status API listening on listen. An
+// address listening on all interfaces, such as ":8686", is reached on the
+// loopback interface.
+func Fetch(ctx context.Context, client *http.Client, listen string) (*Snapshot, error) {
+	url, resp, err := get(ctx, client, listen, "/api/v1/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	var snap Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+	return &snap, nil
+}
+
+// Check asks the status API listening on listen for /healthz and returns
+// an error with its answer unless the supervisor is healthy
+func Check(ctx context.Context, client *http.Client, listen string) error {
+	url, resp, err := get(ctx, client, listen, "/healthz")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// get requests path from the status API listening on listen, on the
+// loopback interface when it listens on all of them
+func get(ctx context.Context, client *http.Client, listen, path string) (string, *http.Response, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", nil, err
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	url := "http://" + net.JoinHostPort(host, port) + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return url, nil, err
+	}
+	resp, err := client.Do(req)
+	return url, resp, err
+}
+
+// write replaces the status file atomically so readers never see a partial snapshot
+func write(path string, snap Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
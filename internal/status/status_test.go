@@ -0,0 +1,116 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTracker_UpdateAndRead(t *testing.T) {
+	dir := t.TempDir()
+	tr := NewTracker(dir)
+
+	err := tr.Update(func(s *Snapshot) {
+		s.State = StatePaused
+		s.Schedule = &Schedule{Windows: []string{"08:00-18:00 weekdays"}, Paused: true}
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	snap, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if snap.State != StatePaused || snap.Schedule == nil || !snap.Schedule.Paused {
+		t.Errorf("Read() = %+v, want paused schedule state", snap)
+	}
+}
+
+func TestTracker_Handler(t *testing.T) {
+	tr := NewTracker("")
+	tr.Update(func(s *Snapshot) { s.State = StateRunning; s.Restarts = 2 })
+
+	rec := httptest.NewRecorder()
+	tr.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/status", nil))
+
+	var snap Snapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &snap); err != nil {
+		t.Fatalf("status response is not JSON: %v", err)
+	}
+	if snap.State != StateRunning || snap.Restarts != 2 {
+		t.Errorf("status = %+v, want running with 2 restarts", snap)
+	}
+
+	rec = httptest.NewRecorder()
+	tr.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != 200 {
+		t.Errorf("/healthz status = %d, want 200", rec.Code)
+	}
+
+	tr.Update(func(s *Snapshot) { s.State = StateStopped })
+	rec = httptest.NewRecorder()
+	tr.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz status once stopped = %d, want 503", rec.Code)
+	}
+
+	// a state directory that can't be written fails the health check
+	unsaved := NewTracker(filepath.Join(t.TempDir(), "missing"))
+	if err := unsaved.Update(func(s *Snapshot) { s.State = StateRunning }); err == nil {
+		t.Fatal("Update() into a missing directory error = nil")
+	}
+	rec = httptest.NewRecorder()
+	unsaved.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), FileName) {
+		t.Errorf("/healthz = %d %q, want 503 naming %s", rec.Code, rec.Body.String(), FileName)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	tr := NewTracker("")
+	tr.Update(func(s *Snapshot) { s.State = StateBackoff; s.LastError = "exit status 1" })
+	srv := httptest.NewServer(tr.Handler())
+	defer srv.Close()
+
+	listen := strings.TrimPrefix(srv.URL, "http://")
+	snap, err := Fetch(context.Background(), srv.Client(), listen)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if snap.State != StateBackoff || snap.LastError != "exit status 1" {
+		t.Errorf("Fetch() = %+v, want backoff with the last error", snap)
+	}
+
+	// a supervisor listening on all interfaces is reached on loopback
+	port := listen[strings.LastIndex(listen, ":"):]
+	if _, err := Fetch(context.Background(), srv.Client(), "0.0.0.0"+port); err != nil {
+		t.Errorf("Fetch(0.0.0.0%s) error = %v", port, err)
+	}
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	if _, err := Fetch(context.Background(), notFound.Client(), strings.TrimPrefix(notFound.URL, "http://")); err == nil {
+		t.Error("Fetch() accepted a 404")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tr := NewTracker("")
+	tr.Update(func(s *Snapshot) { s.State = StateRunning })
+	srv := httptest.NewServer(tr.Handler())
+	defer srv.Close()
+
+	listen := strings.TrimPrefix(srv.URL, "http://")
+	if err := Check(context.Background(), srv.Client(), listen); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	tr.Update(func(s *Snapshot) { s.State = StateStopped })
+	if err := Check(context.Background(), srv.Client(), listen); err == nil || !strings.Contains(err.Error(), "supervisor stopped") {
+		t.Errorf("Check() once stopped error = %v, want the /healthz answer", err)
+	}
+}
@@ -0,0 +1,241 @@
+// Package statuspage renders a read-only public snapshot of the node's
+// status as static JSON and HTML and publishes it to a local directory, S3
+// or a git branch (e.g. GitHub Pages).
+package statuspage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/timefmt"
+)
+
+// CommandRunner runs the aws and git CLIs; tests can replace it
+var CommandRunner = exec.Command
+
+// DefaultRefresh is how often the HTML page reloads itself when Page
+// doesn't say
+const DefaultRefresh = 5 * time.Minute
+
+// GitRepublish is how often an otherwise unchanged page is still committed
+// to a git destination, so its update time shows the node is alive without
+// a commit at every interval
+const GitRepublish = time.Hour
+
+// Page is the public status snapshot
+type Page struct {
+	Node      string    `json:"node"`
+	State     string    `json:"state"`
+	Generated time.Time `json:"generated"`
+	Uptime    string    `json:"uptime"`
+	Restarts  int       `json:"restarts"`
+	LastRound int       `json:"last_round,omitempty"`
+	Rewards   string    `json:"rewards,omitempty"`
+	// Refresh is how often the HTML page reloads itself, normally the
+	// publishing interval
+	Refresh time.Duration `json:"-"`
+	// Time renders the update time on the HTML page
+	Time timefmt.Formatter `json:"-"`
+}
+
+// Same reports whether p shows the same status as o, ignoring when each was
+// generated and the uptime that comes with it
+func (p Page) Same(o Page) bool {
+	p.Generated, p.Uptime = o.Generated, o.Uptime
+	return p == o
+}
+
+// refreshSeconds is the page's meta refresh in whole seconds
+func refreshSeconds(d time.Duration) int {
+	if d <= 0 {
+		d = DefaultRefresh
+	}
+	return max(int(d.Seconds()), 1)
+}
+
+var pageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{"seconds": refreshSeconds}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{seconds .Refresh}}">
+<title>{{.Node}} – G-Swarm status</title>
+<style>body{font-family:sans-serif;max-width:32em;margin:2em auto}td{padding:.3em 1em}</style>
+</head>
+<body>
+<h1>{{.Node}}</h1>
+<table>
+<tr><td>State</td><td>{{.State}}</td></tr>
+<tr><td>Uptime</td><td>{{.Uptime}}</td></tr>
+<tr><td>Restarts</td><td>{{.Restarts}}</td></tr>
+{{if .LastRound}}<tr><td>Last round</td><td>{{.LastRound}}</td></tr>{{end}}
+{{if .Rewards}}<tr><td>Total rewards</td><td>{{.Rewards}}</td></tr>{{end}}
+</table>
+<p><small>Updated {{.Time.Format .Generated}}</small></p>
+</body>
+</html>
+`))
+
+// Render returns the status.json and index.html files for the page
+func Render(p Page) (map[string][]byte, error) {
+	js, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var html bytes.Buffer
+	if err := pageTemplate.Execute(&html, p); err != nil {
+		return nil, err
+	}
+	return map[string][]byte{"status.json": js, "index.html": html.Bytes()}, nil
+}
+
+// Publisher uploads rendered files to a destination
+type Publisher interface {
+	Publish(files map[string][]byte) error
+}
+
+// NewPublisher returns the publisher for a destination:
+//
+//	s3://bucket/prefix            uploaded with the aws CLI
+//	git+<repo-url>#<branch>       committed and pushed, e.g. to gh-pages
+//	/var/www/gswarm or file://... written to a local directory
+//
+// workDir holds the git checkout for git destinations.
+func NewPublisher(dest, workDir string) (Publisher, error) {
+	switch {
+	case strings.HasPrefix(dest, "s3://"):
+		return &S3{URI: strings.TrimRight(dest, "/")}, nil
+	case strings.HasPrefix(dest, "git+"):
+		repo, branch, _ := strings.Cut(strings.TrimPrefix(dest, "git+"), "#")
+		if branch == "" {
+			branch = "gh-pages"
+		}
+		return &Git{Repo: repo, Branch: branch, Dir: filepath.Join(workDir, "statuspage")}, nil
+	case strings.HasPrefix(dest, "file://"):
+		return &Dir{Path: strings.TrimPrefix(dest, "file://")}, nil
+	case strings.Contains(dest, "://"):
+		return nil, fmt.Errorf("unsupported status export destination %q", dest)
+	default:
+		return &Dir{Path: dest}, nil
+	}
+}
+
+// Dir writes the files to a local directory, e.g. one served by nginx
+type Dir struct {
+	Path string
+}
+
+// Publish implements Publisher
+func (d *Dir) Publish(files map[string][]byte) error {
+	if err := os.MkdirAll(d.Path, 0o755); err != nil {
+		return err
+	}
+	return writeFiles(d.Path, files)
+}
+
+// writeFiles writes each file via a temp file so the web server never
+// serves a partial page
+func writeFiles(dir string, files map[string][]byte) error {
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+			return err
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// S3 uploads the files with the aws CLI and its usual credentials
+type S3 struct {
+	URI string
+}
+
+// Publish implements Publisher
+func (s *S3) Publish(files map[string][]byte) error {
+	tmp, err := os.MkdirTemp("", "gswarm-status")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := writeFiles(tmp, files); err != nil {
+		return err
+	}
+	for name := range files {
+		cmd := CommandRunner("aws", "s3", "cp", filepath.Join(tmp, name), s.URI+"/"+name,
+			"--cache-control", "max-age=60", "--only-show-errors")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("aws s3 cp %s: %v: %s", name, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// Git commits the files to a branch and pushes it
+type Git struct {
+	Repo   string
+	Branch string
+	Dir    string
+}
+
+// Publish implements Publisher
+func (g *Git) Publish(files map[string][]byte) error {
+	if err := g.ensureCheckout(); err != nil {
+		return err
+	}
+	if err := writeFiles(g.Dir, files); err != nil {
+		return err
+	}
+	if err := g.git("add", "-A"); err != nil {
+		return err
+	}
+	// Nothing to commit when the page hasn't changed
+	if err := g.git("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	if err := g.git("-c", "user.name=gswarm", "-c", "user.email=gswarm@localhost",
+		"commit", "-q", "-m", "Update node status"); err != nil {
+		return err
+	}
+	return g.git("push", "-q", "origin", "HEAD:"+g.Branch)
+}
+
+// ensureCheckout clones the branch on first use, starting an orphan branch
+// if it doesn't exist yet
+func (g *Git) ensureCheckout() error {
+	if _, err := os.Stat(filepath.Join(g.Dir, ".git")); err == nil {
+		return g.git("pull", "-q", "--rebase", "origin", g.Branch)
+	}
+	if err := os.MkdirAll(filepath.Dir(g.Dir), 0o755); err != nil {
+		return err
+	}
+	cmd := CommandRunner("git", "clone", "-q", "--depth", "1", "--branch", g.Branch, g.Repo, g.Dir)
+	if err := cmd.Run(); err == nil {
+		return nil
+	}
+	os.RemoveAll(g.Dir)
+	cmd = CommandRunner("git", "clone", "-q", "--depth", "1", g.Repo, g.Dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git clone %s: %v: %s", g.Repo, err, strings.TrimSpace(string(out)))
+	}
+	if err := g.git("checkout", "-q", "--orphan", g.Branch); err != nil {
+		return err
+	}
+	return g.git("rm", "-rq", "--ignore-unmatch", ".")
+}
+
+func (g *Git) git(args ...string) error {
+	cmd := CommandRunner("git", append([]string{"-C", g.Dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package statuspage
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	files, err := Render(Page{Node: "node-<1>", State: "running", Generated: time.Now(), Uptime: "2h0m0s", LastRound: 1234, Rewards: "56"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	html := string(files["index.html"])
+	for _, want := range []string{"node-&lt;1&gt;", "1234", "56"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("index.html missing %q", want)
+		}
+	}
+	if !strings.Contains(string(files["status.json"]), `"last_round": 1234`) {
+		t.Errorf("status.json = %s", files["status.json"])
+	}
+	if !strings.Contains(html, `content="300"`) {
+		t.Errorf("index.html doesn't refresh every 5 minutes by default")
+	}
+
+	files, _ = Render(Page{Node: "n", Refresh: time.Minute})
+	if !strings.Contains(string(files["index.html"]), `content="60"`) {
+		t.Errorf("index.html doesn't refresh with the interval:\n%s", files["index.html"])
+	}
+}
+
+func TestPage_Same(t *testing.T) {
+	p := Page{Node: "n", State: "running", Generated: time.Now(), Uptime: "1h0m0s", Restarts: 1}
+	later := p
+	later.Generated, later.Uptime = p.Generated.Add(time.Hour), "2h0m0s"
+	if !p.Same(later) {
+		t.Error("Same() = false for pages differing only in time")
+	}
+	later.Restarts = 2
+	if p.Same(later) {
+		t.Error("Same() = true for pages with different restarts")
+	}
+}
+
+func TestNewPublisher(t *testing.T) {
+	cases := []struct {
+		dest    string
+		want    string
+		wantErr bool
+	}{
+		{"s3://bucket/node1/", "*statuspage.S3", false},
+		{"git+https://github.com/me/status.git#gh-pages", "*statuspage.Git", false},
+		{"file:///var/www/gswarm", "*statuspage.Dir", false},
+		{"/var/www/gswarm", "*statuspage.Dir", false},
+		{"ftp://example.com", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.dest, func(t *testing.T) {
+			p, err := NewPublisher(c.dest, t.TempDir())
+			if (err != nil) != c.wantErr {
+				t.Fatalf("NewPublisher() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if got := fmt.Sprintf("%T", p); !c.wantErr && got != c.want {
+				t.Errorf("NewPublisher() = %s, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDir_Publish(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "www")
+	if err := (&Dir{Path: dir}).Publish(map[string][]byte{"status.json": []byte("{}")}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "status.json")); err != nil || string(data) != "{}" {
+		t.Errorf("status.json = %q, %v", data, err)
+	}
+}
+
+func TestGit_Publish(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	seed := filepath.Join(t.TempDir(), "seed")
+	for _, args := range [][]string{
+		{"init", "-q", "--bare", remote},
+		{"init", "-q", seed},
+		{"-C", seed, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"-C", seed, "push", "-q", remote, "HEAD:main"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	g := &Git{Repo: remote, Branch: "gh-pages", Dir: filepath.Join(t.TempDir(), "pages")}
+	if err := g.Publish(map[string][]byte{"status.json": []byte(`{"state":"running"}`)}); err != nil {
+		t.Fatalf("first Publish() error = %v", err)
+	}
+	if err := g.Publish(map[string][]byte{"status.json": []byte(`{"state":"paused"}`)}); err != nil {
+		t.Fatalf("second Publish() error = %v", err)
+	}
+
+	out, err := exec.Command("git", "--git-dir", remote, "show", "gh-pages:status.json").Output()
+	if err != nil || string(out) != `{"state":"paused"}` {
+		t.Errorf("gh-pages status.json = %q, %v", out, err)
+	}
+}
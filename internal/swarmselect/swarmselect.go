@@ -0,0 +1,116 @@
+// Package swarmselect records which swarm a node should join, so that
+// `gswarm switch` can move a running node to the other swarm: the command
+// writes the selection to the state directory and the supervisor, which
+// watches the file, restarts the trainer with the new swarm's settings.
+package swarmselect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StateFile holds the selection in the state directory
+const StateFile = "swarm.json"
+
+// Selection is the swarm the node was switched to
+type Selection struct {
+	BigSwarm bool      `json:"big_swarm"`
+	Selected time.Time `json:"selected"`
+}
+
+// Name is the swarm's name as shown to users
+func (s Selection) Name() string {
+	if s.BigSwarm {
+		return "Math Hard (big swarm)"
+	}
+	return "Math (small swarm)"
+}
+
+// Read returns the selection saved in stateDir, or nil when there is none
+func Read(stateDir string) (*Selection, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, StateFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read swarm selection: %w", err)
+	}
+	var s Selection
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse swarm selection: %w", err)
+	}
+	return &s, nil
+}
+
+// Write saves s to stateDir, replacing any earlier selection
+func Write(stateDir string, s Selection) error {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(stateDir, StateFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write swarm selection: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Clear removes the selection, so the configured swarm applies again
+func Clear(stateDir string) error {
+	err := os.Remove(filepath.Join(stateDir, StateFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear swarm selection: %w", err)
+	}
+	return nil
+}
+
+// Watch polls stateDir every interval and sends each selection written
+// after Watch was called. A cleared selection isn't sent: it only takes
+// effect on the next start. The channel is closed when ctx is done.
+func Watch(ctx context.Context, stateDir string, interval time.Duration) <-chan Selection {
+	ch := make(chan Selection, 1)
+	path := filepath.Join(stateDir, StateFile)
+	modTime := func() time.Time {
+		if info, err := os.Stat(path); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+	last := modTime()
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			mod := modTime()
+			if mod.IsZero() || mod.Equal(last) {
+				last = mod
+				continue
+			}
+			last = mod
+			s, err := Read(stateDir)
+			if err != nil || s == nil {
+				continue
+			}
+			select {
+			case ch <- *s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,94 @@
+package swarmselect
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadWriteClear(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Read(dir)
+	if err != nil || s != nil {
+		t.Fatalf("Read() on empty dir = %v, %v; want nil, nil", s, err)
+	}
+
+	want := Selection{BigSwarm: true, Selected: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	if err := Write(dir, want); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Read(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s == nil || s.BigSwarm != want.BigSwarm || !s.Selected.Equal(want.Selected) {
+		t.Errorf("Read() = %+v, want %+v", s, want)
+	}
+
+	if err := Clear(dir); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := Read(dir); s != nil {
+		t.Errorf("Read() after Clear = %+v, want nil", s)
+	}
+	if err := Clear(dir); err != nil {
+		t.Errorf("Clear() without a selection: %v", err)
+	}
+}
+
+func TestReadDamaged(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, StateFile), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(dir); err == nil {
+		t.Error("Read() of a damaged file succeeded")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	// A selection that predates the watch isn't a switch
+	if err := Write(dir, Selection{BigSwarm: false}); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, StateFile), past, past)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := Watch(ctx, dir, 10*time.Millisecond)
+
+	select {
+	case s := <-ch:
+		t.Fatalf("got %+v before any switch", s)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := Write(dir, Selection{BigSwarm: true}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case s := <-ch:
+		if !s.BigSwarm {
+			t.Errorf("got %+v, want the big swarm", s)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("switch not seen")
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestName(t *testing.T) {
+	if got := (Selection{BigSwarm: true}).Name(); got != "Math Hard (big swarm)" {
+		t.Errorf("Name() = %q", got)
+	}
+	if got := (Selection{}).Name(); got != "Math (small swarm)" {
+		t.Errorf("Name() = %q", got)
+	}
+}
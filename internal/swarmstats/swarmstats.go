@@ -0,0 +1,320 @@
+// Package swarmstats compares a node's reward velocity with the rest of its
+// swarm. It samples peers across the voter leaderboard, reads their reward
+// totals at each comparison, and ranks the node's rewards per hour since
+// the previous comparison against the sampled peers that earned in that
+// time. A node far below the median usually has slower hardware or a
+// config that doesn't suit it.
+package swarmstats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+)
+
+// StateFile is where the comparer persists its sample and the totals it
+// last read, relative to the state directory
+const StateFile = "swarm_comparison.json"
+
+// DefaultInterval is how often the comparison runs
+const DefaultInterval = 6 * time.Hour
+
+// DefaultSampleSize is the number of other peers sampled
+const DefaultSampleSize = 50
+
+// resampleAfter is how long a sample is kept. Peers come and go, so it is
+// redrawn weekly; the comparison after a redraw only sets a new baseline.
+const resampleAfter = 7 * 24 * time.Hour
+
+// sampleWindow is the number of consecutive leaderboard ranks read per
+// call. The sample is drawn as windows spread evenly over the leaderboard,
+// so it covers strong and weak peers alike in few calls.
+const sampleWindow = 10
+
+// rewardsBatch bounds the peers whose rewards are read in one call
+const rewardsBatch = 50
+
+// LowPercentile is the percentile below which a peer is reported as
+// underperforming
+const LowPercentile = 25
+
+// Chain reads the coordinator contract
+type Chain interface {
+	UniqueVoters(ctx context.Context) (int, error)
+	VoterLeaderboard(ctx context.Context, start, end int) ([]string, []*big.Int, error)
+	TotalRewards(ctx context.Context, peerIDs []string) ([]*big.Int, error)
+}
+
+// state is what the comparer remembers between comparisons
+type state struct {
+	Contract string    `json:"contract"`
+	Sampled  time.Time `json:"sampled"`
+	Sample   []string  `json:"sample"`
+	// Rewards are the totals of the sample and the node's peers at Checked
+	Rewards map[string]*big.Int `json:"rewards"`
+	Checked time.Time           `json:"checked"`
+}
+
+// Comparer ranks the node's peers against a sample of the swarm
+type Comparer struct {
+	Chain      Chain
+	SampleSize int
+
+	contract string
+	state    state
+}
+
+// Load creates a comparer for the coordinator at contract, continuing from
+// the state saved at path. State saved for another contract is discarded.
+func Load(path string, chain Chain, contract string) (*Comparer, error) {
+	c := &Comparer{Chain: chain, SampleSize: DefaultSampleSize, contract: contract}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to read swarm comparison state: %w", err)
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return c, fmt.Errorf("failed to parse swarm comparison state: %w", err)
+	}
+	if strings.EqualFold(s.Contract, contract) {
+		c.state = s
+	}
+	return c, nil
+}
+
+// Save writes the comparer's state to path
+func (c *Comparer) Save(path string) error {
+	data, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write swarm comparison state: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Checked returns when the totals were last read, or the zero time
+func (c *Comparer) Checked() time.Time {
+	return c.state.Checked
+}
+
+// Peer is one of the node's peers ranked against the sample
+type Peer struct {
+	PeerID  string
+	PerHour *big.Int
+	// Percentile is the share of the active sample earning less, 0-100
+	Percentile int
+}
+
+// Result is a comparison of the node's peers with the sample
+type Result struct {
+	Since, Until time.Time
+	Peers        []Peer
+	// Median, P25 and P75 are the active sample's rewards per hour
+	Median, P25, P75 *big.Int
+	// Active is the number of sampled peers that earned; Sampled counts
+	// the inactive ones too
+	Active, Sampled int
+}
+
+// Low returns the peers below LowPercentile
+func (r *Result) Low() []Peer {
+	var low []Peer
+	for _, p := range r.Peers {
+		if p.Percentile < LowPercentile {
+			low = append(low, p)
+		}
+	}
+	return low
+}
+
+// Text describes the comparison, formatting rewards with f
+func (r *Result) Text(f humanize.Format) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Over the last %s, %d of %d sampled peers in the swarm earned rewards.\n",
+		r.Until.Sub(r.Since).Round(time.Minute), r.Active, r.Sampled)
+	fmt.Fprintf(&b, "Swarm median: %s/h (middle half %s–%s/h)\n", f.Int(r.Median), f.Int(r.P25), f.Int(r.P75))
+	for _, p := range r.Peers {
+		fmt.Fprintf(&b, "\nPeer %s: %s/h, %s percentile", p.PeerID, f.Int(p.PerHour), ordinal(p.Percentile))
+	}
+	if low := r.Low(); len(low) > 0 {
+		fmt.Fprintf(&b, "\n\n%d of your peers rank in the bottom quarter of the sample. Check their hardware, model size and logs.", len(low))
+	}
+	return b.String()
+}
+
+// Compare reads the rewards of the node's peers and the sample and ranks
+// the peers by their rewards per hour since the previous comparison. It
+// returns nil when there is nothing to compare yet: on the first run,
+// after the sample is redrawn, or when no sampled peer earned.
+func (c *Comparer) Compare(ctx context.Context, now time.Time, mine []string) (*Result, error) {
+	if !strings.EqualFold(c.state.Contract, c.contract) || len(c.state.Sample) == 0 || now.Sub(c.state.Sampled) >= resampleAfter {
+		sample, err := c.drawSample(ctx, mine)
+		if err != nil {
+			return nil, err
+		}
+		c.state = state{Contract: c.contract, Sampled: now, Sample: sample}
+	}
+
+	peers := append(append([]string{}, mine...), c.state.Sample...)
+	totals, err := c.rewards(ctx, peers)
+	if err != nil {
+		return nil, err
+	}
+	prev, since := c.state.Rewards, c.state.Checked
+	c.state.Rewards, c.state.Checked = totals, now
+	elapsed := now.Sub(since)
+	if prev == nil || elapsed <= 0 {
+		return nil, nil
+	}
+
+	var active []*big.Int
+	for _, id := range c.state.Sample {
+		if rate := perHour(prev[id], totals[id], elapsed); rate != nil && rate.Sign() > 0 {
+			active = append(active, rate)
+		}
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Cmp(active[j]) < 0 })
+
+	r := &Result{
+		Since: since, Until: now,
+		Median: quantile(active, 50), P25: quantile(active, 25), P75: quantile(active, 75),
+		Active: len(active), Sampled: len(c.state.Sample),
+	}
+	for _, id := range mine {
+		rate := perHour(prev[id], totals[id], elapsed)
+		if rate == nil {
+			continue
+		}
+		r.Peers = append(r.Peers, Peer{PeerID: id, PerHour: rate, Percentile: percentile(active, rate)})
+	}
+	return r, nil
+}
+
+// drawSample picks up to SampleSize peers other than mine in windows
+// spread evenly over the voter leaderboard
+func (c *Comparer) drawSample(ctx context.Context, mine []string) ([]string, error) {
+	total, err := c.Chain.UniqueVoters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the number of voters: %w", err)
+	}
+	size := c.SampleSize
+	if size <= 0 {
+		size = DefaultSampleSize
+	}
+	own := make(map[string]bool, len(mine))
+	for _, id := range mine {
+		own[id] = true
+	}
+
+	windows := (size + sampleWindow - 1) / sampleWindow
+	seen := map[string]bool{}
+	var sample []string
+	for w := 0; w < windows && len(sample) < size; w++ {
+		start := w * total / windows
+		end := start + sampleWindow
+		if end > total {
+			end = total
+		}
+		if start >= end {
+			continue
+		}
+		peers, _, err := c.Chain.VoterLeaderboard(ctx, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the voter leaderboard: %w", err)
+		}
+		for _, id := range peers {
+			if !own[id] && !seen[id] && len(sample) < size {
+				seen[id] = true
+				sample = append(sample, id)
+			}
+		}
+	}
+	if len(sample) == 0 {
+		return nil, fmt.Errorf("no other peers on the voter leaderboard")
+	}
+	return sample, nil
+}
+
+// rewards reads the totals of peers in batches
+func (c *Comparer) rewards(ctx context.Context, peers []string) (map[string]*big.Int, error) {
+	totals := make(map[string]*big.Int, len(peers))
+	for start := 0; start < len(peers); start += rewardsBatch {
+		end := start + rewardsBatch
+		if end > len(peers) {
+			end = len(peers)
+		}
+		values, err := c.Chain.TotalRewards(ctx, peers[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rewards: %w", err)
+		}
+		for i, v := range values {
+			totals[peers[start+i]] = v
+		}
+	}
+	return totals, nil
+}
+
+// perHour is the change from prev to cur as a rate per hour, or nil when
+// either is unknown
+func perHour(prev, cur *big.Int, elapsed time.Duration) *big.Int {
+	if prev == nil || cur == nil {
+		return nil
+	}
+	delta := new(big.Int).Sub(cur, prev)
+	delta.Mul(delta, big.NewInt(int64(time.Hour)))
+	return delta.Quo(delta, big.NewInt(int64(elapsed)))
+}
+
+// quantile returns the q-th percentile of sorted by nearest rank
+func quantile(sorted []*big.Int, q int) *big.Int {
+	i := (q*len(sorted) + 99) / 100
+	if i > 0 {
+		i--
+	}
+	return sorted[i]
+}
+
+// percentile returns the share of sorted below rate, counting ties as half
+func percentile(sorted []*big.Int, rate *big.Int) int {
+	below, equal := 0, 0
+	for _, v := range sorted {
+		switch v.Cmp(rate) {
+		case -1:
+			below++
+		case 0:
+			equal++
+		}
+	}
+	return (200*below + 100*equal) / (2 * len(sorted))
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,182 @@
+package swarmstats
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+)
+
+// fakeChain serves a leaderboard of peers p0..pN-1 and their rewards
+type fakeChain struct {
+	peers   []string
+	rewards map[string]int64
+	windows [][2]int
+}
+
+func newFakeChain(n int) *fakeChain {
+	f := &fakeChain{rewards: map[string]int64{}}
+	for i := 0; i < n; i++ {
+		f.peers = append(f.peers, fmt.Sprintf("p%d", i))
+	}
+	return f
+}
+
+func (f *fakeChain) UniqueVoters(context.Context) (int, error) { return len(f.peers), nil }
+
+func (f *fakeChain) VoterLeaderboard(_ context.Context, start, end int) ([]string, []*big.Int, error) {
+	f.windows = append(f.windows, [2]int{start, end})
+	votes := make([]*big.Int, end-start)
+	for i := range votes {
+		votes[i] = big.NewInt(1)
+	}
+	return f.peers[start:end], votes, nil
+}
+
+func (f *fakeChain) TotalRewards(_ context.Context, ids []string) ([]*big.Int, error) {
+	if len(ids) > rewardsBatch {
+		return nil, fmt.Errorf("batch of %d", len(ids))
+	}
+	out := make([]*big.Int, len(ids))
+	for i, id := range ids {
+		out[i] = big.NewInt(f.rewards[id])
+	}
+	return out, nil
+}
+
+func TestDrawSample(t *testing.T) {
+	chain := newFakeChain(1000)
+	c := &Comparer{Chain: chain, SampleSize: 30}
+	sample, err := c.drawSample(context.Background(), []string{"p0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sample) != 29 {
+		t.Errorf("sample has %d peers, want 29 (30 minus the node's own)", len(sample))
+	}
+	want := [][2]int{{0, 10}, {333, 343}, {666, 676}}
+	if fmt.Sprint(chain.windows) != fmt.Sprint(want) {
+		t.Errorf("windows = %v, want %v", chain.windows, want)
+	}
+	for _, id := range sample {
+		if id == "p0" {
+			t.Error("sample includes the node's own peer")
+		}
+	}
+
+	small := newFakeChain(4)
+	c = &Comparer{Chain: small, SampleSize: 50}
+	if sample, err := c.drawSample(context.Background(), nil); err != nil || len(sample) != 4 {
+		t.Errorf("drawSample() on a small swarm = %v, %v", sample, err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	chain := newFakeChain(11)
+	c := &Comparer{Chain: chain, SampleSize: 10, contract: "0xabc"}
+	mine := []string{"mine"}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r, err := c.Compare(context.Background(), now, mine)
+	if err != nil || r != nil {
+		t.Fatalf("first Compare() = %v, %v; want a baseline", r, err)
+	}
+
+	// Over two hours, p0..p9 earn 2..20 (1..10 per hour), p10 nothing,
+	// and the node earns 8 (4 per hour)
+	for i := 0; i < 10; i++ {
+		chain.rewards[fmt.Sprintf("p%d", i)] = int64(2 * (i + 1))
+	}
+	chain.rewards["mine"] = 8
+	r, err = c.Compare(context.Background(), now.Add(2*time.Hour), mine)
+	if err != nil || r == nil {
+		t.Fatalf("Compare() = %v, %v", r, err)
+	}
+	if r.Sampled != 10 || r.Active != 10 {
+		t.Errorf("sampled %d, active %d; want 10 and 10", r.Sampled, r.Active)
+	}
+	if r.Median.Int64() != 5 || r.P25.Int64() != 3 || r.P75.Int64() != 8 {
+		t.Errorf("median %s, P25 %s, P75 %s; want 5, 3, 8", r.Median, r.P25, r.P75)
+	}
+	if len(r.Peers) != 1 || r.Peers[0].PerHour.Int64() != 4 || r.Peers[0].Percentile != 35 {
+		t.Errorf("peers = %+v, want 4/h at the 35th percentile", r.Peers)
+	}
+	if len(r.Low()) != 0 {
+		t.Errorf("Low() = %v", r.Low())
+	}
+
+	text := r.Text(humanize.Format{})
+	for _, want := range []string{"10 of 10 sampled peers", "Swarm median: 5/h", "Peer mine: 4/h, 35th percentile"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() = %q, missing %q", text, want)
+		}
+	}
+
+	// The node stops earning and drops to the bottom
+	for i := 0; i < 10; i++ {
+		chain.rewards[fmt.Sprintf("p%d", i)] += 10
+	}
+	r, err = c.Compare(context.Background(), now.Add(3*time.Hour), mine)
+	if err != nil || r == nil {
+		t.Fatalf("Compare() = %v, %v", r, err)
+	}
+	if len(r.Low()) != 1 || r.Peers[0].Percentile != 0 {
+		t.Errorf("peers = %+v, want the node at the bottom", r.Peers)
+	}
+	if !strings.Contains(r.Text(humanize.Format{}), "bottom quarter") {
+		t.Errorf("Text() doesn't report the low peer: %q", r.Text(humanize.Format{}))
+	}
+}
+
+func TestCompareNoActivePeers(t *testing.T) {
+	chain := newFakeChain(5)
+	c := &Comparer{Chain: chain, contract: "0xabc"}
+	now := time.Now()
+	c.Compare(context.Background(), now, nil)
+	if r, err := c.Compare(context.Background(), now.Add(time.Hour), nil); r != nil || err != nil {
+		t.Errorf("Compare() with an idle swarm = %v, %v; want nil", r, err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	chain := newFakeChain(5)
+	c := &Comparer{Chain: chain, contract: "0xabc"}
+	now := time.Now()
+	if _, err := c.Compare(context.Background(), now, []string{"mine"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path, chain, "0xABC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.rewards["p1"] = 10
+	if r, err := loaded.Compare(context.Background(), now.Add(time.Hour), []string{"mine"}); err != nil || r == nil {
+		t.Errorf("Compare() after Load = %v, %v; want a comparison", r, err)
+	}
+
+	other, err := Load(path, chain, "0xdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other.state.Sample) != 0 {
+		t.Error("state of another contract was kept")
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	for n, want := range map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 52: "52nd", 100: "100th"} {
+		if got := ordinal(n); got != want {
+			t.Errorf("ordinal(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
@@ -2,31 +2,50 @@ package telegram
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
+	"html"
 	"math/big"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Deep-Commit/gswarm/internal/anomaly"
+	"github.com/Deep-Commit/gswarm/internal/atomicfile"
+	"github.com/Deep-Commit/gswarm/internal/chain"
+	"github.com/Deep-Commit/gswarm/internal/clockjump"
+	"github.com/Deep-Commit/gswarm/internal/console"
+	"github.com/Deep-Commit/gswarm/internal/contracts"
+	"github.com/Deep-Commit/gswarm/internal/digest"
+	"github.com/Deep-Commit/gswarm/internal/history"
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+	"github.com/Deep-Commit/gswarm/internal/int256"
+	"github.com/Deep-Commit/gswarm/internal/notify"
+	"github.com/Deep-Commit/gswarm/internal/overrides"
+	"github.com/Deep-Commit/gswarm/internal/redact"
+	"github.com/Deep-Commit/gswarm/internal/rewards"
+	"github.com/Deep-Commit/gswarm/internal/rpc"
+	"github.com/Deep-Commit/gswarm/internal/secrets"
+	"github.com/Deep-Commit/gswarm/internal/statehealth"
+	"github.com/Deep-Commit/gswarm/internal/swarmstats"
+	"github.com/Deep-Commit/gswarm/internal/timefmt"
+	"github.com/Deep-Commit/gswarm/internal/wallet"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 )
 
 // Blockchain constants
 const (
-	blockscoutURL     = "https://gensyn-testnet.explorer.alchemy.com/api"
-	alchemyAPIURL     = "https://gensyn-testnet.g.alchemy.com/v2"
-	alchemyPublicURL  = "https://gensyn-testnet.g.alchemy.com/public"
-	rpcURL            = "https://gensyn-testnet.g.alchemy.com/public"
-	coordAddrMath     = "0x69C6e1D608ec64885E7b185d39b04B491a71768C" // Proxy contract with activity
-	coordAddrMathHard = "0x6947c6E196a48B77eFa9331EC1E3e45f3Ee5Fd58"
+	blockscoutURL    = "https://gensyn-testnet.explorer.alchemy.com/api"
+	explorerURL      = "https://gensyn-testnet.explorer.alchemy.com"
+	alchemyAPIURL    = "https://gensyn-testnet.g.alchemy.com/v2"
+	alchemyPublicURL = "https://gensyn-testnet.g.alchemy.com/public"
+	rpcURL           = "https://gensyn-testnet.g.alchemy.com/public"
 )
 
 // ABI for the getPeerId function
@@ -38,14 +57,44 @@ type TelegramConfig struct {
 	BotToken    string `json:"bot_token"`
 	ChatID      string `json:"chat_id"`
 	WelcomeSent bool   `json:"welcome_sent"`
+	// Proxy routes Telegram API requests, e.g. socks5://127.0.0.1:9050 for Tor
+	Proxy string `json:"proxy,omitempty"`
+	// Subscribers are further chats that get the monitor's updates
+	Subscribers []string `json:"subscribers,omitempty"`
+
+	// refs keeps vault:/ssm: references so saving doesn't write resolved secrets
+	refs map[string]string
 }
 
 const DefaultConfigPath = "telegram-config.json"
 
+// PreviousDataPath is where the monitor persists the last seen totals,
+// relative to the state directory
+const PreviousDataPath = "telegram_previous_data.json"
+
+// RewardsHistoryPath is where the monitor records reward totals over time,
+// relative to the state directory
+const RewardsHistoryPath = "telegram_rewards_history.jsonl"
+
+// PeerHistoryPath is where the monitor records each peer's recent totals
+// for the status API's rewards endpoint, relative to the state directory
+const PeerHistoryPath = "peer_rewards_history.json"
+
+// DigestReportDir is where each daily digest is kept as a CSV file sent
+// with it, relative to the state directory
+const DigestReportDir = "reports"
+
+// DefaultCheckInterval is how often votes and rewards are checked
+const DefaultCheckInterval = 5 * time.Minute
+
+// DefaultPeerRefresh is how often the peers registered to the EOA are re-resolved
+const DefaultPeerRefresh = time.Hour
+
 // BlockchainData represents the blockchain data for a user
 type BlockchainData struct {
 	Votes   *big.Int
 	Rewards *big.Int
+	// Balance is the EOA's balance in wei, nil when it couldn't be read
 	Balance *big.Int
 }
 
@@ -65,6 +114,84 @@ type TelegramService struct {
 	PeerIDs           []string
 	PreviousData      *PreviousData
 	StopChan          chan bool
+	RPC               *rpc.Client
+
+	// Notifiers receive the same rewards updates as the Telegram chat
+	Notifiers notify.Multi
+
+	// History records reward totals; RewardEstimates adds the derived
+	// rewards-per-day trend to updates
+	History         *history.Store
+	RewardEstimates bool
+
+	// RewardFormat controls how reward amounts are shown
+	RewardFormat humanize.Format
+	// Time controls how times are shown
+	Time timefmt.Formatter
+
+	// Contracts are the coordinators queried for votes and rewards, in
+	// order of preference, on chain ChainID
+	Contracts []string
+	ChainID   uint64
+
+	// Rewards is where peer votes and rewards are read; nil reads
+	// Contracts directly
+	Rewards rewards.Source
+
+	// CheckInterval is how often votes and rewards are checked
+	CheckInterval time.Duration
+	// Overrides may change CheckInterval while the monitor runs; nil
+	// keeps it
+	Overrides *overrides.Store
+	// throttled is set while the RPC client's usage budget has run out;
+	// checks are skipped until it clears
+	throttled bool
+
+	// PeerRefresh is how often peer IDs are re-resolved (0 disables);
+	// Commands enables the /refresh chat command
+	PeerRefresh time.Duration
+	Commands    bool
+
+	// StateDir holds the persisted totals; empty means the working directory
+	StateDir string
+
+	// ReadOnlyState skips saving state, for filesystems that are
+	// immutable on purpose; the welcome message is then never sent.
+	// StateHealth records failed saves for the health endpoint.
+	ReadOnlyState bool
+	StateHealth   *statehealth.Tracker
+
+	// PeerHistory records each peer's recent totals for the status API;
+	// nil disables it
+	PeerHistory *history.Peers
+
+	// Anomalies tracks per-peer reward velocity to spot stuck peers and
+	// decreasing rewards; nil disables the alerts
+	Anomalies *anomaly.Detector
+
+	// WalletAlerts alerts when the EOA sends or receives transactions
+	WalletAlerts bool
+	wallet       *wallet.Watcher
+
+	// CompareInterval is how often the peers' reward velocity is compared
+	// with a sample of the swarm (0 disables); CompareSample is the number
+	// of other peers sampled
+	CompareInterval time.Duration
+	CompareSample   int
+	comparer        *swarmstats.Comparer
+
+	// Digest is when the daily summary is sent; nil disables it
+	Digest  *digest.Schedule
+	digests *digest.Scheduler
+
+	// Subscribers are chats that get the same updates as the configured
+	// chat, in addition to the config file's subscribers. Chat commands
+	// are only taken from the configured chat.
+	Subscribers []string
+
+	// Proxy overrides the config file's proxy for Telegram API requests
+	Proxy  string
+	client *http.Client
 }
 
 // NewTelegramService creates a new telegram service instance
@@ -74,13 +201,19 @@ func NewTelegramService(configPath string, forceUpdate bool) *TelegramService {
 		ForceConfigUpdate: forceUpdate,
 		PreviousData:      &PreviousData{Votes: big.NewInt(0), Rewards: big.NewInt(0)},
 		StopChan:          make(chan bool),
+		RPC:               rpc.NewClient(),
+		History:           &history.Store{Path: RewardsHistoryPath},
+		Contracts:         contracts.Default().Addresses(contracts.GensynTestnetChainID),
+		ChainID:           contracts.GensynTestnetChainID,
+		CheckInterval:     DefaultCheckInterval,
+		PeerRefresh:       DefaultPeerRefresh,
 	}
 }
 
 // promptForTelegramConfig walks the user through CLI prompts
 func promptForTelegramConfig() (*TelegramConfig, error) {
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Println("Let's set up your Telegram integration!")
+	console.Infof("Let's set up your Telegram integration!")
 
 	fmt.Print("Enter your Telegram Bot Token: ")
 	botToken, err := reader.ReadString('\n')
@@ -95,6 +228,7 @@ func promptForTelegramConfig() (*TelegramConfig, error) {
 		return nil, err
 	}
 	chatID = strings.TrimSpace(chatID)
+	redact.Add(botToken)
 
 	return &TelegramConfig{
 		BotToken: botToken,
@@ -104,6 +238,19 @@ func promptForTelegramConfig() (*TelegramConfig, error) {
 
 // saveTelegramConfig writes the config to disk
 func saveTelegramConfig(path string, cfg *TelegramConfig) error {
+	if len(cfg.refs) > 0 {
+		c := *cfg
+		if ref, ok := cfg.refs["bot_token"]; ok {
+			c.BotToken = ref
+		}
+		if ref, ok := cfg.refs["chat_id"]; ok {
+			c.ChatID = ref
+		}
+		if ref, ok := cfg.refs["proxy"]; ok {
+			c.Proxy = ref
+		}
+		cfg = &c
+	}
 	f, err := os.Create(path)
 	if err != nil {
 		return err
@@ -126,9 +273,31 @@ func loadTelegramConfig(path string) (*TelegramConfig, error) {
 	if err := dec.Decode(&cfg); err != nil {
 		return nil, err
 	}
+	// Tokens may be stored as vault:/ssm: references
+	fields := map[string]*string{"bot_token": &cfg.BotToken, "chat_id": &cfg.ChatID, "proxy": &cfg.Proxy}
+	for name, v := range fields {
+		if secrets.IsRef(*v) {
+			if cfg.refs == nil {
+				cfg.refs = make(map[string]string)
+			}
+			cfg.refs[name] = *v
+		}
+	}
+	if err := secrets.ResolveAll(fields); err != nil {
+		return nil, err
+	}
+	redact.Add(cfg.BotToken)
 	return &cfg, nil
 }
 
+// LoadConfig loads a Telegram config file written by the monitor
+func LoadConfig(path string) (*TelegramConfig, error) {
+	if path == "" {
+		path = DefaultConfigPath
+	}
+	return loadTelegramConfig(path)
+}
+
 // ensureTelegramConfig loads or prompts for config
 func (t *TelegramService) ensureTelegramConfig() error {
 	cfgPath := t.ConfigPath
@@ -136,7 +305,7 @@ func (t *TelegramService) ensureTelegramConfig() error {
 		cfgPath = DefaultConfigPath
 	}
 	if t.ForceConfigUpdate {
-		fmt.Println("Forcing Telegram config update...")
+		console.Infof("Forcing Telegram config update...")
 		cfg, err := promptForTelegramConfig()
 		if err != nil {
 			return err
@@ -153,7 +322,7 @@ func (t *TelegramService) ensureTelegramConfig() error {
 		t.Config = cfg
 		return nil
 	}
-	fmt.Println("No Telegram config found. Let's set it up.")
+	console.Infof("No Telegram config found. Let's set it up.")
 	cfg, err = promptForTelegramConfig()
 	if err != nil {
 		return err
@@ -174,6 +343,9 @@ func printBanner() {
  ██████  ███████  ███ ███  ██   ██ ██   ██ ██      ██ 
 		G-SWARM Supervisor (Community Project)
 `
+	if !console.Enabled(console.Normal) {
+		return
+	}
 	fmt.Println("\033[38;5;224m")
 	fmt.Println(banner)
 	fmt.Println("\033[0m")
@@ -181,42 +353,11 @@ func printBanner() {
 
 // sendTelegramMessage sends a message to Telegram using the Bot API
 func (t *TelegramService) sendTelegramMessage(text string) error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.Config.BotToken)
-
-	// Prepare the request data
-	data := url.Values{}
-	data.Set("chat_id", t.Config.ChatID)
-	data.Set("text", text)
-
-	// Make the HTTP request
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
-		return fmt.Errorf("failed to send Telegram message: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Check if the request was successful
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Telegram API error: %s - %s", resp.Status, string(body))
-	}
-
-	// Parse the response to check for Telegram API errors
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if !result["ok"].(bool) {
-		return fmt.Errorf("Telegram API error: %v", result["description"])
+	if err := t.broadcast().SendText(text); err != nil {
+		return err
 	}
 
-	fmt.Printf("Message sent successfully to Telegram!\n")
+	console.Debugf("Message sent successfully to Telegram!")
 	return nil
 }
 
@@ -225,18 +366,42 @@ func (t *TelegramService) Run() error {
 	// Print banner
 	printBanner()
 
-	fmt.Println("Starting Telegram monitoring service...")
+	console.Infof("Starting Telegram monitoring service...")
 	if err := t.ensureTelegramConfig(); err != nil {
-		fmt.Printf("Error: %v\n", err)
+		console.Errorf("Error: %v", err)
 		return err
 	}
-	fmt.Printf("Loaded Telegram config: BotToken=%s, ChatID=%s\n", t.Config.BotToken, t.Config.ChatID)
+	console.Infof("Loaded Telegram config for chat %s", t.Config.ChatID)
+	if chats := t.broadcast(); len(chats) > 1 {
+		ids := make([]string, 0, len(chats)-1)
+		for _, tg := range chats[1:] {
+			ids = append(ids, tg.ChatID)
+		}
+		console.Infof("Also sending updates to %s", strings.Join(ids, ", "))
+	}
+
+	proxy := t.Proxy
+	if proxy == "" {
+		proxy = t.Config.Proxy
+	}
+	if proxy != "" {
+		client, err := notify.ProxyClient(proxy)
+		if err != nil {
+			return fmt.Errorf("telegram proxy: %w", err)
+		}
+		t.client = client
+		console.Infof("Sending Telegram requests through the configured proxy")
+	}
+	t.checkChainID()
 
-	// Send welcome message if not sent before
-	if !t.Config.WelcomeSent {
-		fmt.Println("Sending welcome message...")
+	// Send welcome message if not sent before. With read-only state it
+	// couldn't be recorded, and would be sent on every start.
+	if t.ReadOnlyState {
+		console.Infof("State is read-only; not sending a welcome message or saving state.")
+	} else if !t.Config.WelcomeSent {
+		console.Infof("Sending welcome message...")
 		if err := t.sendWelcomeMessage(); err != nil {
-			fmt.Printf("Warning: Could not send welcome message: %v\n", err)
+			console.Warnf("Could not send welcome message: %v", err)
 		} else {
 			// Mark welcome message as sent and save config
 			t.Config.WelcomeSent = true
@@ -247,73 +412,204 @@ func (t *TelegramService) Run() error {
 				configPath = DefaultConfigPath
 			}
 
-			fmt.Printf("Saving updated config to: %s\n", configPath)
-			if err := saveTelegramConfig(configPath, t.Config); err != nil {
-				fmt.Printf("Warning: Could not save updated config: %v\n", err)
-			} else {
-				fmt.Println("Welcome message sent and config updated!")
+			console.Infof("Saving updated config to: %s", configPath)
+			if err := t.persist(configPath, func() error { return saveTelegramConfig(configPath, t.Config) }); err == nil {
+				console.Infof("Welcome message sent and config updated!")
 			}
 		}
 	} else {
-		fmt.Println("Welcome message already sent previously.")
+		console.Infof("Welcome message already sent previously.")
 	}
 
-	// Prompt for EOA address
-	fmt.Println("Please provide your EOA address to start monitoring...")
-	eoaAddress, err := promptForEOAAddress()
-	if err != nil {
-		return fmt.Errorf("failed to get EOA address: %w", err)
+	// Prompt for the EOA address unless it was given
+	if t.UserEOAAddress == "" {
+		console.Infof("Please provide your EOA address to start monitoring...")
+		eoaAddress, err := promptForEOAAddress()
+		if err != nil {
+			return fmt.Errorf("failed to get EOA address: %w", err)
+		}
+		t.UserEOAAddress = eoaAddress
 	}
-	t.UserEOAAddress = eoaAddress
+	eoaAddress := t.UserEOAAddress
 
 	// Fetch peer IDs for the EOA address
-	fmt.Printf("Fetching peer IDs for address: %s\n", eoaAddress)
-	peerIDs, err := t.getPeerIDs(eoaAddress)
+	console.Infof("Fetching peer IDs for address: %s", eoaAddress)
+	peerIDs, err := t.getPeerIDs(eoaAddress, t.peerCacheTTL())
 	if err != nil {
 		return fmt.Errorf("failed to fetch peer IDs: %w", err)
 	}
 	t.PeerIDs = peerIDs
 
-	fmt.Printf("Successfully loaded %d peer IDs for monitoring\n", len(peerIDs))
+	console.Successf("Successfully loaded %d peer IDs for monitoring", len(peerIDs))
+
+	if t.WalletAlerts {
+		w, err := wallet.Load(filepath.Join(t.StateDir, wallet.StateFile), eoaAddress)
+		if err != nil {
+			console.Warnf("%v; starting wallet activity tracking afresh", err)
+		}
+		w.Client, w.Endpoint = t.RPC, alchemyPublicURL
+		t.wallet = w
+	}
+
+	if t.CompareInterval > 0 && len(t.Contracts) > 0 {
+		reader := &chain.Reader{Client: t.RPC, Endpoint: alchemyPublicURL, Contract: t.Contracts[0]}
+		c, err := swarmstats.Load(filepath.Join(t.StateDir, swarmstats.StateFile), reader, t.Contracts[0])
+		if err != nil {
+			console.Warnf("%v; starting the swarm comparison afresh", err)
+		}
+		if t.CompareSample > 0 {
+			c.SampleSize = t.CompareSample
+		}
+		t.comparer = c
+	}
+
+	if t.Digest != nil && t.PeerHistory != nil {
+		d, err := digest.Load(filepath.Join(t.StateDir, digest.StateFile), t.Digest)
+		if err != nil {
+			console.Warnf("%v; waiting for the next digest", err)
+		}
+		t.digests = d
+		console.Infof("Sending a daily digest at %s", t.Digest)
+	}
 
 	// Load previous data from persistent storage
 	previousData, err := t.loadPreviousData()
 	if err != nil {
-		fmt.Printf("Warning: Could not load previous data: %v\n", err)
+		console.Warnf("Could not load previous data: %v", err)
 		previousData = &PreviousData{Votes: big.NewInt(0), Rewards: big.NewInt(0), LastCheck: time.Now()}
 	} else {
-		fmt.Printf("Loaded previous data - Votes: %s, Rewards: %s, Last Check: %s\n",
-			previousData.Votes.String(), previousData.Rewards.String(), previousData.LastCheck.Format("2006-01-02 15:04:05"))
+		console.Infof("Loaded previous data - Votes: %s, Rewards: %s, Last Check: %s",
+			previousData.Votes.String(), previousData.Rewards.String(), t.Time.Format(previousData.LastCheck))
+		if previousData.LastCheck.After(time.Now()) {
+			console.Warnf("The last check is in the future; the system clock went back since. Counting from now.")
+			previousData.LastCheck = time.Now()
+		}
 	}
 
-	fmt.Println("Starting continuous monitoring loop (checking every 5 minutes)...")
-	fmt.Println("Press Ctrl+C to stop monitoring")
+	interval := t.Overrides.Get().CheckInterval.Or(t.CheckInterval)
+	console.Infof("Starting continuous monitoring loop (checking every %s)...", interval)
+	console.Infof("Press Ctrl+C to stop monitoring")
 
 	// Start the monitoring loop
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// New nodes registered under the EOA are picked up periodically or on /refresh
+	var refreshTick <-chan time.Time
+	if t.PeerRefresh > 0 {
+		refreshTicker := time.NewTicker(t.PeerRefresh)
+		defer refreshTicker.Stop()
+		refreshTick = refreshTicker.C
+	}
+	var compareTick <-chan time.Time
+	if t.comparer != nil {
+		compareTicker := time.NewTicker(t.CompareInterval)
+		defer compareTicker.Stop()
+		compareTick = compareTicker.C
+	}
+	// The digest timer is re-armed at least hourly, so suspends and clock
+	// changes only delay the digest until the next wake-up
+	var digestTimer *time.Timer
+	var digestTick <-chan time.Time
+	if t.digests != nil {
+		digestTimer = time.NewTimer(t.untilDigest())
+		defer digestTimer.Stop()
+		digestTick = digestTimer.C
+	}
+	// Tickers run on the monotonic clock, but the digest timer aims at a
+	// wall clock time, so it is re-armed when the clock jumps
+	jumps := make(chan clockjump.Jump, 1)
+	jumpCtx, stopJumps := context.WithCancel(context.Background())
+	defer stopJumps()
+	go clockjump.Watch(jumpCtx, clockjump.DefaultInterval, clockjump.DefaultThreshold, func(j clockjump.Jump) {
+		select {
+		case jumps <- j:
+		default:
+		}
+	})
+	refreshRequested := make(chan struct{}, 1)
+	if t.Commands {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go t.notifier().Commands(ctx, func(cmd string) {
+			if cmd == "refresh" {
+				select {
+				case refreshRequested <- struct{}{}:
+				default:
+				}
+			}
+		})
+		console.Infof("Send /refresh in the chat to re-resolve peer IDs")
+	}
+
+	if t.RPC != nil && t.RPC.Usage != nil && (t.RPC.Usage.PerHour > 0 || t.RPC.Usage.PerDay > 0) {
+		console.Infof("RPC budget: %s", rpcBudget(t.RPC.Usage))
+	}
+
 	// Do initial check
-	if err := t.checkAndNotifyWithPeerIDs(previousData); err != nil {
-		fmt.Printf("Error in initial check: %v\n", err)
+	if !t.overBudget() {
+		if err := t.checkAndNotifyWithPeerIDs(previousData); err != nil {
+			console.Errorf("Error in initial check: %v", err)
+		}
+	}
+	// Restarts don't repeat a comparison made within the interval, unless
+	// it was made while the clock was ahead
+	if t.comparer != nil && (t.comparer.Checked().After(time.Now()) || time.Since(t.comparer.Checked()) >= t.CompareInterval) && !t.overBudget() {
+		t.compareSwarm()
+	}
+	// A digest missed while the monitor was down is sent once on startup
+	if t.digests != nil {
+		t.sendDigest()
 	}
 
 	// Continuous monitoring loop
 	for {
 		select {
+		case <-t.Overrides.Changed():
+			if d := t.Overrides.Get().CheckInterval.Or(t.CheckInterval); d != interval {
+				interval = d
+				ticker.Reset(d)
+				console.Infof("Checking every %s", d)
+			}
 		case <-ticker.C:
+			if t.overBudget() {
+				continue
+			}
 			if err := t.checkAndNotifyWithPeerIDs(previousData); err != nil {
-				fmt.Printf("Error in monitoring check: %v\n", err)
+				console.Errorf("Error in monitoring check: %v", err)
+			}
+		case <-compareTick:
+			if !t.overBudget() {
+				t.compareSwarm()
+			}
+		case <-digestTick:
+			t.sendDigest()
+			digestTimer.Reset(t.untilDigest())
+		case j := <-jumps:
+			console.Warnf("System clock jumped %s (NTP correction, suspend or VM pause); times around %s may be off", j, t.Time.Format(j.At))
+			if digestTimer != nil {
+				digestTimer.Stop()
+				digestTimer.Reset(t.untilDigest())
+			}
+		case <-refreshTick:
+			if !t.overBudget() {
+				t.refreshPeerIDs(false)
+			}
+		case <-refreshRequested:
+			if t.refreshPeerIDs(true) {
+				if err := t.checkAndNotifyWithPeerIDs(previousData); err != nil {
+					console.Errorf("Error in monitoring check: %v", err)
+				}
 			}
 		case <-sigChan:
-			fmt.Println("\nReceived interrupt signal. Stopping monitoring...")
+			console.Infof("\nReceived interrupt signal. Stopping monitoring...")
 			return nil
 		case <-t.StopChan:
-			fmt.Println("Monitoring stopped by user")
+			console.Infof("Monitoring stopped by user")
 			return nil
 		}
 	}
@@ -321,10 +617,12 @@ func (t *TelegramService) Run() error {
 
 // checkAndNotifyWithPeerIDs checks blockchain data for all peer IDs and sends notification if there are changes
 func (t *TelegramService) checkAndNotifyWithPeerIDs(previousData *PreviousData) error {
-	fmt.Printf("\n[%s] Checking blockchain data for %d peer IDs...\n", time.Now().Format("2006-01-02 15:04:05"), len(t.PeerIDs))
+	console.Infof("\n[%s] Checking blockchain data for %d peer IDs...", t.Time.Format(time.Now()), len(t.PeerIDs))
 
 	var totalVotes *big.Int = big.NewInt(0)
 	var totalRewards *big.Int = big.NewInt(0)
+	var balance *big.Int
+	failed := 0
 	var peerData []struct {
 		PeerID  string
 		Votes   *big.Int
@@ -333,18 +631,22 @@ func (t *TelegramService) checkAndNotifyWithPeerIDs(previousData *PreviousData)
 
 	// Check each peer ID with rate limiting (1 second delay between requests)
 	for i, peerID := range t.PeerIDs {
-		fmt.Printf("Checking peer ID %d/%d: %s\n", i+1, len(t.PeerIDs), peerID)
+		console.Debugf("Checking peer ID %d/%d: %s", i+1, len(t.PeerIDs), peerID)
 
 		// Query blockchain data for this peer ID
 		blockchainData, err := t.GetBlockchainDataForPeerID(peerID)
 		if err != nil {
-			fmt.Printf("Warning: Could not get blockchain data for peer ID %s: %v\n", peerID, err)
+			console.Warnf("Could not get blockchain data for peer ID %s: %v", peerID, err)
+			failed++
 			continue
 		}
 
 		// Add to totals
 		totalVotes.Add(totalVotes, blockchainData.Votes)
 		totalRewards.Add(totalRewards, blockchainData.Rewards)
+		if blockchainData.Balance != nil {
+			balance = blockchainData.Balance
+		}
 
 		// Store per-peer data
 		peerData = append(peerData, struct {
@@ -363,14 +665,36 @@ func (t *TelegramService) checkAndNotifyWithPeerIDs(previousData *PreviousData)
 		}
 	}
 
+	perPeer := make(map[string]*big.Int, len(peerData))
+	stats := make(map[string]history.PeerStats, len(peerData))
+	for _, data := range peerData {
+		perPeer[data.PeerID] = data.Rewards
+		stats[data.PeerID] = history.PeerStats{Votes: data.Votes, Rewards: data.Rewards}
+	}
+	t.checkAnomalies(perPeer)
+	t.recordPeerStats(balance, stats)
+	t.checkWallet()
+
+	// Totals missing a peer that couldn't be read would look like a drop,
+	// so changes are only reported from complete data
+	if failed > 0 {
+		if failed == len(t.PeerIDs) {
+			return fmt.Errorf("no peer could be read; change notifications skipped")
+		}
+		console.Warnf("%d of %d peers couldn't be read; skipping change notifications until all of them can", failed, len(t.PeerIDs))
+		return nil
+	}
+
 	// Check if there are any changes
 	votesChanged := totalVotes.Cmp(previousData.Votes) != 0
 	rewardsChanged := totalRewards.Cmp(previousData.Rewards) != 0
 
 	if votesChanged || rewardsChanged {
-		fmt.Printf("Changes detected!\n")
-		fmt.Printf("Previous - Votes: %s, Rewards: %s\n", previousData.Votes.String(), previousData.Rewards.String())
-		fmt.Printf("Current  - Votes: %s, Rewards: %s\n", totalVotes.String(), totalRewards.String())
+		console.Infof("Changes detected!")
+		console.Infof("Previous - Votes: %s, Rewards: %s", humanize.Int(previousData.Votes), t.RewardFormat.Int(previousData.Rewards))
+		console.Infof("Current  - Votes: %s (%s), Rewards: %s (%s)",
+			humanize.Int(totalVotes), humanize.Format{}.Delta(previousData.Votes, totalVotes),
+			t.RewardFormat.Int(totalRewards), t.RewardFormat.Delta(previousData.Rewards, totalRewards))
 
 		// Build per-peer breakdown
 		var peerBreakdown strings.Builder
@@ -382,8 +706,14 @@ func (t *TelegramService) checkAndNotifyWithPeerIDs(previousData *PreviousData)
 			}
 
 			peerBreakdown.WriteString(fmt.Sprintf("🔹 <b>Peer %d:</b> %s\n", i+1, peerID))
-			peerBreakdown.WriteString(fmt.Sprintf("   📈 Votes: %s\n", data.Votes.String()))
-			peerBreakdown.WriteString(fmt.Sprintf("   💰 Rewards: %s\n\n", data.Rewards.String()))
+			peerBreakdown.WriteString(fmt.Sprintf("   📈 Votes: %s\n", humanize.Int(data.Votes)))
+			peerBreakdown.WriteString(fmt.Sprintf("   💰 Rewards: %s\n", t.RewardFormat.Int(data.Rewards)))
+			if t.Anomalies != nil {
+				if v := t.Anomalies.Velocity(data.PeerID); v != nil && v.Sign() > 0 {
+					peerBreakdown.WriteString(fmt.Sprintf("   ⚡ Velocity: %s/h\n", t.RewardFormat.Int(v)))
+				}
+			}
+			peerBreakdown.WriteString("\n")
 		}
 
 		// Prepare notification message
@@ -394,24 +724,33 @@ func (t *TelegramService) checkAndNotifyWithPeerIDs(previousData *PreviousData)
 👤 <b>EOA Address:</b> <code>%s</code>
 🔍 <b>Peer IDs Monitored:</b> %d
 
-📈 <b>Total Votes:</b> %s %s
-💰 <b>Total Rewards:</b> %s %s
-
+📈 <b>Total Votes:</b> %s %s (%s since last check)
+💰 <b>Total Rewards:</b> %s %s (%s since last check)
+%s
 📋 <b>Per-Peer Breakdown:</b>
 %s
 ⏰ <b>Last Check:</b> %s`,
 			t.UserEOAAddress,
 			len(t.PeerIDs),
-			totalVotes.String(),
+			humanize.Int(totalVotes),
 			getChangeIndicator(previousData.Votes, totalVotes),
-			totalRewards.String(),
+			humanize.Format{}.Delta(previousData.Votes, totalVotes),
+			t.RewardFormat.Int(totalRewards),
 			getChangeIndicator(previousData.Rewards, totalRewards),
+			t.RewardFormat.Delta(previousData.Rewards, totalRewards),
+			t.rewardTrendLine(totalRewards, totalVotes),
 			peerBreakdown.String(),
-			time.Now().Format("2006-01-02 15:04:05"))
+			t.Time.Format(time.Now()))
 
 		// Send notification
 		if err := t.sendTelegramMessageHTML(message); err != nil {
-			fmt.Printf("Failed to send Telegram message: %v\n", err)
+			console.Errorf("Failed to send Telegram message: %v", err)
+		}
+		if len(t.Notifiers) > 0 {
+			ev := notify.Event{Type: notify.EventRewards, Message: message, Time: time.Now()}
+			if err := t.Notifiers.Notify(ev); err != nil {
+				console.Errorf("Failed to send notification: %v", err)
+			}
 		}
 
 		// Update previous data
@@ -420,217 +759,317 @@ func (t *TelegramService) checkAndNotifyWithPeerIDs(previousData *PreviousData)
 		previousData.LastCheck = time.Now()
 
 		// Save updated data
-		if err := t.savePreviousData(previousData); err != nil {
-			fmt.Printf("Warning: Could not save previous data: %v\n", err)
-		}
+		t.persist(PreviousDataPath, func() error { return t.savePreviousData(previousData) })
 	} else {
-		fmt.Printf("No changes detected. Votes: %s, Rewards: %s\n", totalVotes.String(), totalRewards.String())
+		console.Infof("No changes detected. Votes: %s, Rewards: %s", humanize.Int(totalVotes), t.RewardFormat.Int(totalRewards))
 	}
 
 	return nil
 }
 
-// GetBlockchainDataForPeerID gets blockchain data for a specific peer ID
-func (t *TelegramService) GetBlockchainDataForPeerID(peerID string) (*BlockchainData, error) {
-	fmt.Printf("Querying blockchain data for peer ID: %s\n", peerID)
-
-	// Try both contract addresses, but only use the first one that returns data
-	// to avoid double-counting
-	contracts := []string{coordAddrMath, coordAddrMathHard}
-	var totalVotes *big.Int = big.NewInt(0)
-	var totalRewards *big.Int = big.NewInt(0)
-
-	for _, contract := range contracts {
-		var contractHasData bool
-
-		// For votes, we pass the peer ID directly
-		if v, err := t.queryUserVotes(peerID, contract); err == nil && v.Cmp(big.NewInt(0)) > 0 {
-			totalVotes = v // Use only this value, don't add
-			fmt.Printf("Found votes for peer ID %s on contract %s: %s\n", peerID, contract, v.String())
-			contractHasData = true
+// checkAnomalies alerts on per-peer reward anomalies that the totals hide,
+// such as one peer flatlining while the others keep earning
+func (t *TelegramService) checkAnomalies(rewards map[string]*big.Int) {
+	if t.Anomalies == nil || len(rewards) == 0 {
+		return
+	}
+	now := time.Now()
+	for _, a := range t.Anomalies.Observe(now, rewards) {
+		text := a.Text(t.RewardFormat, now)
+		evType := notify.EventCrash
+		if a.Kind == anomaly.Recovered {
+			evType = notify.EventInfo
+			console.Infof("%s", text)
+		} else {
+			console.Warnf("%s", text)
 		}
 
-		// For rewards, we pass the peer ID as part of the array
-		peerIds := []string{peerID}
-		if r, err := t.queryUserRewards(peerIds, contract); err == nil && r.Cmp(big.NewInt(0)) > 0 {
-			totalRewards = r // Use only this value, don't add
-			fmt.Printf("Found rewards for peer ID %s on contract %s: %s\n", peerID, contract, r.String())
-			contractHasData = true
+		if err := t.sendTelegramMessageHTML(fmt.Sprintf("⚠️ <b>%s</b>\n\n%s", a.Title(), html.EscapeString(text))); err != nil {
+			console.Errorf("Failed to send Telegram message: %v", err)
 		}
-
-		// If we found any data on this contract, use it and don't check the next one
-		if contractHasData {
-			fmt.Printf("Using data from contract %s for peer ID %s\n", contract, peerID)
-			break
+		if len(t.Notifiers) > 0 {
+			ev := notify.Event{Type: evType, Title: a.Title(), Message: html.EscapeString(text), Time: now}
+			if err := t.Notifiers.Notify(ev); err != nil {
+				console.Errorf("Failed to send notification: %v", err)
+			}
 		}
 	}
 
-	// Get ETH balance for the EOA address (only if it's an Ethereum address)
-	var balance *big.Int = big.NewInt(0)
-	if strings.HasPrefix(t.UserEOAAddress, "0x") && len(t.UserEOAAddress) == 42 {
-		if b, err := t.queryUserBalance(t.UserEOAAddress); err == nil {
-			balance = b
-			fmt.Printf("Found balance for EOA %s: %s\n", t.UserEOAAddress, balance.String())
-		}
-	} else {
-		fmt.Printf("Skipping balance query - not an Ethereum address: %s\n", t.UserEOAAddress)
+	t.persist(anomaly.StateFile, func() error { return t.Anomalies.Save(filepath.Join(t.StateDir, anomaly.StateFile)) })
+}
+
+// checkWallet alerts when the EOA sent or received transactions since the
+// previous check
+func (t *TelegramService) checkWallet() {
+	if t.wallet == nil {
+		return
+	}
+	activity, err := t.wallet.Check(context.Background())
+	if err != nil {
+		console.Warnf("Could not check wallet activity: %v", err)
+	}
+	t.persist(wallet.StateFile, func() error { return t.wallet.Save(filepath.Join(t.StateDir, wallet.StateFile)) })
+	if activity == nil {
+		return
 	}
 
-	return &BlockchainData{
-		Votes:   totalVotes,
-		Rewards: totalRewards,
-		Balance: balance,
-	}, nil
+	text := activity.Text()
+	console.Warnf("%s", text)
+	link := fmt.Sprintf(`<a href="%s/address/%s">View the EOA on the explorer</a>`, explorerURL, activity.Address)
+	if err := t.sendTelegramMessageHTML(fmt.Sprintf("🚨 <b>EOA Activity Detected</b>\n\n%s\n\n%s", html.EscapeString(text), link)); err != nil {
+		console.Errorf("Failed to send Telegram message: %v", err)
+	}
+	if len(t.Notifiers) > 0 {
+		ev := notify.Event{Type: notify.EventCrash, Title: "EOA Activity Detected", Message: html.EscapeString(text) + "\n\n" + link, Time: time.Now()}
+		if err := t.Notifiers.Notify(ev); err != nil {
+			console.Errorf("Failed to send notification: %v", err)
+		}
+	}
 }
 
-// queryUserVotes queries the smart contract for user votes using Alchemy API
-// Function selector: 0xdfb3c7df
-// Function signature: getVoterVoteCount(string memory peerId) public view returns (uint256)
-func (t *TelegramService) queryUserVotes(peerId string, contractAddress string) (*big.Int, error) {
-	// Function selector for getVoterVoteCount: 0xdfb3c7df
-	methodID := "0xdfb3c7df"
-
-	// Create the call data for string parameter
-	// First, encode the offset to the string data (32 bytes)
-	offset := "0000000000000000000000000000000000000000000000000000000000000020"
+// compareSwarm ranks the peers' rewards per hour against a sample of the
+// swarm and reports the result
+func (t *TelegramService) compareSwarm() {
+	result, err := t.comparer.Compare(context.Background(), time.Now(), t.PeerIDs)
+	if err != nil {
+		console.Warnf("Could not compare with the swarm: %v", err)
+		return
+	}
+	t.persist(swarmstats.StateFile, func() error { return t.comparer.Save(filepath.Join(t.StateDir, swarmstats.StateFile)) })
+	if result == nil {
+		console.Infof("Recorded a swarm comparison baseline of %d peers", len(t.PeerIDs))
+		return
+	}
 
-	// Then encode the string length
-	stringLength := fmt.Sprintf("%064x", len(peerId))
+	text := result.Text(t.RewardFormat)
+	title, evType := "Swarm Comparison", notify.EventInfo
+	if len(result.Low()) > 0 {
+		title, evType = "Peers Below the Swarm", notify.EventCrash
+		console.Warnf("%s", text)
+	} else {
+		console.Infof("%s", text)
+	}
+	if err := t.sendTelegramMessageHTML(fmt.Sprintf("📊 <b>%s</b>\n\n%s", title, html.EscapeString(text))); err != nil {
+		console.Errorf("Failed to send Telegram message: %v", err)
+	}
+	if len(t.Notifiers) > 0 {
+		ev := notify.Event{Type: evType, Title: title, Message: html.EscapeString(text), Time: time.Now()}
+		if err := t.Notifiers.Notify(ev); err != nil {
+			console.Errorf("Failed to send notification: %v", err)
+		}
+	}
+}
 
-	// Then encode the string data (padded to 32 bytes)
-	stringBytes := []byte(peerId)
-	stringHex := fmt.Sprintf("%x", stringBytes)
-	// Pad to 32 bytes (64 hex chars)
-	for len(stringHex) < 64 {
-		stringHex += "0"
+// overBudget reports whether the RPC client has used up its hourly or
+// daily budget, in which case polling waits until it clears. The chat is
+// told when polling stops and when it resumes.
+func (t *TelegramService) overBudget() bool {
+	if t.RPC == nil || t.RPC.Usage == nil {
+		return false
+	}
+	e, over := t.RPC.Usage.Exceeded(time.Now())
+	var text string
+	switch {
+	case over && !t.throttled:
+		text = fmt.Sprintf("RPC budget reached: %s. Checks are paused until %s.", e, t.Time.Format(e.Until))
+		console.Warnf("%s", text)
+	case !over && t.throttled:
+		text = "RPC budget available again; checks resume."
+		console.Infof("%s", text)
+	}
+	t.throttled = over
+	if text == "" {
+		return over
+	}
+	if err := t.sendTelegramMessageHTML(fmt.Sprintf("⏸ <b>RPC Budget</b>\n\n%s", html.EscapeString(text))); err != nil {
+		console.Errorf("Failed to send Telegram message: %v", err)
+	}
+	if len(t.Notifiers) > 0 {
+		ev := notify.Event{Type: notify.EventInfo, Title: "RPC Budget", Message: html.EscapeString(text), Time: time.Now()}
+		if err := t.Notifiers.Notify(ev); err != nil {
+			console.Errorf("Failed to send notification: %v", err)
+		}
 	}
+	return over
+}
 
-	// Combine all parts
-	data := methodID + offset + stringLength + stringHex
+// rpcBudget describes u's budgets, e.g. "500 requests per hour, 5000
+// requests per day to each endpoint"
+func rpcBudget(u *rpc.Usage) string {
+	var parts []string
+	if u.PerHour > 0 {
+		parts = append(parts, fmt.Sprintf("%d requests per hour", u.PerHour))
+	}
+	if u.PerDay > 0 {
+		parts = append(parts, fmt.Sprintf("%d requests per day", u.PerDay))
+	}
+	return strings.Join(parts, ", ") + " to each endpoint"
+}
 
-	// Create the eth_call request
-	request := AlchemyRequest{
-		JSONRPC: "2.0",
-		ID:      1,
-		Method:  "eth_call",
-		Params: []interface{}{
-			map[string]interface{}{
-				"data":  data,
-				"to":    coordAddrMath, // Use the small swarm contract
-				"value": "0x0",
-			},
-			"latest",
-		},
+// untilDigest is how long to wait before checking for a due digest
+func (t *TelegramService) untilDigest() time.Duration {
+	wait := time.Until(t.Digest.Next(time.Now()))
+	if wait > time.Hour {
+		wait = time.Hour
 	}
+	return wait
+}
 
-	// Make the request
-	result, err := t.makeAlchemyRequest(request)
+// sendDigest sends the daily summary when its slot is due. The slot is
+// recorded before sending so a restart can't send it twice.
+func (t *TelegramService) sendDigest() {
+	slot, due := t.digests.Due(time.Now())
+	if !due {
+		return
+	}
+	if t.ReadOnlyState {
+		t.digests.Mark(slot)
+	}
+	t.persist(digest.StateFile, func() error { return t.digests.Sent(slot) })
+	summary, err := t.PeerHistory.Summary()
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Alchemy API: %w", err)
+		console.Warnf("Could not send the daily digest: %v", err)
+		return
 	}
 
-	// Parse the result
-	if resultStr, ok := result.(string); ok {
-		if strings.HasPrefix(resultStr, "0x") {
-			resultStr = strings.TrimPrefix(resultStr, "0x")
-			if len(resultStr) >= 64 {
-				votes := new(big.Int)
-				votes.SetString(resultStr, 16)
-				return votes, nil
-			}
+	text := digest.Text(summary, t.RewardFormat)
+	day := slot.In(t.Digest.Location).Format("2006-01-02")
+	title := "Daily Digest for " + day
+	console.Infof("%s\n%s", title, text)
+	if err := t.sendTelegramMessageHTML(fmt.Sprintf("🗓 <b>%s</b>\n\n%s", title, html.EscapeString(text))); err != nil {
+		console.Errorf("Failed to send Telegram message: %v", err)
+	}
+	doc := t.digestCSV(summary, day, title)
+	if doc != nil {
+		if err := t.broadcast().SendDocument(*doc); err != nil {
+			console.Errorf("Failed to send the digest CSV: %v", err)
+		}
+	}
+	if len(t.Notifiers) > 0 {
+		ev := notify.Event{Type: notify.EventInfo, Title: title, Message: html.EscapeString(text), Time: time.Now(), Document: doc}
+		if err := t.Notifiers.Notify(ev); err != nil {
+			console.Errorf("Failed to send notification: %v", err)
 		}
 	}
-
-	return big.NewInt(0), nil
 }
 
-// queryUserRewards queries the smart contract for user rewards using Alchemy API
-// Function selector: 0x80c3d97f
-// Function signature: getTotalRewards(string[] memory peerIds) public view returns (int256[])
-func (t *TelegramService) queryUserRewards(peerIds []string, contractAddress string) (*big.Int, error) {
-	// Function selector for getTotalRewards: 0x80c3d97f
-	methodID := "0x80c3d97f"
-
-	// Create the call data for string[] parameter
-	// First, encode the offset to the array data (32 bytes)
-	offset := "0000000000000000000000000000000000000000000000000000000000000020"
-
-	// Then encode the array length
-	arrayLength := fmt.Sprintf("%064x", len(peerIds))
-
-	// Then encode each string in the array
-	var stringData string
-	currentOffset := len(peerIds) * 32 // Start of string content after the offsets
-	for _, peerId := range peerIds {
-		// Encode string offset (relative to start of array data)
-		stringOffsetHex := fmt.Sprintf("%064x", currentOffset)
-		stringData += stringOffsetHex
-		// The length of the string content in bytes
-		currentOffset += ((len(peerId) + 31) / 32) * 32
-	}
-
-	// Then encode the actual string data
-	for _, peerId := range peerIds {
-		// Encode string length
-		stringLength := fmt.Sprintf("%064x", len(peerId))
-		// Encode string data and pad to 32 bytes
-		stringBytes := []byte(peerId)
-		stringHex := fmt.Sprintf("%x", stringBytes)
-		// Pad to a multiple of 32 bytes (64 hex chars)
-		for len(stringHex)%64 != 0 {
-			stringHex += "0"
-		}
-		stringData += stringLength + stringHex
+// digestCSV saves the day's digest as a spreadsheet in the reports
+// directory, or returns nil when the state directory is read-only or the
+// file can't be written
+func (t *TelegramService) digestCSV(summary *history.Summary, day, title string) *notify.Document {
+	if t.ReadOnlyState {
+		return nil
+	}
+	dir, err := filepath.Abs(filepath.Join(t.StateDir, DigestReportDir))
+	if err == nil {
+		err = os.MkdirAll(dir, 0o755)
+	}
+	path := filepath.Join(dir, "digest-"+day+".csv")
+	if err == nil {
+		err = atomicfile.WriteFile(path, digest.CSV(summary), 0o644)
+	}
+	if err != nil {
+		console.Warnf("Could not save the digest CSV: %v", err)
+		return nil
 	}
+	return &notify.Document{Name: filepath.Base(path), Path: path, Caption: html.EscapeString(title)}
+}
 
-	// Combine all parts
-	data := methodID + offset + arrayLength + stringData
+// recordPeerStats stores each peer's totals and the EOA's balance for the
+// rewards and metrics endpoints of the status API
+func (t *TelegramService) recordPeerStats(balance *big.Int, stats map[string]history.PeerStats) {
+	if t.PeerHistory == nil {
+		return
+	}
+	t.persist(PeerHistoryPath, func() error {
+		return t.PeerHistory.Record(time.Now(), t.UserEOAAddress, balance, t.PeerIDs, stats)
+	})
+}
 
-	// Create the eth_call request
-	request := AlchemyRequest{
-		JSONRPC: "2.0",
-		ID:      1,
-		Method:  "eth_call",
-		Params: []interface{}{
-			map[string]interface{}{
-				"data":  data,
-				"to":    coordAddrMath, // Use the small swarm contract
-				"value": "0x0",
-			},
-			"latest",
-		},
+// persist saves a state file with save, unless state is read-only. The
+// first failure to save each file, and the first save after that works
+// again, are reported in the chat and to the notifiers; failing files
+// also fail the health endpoint.
+func (t *TelegramService) persist(file string, save func() error) error {
+	if t.ReadOnlyState {
+		return nil
+	}
+	var err error
+	if t.StateDir != "" {
+		err = os.MkdirAll(t.StateDir, 0o755)
+	}
+	if err == nil {
+		err = save()
 	}
+	if err != nil {
+		console.Warnf("Could not save %s: %v", file, err)
+	}
+	if t.StateHealth == nil {
+		t.StateHealth = &statehealth.Tracker{}
+	}
+
+	var title, text, evType string
+	switch t.StateHealth.Record(file, err, time.Now()) {
+	case statehealth.Failed:
+		title, evType = "Monitor Can't Save Its State", notify.EventCrash
+		text = fmt.Sprintf("Saving %s failed: %v\n\nUntil it works, a restart loses the last totals and may repeat messages. "+
+			"Fix the file's permissions or free disk space, or run with --read-only-state if the filesystem is read-only on purpose.", file, err)
+	case statehealth.Recovered:
+		title, evType = "Monitor State Saved Again", notify.EventInfo
+		text = fmt.Sprintf("%s is being saved again.", file)
+	default:
+		return err
+	}
+	if err := t.sendTelegramMessageHTML(fmt.Sprintf("💾 <b>%s</b>\n\n%s", title, html.EscapeString(text))); err != nil {
+		console.Errorf("Failed to send Telegram message: %v", err)
+	}
+	if len(t.Notifiers) > 0 {
+		ev := notify.Event{Type: evType, Title: title, Message: html.EscapeString(text), Time: time.Now()}
+		if err := t.Notifiers.Notify(ev); err != nil {
+			console.Errorf("Failed to send notification: %v", err)
+		}
+	}
+	return err
+}
 
-	// Make the request
-	result, err := t.makeAlchemyRequest(request)
+// GetBlockchainDataForPeerID gets a peer's votes and rewards from the
+// rewards source, and the EOA's balance
+func (t *TelegramService) GetBlockchainDataForPeerID(peerID string) (*BlockchainData, error) {
+	console.Debugf("Querying %s for peer ID: %s", t.rewardsSource().Name(), peerID)
+	totals, err := t.rewardsSource().Peer(context.Background(), peerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Alchemy API: %w", err)
+		return nil, err
 	}
 
-	// Parse the result - this returns int256[] (array of rewards)
-	if resultStr, ok := result.(string); ok {
-		if strings.HasPrefix(resultStr, "0x") {
-			resultStr = strings.TrimPrefix(resultStr, "0x")
-			// The result is an array, so we need to parse it correctly
-			// Format: [offset][length][value1][value2]...
-			if len(resultStr) >= 128 { // At least offset + length
-				// Get array length
-				arrayLengthHex := resultStr[64:128]
-				arrayLength := new(big.Int)
-				arrayLength.SetString(arrayLengthHex, 16)
-
-				// If we have at least one value, get the first one
-				if arrayLength.Cmp(big.NewInt(0)) > 0 && len(resultStr) >= 192 {
-					firstValueHex := resultStr[128:192]
-					rewards := new(big.Int)
-					rewards.SetString(firstValueHex, 16)
-					return rewards, nil
-				}
-			}
+	// Get ETH balance for the EOA address (only if it's an Ethereum address)
+	var balance *big.Int
+	if strings.HasPrefix(t.UserEOAAddress, "0x") && len(t.UserEOAAddress) == 42 {
+		if b, err := t.queryUserBalance(t.UserEOAAddress); err == nil {
+			balance = b
+			console.Debugf("Found balance for EOA %s: %s", t.UserEOAAddress, balance.String())
+		} else {
+			console.Debugf("Could not read the balance of EOA %s: %v", t.UserEOAAddress, err)
 		}
+	} else {
+		console.Debugf("Skipping balance query - not an Ethereum address: %s", t.UserEOAAddress)
 	}
 
-	return big.NewInt(0), nil
+	return &BlockchainData{
+		Votes:   totals.Votes,
+		Rewards: totals.Rewards,
+		Balance: balance,
+	}, nil
+}
+
+// rewardsSource returns Rewards, defaulting to the coordinator contracts
+func (t *TelegramService) rewardsSource() rewards.Source {
+	if t.Rewards == nil {
+		if t.RPC == nil {
+			t.RPC = rpc.NewClient()
+		}
+		t.Rewards = rewards.NewChain(t.RPC, alchemyPublicURL, t.Contracts...)
+	}
+	return t.Rewards
 }
 
 // queryUserBalance queries the user's ETH balance using Alchemy API
@@ -652,135 +1091,59 @@ func (t *TelegramService) queryUserBalance(userAddress string) (*big.Int, error)
 		return nil, fmt.Errorf("failed to call Alchemy API: %w", err)
 	}
 
-	// Parse the result
-	if resultStr, ok := result.(string); ok {
-		if strings.HasPrefix(resultStr, "0x") {
-			resultStr = strings.TrimPrefix(resultStr, "0x")
-			balance := new(big.Int)
-			balance.SetString(resultStr, 16)
-			return balance, nil
-		}
+	// A missing or malformed result is a failed query, not an empty wallet
+	resultStr, ok := result.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected eth_getBalance result %v", result)
 	}
-
-	return big.NewInt(0), nil
-}
-
-// makeAlchemyRequest makes a request to the Alchemy API
-func (t *TelegramService) makeAlchemyRequest(request AlchemyRequest) (interface{}, error) {
-	// Prepare the request body
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	digits, ok := strings.CutPrefix(resultStr, "0x")
+	if !ok || digits == "" {
+		return nil, fmt.Errorf("malformed eth_getBalance result %q", resultStr)
 	}
-
-	// Create the HTTP request - use public endpoint
-	url := alchemyPublicURL
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	balance, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return nil, fmt.Errorf("malformed eth_getBalance result %q", resultStr)
 	}
+	return balance, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	// Make the request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+// makeAlchemyRequest makes a request to the Alchemy API through the shared RPC client
+func (t *TelegramService) makeAlchemyRequest(request AlchemyRequest) (interface{}, error) {
+	if t.RPC == nil {
+		t.RPC = rpc.NewClient()
 	}
-	defer resp.Body.Close()
 
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
+	// Use public endpoint
+	result, err := t.RPC.Call(context.Background(), alchemyPublicURL, request.Method, request.Params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Debug: Print the response
-	fmt.Printf("Alchemy API Response: %s\n", string(body))
-
-	// Check if response is JSON
-	if !strings.HasPrefix(strings.TrimSpace(string(body)), "{") {
-		return nil, fmt.Errorf("non-JSON response from Alchemy API: %s", string(body))
+		return nil, fmt.Errorf("Alchemy API: %w", err)
 	}
 
-	// Parse the response
-	var response AlchemyResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
+	console.Debugf("Alchemy API Response: %v", result)
 
-	// Check for errors
-	if response.Error != nil {
-		return nil, fmt.Errorf("Alchemy API error: %s (code: %d)", response.Error.Message, response.Error.Code)
-	}
-
-	return response.Result, nil
+	return result, nil
 }
 
-// GetBlockchainData queries all blockchain data for a user using Alchemy API
-func (t *TelegramService) GetBlockchainData(userAddress string) (*BlockchainData, error) {
-	fmt.Printf("Querying blockchain data for address: %s\n", userAddress)
-
-	// Try both contract addresses
-	contracts := []string{coordAddrMath, coordAddrMathHard}
-
-	var votes *big.Int
-	var rewards *big.Int
-
-	// Try to get votes from either contract
-	// For votes, we pass the address as a peer ID
-	for _, contract := range contracts {
-		if v, err := t.queryUserVotes(userAddress, contract); err == nil && v.Cmp(big.NewInt(0)) > 0 {
-			votes = v
-			fmt.Printf("Found votes in contract %s: %s\n", contract, votes.String())
-			break
-		} else {
-			fmt.Printf("No votes found in contract %s: %v\n", contract, err)
-		}
+// makeCachedRequest is makeAlchemyRequest for results that rarely change,
+// served from the RPC cache when they were fetched within ttl
+func (t *TelegramService) makeCachedRequest(request AlchemyRequest, ttl time.Duration) (interface{}, error) {
+	if t.RPC == nil {
+		t.RPC = rpc.NewClient()
 	}
-
-	// Try to get rewards from either contract
-	// For rewards, we need to pass an array of peer IDs
-	peerIds := []string{userAddress} // For now, treat the address as a peer ID
-	for _, contract := range contracts {
-		if r, err := t.queryUserRewards(peerIds, contract); err == nil && r.Cmp(big.NewInt(0)) > 0 {
-			rewards = r
-			fmt.Printf("Found rewards in contract %s: %s\n", contract, rewards.String())
-			break
-		} else {
-			fmt.Printf("No rewards found in contract %s: %v\n", contract, err)
-		}
-	}
-
-	// Get ETH balance (only if it's an Ethereum address)
-	var balance *big.Int
-	if strings.HasPrefix(userAddress, "0x") && len(userAddress) == 42 {
-		balance, err := t.queryUserBalance(userAddress)
-		if err != nil {
-			fmt.Printf("Failed to get balance: %v\n", err)
-			balance = big.NewInt(0)
-		} else {
-			fmt.Printf("Found balance: %s wei\n", balance.String())
-		}
-	} else {
-		fmt.Printf("Skipping balance query - not an Ethereum address: %s\n", userAddress)
-		balance = big.NewInt(0)
+	result, err := t.RPC.CallCached(context.Background(), alchemyPublicURL, request.Method, request.Params, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("Alchemy API: %w", err)
 	}
+	return result, nil
+}
 
-	// Initialize with zero values if not found
-	if votes == nil {
-		votes = big.NewInt(0)
-	}
-	if rewards == nil {
-		rewards = big.NewInt(0)
+// peerCacheTTL is how long a cached peer list is used at startup: peers
+// are re-resolved on the PeerRefresh schedule anyway
+func (t *TelegramService) peerCacheTTL() time.Duration {
+	if t.PeerRefresh > 0 {
+		return t.PeerRefresh
 	}
-
-	return &BlockchainData{
-		Votes:   votes,
-		Rewards: rewards,
-		Balance: balance,
-	}, nil
+	return DefaultPeerRefresh
 }
 
 // AlchemyRequest represents a JSON-RPC request to Alchemy
@@ -804,46 +1167,11 @@ type AlchemyResponse struct {
 
 // sendTelegramMessageWithMarkdown sends a message to Telegram using the Bot API with MarkdownV2 formatting
 func (t *TelegramService) sendTelegramMessageWithMarkdown(text string) error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.Config.BotToken)
-
-	// Prepare the request data
-	data := url.Values{}
-	data.Set("chat_id", t.Config.ChatID)
-	data.Set("text", text)
-	data.Set("parse_mode", "MarkdownV2")
-
-	// Make the HTTP request
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
-		return fmt.Errorf("failed to send Telegram message: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Check if the request was successful
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Telegram API error: %s - %s", resp.Status, string(body))
-	}
-
-	// Parse the response to check for Telegram API errors
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		// If parsing fails, it might not be a JSON response, but could still be a success.
-		// For now, let's log the raw response and assume success if status is OK.
-		fmt.Printf("Message sent successfully to Telegram! (non-JSON response: %s)\n", string(body))
-		return nil
-	}
-
-	if val, ok := result["ok"]; !ok || !val.(bool) {
-		return fmt.Errorf("Telegram API error: %v", result["description"])
+	if err := t.broadcast().SendMarkdown(text); err != nil {
+		return err
 	}
 
-	fmt.Printf("Message sent successfully to Telegram!\n")
+	console.Debugf("Message sent successfully to Telegram!")
 	return nil
 }
 
@@ -860,51 +1188,59 @@ func escapeLineStartHyphens(text string) string {
 
 // sendTelegramMessageHTML sends a message to Telegram using the Bot API with HTML formatting
 func (t *TelegramService) sendTelegramMessageHTML(text string) error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.Config.BotToken)
+	if err := t.broadcast().SendHTML(text); err != nil {
+		return err
+	}
 
-	// Prepare the request data
-	data := url.Values{}
-	data.Set("chat_id", t.Config.ChatID)
-	data.Set("text", text)
-	data.Set("parse_mode", "HTML")
+	console.Debugf("Message sent successfully to Telegram!")
+	return nil
+}
 
-	// Make the HTTP request
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
-		return fmt.Errorf("failed to send Telegram message: %w", err)
+// checkChainID warns when the RPC endpoint serves a different chain than the
+// one the contracts were configured for
+func (t *TelegramService) checkChainID() {
+	if t.RPC == nil {
+		t.RPC = rpc.NewClient()
 	}
-	defer resp.Body.Close()
-
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
+	reader := &chain.Reader{Client: t.RPC, Endpoint: alchemyPublicURL}
+	got, err := reader.ChainID(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+		console.Warnf("could not verify the chain ID: %v", err)
+		return
 	}
-
-	// Check if the request was successful
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Telegram API error: %s - %s", resp.Status, string(body))
-	}
-
-	// Parse the response to check for Telegram API errors
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		// If parsing fails, it might not be a JSON response, but could still be a success.
-		// For now, let's log the raw response and assume success if status is OK.
-		fmt.Printf("Message sent successfully to Telegram! (non-JSON response: %s)\n", string(body))
-		return nil
+	if got != t.ChainID {
+		console.Warnf("the RPC endpoint is on chain %d, but contracts are configured for chain %d", got, t.ChainID)
 	}
+}
 
-	if val, ok := result["ok"]; !ok || !val.(bool) {
-		return fmt.Errorf("Telegram API error: %v", result["description"])
+// notifier returns the Telegram client for the loaded config
+func (t *TelegramService) notifier() *notify.Telegram {
+	tg := notify.NewTelegram(t.Config.BotToken, t.Config.ChatID)
+	if t.client != nil {
+		tg.Client = t.client
 	}
+	return tg
+}
 
-	fmt.Printf("Message sent successfully to Telegram!\n")
-	return nil
+// broadcast sends to the configured chat and the subscribers
+func (t *TelegramService) broadcast() notify.Broadcast {
+	chats := append([]string{t.Config.ChatID}, t.Config.Subscribers...)
+	return notify.NewBroadcast(t.Config.BotToken, t.client, append(chats, t.Subscribers...)...)
 }
 
 // savePreviousData saves the previous data to a JSON file
 func (t *TelegramService) savePreviousData(data *PreviousData) error {
+	if t.StateDir != "" {
+		if err := os.MkdirAll(t.StateDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+	return WritePreviousData(filepath.Join(t.StateDir, PreviousDataPath), data)
+}
+
+// WritePreviousData writes the blockchain totals in the format
+// ReadPreviousData expects
+func WritePreviousData(filePath string, data *PreviousData) error {
 	// Convert big.Int to string for JSON serialization
 	dataToSave := map[string]interface{}{
 		"votes":      data.Votes.String(),
@@ -912,7 +1248,6 @@ func (t *TelegramService) savePreviousData(data *PreviousData) error {
 		"last_check": data.LastCheck.Format(time.RFC3339),
 	}
 
-	filePath := "telegram_previous_data.json"
 	file, err := os.Create(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to create previous data file: %w", err)
@@ -926,16 +1261,24 @@ func (t *TelegramService) savePreviousData(data *PreviousData) error {
 
 // loadPreviousData loads the previous data from a JSON file
 func (t *TelegramService) loadPreviousData() (*PreviousData, error) {
-	filePath := "telegram_previous_data.json"
+	data, err := ReadPreviousData(filepath.Join(t.StateDir, PreviousDataPath))
+	if err != nil && os.IsNotExist(err) {
+		// File doesn't exist, return default data
+		return &PreviousData{
+			Votes:     big.NewInt(0),
+			Rewards:   big.NewInt(0),
+			LastCheck: time.Now(),
+		}, nil
+	}
+	return data, err
+}
+
+// ReadPreviousData reads the blockchain totals persisted by the monitor
+func ReadPreviousData(filePath string) (*PreviousData, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// File doesn't exist, return default data
-			return &PreviousData{
-				Votes:     big.NewInt(0),
-				Rewards:   big.NewInt(0),
-				LastCheck: time.Now(),
-			}, nil
+			return nil, err
 		}
 		return nil, fmt.Errorf("failed to open previous data file: %w", err)
 	}
@@ -962,6 +1305,7 @@ func (t *TelegramService) loadPreviousData() (*PreviousData, error) {
 	}
 	rewards := new(big.Int)
 	rewards.SetString(rewardsStr, 10)
+	int256.Repair(rewards)
 
 	// Parse last check time
 	lastCheckStr, ok := dataMap["last_check"].(string)
@@ -980,6 +1324,31 @@ func (t *TelegramService) loadPreviousData() (*PreviousData, error) {
 	}, nil
 }
 
+// rewardTrendLine records the current totals in the history and, when
+// estimates are enabled, returns a message line with the rewards trend
+func (t *TelegramService) rewardTrendLine(rewards, votes *big.Int) string {
+	now := time.Now()
+	var samples []history.Sample
+	if t.RewardEstimates {
+		var err error
+		if samples, err = t.History.Load(); err != nil {
+			console.Warnf("Could not load rewards history: %v", err)
+		}
+	}
+	t.persist(RewardsHistoryPath, func() error {
+		return t.History.Append(history.Sample{Time: now, Rewards: rewards, Votes: votes})
+	})
+
+	if !t.RewardEstimates {
+		return ""
+	}
+	est := history.Trend(samples, rewards, now)
+	if est == nil {
+		return "📆 <b>Estimate:</b> collecting history...\n"
+	}
+	return fmt.Sprintf("📆 <b>Estimate:</b> %s\n", est.TextFormat(t.RewardFormat))
+}
+
 // sendWelcomeMessage sends a welcome message to new users
 func (t *TelegramService) sendWelcomeMessage() error {
 	message := `🤖 <b>Welcome to G-Swarm Monitor!</b>
@@ -1018,8 +1387,9 @@ func promptForEOAAddress() (string, error) {
 	return address, nil
 }
 
-// getPeerIDs fetches the peer IDs associated with the given EOA address
-func (t *TelegramService) getPeerIDs(eoaAddress string) ([]string, error) {
+// getPeerIDs fetches the peer IDs associated with the given EOA address,
+// using a list cached within ttl when the RPC client has a cache
+func (t *TelegramService) getPeerIDs(eoaAddress string, ttl time.Duration) ([]string, error) {
 	// Use the correct function selector for getPeerId: 0xb894a469
 	// Function signature: getPeerId(eoas address[]) returns (string[][])
 	// We need to encode an array of addresses
@@ -1040,14 +1410,13 @@ func (t *TelegramService) getPeerIDs(eoaAddress string) ([]string, error) {
 	// Construct the data field: function selector + encoded array
 	data := "0xb894a469" + offset + arrayLength + addressData
 
-	fmt.Printf("Debug: Calling getPeerId with data: %s\n", data)
-	fmt.Printf("Debug: Address parameter: %s\n", addressParam)
+	console.Debugf("Calling getPeerId with data: %s", data)
+	console.Debugf("Address parameter: %s", addressParam)
 
-	// Try both contract addresses
-	contracts := []string{coordAddrMath, coordAddrMathHard}
+	// Try each coordinator contract
 
-	for _, contract := range contracts {
-		fmt.Printf("Debug: Trying contract: %s\n", contract)
+	for _, contract := range t.Contracts {
+		console.Debugf("Trying contract: %s", contract)
 
 		// Create the eth_call request
 		request := AlchemyRequest{
@@ -1065,42 +1434,107 @@ func (t *TelegramService) getPeerIDs(eoaAddress string) ([]string, error) {
 		}
 
 		// Make the request
-		result, err := t.makeAlchemyRequest(request)
+		result, err := t.makeCachedRequest(request, ttl)
 		if err != nil {
-			fmt.Printf("Debug: Error with contract %s: %v\n", contract, err)
+			console.Debugf("Error with contract %s: %v", contract, err)
 			continue
 		}
 
 		// Parse the result
 		resultStr, ok := result.(string)
 		if !ok {
-			fmt.Printf("Debug: Unexpected result type: %T\n", result)
+			console.Debugf("Unexpected result type: %T", result)
 			continue
 		}
 
-		fmt.Printf("Debug: Got result: %s\n", resultStr)
+		console.Debugf("Got result: %s", resultStr)
 
 		// Use ABI-aware decoder to extract peer IDs
 		peerIDs, err := decodePeerIDs(resultStr)
 		if err != nil {
-			fmt.Printf("Debug: Failed to decode peer IDs from contract %s: %v\n", contract, err)
+			console.Debugf("Failed to decode peer IDs from contract %s: %v", contract, err)
 			continue
 		}
 
 		if len(peerIDs) > 0 {
-			fmt.Printf("Found %d peer IDs for address %s on contract %s\n", len(peerIDs), eoaAddress, contract)
+			console.Infof("Found %d peer IDs for address %s on contract %s", len(peerIDs), eoaAddress, contract)
 			for i, peerID := range peerIDs {
-				fmt.Printf("  %d: %s\n", i+1, peerID)
+				console.Infof("  %d: %s", i+1, peerID)
 			}
 			return peerIDs, nil
 		} else {
-			fmt.Printf("Debug: No peer IDs found for this EOA on contract %s\n", contract)
+			console.Debugf("No peer IDs found for this EOA on contract %s", contract)
 		}
 	}
 
 	return nil, fmt.Errorf("no peer IDs found for address: %s on any contract", eoaAddress)
 }
 
+// refreshPeerIDs re-resolves the peers registered to the EOA and announces
+// any that were added or removed. When the refresh was requested from the
+// chat, it also answers if nothing changed. It reports whether the peers changed.
+func (t *TelegramService) refreshPeerIDs(requested bool) bool {
+	peerIDs, err := t.getPeerIDs(t.UserEOAAddress, 0)
+	if err != nil {
+		console.Warnf("could not refresh peer IDs: %v", err)
+		if requested {
+			t.sendTelegramMessageHTML("⚠️ Could not refresh peer IDs: " + html.EscapeString(err.Error()))
+		}
+		return false
+	}
+
+	added, removed := diffPeerIDs(t.PeerIDs, peerIDs)
+	if len(added) == 0 && len(removed) == 0 {
+		if requested {
+			t.sendTelegramMessageHTML(fmt.Sprintf("🔄 Peer IDs unchanged, monitoring %d peers.", len(peerIDs)))
+		}
+		return false
+	}
+	t.PeerIDs = peerIDs
+
+	var msg strings.Builder
+	msg.WriteString("🔄 <b>Peer IDs Updated</b>\n")
+	for _, p := range added {
+		fmt.Fprintf(&msg, "\n➕ <code>%s</code>", html.EscapeString(p))
+	}
+	for _, p := range removed {
+		fmt.Fprintf(&msg, "\n➖ <code>%s</code>", html.EscapeString(p))
+	}
+	fmt.Fprintf(&msg, "\n\nNow monitoring %d peers.", len(peerIDs))
+	console.Infof("Peer IDs changed: %d added, %d removed", len(added), len(removed))
+
+	if err := t.sendTelegramMessageHTML(msg.String()); err != nil {
+		console.Errorf("Failed to send Telegram message: %v", err)
+	}
+	if len(t.Notifiers) > 0 {
+		ev := notify.Event{Type: notify.EventInfo, Message: msg.String(), Time: time.Now()}
+		if err := t.Notifiers.Notify(ev); err != nil {
+			console.Errorf("Failed to send notification: %v", err)
+		}
+	}
+	return true
+}
+
+// diffPeerIDs returns the peers in next but not prev, and in prev but not next
+func diffPeerIDs(prev, next []string) (added, removed []string) {
+	seen := make(map[string]bool, len(prev))
+	for _, p := range prev {
+		seen[p] = true
+	}
+	for _, p := range next {
+		if !seen[p] {
+			added = append(added, p)
+		}
+		delete(seen, p)
+	}
+	for _, p := range prev {
+		if seen[p] {
+			removed = append(removed, p)
+		}
+	}
+	return added, removed
+}
+
 // getChangeIndicator returns an emoji indicating if a value increased, decreased, or stayed the same
 func getChangeIndicator(previous, current *big.Int) string {
 	cmp := current.Cmp(previous)
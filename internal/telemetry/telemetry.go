@@ -0,0 +1,344 @@
+// Package telemetry collects anonymous usage stats, so the maintainers can
+// see which failures actually occur in the field. It is strictly opt-in:
+// nothing is recorded until the user agrees, either at the first
+// interactive start or with --telemetry=on.
+//
+// Events carry the gswarm version, OS, a coarse GPU class and, for crashes,
+// a fingerprint of the error. They never include wallet addresses, peer
+// IDs, hostnames, paths or error text. Events are queued in the state
+// directory and sent in batches; the queue can be inspected at any time
+// with `gswarm telemetry show`.
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/httpclient"
+)
+
+// ConsentFile records the user's choice, relative to the state directory
+const ConsentFile = "telemetry.json"
+
+// QueueFile holds events not yet sent, one JSON object per line
+const QueueFile = "telemetry_queue.jsonl"
+
+// maxQueue bounds the queued events; the oldest are dropped first
+const maxQueue = 500
+
+// Modes of the --telemetry flag
+const (
+	// ModeAsk prompts on the first interactive start and keeps the answer
+	ModeAsk = "ask"
+	ModeOn  = "on"
+	ModeOff = "off"
+)
+
+// ParseMode validates a --telemetry value
+func ParseMode(s string) (string, error) {
+	switch m := strings.ToLower(strings.TrimSpace(s)); m {
+	case "", ModeAsk:
+		return ModeAsk, nil
+	case ModeOn, "true", "yes":
+		return ModeOn, nil
+	case ModeOff, "false", "no":
+		return ModeOff, nil
+	default:
+		return "", fmt.Errorf("invalid telemetry mode %q: use on, off or ask", s)
+	}
+}
+
+// Event types
+const (
+	EventStart = "start"
+	EventCrash = "crash"
+)
+
+// Event is one anonymous report
+type Event struct {
+	// ID is random, so a sent event is taken off the queue by identity
+	// and a resent one can be told from a new one
+	ID   string    `json:"id,omitempty"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// InstallID is random, so reports from one install can be told apart
+	// without identifying it
+	InstallID string `json:"install_id"`
+	Version   string `json:"version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	GPUClass  string `json:"gpu_class,omitempty"`
+	// Fingerprint, ExitReason, ExitCode and Diagnoses describe a crash
+	Fingerprint string   `json:"fingerprint,omitempty"`
+	ExitReason  string   `json:"exit_reason,omitempty"`
+	ExitCode    int      `json:"exit_code,omitempty"`
+	Diagnoses   []string `json:"diagnoses,omitempty"`
+}
+
+// Consent is the user's recorded choice
+type Consent struct {
+	Enabled   bool      `json:"enabled"`
+	InstallID string    `json:"install_id,omitempty"`
+	Decided   time.Time `json:"decided"`
+}
+
+// LoadConsent reads the choice saved in dir, or nil when none was made
+func LoadConsent(dir string) (*Consent, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ConsentFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry consent: %w", err)
+	}
+	var c Consent
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry consent: %w", err)
+	}
+	return &c, nil
+}
+
+// SaveConsent records the choice in dir. Opting in assigns a new install
+// ID unless one exists; opting out deletes any queued events.
+func SaveConsent(dir string, enabled bool) (*Consent, error) {
+	prev, _ := LoadConsent(dir)
+	c := &Consent{Enabled: enabled, Decided: time.Now().UTC()}
+	if enabled {
+		if prev != nil && prev.InstallID != "" {
+			c.InstallID = prev.InstallID
+		} else {
+			c.InstallID = newInstallID()
+		}
+	} else {
+		os.Remove(filepath.Join(dir, QueueFile))
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to save telemetry consent: %w", err)
+		}
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ConsentFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to save telemetry consent: %w", err)
+	}
+	return c, nil
+}
+
+func newInstallID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newEventID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// GPUClass buckets the largest GPU's memory, so the model alone isn't
+// reported
+func GPUClass(vramMiB int) string {
+	switch gib := vramMiB / 1024; {
+	case vramMiB <= 0:
+		return "none"
+	case gib < 8:
+		return "<8GB"
+	case gib < 16:
+		return "8-16GB"
+	case gib < 24:
+		return "16-24GB"
+	case gib < 48:
+		return "24-48GB"
+	default:
+		return "48GB+"
+	}
+}
+
+// Patterns of the parts of an error that vary between machines or runs
+var (
+	hexPattern    = regexp.MustCompile(`0x[0-9a-fA-F]+|\b[0-9a-fA-F]{16,}\b`)
+	pathPattern   = regexp.MustCompile(`(?:[A-Za-z]:)?(?:[/\\][\w.@+-]+)+[/\\]?`)
+	urlPattern    = regexp.MustCompile(`\b\w+://\S+`)
+	quotedPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	numberPattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint hashes an error message with its addresses, paths, URLs,
+// quoted values and numbers stripped, so the same failure on different
+// machines gives the same fingerprint and nothing identifying is sent
+func Fingerprint(msg string) string {
+	s := urlPattern.ReplaceAllString(msg, "<url>")
+	s = hexPattern.ReplaceAllString(s, "<hex>")
+	s = pathPattern.ReplaceAllString(s, "<path>")
+	s = quotedPattern.ReplaceAllString(s, "<str>")
+	s = numberPattern.ReplaceAllString(s, "<n>")
+	s = strings.TrimSpace(spacePattern.ReplaceAllString(s, " "))
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Reporter queues events in Dir and sends them to Endpoint
+type Reporter struct {
+	Dir       string
+	Endpoint  string
+	InstallID string
+	Version   string
+	GPUClass  string
+	// Client defaults to the shared gswarm client
+	Client *http.Client
+
+	// mu guards the queue file; flushing lets one Flush run at a time, so
+	// an event isn't sent twice
+	mu       sync.Mutex
+	flushing sync.Mutex
+}
+
+// Record adds ev to the queue, filling in the install's details
+func (r *Reporter) Record(ev Event) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	if ev.ID == "" {
+		ev.ID = newEventID()
+	}
+	ev.InstallID, ev.Version = r.InstallID, r.Version
+	ev.OS, ev.Arch = runtime.GOOS, runtime.GOARCH
+	if ev.GPUClass == "" {
+		ev.GPUClass = r.GPUClass
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events, err := Queued(r.Dir)
+	if err != nil {
+		return err
+	}
+	events = append(events, ev)
+	if len(events) > maxQueue {
+		events = events[len(events)-maxQueue:]
+	}
+	return r.write(events)
+}
+
+// Queued returns the events in dir waiting to be sent
+func Queued(dir string) ([]Event, error) {
+	f, err := os.Open(filepath.Join(dir, QueueFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry queue: %w", err)
+	}
+	defer f.Close()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev Event
+		// A torn line from a crash mid-write is skipped
+		if json.Unmarshal(scanner.Bytes(), &ev) == nil {
+			events = append(events, ev)
+		}
+	}
+	return events, scanner.Err()
+}
+
+func (r *Reporter) write(events []Event) error {
+	if r.Dir != "" {
+		if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+			return fmt.Errorf("failed to write telemetry queue: %w", err)
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	path := filepath.Join(r.Dir, QueueFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write telemetry queue: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Flush sends the queued events in one request and empties the queue once
+// they are accepted. Without an endpoint the events stay queued.
+func (r *Reporter) Flush(ctx context.Context) error {
+	if r.Endpoint == "" {
+		return nil
+	}
+	r.flushing.Lock()
+	defer r.flushing.Unlock()
+	r.mu.Lock()
+	events, err := Queued(r.Dir)
+	r.mu.Unlock()
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{"events": events})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := r.Client
+	if client == nil {
+		client = httpclient.Default()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to send telemetry: %s", resp.Status)
+	}
+
+	// Keep anything recorded while the request was in flight; the queue may
+	// also have dropped some of the sent events to stay in bounds
+	sent := make(map[string]bool, len(events))
+	for _, ev := range events {
+		sent[ev.ID] = true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queued, err := Queued(r.Dir)
+	if err != nil {
+		return err
+	}
+	var left []Event
+	for _, ev := range queued {
+		if !sent[ev.ID] {
+			left = append(left, ev)
+		}
+	}
+	if len(left) == 0 {
+		return os.Remove(filepath.Join(r.Dir, QueueFile))
+	}
+	return r.write(left)
+}
@@ -0,0 +1,214 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]string{"": ModeAsk, "ask": ModeAsk, "ON": ModeOn, "true": ModeOn, "off": ModeOff, "no": ModeOff} {
+		if got, err := ParseMode(in); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseMode("maybe"); err == nil {
+		t.Error("ParseMode(maybe) succeeded")
+	}
+}
+
+func TestConsent(t *testing.T) {
+	dir := t.TempDir()
+	if c, err := LoadConsent(dir); c != nil || err != nil {
+		t.Fatalf("LoadConsent() with no choice = %v, %v", c, err)
+	}
+	on, err := SaveConsent(dir, true)
+	if err != nil || !on.Enabled || len(on.InstallID) != 32 {
+		t.Fatalf("SaveConsent(true) = %+v, %v", on, err)
+	}
+	again, _ := SaveConsent(dir, true)
+	if again.InstallID != on.InstallID {
+		t.Error("opting in again changed the install ID")
+	}
+
+	r := &Reporter{Dir: dir, InstallID: on.InstallID}
+	if err := r.Record(Event{Type: EventStart}); err != nil {
+		t.Fatal(err)
+	}
+	off, err := SaveConsent(dir, false)
+	if err != nil || off.Enabled || off.InstallID != "" {
+		t.Fatalf("SaveConsent(false) = %+v, %v", off, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, QueueFile)); !os.IsNotExist(err) {
+		t.Error("opting out kept the queued events")
+	}
+	if c, _ := LoadConsent(dir); c == nil || c.Enabled {
+		t.Errorf("LoadConsent() = %+v, want disabled", c)
+	}
+}
+
+func TestGPUClass(t *testing.T) {
+	for mib, want := range map[int]string{0: "none", 4096: "<8GB", 12288: "8-16GB", 24576: "24-48GB", 81920: "48GB+"} {
+		if got := GPUClass(mib); got != want {
+			t.Errorf("GPUClass(%d) = %s, want %s", mib, got, want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(`CUDA out of memory. Tried to allocate 2.00 GiB (GPU 0; 23.65 GiB total) in /home/alice/rl-swarm/train.py`)
+	b := Fingerprint(`CUDA out of memory. Tried to allocate 512.00 GiB (GPU 1; 79.1 GiB total) in /root/rl-swarm/train.py`)
+	if a != b {
+		t.Error("the same failure on different machines gave different fingerprints")
+	}
+	if len(a) != 16 {
+		t.Errorf("fingerprint %q isn't 16 hex digits", a)
+	}
+	c := Fingerprint(`peer 0xAbC123 registered to "QmPeer" at https://rpc.example.com/x`)
+	d := Fingerprint(`peer 0xdef456 registered to "QmOther" at https://other.example.org/y`)
+	if c != d {
+		t.Error("addresses, quoted values or URLs changed the fingerprint")
+	}
+	if a == c {
+		t.Error("different failures gave the same fingerprint")
+	}
+}
+
+func TestRecordFlush(t *testing.T) {
+	dir := t.TempDir()
+	var got []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body struct{ Events []Event }
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		got = append(got, body.Events...)
+	}))
+	defer srv.Close()
+
+	r := &Reporter{Dir: dir, InstallID: "abc", Version: "1.2.3", GPUClass: "24-48GB", Client: srv.Client()}
+	r.Record(Event{Type: EventStart})
+	r.Record(Event{Type: EventCrash, Fingerprint: Fingerprint("boom"), ExitReason: "error", ExitCode: 1})
+
+	// Without an endpoint events stay queued
+	if err := r.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if q, _ := Queued(dir); len(q) != 2 {
+		t.Fatalf("queued %d events, want 2", len(q))
+	}
+
+	r.Endpoint = srv.URL
+	if err := r.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].InstallID != "abc" || got[0].Version != "1.2.3" || got[0].OS == "" || got[1].Type != EventCrash || got[1].GPUClass != "24-48GB" {
+		t.Errorf("sent %+v", got)
+	}
+	if q, _ := Queued(dir); len(q) != 0 {
+		t.Errorf("queue kept %d events after a flush", len(q))
+	}
+}
+
+func TestFlushFailureKeepsQueue(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	r := &Reporter{Dir: t.TempDir(), Endpoint: srv.URL, Client: srv.Client()}
+	r.Record(Event{Type: EventStart})
+	if err := r.Flush(context.Background()); err == nil {
+		t.Error("Flush() succeeded against a failing endpoint")
+	}
+	if q, _ := Queued(r.Dir); len(q) != 1 {
+		t.Errorf("queued %d events, want 1", len(q))
+	}
+}
+
+func TestQueueBound(t *testing.T) {
+	r := &Reporter{Dir: t.TempDir()}
+	for i := 0; i < maxQueue+5; i++ {
+		r.Record(Event{Type: EventStart, ExitCode: i})
+	}
+	q, _ := Queued(r.Dir)
+	if len(q) != maxQueue || q[0].ExitCode != 5 {
+		t.Errorf("queue has %d events starting at %d; want %d starting at 5", len(q), q[0].ExitCode, maxQueue)
+	}
+}
+
+func TestFlush_Concurrent(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent = map[string]int{}
+	)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body struct{ Events []Event }
+		json.NewDecoder(req.Body).Decode(&body)
+		mu.Lock()
+		for _, ev := range body.Events {
+			sent[ev.ID]++
+		}
+		mu.Unlock()
+		<-release
+	}))
+	defer srv.Close()
+	r := &Reporter{Dir: t.TempDir(), Endpoint: srv.URL, Client: srv.Client()}
+	r.Record(Event{Type: EventStart})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Flush(context.Background())
+		}()
+	}
+	// Recorded while the first request is in flight
+	time.Sleep(50 * time.Millisecond)
+	r.Record(Event{Type: EventCrash})
+	close(release)
+	wg.Wait()
+
+	if len(sent) != 2 {
+		t.Errorf("sent %d distinct events, want 2", len(sent))
+	}
+	for id, n := range sent {
+		if n != 1 {
+			t.Errorf("event %s sent %d times", id, n)
+		}
+	}
+	if q, _ := Queued(r.Dir); len(q) != 0 {
+		t.Errorf("queue kept %d events", len(q))
+	}
+}
+
+func TestFlush_TrimmedQueue(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	r := &Reporter{Dir: t.TempDir(), Endpoint: srv.URL, Client: srv.Client()}
+	for i := 0; i < maxQueue; i++ {
+		r.Record(Event{Type: EventStart})
+	}
+	done := make(chan error)
+	go func() { done <- r.Flush(context.Background()) }()
+	time.Sleep(50 * time.Millisecond)
+	// The full queue drops the oldest sent event to make room
+	r.Record(Event{Type: EventCrash})
+	close(block)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if q, _ := Queued(r.Dir); len(q) != 1 || q[0].Type != EventCrash {
+		t.Errorf("queue after the flush = %+v, want the crash", q)
+	}
+}
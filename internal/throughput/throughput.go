@@ -0,0 +1,286 @@
+// Package throughput watches the trainer's training speed, as the rates its
+// progress bars print, and reports when it falls well below the node's own
+// baseline. A GPU that throttles when hot, or another workload sharing it,
+// slows training down long before anything crashes.
+package throughput
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/lines"
+)
+
+// StateFile holds the baselines, relative to the state directory
+const StateFile = "throughput.json"
+
+// Defaults for Monitor
+const (
+	DefaultWindow = 10 * time.Minute
+	DefaultDrop   = 0.3
+)
+
+// Units rates are kept in; seconds per iteration are turned into
+// iterations per second
+const (
+	UnitIterations = "it/s"
+	UnitTokens     = "tokens/s"
+)
+
+// baselineWeight is how much each healthy window moves the baseline
+const baselineWeight = 0.1
+
+// ratePattern matches the rates tqdm and trainers print, e.g. "3.21it/s",
+// "1.52s/it" or "812.4 tokens/s". Dataset maps and downloads print other
+// units, such as examples/s and MB/s, and are left out.
+var ratePattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s?(it/s|s/it|tok(?:en)?s?/s(?:ec)?)\b`)
+
+// Alert is a drop in throughput or its recovery
+type Alert struct {
+	Unit     string
+	Rate     float64
+	Baseline float64
+	// Recovered is set when throughput is back after a drop that started
+	// at Since
+	Recovered bool
+	Since     time.Time
+	At        time.Time
+}
+
+// Change is the rate relative to the baseline, e.g. -45%
+func (a Alert) Change() float64 {
+	return (a.Rate/a.Baseline - 1) * 100
+}
+
+// Text describes the alert
+func (a Alert) Text(window time.Duration) string {
+	if a.Recovered {
+		return fmt.Sprintf("Training throughput is back to %s (baseline %s) after dropping for %s.",
+			formatRate(a.Rate, a.Unit), formatRate(a.Baseline, a.Unit), a.At.Sub(a.Since).Round(time.Minute))
+	}
+	return fmt.Sprintf("Training throughput fell to %s over the last %s, %.0f%% below this node's baseline of %s. "+
+		"A GPU throttling when hot, or another workload using it, is the usual cause; check nvidia-smi for clocks, temperature and other processes.",
+		formatRate(a.Rate, a.Unit), window, -a.Change(), formatRate(a.Baseline, a.Unit))
+}
+
+func formatRate(rate float64, unit string) string {
+	return strconv.FormatFloat(rate, 'f', 2, 64) + " " + unit
+}
+
+// unitState is the current window and the drop, if any, of one unit
+type unitState struct {
+	start    time.Time
+	sum      float64
+	count    int
+	degraded bool
+	since    time.Time
+}
+
+// Monitor is an io.Writer that reads rates from the trainer's output. It
+// averages them over each Window and compares the average with a baseline
+// learnt from earlier windows, reporting a drop of more than Drop and the
+// recovery from it.
+type Monitor struct {
+	Window time.Duration
+	// Drop is the fraction below the baseline that is reported, e.g. 0.3
+	Drop float64
+	// OnAlert, if set, is called with each drop and recovery
+	OnAlert func(Alert)
+
+	mu     sync.Mutex
+	lines  *lines.Splitter
+	state  state
+	units  map[string]*unitState
+	warm   time.Time
+	latest map[string]float64
+	alerts []Alert
+}
+
+type state struct {
+	// Baselines are the usual rates by unit, kept across runs
+	Baselines map[string]float64 `json:"baselines"`
+	Updated   time.Time          `json:"updated"`
+}
+
+// New returns a monitor without baselines; window 0 is DefaultWindow
+func New(window time.Duration, drop float64) *Monitor {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Monitor{Window: window, Drop: drop, state: state{Baselines: map[string]float64{}}}
+}
+
+// Load reads the baselines saved with Save, starting without any if path
+// doesn't exist
+func Load(path string, window time.Duration, drop float64) (*Monitor, error) {
+	m := New(window, drop)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m.state); err != nil {
+		return New(window, drop), fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if m.state.Baselines == nil {
+		m.state.Baselines = map[string]float64{}
+	}
+	return m, nil
+}
+
+// Save writes the baselines to path
+func (m *Monitor) Save(path string) error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save throughput baselines: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Start begins a run at now. The first window is a warm-up, as loading
+// the model and the first steps are slower, and isn't compared.
+func (m *Monitor) Start(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lines = nil
+	m.units = map[string]*unitState{}
+	m.latest = map[string]float64{}
+	m.warm = now.Add(m.Window)
+}
+
+// Rates returns the average rate of each unit over its last window
+func (m *Monitor) Rates() map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rates := make(map[string]float64, len(m.latest))
+	for unit, rate := range m.latest {
+		rates[unit] = rate
+	}
+	return rates
+}
+
+// Baselines returns the baselines for the console, e.g. "3.20 it/s"
+func (m *Monitor) Baselines() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for unit, rate := range m.state.Baselines {
+		out = append(out, formatRate(rate, unit))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Write implements io.Writer
+func (m *Monitor) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	if m.lines == nil {
+		m.lines = lines.New(func(line []byte) {
+			if unit, rate, ok := parseRate(line); ok {
+				if a, ok := m.observe(unit, rate, time.Now()); ok {
+					m.alerts = append(m.alerts, a)
+				}
+			}
+		})
+	}
+	m.lines.Write(p)
+	alerts := m.alerts
+	m.alerts = nil
+	m.mu.Unlock()
+
+	if m.OnAlert != nil {
+		for _, a := range alerts {
+			m.OnAlert(a)
+		}
+	}
+	return len(p), nil
+}
+
+// parseRate reads the last rate on a line, in the unit it is kept in
+func parseRate(line []byte) (string, float64, bool) {
+	matches := ratePattern.FindAllSubmatch(line, -1)
+	if matches == nil {
+		return "", 0, false
+	}
+	match := matches[len(matches)-1]
+	rate, err := strconv.ParseFloat(string(match[1]), 64)
+	if err != nil || rate <= 0 {
+		return "", 0, false
+	}
+	switch unit := string(match[2]); {
+	case unit == "it/s":
+		return UnitIterations, rate, true
+	case unit == "s/it":
+		return UnitIterations, 1 / rate, true
+	case strings.HasPrefix(unit, "tok"):
+		return UnitTokens, rate, true
+	}
+	return "", 0, false
+}
+
+// observe adds a rate seen at now and compares each window once it is
+// complete. Callers hold m.mu.
+func (m *Monitor) observe(unit string, rate float64, now time.Time) (Alert, bool) {
+	if m.units == nil {
+		m.units = map[string]*unitState{}
+		m.latest = map[string]float64{}
+	}
+	u := m.units[unit]
+	if u == nil {
+		u = &unitState{start: now}
+		m.units[unit] = u
+	}
+	if now.Sub(u.start) < m.Window {
+		u.sum += rate
+		u.count++
+		return Alert{}, false
+	}
+
+	// The window is complete; rate starts the next one
+	mean := u.sum / float64(u.count)
+	windowStart := u.start
+	*u = unitState{start: now, sum: rate, count: 1, degraded: u.degraded, since: u.since}
+	if windowStart.Before(m.warm) {
+		return Alert{}, false
+	}
+	m.latest[unit] = mean
+
+	baseline := m.state.Baselines[unit]
+	if baseline == 0 {
+		m.state.Baselines[unit] = mean
+		m.state.Updated = now
+		return Alert{}, false
+	}
+	ratio := mean / baseline
+	switch {
+	case !u.degraded && m.Drop > 0 && ratio < 1-m.Drop:
+		u.degraded, u.since = true, windowStart
+		return Alert{Unit: unit, Rate: mean, Baseline: baseline, Since: windowStart, At: now}, true
+	case u.degraded && ratio >= 1-m.Drop/2:
+		// Half way back counts as recovered, so a rate near the
+		// threshold doesn't alert on every window
+		u.degraded = false
+		return Alert{Unit: unit, Rate: mean, Baseline: baseline, Recovered: true, Since: u.since, At: now}, true
+	case !u.degraded:
+		// Only healthy windows teach the baseline, so a slow GPU doesn't
+		// become the new normal
+		m.state.Baselines[unit] = baseline*(1-baselineWeight) + mean*baselineWeight
+		m.state.Updated = now
+	}
+	return Alert{}, false
+}
@@ -0,0 +1,94 @@
+package throughput
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		line string
+		unit string
+		rate float64
+	}{
+		{" 45%|████▌     | 45/100 [00:14<00:17,  3.20it/s]", UnitIterations, 3.2},
+		{"Training: 12/50 [01:00<03:10, 5.00s/it]", UnitIterations, 0.2},
+		{"generation speed 812.5 tokens/s", UnitTokens, 812.5},
+		{"Map: 100%|██████████| 1000/1000 [00:01<00:00, 842.11 examples/s]", "", 0},
+		{"Starting round: 12/1000000", "", 0},
+	}
+	for _, c := range cases {
+		unit, rate, ok := parseRate([]byte(c.line))
+		if unit != c.unit || rate != c.rate || ok != (c.unit != "") {
+			t.Errorf("parseRate(%q) = %q, %v, %v", c.line, unit, rate, ok)
+		}
+	}
+}
+
+func TestMonitor_Drop(t *testing.T) {
+	m := New(10*time.Minute, 0.3)
+	start := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	m.Start(start)
+	var alerts []Alert
+	// feed a rate every minute for the given number of minutes
+	at := start
+	feed := func(rate float64, minutes int) {
+		for i := 0; i < minutes; i++ {
+			if a, ok := m.observe(UnitIterations, rate, at); ok {
+				alerts = append(alerts, a)
+			}
+			at = at.Add(time.Minute)
+		}
+	}
+
+	// warm-up at a low rate, then the baseline is learnt
+	feed(1, 10)
+	feed(4, 30)
+	if b := m.state.Baselines[UnitIterations]; b != 4 {
+		t.Fatalf("baseline = %v, want 4", b)
+	}
+	if len(alerts) != 0 {
+		t.Fatalf("alerts while steady: %+v", alerts)
+	}
+
+	// thermal throttling halves the rate
+	feed(2, 20)
+	if len(alerts) != 1 || alerts[0].Recovered || alerts[0].Rate != 2 || alerts[0].Baseline != 4 {
+		t.Fatalf("alerts = %+v, want one drop", alerts)
+	}
+	if text := alerts[0].Text(10 * time.Minute); !strings.Contains(text, "2.00 it/s over the last 10m0s, 50% below this node's baseline of 4.00 it/s") {
+		t.Errorf("Text() = %q", text)
+	}
+	if b := m.state.Baselines[UnitIterations]; b != 4 {
+		t.Errorf("slow windows moved the baseline to %v", b)
+	}
+
+	feed(3.9, 11)
+	if len(alerts) != 2 || !alerts[1].Recovered {
+		t.Fatalf("alerts = %+v, want a recovery", alerts)
+	}
+	if text := alerts[1].Text(10 * time.Minute); !strings.Contains(text, "back to 3.90 it/s (baseline 4.00 it/s) after dropping for 30m") {
+		t.Errorf("Text() = %q", text)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	m := New(time.Minute, 0.3)
+	m.state.Baselines[UnitTokens] = 800
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path, time.Minute, 0.3)
+	if err != nil || loaded.state.Baselines[UnitTokens] != 800 {
+		t.Errorf("Load() = %+v, %v", loaded.state, err)
+	}
+	if got := loaded.Baselines(); len(got) != 1 || got[0] != "800.00 tokens/s" {
+		t.Errorf("Baselines() = %v", got)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing"), time.Minute, 0.3); err != nil {
+		t.Errorf("Load() of a missing file = %v", err)
+	}
+}
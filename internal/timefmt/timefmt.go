@@ -0,0 +1,149 @@
+// Package timefmt holds the timezone and timestamp format shared by
+// notifications, reports, logs and the journal.
+package timefmt
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// Timestamp formats selectable by name
+const (
+	FormatDefault = "default" // 2006-01-02 15:04:05
+	FormatRFC3339 = "rfc3339" // 2006-01-02T15:04:05Z07:00
+	FormatUS      = "us"      // Jan 2, 2006 3:04:05 PM MST
+	FormatEU      = "eu"      // 02.01.2006 15:04:05 MST
+)
+
+const (
+	defaultLayout      = "2006-01-02 15:04:05"
+	defaultClockLayout = "15:04"
+)
+
+// roundTrip is written with a custom layout and parsed back: a layout that
+// can't reproduce it loses the date or the time of day
+var roundTrip = time.Date(2025, 11, 23, 19, 47, 0, 0, time.UTC)
+
+// Formatter renders times in a timezone and timestamp format. The zero
+// value uses the system timezone and the default format.
+type Formatter struct {
+	// Location is the timezone times are shown in; nil is the system's
+	Location    *time.Location
+	Layout      string
+	ClockLayout string
+}
+
+// New returns a Formatter for zone and format. An empty zone keeps the
+// system timezone; format is one of the Format names or a Go time layout.
+func New(zone, format string) (Formatter, error) {
+	var f Formatter
+	if zone != "" {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return f, fmt.Errorf("invalid timezone %q: %w", zone, err)
+		}
+		f.Location = loc
+	}
+
+	l, cl, err := layouts(format)
+	if err != nil {
+		return f, err
+	}
+	f.Layout, f.ClockLayout = l, cl
+	return f, nil
+}
+
+// CheckFormat reports whether format is a valid timestamp format
+func CheckFormat(format string) error {
+	_, _, err := layouts(format)
+	return err
+}
+
+// layouts returns the full and clock layouts for format
+func layouts(format string) (string, string, error) {
+	switch strings.ToLower(format) {
+	case "", FormatDefault:
+		return defaultLayout, defaultClockLayout, nil
+	case FormatRFC3339:
+		return time.RFC3339, "15:04Z07:00", nil
+	case FormatUS:
+		return "Jan 2, 2006 3:04:05 PM MST", "3:04 PM", nil
+	case FormatEU:
+		return "02.01.2006 15:04:05 MST", "15:04", nil
+	}
+	if parsed, err := time.Parse(format, roundTrip.Format(format)); err != nil || !parsed.Equal(roundTrip) {
+		return "", "", fmt.Errorf("invalid time format %q: it must show the date and the time to the minute; use %s, %s, %s, %s or a Go time layout",
+			format, FormatDefault, FormatRFC3339, FormatUS, FormatEU)
+	}
+	return format, defaultClockLayout, nil
+}
+
+// In returns t in the formatter's timezone
+func (f Formatter) In(t time.Time) time.Time {
+	if f.Location == nil {
+		return t.Local()
+	}
+	return t.In(f.Location)
+}
+
+// Format renders a full timestamp
+func (f Formatter) Format(t time.Time) string {
+	if f.Layout == "" {
+		return f.In(t).Format(defaultLayout)
+	}
+	return f.In(t).Format(f.Layout)
+}
+
+// Clock renders the time of day, e.g. "14:05" or "2:05 PM"
+func (f Formatter) Clock(t time.Time) string {
+	return f.In(t).Format(f.clockLayout())
+}
+
+// Weekday renders the day and time of day within the coming week, e.g. "Mon 14:05"
+func (f Formatter) Weekday(t time.Time) string {
+	return f.In(t).Format("Mon " + f.clockLayout())
+}
+
+func (f Formatter) clockLayout() string {
+	if f.ClockLayout == "" {
+		return defaultClockLayout
+	}
+	return f.ClockLayout
+}
+
+// NewLogger is log.New with no prefix, except that the date and time flags
+// stamp lines in the formatter's timezone instead of the system's
+func (f Formatter) NewLogger(w io.Writer, flags int) *log.Logger {
+	stamp := flags & (log.Ldate | log.Ltime | log.Lmicroseconds)
+	if f.Location == nil || stamp == 0 {
+		return log.New(w, "", flags)
+	}
+	var layout string
+	if stamp&log.Ldate != 0 {
+		layout = "2006/01/02 "
+	}
+	if stamp&log.Lmicroseconds != 0 {
+		layout += "15:04:05.000000 "
+	} else if stamp&log.Ltime != 0 {
+		layout += "15:04:05 "
+	}
+	return log.New(&stampWriter{w: w, loc: f.Location, layout: layout}, "", flags&^stamp)
+}
+
+// stampWriter prefixes each write, one log line, with the current time
+type stampWriter struct {
+	w      io.Writer
+	loc    *time.Location
+	layout string
+}
+
+func (s *stampWriter) Write(p []byte) (int, error) {
+	line := append([]byte(time.Now().In(s.loc).Format(s.layout)), p...)
+	if _, err := s.w.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
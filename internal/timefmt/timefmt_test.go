@@ -0,0 +1,76 @@
+package timefmt
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	local := time.Local
+	ts := time.Date(2025, 7, 1, 18, 30, 0, 0, time.UTC)
+	cases := []struct {
+		zone, format string
+		want         string
+		wantClock    string
+	}{
+		{"UTC", "", "2025-07-01 18:30:00", "18:30"},
+		{"Europe/Berlin", FormatEU, "01.07.2025 20:30:00 CEST", "20:30"},
+		{"America/New_York", FormatUS, "Jul 1, 2025 2:30:00 PM EDT", "2:30 PM"},
+		{"Asia/Tokyo", FormatRFC3339, "2025-07-02T03:30:00+09:00", "03:30+09:00"},
+		{"UTC", "Jan 2, 2006 15:04", "Jul 1, 2025 18:30", "18:30"},
+	}
+	for _, c := range cases {
+		t.Run(c.zone+" "+c.format, func(t *testing.T) {
+			f, err := New(c.zone, c.format)
+			if err != nil {
+				t.Skipf("New() error = %v (timezone data unavailable?)", err)
+			}
+			if got := f.Format(ts); got != c.want {
+				t.Errorf("Format() = %q, want %q", got, c.want)
+			}
+			if got := f.Clock(ts); got != c.wantClock {
+				t.Errorf("Clock() = %q, want %q", got, c.wantClock)
+			}
+		})
+	}
+	if time.Local != local {
+		t.Error("New() changed time.Local")
+	}
+
+	if _, err := New("Mars/Olympus", ""); err == nil {
+		t.Error("New() with an unknown timezone error = nil, want an error")
+	}
+	for _, format := range []string{"fancy", "Jan 2 15:04", "2006-01-02", "2006-01-02 3:04"} {
+		if _, err := New("", format); err == nil {
+			t.Errorf("New() with format %q error = nil, want an error", format)
+		}
+	}
+}
+
+func TestFormatter_Zero(t *testing.T) {
+	ts := time.Date(2025, 7, 1, 18, 30, 0, 0, time.UTC)
+	if got, want := (Formatter{}).Format(ts), ts.Local().Format("2006-01-02 15:04:05"); got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatter_NewLogger(t *testing.T) {
+	loc := time.FixedZone("UTC+14", 14*3600)
+	var b bytes.Buffer
+	Formatter{Location: loc}.NewLogger(&b, log.LstdFlags|log.Lmicroseconds).Printf("hello")
+
+	m := regexp.MustCompile(`^(\d{4}/\d\d/\d\d \d\d:\d\d:\d\d\.\d{6}) hello\n$`).FindStringSubmatch(b.String())
+	if m == nil {
+		t.Fatalf("log line = %q", b.String())
+	}
+	stamp, err := time.ParseInLocation("2006/01/02 15:04:05.000000", m[1], loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(stamp); d < 0 || d > time.Minute {
+		t.Errorf("log line stamped %s, want now in UTC+14", m[1])
+	}
+}
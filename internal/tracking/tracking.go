@@ -0,0 +1,94 @@
+// Package tracking wires experiment tracking (Weights & Biases and
+// TensorBoard) into the launched training run.
+package tracking
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultWandBProject is the W&B project used when none is configured
+const DefaultWandBProject = "gswarm"
+
+// Options selects which experiment trackers the trainer reports to
+type Options struct {
+	WandB        bool
+	WandBProject string
+	WandBEntity  string
+	// Group ties all restarts of one supervisor session together
+	Group string
+
+	TensorBoard    bool
+	TensorBoardDir string
+}
+
+// Enabled reports whether any tracker is enabled
+func (o Options) Enabled() bool {
+	return o.WandB || o.TensorBoard
+}
+
+func (o Options) project() string {
+	if o.WandBProject == "" {
+		return DefaultWandBProject
+	}
+	return o.WandBProject
+}
+
+// Env returns the environment variables the trackers need
+func (o Options) Env() []string {
+	if !o.WandB {
+		return nil
+	}
+	env := []string{"WANDB_MODE=online", "WANDB_DISABLED=false", "WANDB_PROJECT=" + o.project()}
+	if o.WandBEntity != "" {
+		env = append(env, "WANDB_ENTITY="+o.WandBEntity)
+	}
+	if o.Group != "" {
+		env = append(env, "WANDB_RUN_GROUP="+o.Group)
+	}
+	return env
+}
+
+// Args returns the trainer arguments selecting the report destinations
+func (o Options) Args() []string {
+	var reportTo []string
+	if o.WandB {
+		reportTo = append(reportTo, "wandb")
+	}
+	if o.TensorBoard {
+		reportTo = append(reportTo, "tensorboard")
+	}
+	if len(reportTo) == 0 {
+		return nil
+	}
+
+	args := []string{"--report_to", strings.Join(reportTo, ",")}
+	if o.TensorBoard && o.TensorBoardDir != "" {
+		args = append(args, "--logging_dir", o.TensorBoardDir)
+	}
+	return args
+}
+
+// Links returns human-readable pointers to where metrics can be found
+func (o Options) Links() []string {
+	var links []string
+	if o.WandB {
+		switch {
+		case o.WandBEntity != "" && o.Group != "":
+			links = append(links, fmt.Sprintf("W&B: https://wandb.ai/%s/%s/groups/%s", o.WandBEntity, o.project(), o.Group))
+		case o.WandBEntity != "":
+			links = append(links, fmt.Sprintf("W&B: https://wandb.ai/%s/%s", o.WandBEntity, o.project()))
+		default:
+			links = append(links, fmt.Sprintf("W&B: project %q (set --wandb-entity for a direct link)", o.project()))
+		}
+	}
+	if o.TensorBoard {
+		dir := o.TensorBoardDir
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		links = append(links, fmt.Sprintf("TensorBoard: tensorboard --logdir %s", dir))
+	}
+	return links
+}
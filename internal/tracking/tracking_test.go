@@ -0,0 +1,46 @@
+package tracking
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOptions_Args(t *testing.T) {
+	cases := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"disabled", Options{}, nil},
+		{"wandb only", Options{WandB: true}, []string{"--report_to", "wandb"}},
+		{"both", Options{WandB: true, TensorBoard: true, TensorBoardDir: "runs"}, []string{"--report_to", "wandb,tensorboard", "--logging_dir", "runs"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.opts.Args(); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Args() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestOptions_Env(t *testing.T) {
+	env := Options{WandB: true, WandBEntity: "me", Group: "gswarm-1"}.Env()
+	want := []string{"WANDB_MODE=online", "WANDB_DISABLED=false", "WANDB_PROJECT=gswarm", "WANDB_ENTITY=me", "WANDB_RUN_GROUP=gswarm-1"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Env() = %v, want %v", env, want)
+	}
+
+	if env := (Options{}).Env(); env != nil {
+		t.Errorf("Env() disabled = %v, want nil", env)
+	}
+}
+
+func TestOptions_Links(t *testing.T) {
+	links := Options{WandB: true, WandBEntity: "me", WandBProject: "swarm", Group: "g1"}.Links()
+	if len(links) != 1 || !strings.Contains(links[0], "https://wandb.ai/me/swarm/groups/g1") {
+		t.Errorf("Links() = %v, want group URL", links)
+	}
+}
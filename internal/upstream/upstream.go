@@ -0,0 +1,303 @@
+// Package upstream checks the rl-swarm checkout's remote for new commits
+// and releases, and points out the changes that tend to break a node: new
+// requirement pins, changed trainer configs and launcher scripts.
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StateFile records the last check inside the state directory
+const StateFile = "upstream.json"
+
+// DefaultInterval checks once a day
+const DefaultInterval = 24 * time.Hour
+
+// maxCommits bounds the commit subjects listed in a summary
+const maxCommits = 10
+
+// Change describes the commits the checkout is behind its remote
+type Change struct {
+	// From is the checkout's commit and To the remote's
+	From, To string
+	// FromName and ToName describe the commits after their nearest tag
+	FromName, ToName string
+	// Behind is the number of new commits; Commits lists the newest ones
+	Behind  int
+	Commits []string
+	// Diverged is set when the checkout has commits the remote doesn't
+	Diverged bool
+	// Modified is set when the checkout has local changes
+	Modified bool
+	// Requirements lists changed package pins, e.g. "torch ==2.5.1 → ==2.6.0"
+	Requirements []string
+	// Configs and Scripts list changed trainer configs and launcher files
+	Configs []string
+	Scripts []string
+}
+
+// Breaking reports whether the change touches requirements, configs or
+// launcher scripts
+func (c *Change) Breaking() bool {
+	return len(c.Requirements) > 0 || len(c.Configs) > 0 || len(c.Scripts) > 0
+}
+
+// Upgrade returns the command that updates the checkout in dir
+func (c *Change) Upgrade(dir string) string {
+	pull := fmt.Sprintf("git -C %s pull --ff-only", dir)
+	if c.Diverged {
+		pull = fmt.Sprintf("git -C %s pull --rebase", dir)
+	}
+	if c.Modified {
+		return fmt.Sprintf("git -C %s stash && %s && git -C %s stash pop", dir, pull, dir)
+	}
+	return pull
+}
+
+// Text summarizes the change for the checkout in dir
+func (c *Change) Text(dir string) string {
+	var b strings.Builder
+	commits := "commits"
+	if c.Behind == 1 {
+		commits = "commit"
+	}
+	fmt.Fprintf(&b, "rl-swarm has %d new %s upstream: %s → %s\n", c.Behind, commits, c.FromName, c.ToName)
+	for _, s := range c.Commits {
+		fmt.Fprintf(&b, "  %s\n", s)
+	}
+	if more := c.Behind - len(c.Commits); more > 0 {
+		fmt.Fprintf(&b, "  ... and %d more\n", more)
+	}
+	if c.Breaking() {
+		b.WriteString("\nMay need attention:\n")
+		for _, r := range c.Requirements {
+			fmt.Fprintf(&b, "  requirement %s\n", r)
+		}
+		for _, f := range c.Configs {
+			fmt.Fprintf(&b, "  config %s changed\n", f)
+		}
+		for _, f := range c.Scripts {
+			fmt.Fprintf(&b, "  script %s changed\n", f)
+		}
+	}
+	if c.Diverged {
+		b.WriteString("\nThe checkout has commits that aren't upstream.\n")
+	}
+	if c.Modified {
+		b.WriteString("\nThe checkout has local changes; they are stashed and reapplied.\n")
+	}
+	fmt.Fprintf(&b, "\nTo upgrade, run:\n  %s\nand restart gswarm; changed requirements are reinstalled on the next start.", c.Upgrade(dir))
+	return b.String()
+}
+
+// Check fetches the remote of the checkout in dir and compares it with the
+// checked-out commit. It returns nil when the checkout is up to date.
+// prepare, if not nil, adjusts each git command, e.g. to run it as the
+// checkout's owner, which git requires and which keeps fetched objects
+// theirs.
+func Check(ctx context.Context, dir string, prepare func(*exec.Cmd)) (*Change, error) {
+	r := repo{dir: dir, prepare: prepare}
+	if _, err := r.git(ctx, "fetch", "--quiet", "--tags", "origin"); err != nil {
+		return nil, fmt.Errorf("failed to fetch rl-swarm upstream: %w", err)
+	}
+	head, err := r.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	var remote string
+	for _, ref := range []string{"@{upstream}", "origin/HEAD", "origin/main"} {
+		if remote, err = r.git(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, errors.New("the rl-swarm checkout has no upstream branch")
+	}
+	if _, err := r.git(ctx, "merge-base", "--is-ancestor", remote, head); err == nil {
+		return nil, nil
+	}
+
+	c := &Change{From: head, To: remote}
+	c.FromName, _ = r.git(ctx, "describe", "--tags", "--always", head)
+	c.ToName, _ = r.git(ctx, "describe", "--tags", "--always", remote)
+	if out, err := r.git(ctx, "rev-list", "--count", head+".."+remote); err == nil {
+		fmt.Sscan(out, &c.Behind)
+	}
+	if out, err := r.git(ctx, "log", "--format=%h %s", "-n", fmt.Sprint(maxCommits), head+".."+remote); err == nil && out != "" {
+		c.Commits = strings.Split(out, "\n")
+	}
+	_, err = r.git(ctx, "merge-base", "--is-ancestor", head, remote)
+	c.Diverged = err != nil
+	if out, err := r.git(ctx, "status", "--porcelain", "--untracked-files=no"); err == nil {
+		c.Modified = out != ""
+	}
+
+	files, err := r.git(ctx, "diff", "--name-only", head, remote)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range strings.Split(files, "\n") {
+		switch kind(f) {
+		case "requirements":
+			before, _ := r.git(ctx, "show", head+":"+f)
+			after, _ := r.git(ctx, "show", remote+":"+f)
+			for _, d := range DiffRequirements(before, after) {
+				c.Requirements = append(c.Requirements, f+": "+d)
+			}
+		case "config":
+			c.Configs = append(c.Configs, f)
+		case "script":
+			c.Scripts = append(c.Scripts, f)
+		}
+	}
+	return c, nil
+}
+
+// kind classifies a changed file by how it can affect a node
+func kind(file string) string {
+	name := path.Base(file)
+	switch {
+	case strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt"):
+		return "requirements"
+	case name == "pyproject.toml" || name == "setup.py" || name == "package.json":
+		return "config"
+	case strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml"):
+		return "config"
+	case strings.HasSuffix(name, ".sh") || name == "Dockerfile" || strings.HasPrefix(name, "docker-compose"):
+		return "script"
+	}
+	return ""
+}
+
+// DiffRequirements compares two requirements files by package, returning
+// e.g. "+ trl ==0.19.0", "- accelerate" and "torch ==2.5.1 → ==2.6.0"
+func DiffRequirements(before, after string) []string {
+	old, cur := parseRequirements(before), parseRequirements(after)
+	names := map[string]bool{}
+	for n := range old {
+		names[n] = true
+	}
+	for n := range cur {
+		names[n] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+
+	var diff []string
+	for _, n := range sorted {
+		o, hadOld := old[n]
+		c, hasCur := cur[n]
+		switch {
+		case !hadOld:
+			diff = append(diff, strings.TrimSpace("+ "+n+" "+c))
+		case !hasCur:
+			diff = append(diff, "- "+n)
+		case o != c:
+			diff = append(diff, fmt.Sprintf("%s %s → %s", n, orAny(o), orAny(c)))
+		}
+	}
+	return diff
+}
+
+func orAny(spec string) string {
+	if spec == "" {
+		return "(any version)"
+	}
+	return spec
+}
+
+// parseRequirements maps each package in a requirements file to its
+// version specifier. Options such as -r and --index-url are skipped.
+func parseRequirements(text string) map[string]string {
+	pkgs := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+		end := strings.IndexFunc(line, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-')
+		})
+		if end < 0 {
+			end = len(line)
+		}
+		name := strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(line[:end]))
+		pkgs[name] = strings.Join(strings.Fields(line[end:]), " ")
+	}
+	return pkgs
+}
+
+// State is the last check, so restarts don't check or notify again early
+type State struct {
+	Checked time.Time `json:"checked"`
+	// Notified is the remote commit the operator was last told about
+	Notified string `json:"notified,omitempty"`
+}
+
+// LoadState reads the state from stateDir; a missing file is an empty state
+func LoadState(stateDir string) (State, error) {
+	var st State
+	data, err := os.ReadFile(filepath.Join(stateDir, StateFile))
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return st, fmt.Errorf("failed to parse %s: %w", StateFile, err)
+	}
+	return st, nil
+}
+
+// Save writes the state to stateDir atomically
+func (st State) Save(stateDir string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(stateDir, StateFile)
+	if err := os.WriteFile(file+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// repo runs git in a checkout
+type repo struct {
+	dir     string
+	prepare func(*exec.Cmd)
+}
+
+func (r repo) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", r.dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if r.prepare != nil {
+		r.prepare(cmd)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
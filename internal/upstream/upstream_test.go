@@ -0,0 +1,106 @@
+package upstream
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffRequirements(t *testing.T) {
+	before := "# pinned\ntorch==2.5.1\nTransformers>=4.46\naccelerate\n-r base.txt\nhivemind @ git+https://github.com/gensyn-ai/hivemind@639c964\n"
+	after := "torch==2.6.0\ntransformers>=4.46  # same\ntrl==0.19.0\nhivemind @ git+https://github.com/gensyn-ai/hivemind@1a2b3c4\n"
+	want := []string{
+		"- accelerate",
+		"hivemind @ git+https://github.com/gensyn-ai/hivemind@639c964 → @ git+https://github.com/gensyn-ai/hivemind@1a2b3c4",
+		"torch ==2.5.1 → ==2.6.0",
+		"+ trl ==0.19.0",
+	}
+	if got := DiffRequirements(before, after); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DiffRequirements() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	origin, checkout := filepath.Join(root, "origin"), filepath.Join(root, "rl-swarm")
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	write := func(dir, name, text string) {
+		t.Helper()
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	os.MkdirAll(origin, 0o755)
+	git(origin, "init", "-q", "-b", "main")
+	write(origin, "requirements-gpu.txt", "torch==2.5.1\n")
+	write(origin, "rgym_exp/config/rg-swarm.yaml", "max_round: 1000\n")
+	write(origin, "README.md", "rl-swarm\n")
+	git(origin, "add", ".")
+	git(origin, "commit", "-q", "-m", "initial")
+	git(origin, "tag", "v0.5.3")
+	git(root, "clone", "-q", origin, checkout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if c, err := Check(ctx, checkout, nil); err != nil || c != nil {
+		t.Fatalf("up to date: Check() = %+v, %v", c, err)
+	}
+
+	write(origin, "README.md", "rl-swarm, now with docs\n")
+	git(origin, "commit", "-q", "-am", "docs")
+	write(origin, "requirements-gpu.txt", "torch==2.6.0\ntrl==0.19.0\n")
+	write(origin, "rgym_exp/config/rg-swarm.yaml", "max_round: 2000\n")
+	git(origin, "commit", "-q", "-am", "bump torch")
+	git(origin, "tag", "v0.5.4")
+	write(checkout, "README.md", "local edit\n")
+
+	c, err := Check(ctx, checkout, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil || c.Behind != 2 || len(c.Commits) != 2 || !strings.HasSuffix(c.Commits[0], " bump torch") {
+		t.Fatalf("Check() = %+v", c)
+	}
+	if c.FromName != "v0.5.3" || c.ToName != "v0.5.4" || c.Diverged || !c.Modified {
+		t.Errorf("Check() = %+v", c)
+	}
+	wantReqs := []string{"requirements-gpu.txt: torch ==2.5.1 → ==2.6.0", "requirements-gpu.txt: + trl ==0.19.0"}
+	if strings.Join(c.Requirements, "|") != strings.Join(wantReqs, "|") || len(c.Configs) != 1 || len(c.Scripts) != 0 {
+		t.Errorf("breaking changes = %q %q %q", c.Requirements, c.Configs, c.Scripts)
+	}
+	text := c.Text("rl-swarm")
+	for _, want := range []string{"2 new commits upstream: v0.5.3 → v0.5.4", "config rgym_exp/config/rg-swarm.yaml changed", "git -C rl-swarm stash && git -C rl-swarm pull --ff-only && git -C rl-swarm stash pop"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	if st, err := LoadState(dir); err != nil || !st.Checked.IsZero() {
+		t.Fatalf("LoadState() of a new state dir = %+v, %v", st, err)
+	}
+	want := State{Checked: time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC), Notified: "abc"}
+	if err := want.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LoadState(dir); err != nil || !got.Checked.Equal(want.Checked) || got.Notified != want.Notified {
+		t.Errorf("LoadState() = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package versions identifies the code a node runs: gswarm's own build and
+// the rl-swarm checkout it trains with, so reports say exactly what was
+// running when something broke.
+package versions
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Info is gswarm's version and the rl-swarm checkout's commit
+type Info struct {
+	Gswarm       string `json:"gswarm"`
+	GswarmCommit string `json:"gswarm_commit,omitempty"`
+	// RLSwarm is the checkout's commit; RLSwarmDescribe names it after the
+	// nearest tag, e.g. v0.5.3-2-g1a2b3c4, when git is installed
+	RLSwarm         string `json:"rl_swarm,omitempty"`
+	RLSwarmDescribe string `json:"rl_swarm_describe,omitempty"`
+	// RLSwarmModified is set when the checkout has local changes
+	RLSwarmModified bool `json:"rl_swarm_modified,omitempty"`
+}
+
+// gitTimeout bounds each git command, as a hung filesystem shouldn't hold
+// up startup
+const gitTimeout = 5 * time.Second
+
+// Collect describes gswarm's build and the checkout in dir. A missing
+// checkout leaves the rl-swarm fields empty.
+func Collect(version, commit, dir string) Info {
+	info := Info{Gswarm: version}
+	if commit != "" && commit != "unknown" {
+		info.GswarmCommit = commit
+	}
+	if out, err := git(dir, "rev-parse", "HEAD"); err == nil {
+		info.RLSwarm = out
+		if out, err := git(dir, "describe", "--tags", "--always", "--dirty"); err == nil {
+			info.RLSwarmDescribe = strings.TrimSuffix(out, "-dirty")
+			info.RLSwarmModified = strings.HasSuffix(out, "-dirty")
+		}
+		return info
+	}
+	// Without git, the commit can still be read from .git
+	info.RLSwarm = readHead(filepath.Join(dir, ".git"))
+	return info
+}
+
+// String renders the versions on one line, e.g.
+// "gswarm 1.0.0 (3f2e1d0), rl-swarm v0.5.3-2-g1a2b3c4 (modified)"
+func (i Info) String() string {
+	var b strings.Builder
+	b.WriteString("gswarm " + i.Gswarm)
+	if i.GswarmCommit != "" {
+		b.WriteString(" (" + short(i.GswarmCommit) + ")")
+	}
+	switch {
+	case i.RLSwarmDescribe != "":
+		b.WriteString(", rl-swarm " + i.RLSwarmDescribe)
+	case i.RLSwarm != "":
+		b.WriteString(", rl-swarm " + short(i.RLSwarm))
+	}
+	if i.RLSwarmModified {
+		b.WriteString(" (modified)")
+	}
+	return b.String()
+}
+
+func short(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+func git(dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// readHead resolves HEAD in gitDir, following a branch ref to a loose or
+// packed ref; it returns "" when it can't
+func readHead(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(data))
+	ref, ok := strings.CutPrefix(head, "ref: ")
+	if !ok {
+		return head
+	}
+	if data, err := os.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	f, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if hash, name, ok := strings.Cut(scanner.Text(), " "); ok && name == ref {
+			return hash
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package versions
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollect_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	os.WriteFile(filepath.Join(dir, "run.sh"), []byte("echo\n"), 0o644)
+	run("add", ".")
+	run("commit", "-q", "-m", "init")
+	run("tag", "v0.5.3")
+
+	info := Collect("1.0.0", "3f2e1d0c9b8a", dir)
+	if len(info.RLSwarm) != 40 || info.RLSwarmDescribe != "v0.5.3" || info.RLSwarmModified {
+		t.Errorf("Collect() = %+v", info)
+	}
+	if got := info.String(); got != "gswarm 1.0.0 (3f2e1d0), rl-swarm v0.5.3" {
+		t.Errorf("String() = %q", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, "run.sh"), []byte("echo changed\n"), 0o644)
+	if info := Collect("1.0.0", "", dir); !info.RLSwarmModified || !strings.HasSuffix(info.String(), "v0.5.3 (modified)") {
+		t.Errorf("modified checkout: %+v, %q", info, info.String())
+	}
+}
+
+func TestCollect_NoCheckout(t *testing.T) {
+	info := Collect("1.0.0", "unknown", filepath.Join(t.TempDir(), "missing"))
+	if info.RLSwarm != "" || info.GswarmCommit != "" {
+		t.Errorf("Collect() = %+v, want only the gswarm version", info)
+	}
+	if got := info.String(); got != "gswarm 1.0.0" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	gitDir := t.TempDir()
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644)
+	os.WriteFile(filepath.Join(gitDir, "packed-refs"), []byte("# pack-refs with: peeled\n"+commit+" refs/heads/main\n"), 0o644)
+	if got := readHead(gitDir); got != commit {
+		t.Errorf("packed ref: readHead() = %q", got)
+	}
+
+	os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0o755)
+	os.WriteFile(filepath.Join(gitDir, "refs", "heads", "main"), []byte("fedcba\n"), 0o644)
+	if got := readHead(gitDir); got != "fedcba" {
+		t.Errorf("loose ref: readHead() = %q", got)
+	}
+
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte(commit+"\n"), 0o644)
+	if got := readHead(gitDir); got != commit {
+		t.Errorf("detached: readHead() = %q", got)
+	}
+}
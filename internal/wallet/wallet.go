@@ -0,0 +1,313 @@
+// Package wallet watches the monitored EOA for on-chain activity between
+// checks. The EOA normally only registers peers, so a transaction it sends,
+// or value or tokens it receives, is worth a look: the key may have been
+// compromised, or someone with access made an unexpected change.
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Deep-Commit/gswarm/internal/humanize"
+	"github.com/Deep-Commit/gswarm/internal/rpc"
+)
+
+// StateFile is where the watcher persists what it last saw, relative to
+// the state directory
+const StateFile = "wallet_activity.json"
+
+// maxLogRange bounds the blocks one eth_getLogs request covers, as RPC
+// providers reject wide ranges. Transfers older than that are skipped
+// after a long downtime.
+const maxLogRange = 10000
+
+// transferTopic is the ERC-20 Transfer(address,address,uint256) event
+const transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// Ether formats native balances, which are in wei
+var Ether = humanize.Format{Decimals: 18, Precision: 6, Unit: "ETH"}
+
+// Transfer is an ERC-20 token transfer to or from the EOA
+type Transfer struct {
+	Token    string
+	From     string
+	To       string
+	Value    *big.Int
+	TxHash   string
+	Block    uint64
+	Incoming bool
+}
+
+// Activity is what the EOA did between two checks
+type Activity struct {
+	Address string
+	// Sent is the number of transactions the EOA sent
+	Sent uint64
+	// PrevBalance and Balance are the native balance before and after
+	PrevBalance *big.Int
+	Balance     *big.Int
+	Transfers   []Transfer
+	// FromBlock and ToBlock are the blocks that were checked
+	FromBlock, ToBlock uint64
+	// Skipped is set when blocks were left out to stay within maxLogRange
+	Skipped bool
+}
+
+// Received reports whether the native balance went up
+func (a *Activity) Received() bool {
+	return a.Balance.Cmp(a.PrevBalance) > 0
+}
+
+// Text describes the activity in plain text
+func (a *Activity) Text() string {
+	var b strings.Builder
+	var what []string
+	if a.Sent > 0 {
+		what = append(what, fmt.Sprintf("sent %d %s", a.Sent, plural(a.Sent, "transaction", "transactions")))
+	}
+	if a.Received() {
+		what = append(what, "received "+Ether.Int(new(big.Int).Sub(a.Balance, a.PrevBalance)))
+	}
+	if n := uint64(len(a.Transfers)); n > 0 {
+		what = append(what, fmt.Sprintf("moved tokens in %d %s", n, plural(n, "transfer", "transfers")))
+	}
+	fmt.Fprintf(&b, "The EOA %s %s between blocks %d and %d.", a.Address, strings.Join(what, ", "), a.FromBlock, a.ToBlock)
+	if a.Balance.Cmp(a.PrevBalance) != 0 {
+		fmt.Fprintf(&b, "\nBalance: %s → %s", Ether.Int(a.PrevBalance), Ether.Int(a.Balance))
+	}
+	for _, t := range a.Transfers {
+		if t.Incoming {
+			fmt.Fprintf(&b, "\nReceived %s of token %s from %s (tx %s)", t.Value, t.Token, t.From, t.TxHash)
+		} else {
+			fmt.Fprintf(&b, "\nSent %s of token %s to %s (tx %s)", t.Value, t.Token, t.To, t.TxHash)
+		}
+	}
+	if a.Skipped {
+		fmt.Fprintf(&b, "\nToken transfers before block %d weren't checked.", a.FromBlock)
+	}
+	b.WriteString("\nIf you didn't make these transactions, move your funds and register new peers from a fresh wallet.")
+	return b.String()
+}
+
+// state is what the watcher remembers between checks
+type state struct {
+	Address string    `json:"address"`
+	Nonce   uint64    `json:"nonce"`
+	Balance *big.Int  `json:"balance"`
+	Block   uint64    `json:"block"`
+	Checked time.Time `json:"checked"`
+}
+
+// Watcher compares the EOA's transaction count, balance and token
+// transfers with the previous check
+type Watcher struct {
+	Client   *rpc.Client
+	Endpoint string
+	Address  string
+
+	state state
+}
+
+// Load creates a watcher for address, continuing from the state saved at
+// path. State saved for another address is discarded.
+func Load(path, address string) (*Watcher, error) {
+	w := &Watcher{Client: rpc.NewClient(), Endpoint: rpc.GensynTestnetURL, Address: address}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return w, nil
+	}
+	if err != nil {
+		return w, fmt.Errorf("failed to read wallet state: %w", err)
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return w, fmt.Errorf("failed to parse wallet state: %w", err)
+	}
+	if strings.EqualFold(s.Address, address) && s.Balance != nil {
+		w.state = s
+	}
+	return w, nil
+}
+
+// Save writes the watcher's state to path
+func (w *Watcher) Save(path string) error {
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write wallet state: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Check compares the EOA with the previous check and returns its activity
+// since, or nil when there was none. The first check only records a
+// baseline. A failure to read token transfers is returned along with the
+// activity the transaction count and balance show, and doesn't hold back
+// later checks.
+func (w *Watcher) Check(ctx context.Context) (*Activity, error) {
+	block, err := w.quantity(ctx, "eth_blockNumber")
+	if err != nil {
+		return nil, err
+	}
+	at := "0x" + strconv.FormatUint(block, 16)
+	nonce, err := w.quantity(ctx, "eth_getTransactionCount", w.Address, at)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := w.balance(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := w.state
+	if prev.Block == 0 || block <= prev.Block {
+		if prev.Block == 0 {
+			w.state = state{Address: w.Address, Nonce: nonce, Balance: balance, Block: block, Checked: time.Now()}
+		}
+		return nil, nil
+	}
+	w.state = state{Address: w.Address, Nonce: nonce, Balance: balance, Block: block, Checked: time.Now()}
+
+	a := &Activity{Address: w.Address, PrevBalance: prev.Balance, Balance: balance, FromBlock: prev.Block + 1, ToBlock: block}
+	if nonce > prev.Nonce {
+		a.Sent = nonce - prev.Nonce
+	}
+	if block-a.FromBlock >= maxLogRange {
+		a.FromBlock, a.Skipped = block-maxLogRange+1, true
+	}
+	a.Transfers, err = w.transfers(ctx, a.FromBlock, a.ToBlock)
+	if err != nil {
+		err = fmt.Errorf("token transfers not checked: %w", err)
+	}
+	if a.Sent == 0 && balance.Cmp(prev.Balance) == 0 && len(a.Transfers) == 0 {
+		return nil, err
+	}
+	return a, err
+}
+
+// transfers returns the token transfers to and from the EOA in the blocks
+func (w *Watcher) transfers(ctx context.Context, from, to uint64) ([]Transfer, error) {
+	topic := "0x" + strings.Repeat("0", 24) + strings.ToLower(strings.TrimPrefix(w.Address, "0x"))
+	var all []Transfer
+	for _, topics := range [][]interface{}{
+		{transferTopic, topic},
+		{transferTopic, nil, topic},
+	} {
+		filter := map[string]interface{}{
+			"fromBlock": "0x" + strconv.FormatUint(from, 16),
+			"toBlock":   "0x" + strconv.FormatUint(to, 16),
+			"topics":    topics,
+		}
+		result, err := w.Client.Call(ctx, w.Endpoint, "eth_getLogs", []interface{}{filter})
+		if err != nil {
+			return nil, fmt.Errorf("eth_getLogs failed: %w", err)
+		}
+		logs, err := parseTransfers(result, topic)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, logs...)
+	}
+	return all, nil
+}
+
+// parseTransfers decodes an eth_getLogs result of Transfer events;
+// addressTopic is the EOA as a topic, to tell incoming from outgoing
+func parseTransfers(result interface{}, addressTopic string) ([]Transfer, error) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var logs []struct {
+		Address         string   `json:"address"`
+		Topics          []string `json:"topics"`
+		Data            string   `json:"data"`
+		BlockNumber     string   `json:"blockNumber"`
+		TransactionHash string   `json:"transactionHash"`
+	}
+	if err := json.Unmarshal(raw, &logs); err != nil {
+		return nil, fmt.Errorf("unexpected eth_getLogs result: %w", err)
+	}
+	var transfers []Transfer
+	for _, l := range logs {
+		// ERC-721 transfers index the token ID as a fourth topic
+		if len(l.Topics) != 3 {
+			continue
+		}
+		value, ok := new(big.Int).SetString(strings.TrimPrefix(l.Data, "0x"), 16)
+		if !ok {
+			value = new(big.Int)
+		}
+		block, _ := strconv.ParseUint(strings.TrimPrefix(l.BlockNumber, "0x"), 16, 64)
+		transfers = append(transfers, Transfer{
+			Token:    l.Address,
+			From:     topicAddress(l.Topics[1]),
+			To:       topicAddress(l.Topics[2]),
+			Value:    value,
+			TxHash:   l.TransactionHash,
+			Block:    block,
+			Incoming: strings.EqualFold(l.Topics[2], addressTopic),
+		})
+	}
+	return transfers, nil
+}
+
+func (w *Watcher) quantity(ctx context.Context, method string, params ...interface{}) (uint64, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	result, err := w.Client.Call(ctx, w.Endpoint, method, params)
+	if err != nil {
+		return 0, fmt.Errorf("%s failed: %w", method, err)
+	}
+	s, ok := result.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected %s result %v", method, result)
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s result %q", method, s)
+	}
+	return n, nil
+}
+
+func (w *Watcher) balance(ctx context.Context, at string) (*big.Int, error) {
+	result, err := w.Client.Call(ctx, w.Endpoint, "eth_getBalance", []interface{}{w.Address, at})
+	if err != nil {
+		return nil, fmt.Errorf("eth_getBalance failed: %w", err)
+	}
+	s, ok := result.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected eth_getBalance result %v", result)
+	}
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid eth_getBalance result %q", s)
+	}
+	return n, nil
+}
+
+// topicAddress extracts the address from an indexed address topic
+func topicAddress(topic string) string {
+	t := strings.TrimPrefix(topic, "0x")
+	if len(t) < 40 {
+		return topic
+	}
+	return "0x" + t[len(t)-40:]
+}
+
+func plural(n uint64, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
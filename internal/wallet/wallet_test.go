@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Deep-Commit/gswarm/internal/rpc"
+)
+
+const eoa = "0x6947c6E196a48B77eFa9331EC1E3e45f3Ee5Fd58"
+
+// chainStub serves the calls Check makes from the fields' current values
+type chainStub struct {
+	block, nonce, balance uint64
+	logs                  []map[string]interface{}
+	logsErr               bool
+	ranges                []string
+}
+
+func (c *chainStub) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpc.Request
+		json.NewDecoder(r.Body).Decode(&req)
+		var result interface{}
+		switch req.Method {
+		case "eth_blockNumber":
+			result = fmt.Sprintf("0x%x", c.block)
+		case "eth_getTransactionCount":
+			result = fmt.Sprintf("0x%x", c.nonce)
+		case "eth_getBalance":
+			result = fmt.Sprintf("0x%x", c.balance)
+		case "eth_getLogs":
+			if c.logsErr {
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32600,"message":"method not allowed"}}`, req.ID)
+				return
+			}
+			filter := req.Params[0].(map[string]interface{})
+			c.ranges = append(c.ranges, fmt.Sprintf("%s-%s", filter["fromBlock"], filter["toBlock"]))
+			var matching []map[string]interface{}
+			topics := filter["topics"].([]interface{})
+			for _, l := range c.logs {
+				lt := l["topics"].([]string)
+				if (len(topics) == 2 && strings.EqualFold(lt[1], topics[1].(string))) ||
+					(len(topics) == 3 && strings.EqualFold(lt[2], topics[2].(string))) {
+					matching = append(matching, l)
+				}
+			}
+			result = matching
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		out, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		w.Write(out)
+	}))
+}
+
+func addressTopic(addr string) string {
+	return "0x" + strings.Repeat("0", 24) + strings.ToLower(strings.TrimPrefix(addr, "0x"))
+}
+
+func TestWatcher_Check(t *testing.T) {
+	stub := &chainStub{block: 100, nonce: 3, balance: 1000}
+	srv := stub.serve(t)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), StateFile)
+	w, err := Load(path, eoa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Client, w.Endpoint = rpc.NewClient(), srv.URL
+
+	// The first check records a baseline
+	if a, err := w.Check(context.Background()); a != nil || err != nil {
+		t.Fatalf("first Check() = %+v, %v, want a baseline", a, err)
+	}
+	// New blocks without activity
+	stub.block = 110
+	if a, err := w.Check(context.Background()); a != nil || err != nil {
+		t.Fatalf("quiet Check() = %+v, %v, want no activity", a, err)
+	}
+	if err := w.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted watcher sees a sent transaction and an incoming token
+	stub.block, stub.nonce, stub.balance = 120, 4, 900
+	stub.logs = []map[string]interface{}{{
+		"address":         "0xtoken",
+		"topics":          []string{transferTopic, addressTopic("0x1111111111111111111111111111111111111111"), addressTopic(eoa)},
+		"data":            "0x64",
+		"blockNumber":     "0x75",
+		"transactionHash": "0xfeed",
+	}}
+	w, err = Load(path, eoa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Client, w.Endpoint = rpc.NewClient(), srv.URL
+	a, err := w.Check(context.Background())
+	if err != nil || a == nil {
+		t.Fatalf("Check() = %+v, %v, want activity", a, err)
+	}
+	if a.Sent != 1 || a.Received() || a.FromBlock != 111 || a.ToBlock != 120 {
+		t.Errorf("Check() = %+v, want 1 sent in blocks 111-120", a)
+	}
+	if len(a.Transfers) != 1 || !a.Transfers[0].Incoming || a.Transfers[0].Value.Int64() != 100 ||
+		a.Transfers[0].From != "0x1111111111111111111111111111111111111111" {
+		t.Errorf("Transfers = %+v, want one incoming transfer of 100", a.Transfers)
+	}
+	text := a.Text()
+	for _, want := range []string{"sent 1 transaction", "moved tokens in 1 transfer", "Received 100 of token 0xtoken", "blocks 111 and 120"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() = %q, want %q", text, want)
+		}
+	}
+}
+
+func TestWatcher_CheckWithoutLogs(t *testing.T) {
+	stub := &chainStub{block: 100, balance: 1e15, logsErr: true}
+	srv := stub.serve(t)
+	defer srv.Close()
+	w := &Watcher{Client: rpc.NewClient(), Endpoint: srv.URL, Address: eoa}
+	w.Check(context.Background())
+
+	stub.block, stub.balance = 100+maxLogRange+50, 2.5e15
+	a, err := w.Check(context.Background())
+	if err == nil || a == nil {
+		t.Fatalf("Check() = %+v, %v, want the balance change and a getLogs error", a, err)
+	}
+	if !a.Received() || !a.Skipped || a.FromBlock != 151 {
+		t.Errorf("Check() = %+v, want a received balance with skipped blocks", a)
+	}
+	if !strings.Contains(a.Text(), "received 0.0015 ETH") {
+		t.Errorf("Text() = %q", a.Text())
+	}
+}
+
+func TestLoad_OtherAddress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	w := &Watcher{Address: "0xother", state: state{Address: "0xother", Block: 5, Balance: big.NewInt(1)}}
+	if err := w.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path, eoa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.state.Block != 0 {
+		t.Errorf("Load() kept state for another address: %+v", loaded.state)
+	}
+}
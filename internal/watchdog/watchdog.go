@@ -0,0 +1,144 @@
+// Package watchdog detects training processes that are still alive but no
+// longer making progress, based on output and GPU activity.
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandRunner is a package-level variable that can be replaced in tests
+var CommandRunner = exec.Command
+
+const (
+	// DefaultCheckInterval is how often the watchdog evaluates activity
+	DefaultCheckInterval = 30 * time.Second
+
+	// gpuBusyThreshold is the utilization percentage that counts as activity
+	gpuBusyThreshold = 5
+)
+
+// ErrHung is wrapped by the error of a run stopped for making no progress
+var ErrHung = errors.New("training process hung")
+
+// Watchdog tracks the last time a process showed signs of life
+type Watchdog struct {
+	Timeout       time.Duration
+	CheckInterval time.Duration
+	// GPUBusy reports whether the GPU is doing work; nil disables GPU checks
+	GPUBusy func() bool
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// New creates a watchdog that fires after timeout without activity
+func New(timeout time.Duration) *Watchdog {
+	return &Watchdog{
+		Timeout:       timeout,
+		CheckInterval: DefaultCheckInterval,
+		GPUBusy:       GPUBusy,
+		last:          time.Now(),
+	}
+}
+
+// Touch records activity now
+func (w *Watchdog) Touch() {
+	w.mu.Lock()
+	w.last = time.Now()
+	w.mu.Unlock()
+}
+
+// Idle returns how long it has been since the last recorded activity
+func (w *Watchdog) Idle() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return time.Since(w.last)
+}
+
+// Writer wraps dst so that every write counts as activity
+func (w *Watchdog) Writer(dst io.Writer) io.Writer {
+	return &activityWriter{dst: dst, wd: w}
+}
+
+type activityWriter struct {
+	dst io.Writer
+	wd  *Watchdog
+}
+
+func (a *activityWriter) Write(p []byte) (int, error) {
+	a.wd.Touch()
+	return a.dst.Write(p)
+}
+
+// Watch blocks until ctx is done or the process is considered hung, in
+// which case onHang is called with the idle duration
+func (w *Watchdog) Watch(ctx context.Context, onHang func(idle time.Duration)) {
+	interval := w.CheckInterval
+	if interval <= 0 || interval > w.Timeout {
+		interval = w.Timeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			idle := w.Idle()
+			if idle < w.Timeout {
+				continue
+			}
+			// Silent but computing (e.g. a long generation step) is not a hang
+			if w.GPUBusy != nil && w.GPUBusy() {
+				w.Touch()
+				continue
+			}
+			onHang(idle)
+			return
+		}
+	}
+}
+
+// GPUUtilization returns the highest utilization percentage across GPUs
+func GPUUtilization() (int, error) {
+	output, err := CommandRunner("nvidia-smi", "--query-gpu=utilization.gpu", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return 0, fmt.Errorf("nvidia-smi not available: %w", err)
+	}
+
+	highest := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		util, err := strconv.Atoi(strings.TrimSpace(line))
+		if err != nil {
+			return 0, fmt.Errorf("unable to parse GPU utilization: %q", line)
+		}
+		if util > highest {
+			highest = util
+		}
+	}
+	return highest, nil
+}
+
+// GPUBusy reports whether any GPU is above the activity threshold
+func GPUBusy() bool {
+	util, err := GPUUtilization()
+	return err == nil && util >= gpuBusyThreshold
+}
+
+// DumpStacks captures the Python thread stacks of pid using py-spy, if installed
+func DumpStacks(pid int) (string, error) {
+	output, err := CommandRunner("py-spy", "dump", "--pid", strconv.Itoa(pid)).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("py-spy dump failed: %w", err)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,113 @@
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestWatch_FiresWhenIdle(t *testing.T) {
+	wd := New(20 * time.Millisecond)
+	wd.CheckInterval = 5 * time.Millisecond
+	wd.GPUBusy = nil
+
+	fired := make(chan time.Duration, 1)
+	go wd.Watch(context.Background(), func(idle time.Duration) { fired <- idle })
+
+	select {
+	case idle := <-fired:
+		if idle < wd.Timeout {
+			t.Errorf("onHang idle = %v, want >= %v", idle, wd.Timeout)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watchdog did not fire")
+	}
+}
+
+func TestWatch_OutputKeepsAlive(t *testing.T) {
+	wd := New(40 * time.Millisecond)
+	wd.CheckInterval = 5 * time.Millisecond
+	wd.GPUBusy = nil
+
+	var buf bytes.Buffer
+	out := wd.Writer(&buf)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				out.Write([]byte("step\n"))
+			}
+		}
+	}()
+
+	fired := false
+	wd.Watch(ctx, func(time.Duration) { fired = true })
+	close(stop)
+	<-done
+
+	if fired {
+		t.Error("watchdog fired while output was being produced")
+	}
+	if buf.Len() == 0 {
+		t.Error("writer did not pass output through")
+	}
+}
+
+func TestWatch_GPUBusyKeepsAlive(t *testing.T) {
+	wd := New(10 * time.Millisecond)
+	wd.CheckInterval = 5 * time.Millisecond
+	wd.GPUBusy = func() bool { return true }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+
+	fired := false
+	wd.Watch(ctx, func(time.Duration) { fired = true })
+	if fired {
+		t.Error("watchdog fired while GPU was busy")
+	}
+}
+
+func TestGPUUtilization(t *testing.T) {
+	cases := []struct {
+		name    string
+		output  string
+		want    int
+		wantErr bool
+	}{
+		{"single gpu", "87", 87, false},
+		{"multiple gpus", "3\n42\n0", 42, false},
+		{"garbage", "N/A", 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			origCommandRunner := CommandRunner
+			defer func() { CommandRunner = origCommandRunner }()
+			CommandRunner = func(_ string, _ ...string) *exec.Cmd {
+				return exec.Command("printf", c.output)
+			}
+
+			got, err := GPUUtilization()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("GPUUtilization() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("GPUUtilization() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package wheelcache keeps Python wheels that are slow to build, such as
+// flash-attn, so a rebuilt virtual environment or another instance on the
+// same machine can install them in seconds. Wheels are keyed by the Python,
+// torch and CUDA versions they were built against, since a wheel built for
+// one torch build crashes on import with another.
+package wheelcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Dir is the default cache directory, relative to the state directory
+const Dir = "wheels"
+
+// CommandRunner runs the Python probe; replaced in tests
+var CommandRunner = exec.Command
+
+// Env is what a compiled wheel depends on
+type Env struct {
+	Python   string `json:"python"`
+	Torch    string `json:"torch"`
+	CUDA     string `json:"cuda"`
+	Platform string `json:"platform"`
+	// CXX11ABI is torch's C++ ABI, which extensions must match
+	CXX11ABI bool `json:"cxx11abi"`
+}
+
+// probe prints the Env of the interpreter it runs in
+const probe = `import json, platform, sys, torch
+print(json.dumps({
+    "python": "%d.%d" % sys.version_info[:2],
+    "torch": torch.__version__,
+    "cuda": torch.version.cuda or "cpu",
+    "platform": sys.platform + "-" + platform.machine(),
+    "cxx11abi": bool(torch._C._GLIBCXX_USE_CXX11_ABI),
+}))`
+
+// DetectEnv asks python, usually a virtual environment's interpreter with
+// torch installed, for the versions a wheel depends on. prepare, if not
+// nil, adjusts the command before it runs, e.g. to run it as another user.
+func DetectEnv(python string, prepare func(*exec.Cmd)) (Env, error) {
+	cmd := CommandRunner(python, "-c", probe)
+	if prepare != nil {
+		prepare(cmd)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return Env{}, fmt.Errorf("failed to query the torch and CUDA versions: %w", err)
+	}
+	var env Env
+	if err := json.Unmarshal(out, &env); err != nil {
+		return Env{}, fmt.Errorf("failed to parse the torch and CUDA versions: %w", err)
+	}
+	if env.Python == "" || env.Torch == "" {
+		return Env{}, fmt.Errorf("incomplete version information: %s", strings.TrimSpace(string(out)))
+	}
+	return env, nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._+-]`)
+
+// Key names the cache directory for wheels built in env
+func (e Env) Key() string {
+	abi := "abi0"
+	if e.CXX11ABI {
+		abi = "abi1"
+	}
+	key := fmt.Sprintf("py%s-torch%s-cuda%s-%s-%s", e.Python, e.Torch, e.CUDA, e.Platform, abi)
+	return unsafeChars.ReplaceAllString(key, "_")
+}
+
+// Cache is a directory of wheels grouped by Env.Key
+type Cache struct {
+	Dir string
+}
+
+// Path is the directory holding the wheels built in env
+func (c Cache) Path(env Env) string {
+	return filepath.Join(c.Dir, env.Key())
+}
+
+// Lookup returns the cached wheel of pkg built in env
+func (c Cache) Lookup(pkg string, env Env) (string, bool) {
+	matches := c.wheels(pkg, env)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+func (c Cache) wheels(pkg string, env Env) []string {
+	matches, _ := filepath.Glob(filepath.Join(c.Path(env), wheelName(pkg)+"-*.whl"))
+	return matches
+}
+
+// Store moves a built wheel of pkg into the cache, replacing any other
+// version, and returns its new path
+func (c Cache) Store(pkg string, env Env, wheel string) (string, error) {
+	dir := c.Path(env)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(dir, filepath.Base(wheel))
+	for _, old := range c.wheels(pkg, env) {
+		if old != dst {
+			os.Remove(old)
+		}
+	}
+	if err := os.Rename(wheel, dst); err == nil {
+		return dst, nil
+	}
+	// Across filesystems: copy to a temporary name so a partial wheel is
+	// never picked up
+	tmp := dst + ".tmp"
+	if err := copyFile(wheel, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return "", err
+	}
+	os.Remove(wheel)
+	return dst, nil
+}
+
+// Remove drops a wheel that failed to install
+func (c Cache) Remove(wheel string) error {
+	return os.Remove(wheel)
+}
+
+// wheelName is pkg as it appears in wheel file names
+func wheelName(pkg string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(pkg)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,77 @@
+package wheelcache
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectEnv(t *testing.T) {
+	orig := CommandRunner
+	defer func() { CommandRunner = orig }()
+	CommandRunner = func(string, ...string) *exec.Cmd {
+		return exec.Command("echo", `{"python": "3.10", "torch": "2.5.1+cu121", "cuda": "12.1", "platform": "linux-x86_64", "cxx11abi": false}`)
+	}
+
+	env, err := DetectEnv("python", nil)
+	if err != nil {
+		t.Fatalf("DetectEnv() error = %v", err)
+	}
+	if want := "py3.10-torch2.5.1+cu121-cuda12.1-linux-x86_64-abi0"; env.Key() != want {
+		t.Errorf("Key() = %q, want %q", env.Key(), want)
+	}
+
+	CommandRunner = func(string, ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo ModuleNotFoundError: torch >&2; exit 1")
+	}
+	if _, err := DetectEnv("python", nil); err == nil {
+		t.Error("DetectEnv() expected error without torch")
+	}
+}
+
+func TestCache_StoreLookup(t *testing.T) {
+	cache := Cache{Dir: filepath.Join(t.TempDir(), Dir)}
+	env := Env{Python: "3.11", Torch: "2.4.0", CUDA: "12.4", Platform: "linux-x86_64", CXX11ABI: true}
+	other := env
+	other.Torch = "2.5.1"
+
+	if _, ok := cache.Lookup("flash-attn", env); ok {
+		t.Fatal("Lookup() found a wheel in an empty cache")
+	}
+
+	build := t.TempDir()
+	for _, name := range []string{"flash_attn-2.6.3-cp311-cp311-linux_x86_64.whl", "flash_attn-2.7.4-cp311-cp311-linux_x86_64.whl"} {
+		wheel := filepath.Join(build, name)
+		if err := os.WriteFile(wheel, []byte("wheel"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := cache.Store("flash-attn", env, wheel)
+		if err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+		if _, err := os.Stat(wheel); !os.IsNotExist(err) {
+			t.Errorf("Store() left %s behind", wheel)
+		}
+		if filepath.Dir(stored) != cache.Path(env) {
+			t.Errorf("Store() = %s, want it under %s", stored, cache.Path(env))
+		}
+	}
+
+	got, ok := cache.Lookup("flash-attn", env)
+	if !ok || filepath.Base(got) != "flash_attn-2.7.4-cp311-cp311-linux_x86_64.whl" {
+		t.Errorf("Lookup() = %q, %v; want the last stored wheel", got, ok)
+	}
+	if n := len(cache.wheels("flash-attn", env)); n != 1 {
+		t.Errorf("cache holds %d flash-attn wheels, want 1", n)
+	}
+	if _, ok := cache.Lookup("flash-attn", other); ok {
+		t.Error("Lookup() returned a wheel built against a different torch")
+	}
+	if err := cache.Remove(got); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, ok := cache.Lookup("flash-attn", env); ok {
+		t.Error("Lookup() found a removed wheel")
+	}
+}
@@ -0,0 +1,28 @@
+// Package winsvc installs gswarm as a Windows service and runs it under the
+// service control manager. It talks to the manager through advapi32
+// directly, as procctl does with kernel32, so it needs no extra modules.
+//
+// A service gets no console, so the console close and shutdown events that
+// stop the supervisor elsewhere never arrive; Context is cancelled instead
+// when the manager asks the service to stop.
+package winsvc
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultName is the service name used when none is given
+const DefaultName = "gswarm"
+
+// ErrUnsupported is returned by Install, Uninstall and Run on platforms
+// without a Windows service manager
+var ErrUnsupported = errors.New("Windows services are only available on Windows")
+
+var stopCtx, stop = context.WithCancel(context.Background())
+
+// Context is cancelled when the service manager stops the service or the
+// machine shuts down. Outside a service it is never cancelled.
+func Context() context.Context {
+	return stopCtx
+}
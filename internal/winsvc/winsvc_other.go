@@ -0,0 +1,18 @@
+//go:build !windows
+
+package winsvc
+
+// Install fails: there is no Windows service manager here
+func Install(name, displayName string, args []string) error {
+	return ErrUnsupported
+}
+
+// Uninstall fails: there is no Windows service manager here
+func Uninstall(name string) error {
+	return ErrUnsupported
+}
+
+// Run fails: there is no Windows service manager here
+func Run(name string, run func() error) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,29 @@
+package winsvc
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	if err := Context().Err(); err != nil {
+		t.Errorf("Context().Err() = %v outside a service", err)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the service manager is available")
+	}
+	if err := Install(DefaultName, "gswarm", nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Install() error = %v, want ErrUnsupported", err)
+	}
+	if err := Uninstall(DefaultName); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Uninstall() error = %v, want ErrUnsupported", err)
+	}
+	called := false
+	if err := Run(DefaultName, func() error { called = true; return nil }); !errors.Is(err, ErrUnsupported) || called {
+		t.Errorf("Run() error = %v, called = %v", err, called)
+	}
+}
@@ -0,0 +1,235 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                     = syscall.NewLazyDLL("advapi32.dll")
+	openSCManager                = advapi32.NewProc("OpenSCManagerW")
+	createService                = advapi32.NewProc("CreateServiceW")
+	openService                  = advapi32.NewProc("OpenServiceW")
+	controlService               = advapi32.NewProc("ControlService")
+	deleteService                = advapi32.NewProc("DeleteService")
+	closeServiceHandle           = advapi32.NewProc("CloseServiceHandle")
+	startServiceCtrlDispatcher   = advapi32.NewProc("StartServiceCtrlDispatcherW")
+	registerServiceCtrlHandlerEx = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	setServiceStatus             = advapi32.NewProc("SetServiceStatus")
+)
+
+// Values from winsvc.h and winerror.h
+const (
+	scManagerAllAccess = 0xF003F
+	serviceAllAccess   = 0xF01FF
+
+	serviceWin32OwnProcess = 0x10
+	serviceAutoStart       = 2
+	serviceErrorNormal     = 1
+
+	serviceControlStop        = 1
+	serviceControlInterrogate = 4
+	serviceControlShutdown    = 5
+
+	serviceAcceptStop     = 1
+	serviceAcceptShutdown = 4
+
+	serviceStopped     = 1
+	serviceStopPending = 3
+	serviceRunning     = 4
+
+	errorServiceSpecificError = 1066
+	errorServiceNotActive     = 1062
+)
+
+// stopWaitHint tells the manager how long a stop may take: the trainer's
+// grace plus time for the supervisor to record the stop
+const stopWaitHint = 15000
+
+// serviceStatus is SERVICE_STATUS
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+// serviceTableEntry is SERVICE_TABLE_ENTRYW
+type serviceTableEntry struct {
+	name *uint16
+	proc uintptr
+}
+
+// Install registers a service that starts at boot and runs the current
+// executable with args
+func Install(name, displayName string, args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the gswarm executable: %w", err)
+	}
+	quoted := []string{syscall.EscapeArg(exe)}
+	for _, a := range args {
+		quoted = append(quoted, syscall.EscapeArg(a))
+	}
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	displayPtr, err := syscall.UTF16PtrFromString(displayName)
+	if err != nil {
+		return err
+	}
+	cmdLine, err := syscall.UTF16PtrFromString(strings.Join(quoted, " "))
+	if err != nil {
+		return err
+	}
+
+	m, err := openManager()
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle.Call(m)
+	h, _, err := createService.Call(m, uintptr(unsafe.Pointer(namePtr)), uintptr(unsafe.Pointer(displayPtr)),
+		serviceAllAccess, serviceWin32OwnProcess, serviceAutoStart, serviceErrorNormal,
+		uintptr(unsafe.Pointer(cmdLine)), 0, 0, 0, 0, 0)
+	if h == 0 {
+		return fmt.Errorf("failed to create service %s: %w", name, err)
+	}
+	closeServiceHandle.Call(h)
+	return nil
+}
+
+// Uninstall stops the service if it is running and removes it
+func Uninstall(name string) error {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	m, err := openManager()
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle.Call(m)
+	h, _, err := openService.Call(m, uintptr(unsafe.Pointer(namePtr)), serviceAllAccess)
+	if h == 0 {
+		return fmt.Errorf("failed to open service %s: %w", name, err)
+	}
+	defer closeServiceHandle.Call(h)
+
+	var status serviceStatus
+	if r, _, err := controlService.Call(h, serviceControlStop, uintptr(unsafe.Pointer(&status))); r == 0 {
+		if !errors.Is(err, syscall.Errno(errorServiceNotActive)) {
+			return fmt.Errorf("failed to stop service %s: %w", name, err)
+		}
+	}
+	if r, _, err := deleteService.Call(h); r == 0 {
+		return fmt.Errorf("failed to delete service %s: %w", name, err)
+	}
+	return nil
+}
+
+func openManager() (uintptr, error) {
+	m, _, err := openSCManager.Call(0, 0, scManagerAllAccess)
+	if m == 0 {
+		return 0, fmt.Errorf("failed to open the service manager (run as Administrator): %w", err)
+	}
+	return m, nil
+}
+
+// service is the state of the one service this process runs
+var service struct {
+	name *uint16
+	run  func() error
+	err  error
+
+	mu     sync.Mutex
+	handle uintptr
+	status serviceStatus
+}
+
+// The callbacks are created once: Windows allows a process only a limited
+// number of them
+var (
+	serviceMainCallback = syscall.NewCallback(serviceMain)
+	handlerCallback     = syscall.NewCallback(handler)
+)
+
+// Run connects to the service manager and calls run as the service named
+// name, returning once it has returned. It fails when the process wasn't
+// started by the manager.
+func Run(name string, run func() error) error {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	service.name, service.run = namePtr, run
+	table := []serviceTableEntry{{name: namePtr, proc: serviceMainCallback}, {}}
+	if r, _, err := startServiceCtrlDispatcher.Call(uintptr(unsafe.Pointer(&table[0]))); r == 0 {
+		return fmt.Errorf("failed to connect to the service manager (services are started with sc start): %w", err)
+	}
+	return service.err
+}
+
+// serviceMain is the ServiceMain the manager calls on its own thread
+func serviceMain(argc, argv uintptr) uintptr {
+	h, _, err := registerServiceCtrlHandlerEx.Call(uintptr(unsafe.Pointer(service.name)), handlerCallback, 0)
+	if h == 0 {
+		service.err = fmt.Errorf("failed to register the service handler: %w", err)
+		return 0
+	}
+	service.mu.Lock()
+	service.handle = h
+	service.mu.Unlock()
+
+	setStatus(serviceRunning, 0)
+	service.err = service.run()
+	exitCode := uint32(0)
+	if service.err != nil {
+		exitCode = errorServiceSpecificError
+	}
+	setStatus(serviceStopped, exitCode)
+	return 0
+}
+
+// handler is the HandlerEx the manager calls with controls for the service
+func handler(control, eventType, eventData, context uintptr) uintptr {
+	switch control {
+	case serviceControlStop, serviceControlShutdown:
+		setStatus(serviceStopPending, 0)
+		stop()
+	case serviceControlInterrogate:
+		service.mu.Lock()
+		status := service.status
+		service.mu.Unlock()
+		setStatus(status.CurrentState, status.Win32ExitCode)
+	}
+	return 0
+}
+
+func setStatus(state, exitCode uint32) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	s := serviceStatus{ServiceType: serviceWin32OwnProcess, CurrentState: state, Win32ExitCode: exitCode}
+	switch state {
+	case serviceRunning:
+		s.ControlsAccepted = serviceAcceptStop | serviceAcceptShutdown
+	case serviceStopPending:
+		s.WaitHint = stopWaitHint
+		s.CheckPoint = service.status.CheckPoint + 1
+	}
+	if exitCode == errorServiceSpecificError {
+		s.ServiceSpecificExitCode = 1
+	}
+	service.status = s
+	setServiceStatus.Call(service.handle, uintptr(unsafe.Pointer(&s)))
+}